@@ -0,0 +1,172 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseOID parses an OID in dotted notation, e.g. 1.3.6.1.4.1.311.21.8.
+func parseOID(value string) (asn1.ObjectIdentifier, error) {
+	arcs := strings.Split(value, ".")
+	if len(arcs) < 2 {
+		return nil, fmt.Errorf("%q is not a dotted OID", value)
+	}
+
+	oid := make(asn1.ObjectIdentifier, 0, len(arcs))
+	for _, arc := range arcs {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a dotted OID", value)
+		}
+		oid = append(oid, n)
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, fmt.Errorf("%q is not a valid OID", value)
+	}
+
+	return oid, nil
+}
+
+// validateOID is a schema.SchemaValidateFunc checking parseOID accepts the value.
+func validateOID(i interface{}, k string) ([]string, []error) {
+	value, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	if _, err := parseOID(value); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+
+	return nil, nil
+}
+
+// publicKeyAlgorithmNames names the public key algorithms by OID, including the GOST R 34.10 ones crypto/x509 cannot use,
+// so certificates and keys of mixed estates can still be inventoried.
+var publicKeyAlgorithmNames = map[string]string{
+	"1.2.840.113549.1.1.1": RSA.String(),
+	"1.2.840.10045.2.1":    ECDSA.String(),
+	"1.3.101.112":          ED25519.String(),
+	"1.3.101.110":          "X25519",
+	"1.2.643.2.2.19":       "GOST R 34.10-2001",
+	"1.2.643.7.1.1.1.1":    "GOST R 34.10-2012 256",
+	"1.2.643.7.1.1.1.2":    "GOST R 34.10-2012 512",
+}
+
+// signatureAlgorithmNames names the signature algorithms crypto/x509 does not know.
+var signatureAlgorithmNames = map[string]string{
+	"1.2.643.2.2.3":     "GOST R 34.11-94 with GOST R 34.10-2001",
+	"1.2.643.7.1.1.3.2": "GOST R 34.11-2012 256 with GOST R 34.10-2012 256",
+	"1.2.643.7.1.1.3.3": "GOST R 34.11-2012 512 with GOST R 34.10-2012 512",
+}
+
+// publicKeyParameterNames names the ECDSA curves and the GOST R 34.10 parameter sets by OID.
+var publicKeyParameterNames = map[string]string{
+	"1.3.132.0.33":        P224.String(),
+	"1.2.840.10045.3.1.7": P256.String(),
+	"1.3.132.0.34":        P384.String(),
+	"1.3.132.0.35":        P521.String(),
+	"1.2.643.2.2.35.1":    "CryptoPro-A",
+	"1.2.643.2.2.35.2":    "CryptoPro-B",
+	"1.2.643.2.2.35.3":    "CryptoPro-C",
+	"1.2.643.2.2.36.0":    "CryptoPro-XchA",
+	"1.2.643.2.2.36.1":    "CryptoPro-XchB",
+	"1.2.643.7.1.2.1.1.1": "tc26-256-A",
+	"1.2.643.7.1.2.1.1.2": "tc26-256-B",
+	"1.2.643.7.1.2.1.1.3": "tc26-256-C",
+	"1.2.643.7.1.2.1.1.4": "tc26-256-D",
+	"1.2.643.7.1.2.1.2.1": "tc26-512-A",
+	"1.2.643.7.1.2.1.2.2": "tc26-512-B",
+	"1.2.643.7.1.2.1.2.3": "tc26-512-C",
+}
+
+// publicKeyAlgorithm describes the algorithm of a SubjectPublicKeyInfo or PKCS#8 PrivateKeyInfo.
+type publicKeyAlgorithm struct {
+	// Name is the name of the algorithm, its OID when it is not known.
+	Name string
+	// Parameters is the name of the ECDSA curve or GOST parameter set, its OID when it is not known.
+	// It is empty for the algorithms without parameters.
+	Parameters string
+}
+
+// describeAlgorithmIdentifier returns the publicKeyAlgorithm of the AlgorithmIdentifier of a key.
+func describeAlgorithmIdentifier(algorithm pkix.AlgorithmIdentifier) publicKeyAlgorithm {
+	oid := algorithm.Algorithm.String()
+	described := publicKeyAlgorithm{Name: oid}
+	if name, ok := publicKeyAlgorithmNames[oid]; ok {
+		described.Name = name
+	}
+
+	// ECDSA has the named curve or explicit parameters, GOST R 34.10 a sequence starting with the parameter set
+	var parameters asn1.ObjectIdentifier
+	if oid, err := namedCurveOID(algorithm.Parameters.FullBytes); err == nil && algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		parameters = oid
+	} else if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &parameters); err != nil {
+		var gostParameters struct {
+			PublicKeyParamSet asn1.ObjectIdentifier
+			Rest              asn1.RawValue `asn1:"optional"`
+		}
+		if _, err = asn1.Unmarshal(algorithm.Parameters.FullBytes, &gostParameters); err != nil {
+			return described
+		}
+		parameters = gostParameters.PublicKeyParamSet
+	}
+	described.Parameters = parameters.String()
+	if name, ok := publicKeyParameterNames[described.Parameters]; ok {
+		described.Parameters = name
+	}
+
+	return described
+}
+
+// describePublicKeyInfo returns the publicKeyAlgorithm of a DER SubjectPublicKeyInfo.
+func describePublicKeyInfo(der []byte) (publicKeyAlgorithm, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &spki); err != nil {
+		return publicKeyAlgorithm{}, fmt.Errorf("invalid SubjectPublicKeyInfo: %w", err)
+	}
+
+	return describeAlgorithmIdentifier(spki.Algorithm), nil
+}
+
+// describePrivateKeyInfo returns the publicKeyAlgorithm of a DER PKCS#8 PrivateKeyInfo.
+func describePrivateKeyInfo(der []byte) (publicKeyAlgorithm, error) {
+	var info struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return publicKeyAlgorithm{}, fmt.Errorf("invalid PKCS#8 PrivateKeyInfo: %w", err)
+	}
+
+	return describeAlgorithmIdentifier(info.Algorithm), nil
+}
+
+// certificateSignatureAlgorithm names the signature algorithm of cert, including the ones crypto/x509 does not know.
+func certificateSignatureAlgorithm(cert *x509.Certificate) string {
+	if cert.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		return cert.SignatureAlgorithm.String()
+	}
+
+	var raw struct {
+		TBSCertificate     asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		SignatureValue     asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.Raw, &raw); err != nil {
+		return x509.UnknownSignatureAlgorithm.String()
+	}
+	oid := raw.SignatureAlgorithm.Algorithm.String()
+	if name, ok := signatureAlgorithmNames[oid]; ok {
+		return name
+	}
+	return oid
+}
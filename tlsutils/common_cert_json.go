@@ -0,0 +1,192 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// certificateJSONVersion is the version of the certificateJSON schema, increased on incompatible changes.
+const certificateJSONVersion = 1
+
+// certificateJSONDocument is the schema of certificateJSON. Lists are never null, and absent values are empty,
+// so that policies do not need to check for missing fields.
+type certificateJSONDocument struct {
+	SchemaVersion          int                        `json:"schema_version"`
+	Version                int                        `json:"version"`
+	SerialNumber           string                     `json:"serial_number"`
+	Subject                certificateJSONName        `json:"subject"`
+	Issuer                 certificateJSONName        `json:"issuer"`
+	NotBefore              string                     `json:"not_before"`
+	NotAfter               string                     `json:"not_after"`
+	NotBeforeUnix          int64                      `json:"not_before_unix"`
+	NotAfterUnix           int64                      `json:"not_after_unix"`
+	ValidityHours          int64                      `json:"validity_hours"`
+	SignatureAlgorithm     string                     `json:"signature_algorithm"`
+	PublicKey              certificateJSONPublicKey   `json:"public_key"`
+	IsCA                   bool                       `json:"is_ca"`
+	MaxPathLen             *int                       `json:"max_path_len"`
+	SelfSigned             bool                       `json:"self_signed"`
+	KeyUsages              []string                   `json:"key_usages"`
+	ExtKeyUsages           []string                   `json:"ext_key_usages"`
+	DNSNames               []string                   `json:"dns_names"`
+	IPAddresses            []string                   `json:"ip_addresses"`
+	URIs                   []string                   `json:"uris"`
+	EmailAddresses         []string                   `json:"email_addresses"`
+	SubjectKeyID           string                     `json:"subject_key_id"`
+	AuthorityKeyID         string                     `json:"authority_key_id"`
+	CRLDistributionPoints  []string                   `json:"crl_distribution_points"`
+	FreshestCRLURLs        []string                   `json:"freshest_crl_urls"`
+	OCSPServers            []string                   `json:"ocsp_servers"`
+	IssuingCertificateURLs []string                   `json:"issuing_certificate_urls"`
+	PolicyOIDs             []string                   `json:"policy_oids"`
+	PermittedDNSDomains    []string                   `json:"permitted_dns_domains"`
+	ExcludedDNSDomains     []string                   `json:"excluded_dns_domains"`
+	Extensions             []certificateJSONExtension `json:"extensions"`
+	SHA256Fingerprint      string                     `json:"sha256_fingerprint"`
+}
+
+// certificateJSONName is a distinguished name, with the attribute names of the subject blocks.
+type certificateJSONName struct {
+	DN                 string   `json:"dn"`
+	CommonName         string   `json:"common_name"`
+	SerialNumber       string   `json:"serial_number"`
+	Organization       []string `json:"organization"`
+	OrganizationalUnit []string `json:"organizational_unit"`
+	Country            []string `json:"country"`
+	Province           []string `json:"province"`
+	Locality           []string `json:"locality"`
+	StreetAddress      []string `json:"street_address"`
+	PostalCode         []string `json:"postal_code"`
+}
+
+// certificateJSONPublicKey is the SubjectPublicKeyInfo of the certificate.
+type certificateJSONPublicKey struct {
+	Algorithm  string `json:"algorithm"`
+	Parameters string `json:"parameters"`
+	SPKIPin    string `json:"spki_pin"`
+}
+
+// certificateJSONExtension lists an extension of the certificate, known or not.
+type certificateJSONExtension struct {
+	OID      string `json:"oid"`
+	Critical bool   `json:"critical"`
+}
+
+// certificateJSON renders cert as a JSON document of stable schema, for `jsondecode` and policy engines like OPA.
+// Usages have the allowed_uses names, unknown extended key usages their OID.
+func certificateJSON(cert *x509.Certificate) string {
+	// crypto/x509 parsed the SubjectPublicKeyInfo already
+	algorithm, _ := describePublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+
+	document := certificateJSONDocument{
+		SchemaVersion:      certificateJSONVersion,
+		Version:            cert.Version,
+		SerialNumber:       cert.SerialNumber.Text(16),
+		Subject:            certificateJSONNameOf(cert.Subject),
+		Issuer:             certificateJSONNameOf(cert.Issuer),
+		NotBefore:          cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:           cert.NotAfter.UTC().Format(time.RFC3339),
+		NotBeforeUnix:      cert.NotBefore.Unix(),
+		NotAfterUnix:       cert.NotAfter.Unix(),
+		ValidityHours:      int64(cert.NotAfter.Sub(cert.NotBefore) / time.Hour),
+		SignatureAlgorithm: certificateSignatureAlgorithm(cert),
+		PublicKey: certificateJSONPublicKey{
+			Algorithm:  algorithm.Name,
+			Parameters: algorithm.Parameters,
+			SPKIPin:    spkiPin(cert.RawSubjectPublicKeyInfo),
+		},
+		IsCA:                   cert.IsCA,
+		SelfSigned:             isSelfSigned(cert),
+		KeyUsages:              make([]string, 0),
+		ExtKeyUsages:           make([]string, 0, len(cert.ExtKeyUsage)+len(cert.UnknownExtKeyUsage)),
+		DNSNames:               nonNilStrings(cert.DNSNames),
+		IPAddresses:            make([]string, 0, len(cert.IPAddresses)),
+		URIs:                   make([]string, 0, len(cert.URIs)),
+		EmailAddresses:         nonNilStrings(cert.EmailAddresses),
+		SubjectKeyID:           hex.EncodeToString(cert.SubjectKeyId),
+		AuthorityKeyID:         hex.EncodeToString(cert.AuthorityKeyId),
+		CRLDistributionPoints:  nonNilStrings(cert.CRLDistributionPoints),
+		FreshestCRLURLs:        freshestCRLURLs(cert),
+		OCSPServers:            nonNilStrings(cert.OCSPServer),
+		IssuingCertificateURLs: nonNilStrings(cert.IssuingCertificateURL),
+		PolicyOIDs:             make([]string, 0, len(cert.PolicyIdentifiers)),
+		PermittedDNSDomains:    nonNilStrings(cert.PermittedDNSDomains),
+		ExcludedDNSDomains:     nonNilStrings(cert.ExcludedDNSDomains),
+		Extensions:             make([]certificateJSONExtension, 0, len(cert.Extensions)),
+		SHA256Fingerprint:      sha256Fingerprint(cert),
+	}
+	if cert.IsCA && (cert.MaxPathLen > 0 || cert.MaxPathLenZero) {
+		document.MaxPathLen = &cert.MaxPathLen
+	}
+
+	for _, name := range supportedAllowedUsesStr() {
+		if usage, ok := keyUsages[name]; ok && cert.KeyUsage&usage != 0 {
+			document.KeyUsages = append(document.KeyUsages, name)
+		}
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		for name, known := range extKeyUsages {
+			if known == usage {
+				document.ExtKeyUsages = append(document.ExtKeyUsages, name)
+			}
+		}
+	}
+	for _, usage := range cert.UnknownExtKeyUsage {
+		document.ExtKeyUsages = append(document.ExtKeyUsages, usage.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		document.IPAddresses = append(document.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		document.URIs = append(document.URIs, uri.String())
+	}
+	for _, policy := range cert.PolicyIdentifiers {
+		document.PolicyOIDs = append(document.PolicyOIDs, policy.String())
+	}
+	for _, extension := range cert.Extensions {
+		document.Extensions = append(document.Extensions, certificateJSONExtension{OID: extension.Id.String(), Critical: extension.Critical})
+	}
+
+	// the document only holds strings, numbers and booleans
+	data, _ := json.Marshal(document)
+	return string(data)
+}
+
+// certificateJSONPEM renders the first certificate of certPem with certificateJSON.
+func certificateJSONPEM(certPem string) (string, error) {
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	return certificateJSON(cert), nil
+}
+
+// certificateJSONNameOf converts name to a certificateJSONName.
+func certificateJSONNameOf(name pkix.Name) certificateJSONName {
+	return certificateJSONName{
+		DN:                 name.String(),
+		CommonName:         name.CommonName,
+		SerialNumber:       name.SerialNumber,
+		Organization:       nonNilStrings(name.Organization),
+		OrganizationalUnit: nonNilStrings(name.OrganizationalUnit),
+		Country:            nonNilStrings(name.Country),
+		Province:           nonNilStrings(name.Province),
+		Locality:           nonNilStrings(name.Locality),
+		StreetAddress:      nonNilStrings(name.StreetAddress),
+		PostalCode:         nonNilStrings(name.PostalCode),
+	}
+}
+
+// nonNilStrings returns values, or an empty slice when it is nil, encoded as [] instead of null.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
@@ -0,0 +1,101 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertificateJSON(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	uri, _ := url.Parse("spiffe://example.com/web")
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaf, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(0x2a),
+		Subject:               pkix.Name{CommonName: "www.example.com", Organization: []string{"Example"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(48 * time.Hour),
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		UnknownExtKeyUsage:    []asn1.ObjectIdentifier{{1, 2, 3, 4}},
+		DNSNames:              []string{"www.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("192.0.2.1")},
+		URIs:                  []*url.URL{uri},
+		CRLDistributionPoints: []string{"http://crl.example.com/ca.crl"},
+		PolicyIdentifiers:     []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
+	}, ca, caKey)
+
+	var document certificateJSONDocument
+	if err := json.Unmarshal([]byte(certificateJSON(leaf)), &document); err != nil {
+		t.Fatalf("expected a JSON document, got %s", err)
+	}
+	for name, test := range map[string]struct {
+		got  interface{}
+		want interface{}
+	}{
+		"schema_version":          {document.SchemaVersion, certificateJSONVersion},
+		"serial_number":           {document.SerialNumber, "2a"},
+		"subject":                 {document.Subject.CommonName + "/" + strings.Join(document.Subject.Organization, ","), "www.example.com/Example"},
+		"subject country":         {document.Subject.Country, []string{}},
+		"issuer":                  {document.Issuer.DN, ca.Subject.String()},
+		"not_before":              {document.NotBefore, "2026-01-01T00:00:00Z"},
+		"not_after_unix":          {document.NotAfterUnix, notBefore.Add(48 * time.Hour).Unix()},
+		"validity_hours":          {document.ValidityHours, int64(48)},
+		"signature_algorithm":     {document.SignatureAlgorithm, x509.ECDSAWithSHA256.String()},
+		"public_key":              {document.PublicKey, certificateJSONPublicKey{Algorithm: ECDSA.String(), Parameters: P256.String(), SPKIPin: spkiPin(leaf.RawSubjectPublicKeyInfo)}},
+		"is_ca":                   {document.IsCA, false},
+		"max_path_len":            {document.MaxPathLen, (*int)(nil)},
+		"self_signed":             {document.SelfSigned, false},
+		"key_usages":              {document.KeyUsages, []string{"digital_signature"}},
+		"ext_key_usages":          {document.ExtKeyUsages, []string{"server_auth", "1.2.3.4"}},
+		"dns_names":               {document.DNSNames, []string{"www.example.com"}},
+		"ip_addresses":            {document.IPAddresses, []string{"192.0.2.1"}},
+		"uris":                    {document.URIs, []string{"spiffe://example.com/web"}},
+		"email_addresses":         {document.EmailAddresses, []string{}},
+		"authority_key_id":        {document.AuthorityKeyID, hex.EncodeToString(ca.SubjectKeyId)},
+		"crl_distribution_points": {document.CRLDistributionPoints, []string{"http://crl.example.com/ca.crl"}},
+		"ocsp_servers":            {document.OCSPServers, []string{}},
+		"policy_oids":             {document.PolicyOIDs, []string{"2.23.140.1.2.1"}},
+		"extensions":              {len(document.Extensions), len(leaf.Extensions)},
+		"sha256_fingerprint":      {document.SHA256Fingerprint, sha256Fingerprint(leaf)},
+	} {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("expected %s %#v, got %#v", name, test.want, test.got)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(certificateJSON(ca)), &document); err != nil {
+		t.Fatal(err)
+	}
+	if !document.IsCA || !document.SelfSigned || !strings.Contains(strings.Join(document.KeyUsages, ","), "cert_signing") {
+		t.Errorf("expected a self-signed CA with cert_signing, got %+v", document)
+	}
+}
+
+func TestCertificateJSONWithoutLists(t *testing.T) {
+	cert, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "bare"}}, nil, nil)
+
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(certificateJSON(cert)), &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"key_usages", "ext_key_usages", "dns_names", "ip_addresses", "uris", "email_addresses", "crl_distribution_points", "freshest_crl_urls", "ocsp_servers", "issuing_certificate_urls", "policy_oids", "permitted_dns_domains", "excluded_dns_domains", "extensions"} {
+		if _, ok := document[key].([]interface{}); !ok {
+			t.Errorf("expected %s to be an empty list, got %#v", key, document[key])
+		}
+	}
+
+	if _, err := certificateJSONPEM("not a certificate"); err == nil {
+		t.Errorf("expected an error for an invalid certificate")
+	}
+}
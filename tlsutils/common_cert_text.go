@@ -0,0 +1,361 @@
+package tlsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// opensslSignatureAlgorithms names the signature algorithms like OpenSSL.
+var opensslSignatureAlgorithms = map[x509.SignatureAlgorithm]string{
+	x509.MD5WithRSA:       "md5WithRSAEncryption",
+	x509.SHA1WithRSA:      "sha1WithRSAEncryption",
+	x509.SHA256WithRSA:    "sha256WithRSAEncryption",
+	x509.SHA384WithRSA:    "sha384WithRSAEncryption",
+	x509.SHA512WithRSA:    "sha512WithRSAEncryption",
+	x509.SHA256WithRSAPSS: "rsassaPss",
+	x509.SHA384WithRSAPSS: "rsassaPss",
+	x509.SHA512WithRSAPSS: "rsassaPss",
+	x509.ECDSAWithSHA1:    "ecdsa-with-SHA1",
+	x509.ECDSAWithSHA256:  "ecdsa-with-SHA256",
+	x509.ECDSAWithSHA384:  "ecdsa-with-SHA384",
+	x509.ECDSAWithSHA512:  "ecdsa-with-SHA512",
+	x509.PureEd25519:      "ED25519",
+}
+
+// opensslCurveNames are the OpenSSL short names of the NIST curves.
+var opensslCurveNames = map[string]string{
+	"P-224": "secp224r1",
+	"P-256": "prime256v1",
+	"P-384": "secp384r1",
+	"P-521": "secp521r1",
+}
+
+// opensslAttributeNames are the OpenSSL short names of the distinguished name attributes.
+var opensslAttributeNames = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.5":                    "serialNumber",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "ST",
+	"2.5.4.9":                    "street",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"2.5.4.17":                   "postalCode",
+	"0.9.2342.19200300.100.1.25": "DC",
+	"0.9.2342.19200300.100.1.1":  "UID",
+	"1.2.840.113549.1.9.1":       "emailAddress",
+}
+
+// opensslExtKeyUsageNames are the OpenSSL long names of the extended key usages.
+var opensslExtKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                        "Any Extended Key Usage",
+	x509.ExtKeyUsageServerAuth:                 "TLS Web Server Authentication",
+	x509.ExtKeyUsageClientAuth:                 "TLS Web Client Authentication",
+	x509.ExtKeyUsageCodeSigning:                "Code Signing",
+	x509.ExtKeyUsageEmailProtection:            "E-mail Protection",
+	x509.ExtKeyUsageIPSECEndSystem:             "IPSec End System",
+	x509.ExtKeyUsageIPSECTunnel:                "IPSec Tunnel",
+	x509.ExtKeyUsageIPSECUser:                  "IPSec User",
+	x509.ExtKeyUsageTimeStamping:               "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:                "OCSP Signing",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto: "Microsoft Server Gated Crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:  "Netscape Server Gated Crypto",
+}
+
+// opensslKeyUsageNames are the OpenSSL names of the key usages, in bit order.
+var opensslKeyUsageNames = []string{
+	"Digital Signature", "Non Repudiation", "Key Encipherment", "Data Encipherment", "Key Agreement",
+	"Certificate Sign", "CRL Sign", "Encipher Only", "Decipher Only",
+}
+
+// certificateText renders cert like `openssl x509 -text -noout` of OpenSSL 3, for plans reviewed by humans.
+// Extensions it cannot describe are dumped in hex.
+func certificateText(cert *x509.Certificate) string {
+	var text strings.Builder
+	line := func(indent int, format string, args ...interface{}) {
+		text.WriteString(strings.Repeat(" ", indent))
+		fmt.Fprintf(&text, format, args...)
+		text.WriteString("\n")
+	}
+	hexLines := func(indent, perLine int, data []byte) {
+		for len(data) > 0 {
+			n := min(len(data), perLine)
+			hexLine := colonHex(data[:n])
+			if n < len(data) {
+				hexLine += ":"
+			}
+			line(indent, "%s", hexLine)
+			data = data[n:]
+		}
+	}
+	signatureAlgorithm := certificateSignatureAlgorithm(cert)
+	if name, ok := opensslSignatureAlgorithms[cert.SignatureAlgorithm]; ok {
+		signatureAlgorithm = name
+	}
+
+	line(0, "Certificate:")
+	line(4, "Data:")
+	line(8, "Version: %d (0x%x)", cert.Version, cert.Version-1)
+	if cert.SerialNumber.Sign() >= 0 && cert.SerialNumber.IsInt64() {
+		line(8, "Serial Number: %d (0x%x)", cert.SerialNumber, cert.SerialNumber)
+	} else {
+		line(8, "Serial Number:")
+		serial := new(big.Int).Abs(cert.SerialNumber)
+		if cert.SerialNumber.Sign() < 0 {
+			line(12, "(Negative)%s", colonHex(serial.Bytes()))
+		} else {
+			line(12, "%s", colonHex(serial.Bytes()))
+		}
+	}
+	line(8, "Signature Algorithm: %s", signatureAlgorithm)
+	line(8, "Issuer: %s", opensslName(cert.RawIssuer))
+	line(8, "Validity")
+	line(12, "Not Before: %s", cert.NotBefore.UTC().Format("Jan _2 15:04:05 2006 GMT"))
+	line(12, "Not After : %s", cert.NotAfter.UTC().Format("Jan _2 15:04:05 2006 GMT"))
+	line(8, "Subject: %s", opensslName(cert.RawSubject))
+	line(8, "Subject Public Key Info:")
+	switch pubKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		line(12, "Public Key Algorithm: rsaEncryption")
+		line(16, "Public-Key: (%d bit)", pubKey.N.BitLen())
+		line(16, "Modulus:")
+		modulus := pubKey.N.Bytes()
+		if len(modulus) > 0 && modulus[0]&0x80 != 0 {
+			modulus = append([]byte{0}, modulus...)
+		}
+		hexLines(20, 15, modulus)
+		line(16, "Exponent: %d (0x%x)", pubKey.E, pubKey.E)
+	case *ecdsa.PublicKey:
+		line(12, "Public Key Algorithm: id-ecPublicKey")
+		line(16, "Public-Key: (%d bit)", pubKey.Curve.Params().BitSize)
+		line(16, "pub:")
+		hexLines(20, 15, elliptic.Marshal(pubKey.Curve, pubKey.X, pubKey.Y))
+		line(16, "ASN1 OID: %s", opensslCurveNames[pubKey.Curve.Params().Name])
+		line(16, "NIST CURVE: %s", pubKey.Curve.Params().Name)
+	case ed25519.PublicKey:
+		line(12, "Public Key Algorithm: ED25519")
+		line(16, "ED25519 Public-Key:")
+		line(16, "pub:")
+		hexLines(20, 15, pubKey)
+	default:
+		algorithm, _ := describePublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+		line(12, "Public Key Algorithm: %s", algorithm.Name)
+		hexLines(16, 15, cert.RawSubjectPublicKeyInfo)
+	}
+
+	if len(cert.Extensions) > 0 {
+		line(8, "X509v3 extensions:")
+	}
+	for _, extension := range cert.Extensions {
+		name, values := opensslExtension(cert, extension)
+		critical := ""
+		if extension.Critical {
+			critical = "critical"
+		}
+		line(12, "%s: %s", name, critical)
+		if values == nil {
+			hexLines(16, 18, extension.Value)
+		}
+		for _, value := range values {
+			line(16, "%s", value)
+		}
+	}
+
+	line(4, "Signature Algorithm: %s", signatureAlgorithm)
+	line(4, "Signature Value:")
+	hexLines(8, 18, cert.Signature)
+
+	return text.String()
+}
+
+// certificateTextPEM renders the first certificate of certPem with certificateText.
+func certificateTextPEM(certPem string) (string, error) {
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	return certificateText(cert), nil
+}
+
+// opensslExtension returns the OpenSSL name of a certificate extension and the lines describing its value,
+// nil when it is not known.
+func opensslExtension(cert *x509.Certificate, extension pkix.Extension) (string, []string) {
+	switch extension.Id.String() {
+	case "2.5.29.15":
+		usages := make([]string, 0)
+		for bit, name := range opensslKeyUsageNames {
+			if cert.KeyUsage&(1<<bit) != 0 {
+				usages = append(usages, name)
+			}
+		}
+		return "X509v3 Key Usage", []string{strings.Join(usages, ", ")}
+	case "2.5.29.37":
+		usages := make([]string, 0, len(cert.ExtKeyUsage)+len(cert.UnknownExtKeyUsage))
+		for _, usage := range cert.ExtKeyUsage {
+			usages = append(usages, opensslExtKeyUsageNames[usage])
+		}
+		for _, usage := range cert.UnknownExtKeyUsage {
+			usages = append(usages, usage.String())
+		}
+		return "X509v3 Extended Key Usage", []string{strings.Join(usages, ", ")}
+	case "2.5.29.19":
+		constraint := "CA:FALSE"
+		if cert.IsCA {
+			constraint = "CA:TRUE"
+			if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+				constraint += fmt.Sprintf(", pathlen:%d", cert.MaxPathLen)
+			}
+		}
+		return "X509v3 Basic Constraints", []string{constraint}
+	case "2.5.29.14":
+		return "X509v3 Subject Key Identifier", []string{strings.ToUpper(colonHex(cert.SubjectKeyId))}
+	case "2.5.29.35":
+		return "X509v3 Authority Key Identifier", []string{strings.ToUpper(colonHex(cert.AuthorityKeyId))}
+	case "2.5.29.17":
+		return "X509v3 Subject Alternative Name", []string{strings.Join(opensslGeneralNames(cert.DNSNames, cert.EmailAddresses, cert.IPAddresses, cert.URIs), ", ")}
+	case "2.5.29.31":
+		lines := make([]string, 0, 2*len(cert.CRLDistributionPoints))
+		for _, point := range cert.CRLDistributionPoints {
+			lines = append(lines, "Full Name:", "  URI:"+point)
+		}
+		return "X509v3 CRL Distribution Points", lines
+	case "2.5.29.46":
+		urls, err := distributionPointURLs(extension.Value)
+		if err != nil {
+			return "X509v3 Freshest CRL", nil
+		}
+		lines := make([]string, 0, 2*len(urls))
+		for _, url := range urls {
+			lines = append(lines, "Full Name:", "  URI:"+url)
+		}
+		return "X509v3 Freshest CRL", lines
+	case "1.3.6.1.5.5.7.1.1":
+		lines := make([]string, 0, len(cert.OCSPServer)+len(cert.IssuingCertificateURL))
+		for _, server := range cert.OCSPServer {
+			lines = append(lines, "OCSP - URI:"+server)
+		}
+		for _, issuer := range cert.IssuingCertificateURL {
+			lines = append(lines, "CA Issuers - URI:"+issuer)
+		}
+		return "Authority Information Access", lines
+	case "2.5.29.32":
+		lines := make([]string, 0, len(cert.PolicyIdentifiers))
+		for _, policy := range cert.PolicyIdentifiers {
+			lines = append(lines, "Policy: "+policy.String())
+		}
+		return "X509v3 Certificate Policies", lines
+	case "2.5.29.33":
+		return "X509v3 Policy Mappings", policyExtensionText(extension)
+	case "2.5.29.36":
+		return "X509v3 Policy Constraints", policyExtensionText(extension)
+	case "2.5.29.54":
+		return "X509v3 Inhibit Any Policy", policyExtensionText(extension)
+	case "2.5.29.30":
+		lines := make([]string, 0)
+		permitted := opensslConstraints(cert.PermittedDNSDomains, cert.PermittedEmailAddresses, cert.PermittedIPRanges, cert.PermittedURIDomains)
+		if len(permitted) > 0 {
+			lines = append(lines, "Permitted:")
+			lines = append(lines, permitted...)
+		}
+		excluded := opensslConstraints(cert.ExcludedDNSDomains, cert.ExcludedEmailAddresses, cert.ExcludedIPRanges, cert.ExcludedURIDomains)
+		if len(excluded) > 0 {
+			lines = append(lines, "Excluded:")
+			lines = append(lines, excluded...)
+		}
+		return "X509v3 Name Constraints", lines
+	case "1.3.6.1.4.1.11129.2.4.2":
+		return "CT Precertificate SCTs", nil
+	case "1.3.6.1.5.5.7.48.1.5":
+		return "OCSP No Check", []string{""}
+	case "1.3.6.1.5.5.7.1.24":
+		return "TLS Feature", nil
+	}
+
+	return extension.Id.String(), nil
+}
+
+// opensslGeneralNames formats the subject alternative names like OpenSSL, grouped by type.
+func opensslGeneralNames(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL) []string {
+	names := make([]string, 0, len(dnsNames)+len(emailAddresses)+len(ipAddresses)+len(uris))
+	for _, name := range dnsNames {
+		names = append(names, "DNS:"+name)
+	}
+	for _, email := range emailAddresses {
+		names = append(names, "email:"+email)
+	}
+	for _, ip := range ipAddresses {
+		names = append(names, "IP Address:"+opensslIP(ip))
+	}
+	for _, uri := range uris {
+		names = append(names, "URI:"+uri.String())
+	}
+
+	return names
+}
+
+// opensslConstraints formats the subtrees of a name constraints extension like OpenSSL, one per line.
+func opensslConstraints(dnsDomains, emailAddresses []string, ipRanges []*net.IPNet, uriDomains []string) []string {
+	lines := make([]string, 0, len(dnsDomains)+len(emailAddresses)+len(ipRanges)+len(uriDomains))
+	for _, domain := range dnsDomains {
+		lines = append(lines, "  DNS:"+domain)
+	}
+	for _, email := range emailAddresses {
+		lines = append(lines, "  email:"+email)
+	}
+	for _, ipRange := range ipRanges {
+		lines = append(lines, "  IP:"+ipRange.IP.String()+"/"+net.IP(ipRange.Mask).String())
+	}
+	for _, domain := range uriDomains {
+		lines = append(lines, "  URI:"+domain)
+	}
+
+	return lines
+}
+
+// opensslName formats a DER distinguished name like OpenSSL 3, in the order of the certificate.
+func opensslName(der []byte) string {
+	var rdns pkix.RDNSequence
+	if _, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return ""
+	}
+
+	formatted := make([]string, 0, len(rdns))
+	for _, rdn := range rdns {
+		attributes := make([]string, 0, len(rdn))
+		for _, attribute := range rdn {
+			name := attribute.Type.String()
+			if short, ok := opensslAttributeNames[name]; ok {
+				name = short
+			}
+			attributes = append(attributes, fmt.Sprintf("%s = %v", name, attribute.Value))
+		}
+		formatted = append(formatted, strings.Join(attributes, " + "))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// opensslIP formats an IP address like OpenSSL, IPv6 addresses without zero compression.
+func opensslIP(ip net.IP) string {
+	if ip.To4() != nil || len(ip) != net.IPv6len {
+		return ip.String()
+	}
+
+	groups := make([]string, 0, 8)
+	for i := 0; i < net.IPv6len; i += 2 {
+		groups = append(groups, fmt.Sprintf("%X", int(ip[i])<<8|int(ip[i+1])))
+	}
+
+	return strings.Join(groups, ":")
+}
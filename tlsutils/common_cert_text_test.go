@@ -0,0 +1,70 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertificateText(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	spiffe, err := url.Parse("spiffe://example.com/web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialNumber, _ := new(big.Int).SetString("1234567890abcdef1234567890", 16)
+	notBefore := time.Date(2026, time.March, 5, 8, 30, 0, 0, time.UTC)
+	cert, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "www.example.com", Organization: []string{"Example"}, Country: []string{"FR"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(1, 0, 0),
+		DNSNames:              []string{"www.example.com"},
+		EmailAddresses:        []string{"admin@example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+		URIs:                  []*url.URL{spiffe},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		CRLDistributionPoints: []string{"http://crl.example.com/ca.crl"},
+		OCSPServer:            []string{"http://ocsp.example.com"},
+	}, ca, caKey)
+
+	text, err := certificateTextPEM(certificateToPEM(cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Certificate:\n    Data:\n        Version: 3 (0x2)\n",
+		"        Serial Number:\n            12:34:56:78:90:ab:cd:ef:12:34:56:78:90\n",
+		"        Signature Algorithm: ecdsa-with-SHA256\n        Issuer: CN = Example CA\n",
+		"            Not Before: Mar  5 08:30:00 2026 GMT\n            Not After : Mar  5 08:30:00 2027 GMT\n",
+		"        Subject: C = FR, O = Example, CN = www.example.com\n",
+		"                ASN1 OID: prime256v1\n                NIST CURVE: P-256\n",
+		"            X509v3 Key Usage: critical\n                Digital Signature, Key Encipherment\n",
+		"            X509v3 Extended Key Usage: \n                TLS Web Server Authentication, TLS Web Client Authentication\n",
+		"            X509v3 Basic Constraints: critical\n                CA:FALSE\n",
+		"            X509v3 Authority Key Identifier: \n                " + strings.ToUpper(colonHex(ca.SubjectKeyId)) + "\n",
+		"            Authority Information Access: \n                OCSP - URI:http://ocsp.example.com\n",
+		"                DNS:www.example.com, email:admin@example.com, IP Address:192.0.2.1, IP Address:2001:DB8:0:0:0:0:0:1, URI:spiffe://example.com/web\n",
+		"            X509v3 CRL Distribution Points: \n                Full Name:\n                  URI:http://crl.example.com/ca.crl\n",
+		"    Signature Algorithm: ecdsa-with-SHA256\n    Signature Value:\n        " + colonHex(cert.Signature[:18]) + ":\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected the rendering to contain\n%s\ngot\n%s", want, text)
+		}
+	}
+
+	if text = certificateText(ca); !strings.Contains(text, "X509v3 Basic Constraints: critical\n                CA:TRUE\n") || !strings.Contains(text, "Certificate Sign, CRL Sign\n") {
+		t.Errorf("expected the CA constraints and key usages, got\n%s", text)
+	}
+
+	if _, err = certificateTextPEM("not PEM"); err == nil || !strings.HasPrefix(err.Error(), "unable to parse certificate") {
+		t.Errorf("expected invalid PEM to be refused, got %v", err)
+	}
+}
@@ -1,9 +1,20 @@
 package tlsutils
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"slices"
+	"strings"
+	"time"
 )
 
 func parsePEMCertificate(data []byte) (*x509.Certificate, error) {
@@ -28,3 +39,683 @@ func parsePEMCertificate(data []byte) (*x509.Certificate, error) {
 
 	return cert, nil
 }
+
+func parsePEMCertificateRequest(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse certificate request")
+	}
+
+	preamble, err := pemBlockToPEMPreamble(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify PEM preamble: %w", err)
+	}
+
+	if preamble != PreambleCertificateRequest {
+		return nil, fmt.Errorf("certificate request PEM should be %q, got %q", PreambleCertificateRequest, preamble)
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate request: %w", err)
+	}
+
+	return csr, nil
+}
+
+// parsePEMCertificates parses every CERTIFICATE block found in data, in order.
+// Blocks of any other type are ignored.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	for scanner := newPEMBlockScanner(bytes.NewReader(data)); scanner.Scan(); {
+		block := scanner.Block()
+		if block.Type != PreambleCertificate.String() {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate #%d: %w", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// lenientCertificate is a certificate read by parseCertificatesLenient: cert is nil when crypto/x509 rejected der with err.
+type lenientCertificate struct {
+	der  []byte
+	cert *x509.Certificate
+	err  error
+}
+
+// parseCertificatesLenient parses the CERTIFICATE blocks of PEM data, or data as one DER certificate, keeping the
+// certificates crypto/x509 rejects, like the ones with duplicate extensions.
+func parseCertificatesLenient(data []byte) []lenientCertificate {
+	ders := make([][]byte, 0)
+	if block, _ := pem.Decode(data); block == nil {
+		ders = append(ders, data)
+	}
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == PreambleCertificate.String() {
+			ders = append(ders, block.Bytes)
+		}
+	}
+
+	certs := make([]lenientCertificate, 0, len(ders))
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		certs = append(certs, lenientCertificate{der: der, cert: cert, err: err})
+	}
+
+	return certs
+}
+
+// certificateToPEM encodes the given certificate in PEM format.
+func certificateToPEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: cert.Raw}))
+}
+
+// isSelfSigned returns true if the certificate is signed by its own key.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// certificateDetailsSchema describes a decoded certificate, filled by certificateDetails. Every extension is listed
+// in extensions, and the common ones are decoded as well.
+func certificateDetailsSchema() *schema.Resource {
+	computedStrings := func() *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"subject": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"issuer": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subject_name": distinguishedNameSchema(),
+			"issuer_name":  distinguishedNameSchema(),
+			"serial_number": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"is_ca": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"max_path_len": {
+				Description: "maximum number of intermediate CAs below a CA certificate, -1 when not constrained.",
+				Type:        schema.TypeInt,
+				Computed:    true,
+			},
+			"key_usages":               computedStrings(),
+			"ext_key_usages":           computedStrings(),
+			"dns_names":                computedStrings(),
+			"ip_addresses":             computedStrings(),
+			"uris":                     computedStrings(),
+			"email_addresses":          computedStrings(),
+			"ocsp_servers":             computedStrings(),
+			"issuing_certificate_urls": computedStrings(),
+			"crl_distribution_points":  computedStrings(),
+			"extensions": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"oid": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"critical": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"value_base64": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// certificateDetails returns the attributes of certificateDetailsSchema for cert. Usages have the allowed_uses names,
+// unknown extended key usages their OID.
+func certificateDetails(cert *x509.Certificate) map[string]interface{} {
+	maxPathLen := -1
+	if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+		maxPathLen = cert.MaxPathLen
+	}
+
+	keyUsageNames := make([]string, 0)
+	for _, name := range supportedAllowedUsesStr() {
+		if usage, ok := keyUsages[name]; ok && cert.KeyUsage&usage != 0 {
+			keyUsageNames = append(keyUsageNames, name)
+		}
+	}
+	extKeyUsageNames := make([]string, 0, len(cert.ExtKeyUsage)+len(cert.UnknownExtKeyUsage))
+	for _, usage := range cert.ExtKeyUsage {
+		for name, known := range extKeyUsages {
+			if known == usage {
+				extKeyUsageNames = append(extKeyUsageNames, name)
+			}
+		}
+	}
+	for _, usage := range cert.UnknownExtKeyUsage {
+		extKeyUsageNames = append(extKeyUsageNames, usage.String())
+	}
+
+	ipAddresses := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	extensions := make([]interface{}, 0, len(cert.Extensions))
+	for _, extension := range cert.Extensions {
+		extensions = append(extensions, map[string]interface{}{
+			"oid":          extension.Id.String(),
+			"critical":     extension.Critical,
+			"value_base64": base64.StdEncoding.EncodeToString(extension.Value),
+		})
+	}
+
+	return map[string]interface{}{
+		"subject":                  cert.Subject.String(),
+		"issuer":                   cert.Issuer.String(),
+		"subject_name":             distinguishedName(cert.Subject),
+		"issuer_name":              distinguishedName(cert.Issuer),
+		"serial_number":            cert.SerialNumber.Text(16),
+		"is_ca":                    cert.IsCA,
+		"max_path_len":             maxPathLen,
+		"key_usages":               keyUsageNames,
+		"ext_key_usages":           extKeyUsageNames,
+		"dns_names":                cert.DNSNames,
+		"ip_addresses":             ipAddresses,
+		"uris":                     uris,
+		"email_addresses":          cert.EmailAddresses,
+		"ocsp_servers":             cert.OCSPServer,
+		"issuing_certificate_urls": cert.IssuingCertificateURL,
+		"crl_distribution_points":  cert.CRLDistributionPoints,
+		"extensions":               extensions,
+	}
+}
+
+// distinguishedNameAttributeOIDs are the attributes of a distinguished name with their own distinguishedNameSchema
+// attribute, the others are in extra_rdns.
+var distinguishedNameAttributeOIDs = []asn1.ObjectIdentifier{
+	{2, 5, 4, 3},  // commonName
+	{2, 5, 4, 5},  // serialNumber
+	{2, 5, 4, 6},  // countryName
+	{2, 5, 4, 7},  // localityName
+	{2, 5, 4, 8},  // stateOrProvinceName
+	{2, 5, 4, 9},  // streetAddress
+	{2, 5, 4, 10}, // organizationName
+	{2, 5, 4, 11}, // organizationalUnitName
+	{2, 5, 4, 17}, // postalCode
+}
+
+// distinguishedNameSchema describes a distinguished name filled by distinguishedName, so that configurations do not
+// parse the RFC 2253 strings.
+func distinguishedNameSchema() *schema.Schema {
+	computedStrings := func() *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"common_name": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"serial_number": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"organization":        computedStrings(),
+				"organizational_unit": computedStrings(),
+				"country":             computedStrings(),
+				"province":            computedStrings(),
+				"locality":            computedStrings(),
+				"street_address":      computedStrings(),
+				"postal_code":         computedStrings(),
+				"extra_rdns": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"oid": {
+								Type:     schema.TypeString,
+								Computed: true,
+							},
+							"value": {
+								Type:     schema.TypeString,
+								Computed: true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// distinguishedName returns the distinguishedNameSchema attributes of name. The attributes without their own
+// attribute, like emailAddress, are listed in extra_rdns.
+func distinguishedName(name pkix.Name) []interface{} {
+	extraRDNs := make([]interface{}, 0)
+	for _, attribute := range name.Names {
+		if !slices.ContainsFunc(distinguishedNameAttributeOIDs, attribute.Type.Equal) {
+			extraRDNs = append(extraRDNs, map[string]interface{}{"oid": attribute.Type.String(), "value": fmt.Sprint(attribute.Value)})
+		}
+	}
+
+	return []interface{}{map[string]interface{}{
+		"common_name":         name.CommonName,
+		"serial_number":       name.SerialNumber,
+		"organization":        name.Organization,
+		"organizational_unit": name.OrganizationalUnit,
+		"country":             name.Country,
+		"province":            name.Province,
+		"locality":            name.Locality,
+		"street_address":      name.StreetAddress,
+		"postal_code":         name.PostalCode,
+		"extra_rdns":          extraRDNs,
+	}}
+}
+
+func parsePEMRevocationList(data []byte) (*x509.RevocationList, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse revocation list")
+	}
+
+	preamble, err := pemBlockToPEMPreamble(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify PEM preamble: %w", err)
+	}
+
+	if preamble != PreambleCRL {
+		return nil, fmt.Errorf("revocation list PEM should be %q, got %q", PreambleCRL, preamble)
+	}
+
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse revocation list: %w", err)
+	}
+
+	return crl, nil
+}
+
+// chainAttributes are the attributes of chainSchema. Changing them rebuilds the full chains in-place.
+var chainAttributes = []string{"chain_include_root", "chain_exclude_expired", "chain_max_depth"}
+
+// chainSchema returns the attributes pruning the CA certificates of the full chain outputs, read by chainOptionsFromResourceData.
+func chainSchema(outputs string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"chain_include_root": {
+			Description: fmt.Sprintf("keep self-signed roots in %s. They are left out by default: clients already trust them and sending them in the TLS handshake wastes bytes.", outputs),
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"chain_exclude_expired": {
+			Description: fmt.Sprintf("leave out of %s the CA certificates expired when the chain is built, like cross-signed intermediates past their end date.", outputs),
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"chain_max_depth": {
+			Description:      fmt.Sprintf("maximum number of CA certificates following the leaf in %s, 0 for no limit.", outputs),
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		},
+	}
+}
+
+// chainOptions are the pruning rules of fullChainPEM.
+type chainOptions struct {
+	includeRoot    bool
+	excludeExpired bool
+	maxDepth       int
+	at             time.Time
+}
+
+// chainOptionsFromResourceData reads the attributes of chainSchema, expiry being evaluated at now(d, m).
+func chainOptionsFromResourceData(d resourceAttributes, m interface{}) chainOptions {
+	return chainOptions{
+		includeRoot:    d.Get("chain_include_root").(bool),
+		excludeExpired: d.Get("chain_exclude_expired").(bool),
+		maxDepth:       d.Get("chain_max_depth").(int),
+		at:             now(d, m),
+	}
+}
+
+// fullChainPEM concatenates the certificate with the certificates of the chain, in order,
+// leaving out self-signed roots unless options.includeRoot: clients already trust them and servers should not send them.
+func fullChainPEM(options chainOptions, certPem string, chainPems ...string) (string, error) {
+	fullChain := &strings.Builder{}
+	depth := 0
+	for _, p := range append([]string{certPem}, chainPems...) {
+		certs, err := parsePEMCertificates([]byte(p))
+		if err != nil {
+			return "", err
+		}
+		for _, cert := range certs {
+			if fullChain.Len() > 0 {
+				if !options.includeRoot && isSelfSigned(cert) {
+					continue
+				}
+				if options.excludeExpired && options.at.After(cert.NotAfter) {
+					continue
+				}
+				if options.maxDepth > 0 && depth == options.maxDepth {
+					return fullChain.String(), nil
+				}
+				depth++
+			}
+			fullChain.WriteString(certificateToPEM(cert))
+		}
+	}
+
+	return fullChain.String(), nil
+}
+
+// planFullChains plans the given full chain outputs as unknown when the chainSchema attributes change.
+func planFullChains(diff *schema.ResourceDiff, outputs ...string) error {
+	if diff.Id() == "" || !slices.ContainsFunc(chainAttributes, diff.HasChange) {
+		return nil
+	}
+
+	for _, output := range outputs {
+		if err := diff.SetNewComputed(output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateFullChain rebuilds fullchain_pem from certificate_pem and the given chain when the chainSchema attributes changed.
+func updateFullChain(d *schema.ResourceData, m interface{}, chainPems ...string) error {
+	if !d.HasChanges(chainAttributes...) {
+		return nil
+	}
+
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), d.Get("certificate_pem").(string), chainPems...)
+	if err != nil {
+		return fmt.Errorf("failed to build full chain: %w", err)
+	}
+	if err = d.Set("fullchain_pem", fullChainPem); err != nil {
+		return fmt.Errorf("failed to save fullchain_pem: %w", err)
+	}
+
+	return nil
+}
+
+// listOfStrings converts a TypeList of TypeString attribute, e.g. ca_chain_pem, to a slice.
+func listOfStrings(list []interface{}) []string {
+	strs := make([]string, 0, len(list))
+	for _, str := range list {
+		strs = append(strs, str.(string))
+	}
+
+	return strs
+}
+
+// oidPKCS7SignedData is the content type of PKCS#7 / CMS SignedData, from RFC 5652 section 5.1.
+var oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// parsePKCS7Certificates returns the certificates of a DER PKCS#7 SignedData, as published in .p7c files.
+func parsePKCS7Certificates(der []byte) ([]*x509.Certificate, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("unable to parse PKCS#7 content info: %w", err)
+	}
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, fmt.Errorf("PKCS#7 content type %s is not signed data", contentInfo.ContentType)
+	}
+
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("unable to parse PKCS#7 signed data: %w", err)
+	}
+
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse PKCS#7 certificates: %w", err)
+	}
+
+	return certs, nil
+}
+
+// certificateSummarySchema describes the elements of certificate lists returned by data sources, filled by certificateSummary.
+func certificateSummarySchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"cert_pem": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cert_text": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cert_json": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subject": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"issuer": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subject_name": distinguishedNameSchema(),
+			"issuer_name":  distinguishedNameSchema(),
+			"serial_number": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"not_before": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"not_after": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"not_before_unix": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"not_after_unix": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"remaining_seconds": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"is_ca": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"sha256_fingerprint": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"public_key_algorithm": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"public_key_parameters": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"signature_algorithm": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"parse_error": {
+				Description: "reason crypto/x509 rejected the certificate, when the data source keeps nonconforming certificates.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// certificateSummary returns the attributes of certificateSummarySchema for cert, with its remaining validity at the given time.
+func certificateSummary(cert *x509.Certificate, at time.Time) map[string]interface{} {
+	// crypto/x509 parsed the SubjectPublicKeyInfo already
+	algorithm, _ := describePublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+
+	summary := map[string]interface{}{
+		"cert_pem":              certificateToPEM(cert),
+		"cert_text":             certificateText(cert),
+		"cert_json":             certificateJSON(cert),
+		"subject":               cert.Subject.String(),
+		"issuer":                cert.Issuer.String(),
+		"subject_name":          distinguishedName(cert.Subject),
+		"issuer_name":           distinguishedName(cert.Issuer),
+		"serial_number":         cert.SerialNumber.Text(16),
+		"is_ca":                 cert.IsCA,
+		"sha256_fingerprint":    sha256Fingerprint(cert),
+		"public_key_algorithm":  algorithm.Name,
+		"public_key_parameters": algorithm.Parameters,
+		"signature_algorithm":   certificateSignatureAlgorithm(cert),
+	}
+	for key, value := range certificateValidity("", cert, at) {
+		summary[key] = value
+	}
+
+	return summary
+}
+
+// certificateValiditySchema returns the attributes describing the validity of a certificate, named after prefix
+// and filled by certificateValidity.
+func certificateValiditySchema(prefix, certificate string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		prefix + "not_before": {
+			Description: fmt.Sprintf("time from which %s is valid, in RFC3339.", certificate),
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		prefix + "not_after": {
+			Description: fmt.Sprintf("time until which %s is valid, in RFC3339.", certificate),
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		prefix + "not_before_unix": {
+			Description: fmt.Sprintf("`%snot_before` as a Unix timestamp in seconds.", prefix),
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+		prefix + "not_after_unix": {
+			Description: fmt.Sprintf("`%snot_after` as a Unix timestamp in seconds.", prefix),
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+		prefix + "remaining_seconds": {
+			Description: fmt.Sprintf("seconds left until `%snot_after` when last read, negative once %s expired.", prefix, certificate),
+			Type:        schema.TypeInt,
+			Computed:    true,
+		},
+	}
+}
+
+// certificateValidity returns the attributes of certificateValiditySchema for cert, with its remaining validity at the given time.
+func certificateValidity(prefix string, cert *x509.Certificate, at time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		prefix + "not_before":        cert.NotBefore.UTC().Format(time.RFC3339),
+		prefix + "not_after":         cert.NotAfter.UTC().Format(time.RFC3339),
+		prefix + "not_before_unix":   int(cert.NotBefore.Unix()),
+		prefix + "not_after_unix":    int(cert.NotAfter.Unix()),
+		prefix + "remaining_seconds": int(cert.NotAfter.Sub(at) / time.Second),
+	}
+}
+
+// setCertificateValidity saves the certificateValiditySchema attributes named after prefix of the certificate in PEM format.
+func setCertificateValidity(d *schema.ResourceData, m interface{}, prefix, certPem string) error {
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return fmt.Errorf("unable to parse %scertificate: %w", prefix, err)
+	}
+
+	for key, value := range certificateValidity(prefix, cert, now(d, m)) {
+		if err = d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// rejectedCertificateSummary returns the certificateSummarySchema attributes known of a certificate crypto/x509 rejected
+// with err: its PEM encoding, fingerprint and parse_error.
+func rejectedCertificateSummary(der []byte, err error) map[string]interface{} {
+	sum := sha256.Sum256(der)
+
+	return map[string]interface{}{
+		"cert_pem":           string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: der})),
+		"sha256_fingerprint": hex.EncodeToString(sum[:]),
+		"parse_error":        err.Error(),
+	}
+}
+
+// earlyRenewalDue reports whether the certificate_pem of the prior state is within early_renewal_hours of its expiry,
+// for the resources renewing the certificates signed by a remote CA in-place. The plan of a renewal leaves
+// certificate_pem unknown, Update decides like CustomizeDiff from the prior state.
+func earlyRenewalDue(d resourceChanges, m interface{}) (bool, error) {
+	earlyRenewalHours := intOrProviderDefault(d, "early_renewal_hours", m, func(meta *providerMeta) int {
+		return meta.defaultEarlyRenewalHours
+	})
+	if earlyRenewalHours == 0 {
+		return false, nil
+	}
+
+	certPem, _ := d.GetChange("certificate_pem")
+	cert, err := parsePEMCertificate([]byte(certPem.(string)))
+	if err != nil {
+		return false, fmt.Errorf("unable to parse certificate_pem: %w", err)
+	}
+
+	return !now(d, m).Add(time.Duration(earlyRenewalHours) * time.Hour).Before(cert.NotAfter), nil
+}
@@ -0,0 +1,56 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestFullChainPEM(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	expired, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Expired cross-signed CA"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root, rootKey)
+	leaf, _ := testCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "www.example.com"}}, intermediate, intermediateKey)
+	leafPem, intermediatePem, expiredPem, rootPem := certificateToPEM(leaf), certificateToPEM(intermediate), certificateToPEM(expired), certificateToPEM(root)
+
+	for name, test := range map[string]struct {
+		options chainOptions
+		want    string
+	}{
+		"root left out by default": {
+			want: leafPem + intermediatePem + expiredPem,
+		},
+		"root included": {
+			options: chainOptions{includeRoot: true},
+			want:    leafPem + intermediatePem + expiredPem + rootPem,
+		},
+		"expired excluded": {
+			options: chainOptions{includeRoot: true, excludeExpired: true, at: time.Now()},
+			want:    leafPem + intermediatePem + rootPem,
+		},
+		"max depth": {
+			options: chainOptions{includeRoot: true, excludeExpired: true, maxDepth: 1, at: time.Now()},
+			want:    leafPem + intermediatePem,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := fullChainPEM(test.options, leafPem, intermediatePem+expiredPem, rootPem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("expected\n%s\ngot\n%s", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+)
+
+// COSE_Key labels and values, as registered in the
+// [IANA COSE registries](https://www.iana.org/assignments/cose/cose.xhtml).
+const (
+	coseKeyLabelKty = 1
+	coseKeyLabelKid = 2
+	coseKeyLabelAlg = 3
+	coseKeyLabelCrv = -1
+	coseKeyLabelX   = -2
+	coseKeyLabelY   = -3
+	coseKeyLabelD   = -4
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+)
+
+// coseAlgorithms maps the JWS "alg" values with a COSE equivalent to their COSE algorithm identifier.
+var coseAlgorithms = map[string]int64{
+	"ES256": -7,
+	"ES384": -35,
+	"ES512": -36,
+	"EdDSA": -8,
+}
+
+// coseCurves maps curve names to their COSE elliptic curve identifier.
+var coseCurves = map[string]int64{
+	"P-256":   1,
+	"P-384":   2,
+	"P-521":   3,
+	"Ed25519": 6,
+}
+
+// supportsCOSEKey returns true if the crypto.PrivateKey can be represented as a COSE_Key by this provider.
+func supportsCOSEKey(prvKey crypto.PrivateKey) bool {
+	switch prvKey.(type) {
+	case *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// privateKeyToCOSEKey encodes the given EC or OKP crypto.PrivateKey as a CBOR COSE_Key,
+// as defined in [RFC 9052](https://datatracker.ietf.org/doc/html/rfc9052#section-7).
+// The private key parameter is only included when includePrivate is true.
+func privateKeyToCOSEKey(prvKey crypto.PrivateKey, alg string, kid string, includePrivate bool) ([]byte, error) {
+	coseKey := map[int64]interface{}{}
+
+	if kid != "" {
+		coseKey[coseKeyLabelKid] = []byte(kid)
+	}
+	if coseAlg, ok := coseAlgorithms[alg]; ok {
+		coseKey[coseKeyLabelAlg] = coseAlg
+	}
+
+	switch k := prvKey.(type) {
+	case *ecdsa.PrivateKey:
+		crv, ok := coseCurves[k.Curve.Params().Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported COSE elliptic curve: %s", k.Curve.Params().Name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		coseKey[coseKeyLabelKty] = int64(coseKtyEC2)
+		coseKey[coseKeyLabelCrv] = crv
+		coseKey[coseKeyLabelX] = k.X.FillBytes(make([]byte, size))
+		coseKey[coseKeyLabelY] = k.Y.FillBytes(make([]byte, size))
+		if includePrivate {
+			coseKey[coseKeyLabelD] = k.D.FillBytes(make([]byte, size))
+		}
+	case ed25519.PrivateKey:
+		coseKey[coseKeyLabelKty] = int64(coseKtyOKP)
+		coseKey[coseKeyLabelCrv] = coseCurves["Ed25519"]
+		coseKey[coseKeyLabelX] = []byte(k.Public().(ed25519.PublicKey))
+		if includePrivate {
+			coseKey[coseKeyLabelD] = k.Seed()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported COSE private key type: %T", prvKey)
+	}
+
+	return cborEncodeIntMap(coseKey)
+}
+
+// cborEncodeIntMap encodes a map with integer labels using the deterministic CBOR encoding
+// of [RFC 8949](https://datatracker.ietf.org/doc/html/rfc8949#section-4.2.1).
+// Only int64 and []byte values are supported, which is all a COSE_Key requires.
+func cborEncodeIntMap(m map[int64]interface{}) ([]byte, error) {
+	// Deterministic encoding sorts keys by their encoded form:
+	// unsigned integers (ascending) come before negative integers (descending).
+	labels := make([]int64, 0, len(m))
+	for label := range m {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if (a >= 0) != (b >= 0) {
+			return a >= 0
+		}
+		if a >= 0 {
+			return a < b
+		}
+		return a > b
+	})
+
+	out := cborEncodeHead(5, uint64(len(m)))
+	for _, label := range labels {
+		out = append(out, cborEncodeInt(label)...)
+		switch v := m[label].(type) {
+		case int64:
+			out = append(out, cborEncodeInt(v)...)
+		case []byte:
+			out = append(out, cborEncodeHead(2, uint64(len(v)))...)
+			out = append(out, v...)
+		default:
+			return nil, fmt.Errorf("unsupported CBOR value type: %T", v)
+		}
+	}
+
+	return out, nil
+}
+
+// cborEncodeInt encodes an integer as a CBOR major type 0 (unsigned) or 1 (negative).
+func cborEncodeInt(v int64) []byte {
+	if v >= 0 {
+		return cborEncodeHead(0, uint64(v))
+	}
+	return cborEncodeHead(1, uint64(-1-v))
+}
+
+// cborEncodeHead encodes the initial bytes of a CBOR data item, using the shortest argument form.
+func cborEncodeHead(majorType byte, arg uint64) []byte {
+	mt := majorType << 5
+	switch {
+	case arg < 24:
+		return []byte{mt | byte(arg)}
+	case arg <= 0xff:
+		return []byte{mt | 24, byte(arg)}
+	case arg <= 0xffff:
+		return []byte{mt | 25, byte(arg >> 8), byte(arg)}
+	case arg <= 0xffffffff:
+		return []byte{mt | 26, byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)}
+	default:
+		return []byte{mt | 27, byte(arg >> 56), byte(arg >> 48), byte(arg >> 40), byte(arg >> 32), byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)}
+	}
+}
@@ -0,0 +1,93 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPrivateKeyToCOSEKey(t *testing.T) {
+	// RFC 8037 appendix A.1
+	seed, _ := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	x, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	prvKey := ed25519.NewKeyFromSeed(seed)
+
+	// {1: 1, 2: h'6b', 3: -8, -1: 6, -2: x}
+	expectedPublic, _ := hex.DecodeString("a5010102416b03272006215820" + hex.EncodeToString(x))
+	public, err := privateKeyToCOSEKey(prvKey, "EdDSA", "k", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(public, expectedPublic) {
+		t.Errorf("expected public COSE_Key %x, got %x", expectedPublic, public)
+	}
+
+	// {..., -4: d}
+	expectedPrivate := append([]byte{0xa6}, expectedPublic[1:]...)
+	expectedPrivate = append(append(expectedPrivate, 0x23, 0x58, 0x20), seed...)
+	private, err := privateKeyToCOSEKey(prvKey, "EdDSA", "k", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(private, expectedPrivate) {
+		t.Errorf("expected private COSE_Key %x, got %x", expectedPrivate, private)
+	}
+}
+
+func TestPrivateKeyToCOSEKeyEC(t *testing.T) {
+	prvKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := privateKeyToCOSEKey(prvKey, "ES384", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// {1: 2, 3: -35, -1: 2, -2: x, -3: y}
+	expected := []byte{0xa5, 0x01, 0x02, 0x03, 0x38, 0x22, 0x20, 0x02, 0x21, 0x58, 0x30}
+	expected = append(expected, prvKey.X.FillBytes(make([]byte, 48))...)
+	expected = append(expected, 0x22, 0x58, 0x30)
+	expected = append(expected, prvKey.Y.FillBytes(make([]byte, 48))...)
+	if !bytes.Equal(public, expected) {
+		t.Errorf("expected public COSE_Key %x, got %x", expected, public)
+	}
+}
+
+func TestResourceJWTSigningKeyCOSE(t *testing.T) {
+	testCases := map[string]struct {
+		algorithm string
+		expected  bool
+	}{
+		"es256": {algorithm: "ES256", expected: true},
+		"eddsa": {algorithm: "EdDSA", expected: true},
+		"rs256": {algorithm: "RS256", expected: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			state := testResourceApply(t, resourceJWTSigningKey(), nil, map[string]interface{}{"algorithm": tc.algorithm}, nil)
+			for _, attr := range []string{"private_key_cose", "public_key_cose"} {
+				if set := state.Attributes[attr] != ""; set != tc.expected {
+					t.Errorf("expected %s to be set: %t, got %q", attr, tc.expected, state.Attributes[attr])
+				}
+			}
+			if !tc.expected {
+				return
+			}
+			public, err := base64.StdEncoding.DecodeString(state.Attributes["public_key_cose"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			kid := state.Attributes["kid"]
+			if !bytes.Contains(public, append([]byte{0x02, 0x58, byte(len(kid))}, kid...)) {
+				t.Errorf("expected public_key_cose to hold the kid %s, got %x", kid, public)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// oidExtensionFreshestCRL is the freshest CRL extension pointing at delta CRLs, see RFC 5280 section 4.2.1.15.
+var oidExtensionFreshestCRL = asn1.ObjectIdentifier{2, 5, 29, 46}
+
+// distributionPoint is a DistributionPoint of RFC 5280 section 4.2.1.13.
+type distributionPoint struct {
+	DistributionPoint struct {
+		FullName     []asn1.RawValue  `asn1:"optional,tag:0"`
+		RelativeName pkix.RDNSequence `asn1:"optional,tag:1"`
+	} `asn1:"optional,tag:0"`
+	Reasons   asn1.BitString `asn1:"optional,tag:1"`
+	CRLIssuer asn1.RawValue  `asn1:"optional,tag:2"`
+}
+
+// crlDistributionSchema returns the ForceNew attributes setting the CRL locations of issued certificates,
+// filled by crlDistributionExtensions.
+func crlDistributionSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"crl_distribution_points": {
+			Description: "URLs of the CRLs of the issuer, e.g. the published `crl_pem` of a `tlsutils_x509_crl`, as a CRL distribution points extension.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.IsURLWithScheme([]string{"http", "https", "ldap"}),
+			},
+		},
+		"freshest_crl_urls": {
+			Description: "URLs of the delta CRLs of the issuer, e.g. of a `tlsutils_x509_crl` with `delta_crl_base_number`, as a freshest CRL extension. Clients combine them with the CRLs of `crl_distribution_points`.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.IsURLWithScheme([]string{"http", "https", "ldap"}),
+			},
+		},
+	}
+}
+
+// crlDistributionExtensions sets the CRL locations of the crlDistributionSchema attributes on template.
+func crlDistributionExtensions(d resourceAttributes, template *x509.Certificate) error {
+	template.CRLDistributionPoints = listOfStrings(d.Get("crl_distribution_points").([]interface{}))
+
+	freshestCRLURLs := listOfStrings(d.Get("freshest_crl_urls").([]interface{}))
+	if len(freshestCRLURLs) == 0 {
+		return nil
+	}
+	value, err := marshalDistributionPoints(freshestCRLURLs)
+	if err != nil {
+		return fmt.Errorf("failed to encode freshest CRL: %w", err)
+	}
+	// RFC 5280 requires the extension to be non-critical
+	template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: oidExtensionFreshestCRL, Value: value})
+
+	return nil
+}
+
+// marshalDistributionPoints encodes CRLDistributionPoints with a distribution point per URL, like crypto/x509.
+func marshalDistributionPoints(urls []string) ([]byte, error) {
+	points := make([]distributionPoint, 0, len(urls))
+	for _, url := range urls {
+		var point distributionPoint
+		// uniformResourceIdentifier GeneralName
+		point.DistributionPoint.FullName = []asn1.RawValue{{Tag: 6, Class: asn1.ClassContextSpecific, Bytes: []byte(url)}}
+		points = append(points, point)
+	}
+
+	return asn1.Marshal(points)
+}
+
+// distributionPointURLs returns the URLs of the full names of DER CRLDistributionPoints, as used by the freshest CRL
+// extension.
+func distributionPointURLs(der []byte) ([]string, error) {
+	var points []distributionPoint
+	if rest, err := asn1.Unmarshal(der, &points); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after distribution points")
+	}
+
+	urls := make([]string, 0, len(points))
+	for _, point := range points {
+		for _, name := range point.DistributionPoint.FullName {
+			if name.Class == asn1.ClassContextSpecific && name.Tag == 6 {
+				urls = append(urls, string(name.Bytes))
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+// freshestCRLURLs returns the URLs of the freshest CRL extension of cert, empty when it has none.
+func freshestCRLURLs(cert *x509.Certificate) []string {
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(oidExtensionFreshestCRL) {
+			if urls, err := distributionPointURLs(extension.Value); err == nil {
+				return urls
+			}
+		}
+	}
+
+	return []string{}
+}
@@ -0,0 +1,74 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCRLDistributionExtensions(t *testing.T) {
+	for name, test := range map[string]struct {
+		crlDistributionPoints []interface{}
+		freshestCRLURLs       []interface{}
+	}{
+		"none":                {},
+		"distribution points": {crlDistributionPoints: []interface{}{"http://crl.example.com/ca.crl", "ldap://ldap.example.com/cn=CA"}},
+		"with delta CRLs":     {crlDistributionPoints: []interface{}{"http://crl.example.com/ca.crl"}, freshestCRLURLs: []interface{}{"http://crl.example.com/delta.crl"}},
+		"several delta CRLs":  {crlDistributionPoints: []interface{}{"http://crl.example.com/ca.crl"}, freshestCRLURLs: []interface{}{"http://crl.example.com/delta.crl", "https://crl.example.net/delta.crl"}},
+		"delta CRLs only":     {freshestCRLURLs: []interface{}{"http://crl.example.com/delta.crl"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, crlDistributionSchema(), map[string]interface{}{
+				"crl_distribution_points": test.crlDistributionPoints,
+				"freshest_crl_urls":       test.freshestCRLURLs,
+			})
+			ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+			template := &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}
+			if err := crlDistributionExtensions(d, template); err != nil {
+				t.Fatal(err)
+			}
+			cert, _ := testCertificate(t, template, ca, caKey)
+
+			if got, want := nonNilStrings(cert.CRLDistributionPoints), listOfStrings(test.crlDistributionPoints); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected CRL distribution points %v, got %v", want, got)
+			}
+			if got, want := freshestCRLURLs(cert), listOfStrings(test.freshestCRLURLs); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected freshest CRL %v, got %v", want, got)
+			}
+			for _, extension := range cert.Extensions {
+				if extension.Id.Equal(oidExtensionFreshestCRL) && extension.Critical {
+					t.Errorf("expected a non-critical freshest CRL extension")
+				}
+			}
+
+			text := certificateText(cert)
+			if got := strings.Contains(text, "X509v3 Freshest CRL: \n"); got != (len(test.freshestCRLURLs) > 0) {
+				t.Errorf("expected the freshest CRL extension in the text %t, got\n%s", !got, text)
+			}
+			for _, url := range test.freshestCRLURLs {
+				if !strings.Contains(text, "                Full Name:\n                  URI:"+url.(string)+"\n") {
+					t.Errorf("expected the text to list the freshest CRL %s, got\n%s", url, text)
+				}
+			}
+		})
+	}
+}
+
+func TestDistributionPointURLs(t *testing.T) {
+	der, err := marshalDistributionPoints([]string{"http://crl.example.com/delta.crl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls, err := distributionPointURLs(der); err != nil || !reflect.DeepEqual(urls, []string{"http://crl.example.com/delta.crl"}) {
+		t.Errorf("expected the encoded URL, got %v: %v", urls, err)
+	}
+	if _, err = distributionPointURLs(append(der, 0)); err == nil {
+		t.Errorf("expected an error for trailing data")
+	}
+	if _, err = distributionPointURLs([]byte{0x30, 0x03}); err == nil {
+		t.Errorf("expected an error for invalid distribution points")
+	}
+}
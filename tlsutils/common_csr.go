@@ -0,0 +1,129 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+var (
+	// oidChallengePassword is the PKCS#9 challengePassword attribute, RFC 2985 section 5.4.1.
+	oidChallengePassword = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+	// oidUnstructuredName is the PKCS#9 unstructuredName attribute, RFC 2985 section 5.4.2.
+	oidUnstructuredName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 2}
+)
+
+// certificateRequestAttributesSchema returns the PKCS#9 attributes of the data sources creating certificate requests,
+// read back by certificateRequestAttributes.
+func certificateRequestAttributesSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"challenge_password": {
+			Description: "PKCS#9 challengePassword attribute of the request, the one-time password of SCEP and of the enrollment of routers. It is readable by anyone holding `cert_request_pem`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
+		"unstructured_name": {
+			Description: "PKCS#9 unstructuredName attribute of the request, like the FQDN of a router.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+	}
+}
+
+// certificateRequestAttribute is a single valued PKCS#9 attribute of a certificate request, encoded as a
+// PrintableString, or a UTF8String when the value is not printable.
+type certificateRequestAttribute struct {
+	oid   asn1.ObjectIdentifier
+	value string
+}
+
+// certificateRequestAttributes returns the attributes of certificateRequestAttributesSchema which are set.
+func certificateRequestAttributes(d *schema.ResourceData) []certificateRequestAttribute {
+	attributes := make([]certificateRequestAttribute, 0, 2)
+	if password := d.Get("challenge_password").(string); password != "" {
+		attributes = append(attributes, certificateRequestAttribute{oid: oidChallengePassword, value: password})
+	}
+	if name := d.Get("unstructured_name").(string); name != "" {
+		attributes = append(attributes, certificateRequestAttribute{oid: oidUnstructuredName, value: name})
+	}
+	return attributes
+}
+
+// certificateRequestHashes are the hashes of the signature algorithms crypto/x509 picks for certificate requests.
+var certificateRequestHashes = map[x509.SignatureAlgorithm]crypto.Hash{
+	x509.SHA256WithRSA:   crypto.SHA256,
+	x509.SHA384WithRSA:   crypto.SHA384,
+	x509.SHA512WithRSA:   crypto.SHA512,
+	x509.ECDSAWithSHA256: crypto.SHA256,
+	x509.ECDSAWithSHA384: crypto.SHA384,
+	x509.ECDSAWithSHA512: crypto.SHA512,
+	x509.PureEd25519:     crypto.Hash(0),
+}
+
+// certificateRequestWithAttributes adds attributes to the certificate request der and signs it again with prvKey.
+// crypto/x509 only writes attributes holding sets of names, not the strings of PKCS#9.
+func certificateRequestWithAttributes(der []byte, attributes []certificateRequestAttribute, prvKey crypto.PrivateKey) ([]byte, error) {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	hash, ok := certificateRequestHashes[csr.SignatureAlgorithm]
+	signer, isSigner := prvKey.(crypto.Signer)
+	if !ok || !isSigner {
+		return nil, fmt.Errorf("unsupported signature algorithm %s", csr.SignatureAlgorithm)
+	}
+
+	var request struct {
+		TBS                asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(der, &request); err != nil {
+		return nil, err
+	}
+	var tbs struct {
+		Version       int
+		Subject       asn1.RawValue
+		PublicKey     asn1.RawValue
+		RawAttributes []asn1.RawValue `asn1:"tag:0"`
+	}
+	if _, err = asn1.Unmarshal(csr.RawTBSCertificateRequest, &tbs); err != nil {
+		return nil, err
+	}
+	for _, attribute := range attributes {
+		value, err := asn1.Marshal(attribute.value)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}{Type: attribute.oid, Values: []asn1.RawValue{{FullBytes: value}}})
+		if err != nil {
+			return nil, err
+		}
+		tbs.RawAttributes = append(tbs.RawAttributes, asn1.RawValue{FullBytes: encoded})
+	}
+	if request.TBS.FullBytes, err = asn1.Marshal(tbs); err != nil {
+		return nil, err
+	}
+
+	signed := request.TBS.FullBytes
+	if hash != 0 {
+		h := hash.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
+	signature, err := signer.Sign(rand.Reader, signed, hash)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)}
+
+	return asn1.Marshal(request)
+}
@@ -0,0 +1,79 @@
+package tlsutils
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+)
+
+func TestCertificateRequestWithAttributes(t *testing.T) {
+	for _, algorithm := range []Algorithm{RSA, ECDSA, ED25519} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			prvKey, err := generatePrivateKey(algorithm, 2048, P384)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+				Subject:  pkix.Name{CommonName: "router"},
+				DNSNames: []string{"router.example.com"},
+			}, prvKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			der, err = certificateRequestWithAttributes(der, []certificateRequestAttribute{
+				{oid: oidChallengePassword, value: "one-time secret"},
+				{oid: oidUnstructuredName, value: "router.example.com"},
+			}, prvKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = csr.CheckSignature(); err != nil {
+				t.Errorf("invalid signature: %s", err)
+			}
+			if len(csr.DNSNames) != 1 || csr.DNSNames[0] != "router.example.com" {
+				t.Errorf("expected the extensions to be kept, got %v", csr.DNSNames)
+			}
+
+			var tbs struct {
+				Version       int
+				Subject       asn1.RawValue
+				PublicKey     asn1.RawValue
+				RawAttributes []asn1.RawValue `asn1:"tag:0"`
+			}
+			if _, err = asn1.Unmarshal(csr.RawTBSCertificateRequest, &tbs); err != nil {
+				t.Fatal(err)
+			}
+			// the first attribute holds the extensions
+			values := make(map[string]string)
+			for _, raw := range tbs.RawAttributes[1:] {
+				var attribute struct {
+					Type   asn1.ObjectIdentifier
+					Values []string `asn1:"set"`
+				}
+				if _, err = asn1.Unmarshal(raw.FullBytes, &attribute); err != nil {
+					t.Fatal(err)
+				}
+				values[attribute.Type.String()] = attribute.Values[0]
+			}
+			if values[oidChallengePassword.String()] != "one-time secret" || values[oidUnstructuredName.String()] != "router.example.com" {
+				t.Errorf("unexpected attributes %v", values)
+			}
+		})
+	}
+}
+
+func TestCertificateRequestAttributes(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, certificateRequestAttributesSchema(), map[string]interface{}{"unstructured_name": "router.example.com"})
+	attributes := certificateRequestAttributes(d)
+	if len(attributes) != 1 || !attributes[0].oid.Equal(oidUnstructuredName) || attributes[0].value != "router.example.com" {
+		t.Errorf("expected only unstructuredName to be set, got %v", attributes)
+	}
+}
@@ -0,0 +1,103 @@
+package tlsutils
+
+import (
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net"
+	"sort"
+	"strings"
+)
+
+// suppressEquivalentPEM is a schema.SchemaDiffSuppressFunc ignoring differences in line wrapping, surrounding
+// whitespace and text outside of the PEM blocks, so material produced by other tools does not show perpetual diffs.
+func suppressEquivalentPEM(_, old, new string, _ *schema.ResourceData) bool {
+	return normalizePEM(old) == normalizePEM(new)
+}
+
+// normalizePEM re-encodes every PEM block found in data, in order. Data without PEM block is returned unchanged.
+func normalizePEM(data string) string {
+	normalized := &strings.Builder{}
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		normalized.Write(pem.EncodeToMemory(block))
+	}
+	if normalized.Len() == 0 {
+		return data
+	}
+
+	return normalized.String()
+}
+
+// suppressReorderedList returns a schema.SchemaDiffSuppressFunc for the elements of a list attribute, ignoring
+// changes of order of the whole list once each element is normalized.
+func suppressReorderedList(normalize func(string) string) schema.SchemaDiffSuppressFunc {
+	return func(k, _, _ string, d *schema.ResourceData) bool {
+		dot := strings.LastIndex(k, ".")
+		if dot < 0 {
+			return false
+		}
+		old, new := d.GetChange(k[:dot])
+		oldList, ok := old.([]interface{})
+		if !ok {
+			return false
+		}
+		newList, ok := new.([]interface{})
+		if !ok || len(oldList) != len(newList) {
+			return false
+		}
+
+		oldValues, newValues := normalizedSortedStrings(oldList, normalize), normalizedSortedStrings(newList, normalize)
+		for i := range oldValues {
+			if oldValues[i] != newValues[i] {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+func normalizedSortedStrings(list []interface{}, normalize func(string) string) []string {
+	values := make([]string, 0, len(list))
+	for _, value := range list {
+		s, _ := value.(string)
+		values = append(values, normalize(s))
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+// normalizeDNSName lowercases a DNS name converted to A-labels, leaving invalid names for the validation to report.
+func normalizeDNSName(name string) string {
+	if ascii, err := dnsNameToASCII(name); err == nil {
+		name = ascii
+	}
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// normalizeIPAddress returns the canonical form of an IP address, leaving invalid addresses unchanged.
+func normalizeIPAddress(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// normalizeEmailAddress lowercases the domain of an email address, the local part being case-sensitive.
+func normalizeEmailAddress(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	return email[:at+1] + strings.ToLower(email[at+1:])
+}
+
+func normalizeNothing(value string) string {
+	return value
+}
@@ -0,0 +1,102 @@
+package tlsutils
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"strings"
+	"testing"
+)
+
+func TestSuppressEquivalentPEM(t *testing.T) {
+	ca, _ := testCertificateAuthority(t, "Example CA", nil, nil)
+	other, _ := testCertificateAuthority(t, "Other CA", nil, nil)
+	caPem := certificateToPEM(ca)
+
+	lines := strings.Split(strings.TrimSpace(caPem), "\n")
+	body := strings.Join(lines[1:len(lines)-1], "")
+	rewrapped := lines[0] + "\n" + body[:100] + "\n" + body[100:] + "\n" + lines[len(lines)-1] + "\n"
+
+	for name, test := range map[string]struct {
+		new        string
+		equivalent bool
+	}{
+		"identical":         {new: caPem, equivalent: true},
+		"line wrapping":     {new: rewrapped, equivalent: true},
+		"CRLF line endings": {new: strings.ReplaceAll(caPem, "\n", "\r\n"), equivalent: true},
+		"surrounding text":  {new: "subject=CN=Example CA\n\n" + caPem + "\n\n", equivalent: true},
+		"other certificate": {new: certificateToPEM(other)},
+		"additional block":  {new: caPem + certificateToPEM(other)},
+		"not PEM":           {new: "not PEM"},
+		"empty":             {new: ""},
+		"truncated":         {new: caPem[:40]},
+	} {
+		if got := suppressEquivalentPEM("certificate_pem", caPem, test.new, nil); got != test.equivalent {
+			t.Errorf("%s: expected equivalent %t, got %t", name, test.equivalent, got)
+		}
+	}
+}
+
+func TestNormalizeSubjectAlternativeNames(t *testing.T) {
+	for _, test := range []struct {
+		normalize func(string) string
+		value     string
+		want      string
+	}{
+		{normalizeDNSName, "WWW.Example.COM.", "www.example.com"},
+		{normalizeDNSName, "Bücher.example", "xn--bcher-kva.example"},
+		{normalizeDNSName, "w*.example.com", "w*.example.com"},
+		{normalizeIPAddress, "2001:DB8:0:0::1", "2001:db8::1"},
+		{normalizeIPAddress, "not an IP", "not an IP"},
+		{normalizeEmailAddress, "Admin@Example.COM", "Admin@example.com"},
+		{normalizeEmailAddress, "postmaster", "postmaster"},
+	} {
+		if got := test.normalize(test.value); got != test.want {
+			t.Errorf("expected %q for %q, got %q", test.want, test.value, got)
+		}
+	}
+}
+
+func TestSuppressReorderedList(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := func(caCertPem string, dnsNames, ipAddresses []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"ca_cert_pem":           caCertPem,
+			"ca_private_key_pem":    caKeyPem,
+			"validity_period_hours": 24,
+			"dns_names":             dnsNames,
+			"ip_addresses":          ipAddresses,
+		}
+	}
+
+	r := resourceDualCert()
+	state := testResourceApply(t, r, nil, config(certificateToPEM(ca), []interface{}{"www.example.com", "bücher.example"}, []interface{}{"192.0.2.1", "2001:db8::1"}), &providerMeta{})
+
+	for name, test := range map[string]struct {
+		config      map[string]interface{}
+		requiresNew bool
+	}{
+		"reordered and normalized": {
+			config: config(strings.ReplaceAll(certificateToPEM(ca), "\n", "\r\n"), []interface{}{"XN--BCHER-KVA.example", "WWW.example.com"}, []interface{}{"2001:DB8:0::1", "192.0.2.1"}),
+		},
+		"other DNS name": {
+			config:      config(certificateToPEM(ca), []interface{}{"api.example.com", "bücher.example"}, []interface{}{"192.0.2.1", "2001:db8::1"}),
+			requiresNew: true,
+		},
+		"additional IP address": {
+			config:      config(certificateToPEM(ca), []interface{}{"www.example.com", "bücher.example"}, []interface{}{"192.0.2.1", "2001:db8::1", "192.0.2.2"}),
+			requiresNew: true,
+		},
+	} {
+		diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(test.config), &providerMeta{})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if got := diff != nil && diff.RequiresNew(); got != test.requiresNew {
+			t.Errorf("%s: expected requires new %t, got %t: %v", name, test.requiresNew, got, diff)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tlsutils
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"strings"
+)
+
+// dnsNameToASCII validates a DNS name and returns it with Unicode labels converted to A-labels (punycode).
+// A wildcard is only accepted as the whole leftmost label, followed by at least two labels.
+func dnsNameToASCII(name string) (string, error) {
+	wildcard := false
+	rest := name
+	if strings.HasPrefix(rest, "*.") {
+		wildcard = true
+		rest = strings.TrimPrefix(rest, "*.")
+	}
+	if wildcard && strings.HasPrefix(rest, "*.") {
+		return "", fmt.Errorf("invalid DNS name %q: a wildcard only matches one label, clients do not match names with several wildcard labels", name)
+	}
+	if strings.Contains(rest, "*") {
+		return "", fmt.Errorf("invalid DNS name %q: a wildcard must be the whole leftmost label", name)
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(rest, "."))
+	if err != nil {
+		return "", fmt.Errorf("invalid DNS name %q: %w", name, err)
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if label == "" {
+			return "", fmt.Errorf("invalid DNS name %q: empty label", name)
+		}
+	}
+	if wildcard && strings.Count(ascii, ".") < 1 {
+		return "", fmt.Errorf("invalid DNS name %q: a wildcard needs at least two labels after it", name)
+	}
+
+	if wildcard {
+		return "*." + ascii, nil
+	}
+	return ascii, nil
+}
+
+// validateDNSName is a schema.SchemaValidateFunc checking dnsNameToASCII accepts the value.
+func validateDNSName(i interface{}, k string) ([]string, []error) {
+	name, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	if _, err := dnsNameToASCII(name); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package tlsutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDNSNameToASCII(t *testing.T) {
+	for name, want := range map[string]string{
+		"www.example.com":     "www.example.com",
+		"www.example.com.":    "www.example.com",
+		"bücher.example":      "xn--bcher-kva.example",
+		"*.bücher.example":    "*.xn--bcher-kva.example",
+		"*.example.com":       "*.example.com",
+		"ПРИМЕР.испытание":    "xn--e1afmkfd.xn--80akhbyknj4f",
+		"xn--bcher-kva.intra": "xn--bcher-kva.intra",
+	} {
+		got, err := dnsNameToASCII(name)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", name, err)
+		} else if got != want {
+			t.Errorf("expected %q for %q, got %q", want, name, got)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"*.*.example.com":   "a wildcard only matches one label",
+		"www.*.example.com": "a wildcard must be the whole leftmost label",
+		"w*.example.com":    "a wildcard must be the whole leftmost label",
+		"*.com":             "a wildcard needs at least two labels after it",
+		"www..example.com":  "empty label",
+		"www_example.com":   "invalid DNS name",
+		"-www.example.com":  "invalid DNS name",
+	} {
+		if _, err := dnsNameToASCII(name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q for %q, got %v", want, name, err)
+		}
+	}
+}
+
+func TestCertificateIssueDNSNames(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := testResourceApply(t, resourceDualCert(), nil, map[string]interface{}{
+		"ca_cert_pem":           certificateToPEM(ca),
+		"ca_private_key_pem":    caKeyPem,
+		"validity_period_hours": 24,
+		"dns_names":             []interface{}{"bücher.example", "*.bücher.example"},
+	}, &providerMeta{})
+
+	cert, err := parsePEMCertificate([]byte(state.Attributes["ecdsa_cert_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cert.DNSNames, ",") != "xn--bcher-kva.example,*.xn--bcher-kva.example" {
+		t.Errorf("expected the A-labels of the DNS names, got %v", cert.DNSNames)
+	}
+
+	if _, errs := validateDNSName("www.*.example.com", "dns_names.0"); len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "dns_names.0: ") {
+		t.Errorf("expected a validation error naming the attribute, got %v", errs)
+	}
+}
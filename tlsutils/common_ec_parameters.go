@@ -0,0 +1,202 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// oidPublicKeyECDSA is the id-ecPublicKey algorithm of RFC 5480.
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// oidPrimeField is the prime-field field type of SEC 1 section C.2.
+	oidPrimeField = asn1.ObjectIdentifier{1, 2, 840, 10045, 1, 1}
+)
+
+// namedCurves are the curves explicit EC parameters are normalized to, with their OID of RFC 5480.
+var namedCurves = []struct {
+	oid   asn1.ObjectIdentifier
+	curve elliptic.Curve
+}{
+	{asn1.ObjectIdentifier{1, 3, 132, 0, 33}, elliptic.P224()},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, elliptic.P256()},
+	{asn1.ObjectIdentifier{1, 3, 132, 0, 34}, elliptic.P384()},
+	{asn1.ObjectIdentifier{1, 3, 132, 0, 35}, elliptic.P521()},
+}
+
+// ecExplicitParameters is the SpecifiedECDomain of SEC 1 section C.2, for prime fields.
+type ecExplicitParameters struct {
+	Version int
+	FieldID struct {
+		FieldType asn1.ObjectIdentifier
+		Prime     *big.Int
+	}
+	Curve struct {
+		A    []byte
+		B    []byte
+		Seed asn1.BitString `asn1:"optional"`
+	}
+	Base     []byte
+	Order    *big.Int
+	Cofactor *big.Int `asn1:"optional"`
+}
+
+// namedCurveOID returns the OID of the named curve with the explicit parameters of the DER SpecifiedECDomain der.
+func namedCurveOID(der []byte) (asn1.ObjectIdentifier, error) {
+	var parameters ecExplicitParameters
+	if _, err := asn1.Unmarshal(der, &parameters); err != nil {
+		return nil, fmt.Errorf("unable to parse explicit EC parameters: %w", err)
+	}
+	if !parameters.FieldID.FieldType.Equal(oidPrimeField) {
+		return nil, fmt.Errorf("unsupported EC field type %s, only prime fields are supported", parameters.FieldID.FieldType)
+	}
+
+	a := new(big.Int).SetBytes(parameters.Curve.A)
+	b := new(big.Int).SetBytes(parameters.Curve.B)
+	for _, named := range namedCurves {
+		params := named.curve.Params()
+		// the NIST curves all have a = p - 3
+		if parameters.FieldID.Prime.Cmp(params.P) != 0 || a.Cmp(new(big.Int).Sub(params.P, big.NewInt(3))) != 0 || b.Cmp(params.B) != 0 {
+			continue
+		}
+		if parameters.Order.Cmp(params.N) != 0 || (parameters.Cofactor != nil && parameters.Cofactor.Cmp(big.NewInt(1)) != 0) {
+			continue
+		}
+		if !isCurveBasePoint(params, parameters.Base) {
+			continue
+		}
+		return named.oid, nil
+	}
+
+	return nil, fmt.Errorf("explicit EC parameters do not match any of the supported named curves P224, P256, P384 and P521")
+}
+
+// isCurveBasePoint returns true when the SEC 1 encoded point, compressed or not, is the base point of params.
+func isCurveBasePoint(params *elliptic.CurveParams, point []byte) bool {
+	size := (params.BitSize + 7) / 8
+	switch {
+	case len(point) == 1+2*size && point[0] == 4:
+		return new(big.Int).SetBytes(point[1:1+size]).Cmp(params.Gx) == 0 && new(big.Int).SetBytes(point[1+size:]).Cmp(params.Gy) == 0
+	case len(point) == 1+size && (point[0] == 2 || point[0] == 3):
+		return new(big.Int).SetBytes(point[1:]).Cmp(params.Gx) == 0 && uint(point[0]&1) == params.Gy.Bit(0)
+	}
+
+	return false
+}
+
+// explicitParametersCurveOID returns the OID of the named curve replacing the DER EC parameters, or nil when they
+// are not explicit.
+func explicitParametersCurveOID(parameters []byte) (asn1.ObjectIdentifier, error) {
+	// explicit parameters are a SEQUENCE, named curves an OBJECT IDENTIFIER
+	if len(parameters) == 0 || parameters[0] != 0x30 {
+		return nil, nil
+	}
+
+	return namedCurveOID(parameters)
+}
+
+// namedCurveECPrivateKey rewrites the explicit curve parameters of a DER SEC 1 EC private key, as exported by some
+// HSMs, as the OID of the same named curve. Other keys are returned unchanged.
+func namedCurveECPrivateKey(der []byte) ([]byte, error) {
+	var key struct {
+		Version    int
+		PrivateKey []byte
+		Parameters asn1.RawValue  `asn1:"optional,explicit,tag:0"`
+		PublicKey  asn1.BitString `asn1:"optional,explicit,tag:1"`
+	}
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return der, nil
+	}
+	// with an explicit tag, the raw value is the [0] element, its content the parameters
+	oid, err := explicitParametersCurveOID(key.Parameters.Bytes)
+	if err != nil || oid == nil {
+		return der, err
+	}
+
+	normalized := struct {
+		Version    int
+		PrivateKey []byte
+		Parameters asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+		PublicKey  asn1.BitString        `asn1:"optional,explicit,tag:1"`
+	}{key.Version, key.PrivateKey, oid, key.PublicKey}
+
+	return asn1.Marshal(normalized)
+}
+
+// namedCurvePKCS8PrivateKey is namedCurveECPrivateKey for DER PKCS#8 private keys. Like crypto/x509, the optional
+// attributes are dropped.
+func namedCurvePKCS8PrivateKey(der []byte) ([]byte, error) {
+	var info struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil || !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return der, nil
+	}
+	oid, err := explicitParametersCurveOID(info.Algorithm.Parameters.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	// the embedded SEC 1 key can repeat the parameters
+	privateKey, err := namedCurveECPrivateKey(info.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if oid == nil && bytes.Equal(privateKey, info.PrivateKey) {
+		return der, nil
+	}
+
+	if oid != nil {
+		if info.Algorithm.Parameters.FullBytes, err = asn1.Marshal(oid); err != nil {
+			return nil, err
+		}
+	}
+	info.PrivateKey = privateKey
+	return asn1.Marshal(info)
+}
+
+// namedCurveSubjectPublicKeyInfo is namedCurveECPrivateKey for DER SubjectPublicKeyInfos.
+func namedCurveSubjectPublicKeyInfo(der []byte) ([]byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &spki); err != nil || !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return der, nil
+	}
+	oid, err := explicitParametersCurveOID(spki.Algorithm.Parameters.FullBytes)
+	if err != nil || oid == nil {
+		return der, err
+	}
+
+	if spki.Algorithm.Parameters.FullBytes, err = asn1.Marshal(oid); err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(spki)
+}
+
+// parseECPrivateKey is x509.ParseECPrivateKey also accepting explicit curve parameters.
+func parseECPrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	der, err := namedCurveECPrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseECPrivateKey(der)
+}
+
+// parsePKCS8PrivateKey is x509.ParsePKCS8PrivateKey also accepting EC keys with explicit curve parameters.
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	der, err := namedCurvePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParsePKCS8PrivateKey(der)
+}
@@ -0,0 +1,200 @@
+package tlsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// testExplicitECParameters returns the DER SpecifiedECDomain of a NIST curve, with its base point compressed or not.
+func testExplicitECParameters(t *testing.T, curve elliptic.Curve, compressed bool) []byte {
+	t.Helper()
+
+	params := curve.Params()
+	size := (params.BitSize + 7) / 8
+	parameters := ecExplicitParameters{Version: 1, Order: params.N, Cofactor: big.NewInt(1)}
+	parameters.FieldID.FieldType = oidPrimeField
+	parameters.FieldID.Prime = params.P
+	parameters.Curve.A = new(big.Int).Sub(params.P, big.NewInt(3)).FillBytes(make([]byte, size))
+	parameters.Curve.B = params.B.FillBytes(make([]byte, size))
+	if compressed {
+		parameters.Base = elliptic.MarshalCompressed(curve, params.Gx, params.Gy)
+	} else {
+		parameters.Base = append([]byte{4}, append(params.Gx.FillBytes(make([]byte, size)), params.Gy.FillBytes(make([]byte, size))...)...)
+	}
+
+	der, err := asn1.Marshal(parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// testExplicitECPrivateKey returns the DER SEC 1 encoding of prvKey, with explicit curve parameters.
+func testExplicitECPrivateKey(t *testing.T, prvKey *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key struct {
+		Version    int
+		PrivateKey []byte
+		Parameters asn1.RawValue  `asn1:"optional,explicit,tag:0"`
+		PublicKey  asn1.BitString `asn1:"optional,explicit,tag:1"`
+	}
+	if _, err = asn1.Unmarshal(der, &key); err != nil {
+		t.Fatal(err)
+	}
+	// FullBytes are marshaled as is, so the raw value is the explicit [0] element
+	key.Parameters = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: testExplicitECParameters(t, prvKey.Curve, false)}
+	if der, err = asn1.Marshal(struct {
+		Version    int
+		PrivateKey []byte
+		Parameters asn1.RawValue
+		PublicKey  asn1.BitString `asn1:"optional,explicit,tag:1"`
+	}{key.Version, key.PrivateKey, key.Parameters, key.PublicKey}); err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestNamedCurveOID(t *testing.T) {
+	p256 := elliptic.P256().Params()
+	withParameters := func(change func(*ecExplicitParameters)) []byte {
+		var parameters ecExplicitParameters
+		if _, err := asn1.Unmarshal(testExplicitECParameters(t, elliptic.P256(), false), &parameters); err != nil {
+			t.Fatal(err)
+		}
+		change(&parameters)
+		der, err := asn1.Marshal(parameters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	for name, test := range map[string]struct {
+		parameters []byte
+		oid        asn1.ObjectIdentifier
+		err        string
+	}{
+		"P-256":            {parameters: testExplicitECParameters(t, elliptic.P256(), false), oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+		"P-256 compressed": {parameters: testExplicitECParameters(t, elliptic.P256(), true), oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+		"P-384":            {parameters: testExplicitECParameters(t, elliptic.P384(), false), oid: asn1.ObjectIdentifier{1, 3, 132, 0, 34}},
+		"P-521":            {parameters: testExplicitECParameters(t, elliptic.P521(), true), oid: asn1.ObjectIdentifier{1, 3, 132, 0, 35}},
+		"without cofactor": {parameters: withParameters(func(p *ecExplicitParameters) { p.Cofactor = nil }), oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+		"secp256k1, explicit only": {
+			parameters: withParameters(func(p *ecExplicitParameters) {
+				p.FieldID.Prime, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+				p.Curve.A, p.Curve.B = []byte{0}, []byte{7}
+			}),
+			err: "explicit EC parameters do not match any of the supported named curves",
+		},
+		"other base point": {
+			parameters: withParameters(func(p *ecExplicitParameters) {
+				x, y := elliptic.P256().ScalarBaseMult([]byte{2})
+				p.Base = elliptic.Marshal(elliptic.P256(), x, y)
+			}),
+			err: "do not match any of the supported named curves",
+		},
+		"other cofactor": {
+			parameters: withParameters(func(p *ecExplicitParameters) { p.Cofactor = big.NewInt(4) }),
+			err:        "do not match any of the supported named curves",
+		},
+		"other order": {
+			parameters: withParameters(func(p *ecExplicitParameters) { p.Order = new(big.Int).Add(p256.N, big.NewInt(2)) }),
+			err:        "do not match any of the supported named curves",
+		},
+		"characteristic two field": {
+			parameters: withParameters(func(p *ecExplicitParameters) { p.FieldID.FieldType = asn1.ObjectIdentifier{1, 2, 840, 10045, 1, 2} }),
+			err:        "unsupported EC field type 1.2.840.10045.1.2",
+		},
+		"not DER": {parameters: []byte{0x30, 0x03, 0x02}, err: "unable to parse explicit EC parameters"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			oid, err := namedCurveOID(test.parameters)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !oid.Equal(test.oid) {
+				t.Errorf("expected %s, got %s", test.oid, oid)
+			}
+		})
+	}
+}
+
+func TestParseExplicitECKeys(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			prvKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sec1 := testExplicitECPrivateKey(t, prvKey)
+			if _, err = x509.ParseECPrivateKey(sec1); err == nil {
+				t.Fatalf("expected crypto/x509 to refuse explicit parameters")
+			}
+
+			keyPem := string(pem.EncodeToMemory(&pem.Block{Type: PreamblePrivateKeyEC.String(), Bytes: sec1}))
+			parsed, algorithm, err := parsePrivateKeyPEM([]byte(keyPem))
+			if err != nil {
+				t.Fatalf("unable to parse SEC 1 key: %s", err)
+			}
+			if algorithm != ECDSA || !prvKey.Equal(parsed) {
+				t.Errorf("expected the generated %s key, got %s %T", curve.Params().Name, algorithm, parsed)
+			}
+
+			// PKCS#8 with explicit parameters both in the algorithm and in the embedded SEC 1 key
+			pkcs8, err := asn1.Marshal(struct {
+				Version    int
+				Algorithm  pkix.AlgorithmIdentifier
+				PrivateKey []byte
+			}{
+				Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: testExplicitECParameters(t, curve, true)}},
+				PrivateKey: sec1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed, err := parsePKCS8PrivateKey(pkcs8); err != nil || !prvKey.Equal(parsed) {
+				t.Errorf("expected the PKCS#8 key to be parsed, got %v", err)
+			}
+
+			spki, err := x509.MarshalPKIXPublicKey(prvKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var explicitSPKI struct {
+				Algorithm pkix.AlgorithmIdentifier
+				PublicKey asn1.BitString
+			}
+			if _, err = asn1.Unmarshal(spki, &explicitSPKI); err != nil {
+				t.Fatal(err)
+			}
+			explicitSPKI.Algorithm.Parameters = asn1.RawValue{FullBytes: testExplicitECParameters(t, curve, false)}
+			explicitDER, err := asn1.Marshal(explicitSPKI)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pins, err := subjectPublicKeyInfosFromPEM(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: explicitDER}))
+			if err != nil || len(pins) != 1 || string(pins[0]) != string(spki) {
+				t.Errorf("expected the public key to be normalized to its named curve, got %v", err)
+			}
+		})
+	}
+}
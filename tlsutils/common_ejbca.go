@@ -0,0 +1,120 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strings"
+)
+
+// ejbcaSchema returns the attributes shared by the resources and data sources calling the EJBCA REST API.
+func ejbcaSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"ejbca_url": {
+			Description:      "URL of the EJBCA REST API, e.g. `https://ejbca.example.com/ejbca/ejbca-rest-api`.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPS),
+		},
+		"client_cert_pem": {
+			Description:      "client certificate of an EJBCA administrator in PEM format, optionally followed by its chain.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+			RequiredWith:     []string{"client_key_pem"},
+		},
+		"client_key_pem": {
+			Description:  "private key of `client_cert_pem` in PEM format.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			RequiredWith: []string{"client_cert_pem"},
+		},
+		"bearer_token": {
+			Description: "OAuth access token of an EJBCA administrator, instead of `client_cert_pem`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
+		"ca_cert_pem": {
+			Description:      "CA certificates in PEM format trusted for the TLS connection, in addition to the system roots.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+
+	return s
+}
+
+// ejbcaCertificate is a certificate in the responses of the EJBCA REST API.
+type ejbcaCertificate struct {
+	Certificate      string   `json:"certificate"`
+	SerialNumber     string   `json:"serial_number"`
+	ResponseFormat   string   `json:"response_format"`
+	CertificateChain []string `json:"certificate_chain"`
+}
+
+// parseEJBCACertificate parses a certificate of the EJBCA REST API, base64 DER or PEM depending on its response_format.
+func parseEJBCACertificate(data, format string) (*x509.Certificate, error) {
+	if format == "PEM" || strings.HasPrefix(strings.TrimSpace(data), "-----BEGIN ") {
+		return parsePEMCertificate([]byte(data))
+	}
+
+	der, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// ejbcaClient returns the HTTP client of networkOptions, trusting ca_cert_pem in addition to the system roots and
+// presenting client_cert_pem when set.
+func ejbcaClient(d *schema.ResourceData, options *networkOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSClientConfig(d.Get("ca_cert_pem").(string), d.Get("client_cert_pem").(string), d.Get("client_key_pem").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	client := options.httpClient()
+	client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+
+	return client, nil
+}
+
+// ejbcaRequest sends a request to the given path of the EJBCA REST API, e.g. `v1/certificate/search`,
+// retried as configured by networkSchema.
+func ejbcaRequest(ctx context.Context, d *schema.ResourceData, method, path string, reqBody, respBody interface{}) error {
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return err
+	}
+	client, err := ejbcaClient(d, options)
+	if err != nil {
+		return fmt.Errorf("failed to configure EJBCA client: %w", err)
+	}
+
+	url := strings.TrimRight(d.Get("ejbca_url").(string), "/") + "/" + path
+
+	headers := map[string]string{}
+	if token := d.Get("bearer_token").(string); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	return options.retry(ctx, func(ctx context.Context) error {
+		return doJSONRequest(ctx, client, method, url, headers, reqBody, respBody)
+	})
+}
@@ -0,0 +1,268 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"filippo.io/age"
+	ageArmor "filippo.io/age/armor"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/nacl/box"
+	"io"
+	"strings"
+)
+
+// privateKeyEncryptionSchema returns the age_recipient and pgp_key attributes of the resources generating private
+// keys, with the encrypted_<key> attribute of each of their private key attributes keys, filled by
+// encryptPrivateKeys. The resources rotating their key in place on changes of the recipient set forceNew to false.
+func privateKeyEncryptionSchema(forceNew bool, keys ...string) map[string]*schema.Schema {
+	encrypted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		encrypted = append(encrypted, "`encrypted_"+key+"`")
+	}
+	stored := fmt.Sprintf("When set, the private keys are only stored encrypted to it, ASCII armored, in %s.", strings.Join(encrypted, ", "))
+	if !forceNew {
+		stored += " Changing it rotates the keys."
+	}
+
+	s := map[string]*schema.Schema{
+		"age_recipient": {
+			Description:   "age X25519 recipient (`age1...`). " + stored,
+			Type:          schema.TypeString,
+			Optional:      true,
+			ForceNew:      forceNew,
+			ConflictsWith: []string{"pgp_key"},
+		},
+		"pgp_key": {
+			Description:   "PGP public key, ASCII armored or base64 encoded like the `pgp_key` of `aws_iam_access_key`. " + stored,
+			Type:          schema.TypeString,
+			Optional:      true,
+			ForceNew:      forceNew,
+			ConflictsWith: []string{"age_recipient"},
+		},
+	}
+	for _, key := range keys {
+		s["encrypted_"+key] = &schema.Schema{
+			Description: "`" + key + "` encrypted to `age_recipient` or `pgp_key`, empty when neither is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		}
+	}
+
+	return s
+}
+
+// encryptPrivateKeys moves the private keys of values named by keys to their encrypted_<key> value, encrypted to
+// the age_recipient or pgp_key of d, leaving the plaintext values empty. The encrypted values are empty when neither
+// is set.
+func encryptPrivateKeys(d *schema.ResourceData, values map[string]interface{}, keys ...string) error {
+	encrypt := recipientEncryptor(d.Get("age_recipient").(string), d.Get("pgp_key").(string))
+	for _, key := range keys {
+		values["encrypted_"+key] = ""
+		plaintext, _ := values[key].(string)
+		if encrypt == nil || plaintext == "" {
+			continue
+		}
+
+		encrypted, err := encrypt([]byte(plaintext))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		values["encrypted_"+key] = encrypted
+		values[key] = ""
+	}
+
+	return nil
+}
+
+// recipientEncryptor returns the function encrypting plaintext to ageRecipient or pgpKey, or nil when both are empty.
+func recipientEncryptor(ageRecipient, pgpKey string) func([]byte) (string, error) {
+	if ageRecipient != "" {
+		return func(plaintext []byte) (string, error) {
+			return encryptToAgeRecipient(plaintext, ageRecipient)
+		}
+	}
+	if pgpKey != "" {
+		return func(plaintext []byte) (string, error) {
+			return encryptToPGPKey(plaintext, pgpKey)
+		}
+	}
+
+	return nil
+}
+
+// encryptToAgeRecipient encrypts plaintext to an age X25519 recipient (age1...) and returns it ASCII armored.
+func encryptToAgeRecipient(plaintext []byte, recipient string) (string, error) {
+	r, err := age.ParseX25519Recipient(strings.TrimSpace(recipient))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse age recipient: %w", err)
+	}
+
+	var buf bytes.Buffer
+	armorWriter := ageArmor.NewWriter(&buf)
+	w, err := age.Encrypt(armorWriter, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt to age recipient: %w", err)
+	}
+	if err = writeAndClose(w, plaintext); err != nil {
+		return "", fmt.Errorf("failed to encrypt to age recipient: %w", err)
+	}
+	if err = armorWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to armor age message: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// encryptToPGPKey encrypts plaintext to a PGP public key and returns it ASCII armored.
+// The key is either ASCII armored or, as in aws_iam_access_key, base64 encoded binary.
+func encryptToPGPKey(plaintext []byte, pgpKey string) (string, error) {
+	var entities openpgp.EntityList
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(pgpKey), "-----BEGIN") {
+		entities, err = openpgp.ReadArmoredKeyRing(strings.NewReader(pgpKey))
+	} else {
+		var keyBytes []byte
+		keyBytes, err = base64.StdEncoding.DecodeString(strings.TrimSpace(pgpKey))
+		if err != nil {
+			return "", fmt.Errorf("failed to decode PGP public key: %w", err)
+		}
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(keyBytes))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PGP public key: %w", err)
+	}
+	if len(entities) != 1 {
+		return "", fmt.Errorf("PGP public key should contain exactly one key, got %d", len(entities))
+	}
+
+	var buf bytes.Buffer
+	armorWriter, err := pgpArmor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to armor PGP message: %w", err)
+	}
+	w, err := openpgp.Encrypt(armorWriter, entities, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt to PGP key: %w", err)
+	}
+	if err = writeAndClose(w, plaintext); err != nil {
+		return "", fmt.Errorf("failed to encrypt to PGP key: %w", err)
+	}
+	if err = armorWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to armor PGP message: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Public key encryption schemes of tlsutils_public_key_encrypt.
+const (
+	publicKeyEncryptRSAOAEP   = "rsa_oaep"
+	publicKeyEncryptHPKE      = "hpke"
+	publicKeyEncryptSealedBox = "sealed_box"
+)
+
+// parseEncryptionPublicKey parses a public key, certificate or private key in PEM format, or a base64 encoded
+// raw X25519 public key as published by the GitHub Actions secrets API.
+func parseEncryptionPublicKey(publicKey string) (crypto.PublicKey, error) {
+	if !strings.HasPrefix(strings.TrimSpace(publicKey), "-----BEGIN") {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+		if err != nil {
+			return nil, fmt.Errorf("public key is neither PEM nor base64: %w", err)
+		}
+		x25519Key, err := ecdh.X25519().NewPublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw X25519 public key: %w", err)
+		}
+		return x25519Key, nil
+	}
+
+	spkis, err := subjectPublicKeyInfosFromPEM([]byte(publicKey))
+	if err != nil {
+		return nil, err
+	}
+	if len(spkis) == 0 {
+		return nil, fmt.Errorf("public key PEM does not contain any key or certificate")
+	}
+	pubKey, err := x509.ParsePKIXPublicKey(spkis[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %w", err)
+	}
+
+	return pubKey, nil
+}
+
+// defaultPublicKeyEncryptScheme returns the scheme used for pubKey when none is configured:
+// RSA-OAEP for RSA keys, HPKE for the others.
+func defaultPublicKeyEncryptScheme(pubKey crypto.PublicKey) string {
+	if _, ok := pubKey.(*rsa.PublicKey); ok {
+		return publicKeyEncryptRSAOAEP
+	}
+	return publicKeyEncryptHPKE
+}
+
+// encryptToPublicKey encrypts plaintext to pubKey with scheme. hash is the OAEP hash and info the HPKE info.
+func encryptToPublicKey(pubKey crypto.PublicKey, scheme string, plaintext []byte, hash crypto.Hash, info []byte) ([]byte, error) {
+	switch scheme {
+	case publicKeyEncryptRSAOAEP:
+		rsaKey, ok := pubKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("scheme %s requires an RSA public key, got %T", scheme, pubKey)
+		}
+		ciphertext, err := rsa.EncryptOAEP(hash.New(), rand.Reader, rsaKey, plaintext, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt with RSA-OAEP: %w", err)
+		}
+		return ciphertext, nil
+	case publicKeyEncryptHPKE, publicKeyEncryptSealedBox:
+		ecdhKey, err := ecdhPublicKey(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("scheme %s: %w", scheme, err)
+		}
+		if scheme == publicKeyEncryptHPKE {
+			return hpkeSeal(ecdhKey, info, plaintext)
+		}
+		if ecdhKey.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("scheme %s requires an X25519 public key", scheme)
+		}
+		ciphertext, err := box.SealAnonymous(nil, plaintext, (*[32]byte)(ecdhKey.Bytes()), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt to sealed box: %w", err)
+		}
+		return ciphertext, nil
+	}
+
+	return nil, fmt.Errorf("unsupported encryption scheme %q", scheme)
+}
+
+// ecdhPublicKey converts an ECDSA or X25519 public key to a key agreement key.
+func ecdhPublicKey(pubKey crypto.PublicKey) (*ecdh.PublicKey, error) {
+	switch pubKey := pubKey.(type) {
+	case *ecdh.PublicKey:
+		return pubKey, nil
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pubKey.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("unsupported ECDSA key: %w", err)
+		}
+		return ecdhKey, nil
+	}
+
+	return nil, fmt.Errorf("requires an ECDSA or X25519 public key, got %T", pubKey)
+}
+
+func writeAndClose(w io.WriteCloser, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,143 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"filippo.io/age"
+	ageArmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/nacl/box"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testAgeDecrypt returns the decryption of the ASCII armored age message by identity.
+func testAgeDecrypt(t *testing.T, message string, identity *age.X25519Identity) string {
+	t.Helper()
+
+	r, err := age.Decrypt(ageArmor.NewReader(strings.NewReader(message)), identity)
+	if err != nil {
+		t.Fatalf("failed to decrypt age message: %s", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decrypt age message: %s", err)
+	}
+	return string(plaintext)
+}
+
+// testPGPEntity returns a new PGP key with its public key in base64 encoded binary format.
+func testPGPEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("failed to generate PGP key: %s", err)
+	}
+	var publicKey bytes.Buffer
+	if err = entity.Serialize(&publicKey); err != nil {
+		t.Fatalf("failed to serialize PGP public key: %s", err)
+	}
+	return entity, base64.StdEncoding.EncodeToString(publicKey.Bytes())
+}
+
+// testPGPDecrypt returns the decryption of the ASCII armored PGP message by entity.
+func testPGPDecrypt(t *testing.T, message string, entity *openpgp.Entity) string {
+	t.Helper()
+
+	block, err := pgpArmor.Decode(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("failed to decode PGP message: %s", err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt PGP message: %s", err)
+	}
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatalf("failed to decrypt PGP message: %s", err)
+	}
+	return string(plaintext)
+}
+
+func TestRecipientEncryptor(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := recipientEncryptor(identity.Recipient().String(), "")([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, ageArmor.Header) || testAgeDecrypt(t, encrypted, identity) != "secret" {
+		t.Errorf("expected an armored age message of the plaintext, got %q", encrypted)
+	}
+
+	entity, publicKey := testPGPEntity(t)
+	var armored bytes.Buffer
+	w, err := pgpArmor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, pgpKey := range map[string]string{"base64": publicKey, "armored": armored.String()} {
+		encrypted, err = recipientEncryptor("", pgpKey)([]byte("secret"))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !strings.HasPrefix(encrypted, "-----BEGIN PGP MESSAGE-----") || testPGPDecrypt(t, encrypted, entity) != "secret" {
+			t.Errorf("%s: expected an armored PGP message of the plaintext, got %q", name, encrypted)
+		}
+	}
+
+	if recipientEncryptor("", "") != nil {
+		t.Errorf("expected no encryptor without recipient")
+	}
+	if _, err = recipientEncryptor("age1invalid", "")([]byte("secret")); err == nil || !strings.Contains(err.Error(), "failed to parse age recipient") {
+		t.Errorf("expected an invalid age recipient error, got %v", err)
+	}
+}
+
+func TestEncryptToPublicKeySealedBox(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// raw base64 X25519 key, as published by the GitHub Actions secrets API
+	pubKey, err := parseEncryptionPublicKey(base64.StdEncoding.EncodeToString(publicKey[:]))
+	if err != nil {
+		t.Fatalf("unable to parse the public key: %s", err)
+	}
+
+	plaintext := []byte("sealed box secret")
+	ciphertext, err := encryptToPublicKey(pubKey, publicKeyEncryptSealedBox, plaintext, crypto.SHA256, nil)
+	if err != nil {
+		t.Fatalf("encrypt failed: %s", err)
+	}
+	if len(ciphertext) != box.AnonymousOverhead+len(plaintext) {
+		t.Errorf("expected %d bytes of overhead, got %d", box.AnonymousOverhead, len(ciphertext)-len(plaintext))
+	}
+	opened, ok := box.OpenAnonymous(nil, ciphertext, publicKey, privateKey)
+	if !ok || !bytes.Equal(opened, plaintext) {
+		t.Errorf("expected nacl/box to open the sealed box to %q, got %q (ok: %t)", plaintext, opened, ok)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = encryptToPublicKey(ecdsaKey.Public(), publicKeyEncryptSealedBox, plaintext, crypto.SHA256, nil); err == nil || !strings.Contains(err.Error(), "requires an X25519 public key") {
+		t.Errorf("expected a P-256 key to be rejected, got %v", err)
+	}
+}
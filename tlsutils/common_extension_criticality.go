@@ -0,0 +1,106 @@
+package tlsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// criticalityExtensions maps the extension_criticality names to the extensions crypto/x509 generates.
+var criticalityExtensions = map[string]asn1.ObjectIdentifier{
+	"subject_alt_name":   oidSubjectAltName,
+	"key_usage":          {2, 5, 29, 15},
+	"extended_key_usage": {2, 5, 29, 37},
+	"basic_constraints":  {2, 5, 29, 19},
+	"name_constraints":   {2, 5, 29, 30},
+}
+
+// extensionCriticalitySchema returns the ForceNew map attribute overriding the criticality of the extensions names
+// of the certificate described by certificate, applied by setExtensionCriticality.
+func extensionCriticalitySchema(certificate string, names ...string) *schema.Schema {
+	sort.Strings(names)
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, "`"+name+"`")
+	}
+
+	return &schema.Schema{
+		Description:      fmt.Sprintf("criticality of the extensions of %s by name, overriding the defaults of crypto/x509 to match the profile a validator expects: %s. Setting an extension the certificate does not have is an error.", certificate, strings.Join(quoted, ", ")),
+		Type:             schema.TypeMap,
+		Optional:         true,
+		ForceNew:         true,
+		Elem:             &schema.Schema{Type: schema.TypeBool},
+		ValidateDiagFunc: validation.MapKeyMatch(regexp.MustCompile("^("+strings.Join(names, "|")+")$"), "expected one of "+strings.Join(names, ", ")),
+	}
+}
+
+// setExtensionCriticality overrides the criticality of the extensions crypto/x509 generates from template with
+// criticality, an extensionCriticalitySchema value. crypto/x509 only lets ExtraExtensions replace the extensions it
+// generates, so they are taken from a throwaway self-signed certificate of template and added to ExtraExtensions:
+// later changes to the fields they encode are ignored.
+func setExtensionCriticality(template *x509.Certificate, criticality map[string]interface{}) error {
+	if len(criticality) == 0 {
+		return nil
+	}
+
+	throwawayKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	probe := *template
+	probe.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+	der, err := x509.CreateCertificate(rand.Reader, &probe, &probe, throwawayKey.Public(), throwawayKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+	generated, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	names := make([]string, 0, len(criticality))
+	for name := range criticality {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		extension, ok := findExtension(generated.Extensions, criticalityExtensions[name])
+		if !ok {
+			return fmt.Errorf("unable to set the criticality of %s: the certificate has no such extension", name)
+		}
+		extension.Critical = criticality[name].(bool)
+		template.ExtraExtensions = replaceExtension(template.ExtraExtensions, extension)
+	}
+
+	return nil
+}
+
+// findExtension returns the extension of extensions with the given id.
+func findExtension(extensions []pkix.Extension, id asn1.ObjectIdentifier) (pkix.Extension, bool) {
+	for _, extension := range extensions {
+		if extension.Id.Equal(id) {
+			return extension, true
+		}
+	}
+	return pkix.Extension{}, false
+}
+
+// replaceExtension returns extensions with extension in place of the one with the same id, appended if there is none.
+func replaceExtension(extensions []pkix.Extension, extension pkix.Extension) []pkix.Extension {
+	for i := range extensions {
+		if extensions[i].Id.Equal(extension.Id) {
+			extensions[i] = extension
+			return extensions
+		}
+	}
+	return append(extensions, extension)
+}
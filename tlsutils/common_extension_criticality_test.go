@@ -0,0 +1,58 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+)
+
+// testExtensionCriticality returns the criticality of the extensions of criticalityExtensions in cert by name.
+func testExtensionCriticality(cert *x509.Certificate) map[string]bool {
+	critical := make(map[string]bool)
+	for name, id := range criticalityExtensions {
+		if extension, ok := findExtension(cert.Extensions, id); ok {
+			critical[name] = extension.Critical
+		}
+	}
+	return critical
+}
+
+func TestSetExtensionCriticality(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "www.example.com"},
+		DNSNames:              []string{"www.example.com"},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	criticality := map[string]interface{}{
+		"subject_alt_name":   true,
+		"key_usage":          false,
+		"extended_key_usage": true,
+		"basic_constraints":  false,
+	}
+	if err := setExtensionCriticality(template, criticality); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := testCertificate(t, template, ca, caKey)
+	for name, want := range criticality {
+		if got, ok := testExtensionCriticality(cert)[name]; !ok || got != want {
+			t.Errorf("expected %s to have criticality %t, got %t (present: %t)", name, want, got, ok)
+		}
+	}
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature || len(cert.DNSNames) != 1 || cert.DNSNames[0] != "www.example.com" {
+		t.Errorf("expected the values of the extensions to be kept, got %b %v", cert.KeyUsage, cert.DNSNames)
+	}
+
+	err := setExtensionCriticality(&x509.Certificate{Subject: pkix.Name{CommonName: "no usages"}}, map[string]interface{}{"extended_key_usage": true})
+	if err == nil || !strings.Contains(err.Error(), "unable to set the criticality of extended_key_usage") {
+		t.Errorf("expected an error for the missing extended key usage, got %v", err)
+	}
+
+	attribute := extensionCriticalitySchema("the intermediate CA certificate", "key_usage", "basic_constraints", "name_constraints")
+	if diags := attribute.ValidateDiagFunc(map[string]interface{}{"subject_alt_name": true}, nil); !diags.HasError() {
+		t.Errorf("expected subject_alt_name to be rejected when not listed")
+	}
+}
@@ -0,0 +1,185 @@
+package tlsutils
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net"
+	"net/url"
+)
+
+// generatedCSRSchema returns the csr_pem attribute of the resources sending a CSR to a remote CA, described by
+// description and computed from the generate_key block when it is not set, with the private_key_pem of the
+// generated key and its privateKeyEncryptionSchema. generateCertificateRequest fills them.
+func generatedCSRSchema(description string) map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"csr_pem": {
+			Description:      description + " Generated with its key from `generate_key` when not set.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ExactlyOneOf:     []string{"csr_pem", "generate_key"},
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"generate_key": {
+			Description:  "generate the private key and the CSR sent to the CA, instead of signing `csr_pem`.",
+			Type:         schema.TypeList,
+			Optional:     true,
+			ForceNew:     true,
+			MaxItems:     1,
+			ExactlyOneOf: []string{"csr_pem", "generate_key"},
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"algorithm": {
+						Description:      "name of the algorithm of the key. Defaults to the `default_key_algorithm` of the provider, then `ECDSA`.",
+						Type:             schema.TypeString,
+						Optional:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedAlgorithmsStr(), false)),
+					},
+					"rsa_bits": {
+						Description:      "size of the RSA key in bits, when `algorithm` is `RSA`. Defaults to the `default_rsa_bits` of the provider, then 2048.",
+						Type:             schema.TypeInt,
+						Optional:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+					},
+					"ecdsa_curve": {
+						Description:      "elliptic curve of the key, when `algorithm` is `ECDSA`. Defaults to the `default_ecdsa_curve` of the provider, then `P256`.",
+						Type:             schema.TypeString,
+						Optional:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+					},
+					"subject": certificateSubjectSchema("subject of the CSR. Defaults to the `default_subject` of the provider."),
+					"dns_names": {
+						Description: "DNS names requested in the CSR. Unicode names are converted to A-labels (punycode).",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type:         schema.TypeString,
+							ValidateFunc: validateDNSName,
+						},
+					},
+					"ip_addresses": {
+						Description: "IP addresses requested in the CSR.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type:         schema.TypeString,
+							ValidateFunc: validation.IsIPAddress,
+						},
+					},
+					"uris": {
+						Description: "URIs requested in the CSR.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem:        &schema.Schema{Type: schema.TypeString},
+					},
+				},
+			},
+		},
+		"private_key_pem": {
+			Description: "private key generated with `generate_key` in PEM format, empty when `csr_pem` is set or when the key is encrypted to `age_recipient` or `pgp_key`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+	}
+	for name, attribute := range privateKeyEncryptionSchema(true, "private_key_pem") {
+		s[name] = attribute
+	}
+
+	return s
+}
+
+// generateCertificateRequest generates the key and the CSR of the generate_key block of d, if any, saving them as
+// csr_pem and private_key_pem, the key encrypted to the age_recipient or pgp_key when set.
+func generateCertificateRequest(d *schema.ResourceData, m interface{}) error {
+	blocks := d.Get("generate_key").([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+	block := map[string]interface{}{}
+	if blocks[0] != nil {
+		block = blocks[0].(map[string]interface{})
+	}
+
+	meta, _ := m.(*providerMeta)
+	if meta == nil {
+		meta = &providerMeta{}
+	}
+	algorithm := Algorithm(block["algorithm"].(string))
+	if algorithm == "" {
+		algorithm = meta.defaultKeyAlgorithm
+	}
+	if algorithm == "" {
+		algorithm = ECDSA
+	}
+	rsaBits := block["rsa_bits"].(int)
+	if rsaBits == 0 {
+		rsaBits = meta.defaultRSABits
+	}
+	if rsaBits == 0 {
+		rsaBits = 2048
+	}
+	curve := ECDSACurve(block["ecdsa_curve"].(string))
+	if curve == "" {
+		curve = meta.defaultECDSACurve
+	}
+	if curve == "" {
+		curve = P256
+	}
+	prvKey, err := generatePrivateKey(algorithm, rsaBits, curve)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.CertificateRequest{}
+	if subjects, _ := block["subject"].([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+		template.Subject = certificateSubject(subjects[0].(map[string]interface{}))
+	}
+	template.Subject = subjectWithDefaults(template.Subject, m)
+	for _, name := range block["dns_names"].([]interface{}) {
+		ascii, err := dnsNameToASCII(name.(string))
+		if err != nil {
+			return err
+		}
+		template.DNSNames = append(template.DNSNames, ascii)
+	}
+	for _, address := range block["ip_addresses"].([]interface{}) {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(address.(string)))
+	}
+	for _, rawURI := range block["uris"].([]interface{}) {
+		uri, err := url.Parse(rawURI.(string))
+		if err != nil {
+			return fmt.Errorf("invalid URI %q: %w", rawURI, err)
+		}
+		template.URIs = append(template.URIs, uri)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, prvKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	values := map[string]interface{}{
+		"csr_pem":         string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificateRequest.String(), Bytes: der})),
+		"private_key_pem": privateKeyPem,
+	}
+	if err = encryptPrivateKeys(d, values, "private_key_pem"); err != nil {
+		return err
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// hashAlgorithms maps the names of the hashes accepted by signature verification and RSA-OAEP to their crypto.Hash.
+var hashAlgorithms = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// supportedHashAlgorithmsStr returns the names of hashAlgorithms.
+func supportedHashAlgorithmsStr() []string {
+	supported := make([]string, 0, len(hashAlgorithms))
+	for name := range hashAlgorithms {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return supported
+}
+
+// hashForState computes the hexadecimal representation of the SHA1 checksum of the given values.
+// It is used to derive stable IDs from resource inputs.
+func hashForState(values ...string) string {
+	hash := sha1.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// randomID returns a random ID, for the resources whose outputs are all generated and cannot derive it from inputs.
+func randomID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	return hashForState(hex.EncodeToString(random)), nil
+}
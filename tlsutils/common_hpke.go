@@ -0,0 +1,111 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// hpkeKEMs are the RFC 9180 DHKEM of each supported curve.
+var hpkeKEMs = map[ecdh.Curve]hpkeKEM{
+	ecdh.P256():   {id: 0x0010, hash: crypto.SHA256},
+	ecdh.P384():   {id: 0x0011, hash: crypto.SHA384},
+	ecdh.P521():   {id: 0x0012, hash: crypto.SHA512},
+	ecdh.X25519(): {id: 0x0020, hash: crypto.SHA256},
+}
+
+const (
+	// hpkeKDFHKDFSHA256 and hpkeAEADAES128GCM are the KDF and AEAD of every suite, the most widely implemented.
+	hpkeKDFHKDFSHA256 = 0x0001
+	hpkeAEADAES128GCM = 0x0001
+	hpkeModeBase      = 0x00
+)
+
+type hpkeKEM struct {
+	id   uint16
+	hash crypto.Hash
+}
+
+// hpkeSeal encrypts plaintext to pubKey with single-shot RFC 9180 HPKE in base mode, with HKDF-SHA256, AES-128-GCM
+// and an empty aad. It returns the encapsulated key followed by the ciphertext.
+func hpkeSeal(pubKey *ecdh.PublicKey, info, plaintext []byte) ([]byte, error) {
+	ephemeral, err := pubKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate HPKE ephemeral key: %w", err)
+	}
+
+	return hpkeSealWithEphemeral(pubKey, ephemeral, info, plaintext)
+}
+
+func hpkeSealWithEphemeral(pubKey *ecdh.PublicKey, ephemeral *ecdh.PrivateKey, info, plaintext []byte) ([]byte, error) {
+	kem, ok := hpkeKEMs[pubKey.Curve()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported HPKE curve %s", pubKey.Curve())
+	}
+
+	dh, err := ephemeral.ECDH(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed HPKE key agreement: %w", err)
+	}
+	enc := ephemeral.PublicKey().Bytes()
+
+	// DHKEM ExtractAndExpand, with a shared secret of the size of the KEM hash
+	kemSuiteID := binary.BigEndian.AppendUint16([]byte("KEM"), kem.id)
+	eaePRK := hpkeLabeledExtract(kem.hash, kemSuiteID, nil, "eae_prk", dh)
+	sharedSecret, err := hpkeLabeledExpand(kem.hash, kemSuiteID, eaePRK, "shared_secret", append(enc, pubKey.Bytes()...), kem.hash.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	// key schedule of the base mode, without PSK
+	suiteID := binary.BigEndian.AppendUint16([]byte("HPKE"), kem.id)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, hpkeKDFHKDFSHA256)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, hpkeAEADAES128GCM)
+	keyScheduleContext := []byte{hpkeModeBase}
+	keyScheduleContext = append(keyScheduleContext, hpkeLabeledExtract(crypto.SHA256, suiteID, nil, "psk_id_hash", nil)...)
+	keyScheduleContext = append(keyScheduleContext, hpkeLabeledExtract(crypto.SHA256, suiteID, nil, "info_hash", info)...)
+	secret := hpkeLabeledExtract(crypto.SHA256, suiteID, sharedSecret, "secret", nil)
+	key, err := hpkeLabeledExpand(crypto.SHA256, suiteID, secret, "key", keyScheduleContext, 16)
+	if err != nil {
+		return nil, err
+	}
+	baseNonce, err := hpkeLabeledExpand(crypto.SHA256, suiteID, secret, "base_nonce", keyScheduleContext, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HPKE cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HPKE cipher: %w", err)
+	}
+
+	return aead.Seal(enc, baseNonce, plaintext, nil), nil
+}
+
+func hpkeLabeledExtract(hash crypto.Hash, suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := append([]byte("HPKE-v1"), suiteID...)
+	labeledIKM = append(append(labeledIKM, label...), ikm...)
+	return hkdf.Extract(hash.New, labeledIKM, salt)
+}
+
+func hpkeLabeledExpand(hash crypto.Hash, suiteID, prk []byte, label string, info []byte, length int) ([]byte, error) {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(append(labeledInfo, "HPKE-v1"...), suiteID...)
+	labeledInfo = append(append(labeledInfo, label...), info...)
+
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(hash.New, prk, labeledInfo), out); err != nil {
+		return nil, fmt.Errorf("failed HPKE key derivation: %w", err)
+	}
+	return out, nil
+}
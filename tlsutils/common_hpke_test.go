@@ -0,0 +1,78 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"encoding/hex"
+	"testing"
+)
+
+// testHex decodes a hex test vector.
+func testHex(t *testing.T, s string) []byte {
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid test vector %q: %s", s, err)
+	}
+	return decoded
+}
+
+// testAESGCM returns the AES-GCM cipher of key.
+func testAESGCM(t *testing.T, key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func TestHPKESealRFC9180(t *testing.T) {
+	// RFC 9180 appendix A.1.1: DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM in base mode
+	info := testHex(t, "4f6465206f6e2061204772656369616e2055726e")
+	skEm := testHex(t, "52c4a758a802cd8b936eceea314432798d5baf2d7e9235dc084ab1b9cfa2f736")
+	pkEm := testHex(t, "37fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431")
+	skRm := testHex(t, "4612c550263fc8ad58375df3f557aac531d26850903e55a9f23f21d8534e8ac8")
+	key := testHex(t, "4531685d41d65f03dc48f6b8302c05b0")
+	baseNonce := testHex(t, "56d890e5accaaf011cff4b7d")
+	pt := testHex(t, "4265617574792069732074727574682c20747275746820626561757479")
+	// first encryption of the vector, with the aad "Count-0"
+	ct := testHex(t, "f938558b5d72f1a23810b4be2ab4f84331acc02fc97babc53a52ae8218a355a96d8770ac83d07bea87e13c512a")
+
+	ephemeral, err := ecdh.X25519().NewPrivateKey(skEm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := ecdh.X25519().NewPrivateKey(skRm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead := testAESGCM(t, key)
+	if opened, err := aead.Open(nil, baseNonce, ct, []byte("Count-0")); err != nil || !bytes.Equal(opened, pt) {
+		t.Fatalf("the key and base_nonce of the vector do not open its ciphertext: %v", err)
+	}
+
+	sealed, err := hpkeSealWithEphemeral(recipient.PublicKey(), ephemeral, info, pt)
+	if err != nil {
+		t.Fatalf("seal failed: %s", err)
+	}
+	if !bytes.Equal(sealed[:len(pkEm)], pkEm) {
+		t.Errorf("expected the encapsulated key %x, got %x", pkEm, sealed[:len(pkEm)])
+	}
+	// hpkeSeal has an empty aad, so only the key schedule is shared with the vector ciphertext
+	if want := aead.Seal(nil, baseNonce, pt, nil); !bytes.Equal(sealed[len(pkEm):], want) {
+		t.Errorf("expected the ciphertext %x of the vector key and nonce, got %x", want, sealed[len(pkEm):])
+	}
+
+	other, err := hpkeSealWithEphemeral(recipient.PublicKey(), ephemeral, []byte("other info"), pt)
+	if err != nil {
+		t.Fatalf("seal failed: %s", err)
+	}
+	if bytes.Equal(other, sealed) {
+		t.Errorf("expected the info to change the key schedule")
+	}
+}
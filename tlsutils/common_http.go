@@ -0,0 +1,135 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPTimeout is used by the HTTP clients of resources talking to remote services.
+const defaultHTTPTimeout = 30 * time.Second
+
+// newHTTPClient returns an *http.Client trusting the given CA certificates in PEM format,
+// in addition to the system roots. An empty caCertsPEM keeps only the system roots.
+func newHTTPClient(caCertsPEM string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := newTLSClientConfig(caCertsPEM, "", "")
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: defaultHTTPTimeout}, nil
+}
+
+// newTLSClientConfig returns a *tls.Config trusting the given CA certificates in PEM format in addition to the system
+// roots, and presenting the given client certificate and key in PEM format unless clientCertPEM is empty.
+// It is nil when both caCertsPEM and clientCertPEM are empty.
+func newTLSClientConfig(caCertsPEM, clientCertPEM, clientKeyPEM string) (*tls.Config, error) {
+	if caCertsPEM == "" && clientCertPEM == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{}
+
+	if caCertsPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCertsPEM)) {
+			return nil, fmt.Errorf("no valid certificate found in CA certificates PEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if clientCertPEM != "" {
+		clientCert, err := tls.X509KeyPair([]byte(clientCertPEM), []byte(clientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// httpStatusError is returned by doRequest and doJSONRequest when the remote service answers with a non-2xx status.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// doJSONRequest sends reqBody (if not nil) marshalled as JSON and decodes the JSON response into respBody (if not nil).
+func doJSONRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, reqBody, respBody interface{}) error {
+	requestHeaders := map[string]string{"Accept": "application/json"}
+	for name, value := range headers {
+		requestHeaders[name] = value
+	}
+
+	var reqJSON []byte
+	if reqBody != nil {
+		var err error
+		reqJSON, err = json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		requestHeaders["Content-Type"] = "application/json"
+	}
+
+	respBytes, err := doRequest(ctx, client, method, url, requestHeaders, reqJSON)
+	if err != nil {
+		return err
+	}
+
+	if respBody != nil && len(respBytes) > 0 {
+		if err = json.Unmarshal(respBytes, respBody); err != nil {
+			return fmt.Errorf("failed to decode response of %s %s: %w", method, url, err)
+		}
+	}
+
+	return nil
+}
+
+// doRequest sends reqBody (if not nil) and returns the body of the response, or an *httpStatusError for non-2xx statuses.
+func doRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, reqBody []byte) ([]byte, error) {
+	var body io.Reader
+	if reqBody != nil {
+		body = bytes.NewReader(reqBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s %s: %w", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %w", method, url, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: string(respBytes)}
+	}
+
+	return respBytes, nil
+}
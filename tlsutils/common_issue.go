@@ -0,0 +1,809 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+// keyUsages maps the allowed_uses names to x509 key usages, with the names of the hashicorp/tls provider.
+var keyUsages = map[string]x509.KeyUsage{
+	"digital_signature":  x509.KeyUsageDigitalSignature,
+	"content_commitment": x509.KeyUsageContentCommitment,
+	"key_encipherment":   x509.KeyUsageKeyEncipherment,
+	"data_encipherment":  x509.KeyUsageDataEncipherment,
+	"key_agreement":      x509.KeyUsageKeyAgreement,
+	"cert_signing":       x509.KeyUsageCertSign,
+	"crl_signing":        x509.KeyUsageCRLSign,
+	"encipher_only":      x509.KeyUsageEncipherOnly,
+	"decipher_only":      x509.KeyUsageDecipherOnly,
+}
+
+// extKeyUsages maps the allowed_uses names to x509 extended key usages, with the names of the hashicorp/tls provider.
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any_extended":                  x509.ExtKeyUsageAny,
+	"server_auth":                   x509.ExtKeyUsageServerAuth,
+	"client_auth":                   x509.ExtKeyUsageClientAuth,
+	"code_signing":                  x509.ExtKeyUsageCodeSigning,
+	"email_protection":              x509.ExtKeyUsageEmailProtection,
+	"ipsec_end_system":              x509.ExtKeyUsageIPSECEndSystem,
+	"ipsec_tunnel":                  x509.ExtKeyUsageIPSECTunnel,
+	"ipsec_user":                    x509.ExtKeyUsageIPSECUser,
+	"timestamping":                  x509.ExtKeyUsageTimeStamping,
+	"ocsp_signing":                  x509.ExtKeyUsageOCSPSigning,
+	"microsoft_server_gated_crypto": x509.ExtKeyUsageMicrosoftServerGatedCrypto,
+	"netscape_server_gated_crypto":  x509.ExtKeyUsageNetscapeServerGatedCrypto,
+}
+
+// supportedAllowedUsesStr returns the names accepted by allowed_uses.
+func supportedAllowedUsesStr() []string {
+	supported := make([]string, 0, len(keyUsages)+len(extKeyUsages))
+	for name := range keyUsages {
+		supported = append(supported, name)
+	}
+	for name := range extKeyUsages {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return supported
+}
+
+// supportedExtKeyUsagesStr returns the allowed_uses names of the extended key usages.
+func supportedExtKeyUsagesStr() []string {
+	supported := make([]string, 0, len(extKeyUsages))
+	for name := range extKeyUsages {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return supported
+}
+
+// certificateSubjectSchema returns the schema of a subject block.
+func certificateSubjectSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Description: description,
+		Type:        schema.TypeList,
+		Optional:    true,
+		ForceNew:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"common_name": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"organization": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"organizational_unit": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"street_address": {
+					Type:     schema.TypeList,
+					Optional: true,
+					ForceNew: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"locality": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"province": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"country": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"postal_code": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+				"serial_number": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ForceNew: true,
+				},
+			},
+		},
+	}
+}
+
+// certificateIssueSchema returns the attributes describing the certificates issued by a resource:
+// subject, subject alternative names, validity and allowed uses.
+func certificateIssueSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"subject": certificateSubjectSchema("subject of the certificate."),
+		"dns_names": {
+			Description: "DNS names the certificate is valid for. Unicode names are converted to A-labels (punycode); a wildcard must be the whole leftmost label.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateFunc:     validateDNSName,
+				DiffSuppressFunc: suppressReorderedList(normalizeDNSName),
+			},
+		},
+		"ip_addresses": {
+			Description: "IP addresses the certificate is valid for.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateFunc:     validation.IsIPAddress,
+				DiffSuppressFunc: suppressReorderedList(normalizeIPAddress),
+			},
+		},
+		"uris": {
+			Description: "URIs the certificate is valid for.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				DiffSuppressFunc: suppressReorderedList(normalizeNothing),
+			},
+		},
+		"email_addresses": {
+			Description: "email addresses the certificate is valid for.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				DiffSuppressFunc: suppressReorderedList(normalizeEmailAddress),
+			},
+		},
+		"no_well_defined_expiration": {
+			Description:   "issue the certificate without a well-defined expiration date, valid until 99991231235959Z like the IEEE 802.1AR IDevID certificates, instead of for the validity period.",
+			Type:          schema.TypeBool,
+			Optional:      true,
+			ForceNew:      true,
+			ConflictsWith: []string{"validity_period_hours", "validity_period"},
+		},
+		"truncate_to_ca_expiry": {
+			Description: "cap the validity of the certificate to the not after of the CA certificate, instead of failing when it would outlive it.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"allowed_uses": {
+			Description: "key usages and extended key usages allowed for the certificate, e.g. `digital_signature` or `server_auth`.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(supportedAllowedUsesStr(), false),
+			},
+		},
+		"validation_preset": {
+			Description:      "checks the issued certificate must pass: `none`, `rfc5280-strict` (RFC 5280 profile) or `cabf-br` (CA/Browser Forum Baseline Requirements for TLS servers, including `rfc5280-strict`; wildcard DNS names cannot be combined with IP addresses).",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			Default:          "none",
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedValidationPresetsStr(), false)),
+		},
+		"microsoft_template": {
+			Description: "Active Directory Certificate Services template the certificate is issued from, for the AD CS auto-enrollment clients. At least one of `name` or `oid` must be set.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Description: "name of the template, e.g. `WebServer`, added as the V1 certificate type extension (szOID_ENROLL_CERTTYPE_EXTENSION).",
+						Type:        schema.TypeString,
+						Optional:    true,
+						ForceNew:    true,
+					},
+					"oid": {
+						Description:  "OID of the template, added with its versions as the V2 certificate template extension (szOID_CERTIFICATE_TEMPLATE).",
+						Type:         schema.TypeString,
+						Optional:     true,
+						ForceNew:     true,
+						ValidateFunc: validateOID,
+					},
+					"major_version": {
+						Description: "major version of the template, with `oid`.",
+						Type:        schema.TypeInt,
+						Optional:    true,
+						ForceNew:    true,
+						Default:     100,
+					},
+					"minor_version": {
+						Description: "minor version of the template, with `oid`.",
+						Type:        schema.TypeInt,
+						Optional:    true,
+						ForceNew:    true,
+						Default:     0,
+					},
+				},
+			},
+		},
+		"validity_start_time": {
+			Description: "time after which the certificate is valid, in RFC3339.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"validity_end_time": {
+			Description: "time until which the certificate is valid, in RFC3339.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"user_principal_name": {
+			Description: "Active Directory user principal name, e.g. `user@corp.example.com`, added to the subject alternative names as an otherName.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"profile": {
+			Description:      "preset of usages added to `allowed_uses`: `tls_server` (`digital_signature` and Server Authentication), `tls_client` (`digital_signature` and Client Authentication), `smartcard_logon` (Windows smart card logon: `digital_signature` and `key_encipherment`, Client Authentication and Smart Card Logon extended key usages; requires `user_principal_name`), `ocsp_responder` (delegated OCSP responder: `digital_signature`, OCSP Signing extended key usage and the `id-pkix-ocsp-nocheck` extension) or `devid` (IEEE 802.1AR IDevID or LDevID device identity: `digital_signature`; requires the `serial_number` of the subject, usually with `hardware_module_name`), or the `name` of a `certificate_profile` of the provider. The validity and subject attributes of a provider profile are defaults of the certificate; changing the profile does not issue the certificate again.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotEmpty),
+		},
+		"hardware_module_name": hardwareModuleNameSchema(true),
+		"ski_method": {
+			Description:      "derivation of the subject key identifier from the public key: `sha1` (RFC 5280 section 4.2.1.2 method 1), `sha256_truncated` (SHA-256 truncated to 160 bits, RFC 7093 section 2 method 1) or `none`, leaving the extension out of end-entity certificates. Defaults to `none`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"sha1", "sha256_truncated", "none"}, false)),
+			ConflictsWith:    []string{"subject_key_id"},
+		},
+		"subject_key_id": {
+			Description:      "hex encoded subject key identifier pinned instead of derived with `ski_method`, e.g. to match the identifier an existing PKI computed.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(`^([0-9a-fA-F]{2})+$`), "expected hex encoded bytes")),
+			ConflictsWith:    []string{"ski_method"},
+		},
+		"authority_key_id": {
+			Description:      "authority key identifier of the certificate: `key_id` holds the subject key identifier of the CA, `key_id_issuer_serial` adds the issuer name and serial number of the CA certificate, for validators identifying the CA certificate by them. Defaults to `key_id`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"key_id", "key_id_issuer_serial"}, false)),
+		},
+	}
+	s["extension_criticality"] = extensionCriticalitySchema("the certificate", "subject_alt_name", "key_usage", "extended_key_usage", "basic_constraints")
+	s["serial_number_prefix"] = serialNumberPrefixSchema("serial numbers of the certificates")
+	for name, attribute := range crlDistributionSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range certificateValiditySchema("", "the certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range validityPeriodSchema("", "number of hours, after initial issuing, that the certificate will remain valid for. Defaults to the validity of the `profile`, then to the default validity of the provider. Not needed with `no_well_defined_expiration`.") {
+		s[name] = attribute
+	}
+
+	return s
+}
+
+// certificateTemplate builds a certificate template from the certificateIssueSchema attributes,
+// valid from now and with a random serial number.
+func certificateTemplate(d *schema.ResourceData, m interface{}) (*x509.Certificate, error) {
+	serialNumber, err := prefixedSerialNumber(d.Get("serial_number_prefix").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	profile := certificateProfile{}
+	if name := d.Get("profile").(string); name != "" {
+		if profile, err = certificateProfileOf(name, m); err != nil {
+			return nil, err
+		}
+	}
+
+	notBefore := now(d, m).UTC().Truncate(time.Second)
+	notAfter, err := issueNotAfter(d, m, notBefore, profile)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+	}
+
+	if subjects := d.Get("subject").([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+		template.Subject = certificateSubject(subjects[0].(map[string]interface{}))
+	}
+	template.Subject = subjectWithDefaults(fillSubjectDefaults(template.Subject, profile.subject), m)
+
+	for _, name := range d.Get("dns_names").([]interface{}) {
+		ascii, err := dnsNameToASCII(name.(string))
+		if err != nil {
+			return nil, err
+		}
+		template.DNSNames = append(template.DNSNames, ascii)
+	}
+	for _, address := range d.Get("ip_addresses").([]interface{}) {
+		ip := net.ParseIP(address.(string))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", address)
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+	for _, rawURI := range d.Get("uris").([]interface{}) {
+		uri, err := url.Parse(rawURI.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %s: %w", rawURI, err)
+		}
+		template.URIs = append(template.URIs, uri)
+	}
+	for _, email := range d.Get("email_addresses").([]interface{}) {
+		template.EmailAddresses = append(template.EmailAddresses, email.(string))
+	}
+
+	for _, use := range d.Get("allowed_uses").([]interface{}) {
+		if usage, ok := keyUsages[use.(string)]; ok {
+			template.KeyUsage |= usage
+		}
+		if usage, ok := extKeyUsages[use.(string)]; ok {
+			template.ExtKeyUsage = append(template.ExtKeyUsage, usage)
+		}
+	}
+
+	if name := d.Get("profile").(string); name != "" {
+		if err = applyNamedCertificateProfile(template, name, profile, d.Get("user_principal_name").(string)); err != nil {
+			return nil, err
+		}
+	}
+	if err = setOtherNames(template, d.Get("user_principal_name").(string), d.Get("hardware_module_name").([]interface{})); err != nil {
+		return nil, err
+	}
+
+	if err = crlDistributionExtensions(d, template); err != nil {
+		return nil, err
+	}
+
+	if templates := d.Get("microsoft_template").([]interface{}); len(templates) > 0 && templates[0] != nil {
+		extensions, err := microsoftTemplateExtensions(templates[0].(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, extensions...)
+	}
+
+	return template, nil
+}
+
+// issueNotAfter returns the not after of the certificates issued at notBefore, from the validity_period_hours or
+// validity_period of d, the validity of profile or the default validity of the provider, or noWellDefinedExpiration when the
+// no_well_defined_expiration of d is set.
+func issueNotAfter(d *schema.ResourceData, m interface{}, notBefore time.Time, profile certificateProfile) (time.Time, error) {
+	if d.Get("no_well_defined_expiration").(bool) {
+		return noWellDefinedExpiration, nil
+	}
+
+	if validityPeriod, ok := configuredValidityPeriod(d, "validity_period", "validity_period_hours"); ok {
+		return notBefore.Add(validityPeriod), nil
+	}
+	if profile.validityPeriod > 0 {
+		return notBefore.Add(profile.validityPeriod), nil
+	}
+	if meta, ok := m.(*providerMeta); ok && meta.defaultValidityPeriod > 0 {
+		return notBefore.Add(meta.defaultValidityPeriod), nil
+	}
+
+	return time.Time{}, fmt.Errorf("validity_period_hours or validity_period must be set, in the resource, by the profile or as default of the provider, unless no_well_defined_expiration is set")
+}
+
+var (
+	// oidMicrosoftCertTypeExtension is szOID_ENROLL_CERTTYPE_EXTENSION, the V1 template name as a BMPString.
+	oidMicrosoftCertTypeExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2}
+	// oidMicrosoftCertificateTemplate is szOID_CERTIFICATE_TEMPLATE, the V2 template OID and versions.
+	oidMicrosoftCertificateTemplate = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 7}
+)
+
+// microsoftTemplateExtensions encodes the extensions of a microsoft_template block.
+func microsoftTemplateExtensions(msTemplate map[string]interface{}) ([]pkix.Extension, error) {
+	extensions := make([]pkix.Extension, 0, 2)
+
+	if name := msTemplate["name"].(string); name != "" {
+		bmp := make([]byte, 0, 2*len(name))
+		for _, unit := range utf16.Encode([]rune(name)) {
+			bmp = append(bmp, byte(unit>>8), byte(unit))
+		}
+		// BMPString, UTF-16 big endian, has no encoding/asn1 tag constant
+		value, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: 30, Bytes: bmp})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode microsoft_template name: %w", err)
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidMicrosoftCertTypeExtension, Value: value})
+	}
+
+	if rawOID := msTemplate["oid"].(string); rawOID != "" {
+		oid, err := parseOID(rawOID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid microsoft_template oid: %w", err)
+		}
+		value, err := asn1.Marshal(struct {
+			TemplateID   asn1.ObjectIdentifier
+			MajorVersion int
+			MinorVersion int
+		}{oid, msTemplate["major_version"].(int), msTemplate["minor_version"].(int)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode microsoft_template oid: %w", err)
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidMicrosoftCertificateTemplate, Value: value})
+	}
+
+	if len(extensions) == 0 {
+		return nil, fmt.Errorf("microsoft_template must set at least one of name or oid")
+	}
+
+	return extensions, nil
+}
+
+// certificateSubject converts a subject block to a pkix.Name.
+func certificateSubject(subject map[string]interface{}) pkix.Name {
+	name := pkix.Name{}
+
+	stringList := func(key string) []string {
+		if value, ok := subject[key].(string); ok && value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+
+	name.CommonName, _ = subject["common_name"].(string)
+	name.SerialNumber, _ = subject["serial_number"].(string)
+	name.Organization = stringList("organization")
+	name.OrganizationalUnit = stringList("organizational_unit")
+	name.Locality = stringList("locality")
+	name.Province = stringList("province")
+	name.Country = stringList("country")
+	name.PostalCode = stringList("postal_code")
+	if streetAddress, ok := subject["street_address"].([]interface{}); ok {
+		for _, line := range streetAddress {
+			name.StreetAddress = append(name.StreetAddress, line.(string))
+		}
+	}
+
+	return name
+}
+
+// randomSerialNumber returns a random positive 128 bits serial number.
+func randomSerialNumber() (*big.Int, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	return serialNumber, nil
+}
+
+// maxSerialNumberPrefixBytes leaves prefixedSerialNumber at least 88 random bits in the 20 octets of RFC 5280,
+// above the 64 bits required by the CA/Browser Forum.
+const maxSerialNumberPrefixBytes = 8
+
+// serialNumberPrefixSchema returns the ForceNew serial_number_prefix attribute of the resources issuing certificates,
+// embedding an issuer ID in what.
+func serialNumberPrefixSchema(what string) *schema.Schema {
+	return &schema.Schema{
+		Description:      fmt.Sprintf("hex encoded issuer ID of 1 to %d bytes the %s start with, followed by random bytes, to tell the certificates of different resources apart in logs. The first byte must be between `01` and `7f`, serial numbers being positive. Defaults to fully random serial numbers.", maxSerialNumberPrefixBytes, what),
+		Type:             schema.TypeString,
+		Optional:         true,
+		ForceNew:         true,
+		ValidateDiagFunc: validation.ToDiagFunc(validateSerialNumberPrefix),
+	}
+}
+
+// validateSerialNumberPrefix is a schema.SchemaValidateFunc checking the value is accepted by prefixedSerialNumber.
+func validateSerialNumberPrefix(i interface{}, k string) ([]string, []error) {
+	value, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	prefix, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: expected hex encoded bytes: %w", k, err)}
+	}
+	if len(prefix) == 0 || len(prefix) > maxSerialNumberPrefixBytes {
+		return nil, []error{fmt.Errorf("%s: expected 1 to %d bytes, got %d", k, maxSerialNumberPrefixBytes, len(prefix))}
+	}
+	if prefix[0] == 0 || prefix[0] >= 0x80 {
+		return nil, []error{fmt.Errorf("%s: the first byte must be between 01 and 7f, got %02x", k, prefix[0])}
+	}
+
+	return nil, nil
+}
+
+// prefixedSerialNumber returns a serial number made of the hex encoded prefix followed by 16 random bytes, fewer
+// for long prefixes so that it fits in 20 octets. It is randomSerialNumber when prefix is empty.
+func prefixedSerialNumber(prefix string) (*big.Int, error) {
+	if prefix == "" {
+		return randomSerialNumber()
+	}
+
+	serial, err := hex.DecodeString(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid serial number prefix %q: %w", prefix, err)
+	}
+	random := make([]byte, min(16, 19-len(serial)))
+	if _, err = rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	return new(big.Int).SetBytes(append(serial, random...)), nil
+}
+
+// caExpiryNotAfter returns notAfter when caCert outlives it, the not after of caCert when truncate is set,
+// and fails otherwise, chains outliving their issuer being rejected by clients once it expires. It always fails
+// when caCert expired by notBefore, truncating would invert the validity of the certificate.
+func caExpiryNotAfter(caCert *x509.Certificate, notBefore, notAfter time.Time, truncate bool) (time.Time, error) {
+	if !caCert.NotAfter.After(notBefore) {
+		return notAfter, fmt.Errorf("CA certificate expired at %s, before the certificate would be valid from %s",
+			caCert.NotAfter.UTC().Format(time.RFC3339), notBefore.UTC().Format(time.RFC3339))
+	}
+	if !notAfter.After(caCert.NotAfter) {
+		return notAfter, nil
+	}
+	if !truncate {
+		return notAfter, fmt.Errorf("certificate would be valid until %s, after the CA certificate expires at %s; shorten the validity period or set truncate_to_ca_expiry",
+			notAfter.UTC().Format(time.RFC3339), caCert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return caCert.NotAfter, nil
+}
+
+// parseCertificateAuthority parses a CA certificate and its private key, checking they match, that the certificate
+// is allowed to sign certificates and that it is valid at the given time.
+func parseCertificateAuthority(caCertPEM, caPrivateKeyPEM string, at time.Time) (*x509.Certificate, crypto.PrivateKey, error) {
+	caCert, err := parsePEMCertificate([]byte(caCertPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse CA certificate: %w", err)
+	}
+
+	caKey, _, err := parsePrivateKeyPEM([]byte(caPrivateKeyPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA private key PEM: %w", err)
+	}
+
+	if !privateKeyMatchesCertificate(caKey, caCert) {
+		return nil, nil, fmt.Errorf("CA private key does not match the CA certificate")
+	}
+	if !caCert.IsCA {
+		return nil, nil, fmt.Errorf("CA certificate is not a CA (basic constraints CA:FALSE)")
+	}
+	if caCert.KeyUsage != 0 && caCert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, nil, fmt.Errorf("CA certificate key usage does not allow certificate signing")
+	}
+	if at.Before(caCert.NotBefore) {
+		return nil, nil, fmt.Errorf("CA certificate is not valid yet: valid from %s, now is %s",
+			caCert.NotBefore.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339))
+	}
+	if at.After(caCert.NotAfter) {
+		return nil, nil, fmt.Errorf("CA certificate expired at %s, now is %s",
+			caCert.NotAfter.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339))
+	}
+
+	return caCert, caKey, nil
+}
+
+// issueOptions are the certificateIssueSchema attributes applied by issueCertificateWithOptions.
+type issueOptions struct {
+	validationPreset     string
+	skiMethod            string
+	subjectKeyID         string
+	authorityKeyID       string
+	extensionCriticality map[string]interface{}
+}
+
+// issueOptionsFromResourceData reads the issueOptions of d. The attributes missing from the schema of d are left
+// empty, like subject_key_id in the resources issuing certificates for several keys.
+func issueOptionsFromResourceData(d resourceAttributes) issueOptions {
+	options := issueOptions{}
+	options.validationPreset, _ = d.Get("validation_preset").(string)
+	options.skiMethod, _ = d.Get("ski_method").(string)
+	options.subjectKeyID, _ = d.Get("subject_key_id").(string)
+	options.authorityKeyID, _ = d.Get("authority_key_id").(string)
+	options.extensionCriticality, _ = d.Get("extension_criticality").(map[string]interface{})
+
+	return options
+}
+
+// issueCertificate signs template for pubKey with the CA and checks the certificate against the validation_preset of d,
+// after applying the key identifiers and extension_criticality of d. It returns the certificate in PEM format.
+func issueCertificate(d *schema.ResourceData, template *x509.Certificate, pubKey crypto.PublicKey, caCert *x509.Certificate, caKey crypto.PrivateKey) (string, diag.Diagnostics) {
+	return issueCertificateWithOptions(issueOptionsFromResourceData(d), template, pubKey, caCert, caKey)
+}
+
+// issueCertificateWithOptions is issueCertificate with the options read beforehand, for the resources issuing
+// certificates concurrently.
+func issueCertificateWithOptions(options issueOptions, template *x509.Certificate, pubKey crypto.PublicKey, caCert *x509.Certificate, caKey crypto.PrivateKey) (string, diag.Diagnostics) {
+	if err := setSubjectKeyID(template, pubKey, options.subjectKeyID, options.skiMethod); err != nil {
+		return "", diag.FromErr(err)
+	}
+	if err := setAuthorityKeyID(template, caCert, options.authorityKeyID); err != nil {
+		return "", diag.FromErr(err)
+	}
+	if err := setExtensionCriticality(template, options.extensionCriticality); err != nil {
+		return "", diag.FromErr(err)
+	}
+
+	certPem, err := signCertificate(template, pubKey, caCert, caKey)
+	if err != nil {
+		return "", diag.FromErr(err)
+	}
+
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return "", diag.FromErr(fmt.Errorf("unable to parse issued certificate: %w", err))
+	}
+
+	if diags := validateCertificatePreset(options.validationPreset, cert); diags.HasError() {
+		return "", diags
+	}
+
+	return certPem, nil
+}
+
+// signCertificate signs template for pubKey with the CA and returns the certificate in PEM format. Unless template is
+// self-signed, its path length and extended key usages must be allowed by the ones of the CA, and its subject
+// alternative names by the name constraints of the CA.
+func signCertificate(template *x509.Certificate, pubKey crypto.PublicKey, caCert *x509.Certificate, caKey crypto.PrivateKey) (string, error) {
+	if caCert != template {
+		if err := checkIssuerChaining(caCert, template); err != nil {
+			return "", err
+		}
+		if err := checkNameConstraints(caCert, template); err != nil {
+			return "", err
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, pubKey, caKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: der})), nil
+}
+
+// subjectKeyID returns the subject key identifier of pubKey, the SHA-1 of its subjectPublicKey like crypto/x509.
+func subjectKeyID(pubKey crypto.PublicKey) ([]byte, error) {
+	return subjectKeyIDWithMethod(pubKey, "sha1")
+}
+
+// subjectKeyIDWithMethod returns the subject key identifier of pubKey derived with an ski_method: the SHA-1 of its
+// subjectPublicKey (RFC 5280 section 4.2.1.2 method 1) or its SHA-256 truncated to 160 bits (RFC 7093 section 2
+// method 1).
+func subjectKeyIDWithMethod(pubKey crypto.PublicKey, method string) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %w", err)
+	}
+
+	switch method {
+	case "sha1":
+		keyID := sha1.Sum(spki.PublicKey.Bytes)
+		return keyID[:], nil
+	case "sha256_truncated":
+		keyID := sha256.Sum256(spki.PublicKey.Bytes)
+		return keyID[:sha1.Size], nil
+	default:
+		return nil, fmt.Errorf("unsupported subject key identifier method %q", method)
+	}
+}
+
+// oidExtensionAuthorityKeyID is the authority key identifier extension of RFC 5280 section 4.2.1.1.
+var oidExtensionAuthorityKeyID = asn1.ObjectIdentifier{2, 5, 29, 35}
+
+// authorityKeyIDWithIssuer encodes the authority key identifier of the certificates issued by caCert in full: the
+// subject key identifier of caCert, when it has one, with the issuer name and serial number of caCert, which
+// crypto/x509 cannot generate.
+func authorityKeyIDWithIssuer(caCert *x509.Certificate) (pkix.Extension, error) {
+	// the Name of directoryName [4] is a CHOICE, so explicitly tagged
+	directoryName, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: caCert.RawIssuer})
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode authority key identifier: %w", err)
+	}
+	der, err := asn1.Marshal(caCert.SerialNumber)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode authority key identifier: %w", err)
+	}
+	var serialNumber asn1.RawValue
+	if _, err = asn1.Unmarshal(der, &serialNumber); err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode authority key identifier: %w", err)
+	}
+
+	// keyIdentifier [0], authorityCertIssuer [1] and authorityCertSerialNumber [2], implicitly tagged
+	fields := make([]asn1.RawValue, 0, 3)
+	if len(caCert.SubjectKeyId) > 0 {
+		fields = append(fields, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: caCert.SubjectKeyId})
+	}
+	fields = append(fields,
+		asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: directoryName},
+		asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: serialNumber.Bytes},
+	)
+	value, err := asn1.Marshal(fields)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode authority key identifier: %w", err)
+	}
+
+	return pkix.Extension{Id: oidExtensionAuthorityKeyID, Value: value}, nil
+}
+
+// setAuthorityKeyID adds the full authority key identifier to the extensions of template when form, an
+// authority_key_id value, asks for it. crypto/x509 otherwise fills the key identifier alone from caCert.
+func setAuthorityKeyID(template *x509.Certificate, caCert *x509.Certificate, form string) error {
+	if form != "key_id_issuer_serial" {
+		return nil
+	}
+
+	extension, err := authorityKeyIDWithIssuer(caCert)
+	if err != nil {
+		return err
+	}
+	template.ExtraExtensions = replaceExtension(template.ExtraExtensions, extension)
+	return nil
+}
+
+// setSubjectKeyID sets the subject key identifier of template for pubKey to pinned, a subject_key_id value, or
+// derives it with method, an ski_method value. crypto/x509 leaves it out of end-entity certificates otherwise.
+func setSubjectKeyID(template *x509.Certificate, pubKey crypto.PublicKey, pinned, method string) error {
+	if pinned != "" {
+		keyID, err := hex.DecodeString(pinned)
+		if err != nil {
+			return fmt.Errorf("invalid subject_key_id: %w", err)
+		}
+		template.SubjectKeyId = keyID
+		return nil
+	}
+
+	if method == "" || method == "none" {
+		return nil
+	}
+	keyID, err := subjectKeyIDWithMethod(pubKey, method)
+	if err != nil {
+		return err
+	}
+	template.SubjectKeyId = keyID
+	return nil
+}
@@ -0,0 +1,242 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCertificateAuthority(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, ca, caKey)
+	signer, signerKey := testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "CRL signer"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCRLSign,
+	}, ca, caKey)
+
+	toPEM := func(cert *x509.Certificate, key interface{}) (string, string) {
+		keyPem, err := privateKeyToPEM(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return certificateToPEM(cert), keyPem
+	}
+	caPem, caKeyPem := toPEM(ca, caKey)
+	leafPem, leafKeyPem := toPEM(leaf, leafKey)
+	signerPem, signerKeyPem := toPEM(signer, signerKey)
+
+	for name, test := range map[string]struct {
+		cert string
+		key  string
+		at   time.Time
+		err  string
+	}{
+		"valid":          {cert: caPem, key: caKeyPem, at: ca.NotBefore.Add(time.Minute)},
+		"last second":    {cert: caPem, key: caKeyPem, at: ca.NotAfter},
+		"not yet":        {cert: caPem, key: caKeyPem, at: ca.NotBefore.Add(-time.Minute), err: "CA certificate is not valid yet"},
+		"expired":        {cert: caPem, key: caKeyPem, at: ca.NotAfter.Add(time.Minute), err: "CA certificate expired"},
+		"key mismatch":   {cert: caPem, key: leafKeyPem, at: ca.NotBefore, err: "CA private key does not match the CA certificate"},
+		"not a CA":       {cert: leafPem, key: leafKeyPem, at: leaf.NotBefore, err: "CA:FALSE"},
+		"no keyCertSign": {cert: signerPem, key: signerKeyPem, at: signer.NotBefore, err: "does not allow certificate signing"},
+		"invalid cert":   {cert: "invalid", key: caKeyPem, at: ca.NotBefore, err: "unable to parse CA certificate"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cert, _, err := parseCertificateAuthority(test.cert, test.key, test.at)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !cert.Equal(ca) {
+					t.Errorf("expected the CA certificate")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// testSubjectPublicKey returns the subjectPublicKey bits of cert, which the subject key identifier methods hash.
+func testSubjectPublicKey(t *testing.T, cert *x509.Certificate) []byte {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		t.Fatalf("unable to parse the subject public key info: %s", err)
+	}
+	return spki.PublicKey.Bytes
+}
+
+func TestSubjectKeyIDWithMethod(t *testing.T) {
+	// crypto/x509 derives the subject key identifier of CA certificates with RFC 5280 method 1
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	keyID, err := subjectKeyIDWithMethod(caKey.Public(), "sha1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(keyID, ca.SubjectKeyId) {
+		t.Errorf("expected the subject key identifier of crypto/x509 %x, got %x", ca.SubjectKeyId, keyID)
+	}
+
+	keyID, err = subjectKeyIDWithMethod(caKey.Public(), "sha256_truncated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(testSubjectPublicKey(t, ca)); !bytes.Equal(keyID, sum[:20]) {
+		t.Errorf("expected the SHA-256 of the subject public key truncated to 160 bits %x, got %x", sum[:20], keyID)
+	}
+
+	if _, err = subjectKeyIDWithMethod(caKey.Public(), "md5"); err == nil || !strings.Contains(err.Error(), `unsupported subject key identifier method "md5"`) {
+		t.Errorf("expected an unsupported method error, got %v", err)
+	}
+}
+
+func TestAuthorityKeyIDWithIssuer(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	caCert, caKey := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	extension, err := authorityKeyIDWithIssuer(caCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, ExtraExtensions: []pkix.Extension{extension}}, caCert, caKey)
+
+	var aki struct {
+		KeyIdentifier             []byte        `asn1:"optional,tag:0"`
+		AuthorityCertIssuer       asn1.RawValue `asn1:"optional,tag:1"`
+		AuthorityCertSerialNumber *big.Int      `asn1:"optional,tag:2"`
+	}
+	extension, _ = findExtension(cert.Extensions, oidExtensionAuthorityKeyID)
+	if rest, err := asn1.Unmarshal(extension.Value, &aki); err != nil || len(rest) > 0 {
+		t.Fatalf("unable to parse the authority key identifier: %v", err)
+	}
+	var directoryName asn1.RawValue
+	if _, err = asn1.Unmarshal(aki.AuthorityCertIssuer.Bytes, &directoryName); err != nil {
+		t.Fatalf("unable to parse authorityCertIssuer: %s", err)
+	}
+
+	if !bytes.Equal(aki.KeyIdentifier, caCert.SubjectKeyId) || !bytes.Equal(cert.AuthorityKeyId, caCert.SubjectKeyId) {
+		t.Errorf("expected the key identifier %x, got %x", caCert.SubjectKeyId, aki.KeyIdentifier)
+	}
+	if directoryName.Class != asn1.ClassContextSpecific || directoryName.Tag != 4 || !bytes.Equal(directoryName.Bytes, caCert.RawIssuer) {
+		t.Errorf("expected authorityCertIssuer to be the directoryName of the CA issuer, got %+v", directoryName)
+	}
+	if aki.AuthorityCertSerialNumber == nil || aki.AuthorityCertSerialNumber.Cmp(caCert.SerialNumber) != 0 {
+		t.Errorf("expected authorityCertSerialNumber %s, got %s", caCert.SerialNumber, aki.AuthorityCertSerialNumber)
+	}
+	if err = cert.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("expected the certificate to be signed by the CA: %s", err)
+	}
+}
+
+func TestCAExpiryNotAfter(t *testing.T) {
+	caNotAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	caCert := &x509.Certificate{NotBefore: caNotAfter.AddDate(-10, 0, 0), NotAfter: caNotAfter}
+
+	for name, test := range map[string]struct {
+		notBefore time.Time
+		notAfter  time.Time
+		truncate  bool
+		want      time.Time
+		err       string
+	}{
+		"within":            {notBefore: caNotAfter.AddDate(0, -2, 0), notAfter: caNotAfter.AddDate(0, -1, 0), want: caNotAfter.AddDate(0, -1, 0)},
+		"outliving":         {notBefore: caNotAfter.AddDate(0, -1, 0), notAfter: caNotAfter.AddDate(0, 1, 0), err: "after the CA certificate expires"},
+		"truncated":         {notBefore: caNotAfter.AddDate(0, -1, 0), notAfter: caNotAfter.AddDate(0, 1, 0), truncate: true, want: caNotAfter},
+		"expired":           {notBefore: caNotAfter.AddDate(0, 1, 0), notAfter: caNotAfter.AddDate(0, 2, 0), err: "CA certificate expired"},
+		"expired truncated": {notBefore: caNotAfter.AddDate(0, 1, 0), notAfter: caNotAfter.AddDate(0, 2, 0), truncate: true, err: "CA certificate expired"},
+		"expiring":          {notBefore: caNotAfter, notAfter: caNotAfter.AddDate(0, 1, 0), truncate: true, err: "CA certificate expired"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := caExpiryNotAfter(caCert, test.notBefore, test.notAfter, test.truncate)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("expected not after %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPrefixedSerialNumber(t *testing.T) {
+	for name, test := range map[string]struct {
+		prefix string
+		length int
+	}{
+		"without prefix": {length: 16},
+		"one byte":       {prefix: "2a", length: 17},
+		"three bytes":    {prefix: "7f0001", length: 19},
+		"four bytes":     {prefix: "7f000001", length: 19},
+		"eight bytes":    {prefix: "0102030405060708", length: 19},
+	} {
+		t.Run(name, func(t *testing.T) {
+			first, err := prefixedSerialNumber(test.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := prefixedSerialNumber(test.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.Cmp(second) == 0 {
+				t.Errorf("expected random serial numbers, got %s twice", first)
+			}
+			if first.Sign() <= 0 {
+				t.Errorf("expected a positive serial number, got %s", first)
+			}
+			if test.prefix == "" {
+				return
+			}
+			if got := first.Bytes(); len(got) != test.length || hex.EncodeToString(got[:len(test.prefix)/2]) != test.prefix {
+				t.Errorf("expected %d bytes starting with %s, got %x", test.length, test.prefix, got)
+			}
+		})
+	}
+}
+
+func TestValidateSerialNumberPrefix(t *testing.T) {
+	for name, test := range map[string]struct {
+		value string
+		err   string
+	}{
+		"issuer ID":    {value: "0a2b"},
+		"max length":   {value: "7fffffffffffffff"},
+		"empty":        {value: "", err: "expected 1 to 8 bytes, got 0"},
+		"too long":     {value: "010203040506070809", err: "expected 1 to 8 bytes, got 9"},
+		"not hex":      {value: "issuer", err: "expected hex encoded bytes"},
+		"odd length":   {value: "abc", err: "expected hex encoded bytes"},
+		"leading zero": {value: "00ff", err: "the first byte must be between 01 and 7f, got 00"},
+		"negative":     {value: "80", err: "the first byte must be between 01 and 7f, got 80"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, errs := validateSerialNumberPrefix(test.value, "serial_number_prefix")
+			if test.err == "" {
+				if len(errs) > 0 {
+					t.Errorf("expected %q to be valid, got %v", test.value, errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.err) {
+				t.Errorf("expected error %q, got %v", test.err, errs)
+			}
+		})
+	}
+}
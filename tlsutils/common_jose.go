@@ -0,0 +1,201 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/crypto/pbkdf2"
+	"hash"
+	"strings"
+)
+
+// jwsAlgorithmForKey returns the JWS "alg" value used to sign with prvKey: ES256, ES384 and ES512
+// following the curve of ECDSA keys, RS256 for RSA keys and EdDSA for Ed25519 keys.
+func jwsAlgorithmForKey(prvKey crypto.PrivateKey) (string, error) {
+	switch k := prvKey.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return "ES256", nil
+		case 384:
+			return "ES384", nil
+		case 521:
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve: %s", k.Curve.Params().Name)
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+}
+
+// signCompactJWS signs payload with prvKey and returns the JWS in compact serialization,
+// as defined in [RFC 7515](https://datatracker.ietf.org/doc/html/rfc7515#section-7.1).
+// The "alg" member of header is set from the key.
+func signCompactJWS(prvKey crypto.PrivateKey, header map[string]interface{}, payload []byte) (string, error) {
+	alg, err := jwsAlgorithmForKey(prvKey)
+	if err != nil {
+		return "", err
+	}
+	protected := map[string]interface{}{"alg": alg}
+	for name, value := range header {
+		protected[name] = value
+	}
+	headerJSON, err := json.Marshal(protected)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWS header: %w", err)
+	}
+	signingInput := base64URLEncode(headerJSON) + "." + base64URLEncode(payload)
+
+	var signature []byte
+	switch k := prvKey.(type) {
+	case *ecdsa.PrivateKey:
+		hashFunc := map[string]crypto.Hash{"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512}[alg]
+		h := hashFunc.New()
+		h.Write([]byte(signingInput))
+		r, s, err := ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		if err != nil {
+			return "", fmt.Errorf("failed to sign JWS: %w", err)
+		}
+		// JWS ECDSA signatures are the fixed size concatenation of r and s, not ASN.1
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			return "", fmt.Errorf("failed to sign JWS: %w", err)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signingInput))
+	}
+
+	return signingInput + "." + base64URLEncode(signature), nil
+}
+
+// decryptPBES2JWE decrypts a JWE in compact serialization encrypted with a password, like the
+// encrypted keys of step-ca JWK provisioners. Only the PBES2 key management algorithms
+// of [RFC 7518](https://datatracker.ietf.org/doc/html/rfc7518#section-4.8) with AES GCM content encryption are supported.
+func decryptPBES2JWE(jwe string, password []byte) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(jwe), ".")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expected a JWE in compact serialization, got %d parts", len(parts))
+	}
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		var err error
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, fmt.Errorf("invalid JWE part #%d: %w", i, err)
+		}
+	}
+	headerJSON, wrappedKey, iv, ciphertext, tag := decoded[0], decoded[1], decoded[2], decoded[3], decoded[4]
+
+	var header struct {
+		Alg string `json:"alg"`
+		Enc string `json:"enc"`
+		P2s string `json:"p2s"`
+		P2c int    `json:"p2c"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("invalid JWE header: %w", err)
+	}
+
+	kdfs := map[string]struct {
+		hash    func() hash.Hash
+		keySize int
+	}{
+		"PBES2-HS256+A128KW": {sha256.New, 16},
+		"PBES2-HS384+A192KW": {sha512.New384, 24},
+		"PBES2-HS512+A256KW": {sha512.New, 32},
+	}
+	kdf, ok := kdfs[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWE algorithm: %s", header.Alg)
+	}
+	encKeySizes := map[string]int{"A128GCM": 16, "A192GCM": 24, "A256GCM": 32}
+	encKeySize, ok := encKeySizes[header.Enc]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWE content encryption: %s", header.Enc)
+	}
+	p2s, err := base64.RawURLEncoding.DecodeString(header.P2s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWE header p2s: %w", err)
+	}
+	if header.P2c < 1 {
+		return nil, fmt.Errorf("invalid JWE header p2c: %d", header.P2c)
+	}
+
+	// the salt is the algorithm name and the p2s salt input, separated by a zero byte
+	salt := append(append([]byte(header.Alg), 0), p2s...)
+	kek := pbkdf2.Key(password, salt, header.P2c, kdf.keySize, kdf.hash)
+	cek, err := aesKeyUnwrap(kek, wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap JWE key, the password is probably wrong: %w", err)
+	}
+	if len(cek) != encKeySize {
+		return nil, fmt.Errorf("unwrapped JWE key is %d bytes long, expected %d for %s", len(cek), encKeySize, header.Enc)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt JWE: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// aesKeyUnwrap unwraps a key wrapped with the AES Key Wrap algorithm of [RFC 3394](https://datatracker.ietf.org/doc/html/rfc3394#section-2.2.2).
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("wrapped key should be a multiple of 8 bytes and at least 24 bytes long, got %d", len(wrapped))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	r := make([]byte, n*8)
+	copy(r, wrapped[8:])
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+
+	defaultIV := []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	if subtle.ConstantTimeCompare(a, defaultIV) != 1 {
+		return nil, fmt.Errorf("integrity check failed")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,311 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// issuanceJournalEntry records a certificate issued by the provider, like a line of the index.txt of easy-rsa.
+type issuanceJournalEntry struct {
+	SerialNumber   string     `json:"serial_number"`
+	Subject        string     `json:"subject"`
+	Issuer         string     `json:"issuer"`
+	AuthorityKeyID string     `json:"authority_key_id,omitempty"`
+	NotBefore      time.Time  `json:"not_before"`
+	NotAfter       time.Time  `json:"not_after"`
+	SupersededAt   *time.Time `json:"superseded_at,omitempty"`
+	// IssuancePolicy restricts the certificates issued by this certificate, when it is a CA.
+	IssuancePolicy *issuancePolicy `json:"issuance_policy,omitempty"`
+}
+
+// issuancePolicy restricts the certificates a CA of the issuance journal issues through the provider.
+type issuancePolicy struct {
+	MaxValidityHours int `json:"max_validity_hours"`
+	// Action is truncate or error, when a certificate would be valid for longer.
+	Action string `json:"action"`
+}
+
+// issuanceJournal stores the issuanceJournalEntry of the issued certificates.
+// Backends only have to load and replace the whole journal. update must hold a lock across processes, keeping
+// concurrent updates from losing entries.
+type issuanceJournal interface {
+	entries() ([]issuanceJournalEntry, error)
+	update(change func(entries []issuanceJournalEntry) []issuanceJournalEntry) error
+}
+
+// fileIssuanceJournalLocks serializes the updates of each journal file by the resources applied in parallel.
+// Other processes are kept out by the lock file of fileIssuanceJournal.lock.
+var fileIssuanceJournalLocks sync.Map
+
+const (
+	// fileIssuanceJournalLockTimeout is how long an update waits for the lock file of another process.
+	fileIssuanceJournalLockTimeout = time.Minute
+	// fileIssuanceJournalLockStale is the age after which a lock file is considered left over by a process that
+	// died while holding it. Updates only hold it while reading and replacing the file.
+	fileIssuanceJournalLockStale = 30 * time.Second
+)
+
+// fileIssuanceJournal is an issuanceJournal kept in a local JSON file, replaced atomically on each update.
+type fileIssuanceJournal struct {
+	path string
+}
+
+func (j *fileIssuanceJournal) entries() ([]issuanceJournalEntry, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []issuanceJournalEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuance journal: %w", err)
+	}
+
+	entries := make([]issuanceJournalEntry, 0)
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse issuance journal %s: %w", j.path, err)
+	}
+
+	return entries, nil
+}
+
+func (j *fileIssuanceJournal) update(change func(entries []issuanceJournalEntry) []issuanceJournalEntry) error {
+	lock, _ := fileIssuanceJournalLocks.LoadOrStore(j.path, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := j.entries()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(change(entries), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal issuance journal: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write issuance journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(append(data, '\n')); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write issuance journal: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to write issuance journal: %w", err)
+	}
+
+	return nil
+}
+
+// lock creates the lock file of the journal exclusively, waiting for other processes holding it, and returns the
+// function removing it.
+func (j *fileIssuanceJournal) lock() (func(), error) {
+	lockPath := j.path + ".lock"
+	deadline := time.Now().Add(fileIssuanceJournalLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, _ = fmt.Fprintf(f, "%d\n", os.Getpid())
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock issuance journal: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fileIssuanceJournalLockStale {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock issuance journal: %s is held by another process, remove it if no Terraform run uses the journal", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// providerIssuanceJournal returns the issuance_journal of the provider, nil when it is not configured.
+func providerIssuanceJournal(m interface{}) issuanceJournal {
+	if meta, ok := m.(*providerMeta); ok {
+		return meta.issuanceJournal
+	}
+	return nil
+}
+
+// recordIssuedCertificates adds the certificates in PEM format to the issuance journal of the provider, if any.
+func recordIssuedCertificates(m interface{}, certPems ...string) error {
+	return recordIssuedCertificateAuthorities(m, nil, certPems...)
+}
+
+// recordIssuedCertificateAuthorities adds the CA certificates in PEM format to the issuance journal of the provider,
+// if any, with the policy restricting the certificates they issue.
+func recordIssuedCertificateAuthorities(m interface{}, policy *issuancePolicy, certPems ...string) error {
+	journal := providerIssuanceJournal(m)
+	if journal == nil {
+		return nil
+	}
+
+	added := make([]issuanceJournalEntry, 0, len(certPems))
+	for _, certPem := range certPems {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return fmt.Errorf("unable to parse issued certificate: %w", err)
+		}
+		added = append(added, issuanceJournalEntry{
+			SerialNumber:   cert.SerialNumber.Text(16),
+			Subject:        cert.Subject.String(),
+			Issuer:         cert.Issuer.String(),
+			AuthorityKeyID: hex.EncodeToString(cert.AuthorityKeyId),
+			NotBefore:      cert.NotBefore.UTC(),
+			NotAfter:       cert.NotAfter.UTC(),
+			IssuancePolicy: policy,
+		})
+	}
+
+	return journal.update(func(entries []issuanceJournalEntry) []issuanceJournalEntry {
+		return append(entries, added...)
+	})
+}
+
+// supersedeIssuedCertificates marks the certificates in PEM format as superseded in the issuance journal of the provider, if any.
+// Certificates missing from the journal, issued before it was configured, are ignored.
+func supersedeIssuedCertificates(m interface{}, at time.Time, certPems ...string) error {
+	journal := providerIssuanceJournal(m)
+	if journal == nil {
+		return nil
+	}
+
+	superseded := make(map[string]bool, len(certPems))
+	for _, certPem := range certPems {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return fmt.Errorf("unable to parse issued certificate: %w", err)
+		}
+		superseded[cert.Issuer.String()+"/"+cert.SerialNumber.Text(16)] = true
+	}
+
+	at = at.UTC()
+	return journal.update(func(entries []issuanceJournalEntry) []issuanceJournalEntry {
+		for i := range entries {
+			if entries[i].SupersededAt == nil && superseded[entries[i].Issuer+"/"+entries[i].SerialNumber] {
+				entries[i].SupersededAt = &at
+			}
+		}
+		return entries
+	})
+}
+
+// setIssuancePolicy replaces the policy of the CA certificates in PEM format in the issuance journal of the provider, if any.
+func setIssuancePolicy(m interface{}, policy *issuancePolicy, certPems ...string) error {
+	journal := providerIssuanceJournal(m)
+	if journal == nil {
+		return nil
+	}
+
+	updated := make(map[string]bool, len(certPems))
+	for _, certPem := range certPems {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return fmt.Errorf("unable to parse CA certificate: %w", err)
+		}
+		updated[cert.Issuer.String()+"/"+cert.SerialNumber.Text(16)] = true
+	}
+
+	return journal.update(func(entries []issuanceJournalEntry) []issuanceJournalEntry {
+		for i := range entries {
+			if updated[entries[i].Issuer+"/"+entries[i].SerialNumber] {
+				entries[i].IssuancePolicy = policy
+			}
+		}
+		return entries
+	})
+}
+
+// issuancePolicyNotAfter returns notAfter capped by the issuance policy of caCert in the issuance journal of the
+// provider, failing instead when the policy action is error. Without journal or policy, notAfter is returned as is.
+func issuancePolicyNotAfter(m interface{}, caCert *x509.Certificate, notBefore, notAfter time.Time) (time.Time, error) {
+	journal := providerIssuanceJournal(m)
+	if journal == nil {
+		return notAfter, nil
+	}
+
+	entries, err := journal.entries()
+	if err != nil {
+		return notAfter, err
+	}
+
+	var policy *issuancePolicy
+	for _, entry := range entries {
+		if entry.Issuer == caCert.Issuer.String() && entry.SerialNumber == caCert.SerialNumber.Text(16) {
+			policy = entry.IssuancePolicy
+		}
+	}
+	if policy == nil {
+		return notAfter, nil
+	}
+
+	maxNotAfter := notBefore.Add(time.Duration(policy.MaxValidityHours) * time.Hour)
+	if !notAfter.After(maxNotAfter) {
+		return notAfter, nil
+	}
+	if policy.Action == "error" {
+		return notAfter, fmt.Errorf("validity of %d hours exceeds the max_issue_validity_hours (%d) of the CA %q",
+			int(notAfter.Sub(notBefore).Hours()), policy.MaxValidityHours, caCert.Subject.String())
+	}
+
+	return maxNotAfter, nil
+}
+
+// supersededJournalEntries returns the entries of the issuance journal of the provider issued by caCert, superseded
+// and not expired at the given time, ordered by serial number. It fails when the provider has no issuance journal.
+func supersededJournalEntries(m interface{}, caCert *x509.Certificate, at time.Time) ([]issuanceJournalEntry, error) {
+	journal := providerIssuanceJournal(m)
+	if journal == nil {
+		return nil, fmt.Errorf("the provider has no issuance_journal")
+	}
+
+	entries, err := journal.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	caKeyID := hex.EncodeToString(caCert.SubjectKeyId)
+	superseded := make([]issuanceJournalEntry, 0)
+	for _, entry := range entries {
+		if entry.SupersededAt == nil || !at.Before(entry.NotAfter) {
+			continue
+		}
+		if entry.AuthorityKeyID != "" && caKeyID != "" {
+			if entry.AuthorityKeyID != caKeyID {
+				continue
+			}
+		} else if entry.Issuer != caCert.Subject.String() {
+			continue
+		}
+		superseded = append(superseded, entry)
+	}
+	sort.Slice(superseded, func(i, j int) bool {
+		return superseded[i].SerialNumber < superseded[j].SerialNumber
+	})
+
+	return superseded, nil
+}
@@ -0,0 +1,69 @@
+package tlsutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileIssuanceJournalConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+	// different paths of the same file don't share the in-process lock, like two Terraform runs
+	journals := []*fileIssuanceJournal{
+		{path: filepath.Join(dir, "journal.json")},
+		{path: dir + string(filepath.Separator) + "." + string(filepath.Separator) + "journal.json"},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- journals[i%2].update(func(entries []issuanceJournalEntry) []issuanceJournalEntry {
+				// leave time to the other journal to read the file
+				time.Sleep(time.Millisecond)
+				return append(entries, issuanceJournalEntry{SerialNumber: fmt.Sprintf("%x", i)})
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("update failed: %s", err)
+		}
+	}
+
+	entries, err := journals[0].entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 100 {
+		t.Errorf("expected 100 entries, got %d", len(entries))
+	}
+	if _, err = os.Stat(journals[0].path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be removed, got %v", err)
+	}
+}
+
+func TestFileIssuanceJournalStaleLock(t *testing.T) {
+	journal := &fileIssuanceJournal{path: filepath.Join(t.TempDir(), "journal.json")}
+	if err := os.WriteFile(journal.path+".lock", []byte("1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * fileIssuanceJournalLockStale)
+	if err := os.Chtimes(journal.path+".lock", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	err := journal.update(func(entries []issuanceJournalEntry) []issuanceJournalEntry {
+		return append(entries, issuanceJournalEntry{SerialNumber: "1"})
+	})
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over: %s", err)
+	}
+}
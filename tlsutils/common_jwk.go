@@ -0,0 +1,221 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// jwsAlgorithm describes the key required by a JWS "alg" value,
+// as defined in [RFC 7518](https://datatracker.ietf.org/doc/html/rfc7518#section-3.1).
+type jwsAlgorithm struct {
+	algorithm Algorithm
+	curve     ECDSACurve
+}
+
+// jwsAlgorithms provides a jwsAlgorithm given a specific JWS "alg" value.
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	"RS256": {algorithm: RSA},
+	"RS384": {algorithm: RSA},
+	"RS512": {algorithm: RSA},
+	"PS256": {algorithm: RSA},
+	"PS384": {algorithm: RSA},
+	"PS512": {algorithm: RSA},
+	"ES256": {algorithm: ECDSA, curve: P256},
+	"ES384": {algorithm: ECDSA, curve: P384},
+	"ES512": {algorithm: ECDSA, curve: P521},
+	"EdDSA": {algorithm: ED25519},
+}
+
+// supportedJWSAlgorithmsStr returns the JWS "alg" values currently supported by this provider.
+func supportedJWSAlgorithmsStr() []string {
+	return []string{
+		"RS256", "RS384", "RS512",
+		"PS256", "PS384", "PS512",
+		"ES256", "ES384", "ES512",
+		"EdDSA",
+	}
+}
+
+// jsonWebKey is the JSON representation of a key, as defined in [RFC 7517](https://datatracker.ietf.org/doc/html/rfc7517).
+// Members are declared in the order they should appear once marshalled.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+// privateKeyToJWK builds the jsonWebKey of the given crypto.PrivateKey.
+// The private members are only populated when includePrivate is true.
+func privateKeyToJWK(prvKey crypto.PrivateKey, includePrivate bool) (*jsonWebKey, error) {
+	switch k := prvKey.(type) {
+	case *rsa.PrivateKey:
+		k.Precompute()
+		jwk := &jsonWebKey{
+			Kty: "RSA",
+			N:   base64URLEncode(k.N.Bytes()),
+			E:   base64URLEncode(big.NewInt(int64(k.E)).Bytes()),
+		}
+		if includePrivate {
+			jwk.D = base64URLEncode(k.D.Bytes())
+			jwk.P = base64URLEncode(k.Primes[0].Bytes())
+			jwk.Q = base64URLEncode(k.Primes[1].Bytes())
+			jwk.DP = base64URLEncode(k.Precomputed.Dp.Bytes())
+			jwk.DQ = base64URLEncode(k.Precomputed.Dq.Bytes())
+			jwk.QI = base64URLEncode(k.Precomputed.Qinv.Bytes())
+		}
+		return jwk, nil
+	case *ecdsa.PrivateKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk := &jsonWebKey{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64URLEncode(k.X.FillBytes(make([]byte, size))),
+			Y:   base64URLEncode(k.Y.FillBytes(make([]byte, size))),
+		}
+		if includePrivate {
+			jwk.D = base64URLEncode(k.D.FillBytes(make([]byte, size)))
+		}
+		return jwk, nil
+	case ed25519.PrivateKey:
+		jwk := &jsonWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64URLEncode(k.Public().(ed25519.PublicKey)),
+		}
+		if includePrivate {
+			jwk.D = base64URLEncode(k.Seed())
+		}
+		return jwk, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+}
+
+// jwkToPrivateKey parses the private key of a jsonWebKey, the reverse of privateKeyToJWK.
+func jwkToPrivateKey(jwk *jsonWebKey) (crypto.PrivateKey, error) {
+	if jwk.D == "" {
+		return nil, fmt.Errorf("JWK has no private member \"d\"")
+	}
+
+	members := map[string][]byte{}
+	for name, value := range map[string]string{"x": jwk.X, "y": jwk.Y, "n": jwk.N, "e": jwk.E, "d": jwk.D, "p": jwk.P, "q": jwk.Q} {
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK member %q: %w", name, err)
+		}
+		members[name] = decoded
+	}
+	bigInt := func(name string) *big.Int {
+		return new(big.Int).SetBytes(members[name])
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		if jwk.P == "" || jwk.Q == "" {
+			return nil, fmt.Errorf("RSA JWK without the \"p\" and \"q\" primes is not supported")
+		}
+		prvKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: bigInt("n"), E: int(bigInt("e").Int64())},
+			D:         bigInt("d"),
+			Primes:    []*big.Int{bigInt("p"), bigInt("q")},
+		}
+		if err := prvKey.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA JWK: %w", err)
+		}
+		prvKey.Precompute()
+		return prvKey, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported JWK curve: %s", jwk.Crv)
+		}
+		prvKey := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: curve, X: bigInt("x"), Y: bigInt("y")},
+			D:         bigInt("d"),
+		}
+		x, y := curve.ScalarBaseMult(members["d"])
+		if x.Cmp(prvKey.X) != 0 || y.Cmp(prvKey.Y) != 0 {
+			return nil, fmt.Errorf("invalid EC JWK: \"d\" does not match \"x\" and \"y\"")
+		}
+		return prvKey, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported JWK curve: %s", jwk.Crv)
+		}
+		if len(members["d"]) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid Ed25519 JWK: \"d\" is %d bytes long", len(members["d"]))
+		}
+		prvKey := ed25519.NewKeyFromSeed(members["d"])
+		if jwk.X != "" && !bytes.Equal(prvKey.Public().(ed25519.PublicKey), members["x"]) {
+			return nil, fmt.Errorf("invalid Ed25519 JWK: \"d\" does not match \"x\"")
+		}
+		return prvKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type: %s", jwk.Kty)
+	}
+}
+
+// thumbprint computes the JWK Thumbprint of the key,
+// as defined in [RFC 7638](https://datatracker.ietf.org/doc/html/rfc7638).
+func (jwk *jsonWebKey) thumbprint() (string, error) {
+	// Only the required members are hashed, in lexicographic order and without whitespace
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported JWK key type: %s", jwk.Kty)
+	}
+
+	membersJSON, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWK thumbprint members: %w", err)
+	}
+
+	hash := sha256.Sum256(membersJSON)
+	return base64URLEncode(hash[:]), nil
+}
+
+// base64URLEncode encodes data using the unpadded base64url alphabet used by JOSE.
+func base64URLEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
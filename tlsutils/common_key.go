@@ -1,13 +1,20 @@
 package tlsutils
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"slices"
+	"strings"
 )
 
 // keyParser parses a private key from the given []byte,
@@ -20,20 +27,39 @@ var keyParsers = map[PEMPreamble]keyParser{
 		return x509.ParsePKCS1PrivateKey(der)
 	},
 	PreamblePrivateKeyEC: func(der []byte) (crypto.PrivateKey, error) {
-		return x509.ParseECPrivateKey(der)
+		return parseECPrivateKey(der)
 	},
 	PreamblePrivateKeyPKCS8: func(der []byte) (crypto.PrivateKey, error) {
-		return x509.ParsePKCS8PrivateKey(der)
+		return parsePKCS8PrivateKey(der)
 	},
 }
 
+// decodePrivateKeyPEM returns the first PEM block of data holding a private key, skipping the other blocks such as
+// the EC PARAMETERS written before the key by `openssl ecparam -genkey`, or certificates bundled with the key.
+// Without private key block, it returns the first block, nil when data has none.
+func decodePrivateKeyPEM(data []byte) *pem.Block {
+	var first *pem.Block
+	scanner := newPEMBlockScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if strings.HasSuffix(scanner.Block().Type, "PRIVATE KEY") {
+			return scanner.Block()
+		}
+		if first == nil {
+			first = scanner.Block()
+		}
+	}
+
+	return first
+}
+
 // parsePrivateKeyPEM takes a slide of bytes containing a private key
 // encoded in [PEM (RFC 1421)](https://datatracker.ietf.org/doc/html/rfc1421) format,
 // and returns a crypto.PrivateKey implementation, together with the Algorithm used by the key.
+// Blocks before the private key, like EC parameters or certificates, are skipped.
 func parsePrivateKeyPEM(keyPEMBytes []byte) (crypto.PrivateKey, Algorithm, error) {
-	pemBlock, rest := pem.Decode(keyPEMBytes)
+	pemBlock := decodePrivateKeyPEM(keyPEMBytes)
 	if pemBlock == nil {
-		return nil, "", fmt.Errorf("failed to decode PEM block: decoded bytes %d, undecoded %d", len(keyPEMBytes)-len(rest), len(rest))
+		return nil, "", fmt.Errorf("failed to decode PEM block: decoded bytes 0, undecoded %d", len(keyPEMBytes))
 	}
 
 	// Identify the PEM preamble from the block
@@ -76,3 +102,219 @@ func privateKeyToAlgorithm(prvKey crypto.PrivateKey) (Algorithm, error) {
 		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
 	}
 }
+
+// keyGenerator generates a crypto.PrivateKey for a specific Algorithm.
+// rsaBits is only used for RSA and curve is only used for ECDSA.
+type keyGenerator func(rsaBits int, curve ECDSACurve) (crypto.PrivateKey, error)
+
+// keyGenerators provides a keyGenerator given a specific Algorithm.
+var keyGenerators = map[Algorithm]keyGenerator{
+	RSA: func(rsaBits int, _ ECDSACurve) (crypto.PrivateKey, error) {
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	},
+	ECDSA: func(_ int, curve ECDSACurve) (crypto.PrivateKey, error) {
+		ellipticCurve, err := ecdsaCurveToEllipticCurve(curve)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(ellipticCurve, rand.Reader)
+	},
+	ED25519: func(_ int, _ ECDSACurve) (crypto.PrivateKey, error) {
+		_, prvKey, err := ed25519.GenerateKey(rand.Reader)
+		return prvKey, err
+	},
+}
+
+// generatePrivateKey creates a new crypto.PrivateKey using the given Algorithm.
+func generatePrivateKey(algorithm Algorithm, rsaBits int, curve ECDSACurve) (crypto.PrivateKey, error) {
+	generator, ok := keyGenerators[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key algorithm: %s", algorithm)
+	}
+
+	prvKey, err := generator(rsaBits, curve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s private key: %w", algorithm, err)
+	}
+
+	return prvKey, nil
+}
+
+// keyAlgorithmOrProviderDefault returns the algorithm attribute of a resource when it is set in its configuration,
+// the default_key_algorithm of the provider otherwise, then fallback.
+func keyAlgorithmOrProviderDefault(d resourceAttributes, m interface{}, fallback Algorithm) Algorithm {
+	if algorithm := stringOrProviderDefault(d, "algorithm", m, func(meta *providerMeta) string {
+		return meta.defaultKeyAlgorithm.String()
+	}); algorithm != "" {
+		return Algorithm(algorithm)
+	}
+	return fallback
+}
+
+// rsaBitsOrProviderDefault returns the rsa_bits attribute of a resource when it is set in its configuration,
+// the default_rsa_bits of the provider otherwise, then fallback.
+func rsaBitsOrProviderDefault(d resourceAttributes, m interface{}, fallback int) int {
+	if rsaBits := intOrProviderDefault(d, "rsa_bits", m, func(meta *providerMeta) int {
+		return meta.defaultRSABits
+	}); rsaBits != 0 {
+		return rsaBits
+	}
+	return fallback
+}
+
+// ecdsaCurveOrProviderDefault returns the ecdsa_curve attribute of a resource when it is set in its configuration,
+// the default_ecdsa_curve of the provider otherwise, then fallback.
+func ecdsaCurveOrProviderDefault(d resourceAttributes, m interface{}, fallback ECDSACurve) ECDSACurve {
+	if curve := stringOrProviderDefault(d, "ecdsa_curve", m, func(meta *providerMeta) string {
+		return meta.defaultECDSACurve.String()
+	}); curve != "" {
+		return ECDSACurve(curve)
+	}
+	return fallback
+}
+
+// keyParametersUsedBy returns the key parameter attributes the keys of algorithm depend on.
+func keyParametersUsedBy(algorithm Algorithm) []string {
+	switch algorithm {
+	case RSA:
+		return []string{"algorithm", "rsa_bits"}
+	case ECDSA:
+		return []string{"algorithm", "ecdsa_curve"}
+	default:
+		return []string{"algorithm"}
+	}
+}
+
+// planKeyParameters plans the key parameter attributes of a resource with their value resolved against the provider
+// defaults. A new resource gets all of them; an existing one only the used ones, those of the keys it actually
+// generated, and is replaced when a change of the provider defaults changes them.
+func planKeyParameters(diff *schema.ResourceDiff, parameters map[string]interface{}, used ...string) error {
+	for key, value := range parameters {
+		if diff.Id() != "" && !slices.Contains(used, key) {
+			continue
+		}
+		if diff.Get(key) == value {
+			continue
+		}
+		if err := diff.SetNew(key, value); err != nil {
+			return err
+		}
+		if diff.Id() != "" {
+			if err := diff.ForceNew(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ecdsaCurveToEllipticCurve returns the elliptic.Curve implementation for the given ECDSACurve.
+func ecdsaCurveToEllipticCurve(curve ECDSACurve) (elliptic.Curve, error) {
+	switch curve {
+	case P224:
+		return elliptic.P224(), nil
+	case P256:
+		return elliptic.P256(), nil
+	case P384:
+		return elliptic.P384(), nil
+	case P521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ECDSA curve: %s", curve)
+	}
+}
+
+// privateKeyToPEM encodes a crypto.PrivateKey in PEM format, using PKCS#1 for RSA,
+// SEC 1 for ECDSA and PKCS#8 for ED25519 (the same encodings used by the hashicorp/tls provider).
+func privateKeyToPEM(prvKey crypto.PrivateKey) (string, error) {
+	var block *pem.Block
+
+	switch k := prvKey.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: PreamblePrivateKeyRSA.String(), Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal ECDSA private key: %w", err)
+		}
+		block = &pem.Block{Type: PreamblePrivateKeyEC.String(), Bytes: der}
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal ED25519 private key: %w", err)
+		}
+		block = &pem.Block{Type: PreamblePrivateKeyPKCS8.String(), Bytes: der}
+	default:
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// privateKeyToPEMFormat encodes a crypto.PrivateKey in PEM format with the given PrivateKeyFormat.
+func privateKeyToPEMFormat(prvKey crypto.PrivateKey, format PrivateKeyFormat) (string, error) {
+	switch format {
+	case PrivateKeyFormatTraditional:
+		return privateKeyToPEM(prvKey)
+	case PrivateKeyFormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(prvKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal PKCS#8 private key: %w", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: PreamblePrivateKeyPKCS8.String(), Bytes: der})), nil
+	default:
+		return "", fmt.Errorf("unsupported private key format: %s", format)
+	}
+}
+
+// privateKeyToOpenSSH encodes a crypto.PrivateKey in the OpenSSH private key format.
+func privateKeyToOpenSSH(prvKey crypto.PrivateKey) (string, error) {
+	block, err := ssh.MarshalPrivateKey(prvKey, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OpenSSH private key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// publicKeyToOpenSSH encodes the public part of a crypto.PrivateKey in the OpenSSH authorized_keys format.
+func publicKeyToOpenSSH(prvKey crypto.PrivateKey) (string, error) {
+	signer, ok := prvKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+
+	pubKey, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OpenSSH public key: %w", err)
+	}
+
+	return string(ssh.MarshalAuthorizedKey(pubKey)), nil
+}
+
+// publicKeyToPEM encodes the public part of a crypto.PrivateKey as a PKIX (SubjectPublicKeyInfo) PEM.
+func publicKeyToPEM(prvKey crypto.PrivateKey) (string, error) {
+	signer, ok := prvKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: der})), nil
+}
+
+// privateKeyMatchesCertificate returns true if the public part of prvKey is the certificate public key.
+func privateKeyMatchesCertificate(prvKey crypto.PrivateKey, cert *x509.Certificate) bool {
+	signer, ok := prvKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+
+	pubKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pubKey.Equal(cert.PublicKey)
+}
@@ -0,0 +1,204 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+// Formats of private keys accepted and produced by parseAnyPrivateKey and encodeAnyPrivateKey.
+const (
+	keyFormatPKCS1   = "pkcs1"
+	keyFormatSEC1    = "sec1"
+	keyFormatPKCS8   = "pkcs8"
+	keyFormatOpenSSH = "openssh"
+	keyFormatJWK     = "jwk"
+	keyFormatPPK2    = "ppk2"
+	keyFormatPPK3    = "ppk3"
+)
+
+// supportedKeyConvertFormatsStr returns the formats encodeAnyPrivateKey can produce.
+func supportedKeyConvertFormatsStr() []string {
+	return []string{keyFormatPKCS1, keyFormatSEC1, keyFormatPKCS8, keyFormatOpenSSH, keyFormatJWK, keyFormatPPK2, keyFormatPPK3}
+}
+
+// parseAnyPrivateKey parses a private key in PEM (PKCS#1, SEC 1 or PKCS#8, encrypted or not), OpenSSH, JWK
+// or base64 encoded DER format. It returns the key together with its format and whether it was encrypted.
+func parseAnyPrivateKey(data, passphrase string) (crypto.PrivateKey, string, bool, error) {
+	trimmed := strings.TrimSpace(data)
+	if strings.HasPrefix(trimmed, "{") {
+		var jwk jsonWebKey
+		if err := json.Unmarshal([]byte(trimmed), &jwk); err != nil {
+			return nil, "", false, fmt.Errorf("unable to parse JWK: %w", err)
+		}
+		prvKey, err := jwkToPrivateKey(&jwk)
+		return prvKey, keyFormatJWK, false, err
+	}
+
+	block := decodePrivateKeyPEM([]byte(trimmed))
+	if block == nil {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(trimmed), ""))
+		if err != nil {
+			return nil, "", false, fmt.Errorf("private key is neither PEM, JWK nor base64 encoded DER")
+		}
+		prvKey, format, err := parseDERPrivateKey(der)
+		return prvKey, format, false, err
+	}
+
+	switch {
+	case block.Type == PreamblePrivateKeyOpenSSH.String():
+		encrypted := false
+		// ssh only parses the first block
+		keyPEM := pem.EncodeToMemory(block)
+		prvKey, err := ssh.ParseRawPrivateKey(keyPEM)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			encrypted = true
+			if passphrase == "" {
+				return nil, "", true, fmt.Errorf("the OpenSSH private key is encrypted and no passphrase is set")
+			}
+			prvKey, err = ssh.ParseRawPrivateKeyWithPassphrase(keyPEM, []byte(passphrase))
+		}
+		if err != nil {
+			return nil, "", encrypted, fmt.Errorf("unable to parse OpenSSH private key: %w", err)
+		}
+		// ssh returns a pointer for ED25519 keys, crypto/x509 a value
+		if k, ok := prvKey.(*ed25519.PrivateKey); ok {
+			prvKey = *k
+		}
+		return prvKey, keyFormatOpenSSH, encrypted, nil
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if passphrase == "" {
+			return nil, "", true, fmt.Errorf("the PKCS#8 private key is encrypted and no passphrase is set")
+		}
+		der, err := decryptPKCS8PrivateKey(block.Bytes, []byte(passphrase))
+		if err != nil {
+			return nil, "", true, err
+		}
+		prvKey, err := parsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, "", true, fmt.Errorf("unable to parse decrypted PKCS#8 private key: %w", err)
+		}
+		return prvKey, keyFormatPKCS8, true, nil
+	//nolint:staticcheck // legacy PEM encryption is still what some software produces
+	case x509.IsEncryptedPEMBlock(block):
+		if passphrase == "" {
+			return nil, "", true, fmt.Errorf("the %s is encrypted and no passphrase is set", block.Type)
+		}
+		//nolint:staticcheck // see above
+		der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return nil, "", true, fmt.Errorf("failed to decrypt %s: %w", block.Type, err)
+		}
+		prvKey, format, err := parseDERPrivateKey(der)
+		return prvKey, format, true, err
+	default:
+		prvKey, format, err := parseDERPrivateKey(block.Bytes)
+		return prvKey, format, false, err
+	}
+}
+
+// parseDERPrivateKey parses a PKCS#8, PKCS#1 or SEC 1 DER private key, returning its format.
+func parseDERPrivateKey(der []byte) (crypto.PrivateKey, string, error) {
+	if prvKey, err := parsePKCS8PrivateKey(der); err == nil {
+		return prvKey, keyFormatPKCS8, nil
+	}
+	if prvKey, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return prvKey, keyFormatPKCS1, nil
+	}
+	if prvKey, err := parseECPrivateKey(der); err == nil {
+		return prvKey, keyFormatSEC1, nil
+	}
+
+	return nil, "", fmt.Errorf("private key is not a PKCS#8, PKCS#1 or SEC 1 key")
+}
+
+// encodeAnyPrivateKey encodes prvKey in the given format, encrypted with passphrase when it is not empty:
+// PBES2 with AES-256-CBC for PKCS#8, bcrypt with AES-256-CTR for OpenSSH, AES-256-CBC for PPK, and the legacy
+// OpenSSL PEM encryption with AES-256-CBC for PKCS#1 and SEC 1. JWK cannot be encrypted.
+func encodeAnyPrivateKey(prvKey crypto.PrivateKey, format, passphrase string) (string, error) {
+	var block *pem.Block
+	switch format {
+	case keyFormatPKCS1:
+		k, ok := prvKey.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("only RSA keys can be encoded in PKCS#1")
+		}
+		block = &pem.Block{Type: PreamblePrivateKeyRSA.String(), Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case keyFormatSEC1:
+		k, ok := prvKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("only ECDSA keys can be encoded in SEC 1")
+		}
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal ECDSA private key: %w", err)
+		}
+		block = &pem.Block{Type: PreamblePrivateKeyEC.String(), Bytes: der}
+	case keyFormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(prvKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal PKCS#8 private key: %w", err)
+		}
+		if passphrase == "" {
+			block = &pem.Block{Type: PreamblePrivateKeyPKCS8.String(), Bytes: der}
+			break
+		}
+		encrypted, err := encryptPKCS8PrivateKey(der, []byte(passphrase))
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt PKCS#8 private key: %w", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encrypted})), nil
+	case keyFormatOpenSSH:
+		var err error
+		if passphrase == "" {
+			block, err = ssh.MarshalPrivateKey(prvKey, "")
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(prvKey, "", []byte(passphrase))
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal OpenSSH private key: %w", err)
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	case keyFormatJWK:
+		if passphrase != "" {
+			return "", fmt.Errorf("JWK private keys cannot be encrypted")
+		}
+		jwk, err := privateKeyToJWK(prvKey, true)
+		if err != nil {
+			return "", err
+		}
+		jwkJSON, err := json.Marshal(jwk)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JWK: %w", err)
+		}
+		return string(jwkJSON), nil
+	case keyFormatPPK2:
+		return encodePPK(prvKey, 2, passphrase)
+	case keyFormatPPK3:
+		return encodePPK(prvKey, 3, passphrase)
+	default:
+		return "", fmt.Errorf("unsupported private key format: %s", format)
+	}
+
+	if passphrase != "" {
+		//nolint:staticcheck // legacy PEM encryption is the only one PKCS#1 and SEC 1 keys have
+		encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte(passphrase), x509.PEMCipherAES256)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt %s: %w", block.Type, err)
+		}
+		block = encrypted
+	}
+
+	return string(pem.EncodeToMemory(block)), nil
+}
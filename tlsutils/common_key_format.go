@@ -0,0 +1,88 @@
+package tlsutils
+
+import (
+	"crypto"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// keyFormatSchema returns the attributes choosing how the generated private keys are encoded.
+// They change in-place: the resources re-encode their keys instead of generating new ones.
+func keyFormatSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"private_key_format": {
+			Description:      "encoding of the private keys in PEM format: `traditional` (PKCS#1 for RSA, SEC 1 for ECDSA, PKCS#8 for ED25519) or `pkcs8`. Changing it re-encodes the keys without generating new ones, unless they are encrypted to `age_recipient` or `pgp_key`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          PrivateKeyFormatTraditional.String(),
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedPrivateKeyFormatsStr(), false)),
+		},
+		"openssh_output": {
+			Description: "also output the keys in OpenSSH format. Changing it does not generate new keys, unless they are encrypted to `age_recipient` or `pgp_key`.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+	}
+}
+
+// keyRepresentations encodes prvKey according to the keyFormatSchema attributes of d, keyed by
+// `private_key_pem`, `private_key_openssh` and `public_key_openssh`. The OpenSSH encodings are empty unless openssh_output is set.
+func keyRepresentations(d resourceAttributes, prvKey crypto.PrivateKey) (map[string]string, error) {
+	privateKeyPem, err := privateKeyToPEMFormat(prvKey, PrivateKeyFormat(d.Get("private_key_format").(string)))
+	if err != nil {
+		return nil, err
+	}
+
+	representations := map[string]string{
+		"private_key_pem":     privateKeyPem,
+		"private_key_openssh": "",
+		"public_key_openssh":  "",
+	}
+	if d.Get("openssh_output").(bool) {
+		if representations["private_key_openssh"], err = privateKeyToOpenSSH(prvKey); err != nil {
+			return nil, err
+		}
+		if representations["public_key_openssh"], err = publicKeyToOpenSSH(prvKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return representations, nil
+}
+
+// keyFormatSetNewComputed marks as computed the representations of the keys with the given attribute prefixes
+// that change with the keyFormatSchema attributes. Keys encrypted to the age_recipient or pgp_key of
+// privateKeyEncryptionSchema are not in the state to be re-encoded: the resource is replaced instead.
+func keyFormatSetNewComputed(diff *schema.ResourceDiff, prefixes ...string) error {
+	ageRecipient, _ := diff.Get("age_recipient").(string)
+	pgpKey, _ := diff.Get("pgp_key").(string)
+	if ageRecipient != "" || pgpKey != "" {
+		for _, key := range []string{"private_key_format", "openssh_output"} {
+			if !diff.HasChange(key) {
+				continue
+			}
+			if err := diff.ForceNew(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		computed := make([]string, 0)
+		if diff.HasChange("private_key_format") {
+			computed = append(computed, prefix+"private_key_pem")
+		}
+		if diff.HasChange("openssh_output") {
+			computed = append(computed, prefix+"private_key_openssh", prefix+"public_key_openssh")
+		}
+		for _, key := range computed {
+			if err := diff.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
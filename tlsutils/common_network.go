@@ -0,0 +1,270 @@
+package tlsutils
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// networkSchema returns the attributes shared by the data sources connecting to remote endpoints.
+func networkSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"connect_timeout": {
+			Description:      "maximum time to establish each connection, including the proxy and TLS handshakes, as a Go duration.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          "30s",
+			ValidateDiagFunc: validation.ToDiagFunc(validateDuration),
+		},
+		"retries": {
+			Description:      "number of times a failed connection is retried.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          0,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		},
+		"retry_backoff": {
+			Description:      "wait before the first retry, as a Go duration. It doubles after each retry.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          "1s",
+			ValidateDiagFunc: validation.ToDiagFunc(validateDuration),
+		},
+		"proxy_url": {
+			Description: "proxy to connect through: `http://`, `https://` (HTTP CONNECT) or `socks5://`, with optional credentials. Defaults to the `HTTPS_PROXY` and `NO_PROXY` environment variables.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+	}
+}
+
+// validateDuration is a schema.SchemaValidateFunc checking the value is a positive Go duration.
+func validateDuration(i interface{}, k string) ([]string, []error) {
+	value, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	if duration <= 0 {
+		return nil, []error{fmt.Errorf("%s: expected a positive duration, got %s", k, value)}
+	}
+
+	return nil, nil
+}
+
+// networkOptions are the connection settings configured by networkSchema.
+type networkOptions struct {
+	connectTimeout time.Duration
+	retries        int
+	retryBackoff   time.Duration
+	proxyURL       *url.URL
+}
+
+// networkOptionsFromResourceData reads the attributes of networkSchema.
+func networkOptionsFromResourceData(d *schema.ResourceData) (*networkOptions, error) {
+	connectTimeout, err := time.ParseDuration(d.Get("connect_timeout").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid connect_timeout: %w", err)
+	}
+
+	retryBackoff, err := time.ParseDuration(d.Get("retry_backoff").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry_backoff: %w", err)
+	}
+
+	options := &networkOptions{
+		connectTimeout: connectTimeout,
+		retries:        d.Get("retries").(int),
+		retryBackoff:   retryBackoff,
+	}
+	if proxyURL := d.Get("proxy_url").(string); proxyURL != "" {
+		if options.proxyURL, err = url.Parse(proxyURL); err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+	}
+
+	return options, nil
+}
+
+// retry calls fn until it succeeds, up to retries more times, waiting retryBackoff before the first retry
+// and twice as long before each next one. The last error is returned.
+func (o *networkOptions) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := o.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= o.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// httpClient returns an *http.Client using the configured proxy, or the one of the environment,
+// and connect_timeout for the TCP connection and TLS handshake.
+func (o *networkOptions) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.proxyURL != nil {
+		transport.Proxy = http.ProxyURL(o.proxyURL)
+	}
+	transport.DialContext = (&net.Dialer{Timeout: o.connectTimeout}).DialContext
+	transport.TLSHandshakeTimeout = o.connectTimeout
+
+	return &http.Client{Transport: transport, Timeout: defaultHTTPTimeout}
+}
+
+// dialContext opens a TCP connection to address (host:port), through the configured proxy
+// or the one of the environment for an https URL of address.
+func (o *networkOptions) dialContext(ctx context.Context, address string) (net.Conn, error) {
+	proxyURL := o.proxyURL
+	if proxyURL == nil {
+		var err error
+		proxyURL, err = httpproxy.FromEnvironment().ProxyFunc()(&url.URL{Scheme: "https", Host: address})
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy in environment: %w", err)
+		}
+	}
+
+	direct := &net.Dialer{}
+	if proxyURL == nil {
+		return direct.DialContext(ctx, "tcp", address)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, direct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOCKS5 proxy %s: %w", proxyURL.Redacted(), err)
+		}
+		conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("through SOCKS5 proxy %s: %w", proxyURL.Redacted(), err)
+		}
+		return conn, nil
+	case "http", "https":
+		return dialHTTPConnect(ctx, direct, proxyURL, address)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// dialHTTPConnect opens a tunnel to address through the HTTP proxy at proxyURL, with the CONNECT method.
+func dialHTTPConnect(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed TLS handshake with proxy %s: %w", proxyURL.Redacted(), err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: address}, Host: address, Header: http.Header{}}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response of proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Redacted(), address, resp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose first bytes were already read into reader.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// download returns the content at rawURL, an http, https or file URL. HTTP requests are retried.
+func (o *networkOptions) download(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		content, err := os.ReadFile(u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", u.Path, err)
+		}
+		return content, nil
+	case "http", "https":
+		client := o.httpClient()
+		var content []byte
+		err = o.retry(ctx, func(ctx context.Context) error {
+			content, err = doRequest(ctx, client, http.MethodGet, rawURL, nil, nil)
+			return err
+		})
+		return content, err
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+}
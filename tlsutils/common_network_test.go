@@ -0,0 +1,129 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConnectProxy serves an HTTP proxy tunneling CONNECT requests authenticated as user:password, counting them in
+// connects. It returns the proxy URL without credentials.
+func testConnectProxy(t *testing.T, connects *int32) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "expected CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:password")) {
+			http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+			return
+		}
+		atomic.AddInt32(connects, 1)
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestNetworkOptionsRetry(t *testing.T) {
+	options := &networkOptions{connectTimeout: time.Second, retries: 2, retryBackoff: 10 * time.Millisecond}
+
+	var attempts []time.Time
+	err := options.retry(context.Background(), func(context.Context) error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 3 {
+			return errors.New("refused")
+		}
+		return nil
+	})
+	if err != nil || len(attempts) != 3 {
+		t.Fatalf("expected success on the third attempt, got %v after %d attempts", err, len(attempts))
+	}
+	if first, second := attempts[1].Sub(attempts[0]), attempts[2].Sub(attempts[1]); first < 10*time.Millisecond || second < 20*time.Millisecond {
+		t.Errorf("expected the backoff to double, waited %s then %s", first, second)
+	}
+
+	attempts = nil
+	err = options.retry(context.Background(), func(context.Context) error {
+		attempts = append(attempts, time.Now())
+		return errors.New("refused")
+	})
+	if err == nil || err.Error() != "refused" || len(attempts) != 3 {
+		t.Errorf("expected the last error after 3 attempts, got %v after %d attempts", err, len(attempts))
+	}
+}
+
+func TestNetworkOptionsProxy(t *testing.T) {
+	cert, key := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, nil, nil)
+	address := testTLSServer(t, key, cert)
+	var connects int32
+	proxyURL := testConnectProxy(t, &connects)
+
+	d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":   address,
+		"proxy_url": strings.Replace(proxyURL, "http://", "http://user:password@", 1),
+	})
+	if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if atomic.LoadInt32(&connects) != 1 || d.Get("certificate_pem").(string) != certificateToPEM(cert) {
+		t.Errorf("expected the certificate through one CONNECT tunnel, got %d tunnels", connects)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":       address,
+		"proxy_url":     proxyURL,
+		"retries":       1,
+		"retry_backoff": "1ms",
+	})
+	diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{})
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "407 Proxy Authentication Required") {
+		t.Errorf("expected the proxy to refuse a tunnel without credentials, got %v", diags)
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	if _, errs := validateDuration("1m30s", "connect_timeout"); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	for value, want := range map[string]string{
+		"30":  "connect_timeout: time: missing unit in duration",
+		"0s":  "connect_timeout: expected a positive duration, got 0s",
+		"-1s": "connect_timeout: expected a positive duration, got -1s",
+	} {
+		if _, errs := validateDuration(value, "connect_timeout"); len(errs) != 1 || !strings.Contains(errs[0].Error(), want) {
+			t.Errorf("expected %q for %q, got %v", want, value, errs)
+		}
+	}
+}
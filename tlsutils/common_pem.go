@@ -0,0 +1,79 @@
+package tlsutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/pem"
+	"io"
+)
+
+// pemBlockScanner reads the PEM blocks of a reader one at a time, keeping only the current block in memory,
+// so bundles of thousands of certificates and multi-megabyte CRLs are not loaded whole. Text around the blocks
+// and malformed blocks are skipped, as with pem.Decode.
+type pemBlockScanner struct {
+	reader *bufio.Reader
+	block  *pem.Block
+	err    error
+}
+
+func newPEMBlockScanner(r io.Reader) *pemBlockScanner {
+	return &pemBlockScanner{reader: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Scan advances to the next block, returning false at the end of the input or on a read error.
+func (s *pemBlockScanner) Scan() bool {
+	s.block = nil
+	var current []byte
+	for s.err == nil {
+		line, err := s.readLine()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return false
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")) && bytes.HasSuffix(trimmed, []byte("-----")):
+			// a BEGIN line inside a block restarts it, like pem.Decode does
+			current = append(current[:0], trimmed...)
+			current = append(current, '\n')
+		case current == nil:
+			// text outside of a block
+		case bytes.HasPrefix(trimmed, []byte("-----END ")):
+			current = append(current, trimmed...)
+			current = append(current, '\n')
+			if block, _ := pem.Decode(current); block != nil {
+				s.block = block
+				return true
+			}
+			current = nil
+		default:
+			current = append(current, line...)
+			current = append(current, '\n')
+		}
+	}
+
+	return false
+}
+
+// Block returns the block read by the last call to Scan.
+func (s *pemBlockScanner) Block() *pem.Block {
+	return s.block
+}
+
+// Err returns the read error that stopped Scan, nil at the end of the input.
+func (s *pemBlockScanner) Err() error {
+	return s.err
+}
+
+// readLine returns the next line without its line ending, io.EOF once the input is exhausted.
+func (s *pemBlockScanner) readLine() ([]byte, error) {
+	line, err := s.reader.ReadBytes('\n')
+	if len(line) == 0 && err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(line, "\r\n"), nil
+}
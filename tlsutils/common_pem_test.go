@@ -0,0 +1,57 @@
+package tlsutils
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPEMBlockScanner(t *testing.T) {
+	first := string(pem.EncodeToMemory(&pem.Block{Type: "FIRST", Bytes: []byte("first")}))
+	second := &pem.Block{Type: "SECOND", Headers: map[string]string{"Proc-Type": "4,ENCRYPTED"}, Bytes: bytes.Repeat([]byte("second"), 100)}
+	third := string(pem.EncodeToMemory(&pem.Block{Type: "THIRD", Bytes: []byte("third")}))
+
+	for name, test := range map[string]struct {
+		input string
+		want  []string
+	}{
+		"surrounding text":   {input: "bundle:\n" + first + "comment\n" + string(pem.EncodeToMemory(second)) + "trailer", want: []string{"FIRST", "SECOND"}},
+		"CRLF line endings":  {input: strings.ReplaceAll(first+string(pem.EncodeToMemory(second)), "\n", "\r\n"), want: []string{"FIRST", "SECOND"}},
+		"indented":           {input: "  " + strings.ReplaceAll(first, "\n", "\n  "), want: []string{"FIRST"}},
+		"no final newline":   {input: strings.TrimSuffix(first, "\n"), want: []string{"FIRST"}},
+		"restarted block":    {input: "-----BEGIN BROKEN-----\nAAAA\n" + first, want: []string{"FIRST"}},
+		"malformed block":    {input: "-----BEGIN BROKEN-----\n!!!!\n-----END BROKEN-----\n" + third, want: []string{"THIRD"}},
+		"truncated":          {input: first + third[:20], want: []string{"FIRST"}},
+		"no block":           {input: "not PEM", want: []string{}},
+		"consecutive blocks": {input: first + string(pem.EncodeToMemory(second)) + third, want: []string{"FIRST", "SECOND", "THIRD"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := make([]string, 0)
+			scanner := newPEMBlockScanner(strings.NewReader(test.input))
+			for scanner.Scan() {
+				block := scanner.Block()
+				got = append(got, block.Type)
+				if block.Type == second.Type && !reflect.DeepEqual(block, second) {
+					t.Errorf("expected the headers and bytes of %s to be decoded, got %v", second.Type, block)
+				}
+			}
+			if scanner.Err() != nil {
+				t.Fatalf("scan failed: %s", scanner.Err())
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("expected blocks %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	errRead := errors.New("read failed")
+	scanner := newPEMBlockScanner(io.MultiReader(strings.NewReader(first), iotest.ErrReader(errRead)))
+	if !scanner.Scan() || scanner.Scan() || !errors.Is(scanner.Err(), errRead) {
+		t.Errorf("expected the block before the read error, then the error, got %v", scanner.Err())
+	}
+}
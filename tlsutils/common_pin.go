@@ -0,0 +1,80 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+)
+
+// subjectPublicKeyInfosFromPEM returns the DER SubjectPublicKeyInfo of every certificate, public key
+// and private key block found in data, in order. Blocks of any other type are ignored.
+func subjectPublicKeyInfosFromPEM(data []byte) ([][]byte, error) {
+	spkis := make([][]byte, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		preamble, err := pemBlockToPEMPreamble(block)
+		if err != nil {
+			continue
+		}
+
+		switch preamble {
+		case PreambleCertificate:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse certificate: %w", err)
+			}
+			spkis = append(spkis, cert.RawSubjectPublicKeyInfo)
+		case PreamblePublicKey:
+			spki, err := namedCurveSubjectPublicKeyInfo(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse public key: %w", err)
+			}
+			if _, err = x509.ParsePKIXPublicKey(spki); err != nil {
+				return nil, fmt.Errorf("unable to parse public key: %w", err)
+			}
+			spkis = append(spkis, spki)
+		case PreamblePrivateKeyPKCS8, PreamblePrivateKeyRSA, PreamblePrivateKeyEC:
+			prvKey, err := keyParsers[preamble](block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse private key given PEM preamble '%s': %w", preamble, err)
+			}
+			spki, err := x509.MarshalPKIXPublicKey(prvKey.(crypto.Signer).Public())
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal public key: %w", err)
+			}
+			spkis = append(spkis, spki)
+		}
+	}
+
+	return spkis, nil
+}
+
+// spkiPin returns the base64 encoded SHA-256 of a DER SubjectPublicKeyInfo, as used by
+// HPKP pin-sha256 and Android network security config pins.
+func spkiPin(spki []byte) string {
+	sum := sha256.Sum256(spki)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// validateSPKIPin is a schema.SchemaValidateFunc checking the value is a pin as returned by spkiPin.
+func validateSPKIPin(i interface{}, k string) ([]string, []error) {
+	value, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, []error{fmt.Errorf("%s: expected a base64 encoded SHA-256, got %q", k, value)}
+	}
+
+	return nil, nil
+}
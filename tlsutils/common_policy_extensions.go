@@ -0,0 +1,192 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"slices"
+	"strings"
+)
+
+var (
+	// oidExtensionPolicyMappings is the policy mappings extension of RFC 5280 section 4.2.1.5.
+	oidExtensionPolicyMappings = asn1.ObjectIdentifier{2, 5, 29, 33}
+	// oidExtensionPolicyConstraints is the policy constraints extension of RFC 5280 section 4.2.1.11.
+	oidExtensionPolicyConstraints = asn1.ObjectIdentifier{2, 5, 29, 36}
+	// oidExtensionInhibitAnyPolicy is the inhibit anyPolicy extension of RFC 5280 section 4.2.1.14.
+	oidExtensionInhibitAnyPolicy = asn1.ObjectIdentifier{2, 5, 29, 54}
+	// oidAnyPolicy is the anyPolicy certificate policy, which cannot be mapped.
+	oidAnyPolicy = asn1.ObjectIdentifier{2, 5, 29, 32, 0}
+)
+
+// policyMapping is a PolicyMappings element of RFC 5280 section 4.2.1.5.
+type policyMapping struct {
+	IssuerDomainPolicy  asn1.ObjectIdentifier
+	SubjectDomainPolicy asn1.ObjectIdentifier
+}
+
+// policyConstraints is the PolicyConstraints of RFC 5280 section 4.2.1.11, -1 marking an absent field.
+type policyConstraints struct {
+	RequireExplicitPolicy int `asn1:"optional,tag:0,default:-1"`
+	InhibitPolicyMapping  int `asn1:"optional,tag:1,default:-1"`
+}
+
+// policyExtensionsSchema returns the ForceNew attributes setting the certificate policies and the policy processing
+// extensions of the CA certificate described by certificate, filled by policyExtensions.
+func policyExtensionsSchema(certificate string) map[string]*schema.Schema {
+	skipCerts := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Description:      description,
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		}
+	}
+
+	return map[string]*schema.Schema{
+		"policy_oids": {
+			Description: fmt.Sprintf("certificate policies of %s, as OIDs.", certificate),
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateOID,
+			},
+		},
+		"policy_mappings": {
+			Description: fmt.Sprintf("policies of the issuer domain considered equivalent to policies of the subject domain, as a critical policy mappings extension of %s, e.g. between the domains of a bridge CA. The `issuer_domain_policy` must be in `policy_oids`.", certificate),
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"issuer_domain_policy": {
+						Description:      "policy OID of the domain of the issuer. Must not be anyPolicy.",
+						Type:             schema.TypeString,
+						Required:         true,
+						ForceNew:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validateOID),
+					},
+					"subject_domain_policy": {
+						Description:      "equivalent policy OID of the domain of the subject. Must not be anyPolicy.",
+						Type:             schema.TypeString,
+						Required:         true,
+						ForceNew:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validateOID),
+					},
+				},
+			},
+		},
+		"require_explicit_policy": skipCerts(fmt.Sprintf("number of certificates after %s from which the path must have an acceptable policy, in a critical policy constraints extension.", certificate)),
+		"inhibit_policy_mapping":  skipCerts(fmt.Sprintf("number of certificates after %s from which policy mappings are no longer allowed, in a critical policy constraints extension.", certificate)),
+		"inhibit_any_policy":      skipCerts(fmt.Sprintf("number of certificates after %s from which anyPolicy no longer matches other policies, as a critical inhibit anyPolicy extension.", certificate)),
+	}
+}
+
+// policyExtensions sets the certificate policies and the extensions of the policyExtensionsSchema attributes
+// configured for the resource on template.
+func policyExtensions(d resourceAttributes, template *x509.Certificate) error {
+	for i, raw := range d.Get("policy_oids").([]interface{}) {
+		oid, err := parseOID(raw.(string))
+		if err != nil {
+			return fmt.Errorf("invalid policy_oids.%d: %w", i, err)
+		}
+		template.PolicyIdentifiers = append(template.PolicyIdentifiers, oid)
+	}
+
+	mappings := make([]policyMapping, 0)
+	for i, raw := range d.Get("policy_mappings").([]interface{}) {
+		entry := raw.(map[string]interface{})
+		issuerPolicy, err := parseOID(entry["issuer_domain_policy"].(string))
+		if err != nil {
+			return fmt.Errorf("invalid policy_mappings.%d.issuer_domain_policy: %w", i, err)
+		}
+		subjectPolicy, err := parseOID(entry["subject_domain_policy"].(string))
+		if err != nil {
+			return fmt.Errorf("invalid policy_mappings.%d.subject_domain_policy: %w", i, err)
+		}
+		if issuerPolicy.Equal(oidAnyPolicy) || subjectPolicy.Equal(oidAnyPolicy) {
+			return fmt.Errorf("policy_mappings.%d: anyPolicy cannot be mapped", i)
+		}
+		if !slices.ContainsFunc(template.PolicyIdentifiers, issuerPolicy.Equal) {
+			return fmt.Errorf("policy_mappings.%d: issuer_domain_policy %s is not in policy_oids", i, issuerPolicy)
+		}
+		mappings = append(mappings, policyMapping{issuerPolicy, subjectPolicy})
+	}
+	if len(mappings) > 0 {
+		value, err := asn1.Marshal(mappings)
+		if err != nil {
+			return fmt.Errorf("failed to encode policy mappings: %w", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: oidExtensionPolicyMappings, Critical: true, Value: value})
+	}
+
+	constraints := policyConstraints{RequireExplicitPolicy: -1, InhibitPolicyMapping: -1}
+	if value, ok := configuredAttribute(d, "require_explicit_policy"); ok {
+		constraints.RequireExplicitPolicy = value.(int)
+	}
+	if value, ok := configuredAttribute(d, "inhibit_policy_mapping"); ok {
+		constraints.InhibitPolicyMapping = value.(int)
+	}
+	// RFC 5280 forbids empty policy constraints
+	if constraints.RequireExplicitPolicy >= 0 || constraints.InhibitPolicyMapping >= 0 {
+		value, err := asn1.Marshal(constraints)
+		if err != nil {
+			return fmt.Errorf("failed to encode policy constraints: %w", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: oidExtensionPolicyConstraints, Critical: true, Value: value})
+	}
+
+	if skipCerts, ok := configuredAttribute(d, "inhibit_any_policy"); ok {
+		value, err := asn1.Marshal(skipCerts.(int))
+		if err != nil {
+			return fmt.Errorf("failed to encode inhibit anyPolicy: %w", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: oidExtensionInhibitAnyPolicy, Critical: true, Value: value})
+	}
+
+	return nil
+}
+
+// policyExtensionText describes the value of the policy mappings, policy constraints and inhibit anyPolicy
+// extensions like OpenSSL, nil when it cannot be parsed.
+func policyExtensionText(extension pkix.Extension) []string {
+	switch {
+	case extension.Id.Equal(oidExtensionPolicyMappings):
+		var mappings []policyMapping
+		if rest, err := asn1.Unmarshal(extension.Value, &mappings); err != nil || len(rest) > 0 {
+			return nil
+		}
+		lines := make([]string, 0, len(mappings))
+		for _, mapping := range mappings {
+			lines = append(lines, fmt.Sprintf("%s:%s", mapping.IssuerDomainPolicy, mapping.SubjectDomainPolicy))
+		}
+		return lines
+	case extension.Id.Equal(oidExtensionPolicyConstraints):
+		constraints := policyConstraints{RequireExplicitPolicy: -1, InhibitPolicyMapping: -1}
+		if rest, err := asn1.Unmarshal(extension.Value, &constraints); err != nil || len(rest) > 0 {
+			return nil
+		}
+		fields := make([]string, 0, 2)
+		if constraints.RequireExplicitPolicy >= 0 {
+			fields = append(fields, fmt.Sprintf("Require Explicit Policy:%d", constraints.RequireExplicitPolicy))
+		}
+		if constraints.InhibitPolicyMapping >= 0 {
+			fields = append(fields, fmt.Sprintf("Inhibit Policy Mapping:%d", constraints.InhibitPolicyMapping))
+		}
+		return []string{strings.Join(fields, ", ")}
+	case extension.Id.Equal(oidExtensionInhibitAnyPolicy):
+		var skipCerts int
+		if rest, err := asn1.Unmarshal(extension.Value, &skipCerts); err != nil || len(rest) > 0 {
+			return nil
+		}
+		return []string{fmt.Sprint(skipCerts)}
+	}
+
+	return nil
+}
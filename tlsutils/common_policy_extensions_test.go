@@ -0,0 +1,96 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyExtensions(t *testing.T) {
+	for name, test := range map[string]struct {
+		config map[string]interface{}
+		text   map[string][]string
+		err    string
+	}{
+		"none": {
+			config: map[string]interface{}{},
+			text:   map[string][]string{},
+		},
+		"bridge": {
+			config: map[string]interface{}{
+				"policy_oids": []interface{}{"1.2.3.1", "1.2.3.2"},
+				"policy_mappings": []interface{}{
+					map[string]interface{}{"issuer_domain_policy": "1.2.3.1", "subject_domain_policy": "1.5.6.1"},
+					map[string]interface{}{"issuer_domain_policy": "1.2.3.2", "subject_domain_policy": "1.5.6.2"},
+				},
+				"require_explicit_policy": 2,
+				"inhibit_policy_mapping":  1,
+				"inhibit_any_policy":      3,
+			},
+			text: map[string][]string{
+				"2.5.29.33": {"1.2.3.1:1.5.6.1", "1.2.3.2:1.5.6.2"},
+				"2.5.29.36": {"Require Explicit Policy:2, Inhibit Policy Mapping:1"},
+				"2.5.29.54": {"3"},
+			},
+		},
+		"require explicit policy only": {
+			config: map[string]interface{}{"require_explicit_policy": 1},
+			text:   map[string][]string{"2.5.29.36": {"Require Explicit Policy:1"}},
+		},
+		"issuer policy not in policy_oids": {
+			config: map[string]interface{}{
+				"policy_oids":     []interface{}{"1.2.3.1"},
+				"policy_mappings": []interface{}{map[string]interface{}{"issuer_domain_policy": "1.2.3.2", "subject_domain_policy": "1.5.6.2"}},
+			},
+			err: "policy_mappings.0: issuer_domain_policy 1.2.3.2 is not in policy_oids",
+		},
+		"anyPolicy mapped": {
+			config: map[string]interface{}{
+				"policy_oids":     []interface{}{"2.5.29.32.0"},
+				"policy_mappings": []interface{}{map[string]interface{}{"issuer_domain_policy": "2.5.29.32.0", "subject_domain_policy": "1.5.6.2"}},
+			},
+			err: "policy_mappings.0: anyPolicy cannot be mapped",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, policyExtensionsSchema("the certificate"), test.config)
+			template := &x509.Certificate{}
+			err := policyExtensions(d, template)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text := make(map[string][]string, len(template.ExtraExtensions))
+			for _, extension := range template.ExtraExtensions {
+				if !extension.Critical {
+					t.Errorf("expected %s to be critical", extension.Id)
+				}
+				text[extension.Id.String()] = policyExtensionText(extension)
+			}
+			if !reflect.DeepEqual(text, test.text) {
+				t.Errorf("expected extensions %v, got %v", test.text, text)
+			}
+			if policies, _ := test.config["policy_oids"].([]interface{}); len(policies) != len(template.PolicyIdentifiers) {
+				t.Errorf("expected the policies %v, got %v", policies, template.PolicyIdentifiers)
+			}
+		})
+	}
+}
+
+func TestPolicyExtensionTextInvalid(t *testing.T) {
+	for _, oid := range []asn1.ObjectIdentifier{oidExtensionPolicyMappings, oidExtensionPolicyConstraints, oidExtensionInhibitAnyPolicy} {
+		if got := policyExtensionText(pkix.Extension{Id: oid, Value: []byte{0x30, 0x03}}); got != nil {
+			t.Errorf("expected no text for an invalid %s, got %v", oid, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+	"hash"
+	"math/big"
+	"strings"
+)
+
+// Argon2id parameters of the encrypted PPK version 3 files: the memory and parallelism of puttygen,
+// with a fixed number of passes instead of its time based calibration.
+const (
+	ppkArgon2Memory      = 8192
+	ppkArgon2Passes      = 21
+	ppkArgon2Parallelism = 1
+)
+
+// encodePPK encodes a private key in the PuTTY PPK format version 2 or 3, encrypted with AES-256-CBC
+// when passphrase is set.
+func encodePPK(prvKey crypto.PrivateKey, version int, passphrase string) (string, error) {
+	if k, ok := prvKey.(*ed25519.PrivateKey); ok {
+		prvKey = *k
+	}
+	signer, ok := prvKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+	pubKey, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OpenSSH public key: %w", err)
+	}
+	publicBlob := pubKey.Marshal()
+
+	var privateBlob []byte
+	switch k := prvKey.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return "", fmt.Errorf("multi-prime RSA keys cannot be encoded in PPK")
+		}
+		k.Precompute()
+		privateBlob = ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{k.D, k.Primes[0], k.Primes[1], k.Precomputed.Qinv})
+	case *ecdsa.PrivateKey:
+		privateBlob = ssh.Marshal(struct{ D *big.Int }{k.D})
+	case ed25519.PrivateKey:
+		privateBlob = ssh.Marshal(struct{ Seed []byte }{k.Seed()})
+	default:
+		return "", fmt.Errorf("unsupported private key type: %T", prvKey)
+	}
+
+	encryption := "none"
+	headers := ""
+	var macKey, cipherKey, iv []byte
+	switch {
+	case version == 2:
+		if passphrase != "" {
+			cipherKey = append(ppkV2PassphraseHash(0, passphrase), ppkV2PassphraseHash(1, passphrase)...)[:32]
+			iv = make([]byte, aes.BlockSize)
+		}
+		macKeyHash := sha1.Sum([]byte("putty-private-key-file-mac-key" + passphrase))
+		macKey = macKeyHash[:]
+	case version == 3 && passphrase != "":
+		salt := make([]byte, 16)
+		if _, err = rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate PPK salt: %w", err)
+		}
+		derived := argon2.IDKey([]byte(passphrase), salt, ppkArgon2Passes, ppkArgon2Memory, ppkArgon2Parallelism, 80)
+		cipherKey, iv, macKey = derived[:32], derived[32:48], derived[48:]
+		headers = fmt.Sprintf("Key-Derivation: Argon2id\nArgon2-Memory: %d\nArgon2-Passes: %d\nArgon2-Parallelism: %d\nArgon2-Salt: %s\n",
+			ppkArgon2Memory, ppkArgon2Passes, ppkArgon2Parallelism, hex.EncodeToString(salt))
+	case version == 3:
+		macKey = []byte{}
+	default:
+		return "", fmt.Errorf("unsupported PPK version %d", version)
+	}
+
+	if cipherKey != nil {
+		encryption = "aes256-cbc"
+		// PuTTY pads version 2 with the SHA-1 of the private blob, version 3 with random bytes
+		padding := make([]byte, (aes.BlockSize-len(privateBlob)%aes.BlockSize)%aes.BlockSize)
+		if version == 2 {
+			sum := sha1.Sum(privateBlob)
+			copy(padding, sum[:])
+		} else if _, err = rand.Read(padding); err != nil {
+			return "", fmt.Errorf("failed to generate PPK padding: %w", err)
+		}
+		privateBlob = append(privateBlob, padding...)
+	}
+
+	var mac hash.Hash
+	if version == 2 {
+		mac = hmac.New(sha1.New, macKey)
+	} else {
+		mac = hmac.New(sha256.New, macKey)
+	}
+	comment := ""
+	mac.Write(ssh.Marshal(struct {
+		Algorithm, Encryption, Comment string
+		Public, Private                []byte
+	}{pubKey.Type(), encryption, comment, publicBlob, privateBlob}))
+
+	if cipherKey != nil {
+		block, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to create PPK cipher: %w", err)
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(privateBlob, privateBlob)
+	}
+
+	var ppk strings.Builder
+	fmt.Fprintf(&ppk, "PuTTY-User-Key-File-%d: %s\nEncryption: %s\nComment: %s\n", version, pubKey.Type(), encryption, comment)
+	publicLines := ppkBase64Lines(publicBlob)
+	fmt.Fprintf(&ppk, "Public-Lines: %d\n%s", len(publicLines), strings.Join(publicLines, ""))
+	ppk.WriteString(headers)
+	privateLines := ppkBase64Lines(privateBlob)
+	fmt.Fprintf(&ppk, "Private-Lines: %d\n%s", len(privateLines), strings.Join(privateLines, ""))
+	fmt.Fprintf(&ppk, "Private-MAC: %s\n", hex.EncodeToString(mac.Sum(nil)))
+
+	return ppk.String(), nil
+}
+
+// ppkV2PassphraseHash is the SHA-1 of a sequence number and the passphrase, two of which make the version 2 cipher key.
+func ppkV2PassphraseHash(sequence uint32, passphrase string) []byte {
+	sum := sha1.Sum(append(ssh.Marshal(struct{ N uint32 }{sequence}), passphrase...))
+	return sum[:]
+}
+
+// ppkBase64Lines encodes data in base64, in lines of at most 64 characters.
+func ppkBase64Lines(data []byte) []string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	lines := make([]string, 0, len(encoded)/64+1)
+	for len(encoded) > 0 {
+		n := min(len(encoded), 64)
+		lines = append(lines, encoded[:n]+"\n")
+		encoded = encoded[n:]
+	}
+	return lines
+}
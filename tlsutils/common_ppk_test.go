@@ -0,0 +1,213 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testPPK is a PPK file read following the PuTTY documentation of the format, independently of encodePPK.
+type testPPK struct {
+	version                      int
+	algorithm, encryption        string
+	comment                      string
+	headers                      map[string]string
+	publicBlob, encryptedPrivate []byte
+	mac                          []byte
+}
+
+// testReadPPK splits a PPK file in its headers and base64 blocks, checking the line lengths of PuTTY.
+func testReadPPK(t *testing.T, file string) testPPK {
+	lines := strings.Split(strings.TrimSuffix(file, "\n"), "\n")
+	ppk := testPPK{headers: make(map[string]string)}
+	readBlock := func(count string) []byte {
+		n, err := strconv.Atoi(count)
+		if err != nil || n > len(lines) {
+			t.Fatalf("invalid line count %q", count)
+		}
+		var encoded strings.Builder
+		for _, line := range lines[:n] {
+			if len(line) > 64 {
+				t.Errorf("expected base64 lines of at most 64 characters, got %d", len(line))
+			}
+			encoded.WriteString(line)
+		}
+		lines = lines[n:]
+		decoded, err := base64.StdEncoding.DecodeString(encoded.String())
+		if err != nil {
+			t.Fatalf("invalid base64 block: %s", err)
+		}
+		return decoded
+	}
+
+	for len(lines) > 0 {
+		name, value, ok := strings.Cut(lines[0], ": ")
+		if !ok {
+			t.Fatalf("invalid PPK line %q", lines[0])
+		}
+		lines = lines[1:]
+		switch name {
+		case "PuTTY-User-Key-File-2", "PuTTY-User-Key-File-3":
+			ppk.version = int(name[len(name)-1] - '0')
+			ppk.algorithm = value
+		case "Encryption":
+			ppk.encryption = value
+		case "Comment":
+			ppk.comment = value
+		case "Public-Lines":
+			ppk.publicBlob = readBlock(value)
+		case "Private-Lines":
+			ppk.encryptedPrivate = readBlock(value)
+		case "Private-MAC":
+			mac, err := hex.DecodeString(value)
+			if err != nil {
+				t.Fatalf("invalid Private-MAC: %s", err)
+			}
+			ppk.mac = mac
+		default:
+			ppk.headers[name] = value
+		}
+	}
+	return ppk
+}
+
+// testOpenPPK decrypts the private blob of ppk with passphrase and returns it when the MAC matches.
+func testOpenPPK(t *testing.T, ppk testPPK, passphrase string) ([]byte, bool) {
+	var cipherKey, iv, macKey []byte
+	var mac func() hash.Hash
+	switch ppk.version {
+	case 2:
+		// cipher key: SHA-1 of the sequence numbers 0 and 1 followed by the passphrase, zero IV
+		for sequence := uint32(0); sequence < 2; sequence++ {
+			sum := sha1.Sum(append(binary.BigEndian.AppendUint32(nil, sequence), passphrase...))
+			cipherKey = append(cipherKey, sum[:]...)
+		}
+		cipherKey, iv = cipherKey[:32], make([]byte, aes.BlockSize)
+		sum := sha1.Sum([]byte("putty-private-key-file-mac-key" + passphrase))
+		macKey, mac = sum[:], sha1.New
+	case 3:
+		mac = sha256.New
+		if ppk.encryption != "none" {
+			if ppk.headers["Key-Derivation"] != "Argon2id" {
+				t.Fatalf("expected the Argon2id key derivation, got %q", ppk.headers["Key-Derivation"])
+			}
+			memory, _ := strconv.Atoi(ppk.headers["Argon2-Memory"])
+			passes, _ := strconv.Atoi(ppk.headers["Argon2-Passes"])
+			parallelism, _ := strconv.Atoi(ppk.headers["Argon2-Parallelism"])
+			salt, err := hex.DecodeString(ppk.headers["Argon2-Salt"])
+			if err != nil || memory == 0 || passes == 0 || parallelism == 0 {
+				t.Fatalf("invalid Argon2 parameters %v", ppk.headers)
+			}
+			derived := argon2.IDKey([]byte(passphrase), salt, uint32(passes), uint32(memory), uint8(parallelism), 80)
+			cipherKey, iv, macKey = derived[:32], derived[32:48], derived[48:]
+		}
+	default:
+		t.Fatalf("unexpected PPK version %d", ppk.version)
+	}
+
+	private := append([]byte{}, ppk.encryptedPrivate...)
+	switch ppk.encryption {
+	case "none":
+	case "aes256-cbc":
+		if len(private)%aes.BlockSize != 0 {
+			t.Fatalf("expected whole AES blocks, got %d bytes", len(private))
+		}
+		block, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(private, private)
+	default:
+		t.Fatalf("unexpected encryption %q", ppk.encryption)
+	}
+
+	h := hmac.New(mac, macKey)
+	for _, field := range [][]byte{[]byte(ppk.algorithm), []byte(ppk.encryption), []byte(ppk.comment), ppk.publicBlob, private} {
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		h.Write(field)
+	}
+	return private, hmac.Equal(h.Sum(nil), ppk.mac)
+}
+
+func TestEncodePPK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey.Precompute()
+	for _, key := range []struct {
+		name        string
+		prvKey      crypto.Signer
+		privateBlob []byte
+	}{
+		{"rsa", rsaKey, ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{rsaKey.D, rsaKey.Primes[0], rsaKey.Primes[1], rsaKey.Precomputed.Qinv})},
+		{"ecdsa", ecdsaKey, ssh.Marshal(struct{ D *big.Int }{ecdsaKey.D})},
+		{"ed25519", ed25519Key, ssh.Marshal(struct{ Seed []byte }{ed25519Key.Seed()})},
+	} {
+		for _, version := range []int{2, 3} {
+			for _, passphrase := range []string{"", "correct horse"} {
+				t.Run(key.name+"/v"+strconv.Itoa(version)+"/passphrase="+strconv.FormatBool(passphrase != ""), func(t *testing.T) {
+					encoded, err := encodePPK(key.prvKey, version, passphrase)
+					if err != nil {
+						t.Fatalf("encode failed: %s", err)
+					}
+					ppk := testReadPPK(t, encoded)
+
+					pubKey, err := ssh.NewPublicKey(key.prvKey.Public())
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ppk.version != version || ppk.algorithm != pubKey.Type() || !bytes.Equal(ppk.publicBlob, pubKey.Marshal()) {
+						t.Errorf("unexpected version %d, algorithm %q or public blob", ppk.version, ppk.algorithm)
+					}
+					if wantEncryption := map[bool]string{false: "none", true: "aes256-cbc"}[passphrase != ""]; ppk.encryption != wantEncryption {
+						t.Errorf("expected encryption %s, got %s", wantEncryption, ppk.encryption)
+					}
+
+					private, ok := testOpenPPK(t, ppk, passphrase)
+					if !ok {
+						t.Fatalf("expected the Private-MAC to match")
+					}
+					if passphrase == "" && !bytes.Equal(private, key.privateBlob) {
+						t.Errorf("expected the private blob %x, got %x", key.privateBlob, private)
+					}
+					if !bytes.HasPrefix(private, key.privateBlob) || len(private)-len(key.privateBlob) >= aes.BlockSize {
+						t.Errorf("expected the private blob %x followed by padding, got %x", key.privateBlob, private)
+					}
+					if passphrase != "" {
+						if _, ok = testOpenPPK(t, ppk, "wrong"); ok {
+							t.Errorf("expected the Private-MAC not to match with a wrong passphrase")
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package tlsutils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// oidExtKeyUsageSmartCardLogon is the Microsoft Smart Card Logon extended key usage, szOID_KP_SMARTCARD_LOGON.
+	oidExtKeyUsageSmartCardLogon = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}
+	// oidUserPrincipalName is the otherName type of the Active Directory user principal name, szOID_NT_PRINCIPAL_NAME.
+	oidUserPrincipalName = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+	oidSubjectAltName    = asn1.ObjectIdentifier{2, 5, 29, 17}
+	// oidHardwareModuleName is the otherName type of the hardware module names of RFC 4108 section 5,
+	// id-on-hardwareModuleName, identifying the device of IEEE 802.1AR DevID certificates.
+	oidHardwareModuleName = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 8, 4}
+	// oidExtensionOCSPNoCheck is id-pkix-ocsp-nocheck, RFC 6960 4.2.2.2.1: clients don't check the revocation of
+	// the OCSP responder itself.
+	oidExtensionOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+)
+
+// noWellDefinedExpiration is the not after of the certificates that have no well-defined expiration date,
+// 99991231235959Z, see RFC 5280 section 4.1.2.5. IEEE 802.1AR IDevID certificates live as long as their device.
+var noWellDefinedExpiration = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// certificateProfile is a preset of usages added to a certificate on top of its allowed_uses.
+type certificateProfile struct {
+	keyUsage           x509.KeyUsage
+	extKeyUsage        []x509.ExtKeyUsage
+	unknownExtKeyUsage []asn1.ObjectIdentifier
+	extraExtensions    []pkix.Extension
+	// requiresUPN is set when the certificate is useless without a user_principal_name
+	requiresUPN bool
+	// requiresSubjectSerialNumber is set when the subject must hold the serial number of the device
+	requiresSubjectSerialNumber bool
+	// policyIdentifiers, subject and validityPeriod are set by the certificate_profile blocks of the provider:
+	// the subject attributes and validity are defaults of the resources
+	policyIdentifiers []asn1.ObjectIdentifier
+	subject           pkix.Name
+	validityPeriod    time.Duration
+}
+
+// certificateProfiles provides the usages of each profile.
+var certificateProfiles = map[string]certificateProfile{
+	"tls_server": {
+		keyUsage:    x509.KeyUsageDigitalSignature,
+		extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	},
+	"tls_client": {
+		keyUsage:    x509.KeyUsageDigitalSignature,
+		extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	},
+	// IEEE 802.1AR device identity, IDevID or LDevID, named by the serialNumber of its subject
+	"devid": {
+		keyUsage:                    x509.KeyUsageDigitalSignature,
+		requiresSubjectSerialNumber: true,
+	},
+	// Windows smart card logon, mapped to the account by the UPN
+	"smartcard_logon": {
+		keyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		extKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		unknownExtKeyUsage: []asn1.ObjectIdentifier{oidExtKeyUsageSmartCardLogon},
+		requiresUPN:        true,
+	},
+	// delegated OCSP responder of the issuing CA
+	"ocsp_responder": {
+		keyUsage:        x509.KeyUsageDigitalSignature,
+		extKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		extraExtensions: []pkix.Extension{{Id: oidExtensionOCSPNoCheck, Value: asn1.NullBytes}},
+	},
+}
+
+// supportedCertificateProfilesStr returns the names accepted by profile.
+func supportedCertificateProfilesStr() []string {
+	supported := make([]string, 0, len(certificateProfiles))
+	for name := range certificateProfiles {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return supported
+}
+
+// certificateProfileOf returns the built-in profile name, or the certificate_profile of the provider of that name.
+func certificateProfileOf(name string, m interface{}) (certificateProfile, error) {
+	if profile, ok := certificateProfiles[name]; ok {
+		return profile, nil
+	}
+	if meta, ok := m.(*providerMeta); ok {
+		if profile, ok := meta.certificateProfiles[name]; ok {
+			return profile, nil
+		}
+	}
+
+	return certificateProfile{}, fmt.Errorf("unknown profile %q: expected one of %s or the name of a certificate_profile of the provider",
+		name, strings.Join(supportedCertificateProfilesStr(), ", "))
+}
+
+// applyNamedCertificateProfile adds the usages of profile, called name, to template once the template has what the
+// profile requires. upn is the user_principal_name of the certificate.
+func applyNamedCertificateProfile(template *x509.Certificate, name string, profile certificateProfile, upn string) error {
+	if profile.requiresUPN && upn == "" {
+		return fmt.Errorf("user_principal_name is required with profile %q", name)
+	}
+	if profile.requiresSubjectSerialNumber && template.Subject.SerialNumber == "" {
+		return fmt.Errorf("a subject serial_number is required with profile %q", name)
+	}
+	applyCertificateProfile(template, profile)
+
+	return nil
+}
+
+// applyCertificateProfile adds the usages of profile to template, skipping the ones it already has.
+func applyCertificateProfile(template *x509.Certificate, profile certificateProfile) {
+	template.KeyUsage |= profile.keyUsage
+	for _, usage := range profile.extKeyUsage {
+		if !slices.Contains(template.ExtKeyUsage, usage) {
+			template.ExtKeyUsage = append(template.ExtKeyUsage, usage)
+		}
+	}
+	template.UnknownExtKeyUsage = append(template.UnknownExtKeyUsage, profile.unknownExtKeyUsage...)
+	template.ExtraExtensions = append(template.ExtraExtensions, profile.extraExtensions...)
+	for _, policy := range profile.policyIdentifiers {
+		if !slices.ContainsFunc(template.PolicyIdentifiers, policy.Equal) {
+			template.PolicyIdentifiers = append(template.PolicyIdentifiers, policy)
+		}
+	}
+}
+
+// certificateProfileSchema returns the schema of the certificate_profile blocks of the provider, read by
+// certificateProfilesFromConfig.
+func certificateProfileSchema() *schema.Schema {
+	return &schema.Schema{
+		Description: "named certificate profiles, referenced by the `profile` of the certificate issuing resources like the built-in ones. Changing a profile does not issue the certificates using it again.",
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Description:      "name of the profile. Must differ from the built-in profiles.",
+					Type:             schema.TypeString,
+					Required:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotEmpty),
+				},
+				"allowed_uses": {
+					Description: "key usages and extended key usages added to the certificates, like their `allowed_uses`.",
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validation.StringInSlice(supportedAllowedUsesStr(), false),
+					},
+				},
+				"validity_period_hours": {
+					Description:      "validity of the certificates whose resource does not set one.",
+					Type:             schema.TypeInt,
+					Optional:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+				},
+				"validity_period": {
+					Description:      "`validity_period_hours` as " + validityPeriodDescription + ".",
+					Type:             schema.TypeString,
+					Optional:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validateValidityPeriod),
+				},
+				"subject": subjectDefaultsSchema("subject attributes of the certificates, for the ones their resource leaves empty."),
+				"policy_oids": {
+					Description: "certificate policies added to the certificates, as OIDs.",
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateOID,
+					},
+				},
+				"extension": {
+					Description: "extensions added as is to the certificates.",
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"oid": {
+								Description:  "OID of the extension.",
+								Type:         schema.TypeString,
+								Required:     true,
+								ValidateFunc: validateOID,
+							},
+							"critical": {
+								Description: "mark the extension critical.",
+								Type:        schema.TypeBool,
+								Optional:    true,
+							},
+							"value_base64": {
+								Description:      "DER encoded value of the extension, in base64.",
+								Type:             schema.TypeString,
+								Required:         true,
+								ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// certificateProfilesFromConfig converts the certificate_profile blocks of the provider to profiles by name.
+func certificateProfilesFromConfig(blocks []interface{}) (map[string]certificateProfile, error) {
+	profiles := make(map[string]certificateProfile, len(blocks))
+	for _, raw := range blocks {
+		block := raw.(map[string]interface{})
+		name := block["name"].(string)
+		if _, ok := certificateProfiles[name]; ok {
+			return nil, fmt.Errorf("certificate_profile %q: the name of a built-in profile", name)
+		}
+		if _, ok := profiles[name]; ok {
+			return nil, fmt.Errorf("duplicate certificate_profile %q", name)
+		}
+
+		if block["validity_period"].(string) != "" && block["validity_period_hours"].(int) > 0 {
+			return nil, fmt.Errorf("certificate_profile %q: only one of validity_period_hours and validity_period can be set", name)
+		}
+
+		profile := certificateProfile{
+			validityPeriod: effectiveValidityPeriod(block["validity_period"].(string), block["validity_period_hours"].(int)),
+		}
+		for _, use := range block["allowed_uses"].([]interface{}) {
+			if usage, ok := keyUsages[use.(string)]; ok {
+				profile.keyUsage |= usage
+			}
+			if usage, ok := extKeyUsages[use.(string)]; ok {
+				profile.extKeyUsage = append(profile.extKeyUsage, usage)
+			}
+		}
+		if subjects := block["subject"].([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+			profile.subject = certificateSubject(subjects[0].(map[string]interface{}))
+		}
+		for i, rawOID := range block["policy_oids"].([]interface{}) {
+			oid, err := parseOID(rawOID.(string))
+			if err != nil {
+				return nil, fmt.Errorf("certificate_profile %q: invalid policy_oids.%d: %w", name, i, err)
+			}
+			profile.policyIdentifiers = append(profile.policyIdentifiers, oid)
+		}
+		for i, rawExtension := range block["extension"].([]interface{}) {
+			extension := rawExtension.(map[string]interface{})
+			oid, err := parseOID(extension["oid"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("certificate_profile %q: invalid extension.%d.oid: %w", name, i, err)
+			}
+			value, err := base64.StdEncoding.DecodeString(extension["value_base64"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("certificate_profile %q: invalid extension.%d.value_base64: %w", name, i, err)
+			}
+			profile.extraExtensions = append(profile.extraExtensions, pkix.Extension{Id: oid, Critical: extension["critical"].(bool), Value: value})
+		}
+
+		profiles[name] = profile
+	}
+
+	return profiles, nil
+}
+
+// hardwareModuleNameSchema returns the schema of a hardware_module_name block, read by setOtherNames.
+func hardwareModuleNameSchema(forceNew bool) *schema.Schema {
+	return &schema.Schema{
+		Description: "hardware module of an IEEE 802.1AR device identity, added to the subject alternative names as a hardwareModuleName otherName (RFC 4108).",
+		Type:        schema.TypeList,
+		Optional:    true,
+		ForceNew:    forceNew,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"type": {
+					Description:  "OID of the hardware type, assigned by the manufacturer.",
+					Type:         schema.TypeString,
+					Required:     true,
+					ForceNew:     forceNew,
+					ValidateFunc: validateOID,
+				},
+				"serial_number": {
+					Description: "serial number of the hardware module, encoded as the octets of the text.",
+					Type:        schema.TypeString,
+					Required:    true,
+					ForceNew:    forceNew,
+				},
+			},
+		},
+	}
+}
+
+// setOtherNames adds the otherNames of upn and of the hardware_module_name blocks hardwareModules, when set, to the
+// subject alternative names of template.
+func setOtherNames(template *x509.Certificate, upn string, hardwareModules []interface{}) error {
+	otherNames := make([]asn1.RawValue, 0, 2)
+	if upn != "" {
+		upnValue, err := asn1.MarshalWithParams(upn, "utf8")
+		if err != nil {
+			return fmt.Errorf("failed to encode user_principal_name: %w", err)
+		}
+		otherName, err := encodeOtherName(oidUserPrincipalName, upnValue)
+		if err != nil {
+			return fmt.Errorf("failed to encode user_principal_name: %w", err)
+		}
+		otherNames = append(otherNames, otherName)
+	}
+	if len(hardwareModules) > 0 && hardwareModules[0] != nil {
+		hardwareModule := hardwareModules[0].(map[string]interface{})
+		hwType, err := parseOID(hardwareModule["type"].(string))
+		if err != nil {
+			return fmt.Errorf("invalid hardware_module_name type: %w", err)
+		}
+		otherName, err := hardwareModuleNameOtherName(hwType, []byte(hardwareModule["serial_number"].(string)))
+		if err != nil {
+			return err
+		}
+		otherNames = append(otherNames, otherName)
+	}
+	if len(otherNames) == 0 {
+		return nil
+	}
+
+	extension, err := subjectAltNameWithOtherNames(template, otherNames)
+	if err != nil {
+		return err
+	}
+	template.ExtraExtensions = replaceExtension(template.ExtraExtensions, extension)
+	return nil
+}
+
+// hardwareModuleNameOtherName encodes the hardwareModuleName otherName of the hardware module of type hwType with
+// the serial number hwSerialNum.
+func hardwareModuleNameOtherName(hwType asn1.ObjectIdentifier, hwSerialNum []byte) (asn1.RawValue, error) {
+	value, err := asn1.Marshal(struct {
+		HwType      asn1.ObjectIdentifier
+		HwSerialNum []byte
+	}{hwType, hwSerialNum})
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("failed to encode hardware_module_name: %w", err)
+	}
+	otherName, err := encodeOtherName(oidHardwareModuleName, value)
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("failed to encode hardware_module_name: %w", err)
+	}
+
+	return otherName, nil
+}
+
+// encodeOtherName encodes the otherName GeneralName of the given type holding the DER encoded value.
+func encodeOtherName(typeID asn1.ObjectIdentifier, value []byte) (asn1.RawValue, error) {
+	typeIDBytes, err := asn1.Marshal(typeID)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	explicitValue, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: value})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: append(typeIDBytes, explicitValue...)}, nil
+}
+
+// subjectAltNameWithOtherNames encodes the subject alternative names of template with otherNames, which crypto/x509
+// cannot generate. Added to ExtraExtensions, it replaces the extension built from the template.
+func subjectAltNameWithOtherNames(template *x509.Certificate, otherNames []asn1.RawValue) (pkix.Extension, error) {
+	names := slices.Clone(otherNames)
+	// GeneralName choices: otherName [0], rfc822Name [1], dNSName [2], uniformResourceIdentifier [6], iPAddress [7]
+	for _, email := range template.EmailAddresses {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: []byte(email)})
+	}
+	for _, name := range template.DNSNames {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(name)})
+	}
+	for _, uri := range template.URIs {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 6, Bytes: []byte(uri.String())})
+	}
+	for _, ip := range template.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 7, Bytes: ip})
+	}
+
+	value, err := asn1.Marshal(names)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode subject alternative names: %w", err)
+	}
+
+	// RFC 5280 4.2.1.6: critical when the subject is empty
+	return pkix.Extension{Id: oidSubjectAltName, Critical: len(template.Subject.ToRDNSequence()) == 0, Value: value}, nil
+}
@@ -0,0 +1,257 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertificateProfileDevID(t *testing.T) {
+	key, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "switch", SerialNumber: "SN-0042"},
+		DNSNames:     []string{"switch.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     noWellDefinedExpiration,
+	}
+	if err = applyNamedCertificateProfile(template, "devid", certificateProfiles["devid"], ""); err != nil {
+		t.Fatal(err)
+	}
+	otherName, err := hardwareModuleNameOtherName(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, []byte("HW-0042"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extension, err := subjectAltNameWithOtherNames(template, []asn1.RawValue{otherName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.ExtraExtensions = append(template.ExtraExtensions, extension)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.(crypto.Signer).Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.NotAfter.Equal(noWellDefinedExpiration) || !bytes.Contains(cert.RawTBSCertificate, []byte("99991231235959Z")) {
+		t.Errorf("expected the GeneralizedTime 99991231235959Z as not after, got %s", cert.NotAfter)
+	}
+	if cert.Subject.SerialNumber != "SN-0042" || cert.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Errorf("expected a DevID with serial number SN-0042 and digitalSignature, got %q and key usage %b", cert.Subject.SerialNumber, cert.KeyUsage)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "switch.example.com" {
+		t.Errorf("expected the DNS names of the template to be kept, got %v", cert.DNSNames)
+	}
+
+	var names []asn1.RawValue
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSubjectAltName) {
+			if _, err = asn1.Unmarshal(ext.Value, &names); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(names) != 2 {
+		t.Fatalf("expected two subject alternative names, got %d", len(names))
+	}
+	var hardwareModuleName struct {
+		TypeID asn1.ObjectIdentifier
+		Value  struct {
+			HwType      asn1.ObjectIdentifier
+			HwSerialNum []byte
+		} `asn1:"explicit,tag:0"`
+	}
+	if _, err = asn1.UnmarshalWithParams(names[0].FullBytes, &hardwareModuleName, "tag:0"); err != nil {
+		t.Fatalf("unable to parse the otherName: %s", err)
+	}
+	if !hardwareModuleName.TypeID.Equal(oidHardwareModuleName) || hardwareModuleName.Value.HwType.String() != "1.3.6.1.4.1.99999.1" || string(hardwareModuleName.Value.HwSerialNum) != "HW-0042" {
+		t.Errorf("expected the hardwareModuleName 1.3.6.1.4.1.99999.1 HW-0042, got %+v", hardwareModuleName)
+	}
+}
+
+func TestCertificateProfileDevIDRequiresSerialNumber(t *testing.T) {
+	template := &x509.Certificate{Subject: pkix.Name{CommonName: "switch"}}
+	if err := applyNamedCertificateProfile(template, "devid", certificateProfiles["devid"], ""); err == nil || !strings.Contains(err.Error(), "subject serial_number is required") {
+		t.Errorf("expected a DevID without subject serial number to fail, got %v", err)
+	}
+}
+
+func TestCertificateProfileSmartCardLogon(t *testing.T) {
+	key, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "user"},
+		EmailAddresses: []string{"user@example.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+	}
+	if err = applyNamedCertificateProfile(template, "smartcard_logon", certificateProfiles["smartcard_logon"], "user@corp.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err = setOtherNames(template, "user@corp.example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.(crypto.Signer).Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Errorf("expected digitalSignature and keyEncipherment, got key usage %b", cert.KeyUsage)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth || len(cert.UnknownExtKeyUsage) != 1 || !cert.UnknownExtKeyUsage[0].Equal(oidExtKeyUsageSmartCardLogon) {
+		t.Errorf("expected the Client Authentication and Smart Card Logon extended key usages, got %v and %v", cert.ExtKeyUsage, cert.UnknownExtKeyUsage)
+	}
+	if len(cert.EmailAddresses) != 1 || cert.EmailAddresses[0] != "user@example.com" {
+		t.Errorf("expected the email addresses of the template to be kept, got %v", cert.EmailAddresses)
+	}
+
+	var names []asn1.RawValue
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSubjectAltName) {
+			if _, err = asn1.Unmarshal(ext.Value, &names); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(names) != 2 {
+		t.Fatalf("expected two subject alternative names, got %d", len(names))
+	}
+	var upn struct {
+		TypeID asn1.ObjectIdentifier
+		Value  string `asn1:"explicit,tag:0,utf8"`
+	}
+	if _, err = asn1.UnmarshalWithParams(names[0].FullBytes, &upn, "tag:0"); err != nil {
+		t.Fatalf("unable to parse the otherName: %s", err)
+	}
+	if !upn.TypeID.Equal(oidUserPrincipalName) || upn.Value != "user@corp.example.com" {
+		t.Errorf("expected the user principal name user@corp.example.com, got %+v", upn)
+	}
+
+	template = &x509.Certificate{Subject: pkix.Name{CommonName: "user"}}
+	if err = applyNamedCertificateProfile(template, "smartcard_logon", certificateProfiles["smartcard_logon"], ""); err == nil || !strings.Contains(err.Error(), "user_principal_name is required") {
+		t.Errorf("expected smartcard_logon without user_principal_name to fail, got %v", err)
+	}
+}
+
+func TestCertificateProfileOfProvider(t *testing.T) {
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"certificate_profile": []interface{}{map[string]interface{}{
+			"name":                  "internal_web",
+			"allowed_uses":          []interface{}{"digital_signature", "server_auth"},
+			"validity_period_hours": 720,
+			"subject":               []interface{}{map[string]interface{}{"organization": "Example", "country": "NL"}},
+			"policy_oids":           []interface{}{"1.3.6.1.4.1.99999.1"},
+			"extension":             []interface{}{map[string]interface{}{"oid": "1.3.6.1.4.1.99999.2", "value_base64": "BQA="}},
+		}},
+	}))
+	if diags.HasError() {
+		t.Fatalf("configure failed: %v", diags)
+	}
+
+	profile, err := certificateProfileOf("internal_web", p.Meta())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.validityPeriod != 30*24*time.Hour {
+		t.Errorf("expected the validity of the profile, got %s", profile.validityPeriod)
+	}
+	subject := fillSubjectDefaults(pkix.Name{Country: []string{"DE"}}, profile.subject)
+	if strings.Join(subject.Organization, ",") != "Example" || strings.Join(subject.Country, ",") != "DE" {
+		t.Errorf("expected the subject of the profile for the attributes left empty only, got %s", subject)
+	}
+
+	template := &x509.Certificate{}
+	if err = applyNamedCertificateProfile(template, "internal_web", profile, ""); err != nil {
+		t.Fatal(err)
+	}
+	if template.KeyUsage != x509.KeyUsageDigitalSignature || len(template.ExtKeyUsage) != 1 || template.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("expected the usages of the profile, got %b %v", template.KeyUsage, template.ExtKeyUsage)
+	}
+	if len(template.PolicyIdentifiers) != 1 || !template.PolicyIdentifiers[0].Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}) {
+		t.Errorf("expected the policy of the profile, got %v", template.PolicyIdentifiers)
+	}
+	if len(template.ExtraExtensions) != 1 || !template.ExtraExtensions[0].Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 2}) || !bytes.Equal(template.ExtraExtensions[0].Value, asn1.NullBytes) {
+		t.Errorf("expected the extension of the profile, got %v", template.ExtraExtensions)
+	}
+
+	p = Provider()
+	diags = p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"certificate_profile": []interface{}{map[string]interface{}{"name": "internal_web", "validity_period": "P30D"}},
+	}))
+	if diags.HasError() {
+		t.Fatalf("configure failed: %v", diags)
+	}
+	if profile, err = certificateProfileOf("internal_web", p.Meta()); err != nil || profile.validityPeriod != 30*24*time.Hour {
+		t.Errorf("expected the validity_period of the profile, got %s (%v)", profile.validityPeriod, err)
+	}
+
+	if _, err = certificateProfileOf("devid", p.Meta()); err != nil {
+		t.Errorf("expected the built-in profiles to be found, got %s", err)
+	}
+	if _, err = certificateProfileOf("unknown", p.Meta()); err == nil || !strings.Contains(err.Error(), `unknown profile "unknown"`) {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+
+	for _, blocks := range [][]interface{}{
+		{map[string]interface{}{"name": "devid"}},
+		{map[string]interface{}{"name": "web"}, map[string]interface{}{"name": "web"}},
+		{map[string]interface{}{"name": "web", "validity_period_hours": 24, "validity_period": "P1D"}},
+	} {
+		diags = Provider().Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{"certificate_profile": blocks}))
+		if !diags.HasError() {
+			t.Errorf("expected an error for %v", blocks)
+		}
+	}
+}
+
+func TestCertificateProfileOCSPResponder(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	template := &x509.Certificate{Subject: pkix.Name{CommonName: "ocsp.example.com"}}
+	if err := applyNamedCertificateProfile(template, "ocsp_responder", certificateProfiles["ocsp_responder"], ""); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := testCertificate(t, template, ca, caKey)
+
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Errorf("expected digitalSignature only, got key usage %b", cert.KeyUsage)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageOCSPSigning {
+		t.Errorf("expected the OCSP Signing extended key usage only, got %v", cert.ExtKeyUsage)
+	}
+	var noCheck bool
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(oidExtensionOCSPNoCheck) {
+			noCheck = true
+			if extension.Critical || !bytes.Equal(extension.Value, asn1.NullBytes) {
+				t.Errorf("expected a non-critical NULL id-pkix-ocsp-nocheck, got %+v", extension)
+			}
+		}
+	}
+	if !noCheck {
+		t.Errorf("expected the id-pkix-ocsp-nocheck extension")
+	}
+}
@@ -0,0 +1,228 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ocsp"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// revocationStatus is the outcome of checkRevocation.
+type revocationStatus string
+
+const (
+	revocationStatusGood    revocationStatus = "good"
+	revocationStatusRevoked revocationStatus = "revoked"
+	// no OCSP responder nor CRL distribution point could tell
+	revocationStatusUnknown revocationStatus = "unknown"
+)
+
+// revocationClockSkew is how far in the future the this update time of OCSP responses and CRLs can be, for responders
+// with a clock slightly ahead.
+const revocationClockSkew = 5 * time.Minute
+
+// revocationCheckSchema returns the check_revocation attribute of the resources whose refresh can plan a new
+// certificate once the current one is revoked, see refreshRevocationStatus.
+func revocationCheckSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"check_revocation": {
+			Description: "on refresh, ask the OCSP responders and CRL distribution points of the certificate whether it was revoked, and plan a new certificate if so.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+	}
+}
+
+// refreshRevocationStatus checks whether certificatePEM, issued by issuerPEM, was revoked at the evaluation time of
+// the resource. A revoked certificate removes the resource from the state with a warning, so the next apply issues a
+// new one. When no source can answer, the state is kept and their errors are reported as a warning.
+func refreshRevocationStatus(ctx context.Context, d *schema.ResourceData, m interface{}, client *http.Client, certificatePEM, issuerPEM string) diag.Diagnostics {
+	revoked, diags := revocationDiagnostics(ctx, client, certificatePEM, issuerPEM, now(d, m))
+	if revoked {
+		d.SetId("")
+	}
+
+	return diags
+}
+
+// revocationDiagnostics checks whether certificatePEM, issued by issuerPEM, was revoked at the given time, for the
+// resources dropping the revoked certificates from their state. Revoked certificates and the errors of the sources,
+// when none can answer, are reported as warnings.
+func revocationDiagnostics(ctx context.Context, client *http.Client, certificatePEM, issuerPEM string, at time.Time) (bool, diag.Diagnostics) {
+	cert, err := parsePEMCertificate([]byte(certificatePEM))
+	if err != nil {
+		return false, diag.FromErr(fmt.Errorf("unable to parse certificate: %w", err))
+	}
+
+	issuer, err := parsePEMCertificate([]byte(issuerPEM))
+	if err != nil {
+		return false, diag.FromErr(fmt.Errorf("unable to parse issuer certificate: %w", err))
+	}
+
+	status, errs := checkRevocation(ctx, client, cert, issuer, at)
+	switch status {
+	case revocationStatusRevoked:
+		return true, diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Certificate %s with serial number %s has been revoked", cert.Subject, cert.SerialNumber),
+			Detail:   "The certificate is removed from the state and will be issued again.",
+		}}
+	case revocationStatusUnknown:
+		if len(errs) > 0 {
+			return false, diag.Diagnostics{{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("Unable to check the revocation status of certificate %s with serial number %s", cert.Subject, cert.SerialNumber),
+				Detail:   joinErrors(errs),
+			}}
+		}
+	}
+
+	return false, nil
+}
+
+// checkRevocation asks the OCSP responders of cert, then its CRL distribution points, whether it is revoked at the
+// given time. The first source giving a fresh answer wins; the errors of the sources that failed, stale answers
+// included, are returned along with revocationStatusUnknown when none did.
+func checkRevocation(ctx context.Context, client *http.Client, cert, issuer *x509.Certificate, at time.Time) (revocationStatus, []error) {
+	errs := make([]error, 0)
+
+	for _, server := range cert.OCSPServer {
+		revoked, err := checkOCSP(ctx, client, server, cert, issuer, at)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OCSP responder %s: %w", server, err))
+			continue
+		}
+		return revoked, nil
+	}
+
+	for _, url := range cert.CRLDistributionPoints {
+		revoked, err := checkCRL(ctx, client, url, cert, issuer, at)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CRL %s: %w", url, err))
+			continue
+		}
+		return revoked, nil
+	}
+
+	return revocationStatusUnknown, errs
+}
+
+func checkOCSP(ctx context.Context, client *http.Client, server string, cert, issuer *x509.Certificate, at time.Time) (revocationStatus, error) {
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build OCSP request: %w", err)
+	}
+
+	respBytes, err := doRequest(ctx, client, http.MethodPost, server, map[string]string{"Content-Type": "application/ocsp-request"}, req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ocsp.ParseResponseForCert(respBytes, cert, issuer)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse OCSP response: %w", err)
+	}
+	// a cached or replayed response could predate the revocation
+	if err = checkRevocationFreshness("OCSP response", resp.ThisUpdate, resp.NextUpdate, at); err != nil {
+		return "", err
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return revocationStatusGood, nil
+	case ocsp.Revoked:
+		return revocationStatusRevoked, nil
+	default:
+		return "", fmt.Errorf("responder does not know the certificate")
+	}
+}
+
+func checkCRL(ctx context.Context, client *http.Client, url string, cert, issuer *x509.Certificate, at time.Time) (revocationStatus, error) {
+	respBytes, err := doRequest(ctx, client, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var crl *x509.RevocationList
+	if bytes.HasPrefix(bytes.TrimSpace(respBytes), []byte("-----BEGIN")) {
+		crl, err = parsePEMRevocationList(respBytes)
+	} else {
+		crl, err = x509.ParseRevocationList(respBytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse CRL: %w", err)
+	}
+
+	if err = crl.CheckSignatureFrom(issuer); err != nil {
+		return "", fmt.Errorf("CRL is not signed by the issuer: %w", err)
+	}
+	if err = checkRevocationFreshness("CRL", crl.ThisUpdate, crl.NextUpdate, at); err != nil {
+		return "", err
+	}
+	// a delta only lists the changes since its base CRL, a missing serial number is not a good status
+	baseNumber, err := crlDeltaBaseNumber(crl)
+	if err != nil {
+		return "", err
+	}
+	if baseNumber != nil {
+		return "", fmt.Errorf("delta CRL of base CRL number %s cannot tell alone whether the certificate is revoked", baseNumber)
+	}
+
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return revocationStatusRevoked, nil
+		}
+	}
+
+	return revocationStatusGood, nil
+}
+
+// crlDeltaBaseNumber returns the base CRL number of the delta CRL indicator extension of crl, nil when crl is a
+// complete CRL.
+func crlDeltaBaseNumber(crl *x509.RevocationList) (*big.Int, error) {
+	for _, extension := range crl.Extensions {
+		if !extension.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			continue
+		}
+		baseNumber := new(big.Int)
+		if rest, err := asn1.Unmarshal(extension.Value, &baseNumber); err != nil || len(rest) > 0 {
+			return nil, fmt.Errorf("invalid delta CRL indicator extension")
+		}
+		return baseNumber, nil
+	}
+
+	return nil, nil
+}
+
+// checkRevocationFreshness fails when the OCSP response or CRL described by what, with the given this and next update
+// times, is not valid at the given time. A zero nextUpdate means newer information is always available, as in
+// RFC 6960.
+func checkRevocationFreshness(what string, thisUpdate, nextUpdate, at time.Time) error {
+	if thisUpdate.After(at.Add(revocationClockSkew)) {
+		return fmt.Errorf("%s is not valid before %s", what, thisUpdate.UTC().Format(time.RFC3339))
+	}
+	if !nextUpdate.IsZero() && at.After(nextUpdate) {
+		return fmt.Errorf("%s expired at %s", what, nextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// joinErrors formats errs on one line each, for diagnostics details.
+func joinErrors(errs []error) string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,195 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/crypto/ocsp"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRevokedSerialNumber is the serial number revoked by the responders of testRevocationServer.
+var testRevokedSerialNumber = big.NewInt(42)
+
+// testRevocationSources are the validity windows of the OCSP response and of the CRL served by testRevocationServer.
+type testRevocationSources struct {
+	ocspThisUpdate, ocspNextUpdate time.Time
+	crlThisUpdate, crlNextUpdate   time.Time
+	// crlDelta serves a delta CRL of base CRL number 1
+	crlDelta bool
+}
+
+// testRevocationServer serves an OCSP responder on /ocsp and a CRL on /crl for issuer, with the validity windows of
+// sources. Only testRevokedSerialNumber is revoked.
+func testRevocationServer(t *testing.T, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey, sources *testRevocationSources) *httptest.Server {
+	t.Helper()
+
+	revokedAt := time.Now().Add(-time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		var err error
+		switch r.URL.Path {
+		case "/ocsp":
+			var reqBytes []byte
+			var req *ocsp.Request
+			if reqBytes, err = io.ReadAll(r.Body); err == nil {
+				req, err = ocsp.ParseRequest(reqBytes)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			status := ocsp.Good
+			if req.SerialNumber.Cmp(testRevokedSerialNumber) == 0 {
+				status = ocsp.Revoked
+			}
+			body, err = ocsp.CreateResponse(issuer, issuer, ocsp.Response{
+				Status:       status,
+				SerialNumber: req.SerialNumber,
+				RevokedAt:    revokedAt,
+				ThisUpdate:   sources.ocspThisUpdate,
+				NextUpdate:   sources.ocspNextUpdate,
+			}, issuerKey)
+		case "/crl":
+			template := &x509.RevocationList{
+				Number:                    big.NewInt(2),
+				ThisUpdate:                sources.crlThisUpdate,
+				NextUpdate:                sources.crlNextUpdate,
+				RevokedCertificateEntries: []x509.RevocationListEntry{{SerialNumber: testRevokedSerialNumber, RevocationTime: revokedAt}},
+			}
+			if sources.crlDelta {
+				template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionDeltaCRLIndicator, Critical: true, Value: []byte{0x02, 0x01, 0x01}}}
+			}
+			body, err = x509.CreateRevocationList(rand.Reader, template, issuer, issuerKey)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// testRevocationCertificate issues a certificate of the given serial number by issuer, pointing to the OCSP responder
+// and CRL of server.
+func testRevocationCertificate(t *testing.T, server *httptest.Server, serialNumber *big.Int, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	cert, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "www.example.com"},
+		OCSPServer:            []string{server.URL + "/ocsp"},
+		CRLDistributionPoints: []string{server.URL + "/crl"},
+	}, issuer, issuerKey)
+
+	return cert
+}
+
+// testRefreshRevocation refreshes a state of r with check_revocation set and the given attributes, returning nil when
+// the resource was removed from the state.
+func testRefreshRevocation(t *testing.T, r *schema.Resource, attributes map[string]string) (*terraform.InstanceState, diag.Diagnostics) {
+	t.Helper()
+
+	state := &terraform.InstanceState{ID: "test", Attributes: map[string]string{"id": "test", "check_revocation": "true"}}
+	for name, value := range attributes {
+		state.Attributes[name] = value
+	}
+
+	return r.RefreshWithoutUpgrade(context.Background(), state, &providerMeta{})
+}
+
+func TestCheckRevocationFreshness(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	at := time.Now().UTC().Truncate(time.Second)
+
+	var sources testRevocationSources
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	cert := testRevocationCertificate(t, server, testRevokedSerialNumber, issuer, issuerKey)
+
+	fresh := [2]time.Time{at.Add(-time.Hour), at.Add(time.Hour)}
+	expired := [2]time.Time{at.Add(-48 * time.Hour), at.Add(-24 * time.Hour)}
+	future := [2]time.Time{at.Add(time.Hour), at.Add(48 * time.Hour)}
+	tests := map[string]struct {
+		ocsp, crl [2]time.Time
+		crlDelta  bool
+		status    revocationStatus
+		errors    []string
+	}{
+		"fresh OCSP response":                {ocsp: fresh, crl: expired, status: revocationStatusRevoked},
+		"OCSP response without next update":  {ocsp: [2]time.Time{fresh[0]}, crl: expired, status: revocationStatusRevoked},
+		"expired OCSP response, fresh CRL":   {ocsp: expired, crl: fresh, status: revocationStatusRevoked},
+		"future OCSP response, fresh CRL":    {ocsp: future, crl: fresh, status: revocationStatusRevoked},
+		"expired OCSP response, expired CRL": {ocsp: expired, crl: expired, status: revocationStatusUnknown, errors: []string{"OCSP response expired at", "CRL expired at"}},
+		"future OCSP response, future CRL":   {ocsp: future, crl: future, status: revocationStatusUnknown, errors: []string{"OCSP response is not valid before", "CRL is not valid before"}},
+		"expired OCSP response, delta CRL":   {ocsp: expired, crl: fresh, crlDelta: true, status: revocationStatusUnknown, errors: []string{"OCSP response expired at", "delta CRL of base CRL number 1 cannot tell alone"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sources = testRevocationSources{test.ocsp[0], test.ocsp[1], test.crl[0], test.crl[1], test.crlDelta}
+
+			status, errs := checkRevocation(context.Background(), server.Client(), cert, issuer, at)
+			if status != test.status {
+				t.Errorf("expected status %s, got %s (%v)", test.status, status, errs)
+			}
+			if status != revocationStatusUnknown {
+				return
+			}
+			if len(errs) != len(test.errors) {
+				t.Fatalf("expected %d errors, got %v", len(test.errors), errs)
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), test.errors[i]) {
+					t.Errorf("expected error %d to contain %q, got %q", i, test.errors[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshRevocationStatus(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	sources := testRevocationSources{ocspThisUpdate: time.Now().Add(-time.Hour), ocspNextUpdate: time.Now().Add(time.Hour), crlThisUpdate: time.Now().Add(-time.Hour), crlNextUpdate: time.Now().Add(time.Hour)}
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	revoked := testRevocationCertificate(t, server, testRevokedSerialNumber, issuer, issuerKey)
+	good := testRevocationCertificate(t, server, big.NewInt(43), issuer, issuerKey)
+
+	r := resourceVaultPKISignedCert()
+	state, diags := testRefreshRevocation(t, r, map[string]string{"certificate_pem": certificateToPEM(revoked), "issuing_ca_pem": certificateToPEM(issuer)})
+	if state != nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Summary, "serial number 42 has been revoked") {
+		t.Errorf("expected a revoked certificate to be removed from the state with a warning, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, map[string]string{"certificate_pem": certificateToPEM(good), "issuing_ca_pem": certificateToPEM(issuer)})
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected a good certificate to be kept, got %v", diags)
+	}
+
+	sources.ocspNextUpdate = time.Now().Add(-time.Minute)
+	sources.crlNextUpdate = sources.ocspNextUpdate
+	state, diags = testRefreshRevocation(t, r, map[string]string{"certificate_pem": certificateToPEM(revoked), "issuing_ca_pem": certificateToPEM(issuer)})
+	if state == nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Summary, "Unable to check the revocation status") {
+		t.Errorf("expected the state to be kept with a warning when no source can answer, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, map[string]string{"check_revocation": "false", "certificate_pem": certificateToPEM(revoked), "issuing_ca_pem": certificateToPEM(issuer)})
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected no check without check_revocation, got %v", diags)
+	}
+}
@@ -0,0 +1,29 @@
+package tlsutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// priorTimeReached reports whether the RFC3339 time of key in the prior state, moved by offset, is reached. The plan
+// of a rotation or renewal leaves key unknown, so Update decides like CustomizeDiff did from the prior state.
+func priorTimeReached(d resourceChanges, m interface{}, key string, offset time.Duration) (bool, error) {
+	value, _ := d.GetChange(key)
+	at, err := time.Parse(time.RFC3339, value.(string))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s in state: %w", key, err)
+	}
+
+	return !now(d, m).Before(at.Add(offset)), nil
+}
+
+// rotationDue reports whether rotation_interval_hours have passed since rotated_at, for the resources rotating
+// generated keys in-place.
+func rotationDue(d resourceChanges, m interface{}) (bool, error) {
+	interval := d.Get("rotation_interval_hours").(int)
+	if interval == 0 {
+		return false, nil
+	}
+
+	return priorTimeReached(d, m, "rotated_at", time.Duration(interval)*time.Hour)
+}
@@ -0,0 +1,83 @@
+package tlsutils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// shamirSplit splits secret into parts shares, any threshold of which can rebuild it.
+// Each byte is shared with a random polynomial over GF(2^8), the field of AES.
+// Shares use the format of github.com/hashicorp/vault/shamir: the y values followed by the x coordinate.
+func shamirSplit(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("cannot split an empty secret")
+	}
+	if threshold < 2 || threshold > parts || parts > 255 {
+		return nil, fmt.Errorf("invalid threshold %d for %d shares", threshold, parts)
+	}
+
+	xCoordinates, err := shamirXCoordinates(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = xCoordinates[i]
+	}
+
+	coefficients := make([]byte, threshold)
+	for idx, value := range secret {
+		coefficients[0] = value
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to generate polynomial: %w", err)
+		}
+		for i, x := range xCoordinates {
+			shares[i][idx] = gf256Evaluate(coefficients, x)
+		}
+	}
+
+	return shares, nil
+}
+
+// shamirXCoordinates returns parts distinct, random and non-zero x coordinates.
+func shamirXCoordinates(parts int) ([]byte, error) {
+	candidates := make([]byte, 255)
+	for i := range candidates {
+		candidates[i] = byte(i + 1)
+	}
+
+	for i := len(candidates) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate x coordinates: %w", err)
+		}
+		candidates[i], candidates[j.Int64()] = candidates[j.Int64()], candidates[i]
+	}
+
+	return candidates[:parts], nil
+}
+
+// gf256Evaluate evaluates the polynomial with the given coefficients, lowest degree first, at x.
+func gf256Evaluate(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gf256Mul(result, x) ^ coefficients[i]
+	}
+
+	return result
+}
+
+// gf256Mul multiplies a and b modulo x^8 + x^4 + x^3 + x + 1, without data dependent branches.
+func gf256Mul(a, b byte) byte {
+	result := byte(0)
+	for i := 0; i < 8; i++ {
+		result ^= a & -(b & 1)
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+
+	return result
+}
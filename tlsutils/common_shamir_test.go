@@ -0,0 +1,141 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/bits"
+	"testing"
+)
+
+// testGF256Inverse returns the multiplicative inverse of a, a^254 in GF(2^8).
+func testGF256Inverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gf256Mul(result, a)
+	}
+	return result
+}
+
+// testShamirCombine rebuilds the secret from shares in the github.com/hashicorp/vault/shamir format, interpolating
+// the polynomial of each byte at x = 0 like its Combine.
+func testShamirCombine(shares [][]byte) []byte {
+	secret := make([]byte, len(shares[0])-1)
+	for idx := range secret {
+		for i, share := range shares {
+			xi := share[len(share)-1]
+			basis := byte(1)
+			for j, other := range shares {
+				if i == j {
+					continue
+				}
+				xj := other[len(other)-1]
+				// (0 - xj) / (xi - xj), subtraction being addition in GF(2^8)
+				basis = gf256Mul(basis, gf256Mul(xj, testGF256Inverse(xi^xj)))
+			}
+			secret[idx] ^= gf256Mul(share[idx], basis)
+		}
+	}
+	return secret
+}
+
+// testShamirSubset returns the shares selected by the bits of mask.
+func testShamirSubset(shares [][]byte, mask int) [][]byte {
+	subset := make([][]byte, 0, bits.OnesCount(uint(mask)))
+	for i, share := range shares {
+		if mask&(1<<i) != 0 {
+			subset = append(subset, share)
+		}
+	}
+	return subset
+}
+
+func TestGF256Mul(t *testing.T) {
+	// the field tests of github.com/hashicorp/vault/shamir and FIPS 197 section 4.2
+	for _, test := range []struct{ a, b, product byte }{
+		{3, 7, 9}, {3, 0, 0}, {0, 3, 0}, {0x57, 0x83, 0xc1}, {0x57, 0x13, 0xfe},
+	} {
+		if got := gf256Mul(test.a, test.b); got != test.product {
+			t.Errorf("expected %#x * %#x = %#x, got %#x", test.a, test.b, test.product, got)
+		}
+	}
+	for a := 1; a < 256; a++ {
+		if got := gf256Mul(byte(a), testGF256Inverse(byte(a))); got != 1 {
+			t.Fatalf("expected %#x times its inverse to be 1, got %#x", a, got)
+		}
+	}
+}
+
+func TestShamirSplitSubsets(t *testing.T) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	for parts := 2; parts <= 5; parts++ {
+		for threshold := 2; threshold <= parts; threshold++ {
+			shares, err := shamirSplit(secret, parts, threshold)
+			if err != nil {
+				t.Fatalf("split %d of %d failed: %s", threshold, parts, err)
+			}
+			xCoordinates := make(map[byte]bool)
+			for _, share := range shares {
+				if len(share) != len(secret)+1 {
+					t.Fatalf("expected shares of %d bytes, got %d", len(secret)+1, len(share))
+				}
+				x := share[len(share)-1]
+				if x == 0 || xCoordinates[x] {
+					t.Fatalf("expected distinct non-zero x coordinates, got %d twice or zero", x)
+				}
+				xCoordinates[x] = true
+			}
+
+			for mask := 1; mask < 1<<parts; mask++ {
+				subset := testShamirSubset(shares, mask)
+				combined := testShamirCombine(subset)
+				switch {
+				case len(subset) >= threshold && !bytes.Equal(combined, secret):
+					t.Errorf("expected shares %b of a %d of %d split to rebuild the secret", mask, threshold, parts)
+				case len(subset) < threshold && bytes.Equal(combined, secret):
+					t.Errorf("expected the %d shares %b of a %d of %d split not to rebuild the secret", len(subset), mask, threshold, parts)
+				}
+			}
+		}
+	}
+}
+
+func TestShamirCombineKnownAnswer(t *testing.T) {
+	// shares of "*\x00" (0x2a 0x00) for the polynomials 0x2a + x + 2x^2 and 0 + 0x80x + 0x40x^2, evaluated by hand
+	// at x = 1, 2 and 3 and written in the vault format: the y values followed by x
+	shares := make([][]byte, 0, 3)
+	for _, share := range []string{"29c001", "200002", "23c003"} {
+		decoded, err := hex.DecodeString(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares = append(shares, decoded)
+	}
+	if got := testShamirCombine(shares); !bytes.Equal(got, []byte{0x2a, 0x00}) {
+		t.Errorf("expected the secret 2a00, got %x", got)
+	}
+
+	for i, x := range []byte{1, 2, 3} {
+		coefficients := [][]byte{{0x2a, 0x01, 0x02}, {0x00, 0x80, 0x40}}
+		for idx, polynomial := range coefficients {
+			if got := gf256Evaluate(polynomial, x); got != shares[i][idx] {
+				t.Errorf("expected byte %d of share %d to be %#x, got %#x", idx, x, shares[i][idx], got)
+			}
+		}
+	}
+}
+
+func TestShamirSplitInvalid(t *testing.T) {
+	for _, test := range []struct{ parts, threshold int }{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := shamirSplit([]byte("secret"), test.parts, test.threshold); err == nil {
+			t.Errorf("expected %d of %d to be rejected", test.threshold, test.parts)
+		}
+	}
+	if _, err := shamirSplit(nil, 3, 2); err == nil {
+		t.Errorf("expected an empty secret to be rejected")
+	}
+}
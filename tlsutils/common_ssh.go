@@ -0,0 +1,39 @@
+package tlsutils
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+// parseAnyPublicKey parses a public key in OpenSSH authorized_keys format, or the first public key, certificate
+// or private key of PEM data.
+func parseAnyPublicKey(data string) (crypto.PublicKey, error) {
+	if !strings.HasPrefix(strings.TrimSpace(data), "-----BEGIN") {
+		sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse OpenSSH public key: %w", err)
+		}
+		cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported OpenSSH public key type %s", sshKey.Type())
+		}
+		return cryptoKey.CryptoPublicKey(), nil
+	}
+
+	spkis, err := subjectPublicKeyInfosFromPEM([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(spkis) == 0 {
+		return nil, fmt.Errorf("PEM does not contain any public key, certificate or private key")
+	}
+	pubKey, err := x509.ParsePKIXPublicKey(spkis[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %w", err)
+	}
+
+	return pubKey, nil
+}
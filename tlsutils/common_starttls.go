@@ -0,0 +1,295 @@
+package tlsutils
+
+import (
+	"bufio"
+	"context"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// starttlsNegotiators upgrade a plaintext connection to the point where the TLS handshake starts,
+// for each protocol supported by starttls. They return the reader used for the negotiation:
+// the server never sends data before the ClientHello, so anything left in it is an error.
+var starttlsNegotiators = map[string]func(conn net.Conn) (*bufio.Reader, error){
+	"smtp":     starttlsSMTP,
+	"imap":     starttlsIMAP,
+	"pop3":     starttlsPOP3,
+	"ldap":     starttlsLDAP,
+	"postgres": starttlsPostgres,
+}
+
+// supportedSTARTTLSProtocolsStr returns the protocols accepted by starttls.
+func supportedSTARTTLSProtocolsStr() []string {
+	return []string{"smtp", "imap", "pop3", "ldap", "postgres"}
+}
+
+// negotiateSTARTTLS asks the server on conn to switch to TLS with the given protocol.
+func negotiateSTARTTLS(ctx context.Context, conn net.Conn, protocol string) error {
+	negotiator, ok := starttlsNegotiators[protocol]
+	if !ok {
+		return fmt.Errorf("unsupported STARTTLS protocol %q", protocol)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	reader, err := negotiator(conn)
+	if err != nil {
+		return fmt.Errorf("failed %s STARTTLS negotiation: %w", protocol, err)
+	}
+	if reader.Buffered() > 0 {
+		return fmt.Errorf("failed %s STARTTLS negotiation: unexpected data from the server", protocol)
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
+func starttlsSMTP(conn net.Conn) (*bufio.Reader, error) {
+	reader := bufio.NewReader(conn)
+	text := textproto.NewReader(reader)
+
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(conn, "EHLO tlsutils\r\n"); err != nil {
+		return nil, err
+	}
+	_, extensions, err := text.ReadResponse(250)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(strings.ToUpper(extensions), "STARTTLS") {
+		return nil, fmt.Errorf("server does not offer STARTTLS")
+	}
+	if _, err = io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return nil, err
+	}
+	if _, _, err = text.ReadResponse(220); err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+func starttlsIMAP(conn net.Conn) (*bufio.Reader, error) {
+	reader := bufio.NewReader(conn)
+	text := textproto.NewReader(reader)
+
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return nil, fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	if _, err = io.WriteString(conn, "a1 STARTTLS\r\n"); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if !strings.HasPrefix(line, "a1 OK") {
+				return nil, fmt.Errorf("server refused STARTTLS: %s", line)
+			}
+			return reader, nil
+		}
+	}
+}
+
+func starttlsPOP3(conn net.Conn) (*bufio.Reader, error) {
+	reader := bufio.NewReader(conn)
+	text := textproto.NewReader(reader)
+
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return nil, fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	if _, err = io.WriteString(conn, "STLS\r\n"); err != nil {
+		return nil, err
+	}
+	line, err := text.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return nil, fmt.Errorf("server refused STLS: %s", line)
+	}
+
+	return reader, nil
+}
+
+// ldapStartTLSOID is the name of the StartTLS extended operation, from RFC 4511 section 4.14.
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+func starttlsLDAP(conn net.Conn) (*bufio.Reader, error) {
+	// LDAPMessage { messageID 1, extendedReq [APPLICATION 23] { requestName [0] ldapStartTLSOID } }
+	request, err := asn1.Marshal(struct {
+		MessageID int
+		Request   asn1.RawValue
+	}{1, asn1.RawValue{Class: asn1.ClassApplication, Tag: 23, IsCompound: true, Bytes: append([]byte{0x80, byte(len(ldapStartTLSOID))}, ldapStartTLSOID...)}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	message, err := readBERElement(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// LDAPMessage { messageID, extendedResp [APPLICATION 24] { resultCode ENUMERATED, ... } }. Servers like Active
+	// Directory use non-minimal BER lengths encoding/asn1 rejects, so the elements are walked by hand.
+	envelope, _, err := parseBERElement(message)
+	if err != nil || envelope.class != asn1.ClassUniversal || envelope.tag != asn1.TagSequence {
+		return nil, fmt.Errorf("unable to parse LDAP response: not a sequence")
+	}
+	messageID, rest, err := parseBERElement(envelope.content)
+	if err != nil || messageID.class != asn1.ClassUniversal || messageID.tag != asn1.TagInteger {
+		return nil, fmt.Errorf("unable to parse LDAP response: invalid message ID")
+	}
+	response, _, err := parseBERElement(rest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse LDAP response: %w", err)
+	}
+	if response.class != asn1.ClassApplication || response.tag != 24 {
+		return nil, fmt.Errorf("unexpected LDAP response with tag %d", response.tag)
+	}
+	resultCode, _, err := parseBERElement(response.content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse LDAP result code: %w", err)
+	}
+	if resultCode.class != asn1.ClassUniversal || resultCode.tag != asn1.TagEnum || len(resultCode.content) == 0 || new(big.Int).SetBytes(resultCode.content).Sign() != 0 {
+		return nil, fmt.Errorf("server refused StartTLS with result code %x", resultCode.content)
+	}
+
+	return reader, nil
+}
+
+// berMaxElementSize bounds the BER elements read from servers.
+const berMaxElementSize = 1 << 20
+
+// berElement is a BER element with a low tag number and a definite length.
+type berElement struct {
+	class   int
+	tag     int
+	content []byte
+}
+
+// parseBERLength decodes the definite length following the identifier octet of a BER element in data. Long form
+// lengths may use up to 4 octets and leading zeros. It returns the length and the number of octets encoding it.
+func parseBERLength(data []byte) (int, int, error) {
+	if len(data) == 0 {
+		return 0, 0, fmt.Errorf("truncated BER length")
+	}
+	if data[0]&0x80 == 0 {
+		return int(data[0]), 1, nil
+	}
+	count := int(data[0] & 0x7f)
+	if count == 0 || count > 4 {
+		return 0, 0, fmt.Errorf("unsupported BER length encoding")
+	}
+	if len(data) < 1+count {
+		return 0, 0, fmt.Errorf("truncated BER length")
+	}
+	length := 0
+	for _, b := range data[1 : 1+count] {
+		length = length<<8 | int(b)
+	}
+	if length > berMaxElementSize {
+		return 0, 0, fmt.Errorf("BER element of %d bytes is too large", length)
+	}
+
+	return length, 1 + count, nil
+}
+
+// parseBERElement decodes the first BER element of data, and returns it with the bytes following it.
+func parseBERElement(data []byte) (berElement, []byte, error) {
+	if len(data) == 0 {
+		return berElement{}, nil, fmt.Errorf("truncated BER element")
+	}
+	if data[0]&0x1f == 0x1f {
+		return berElement{}, nil, fmt.Errorf("unsupported BER tag encoding")
+	}
+	length, lengthSize, err := parseBERLength(data[1:])
+	if err != nil {
+		return berElement{}, nil, err
+	}
+	end := 1 + lengthSize + length
+	if len(data) < end {
+		return berElement{}, nil, fmt.Errorf("truncated BER element")
+	}
+
+	return berElement{
+		class:   int(data[0] >> 6),
+		tag:     int(data[0] & 0x1f),
+		content: data[1+lengthSize : end],
+	}, data[end:], nil
+}
+
+// readBERElement reads one complete BER element with a definite length from reader.
+func readBERElement(reader *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if header[1]&0x80 != 0 {
+		lengthBytes := make([]byte, header[1]&0x7f)
+		if len(lengthBytes) == 0 || len(lengthBytes) > 4 {
+			return nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+			return nil, err
+		}
+		header = append(header, lengthBytes...)
+	}
+	length, _, err := parseBERLength(header[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+
+	return append(header, content...), nil
+}
+
+// postgresSSLRequestCode is the code of the SSLRequest message of the PostgreSQL frontend/backend protocol.
+const postgresSSLRequestCode = 80877103
+
+func starttlsPostgres(conn net.Conn) (*bufio.Reader, error) {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	answer, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if answer != 'S' {
+		return nil, fmt.Errorf("server does not accept SSL connections")
+	}
+
+	return reader, nil
+}
@@ -0,0 +1,150 @@
+package tlsutils
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBERLongForm encodes a BER element with the non-minimal 4 octets length Active Directory sends.
+func testBERLongForm(identifier byte, content []byte) []byte {
+	element := []byte{identifier, 0x84, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(element[2:], uint32(len(content)))
+	return append(element, content...)
+}
+
+// testLDAPStartTLSResponse is the StartTLS ExtendedResponse of Active Directory with the given result code.
+func testLDAPStartTLSResponse(resultCode byte) []byte {
+	extendedResponse := []byte{0x0a, 0x01, resultCode, 0x04, 0x00, 0x04, 0x00, 0x8a, byte(len(ldapStartTLSOID))}
+	extendedResponse = append(extendedResponse, ldapStartTLSOID...)
+	message := append([]byte{0x02, 0x01, 0x01}, testBERLongForm(0x78, extendedResponse)...)
+	return testBERLongForm(0x30, message)
+}
+
+func TestNegotiateSTARTTLSLDAP(t *testing.T) {
+	for name, tt := range map[string]struct {
+		response []byte
+		err      string
+	}{
+		"active directory":  {response: testLDAPStartTLSResponse(0)},
+		"refused":           {response: testLDAPStartTLSResponse(0x34), err: "server refused StartTLS with result code 34"},
+		"not a sequence":    {response: []byte{0x04, 0x00}, err: "not a sequence"},
+		"length too long":   {response: []byte{0x30, 0x85, 0, 0, 0, 0, 0}, err: "unsupported BER length encoding"},
+		"truncated message": {response: []byte{0x30, 0x84, 0, 0, 0, 0x05, 0x02, 0x01, 0x01, 0x78, 0x84}, err: "truncated BER length"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				defer server.Close()
+				if _, err := readBERElement(bufio.NewReader(server)); err != nil {
+					return
+				}
+				_, _ = server.Write(tt.response)
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := negotiateSTARTTLS(ctx, client, "ldap")
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseBERElement(t *testing.T) {
+	element, rest, err := parseBERElement([]byte{0x02, 0x81, 0x01, 0x07, 0xff})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if element.tag != 2 || string(element.content) != "\x07" || string(rest) != "\xff" {
+		t.Errorf("unexpected element %+v, rest %x", element, rest)
+	}
+
+	if _, _, err = parseBERElement([]byte{0x02, 0x84, 0x7f, 0xff, 0xff, 0xff}); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("expected a too large error, got %v", err)
+	}
+}
+
+// testSTARTTLSServer accepts connections on a local address, plays the server side of dialog, alternating the lines
+// it sends and the line it expects, then does the TLS handshake with certificate. It returns the address as host:port.
+func testSTARTTLSServer(t *testing.T, certificate tls.Certificate, dialog ...string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				text := textproto.NewReader(bufio.NewReader(conn))
+				for i, line := range dialog {
+					if i%2 == 0 {
+						if _, err := io.WriteString(conn, line); err != nil {
+							return
+						}
+					} else if got, err := text.ReadLine(); err != nil || got != line {
+						return
+					}
+				}
+				_ = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{certificate}}).Handshake()
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestDataSourceEndpointCertificateSTARTTLS(t *testing.T) {
+	cert, key := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "mail.example.com"}}, nil, nil)
+	certificate := tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}
+
+	for protocol, dialog := range map[string][]string{
+		"smtp": {"220 mail.example.com ESMTP\r\n", "EHLO tlsutils", "250-mail.example.com\r\n250 STARTTLS\r\n", "STARTTLS", "220 Ready to start TLS\r\n"},
+		"imap": {"* OK IMAP4rev1 ready\r\n", "a1 STARTTLS", "a1 OK Begin TLS negotiation now\r\n"},
+		"pop3": {"+OK POP3 ready\r\n", "STLS", "+OK Begin TLS negotiation\r\n"},
+	} {
+		t.Run(protocol, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+				"address":  testSTARTTLSServer(t, certificate, dialog...),
+				"starttls": protocol,
+			})
+			if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+			if got := d.Get("certificate_pem").(string); got != certificateToPEM(cert) {
+				t.Errorf("expected the certificate served after STARTTLS, got %q", got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":  testSTARTTLSServer(t, certificate, "220 mail.example.com ESMTP\r\n", "EHLO tlsutils", "250 mail.example.com\r\n"),
+		"starttls": "smtp",
+	})
+	if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "failed smtp STARTTLS negotiation: server does not offer STARTTLS") {
+		t.Errorf("expected an error when the server does not offer STARTTLS, got %v", diags)
+	}
+}
@@ -0,0 +1,243 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	// oidContentTypeTSTInfo is the content type of RFC 3161 timestamp tokens, from RFC 3161 section 2.4.2.
+	oidContentTypeTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	// oidAttributeContentType and oidAttributeMessageDigest are the CMS signed attributes of RFC 5652 section 11.
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	// oidSignatureRSAPSS is the RSASSA-PSS signature algorithm of RFC 4055.
+	oidSignatureRSAPSS = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+)
+
+// hashAlgorithmOIDs are the OIDs of hashAlgorithms, used in CMS and RFC 3161 message imprints.
+var hashAlgorithmOIDs = map[string]asn1.ObjectIdentifier{
+	"sha1":   {1, 3, 14, 3, 2, 26},
+	"sha256": {2, 16, 840, 1, 101, 3, 4, 2, 1},
+	"sha384": {2, 16, 840, 1, 101, 3, 4, 2, 2},
+	"sha512": {2, 16, 840, 1, 101, 3, 4, 2, 3},
+}
+
+// hashAlgorithmName returns the name in hashAlgorithms of a hash OID.
+func hashAlgorithmName(oid asn1.ObjectIdentifier) (string, error) {
+	for name, hashOID := range hashAlgorithmOIDs {
+		if hashOID.Equal(oid) {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported hash algorithm %s", oid)
+}
+
+// timestampMessageImprint is the MessageImprint of RFC 3161 section 2.4.1: the hash of the timestamped data.
+type timestampMessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timestampRequest is the TimeStampReq of RFC 3161 section 2.4.1.
+type timestampRequest struct {
+	Version        int
+	MessageImprint timestampMessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+}
+
+// timestampResponse is the TimeStampResp of RFC 3161 section 2.4.2.
+type timestampResponse struct {
+	Status struct {
+		Status       int
+		StatusString []string       `asn1:"optional"`
+		FailInfo     asn1.BitString `asn1:"optional"`
+	}
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// timestampInfo is the TSTInfo of RFC 3161 section 2.4.2, the content signed by the TSA.
+type timestampInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint timestampMessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       struct {
+		Seconds int `asn1:"optional"`
+		Millis  int `asn1:"optional,tag:0"`
+		Micros  int `asn1:"optional,tag:1"`
+	} `asn1:"optional"`
+	Ordering   bool          `asn1:"optional"`
+	Nonce      *big.Int      `asn1:"optional"`
+	TSA        asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// cmsSignerInfo is the SignerInfo of RFC 5652 section 5.3.
+type cmsSignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// cmsAttribute is an Attribute of RFC 5652 section 5.3, its values being a DER SET.
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// timestampToken is a parsed RFC 3161 TimeStampToken: a CMS SignedData of a TSTInfo.
+type timestampToken struct {
+	info   timestampInfo
+	signer cmsSignerInfo
+	// content is the DER TSTInfo, digested in the messageDigest signed attribute
+	content []byte
+	certs   []*x509.Certificate
+}
+
+// parseTimestampToken parses a DER TimeStampToken, without verifying it.
+func parseTimestampToken(der []byte) (*timestampToken, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("unable to parse timestamp token content info: %w", err)
+	}
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, fmt.Errorf("timestamp token content type %s is not signed data", contentInfo.ContentType)
+	}
+
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo struct {
+			EContentType asn1.ObjectIdentifier
+			EContent     []byte `asn1:"explicit,optional,tag:0"`
+		}
+		Certificates asn1.RawValue   `asn1:"optional,tag:0"`
+		CRLs         asn1.RawValue   `asn1:"optional,tag:1"`
+		SignerInfos  []cmsSignerInfo `asn1:"set"`
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("unable to parse timestamp token signed data: %w", err)
+	}
+	if !signedData.EncapContentInfo.EContentType.Equal(oidContentTypeTSTInfo) {
+		return nil, fmt.Errorf("timestamp token content type %s is not TSTInfo", signedData.EncapContentInfo.EContentType)
+	}
+	if len(signedData.SignerInfos) != 1 {
+		return nil, fmt.Errorf("timestamp token has %d signers, expected one", len(signedData.SignerInfos))
+	}
+
+	token := &timestampToken{
+		signer:  signedData.SignerInfos[0],
+		content: signedData.EncapContentInfo.EContent,
+	}
+	if _, err := asn1.Unmarshal(token.content, &token.info); err != nil {
+		return nil, fmt.Errorf("unable to parse TSTInfo: %w", err)
+	}
+	if len(signedData.Certificates.Bytes) > 0 {
+		certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse timestamp token certificates: %w", err)
+		}
+		token.certs = certs
+	}
+
+	return token, nil
+}
+
+// signerCertificate returns the certificate of the signer, among the certificates of the token and others.
+func (t *timestampToken) signerCertificate(others []*x509.Certificate) (*x509.Certificate, error) {
+	for _, cert := range append(append([]*x509.Certificate{}, t.certs...), others...) {
+		if t.signedBy(cert) {
+			return cert, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the certificate of the TSA is neither in the timestamp token nor given")
+}
+
+// signedBy returns true when the signer identifier of the token designates cert: either by issuer and serial number,
+// or by subject key identifier.
+func (t *timestampToken) signedBy(cert *x509.Certificate) bool {
+	sid := t.signer.SID
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		return len(cert.SubjectKeyId) > 0 && bytes.Equal(sid.Bytes, cert.SubjectKeyId)
+	}
+
+	var issuerAndSerial struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}
+	if _, err := asn1.Unmarshal(sid.FullBytes, &issuerAndSerial); err != nil {
+		return false
+	}
+
+	return bytes.Equal(issuerAndSerial.Issuer.FullBytes, cert.RawIssuer) && issuerAndSerial.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+// verifySignature checks the token is signed by cert: the signed attributes must digest the TSTInfo,
+// and be signed by the key of cert.
+func (t *timestampToken) verifySignature(cert *x509.Certificate) error {
+	if len(t.signer.SignedAttrs.FullBytes) == 0 {
+		return fmt.Errorf("timestamp token has no signed attributes")
+	}
+	hashName, err := hashAlgorithmName(t.signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	hash := hashAlgorithms[hashName]
+
+	// the signature is computed over the DER SET of the attributes, not their implicit [0] tagging
+	signedAttrs := append([]byte{0x31}, t.signer.SignedAttrs.FullBytes[1:]...)
+	var attributes []cmsAttribute
+	if _, err = asn1.UnmarshalWithParams(signedAttrs, &attributes, "set"); err != nil {
+		return fmt.Errorf("unable to parse signed attributes: %w", err)
+	}
+
+	var contentType asn1.ObjectIdentifier
+	var messageDigest []byte
+	for _, attribute := range attributes {
+		switch {
+		case attribute.Type.Equal(oidAttributeContentType):
+			_, err = asn1.Unmarshal(attribute.Values.Bytes, &contentType)
+		case attribute.Type.Equal(oidAttributeMessageDigest):
+			_, err = asn1.Unmarshal(attribute.Values.Bytes, &messageDigest)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to parse signed attribute %s: %w", attribute.Type, err)
+		}
+	}
+	if !contentType.Equal(oidContentTypeTSTInfo) {
+		return fmt.Errorf("signed content type attribute is %s, not TSTInfo", contentType)
+	}
+	digest := hash.New()
+	digest.Write(t.content)
+	if !bytes.Equal(messageDigest, digest.Sum(nil)) {
+		return fmt.Errorf("signed message digest attribute does not match the TSTInfo")
+	}
+
+	rsaPadding := "pkcs1v15"
+	if t.signer.SignatureAlgorithm.Algorithm.Equal(oidSignatureRSAPSS) {
+		rsaPadding = "pss"
+	}
+	if _, err = verifySignature(cert.PublicKey, signedAttrs, t.signer.Signature, hash, rsaPadding, "der"); err != nil {
+		return err
+	}
+
+	return nil
+}
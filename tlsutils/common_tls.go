@@ -0,0 +1,94 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+)
+
+// fetchPeerCertificates connects to address (host:port) with options and returns the certificates presented
+// by the server in the TLS handshake, leaf first. They are not verified: the caller decides what to check.
+// The SNI is serverName, or the host of address when it is empty and not an IP address.
+// A non-empty starttls names the protocol negotiating the switch to TLS, otherwise the handshake starts right away.
+func fetchPeerCertificates(ctx context.Context, options *networkOptions, address, serverName, starttls string) ([]*x509.Certificate, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	config := &tls.Config{InsecureSkipVerify: true, ServerName: serverName}
+	if serverName == "" && net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+
+	var certs []*x509.Certificate
+	err = options.retry(ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, options.connectTimeout)
+		defer cancel()
+
+		conn, err := options.dialContext(ctx, address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+		defer conn.Close()
+
+		if starttls != "" {
+			if err = negotiateSTARTTLS(ctx, conn, starttls); err != nil {
+				return fmt.Errorf("%s: %w", address, err)
+			}
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("failed TLS handshake with %s: %w", address, err)
+		}
+
+		certs = tlsConn.ConnectionState().PeerCertificates
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s did not present any certificate", address)
+	}
+
+	return certs, nil
+}
+
+// verifyPeerCertificates checks that certs, leaf first, chain up to one of the roots in rootsPEM,
+// or to the system roots when it is empty, and that the leaf is valid for hostname at the given time.
+func verifyPeerCertificates(certs []*x509.Certificate, hostname, rootsPEM string, at time.Time) error {
+	var roots *x509.CertPool
+	if rootsPEM != "" {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM([]byte(rootsPEM)) {
+			return fmt.Errorf("no valid certificate found in CA certificates PEM")
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       hostname,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+	})
+
+	return err
+}
+
+// sha256Fingerprint returns the lowercase hex SHA-256 of the DER encoding of cert.
+func sha256Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
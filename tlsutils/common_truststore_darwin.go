@@ -0,0 +1,34 @@
+//go:build darwin
+
+package tlsutils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// trustStoreKeychains are the system keychains holding the Apple roots and the certificates added by administrators.
+var trustStoreKeychains = []string{
+	"/System/Library/Keychains/SystemRootCertificates.keychain",
+	"/Library/Keychains/System.keychain",
+}
+
+// readOSTrustStore returns the certificates of the system keychains, exported with the security tool.
+// It also returns where they were read from and the certificates skipped by parseTrustStorePEM.
+func readOSTrustStore() ([]*x509.Certificate, string, []string, error) {
+	certs := make([]*x509.Certificate, 0)
+	skipped := make([]string, 0)
+	for _, keychain := range trustStoreKeychains {
+		out, err := exec.Command("/usr/bin/security", "find-certificate", "-a", "-p", keychain).Output()
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to export certificates of %s: %w", keychain, err)
+		}
+		parsed, skippedHere := parseTrustStorePEM(out, keychain)
+		certs = append(certs, parsed...)
+		skipped = append(skipped, skippedHere...)
+	}
+
+	return certs, strings.Join(trustStoreKeychains, ":"), skipped, nil
+}
@@ -0,0 +1,76 @@
+//go:build !windows && !darwin
+
+package tlsutils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// trustStoreFiles are the CA bundles of the usual Linux and BSD distributions, in the order crypto/x509 looks for them.
+var trustStoreFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian, Ubuntu, Gentoo, Arch
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora, RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS, RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine, FreeBSD, OpenBSD
+	"/usr/local/etc/ssl/cert.pem",                       // FreeBSD ports
+}
+
+// trustStoreDirectories hold one certificate per file, when no bundle is found.
+var trustStoreDirectories = []string{
+	"/etc/ssl/certs",
+	"/etc/pki/tls/certs",
+}
+
+// readOSTrustStore returns the certificates of the first CA bundle found, or of the certificate directories,
+// honoring SSL_CERT_FILE and SSL_CERT_DIR like crypto/x509. It also returns where they were read from and the
+// certificates skipped by parseTrustStorePEM.
+func readOSTrustStore() ([]*x509.Certificate, string, []string, error) {
+	files := trustStoreFiles
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		files = []string{file}
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		certs, skipped := parseTrustStorePEM(content, file)
+		return certs, file, skipped, nil
+	}
+
+	directories := trustStoreDirectories
+	if dirs := os.Getenv("SSL_CERT_DIR"); dirs != "" {
+		directories = strings.Split(dirs, ":")
+	}
+	certs := make([]*x509.Certificate, 0)
+	skipped := make([]string, 0)
+	read := make([]string, 0)
+	for _, directory := range directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			continue
+		}
+		read = append(read, directory)
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(directory, entry.Name()))
+			if err != nil {
+				continue
+			}
+			// directories also contain non certificate files, without PEM certificate blocks
+			parsed, skippedHere := parseTrustStorePEM(content, filepath.Join(directory, entry.Name()))
+			certs = append(certs, parsed...)
+			skipped = append(skipped, skippedHere...)
+		}
+	}
+	if len(read) == 0 {
+		return nil, "", nil, fmt.Errorf("no CA bundle or certificate directory found")
+	}
+
+	return certs, strings.Join(read, ":"), skipped, nil
+}
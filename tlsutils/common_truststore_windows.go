@@ -0,0 +1,47 @@
+//go:build windows
+
+package tlsutils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// readOSTrustStore returns the certificates of the ROOT system store of the current user,
+// which includes the machine trusted roots. It also returns where they were read from and the certificates
+// crypto/x509 cannot parse, skipped like parseTrustStorePEM does.
+func readOSTrustStore() ([]*x509.Certificate, string, []string, error) {
+	storeName, err := syscall.UTF16PtrFromString("ROOT")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	store, err := syscall.CertOpenSystemStore(0, storeName)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to open the ROOT certificate store: %w", err)
+	}
+	defer syscall.CertCloseStore(store, 0)
+
+	certs := make([]*x509.Certificate, 0)
+	skipped := make([]string, 0)
+	var certContext *syscall.CertContext
+	for index := 0; ; index++ {
+		// the enumeration fails with CRYPT_E_NOT_FOUND after the last certificate
+		certContext, _ = syscall.CertEnumCertificatesInStore(store, certContext)
+		if certContext == nil {
+			break
+		}
+
+		der := make([]byte, certContext.Length)
+		copy(der, unsafe.Slice(certContext.EncodedCert, certContext.Length))
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("certificate #%d of ROOT: %s", index, err))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, "ROOT", skipped, nil
+}
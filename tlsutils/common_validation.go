@@ -0,0 +1,349 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// certificateCheck is one rule of a validation preset.
+type certificateCheck struct {
+	name  string
+	check func(cert *x509.Certificate) error
+}
+
+// rfc5280StrictChecks enforce the certificate profile of RFC 5280 section 4.
+var rfc5280StrictChecks = []certificateCheck{
+	{"serial number is positive and at most 20 octets", func(cert *x509.Certificate) error {
+		if cert.SerialNumber.Sign() <= 0 {
+			return fmt.Errorf("serial number %s is not positive", cert.SerialNumber)
+		}
+		if len(cert.SerialNumber.Bytes()) > 20 {
+			return fmt.Errorf("serial number is %d octets long", len(cert.SerialNumber.Bytes()))
+		}
+		return nil
+	}},
+	{"validity period is not empty", func(cert *x509.Certificate) error {
+		if !cert.NotAfter.After(cert.NotBefore) {
+			return fmt.Errorf("notAfter %s is not after notBefore %s", cert.NotAfter.Format(time.RFC3339), cert.NotBefore.Format(time.RFC3339))
+		}
+		return nil
+	}},
+	{"subject or subject alternative name is present", func(cert *x509.Certificate) error {
+		if len(cert.RawSubject) <= 2 && len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.URIs)+len(cert.EmailAddresses) == 0 {
+			return fmt.Errorf("the certificate has an empty subject and no subject alternative name")
+		}
+		return nil
+	}},
+	{"keyCertSign is only asserted by CA certificates", func(cert *x509.Certificate) error {
+		if cert.KeyUsage&x509.KeyUsageCertSign != 0 && !cert.IsCA {
+			return fmt.Errorf("key usage has keyCertSign but basic constraints has CA:FALSE")
+		}
+		if cert.IsCA && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+			return fmt.Errorf("CA certificate without keyCertSign key usage")
+		}
+		return nil
+	}},
+	{"CA certificates have a subject key identifier", func(cert *x509.Certificate) error {
+		if cert.IsCA && len(cert.SubjectKeyId) == 0 {
+			return fmt.Errorf("CA certificate without subject key identifier")
+		}
+		return nil
+	}},
+	{"certificates not self-signed have an authority key identifier", func(cert *x509.Certificate) error {
+		if len(cert.AuthorityKeyId) == 0 && !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			return fmt.Errorf("the issuer has no subject key identifier to use as authority key identifier")
+		}
+		return nil
+	}},
+}
+
+// cabfBRReservedSuffixes are the internal name suffixes rejected by the CA/Browser Forum Baseline Requirements.
+var cabfBRReservedSuffixes = []string{".local", ".localhost", ".internal", ".lan", ".home.arpa", ".corp", ".test", ".invalid", ".example"}
+
+// cabfBRChecks enforce the TLS server certificate profile of the CA/Browser Forum Baseline Requirements,
+// in addition to rfc5280StrictChecks.
+var cabfBRChecks = append(append([]certificateCheck{}, rfc5280StrictChecks...), []certificateCheck{
+	{"validity period is at most 398 days", func(cert *x509.Certificate) error {
+		if validity := cert.NotAfter.Sub(cert.NotBefore); validity > 398*24*time.Hour {
+			return fmt.Errorf("validity period is %d days", int(validity.Hours()/24))
+		}
+		return nil
+	}},
+	{"serial number has at least 64 bits", func(cert *x509.Certificate) error {
+		if cert.SerialNumber.BitLen() < 64 {
+			return fmt.Errorf("serial number has %d bits", cert.SerialNumber.BitLen())
+		}
+		return nil
+	}},
+	{"subject alternative names contain a DNS name or an IP address", func(cert *x509.Certificate) error {
+		if len(cert.DNSNames)+len(cert.IPAddresses) == 0 {
+			return fmt.Errorf("no DNS name or IP address in subject alternative names")
+		}
+		return nil
+	}},
+	{"common name is one of the subject alternative names", func(cert *x509.Certificate) error {
+		if cert.Subject.CommonName == "" {
+			return nil
+		}
+		for _, name := range cert.DNSNames {
+			if strings.EqualFold(name, cert.Subject.CommonName) {
+				return nil
+			}
+		}
+		for _, ip := range cert.IPAddresses {
+			if ip.String() == cert.Subject.CommonName {
+				return nil
+			}
+		}
+		return fmt.Errorf("common name %q is not in subject alternative names", cert.Subject.CommonName)
+	}},
+	{"no internal names or addresses", func(cert *x509.Certificate) error {
+		for _, name := range cert.DNSNames {
+			lower := strings.ToLower(name)
+			if !strings.Contains(strings.TrimPrefix(lower, "*."), ".") {
+				return fmt.Errorf("DNS name %q is not fully qualified", name)
+			}
+			for _, suffix := range cabfBRReservedSuffixes {
+				if strings.HasSuffix(lower, suffix) {
+					return fmt.Errorf("DNS name %q uses the reserved suffix %s", name, suffix)
+				}
+			}
+		}
+		for _, ip := range cert.IPAddresses {
+			if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("IP address %s is reserved", ip)
+			}
+		}
+		return nil
+	}},
+	{"wildcard DNS names are not combined with IP addresses", func(cert *x509.Certificate) error {
+		if len(cert.IPAddresses) == 0 {
+			return nil
+		}
+		for _, name := range cert.DNSNames {
+			if strings.HasPrefix(name, "*.") {
+				return fmt.Errorf("wildcard DNS name %q in a certificate with IP address %s", name, cert.IPAddresses[0])
+			}
+		}
+		return nil
+	}},
+	{"extended key usage has serverAuth and not anyExtendedKeyUsage", func(cert *x509.Certificate) error {
+		serverAuth := false
+		for _, usage := range cert.ExtKeyUsage {
+			switch usage {
+			case x509.ExtKeyUsageServerAuth:
+				serverAuth = true
+			case x509.ExtKeyUsageAny:
+				return fmt.Errorf("extended key usage has anyExtendedKeyUsage")
+			}
+		}
+		if !serverAuth {
+			return fmt.Errorf("extended key usage does not have serverAuth")
+		}
+		return nil
+	}},
+	{"public key is RSA of at least 2048 bits or ECDSA on P-256, P-384 or P-521", func(cert *x509.Certificate) error {
+		switch k := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if k.N.BitLen() < 2048 || k.N.BitLen()%8 != 0 {
+				return fmt.Errorf("RSA key of %d bits", k.N.BitLen())
+			}
+		case *ecdsa.PublicKey:
+			switch k.Curve.Params().Name {
+			case "P-256", "P-384", "P-521":
+			default:
+				return fmt.Errorf("ECDSA key on curve %s", k.Curve.Params().Name)
+			}
+		default:
+			return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+		}
+		return nil
+	}},
+}...)
+
+// checkIssuerChaining returns an error when template would not chain to caCert: a CA certificate beyond the path
+// length budget of caCert, or extended key usages caCert does not allow. Verifiers like crypto/x509 and browsers
+// require the extended key usages of a leaf to be allowed by every CA of its chain.
+func checkIssuerChaining(caCert, template *x509.Certificate) error {
+	if template.IsCA {
+		if caCert.MaxPathLen == 0 && caCert.MaxPathLenZero {
+			return fmt.Errorf("the CA certificate has a path length constraint of 0 and cannot issue CA certificates")
+		}
+		if caCert.MaxPathLen > 0 && (template.MaxPathLen > 0 || template.MaxPathLenZero) && template.MaxPathLen >= caCert.MaxPathLen {
+			return fmt.Errorf("path length constraint %d exceeds the budget of the CA certificate: its path length constraint of %d allows at most %d",
+				template.MaxPathLen, caCert.MaxPathLen, caCert.MaxPathLen-1)
+		}
+	}
+
+	if len(caCert.ExtKeyUsage) == 0 && len(caCert.UnknownExtKeyUsage) == 0 {
+		return nil
+	}
+	for _, usage := range caCert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageAny {
+			return nil
+		}
+	}
+	for _, usage := range template.ExtKeyUsage {
+		if !slices.Contains(caCert.ExtKeyUsage, usage) {
+			return fmt.Errorf("extended key usage %s is not allowed by the extended key usages of the CA certificate", extKeyUsageName(usage))
+		}
+	}
+	for _, usage := range template.UnknownExtKeyUsage {
+		if !slices.ContainsFunc(caCert.UnknownExtKeyUsage, usage.Equal) {
+			return fmt.Errorf("extended key usage %s is not allowed by the extended key usages of the CA certificate", usage)
+		}
+	}
+
+	return nil
+}
+
+// checkNameConstraints returns an error for the first subject alternative name of template not permitted,
+// or excluded, by the name constraints of caCert: clients verifying the chain reject such certificates.
+func checkNameConstraints(caCert, template *x509.Certificate) error {
+	for _, name := range template.DNSNames {
+		if err := checkNameConstraint("DNS name", name, caCert.PermittedDNSDomains, caCert.ExcludedDNSDomains, matchDomainConstraint); err != nil {
+			return err
+		}
+	}
+	for _, ip := range template.IPAddresses {
+		if err := checkNameConstraint("IP address", ip.String(), ipNetStrings(caCert.PermittedIPRanges), ipNetStrings(caCert.ExcludedIPRanges), matchIPConstraint); err != nil {
+			return err
+		}
+	}
+	for _, email := range template.EmailAddresses {
+		if err := checkNameConstraint("email address", email, caCert.PermittedEmailAddresses, caCert.ExcludedEmailAddresses, matchEmailConstraint); err != nil {
+			return err
+		}
+	}
+	for _, uri := range template.URIs {
+		if err := checkNameConstraint("URI", uri.String(), caCert.PermittedURIDomains, caCert.ExcludedURIDomains, matchURIConstraint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extKeyUsageName returns the allowed_uses name of usage.
+func extKeyUsageName(usage x509.ExtKeyUsage) string {
+	for name, known := range extKeyUsages {
+		if known == usage {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("%d", usage)
+}
+
+// checkNameConstraint checks name matches one of permitted, when not empty, and none of excluded.
+func checkNameConstraint(kind, name string, permitted, excluded []string, match func(name, constraint string) bool) error {
+	for _, constraint := range excluded {
+		if match(name, constraint) {
+			return fmt.Errorf("%s %q is excluded by the name constraint %q of the CA", kind, name, constraint)
+		}
+	}
+	if len(permitted) == 0 {
+		return nil
+	}
+	for _, constraint := range permitted {
+		if match(name, constraint) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s %q is not permitted by the name constraints of the CA: %s", kind, name, strings.Join(permitted, ", "))
+}
+
+// matchDomainConstraint matches a DNS name like RFC 5280 section 4.2.1.10: `example.com` matches the domain
+// and its subdomains, `.example.com` only its subdomains.
+func matchDomainConstraint(name, constraint string) bool {
+	name, constraint = strings.ToLower(strings.TrimSuffix(name, ".")), strings.ToLower(constraint)
+	if constraint == "" {
+		return true
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(name, constraint)
+	}
+
+	return name == constraint || strings.HasSuffix(name, "."+constraint)
+}
+
+// matchEmailConstraint matches an email address against a mailbox, a host or, starting with a dot, a domain.
+func matchEmailConstraint(email, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+	host := email[strings.LastIndex(email, "@")+1:]
+	if strings.HasPrefix(constraint, ".") {
+		return matchDomainConstraint(host, constraint)
+	}
+
+	return strings.EqualFold(host, constraint)
+}
+
+// matchURIConstraint matches the host of a URI against a host or, starting with a dot, a domain.
+func matchURIConstraint(rawURI, constraint string) bool {
+	parsed, err := url.Parse(rawURI)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return matchDomainConstraint(parsed.Hostname(), constraint)
+	}
+
+	return strings.EqualFold(parsed.Hostname(), constraint)
+}
+
+// matchIPConstraint matches an IP address against a network in CIDR notation.
+func matchIPConstraint(ip, constraint string) bool {
+	_, network, err := net.ParseCIDR(constraint)
+
+	return err == nil && network.Contains(net.ParseIP(ip))
+}
+
+// ipNetStrings returns the CIDR notation of networks.
+func ipNetStrings(networks []*net.IPNet) []string {
+	strs := make([]string, 0, len(networks))
+	for _, network := range networks {
+		strs = append(strs, network.String())
+	}
+
+	return strs
+}
+
+// validationPresets provides the checks of each validation_preset.
+var validationPresets = map[string][]certificateCheck{
+	"none":           nil,
+	"rfc5280-strict": rfc5280StrictChecks,
+	"cabf-br":        cabfBRChecks,
+}
+
+// supportedValidationPresetsStr returns the names accepted by validation_preset.
+func supportedValidationPresetsStr() []string {
+	return []string{"none", "rfc5280-strict", "cabf-br"}
+}
+
+// validateCertificatePreset runs the checks of preset on cert and returns one error per failed check.
+func validateCertificatePreset(preset string, cert *x509.Certificate) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, check := range validationPresets[preset] {
+		if err := check.check(cert); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Certificate fails %s check: %s", preset, check.name),
+				Detail:   err.Error(),
+			})
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,236 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckIssuerChaining(t *testing.T) {
+	oidCustomUsage := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+	for name, test := range map[string]struct {
+		caCert   x509.Certificate
+		template x509.Certificate
+		err      string
+	}{
+		"unconstrained":          {template: x509.Certificate{IsCA: true, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}},
+		"pathlen budget":         {caCert: x509.Certificate{MaxPathLen: 2}, template: x509.Certificate{IsCA: true, MaxPathLen: 1}},
+		"pathlen unset":          {caCert: x509.Certificate{MaxPathLen: 1}, template: x509.Certificate{IsCA: true, MaxPathLen: -1}},
+		"pathlen zero leaf":      {caCert: x509.Certificate{MaxPathLenZero: true}, template: x509.Certificate{}},
+		"pathlen zero CA":        {caCert: x509.Certificate{MaxPathLenZero: true}, template: x509.Certificate{IsCA: true, MaxPathLen: -1}, err: "cannot issue CA certificates"},
+		"pathlen over budget":    {caCert: x509.Certificate{MaxPathLen: 1}, template: x509.Certificate{IsCA: true, MaxPathLen: 1}, err: "allows at most 0"},
+		"pathlen zero in budget": {caCert: x509.Certificate{MaxPathLen: 1}, template: x509.Certificate{IsCA: true, MaxPathLenZero: true}},
+		"nested usages": {
+			caCert:   x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}},
+			template: x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}},
+		},
+		"any usage": {
+			caCert:   x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}},
+			template: x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidCustomUsage}},
+		},
+		"usage not nested": {
+			caCert:   x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}},
+			template: x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}},
+			err:      "extended key usage client_auth is not allowed",
+		},
+		"unknown usage nested": {
+			caCert:   x509.Certificate{UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidCustomUsage}},
+			template: x509.Certificate{UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidCustomUsage}},
+		},
+		"unknown usage not nested": {
+			caCert:   x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}},
+			template: x509.Certificate{UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidCustomUsage}},
+			err:      "extended key usage 1.3.6.1.4.1.99999.1 is not allowed",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkIssuerChaining(&test.caCert, &test.template)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestCheckIssuerChainingVerifies(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	serverCA, serverCAKey := testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "server-only CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, root, rootKey)
+
+	// the certificates checkIssuerChaining refuses are the ones crypto/x509 does not verify
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(serverCA)
+	for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+		template := &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, ExtKeyUsage: []x509.ExtKeyUsage{usage}}
+		checkErr := checkIssuerChaining(serverCA, template)
+		leaf, _ := testCertificate(t, template, serverCA, serverCAKey)
+		_, verifyErr := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{usage}})
+		if (checkErr == nil) != (verifyErr == nil) {
+			t.Errorf("expected the check of %s to agree with crypto/x509, got %v and %v", extKeyUsageName(usage), checkErr, verifyErr)
+		}
+	}
+}
+
+func TestCheckNameConstraints(t *testing.T) {
+	_, permittedNet, _ := net.ParseCIDR("10.0.0.0/8")
+	_, excludedNet, _ := net.ParseCIDR("10.1.0.0/16")
+	caCert := &x509.Certificate{
+		PermittedDNSDomains:     []string{"example.com", ".example.org"},
+		ExcludedDNSDomains:      []string{"secret.example.com"},
+		PermittedIPRanges:       []*net.IPNet{permittedNet},
+		ExcludedIPRanges:        []*net.IPNet{excludedNet},
+		PermittedEmailAddresses: []string{"example.com"},
+		PermittedURIDomains:     []string{".example.com"},
+	}
+	spiffe, _ := url.Parse("spiffe://web.example.com/api")
+	other, _ := url.Parse("https://example.net/")
+
+	for name, test := range map[string]struct {
+		template x509.Certificate
+		err      string
+	}{
+		"permitted":              {template: x509.Certificate{DNSNames: []string{"example.com", "www.example.com", "www.example.org"}, IPAddresses: []net.IP{net.ParseIP("10.2.0.1")}, EmailAddresses: []string{"admin@example.com"}, URIs: []*url.URL{spiffe}}},
+		"no names":               {},
+		"domain not permitted":   {template: x509.Certificate{DNSNames: []string{"www.example.net"}}, err: `DNS name "www.example.net" is not permitted by the name constraints of the CA: example.com, .example.org`},
+		"subdomains only":        {template: x509.Certificate{DNSNames: []string{"example.org"}}, err: `DNS name "example.org" is not permitted`},
+		"domain excluded":        {template: x509.Certificate{DNSNames: []string{"db.secret.example.com"}}, err: `DNS name "db.secret.example.com" is excluded by the name constraint "secret.example.com"`},
+		"IP address excluded":    {template: x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.1.2.3")}}, err: `IP address "10.1.2.3" is excluded by the name constraint "10.1.0.0/16"`},
+		"IP address outside":     {template: x509.Certificate{IPAddresses: []net.IP{net.ParseIP("192.0.2.1")}}, err: `IP address "192.0.2.1" is not permitted`},
+		"email not permitted":    {template: x509.Certificate{EmailAddresses: []string{"admin@mail.example.com"}}, err: `email address "admin@mail.example.com" is not permitted`},
+		"URI host not permitted": {template: x509.Certificate{URIs: []*url.URL{other}}, err: `URI "https://example.net/" is not permitted`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkNameConstraints(caCert, &test.template)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateCertificatePreset(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	serialNumber := new(big.Int).Lsh(big.NewInt(1), 100)
+
+	for name, test := range map[string]struct {
+		template x509.Certificate
+		preset   string
+		failed   []string
+	}{
+		"none": {template: x509.Certificate{SerialNumber: big.NewInt(1)}, preset: "none"},
+		"rfc5280-strict": {
+			template: x509.Certificate{SerialNumber: serialNumber, Subject: pkix.Name{CommonName: "www.example.com"}},
+			preset:   "rfc5280-strict",
+		},
+		"rfc5280-strict without names": {
+			template: x509.Certificate{SerialNumber: serialNumber},
+			preset:   "rfc5280-strict",
+			failed:   []string{"subject or subject alternative name is present"},
+		},
+		"cabf-br": {
+			template: x509.Certificate{SerialNumber: serialNumber, Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}},
+			preset:   "cabf-br",
+		},
+		"cabf-br wildcard with IP address": {
+			template: x509.Certificate{
+				SerialNumber: serialNumber,
+				Subject:      pkix.Name{CommonName: "*.example.com"},
+				DNSNames:     []string{"*.example.com"},
+				IPAddresses:  []net.IP{net.ParseIP("192.0.2.1")},
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			},
+			preset: "cabf-br",
+			failed: []string{"wildcard DNS names are not combined with IP addresses"},
+		},
+		"cabf-br internal name": {
+			template: x509.Certificate{
+				SerialNumber: big.NewInt(42),
+				Subject:      pkix.Name{CommonName: "www.example.com"},
+				DNSNames:     []string{"db.internal"},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(400 * 24 * time.Hour),
+			},
+			preset: "cabf-br",
+			failed: []string{
+				"validity period is at most 398 days",
+				"serial number has at least 64 bits",
+				"common name is one of the subject alternative names",
+				"no internal names or addresses",
+				"extended key usage has serverAuth and not anyExtendedKeyUsage",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cert, _ := testCertificate(t, &test.template, ca, caKey)
+			diags := validateCertificatePreset(test.preset, cert)
+			if len(diags) != len(test.failed) {
+				t.Fatalf("expected %d failed checks, got %v", len(test.failed), diags)
+			}
+			for i, check := range test.failed {
+				if want := "Certificate fails " + test.preset + " check: " + check; diags[i].Summary != want {
+					t.Errorf("expected %q, got %q", want, diags[i].Summary)
+				}
+			}
+		})
+	}
+}
+
+func TestIssueCertificateValidationPreset(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]interface{}{
+		"ca_cert_pem":           certificateToPEM(ca),
+		"ca_private_key_pem":    caKeyPem,
+		"validity_period_hours": 24,
+		"allowed_uses":          []interface{}{"digital_signature", "server_auth"},
+		"subject":               []interface{}{map[string]interface{}{"common_name": "www.example.com"}},
+		"dns_names":             []interface{}{"www.example.com"},
+		"validation_preset":     "cabf-br",
+	}
+	testResourceApply(t, resourceDualCert(), nil, config, &providerMeta{})
+
+	config["dns_names"] = []interface{}{"www.example.com", "db.internal"}
+	r := resourceDualCert()
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, diags := r.Apply(context.Background(), nil, diff, &providerMeta{})
+	if !diags.HasError() || diags[0].Summary != "Certificate fails cabf-br check: no internal names or addresses" {
+		t.Errorf("expected the internal name to fail the cabf-br preset, got %v", diags)
+	}
+	if state != nil && state.ID != "" {
+		t.Errorf("expected no certificate to be stored, got %s", state.ID)
+	}
+}
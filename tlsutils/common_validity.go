@@ -0,0 +1,167 @@
+package tlsutils
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// iso8601DurationPattern matches the ISO 8601 durations PnYnMnWnDTnHnMnS, seconds possibly decimal.
+var iso8601DurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// iso8601DurationUnits are the lengths of the designators of iso8601DurationPattern, in order. Years and months
+// have a fixed length of 365 and 30 days, so that a duration does not depend on the date it starts.
+var iso8601DurationUnits = []time.Duration{
+	365 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+	time.Hour,
+	time.Minute,
+	time.Second,
+}
+
+// validityPeriodDescription documents the formats accepted by parseValidityPeriod.
+const validityPeriodDescription = "a Go duration such as `8760h`, or an ISO 8601 duration such as `P1Y` or `P90D`, years counting 365 days and months 30 days"
+
+// parseValidityPeriod parses a positive Go duration, or ISO 8601 duration.
+func parseValidityPeriod(value string) (time.Duration, error) {
+	var duration time.Duration
+	if match := iso8601DurationPattern.FindStringSubmatch(value); match != nil && value != "P" && value[len(value)-1] != 'T' {
+		seconds := 0.0
+		for i, unit := range iso8601DurationUnits {
+			if match[i+1] == "" {
+				continue
+			}
+			count, err := strconv.ParseFloat(match[i+1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			seconds += count * unit.Seconds()
+		}
+		if seconds >= math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("duration %q is too long", value)
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	} else {
+		var err error
+		if duration, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid duration %q, expected a Go duration like 8760h or an ISO 8601 duration like P1Y", value)
+		}
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("expected a positive duration, got %s", value)
+	}
+
+	return duration, nil
+}
+
+// validateValidityPeriod is a schema.SchemaValidateFunc checking the value is accepted by parseValidityPeriod.
+func validateValidityPeriod(i interface{}, k string) ([]string, []error) {
+	value, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	if _, err := parseValidityPeriod(value); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+
+	return nil, nil
+}
+
+// formatValidityPeriod formats a duration in whole hours when possible, as accepted by Go, Vault and step-ca.
+func formatValidityPeriod(duration time.Duration) string {
+	if duration%time.Hour == 0 {
+		return fmt.Sprintf("%dh", duration/time.Hour)
+	}
+
+	return fmt.Sprintf("%ds", int64(duration.Seconds()))
+}
+
+// suppressEquivalentValidityPeriod returns a schema.SchemaDiffSuppressFunc, set on both the duration string attribute
+// periodKey and the number of hours hoursKey, ignoring changes that keep the effective validity, e.g. from
+// `validity_period_hours = 8760` to `validity_period = "P365D"`. hoursKey is empty for a lone duration attribute.
+func suppressEquivalentValidityPeriod(periodKey, hoursKey string) schema.SchemaDiffSuppressFunc {
+	return func(_, _, _ string, d *schema.ResourceData) bool {
+		oldPeriod, newPeriod := d.GetChange(periodKey)
+		var oldHours, newHours interface{} = 0, 0
+		if hoursKey != "" {
+			oldHours, newHours = d.GetChange(hoursKey)
+		}
+
+		oldValidity := effectiveValidityPeriod(oldPeriod.(string), oldHours.(int))
+		return oldValidity > 0 && oldValidity == effectiveValidityPeriod(newPeriod.(string), newHours.(int))
+	}
+}
+
+// effectiveValidityPeriod returns the validity of a duration string, or of a number of hours when it is empty.
+// Invalid durations are 0.
+func effectiveValidityPeriod(period string, hours int) time.Duration {
+	if period == "" {
+		return time.Duration(hours) * time.Hour
+	}
+
+	duration, err := parseValidityPeriod(period)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// validityPeriodOrProviderDefault returns the validity configured by the periodKey or hoursKey attributes of the
+// resource, and the default validity of the provider otherwise. It is 0 when none is set.
+func validityPeriodOrProviderDefault(d resourceAttributes, periodKey, hoursKey string, m interface{}) time.Duration {
+	if validityPeriod, ok := configuredValidityPeriod(d, periodKey, hoursKey); ok {
+		return validityPeriod
+	}
+
+	if meta, ok := m.(*providerMeta); ok {
+		return meta.defaultValidityPeriod
+	}
+	return effectiveValidityPeriod(d.Get(periodKey).(string), d.Get(hoursKey).(int))
+}
+
+// configuredValidityPeriod returns the validity configured by the periodKey or hoursKey attributes of the resource,
+// and false when neither is set.
+func configuredValidityPeriod(d resourceAttributes, periodKey, hoursKey string) (time.Duration, bool) {
+	if value, ok := configuredAttribute(d, periodKey); ok {
+		return effectiveValidityPeriod(value.(string), 0), true
+	}
+	if value, ok := configuredAttribute(d, hoursKey); ok {
+		return time.Duration(value.(int)) * time.Hour, true
+	}
+
+	return 0, false
+}
+
+// validityPeriodSchema returns the ForceNew attributes prefix+"validity_period_hours" and prefix+"validity_period",
+// configuring the same validity as a number of hours or a duration string.
+func validityPeriodSchema(prefix, description string) map[string]*schema.Schema {
+	periodKey, hoursKey := prefix+"validity_period", prefix+"validity_period_hours"
+
+	return map[string]*schema.Schema{
+		hoursKey: {
+			Description:      description,
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ForceNew:         true,
+			ConflictsWith:    []string{periodKey},
+			DiffSuppressFunc: suppressEquivalentValidityPeriod(periodKey, hoursKey),
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+		},
+		periodKey: {
+			Description:      "`" + hoursKey + "` as " + validityPeriodDescription + ".",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ConflictsWith:    []string{hoursKey},
+			DiffSuppressFunc: suppressEquivalentValidityPeriod(periodKey, hoursKey),
+			ValidateDiagFunc: validation.ToDiagFunc(validateValidityPeriod),
+		},
+	}
+}
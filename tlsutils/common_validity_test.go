@@ -0,0 +1,94 @@
+package tlsutils
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValidityPeriod(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"8760h":      8760 * time.Hour,
+		"90m":        90 * time.Minute,
+		"P1Y":        365 * 24 * time.Hour,
+		"P1M":        30 * 24 * time.Hour,
+		"P2W":        14 * 24 * time.Hour,
+		"P90D":       90 * 24 * time.Hour,
+		"PT36H":      36 * time.Hour,
+		"P1DT12H":    36 * time.Hour,
+		"PT1.5S":     1500 * time.Millisecond,
+		"P1Y2M3DT4H": (365+60+3)*24*time.Hour + 4*time.Hour,
+	} {
+		got, err := parseValidityPeriod(value)
+		if err != nil {
+			t.Errorf("%s: %s", value, err)
+		} else if got != want {
+			t.Errorf("%s: expected %s, got %s", value, want, got)
+		}
+	}
+
+	for value, want := range map[string]string{
+		"":           "invalid duration",
+		"P":          "invalid duration",
+		"P1DT":       "invalid duration",
+		"1Y":         "invalid duration",
+		"P1H":        "invalid duration",
+		"-24h":       "expected a positive duration",
+		"P0D":        "expected a positive duration",
+		"P99999999Y": "is too long",
+	} {
+		if _, err := parseValidityPeriod(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error %q, got %v", value, want, err)
+		}
+	}
+}
+
+func TestFormatValidityPeriod(t *testing.T) {
+	for duration, want := range map[time.Duration]string{
+		365 * 24 * time.Hour: "8760h",
+		90 * time.Minute:     "5400s",
+	} {
+		if got := formatValidityPeriod(duration); got != want {
+			t.Errorf("expected %s for %s, got %s", want, duration, got)
+		}
+	}
+}
+
+func TestSuppressEquivalentValidityPeriod(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := func(key string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"ca_cert_pem":        certificateToPEM(ca),
+			"ca_private_key_pem": caKeyPem,
+			key:                  value,
+		}
+	}
+
+	r := resourceDualCert()
+	state := testResourceApply(t, r, nil, config("validity_period_hours", 24), &providerMeta{})
+
+	for name, test := range map[string]struct {
+		config      map[string]interface{}
+		requiresNew bool
+	}{
+		"same hours":             {config: config("validity_period_hours", 24)},
+		"equivalent ISO 8601":    {config: config("validity_period", "P1D")},
+		"equivalent Go duration": {config: config("validity_period", "1440m")},
+		"longer duration string": {config: config("validity_period", "P2D"), requiresNew: true},
+		"more hours":             {config: config("validity_period_hours", 48), requiresNew: true},
+	} {
+		diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(test.config), &providerMeta{})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if got := diff != nil && diff.RequiresNew(); got != test.requiresNew {
+			t.Errorf("%s: expected requires new %t, got %t: %v", name, test.requiresNew, got, diff)
+		}
+	}
+}
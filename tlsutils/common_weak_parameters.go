@@ -0,0 +1,158 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"slices"
+	"time"
+)
+
+// maxServerValidity is the longest validity of the TLS server certificates accepted by browsers since September 2020.
+const maxServerValidity = 398 * 24 * time.Hour
+
+// weakParameterCheck returns why the value of an attribute is weak, or an empty string when it is not or is not set.
+type weakParameterCheck func(value interface{}, now time.Time) string
+
+// warnWeakParameter returns a schema.SchemaValidateDiagFunc running validate, when not nil, and warning at plan time
+// when check finds the value weak. The warnings become errors with the weak_parameters_as_errors of the provider,
+// checked by checkWeakParameters: validation runs without the provider configuration.
+func warnWeakParameter(check weakParameterCheck, validate schema.SchemaValidateDiagFunc) schema.SchemaValidateDiagFunc {
+	return func(i interface{}, path cty.Path) diag.Diagnostics {
+		var diags diag.Diagnostics
+		if validate != nil {
+			diags = validate(i, path)
+		}
+		if reason := check(i, time.Now()); reason != "" {
+			diags = append(diags, diag.Diagnostic{
+				Severity:      diag.Warning,
+				Summary:       "Weak parameter",
+				Detail:        reason + ". Set weak_parameters_as_errors in the provider to reject it.",
+				AttributePath: path,
+			})
+		}
+
+		return diags
+	}
+}
+
+// warnWeakParameters wraps the ValidateDiagFunc of the attributes of checks in s with warnWeakParameter.
+func warnWeakParameters(s map[string]*schema.Schema, checks map[string]weakParameterCheck) {
+	for key, check := range checks {
+		s[key].ValidateDiagFunc = warnWeakParameter(check, s[key].ValidateDiagFunc)
+	}
+}
+
+// checkWeakParameters fails when the provider sets weak_parameters_as_errors and one of the configured attributes
+// of checks is weak. Resources only check the attributes they change, so that existing resources keep planning.
+func checkWeakParameters(d resourceAttributes, m interface{}, checks map[string]weakParameterCheck) error {
+	if meta, ok := m.(*providerMeta); !ok || !meta.weakParametersAsErrors {
+		return nil
+	}
+
+	keys := make([]string, 0, len(checks))
+	for key := range checks {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if diff, ok := d.(*schema.ResourceDiff); ok && diff.Id() != "" && !diff.HasChange(key) {
+			continue
+		}
+		value, ok := configuredAttribute(d, key)
+		if !ok {
+			continue
+		}
+		if reason := checks[key](value, now(d, m)); reason != "" {
+			return fmt.Errorf("weak parameter %s: %s, rejected by weak_parameters_as_errors of the provider", key, reason)
+		}
+	}
+
+	return nil
+}
+
+// weakHash reports the SHA-1 hash name, broken for signatures since the SHAttered collision.
+func weakHash(value interface{}, _ time.Time) string {
+	if value == "sha1" {
+		return "SHA-1 is vulnerable to collisions, use sha256 or longer"
+	}
+
+	return ""
+}
+
+// weakCARSABits reports RSA CA key sizes under 3072 bits, the minimum recommended by NIST beyond 2030.
+func weakCARSABits(value interface{}, _ time.Time) string {
+	if bits, ok := value.(int); ok && bits > 0 && bits < 3072 {
+		return fmt.Sprintf("RSA CA keys of %d bits are too short for long-lived CAs, use 3072 bits or more", bits)
+	}
+
+	return ""
+}
+
+// weakServerValidityHours reports validity periods in hours longer than maxServerValidity.
+func weakServerValidityHours(value interface{}, _ time.Time) string {
+	if hours, ok := value.(int); ok {
+		return weakServerValidity(time.Duration(hours) * time.Hour)
+	}
+
+	return ""
+}
+
+// weakServerValidityPeriod reports validity duration strings longer than maxServerValidity.
+func weakServerValidityPeriod(value interface{}, _ time.Time) string {
+	if period, ok := value.(string); ok && period != "" {
+		return weakServerValidity(effectiveValidityPeriod(period, 0))
+	}
+
+	return ""
+}
+
+func weakServerValidity(validity time.Duration) string {
+	if validity > maxServerValidity {
+		return fmt.Sprintf("a validity of %d days exceeds the 398 days browsers accept for TLS server certificates", validity/(24*time.Hour))
+	}
+
+	return ""
+}
+
+// weakCertificates reports the first weakness of the certificates of a PEM input: a SHA-1 signature but on roots, an RSA key
+// under 2048 bits, or 3072 bits for a CA, or more than 90% of the validity elapsed. Invalid PEM is left to the
+// resource to report.
+func weakCertificates(value interface{}, now time.Time) string {
+	data, ok := value.(string)
+	if !ok || data == "" {
+		return ""
+	}
+	certs, err := parsePEMCertificates([]byte(data))
+	if err != nil {
+		return ""
+	}
+
+	for _, cert := range certs {
+		name := fmt.Sprintf("certificate %q", cert.Subject.String())
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			// the signature of self-signed roots is not relied upon
+			if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+				return name + " is signed with SHA-1, vulnerable to collisions"
+			}
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			if bits := key.N.BitLen(); bits < 2048 {
+				return fmt.Sprintf("%s has a %d-bit RSA key, factorable", name, bits)
+			} else if cert.IsCA && bits < 3072 {
+				return fmt.Sprintf("%s is a CA with a %d-bit RSA key, too short for long-lived CAs", name, bits)
+			}
+		}
+		if validity := cert.NotAfter.Sub(cert.NotBefore); validity > 0 && now.Sub(cert.NotBefore) >= validity*9/10 {
+			return fmt.Sprintf("%s is past 90%% of its validity, it expires at %s", name, cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,148 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRSACertificate returns the PEM certificate of template for a new RSA key of bits, self-signed.
+func testRSACertificate(t *testing.T, template *x509.Certificate, bits int) string {
+	t.Helper()
+
+	prvKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(1)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(24 * time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, template, prvKey.Public(), prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificateToPEM(cert)
+}
+
+func TestWeakParameterChecks(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	expiring, _ := testCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "expiring.example.com"},
+		NotBefore: time.Now().Add(-10 * time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}, ca, caKey)
+
+	for name, test := range map[string]struct {
+		check  weakParameterCheck
+		value  interface{}
+		reason string
+	}{
+		"sha1":                  {check: weakHash, value: "sha1", reason: "SHA-1 is vulnerable to collisions"},
+		"sha256":                {check: weakHash, value: "sha256"},
+		"RSA 2048 CA key":       {check: weakCARSABits, value: 2048, reason: "RSA CA keys of 2048 bits are too short"},
+		"RSA 3072 CA key":       {check: weakCARSABits, value: 3072},
+		"unset RSA bits":        {check: weakCARSABits, value: 0},
+		"398 days in hours":     {check: weakServerValidityHours, value: 398 * 24},
+		"399 days in hours":     {check: weakServerValidityHours, value: 399 * 24, reason: "a validity of 399 days exceeds the 398 days"},
+		"one year":              {check: weakServerValidityPeriod, value: "P1Y"},
+		"two years":             {check: weakServerValidityPeriod, value: "P2Y", reason: "a validity of 730 days exceeds the 398 days"},
+		"unset validity period": {check: weakServerValidityPeriod, value: ""},
+		"CA certificate":        {check: weakCertificates, value: certificateToPEM(ca)},
+		"expiring certificate": {
+			check:  weakCertificates,
+			value:  certificateToPEM(ca) + certificateToPEM(expiring),
+			reason: `certificate "CN=expiring.example.com" is past 90% of its validity`,
+		},
+		"RSA 1024 certificate": {
+			check:  weakCertificates,
+			value:  testRSACertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "short.example.com"}}, 1024),
+			reason: `certificate "CN=short.example.com" has a 1024-bit RSA key, factorable`,
+		},
+		"RSA 2048 CA certificate": {
+			check:  weakCertificates,
+			value:  testRSACertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "RSA CA"}, IsCA: true, BasicConstraintsValid: true}, 2048),
+			reason: `certificate "CN=RSA CA" is a CA with a 2048-bit RSA key`,
+		},
+		"RSA 2048 leaf certificate": {
+			check: weakCertificates,
+			value: testRSACertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, 2048),
+		},
+		"invalid PEM": {check: weakCertificates, value: "not PEM"},
+	} {
+		if got := test.check(test.value, time.Now()); (test.reason == "") != (got == "") || !strings.Contains(got, test.reason) {
+			t.Errorf("%s: expected reason %q, got %q", name, test.reason, got)
+		}
+	}
+}
+
+func TestWarnWeakParameter(t *testing.T) {
+	validate := warnWeakParameter(weakHash, func(i interface{}, path cty.Path) diag.Diagnostics {
+		if i == "md5" {
+			return diag.Errorf("unsupported")
+		}
+		return nil
+	})
+	path := cty.GetAttrPath("hash")
+
+	diags := validate("sha1", path)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Weak parameter" || !diags[0].AttributePath.Equals(path) {
+		t.Errorf("expected a weak parameter warning on hash, got %v", diags)
+	}
+	if diags = validate("sha256", path); len(diags) > 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+	if diags = validate("md5", path); !diags.HasError() {
+		t.Errorf("expected the validation to run, got %v", diags)
+	}
+}
+
+func TestCheckWeakParameters(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]interface{}{
+		"ca_cert_pem":        certificateToPEM(ca),
+		"ca_private_key_pem": caKeyPem,
+		"validity_period":    "P400D",
+		// the test CA is valid for a year only
+		"truncate_to_ca_expiry": true,
+	}
+	strict := &providerMeta{weakParametersAsErrors: true}
+
+	r := resourceDualCert()
+	if _, err = r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &providerMeta{}); err != nil {
+		t.Errorf("expected weak parameters to be accepted by default, got %s", err)
+	}
+	if _, err = r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), strict); err == nil || !strings.Contains(err.Error(), "weak parameter validity_period: a validity of 400 days exceeds the 398 days") {
+		t.Errorf("expected weak parameters to be rejected with weak_parameters_as_errors, got %v", err)
+	}
+
+	// existing resources only check the attributes they change
+	state := testResourceApply(t, r, nil, config, &providerMeta{})
+	config["private_key_format"] = PrivateKeyFormatPKCS8.String()
+	if _, err = r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), strict); err != nil {
+		t.Errorf("expected an unchanged weak parameter to be accepted, got %s", err)
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceSignatureVerify().Schema, map[string]interface{}{"hash": "sha1"})
+	if diags := dataSourceSignatureVerifyRead(context.Background(), d, strict); !diags.HasError() || !strings.Contains(diags[0].Summary, "weak parameter hash: SHA-1") {
+		t.Errorf("expected data sources to reject weak parameters from Read, got %v", diags)
+	}
+}
@@ -0,0 +1,210 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+)
+
+// Limits enforced by the ACM ImportCertificate API.
+const (
+	acmMaxCertificateBodyBytes  = 32768
+	acmMaxCertificateChainBytes = 2097152
+	acmMaxPrivateKeyBytes       = 5120
+)
+
+func dataSourceACMCertificate() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"certificate_pem": {
+			Description: "leaf certificate in PEM format. When it contains a bundle, the first certificate is the leaf and the others are added to the chain.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"chain_pem": {
+			Description: "intermediate (and optionally root) certificates in PEM format.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"private_key_pem": {
+			Description: "private key of the leaf certificate in PEM format.",
+			Type:        schema.TypeString,
+			Required:    true,
+			Sensitive:   true,
+		},
+		"certificate_body": {
+			Description: "leaf certificate in PEM format, for the ACM `certificate_body`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_body` rendered like `openssl x509 -text`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_body` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"certificate_chain": {
+			Description: "intermediate certificates in PEM format, without the leaf and self-signed roots, for the ACM `certificate_chain`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"private_key": {
+			Description: "unencrypted private key in PEM format, for the ACM `private_key`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the leaf certificate") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Split certificate material the way the AWS ACM certificate import expects it",
+		ReadContext: dataSourceACMCertificateRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceACMCertificateRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	certs, err := parsePEMCertificates([]byte(d.Get("certificate_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+	if len(certs) == 0 {
+		return diag.FromErr(fmt.Errorf("certificate_pem does not contain any certificate"))
+	}
+	leaf := certs[0]
+
+	chainCerts, err := parsePEMCertificates([]byte(d.Get("chain_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse chain_pem: %w", err))
+	}
+
+	prvKey, _, err := parsePrivateKeyPEM([]byte(d.Get("private_key_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to parse private key PEM: %w", err))
+	}
+
+	if err = acmValidatePrivateKey(prvKey); err != nil {
+		return diag.FromErr(err)
+	}
+	if !privateKeyMatchesCertificate(prvKey, leaf) {
+		return diag.FromErr(fmt.Errorf("private_key_pem does not match the public key of the leaf certificate"))
+	}
+
+	privateKey, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode private key PEM: %w", err))
+	}
+
+	certificateBody := certificateToPEM(leaf)
+
+	var certificateChain strings.Builder
+	for _, cert := range acmIntermediates(leaf, append(certs[1:], chainCerts...)) {
+		certificateChain.WriteString(certificateToPEM(cert))
+	}
+
+	if len(certificateBody) > acmMaxCertificateBodyBytes {
+		return diag.FromErr(fmt.Errorf("certificate body is %d bytes, ACM accepts at most %d", len(certificateBody), acmMaxCertificateBodyBytes))
+	}
+	if certificateChain.Len() > acmMaxCertificateChainBytes {
+		return diag.FromErr(fmt.Errorf("certificate chain is %d bytes, ACM accepts at most %d", certificateChain.Len(), acmMaxCertificateChainBytes))
+	}
+	if len(privateKey) > acmMaxPrivateKeyBytes {
+		return diag.FromErr(fmt.Errorf("private key is %d bytes, ACM accepts at most %d", len(privateKey), acmMaxPrivateKeyBytes))
+	}
+
+	d.SetId(hashForState(certificateBody, certificateChain.String()))
+
+	if err = d.Set("certificate_body", certificateBody); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save certificate_body: %w", err))
+	}
+	if err = d.Set("cert_text", certificateText(leaf)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save cert_text: %w", err))
+	}
+	if err = d.Set("cert_json", certificateJSON(leaf)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save cert_json: %w", err))
+	}
+	if err = d.Set("certificate_chain", certificateChain.String()); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save certificate_chain: %w", err))
+	}
+	if err = d.Set("private_key", privateKey); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save private_key: %w", err))
+	}
+	for key, value := range certificateValidity("", leaf, now(d, m)) {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// acmValidatePrivateKey checks the private key is of a type and size ACM can import.
+func acmValidatePrivateKey(prvKey interface{}) error {
+	switch k := prvKey.(type) {
+	case *rsa.PrivateKey:
+		switch k.N.BitLen() {
+		case 1024, 2048, 3072, 4096:
+			return nil
+		}
+		return fmt.Errorf("ACM does not support %d bit RSA keys", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().Name {
+		case "P-256", "P-384", "P-521":
+			return nil
+		}
+		return fmt.Errorf("ACM does not support ECDSA keys on curve %s", k.Curve.Params().Name)
+	default:
+		return fmt.Errorf("ACM does not support private keys of type %T", prvKey)
+	}
+}
+
+// acmIntermediates returns the intermediates of candidates, without duplicates, the leaf itself or self-signed roots.
+// Certificates found walking up from the leaf come first, in issuing order, followed by the remaining ones.
+func acmIntermediates(leaf *x509.Certificate, candidates []*x509.Certificate) []*x509.Certificate {
+	remaining := make([]*x509.Certificate, 0, len(candidates))
+	for _, cert := range candidates {
+		if cert.Equal(leaf) || isSelfSigned(cert) {
+			continue
+		}
+		duplicate := false
+		for _, other := range remaining {
+			if other.Equal(cert) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			remaining = append(remaining, cert)
+		}
+	}
+
+	chain := make([]*x509.Certificate, 0, len(remaining))
+	for current := leaf; ; {
+		found := -1
+		for i, cert := range remaining {
+			if current.CheckSignatureFrom(cert) == nil {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			break
+		}
+		current = remaining[found]
+		chain = append(chain, current)
+		remaining = append(remaining[:found], remaining[found+1:]...)
+	}
+
+	return append(chain, remaining...)
+}
@@ -0,0 +1,55 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceACMCertificate(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}}, intermediate, intermediateKey)
+	leafKeyPem, err := privateKeyToPEM(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bundle has the leaf first, the chain is out of order with a duplicate and the root
+	d := schema.TestResourceDataRaw(t, dataSourceACMCertificate().Schema, map[string]interface{}{
+		"certificate_pem": strings.ReplaceAll(certificateToPEM(leaf)+certificateToPEM(intermediate), "\n", "\r\n"),
+		"chain_pem":       certificateToPEM(root) + certificateToPEM(intermediate),
+		"private_key_pem": leafKeyPem,
+	})
+	if diags := dataSourceACMCertificateRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if got := d.Get("certificate_body").(string); got != certificateToPEM(leaf) {
+		t.Errorf("expected the leaf with LF line endings as certificate_body, got %q", got)
+	}
+	if got := d.Get("certificate_chain").(string); got != certificateToPEM(intermediate) {
+		t.Errorf("expected the intermediate only as certificate_chain, got %q", got)
+	}
+	if got := d.Get("private_key").(string); got != leafKeyPem {
+		t.Errorf("expected the leaf key as private_key, got %q", got)
+	}
+	if d.Id() == "" {
+		t.Errorf("expected an ID")
+	}
+
+	_, otherKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "other"}}, nil, nil)
+	otherKeyPem, err := privateKeyToPEM(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = schema.TestResourceDataRaw(t, dataSourceACMCertificate().Schema, map[string]interface{}{
+		"certificate_pem": certificateToPEM(leaf),
+		"private_key_pem": otherKeyPem,
+	})
+	if diags := dataSourceACMCertificateRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "does not match") {
+		t.Errorf("expected a key mismatch error, got %v", diags)
+	}
+}
@@ -0,0 +1,128 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"strings"
+)
+
+func dataSourceAIAChain() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"certificate_pem": {
+			Description: "leaf certificate in PEM format.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"max_depth": {
+			Description:      "maximum number of issuers to fetch.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          5,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 20)),
+		},
+		"chain_pem": {
+			Description: "intermediate certificates fetched from the authority information access caIssuers URLs, issuer of the leaf first, in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"fullchain_pem": {
+			Description: "leaf certificate followed by `chain_pem`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"root_pem": {
+			Description: "self-signed root certificate the chain ends with, in PEM format. Empty when the last issuer does not publish a caIssuers URL.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Build the chain of a certificate by following its authority information access caIssuers URLs",
+		ReadContext: dataSourceAIAChainRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceAIAChainRead(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	leaf, err := parsePEMCertificate([]byte(d.Get("certificate_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	chain := &strings.Builder{}
+	rootPem := ""
+	for cert, depth := leaf, 0; !isSelfSigned(cert); depth++ {
+		if len(cert.IssuingCertificateURL) == 0 {
+			break
+		}
+		if depth == d.Get("max_depth").(int) {
+			return diag.FromErr(fmt.Errorf("chain is longer than max_depth (%d)", depth))
+		}
+
+		issuer, err := fetchAIAIssuer(ctx, options, cert)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if isSelfSigned(issuer) {
+			rootPem = certificateToPEM(issuer)
+		} else {
+			chain.WriteString(certificateToPEM(issuer))
+		}
+		cert = issuer
+	}
+
+	leafPem := certificateToPEM(leaf)
+	d.SetId(hashForState(leafPem, chain.String(), rootPem))
+
+	values := map[string]string{
+		"chain_pem":     chain.String(),
+		"fullchain_pem": leafPem + chain.String(),
+		"root_pem":      rootPem,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// fetchAIAIssuer downloads the caIssuers URLs of cert in order and returns the first certificate that signed it.
+func fetchAIAIssuer(ctx context.Context, options *networkOptions, cert *x509.Certificate) (*x509.Certificate, error) {
+	var errs []error
+	for _, issuerURL := range cert.IssuingCertificateURL {
+		content, err := options.download(ctx, issuerURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		candidates, _, err := parseCertificatesOrCRL(content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to parse content of %s: %w", issuerURL, err))
+			continue
+		}
+		for _, candidate := range candidates {
+			if cert.CheckSignatureFrom(candidate) == nil {
+				return candidate, nil
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s does not contain the issuer of %q", issuerURL, cert.Subject.String()))
+	}
+
+	return nil, fmt.Errorf("failed to fetch the issuer of %q: %s", cert.Subject.String(), joinErrors(errs))
+}
@@ -0,0 +1,66 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceAIAChain(t *testing.T) {
+	published := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := published[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	other, _ := testCertificateAuthority(t, "Other CA", nil, nil)
+	intermediate, intermediateKey := testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Intermediate CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		IssuingCertificateURL: []string{server.URL + "/missing.crt", server.URL + "/root.crt"},
+	}, root, rootKey)
+	leaf, _ := testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "www.example.com"},
+		IssuingCertificateURL: []string{server.URL + "/intermediate.p7c"},
+	}, intermediate, intermediateKey)
+	published["/root.crt"] = root.Raw
+	published["/intermediate.p7c"] = testPKCS7Certificates(t, other, intermediate)
+
+	d := schema.TestResourceDataRaw(t, dataSourceAIAChain().Schema, map[string]interface{}{"certificate_pem": certificateToPEM(leaf)})
+	if diags := dataSourceAIAChainRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if got := d.Get("chain_pem").(string); got != certificateToPEM(intermediate) {
+		t.Errorf("expected the intermediate signing the leaf as chain, got %q", got)
+	}
+	if got := d.Get("fullchain_pem").(string); got != certificateToPEM(leaf)+certificateToPEM(intermediate) {
+		t.Errorf("expected the leaf and the intermediate as full chain, got %q", got)
+	}
+	if got := d.Get("root_pem").(string); got != certificateToPEM(root) {
+		t.Errorf("expected the root from the second caIssuers URL, got %q", got)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceAIAChain().Schema, map[string]interface{}{"certificate_pem": certificateToPEM(leaf), "max_depth": 1})
+	if diags := dataSourceAIAChainRead(context.Background(), d, &providerMeta{}); !diags.HasError() || diags[0].Summary != "chain is longer than max_depth (1)" {
+		t.Errorf("expected the chain to exceed max_depth, got %v", diags)
+	}
+
+	published["/intermediate.p7c"] = testPKCS7Certificates(t, other)
+	d = schema.TestResourceDataRaw(t, dataSourceAIAChain().Schema, map[string]interface{}{"certificate_pem": certificateToPEM(leaf)})
+	if diags := dataSourceAIAChainRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "does not contain the issuer of \"CN=www.example.com\"") {
+		t.Errorf("expected an error without the issuer at the caIssuers URL, got %v", diags)
+	}
+}
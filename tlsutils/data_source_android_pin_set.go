@@ -0,0 +1,133 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func dataSourceAndroidPinSet() *schema.Resource {
+	return &schema.Resource{
+		Description: "Render an Android network security config pin-set from certificates and keys",
+		ReadContext: dataSourceAndroidPinSetRead,
+		Schema: map[string]*schema.Schema{
+			"pins_pem": {
+				Description: "certificates, public keys or private keys in PEM format to pin. Each element can contain several PEM blocks.",
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"backup_pins_pem": {
+				Description: "backup public keys, certificates or private keys in PEM format, not yet in use, to pin as well.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"expiration": {
+				Description:      "date after which the pins are no longer enforced, in `yyyy-MM-dd` format.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`), "expected a date in yyyy-MM-dd format")),
+			},
+			"pins": {
+				Description: "base64 encoded SHA-256 of the SubjectPublicKeyInfo of `pins_pem`, in order and without duplicates.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"backup_pins": {
+				Description: "base64 encoded SHA-256 of the SubjectPublicKeyInfo of `backup_pins_pem`, in order and without duplicates.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"xml": {
+				Description: "`<pin-set>` element with `pins` then `backup_pins`, to embed in a `<domain-config>`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceAndroidPinSetRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	expiration := d.Get("expiration").(string)
+	if expiration != "" {
+		if _, err := time.Parse("2006-01-02", expiration); err != nil {
+			return diag.FromErr(fmt.Errorf("invalid expiration %q: %w", expiration, err))
+		}
+	}
+
+	seen := map[string]bool{}
+	pins, err := dataSourceAndroidPinSetPins("pins_pem", d.Get("pins_pem").([]interface{}), seen)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	backupPins, err := dataSourceAndroidPinSetPins("backup_pins_pem", d.Get("backup_pins_pem").([]interface{}), seen)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	xml := &strings.Builder{}
+	if expiration != "" {
+		fmt.Fprintf(xml, "<pin-set expiration=\"%s\">\n", expiration)
+	} else {
+		xml.WriteString("<pin-set>\n")
+	}
+	for _, pin := range append(append([]string{}, pins...), backupPins...) {
+		fmt.Fprintf(xml, "    <pin digest=\"SHA-256\">%s</pin>\n", pin)
+	}
+	xml.WriteString("</pin-set>\n")
+
+	d.SetId(hashForState(xml.String()))
+
+	if err = d.Set("pins", pins); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save pins: %w", err))
+	}
+	if err = d.Set("backup_pins", backupPins); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save backup_pins: %w", err))
+	}
+	if err = d.Set("xml", xml.String()); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save xml: %w", err))
+	}
+
+	if len(backupPins) == 0 {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Pin set without backup pin",
+			Detail:   "Android recommends at least one backup pin, so the app keeps working when the pinned keys are replaced.",
+		}}
+	}
+
+	return nil
+}
+
+// dataSourceAndroidPinSetPins computes the pins of every key found in pems, skipping the ones already seen.
+func dataSourceAndroidPinSetPins(key string, pems []interface{}, seen map[string]bool) ([]string, error) {
+	pins := make([]string, 0)
+	for i, p := range pems {
+		spkis, err := subjectPublicKeyInfosFromPEM([]byte(p.(string)))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%d: %w", key, i, err)
+		}
+		if len(spkis) == 0 {
+			return nil, fmt.Errorf("%s.%d: no certificate or key found", key, i)
+		}
+
+		for _, spki := range spkis {
+			pin := spkiPin(spki)
+			if !seen[pin] {
+				seen[pin] = true
+				pins = append(pins, pin)
+			}
+		}
+	}
+
+	return pins, nil
+}
@@ -0,0 +1,72 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceAndroidPinSet(t *testing.T) {
+	cert, certKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, nil, nil)
+	_, backupKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "backup"}}, nil, nil)
+	backupKeyPem, err := privateKeyToPEM(backupKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(certKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPem := string(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: publicKeyDer}))
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	certPin := base64.StdEncoding.EncodeToString(sum[:])
+
+	// the public key of the certificate is the same pin, the backup key is a private key
+	d := schema.TestResourceDataRaw(t, dataSourceAndroidPinSet().Schema, map[string]interface{}{
+		"pins_pem":        []interface{}{certificateToPEM(cert) + publicKeyPem},
+		"backup_pins_pem": []interface{}{backupKeyPem, publicKeyPem},
+		"expiration":      "2030-01-31",
+	})
+	if diags := dataSourceAndroidPinSetRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	backupPin := d.Get("backup_pins.0").(string)
+	if got := d.Get("pins").([]interface{}); len(got) != 1 || got[0] != certPin {
+		t.Errorf("expected the single pin %s, got %v", certPin, got)
+	}
+	if got := d.Get("backup_pins").([]interface{}); len(got) != 1 || backupPin == certPin {
+		t.Errorf("expected the backup key only as backup pin, got %v", got)
+	}
+	want := "<pin-set expiration=\"2030-01-31\">\n" +
+		"    <pin digest=\"SHA-256\">" + certPin + "</pin>\n" +
+		"    <pin digest=\"SHA-256\">" + backupPin + "</pin>\n" +
+		"</pin-set>\n"
+	if got := d.Get("xml").(string); got != want {
+		t.Errorf("expected xml %q, got %q", want, got)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceAndroidPinSet().Schema, map[string]interface{}{
+		"pins_pem": []interface{}{certificateToPEM(cert)},
+	})
+	diags := dataSourceAndroidPinSetRead(context.Background(), d, &providerMeta{})
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Pin set without backup pin" {
+		t.Errorf("expected a warning without backup pin, got %v", diags)
+	}
+	if got := d.Get("xml").(string); !strings.HasPrefix(got, "<pin-set>\n") {
+		t.Errorf("expected a pin-set without expiration, got %q", got)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceAndroidPinSet().Schema, map[string]interface{}{
+		"pins_pem": []interface{}{certificateToPEM(cert), "no PEM here"},
+	})
+	if diags = dataSourceAndroidPinSetRead(context.Background(), d, &providerMeta{}); !diags.HasError() || diags[0].Summary != "pins_pem.1: no certificate or key found" {
+		t.Errorf("expected an error for an element without key, got %v", diags)
+	}
+}
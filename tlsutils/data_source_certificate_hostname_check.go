@@ -0,0 +1,135 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net"
+	"strings"
+	"time"
+)
+
+func dataSourceCertificateHostnameCheck() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"certificate_pem": {
+			Description: "certificate to check in PEM format.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"hostnames": {
+			Description: "hostnames or IP addresses the certificate must be valid for. They are matched against the subject alternative names, with a wildcard matching exactly one leftmost label. Unicode hostnames are converted to punycode.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"evaluation_time": {
+			Description:      "time in RFC3339 format at which the certificate must be valid, instead of the provider evaluation_time or the current time.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+		},
+		"valid": {
+			Description: "true when the certificate matches all `hostnames` and is within its validity period at the evaluation time.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+		"within_validity": {
+			Description: "true when the evaluation time is between the not before and not after of the certificate.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+		"matching_hostnames": {
+			Description: "elements of `hostnames` the certificate is valid for.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"mismatched_hostnames": {
+			Description: "elements of `hostnames` the certificate is not valid for.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"errors": {
+			Description: "reasons the certificate is not valid, empty when `valid` is true.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the certificate") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Check a certificate is valid for hostnames or IP addresses",
+		ReadContext: dataSourceCertificateHostnameCheckRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceCertificateHostnameCheckRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	certPem := d.Get("certificate_pem").(string)
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+	at := now(d, m)
+
+	errs := make([]interface{}, 0)
+	withinValidity := true
+	if at.Before(cert.NotBefore) {
+		withinValidity = false
+		errs = append(errs, fmt.Sprintf("certificate is not valid before %s", cert.NotBefore.Format(time.RFC3339)))
+	}
+	if at.After(cert.NotAfter) {
+		withinValidity = false
+		errs = append(errs, fmt.Sprintf("certificate expired at %s", cert.NotAfter.Format(time.RFC3339)))
+	}
+
+	hostnames := d.Get("hostnames").([]interface{})
+	matching := make([]interface{}, 0, len(hostnames))
+	mismatched := make([]interface{}, 0)
+	for i, raw := range hostnames {
+		hostname := raw.(string)
+		normalized := hostname
+		if net.ParseIP(hostname) == nil {
+			if normalized, err = dnsNameToASCII(hostname); err != nil {
+				return diag.FromErr(fmt.Errorf("invalid hostnames.%d: %w", i, err))
+			}
+		}
+		if err = cert.VerifyHostname(normalized); err != nil {
+			mismatched = append(mismatched, hostname)
+			errs = append(errs, err.Error())
+			continue
+		}
+		matching = append(matching, hostname)
+	}
+
+	hostnamesStr := make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		hostnamesStr = append(hostnamesStr, hostname.(string))
+	}
+	d.SetId(hashForState(certPem, strings.Join(hostnamesStr, ","), at.Format(time.RFC3339)))
+
+	values := map[string]interface{}{
+		"valid":                withinValidity && len(mismatched) == 0,
+		"within_validity":      withinValidity,
+		"matching_hostnames":   matching,
+		"mismatched_hostnames": mismatched,
+		"errors":               errs,
+	}
+	for key, value := range certificateValidity("", cert, at) {
+		values[key] = value
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataSourceCertificateHostnameCheck(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	cert, _ := testCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "ignored.example.com"},
+		DNSNames:    []string{"www.example.com", "*.xn--bcher-kva.example"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+		NotAfter:    notAfter,
+	}, nil, nil)
+
+	for name, test := range map[string]struct {
+		hostnames      []interface{}
+		evaluationTime string
+		valid          bool
+		withinValidity bool
+		matching       []interface{}
+		mismatched     []interface{}
+		err            string
+	}{
+		"matching names and IP address": {
+			hostnames:      []interface{}{"www.example.com", "192.0.2.1", "shop.bücher.example"},
+			valid:          true,
+			withinValidity: true,
+			matching:       []interface{}{"www.example.com", "192.0.2.1", "shop.bücher.example"},
+			mismatched:     []interface{}{},
+		},
+		"common name and nested wildcard label": {
+			hostnames:      []interface{}{"ignored.example.com", "a.b.xn--bcher-kva.example", "www.example.com"},
+			withinValidity: true,
+			matching:       []interface{}{"www.example.com"},
+			mismatched:     []interface{}{"ignored.example.com", "a.b.xn--bcher-kva.example"},
+			err:            "certificate is valid for www.example.com, *.xn--bcher-kva.example, not ignored.example.com",
+		},
+		"expired at the evaluation time": {
+			hostnames:      []interface{}{"www.example.com"},
+			evaluationTime: notAfter.Add(time.Minute).Format(time.RFC3339),
+			matching:       []interface{}{"www.example.com"},
+			mismatched:     []interface{}{},
+			err:            "certificate expired at " + notAfter.UTC().Format(time.RFC3339),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"certificate_pem": certificateToPEM(cert),
+				"hostnames":       test.hostnames,
+			}
+			if test.evaluationTime != "" {
+				raw["evaluation_time"] = test.evaluationTime
+			}
+			d := schema.TestResourceDataRaw(t, dataSourceCertificateHostnameCheck().Schema, raw)
+			if diags := dataSourceCertificateHostnameCheckRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			if got := d.Get("valid").(bool); got != test.valid {
+				t.Errorf("expected valid %t, got %t", test.valid, got)
+			}
+			if got := d.Get("within_validity").(bool); got != test.withinValidity {
+				t.Errorf("expected within_validity %t, got %t", test.withinValidity, got)
+			}
+			if got := d.Get("matching_hostnames").([]interface{}); !reflect.DeepEqual(got, test.matching) {
+				t.Errorf("expected matching_hostnames %v, got %v", test.matching, got)
+			}
+			if got := d.Get("mismatched_hostnames").([]interface{}); !reflect.DeepEqual(got, test.mismatched) {
+				t.Errorf("expected mismatched_hostnames %v, got %v", test.mismatched, got)
+			}
+			errs := d.Get("errors").([]interface{})
+			if test.err == "" && len(errs) > 0 || test.err != "" && (len(errs) == 0 || !strings.Contains(errs[0].(string), test.err)) {
+				t.Errorf("expected errors starting with %q, got %v", test.err, errs)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceCertificateHostnameCheck().Schema, map[string]interface{}{
+		"certificate_pem": certificateToPEM(cert),
+		"hostnames":       []interface{}{"www.example.com", "w*.example.com"},
+	})
+	if diags := dataSourceCertificateHostnameCheckRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.HasPrefix(diags[0].Summary, "invalid hostnames.1: ") {
+		t.Errorf("expected an invalid hostname to be refused, got %v", diags)
+	}
+}
+
+func TestDataSourceCertificateHostnameCheckValidity(t *testing.T) {
+	notBefore := time.Now().Add(-time.Hour).Truncate(time.Second)
+	notAfter := notBefore.Add(2 * time.Hour)
+	cert, _ := testCertificate(t, &x509.Certificate{DNSNames: []string{"www.example.com"}, NotBefore: notBefore, NotAfter: notAfter}, nil, nil)
+
+	for evaluationTime, remaining := range map[time.Time]int{
+		notAfter.Add(-90 * time.Second): 90,
+		notAfter.Add(time.Minute):       -60,
+	} {
+		d := schema.TestResourceDataRaw(t, dataSourceCertificateHostnameCheck().Schema, map[string]interface{}{
+			"certificate_pem": certificateToPEM(cert),
+			"hostnames":       []interface{}{"www.example.com"},
+			"evaluation_time": evaluationTime.Format(time.RFC3339),
+		})
+		if diags := dataSourceCertificateHostnameCheckRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+			t.Fatalf("read failed: %v", diags)
+		}
+
+		want := map[string]interface{}{
+			"not_before":        notBefore.UTC().Format(time.RFC3339),
+			"not_after":         notAfter.UTC().Format(time.RFC3339),
+			"not_before_unix":   int(notBefore.Unix()),
+			"not_after_unix":    int(notAfter.Unix()),
+			"remaining_seconds": remaining,
+		}
+		for key, value := range want {
+			if got := d.Get(key); got != value {
+				t.Errorf("at %s: expected %s %v, got %v", evaluationTime, key, value, got)
+			}
+		}
+	}
+}
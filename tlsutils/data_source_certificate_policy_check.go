@@ -0,0 +1,168 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"slices"
+	"strings"
+	"time"
+)
+
+func dataSourceCertificatePolicyCheck() *schema.Resource {
+	return &schema.Resource{
+		Description: "Check a certificate against a policy, for check blocks and preconditions",
+		ReadContext: dataSourceCertificatePolicyCheckRead,
+		Schema: map[string]*schema.Schema{
+			"certificate_pem": {
+				Description: "certificate to check in PEM format.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"policy": {
+				Description: "policy the certificate must match. Unset rules are not checked.",
+				Type:        schema.TypeList,
+				Required:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_validity_hours": {
+							Description:      "maximum number of hours between the not before and not after of the certificate.",
+							Type:             schema.TypeInt,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+						},
+						"allowed_san_suffixes": {
+							Description: "DNS suffixes the DNS names of the certificate must be equal to or a subdomain of, e.g. `example.com` allows `example.com` and `*.api.example.com`. Unicode suffixes are converted to punycode.",
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validateDNSName,
+							},
+						},
+						"required_ext_key_usages": {
+							Description: "extended key usages the certificate must have: " + strings.Join(supportedExtKeyUsagesStr(), ", ") + ".",
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.StringInSlice(supportedExtKeyUsagesStr(), false),
+							},
+						},
+						"min_rsa_bits": {
+							Description:      "minimum size in bits of an RSA key.",
+							Type:             schema.TypeInt,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+						},
+						"min_ecdsa_bits": {
+							Description:      "minimum curve size in bits of an ECDSA key, e.g. 384 rejects P256 keys.",
+							Type:             schema.TypeInt,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+						},
+					},
+				},
+			},
+			"valid": {
+				Description: "true when the certificate matches all the rules of the policy.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"violations": {
+				Description: "rules of the policy the certificate breaks, empty when `valid` is true.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceCertificatePolicyCheckRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	certPem := d.Get("certificate_pem").(string)
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+	policy := map[string]interface{}{}
+	if policies := d.Get("policy").([]interface{}); len(policies) > 0 && policies[0] != nil {
+		policy = policies[0].(map[string]interface{})
+	}
+
+	violations, err := certificatePolicyViolations(cert, policy)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(hashForState(certPem, fmt.Sprint(policy)))
+
+	values := map[string]interface{}{
+		"valid":      len(violations) == 0,
+		"violations": violations,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// certificatePolicyViolations returns the rules of a policy block cert breaks.
+func certificatePolicyViolations(cert *x509.Certificate, policy map[string]interface{}) ([]string, error) {
+	violations := make([]string, 0)
+
+	if maxHours, _ := policy["max_validity_hours"].(int); maxHours > 0 {
+		if validity := cert.NotAfter.Sub(cert.NotBefore); validity > time.Duration(maxHours)*time.Hour {
+			violations = append(violations, fmt.Sprintf("validity of %d hours exceeds the maximum of %d hours", int(validity.Hours()), maxHours))
+		}
+	}
+
+	if rawSuffixes, _ := policy["allowed_san_suffixes"].([]interface{}); len(rawSuffixes) > 0 {
+		suffixes := make([]string, 0, len(rawSuffixes))
+		for i, raw := range rawSuffixes {
+			suffix, err := dnsNameToASCII(strings.TrimPrefix(raw.(string), "."))
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy.0.allowed_san_suffixes.%d: %w", i, err)
+			}
+			suffixes = append(suffixes, strings.ToLower(suffix))
+		}
+		for _, name := range cert.DNSNames {
+			lower := strings.ToLower(name)
+			allowed := slices.ContainsFunc(suffixes, func(suffix string) bool {
+				return lower == suffix || strings.HasSuffix(lower, "."+suffix)
+			})
+			if !allowed {
+				violations = append(violations, fmt.Sprintf("DNS name %q is not under an allowed suffix", name))
+			}
+		}
+	}
+
+	rawUsages, _ := policy["required_ext_key_usages"].([]interface{})
+	for _, raw := range rawUsages {
+		if !slices.Contains(cert.ExtKeyUsage, extKeyUsages[raw.(string)]) {
+			violations = append(violations, fmt.Sprintf("extended key usage %s is missing", raw.(string)))
+		}
+	}
+
+	switch pubKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if minBits, _ := policy["min_rsa_bits"].(int); pubKey.N.BitLen() < minBits {
+			violations = append(violations, fmt.Sprintf("RSA key of %d bits is smaller than the minimum of %d bits", pubKey.N.BitLen(), minBits))
+		}
+	case *ecdsa.PublicKey:
+		if minBits, _ := policy["min_ecdsa_bits"].(int); pubKey.Curve.Params().BitSize < minBits {
+			violations = append(violations, fmt.Sprintf("ECDSA key of %d bits is smaller than the minimum of %d bits", pubKey.Curve.Params().BitSize, minBits))
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,84 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataSourceCertificatePolicyCheck(t *testing.T) {
+	notBefore := time.Now().Truncate(time.Second)
+	cert, _ := testCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "www.example.com"},
+		DNSNames:    []string{"www.example.com", "*.api.example.com", "example.org"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		NotBefore:   notBefore,
+		NotAfter:    notBefore.Add(48 * time.Hour),
+	}, nil, nil)
+
+	for name, test := range map[string]struct {
+		policy     map[string]interface{}
+		violations []interface{}
+	}{
+		"matching": {
+			policy: map[string]interface{}{
+				"max_validity_hours":      48,
+				"allowed_san_suffixes":    []interface{}{"example.com", ".example.org"},
+				"required_ext_key_usages": []interface{}{"server_auth"},
+				"min_rsa_bits":            4096,
+				"min_ecdsa_bits":          256,
+			},
+			violations: []interface{}{},
+		},
+		"empty policy": {
+			policy:     map[string]interface{}{},
+			violations: []interface{}{},
+		},
+		"validity": {
+			policy:     map[string]interface{}{"max_validity_hours": 24},
+			violations: []interface{}{"validity of 48 hours exceeds the maximum of 24 hours"},
+		},
+		"SAN suffixes": {
+			policy:     map[string]interface{}{"allowed_san_suffixes": []interface{}{"api.example.com", "example.org"}},
+			violations: []interface{}{`DNS name "www.example.com" is not under an allowed suffix`},
+		},
+		"extended key usages": {
+			policy:     map[string]interface{}{"required_ext_key_usages": []interface{}{"server_auth", "client_auth"}},
+			violations: []interface{}{"extended key usage client_auth is missing"},
+		},
+		"ECDSA key size": {
+			policy:     map[string]interface{}{"min_ecdsa_bits": 384},
+			violations: []interface{}{"ECDSA key of 256 bits is smaller than the minimum of 384 bits"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourceCertificatePolicyCheck().Schema, map[string]interface{}{
+				"certificate_pem": certificateToPEM(cert),
+				"policy":          []interface{}{test.policy},
+			})
+			if diags := dataSourceCertificatePolicyCheckRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			if got := d.Get("valid").(bool); got != (len(test.violations) == 0) {
+				t.Errorf("expected valid %t, got %t", len(test.violations) == 0, got)
+			}
+			if got := d.Get("violations").([]interface{}); !reflect.DeepEqual(got, test.violations) {
+				t.Errorf("expected violations %v, got %v", test.violations, got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceCertificatePolicyCheck().Schema, map[string]interface{}{
+		"certificate_pem": "not a certificate",
+		"policy":          []interface{}{map[string]interface{}{"max_validity_hours": 24}},
+	})
+	if diags := dataSourceCertificatePolicyCheckRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.HasPrefix(diags[0].Summary, "unable to parse certificate_pem") {
+		t.Errorf("expected an invalid certificate to fail, got %v", diags)
+	}
+}
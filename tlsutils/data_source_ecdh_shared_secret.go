@@ -0,0 +1,191 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+func dataSourceECDHSharedSecret() *schema.Resource {
+	return &schema.Resource{
+		Description: "Compute the ECDH or X25519 shared secret of a private key and a peer public key, and a key derived from it with HKDF, for provisioning symmetric credentials to devices publishing only a public key",
+		ReadContext: dataSourceECDHSharedSecretRead,
+		Schema: map[string]*schema.Schema{
+			"private_key": {
+				Description: "private key in PEM format (ECDSA, or X25519 in PKCS#8), or base64 encoded raw X25519 private key as used by WireGuard.",
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+			},
+			"peer_public_key": {
+				Description: "public key of the peer on the same curve: public key, certificate or private key in PEM format, or base64 encoded raw X25519 public key.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"hkdf_hash": {
+				Description:      "hash of the HKDF (RFC 5869) deriving `derived_key_hex` and `derived_key_base64`: `sha1`, `sha256`, `sha384` or `sha512`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "sha256",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedHashAlgorithmsStr(), false)),
+			},
+			"hkdf_salt_base64": {
+				Description:      "base64 encoded salt of the HKDF. Defaults to no salt, a string of zeros of the hash length.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+			},
+			"hkdf_info": {
+				Description: "application info of the HKDF, binding the derived key to its purpose, e.g. `device-psk-v1`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"derived_key_length": {
+				Description:      "length of the derived key in bytes, at most 255 times the hash length.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          32,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+			},
+			"shared_secret_hex": {
+				Description: "raw shared secret, hex encoded. Prefer the derived key: the raw secret is not uniformly random.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"derived_key_hex": {
+				Description: "key derived from the shared secret with HKDF, hex encoded.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"derived_key_base64": {
+				Description: "key derived from the shared secret with HKDF, base64 encoded.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"public_key_pem": {
+				Description: "public key of `private_key` in PEM (SubjectPublicKeyInfo) format, to publish to the peer.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceECDHSharedSecretRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	prvKey, err := parseECDHPrivateKey(d.Get("private_key").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid private_key: %w", err))
+	}
+	peerKey, err := parseEncryptionPublicKey(d.Get("peer_public_key").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid peer_public_key: %w", err))
+	}
+	peerECDHKey, err := ecdhPublicKey(peerKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid peer_public_key: %w", err))
+	}
+	if peerECDHKey.Curve() != prvKey.Curve() {
+		return diag.FromErr(fmt.Errorf("peer_public_key is on %s, private_key on %s", peerECDHKey.Curve(), prvKey.Curve()))
+	}
+
+	secret, err := prvKey.ECDH(peerECDHKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to compute shared secret: %w", err))
+	}
+
+	hash := hashAlgorithms[d.Get("hkdf_hash").(string)]
+	length := d.Get("derived_key_length").(int)
+	if length > 255*hash.Size() {
+		return diag.FromErr(fmt.Errorf("derived_key_length can be at most %d bytes with %s", 255*hash.Size(), d.Get("hkdf_hash").(string)))
+	}
+	salt, err := base64.StdEncoding.DecodeString(d.Get("hkdf_salt_base64").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid hkdf_salt_base64: %w", err))
+	}
+	derivedKey := make([]byte, length)
+	if _, err = io.ReadFull(hkdf.New(hash.New, secret, salt, []byte(d.Get("hkdf_info").(string))), derivedKey); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to derive key: %w", err))
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(prvKey.PublicKey())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal public key: %w", err))
+	}
+	publicKeyPem := string(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: spki}))
+
+	d.SetId(hashForState(publicKeyPem, hex.EncodeToString(peerECDHKey.Bytes())))
+
+	values := map[string]string{
+		"shared_secret_hex":  hex.EncodeToString(secret),
+		"derived_key_hex":    hex.EncodeToString(derivedKey),
+		"derived_key_base64": base64.StdEncoding.EncodeToString(derivedKey),
+		"public_key_pem":     publicKeyPem,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// parseECDHPrivateKey parses an ECDSA or X25519 private key in PEM format, or a base64 encoded raw X25519 private key,
+// as a key agreement key.
+func parseECDHPrivateKey(privateKey string) (*ecdh.PrivateKey, error) {
+	if !strings.HasPrefix(strings.TrimSpace(privateKey), "-----BEGIN") {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+		if err != nil {
+			return nil, fmt.Errorf("private key is neither PEM nor base64: %w", err)
+		}
+		x25519Key, err := ecdh.X25519().NewPrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw X25519 private key: %w", err)
+		}
+		return x25519Key, nil
+	}
+
+	pemBlock := decodePrivateKeyPEM([]byte(privateKey))
+	if pemBlock == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	preamble, err := pemBlockToPEMPreamble(pemBlock)
+	if err != nil {
+		return nil, err
+	}
+	parser, ok := keyParsers[preamble]
+	if !ok {
+		return nil, fmt.Errorf("unable to determine parser for PEM preamble: %s", preamble)
+	}
+	prvKey, err := parser(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key given PEM preamble '%s': %w", preamble, err)
+	}
+
+	switch prvKey := prvKey.(type) {
+	case *ecdh.PrivateKey:
+		return prvKey, nil
+	case *ecdsa.PrivateKey:
+		ecdhKey, err := prvKey.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("unsupported ECDSA key: %w", err)
+		}
+		return ecdhKey, nil
+	}
+
+	return nil, fmt.Errorf("unsupported %T key, expected an ECDSA or X25519 key", prvKey)
+}
@@ -0,0 +1,101 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceECDHSharedSecret(t *testing.T) {
+	// RFC 7748 section 6.1
+	alicePrivateKey, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPublicKey, _ := hex.DecodeString("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	sharedSecret, _ := hex.DecodeString("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	d := schema.TestResourceDataRaw(t, dataSourceECDHSharedSecret().Schema, map[string]interface{}{
+		"private_key":        base64.StdEncoding.EncodeToString(alicePrivateKey),
+		"peer_public_key":    base64.StdEncoding.EncodeToString(bobPublicKey),
+		"hkdf_salt_base64":   base64.StdEncoding.EncodeToString([]byte("salt")),
+		"hkdf_info":          "device-psk-v1",
+		"derived_key_length": 16,
+	})
+	if diags := dataSourceECDHSharedSecretRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if got := d.Get("shared_secret_hex").(string); got != hex.EncodeToString(sharedSecret) {
+		t.Errorf("expected the shared secret of RFC 7748, got %s", got)
+	}
+	derivedKey := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, []byte("salt"), []byte("device-psk-v1")), derivedKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Get("derived_key_hex").(string); got != hex.EncodeToString(derivedKey) {
+		t.Errorf("expected derived key %x, got %s", derivedKey, got)
+	}
+	if got := d.Get("derived_key_base64").(string); got != base64.StdEncoding.EncodeToString(derivedKey) {
+		t.Errorf("expected derived key %s, got %s", base64.StdEncoding.EncodeToString(derivedKey), got)
+	}
+
+	// both sides of an ECDSA key pair derive the same key
+	keyPEMs := make([]string, 2)
+	publicKeyPEMs := make([]string, 2)
+	for i := range keyPEMs {
+		prvKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keyPEMs[i], err = privateKeyToPEM(prvKey); err != nil {
+			t.Fatal(err)
+		}
+		d = schema.TestResourceDataRaw(t, dataSourceECDHSharedSecret().Schema, map[string]interface{}{"private_key": keyPEMs[i], "peer_public_key": keyPEMs[i]})
+		if diags := dataSourceECDHSharedSecretRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+			t.Fatalf("read failed: %v", diags)
+		}
+		publicKeyPEMs[i] = d.Get("public_key_pem").(string)
+	}
+	derivedKeys := make([]string, 2)
+	for i := range keyPEMs {
+		d = schema.TestResourceDataRaw(t, dataSourceECDHSharedSecret().Schema, map[string]interface{}{"private_key": keyPEMs[i], "peer_public_key": publicKeyPEMs[1-i]})
+		if diags := dataSourceECDHSharedSecretRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+			t.Fatalf("read failed: %v", diags)
+		}
+		derivedKeys[i] = d.Get("derived_key_hex").(string)
+	}
+	if derivedKeys[0] != derivedKeys[1] || len(derivedKeys[0]) != 64 {
+		t.Errorf("expected both peers to derive the same 32 bytes key, got %s and %s", derivedKeys[0], derivedKeys[1])
+	}
+
+	for name, test := range map[string]struct {
+		raw map[string]interface{}
+		err string
+	}{
+		"curve mismatch": {
+			raw: map[string]interface{}{"private_key": keyPEMs[0], "peer_public_key": base64.StdEncoding.EncodeToString(bobPublicKey)},
+			err: "peer_public_key is on X25519, private_key on P-256",
+		},
+		"derived key too long": {
+			raw: map[string]interface{}{"private_key": keyPEMs[0], "peer_public_key": publicKeyPEMs[1], "derived_key_length": 255*32 + 1},
+			err: "derived_key_length can be at most 8160 bytes with sha256",
+		},
+		"invalid private key": {
+			raw: map[string]interface{}{"private_key": "not a key", "peer_public_key": publicKeyPEMs[1]},
+			err: "invalid private_key",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourceECDHSharedSecret().Schema, test.raw)
+			if diags := dataSourceECDHSharedSecretRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, test.err) {
+				t.Errorf("expected error %q, got %v", test.err, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strings"
+)
+
+// ejbcaSearchProperties and ejbcaSearchOperations are the criteria of the EJBCA certificate search endpoint.
+var (
+	ejbcaSearchProperties = []string{"QUERY", "END_ENTITY_PROFILE", "CERTIFICATE_PROFILE", "CA", "STATUS", "ISSUED_DATE", "EXPIRE_DATE", "REVOCATION_DATE"}
+	ejbcaSearchOperations = []string{"EQUAL", "LIKE", "BEFORE", "AFTER"}
+)
+
+func dataSourceEJBCACertificates() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"criteria": {
+			Description: "search criteria, all of which the certificates must match.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"property": {
+						Description:  "property to match: " + strings.Join(ejbcaSearchProperties, ", ") + ". `QUERY` matches the subject DN, the SANs, the username and the serial number.",
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(ejbcaSearchProperties, false),
+					},
+					"value": {
+						Description: "value of the property, e.g. a CA name for `CA`, `CERT_ACTIVE` or `CERT_REVOKED` for `STATUS`, or a time in RFC3339 format for the dates.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"operation": {
+						Description:  "comparison: `EQUAL` or `LIKE` for text properties, `BEFORE` or `AFTER` for dates.",
+						Type:         schema.TypeString,
+						Optional:     true,
+						Default:      "EQUAL",
+						ValidateFunc: validation.StringInSlice(ejbcaSearchOperations, false),
+					},
+				},
+			},
+		},
+		"max_results": {
+			Description:      "maximum number of certificates returned.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          100,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+		},
+		"certificates": {
+			Description: "certificates found.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        certificateSummarySchema(),
+		},
+		"more_results": {
+			Description: "true when more than `max_results` certificates match.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range ejbcaSchema() {
+		// data sources are read again on every plan, nothing is replaced
+		attribute.ForceNew = false
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Search certificates with the REST API of EJBCA",
+		ReadContext: dataSourceEJBCACertificatesRead,
+		Schema:      s,
+	}
+}
+
+// ejbcaSearchRequest is the body of the EJBCA certificate search endpoint.
+type ejbcaSearchRequest struct {
+	MaxNumberOfResults int                   `json:"max_number_of_results"`
+	Criteria           []ejbcaSearchCriteria `json:"criteria"`
+}
+
+type ejbcaSearchCriteria struct {
+	Property  string `json:"property"`
+	Value     string `json:"value"`
+	Operation string `json:"operation"`
+}
+
+// ejbcaSearchResponse is the relevant part of the response of the EJBCA certificate search endpoint.
+type ejbcaSearchResponse struct {
+	Certificates []ejbcaCertificate `json:"certificates"`
+	MoreResults  bool               `json:"more_results"`
+}
+
+func dataSourceEJBCACertificatesRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	reqBody := ejbcaSearchRequest{MaxNumberOfResults: d.Get("max_results").(int)}
+	for _, raw := range d.Get("criteria").([]interface{}) {
+		criteria := raw.(map[string]interface{})
+		reqBody.Criteria = append(reqBody.Criteria, ejbcaSearchCriteria{
+			Property:  criteria["property"].(string),
+			Value:     criteria["value"].(string),
+			Operation: criteria["operation"].(string),
+		})
+	}
+
+	var resp ejbcaSearchResponse
+	if err := ejbcaRequest(ctx, d, http.MethodPost, "v1/certificate/search", reqBody, &resp); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to search certificates in EJBCA: %w", err))
+	}
+
+	at := now(d, m)
+	certificates := make([]interface{}, 0, len(resp.Certificates))
+	fingerprints := make([]string, 0, len(resp.Certificates))
+	for i, found := range resp.Certificates {
+		cert, err := parseEJBCACertificate(found.Certificate, found.ResponseFormat)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("EJBCA returned an invalid certificates.%d: %w", i, err))
+		}
+		certificates = append(certificates, certificateSummary(cert, at))
+		fingerprints = append(fingerprints, sha256Fingerprint(cert))
+	}
+
+	d.SetId(hashForState(d.Get("ejbca_url").(string), fmt.Sprint(reqBody), strings.Join(fingerprints, ",")))
+
+	values := map[string]interface{}{
+		"certificates": certificates,
+		"more_results": resp.MoreResults,
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
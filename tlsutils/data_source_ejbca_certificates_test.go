@@ -0,0 +1,47 @@
+package tlsutils
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceEJBCACertificates(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "EJBCA CA", nil, nil)
+	server := newTestEJBCAServer(t, ca, caKey)
+	enroll := testEJBCAConfig(server)
+	for name, value := range map[string]interface{}{
+		"certificate_profile_name":   "SERVER",
+		"end_entity_profile_name":    "SERVER",
+		"certificate_authority_name": "EJBCA CA",
+	} {
+		enroll[name] = value
+	}
+	for _, name := range []string{"web.example.com", "api.example.com"} {
+		enroll["csr_pem"] = testCertificateRequest(t, name)
+		enroll["username"] = name
+		testResourceApply(t, resourceEJBCAEnrolledCert(), nil, enroll, &providerMeta{})
+	}
+
+	config := testEJBCAConfig(server)
+	config["criteria"] = []interface{}{map[string]interface{}{"property": "CA", "value": "EJBCA CA"}}
+	config["max_results"] = 1
+	d := schema.TestResourceDataRaw(t, dataSourceEJBCACertificates().Schema, config)
+	if diags := dataSourceEJBCACertificatesRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	certificates := d.Get("certificates").([]interface{})
+	if len(certificates) != 1 || !d.Get("more_results").(bool) {
+		t.Fatalf("expected 1 certificate with more results, got %d (more_results %v)", len(certificates), d.Get("more_results"))
+	}
+	if subject := certificates[0].(map[string]interface{})["subject"]; subject != "CN=web.example.com" {
+		t.Errorf("expected the first enrolled certificate, got %v", subject)
+	}
+
+	config["bearer_token"] = "wrong"
+	d = schema.TestResourceDataRaw(t, dataSourceEJBCACertificates().Schema, config)
+	if diags := dataSourceEJBCACertificatesRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "failed to search certificates in EJBCA") {
+		t.Errorf("expected an unauthorized search to fail, got %v", diags)
+	}
+}
@@ -0,0 +1,179 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"strings"
+)
+
+func dataSourceEndpointCertificate() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"address": {
+			Description: "endpoint to connect to, as `host:port`.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"server_name": {
+			Description: "server name sent as SNI. Defaults to the host of `address`, unless it is an IP address.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"verify_hostname": {
+			Description: "DNS name or IP address the served certificate must be valid for. When set, the served chain is verified against `ca_cert_pem` and the result is reported in `verified` and `verification_error`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"ca_cert_pem": {
+			Description: "CA certificates in PEM format trusted when verifying the served chain. Defaults to the system roots.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"starttls": {
+			Description:      "protocol used to switch a plaintext connection to TLS before the handshake: `smtp`, `imap`, `pop3`, `ldap` or `postgres`. By default the handshake starts right away.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedSTARTTLSProtocolsStr(), false)),
+		},
+		"expected_certificate_pem": {
+			Description: "certificate the endpoint should serve, in PEM format, usually the one managed by Terraform. A warning is raised when the endpoint serves another one.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"certificate_pem": {
+			Description: "leaf certificate served by the endpoint in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"chain_pem": {
+			Description: "other certificates served by the endpoint, in the order they were sent, in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"sha256_fingerprint": {
+			Description: "hex encoded SHA-256 of the served leaf certificate.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"expected_sha256_fingerprint": {
+			Description: "hex encoded SHA-256 of `expected_certificate_pem`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"verified": {
+			Description: "true when `verify_hostname` is set and the served chain is valid for it.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+		"verification_error": {
+			Description: "reason the served chain is not valid for `verify_hostname`, empty when it is.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"drifted": {
+			Description: "true when `expected_certificate_pem` is set and differs from the served leaf certificate.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range certificateValiditySchema("", "the served leaf certificate") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Fetch the certificate served by a TLS endpoint and detect drift from the managed one",
+		ReadContext: dataSourceEndpointCertificateRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceEndpointCertificateRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	expectedFingerprint := ""
+	if expectedPem := d.Get("expected_certificate_pem").(string); expectedPem != "" {
+		expected, err := parsePEMCertificate([]byte(expectedPem))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse expected_certificate_pem: %w", err))
+		}
+		expectedFingerprint = sha256Fingerprint(expected)
+	}
+
+	if caCertPem := d.Get("ca_cert_pem").(string); caCertPem != "" {
+		if roots, err := parsePEMCertificates([]byte(caCertPem)); err != nil || len(roots) == 0 {
+			return diag.FromErr(fmt.Errorf("ca_cert_pem does not contain any valid certificate"))
+		}
+	}
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	address := d.Get("address").(string)
+	certs, err := fetchPeerCertificates(ctx, options, address, d.Get("server_name").(string), d.Get("starttls").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	chain := &strings.Builder{}
+	for _, cert := range certs[1:] {
+		chain.WriteString(certificateToPEM(cert))
+	}
+	fingerprint := sha256Fingerprint(certs[0])
+	drifted := expectedFingerprint != "" && expectedFingerprint != fingerprint
+
+	verified, verificationError := false, ""
+	if hostname := d.Get("verify_hostname").(string); hostname != "" {
+		if err = verifyPeerCertificates(certs, hostname, d.Get("ca_cert_pem").(string), now(d, m)); err != nil {
+			verificationError = err.Error()
+		} else {
+			verified = true
+		}
+	}
+
+	d.SetId(hashForState(address, fingerprint))
+
+	values := map[string]interface{}{
+		"certificate_pem":             certificateToPEM(certs[0]),
+		"cert_text":                   certificateText(certs[0]),
+		"cert_json":                   certificateJSON(certs[0]),
+		"chain_pem":                   chain.String(),
+		"sha256_fingerprint":          fingerprint,
+		"expected_sha256_fingerprint": expectedFingerprint,
+		"verified":                    verified,
+		"verification_error":          verificationError,
+		"drifted":                     drifted,
+	}
+	for key, value := range certificateValidity("", certs[0], now(d, m)) {
+		values[key] = value
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	if drifted {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Served certificate has drifted",
+			Detail:   fmt.Sprintf("%s serves the certificate %s (serial %s, subject %q), not the expected %s.", address, fingerprint, certs[0].SerialNumber.Text(16), certs[0].Subject.String(), expectedFingerprint),
+		}}
+	}
+
+	return nil
+}
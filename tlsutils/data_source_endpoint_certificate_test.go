@@ -0,0 +1,179 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTLSServer accepts TLS connections on a local address, serving the certificate chain certs of key and closing
+// each connection after the handshake. It returns the address as host:port.
+func testTLSServer(t *testing.T, key crypto.PrivateKey, certs ...*x509.Certificate) string {
+	t.Helper()
+
+	chain := tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+	for _, cert := range certs {
+		chain.Certificate = append(chain.Certificate, cert.Raw)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{chain}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestDataSourceEndpointCertificate(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}}, intermediate, intermediateKey)
+	other, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, intermediate, intermediateKey)
+	address := testTLSServer(t, leafKey, leaf, intermediate)
+
+	d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":                  address,
+		"expected_certificate_pem": certificateToPEM(leaf),
+	})
+	if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if got := d.Get("certificate_pem").(string); got != certificateToPEM(leaf) {
+		t.Errorf("expected the served leaf, got %q", got)
+	}
+	if got := d.Get("chain_pem").(string); got != certificateToPEM(intermediate) {
+		t.Errorf("expected the served intermediate as chain, got %q", got)
+	}
+	if got := d.Get("sha256_fingerprint").(string); got != sha256Fingerprint(leaf) || got != d.Get("expected_sha256_fingerprint").(string) {
+		t.Errorf("expected the fingerprint of the leaf %s, got %s", sha256Fingerprint(leaf), got)
+	}
+	if d.Get("drifted").(bool) {
+		t.Errorf("expected no drift when the expected certificate is served")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":                  address,
+		"expected_certificate_pem": certificateToPEM(other),
+	})
+	diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{})
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "Served certificate has drifted" {
+		t.Errorf("expected a drift warning, got %v", diags)
+	}
+	if !d.Get("drifted").(bool) || d.Get("expected_sha256_fingerprint").(string) != sha256Fingerprint(other) {
+		t.Errorf("expected drifted with the fingerprint of the expected certificate")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{"address": "localhost"})
+	if diags = dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); !diags.HasError() {
+		t.Errorf("expected an error for an address without port")
+	}
+}
+
+func TestDataSourceEndpointCertificateVerifyHostname(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	other, _ := testCertificateAuthority(t, "Other CA", nil, nil)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}}, root, rootKey)
+
+	serverNames := make(chan string, 1)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		serverNames <- hello.ServerName
+		return &tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey}, nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	for name, test := range map[string]struct {
+		attributes map[string]interface{}
+		serverName string
+		verified   bool
+		err        string
+	}{
+		"IP address without SNI": {},
+		"SNI override": {
+			attributes: map[string]interface{}{"server_name": "www.example.com", "verify_hostname": "www.example.com", "ca_cert_pem": certificateToPEM(root)},
+			serverName: "www.example.com",
+			verified:   true,
+		},
+		"other hostname": {
+			attributes: map[string]interface{}{"verify_hostname": "api.example.com", "ca_cert_pem": certificateToPEM(root)},
+			err:        "certificate is valid for www.example.com, not api.example.com",
+		},
+		"other CA": {
+			attributes: map[string]interface{}{"verify_hostname": "www.example.com", "ca_cert_pem": certificateToPEM(other)},
+			err:        "certificate signed by unknown authority",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			raw := map[string]interface{}{"address": listener.Addr().String()}
+			for key, value := range test.attributes {
+				raw[key] = value
+			}
+			d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, raw)
+			if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			select {
+			case serverName := <-serverNames:
+				if serverName != test.serverName {
+					t.Errorf("expected SNI %q, got %q", test.serverName, serverName)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected a handshake")
+			}
+			if got := d.Get("verified").(bool); got != test.verified {
+				t.Errorf("expected verified %t, got %t", test.verified, got)
+			}
+			if got := d.Get("verification_error").(string); !strings.Contains(got, test.err) || (test.err == "") != (got == "") {
+				t.Errorf("expected verification error %q, got %q", test.err, got)
+			}
+		})
+	}
+
+	// the chain is verified at the provider evaluation time
+	d := schema.TestResourceDataRaw(t, dataSourceEndpointCertificate().Schema, map[string]interface{}{
+		"address":         listener.Addr().String(),
+		"verify_hostname": "www.example.com",
+		"ca_cert_pem":     certificateToPEM(root),
+	})
+	if diags := dataSourceEndpointCertificateRead(context.Background(), d, &providerMeta{evaluationTime: time.Now().Add(48 * time.Hour)}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	<-serverNames
+	if d.Get("verified").(bool) || !strings.Contains(d.Get("verification_error").(string), "expired") {
+		t.Errorf("expected the served certificate to be expired at the evaluation time, got %q", d.Get("verification_error"))
+	}
+}
@@ -0,0 +1,135 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"slices"
+	"strings"
+)
+
+func dataSourceEndpointPinVerify() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"address": {
+			Description: "endpoint to connect to, as `host:port`.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"server_name": {
+			Description: "server name sent as SNI. Defaults to the host of `address`, unless it is an IP address.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"starttls": {
+			Description:      "protocol used to switch a plaintext connection to TLS before the handshake: `smtp`, `imap`, `pop3`, `ldap` or `postgres`. By default the handshake starts right away.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedSTARTTLSProtocolsStr(), false)),
+		},
+		"pins": {
+			Description: "base64 encoded SHA-256 of the SubjectPublicKeyInfos pinned by the clients, e.g. the `pins` of a `tlsutils_android_pin_set`.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateSPKIPin,
+			},
+		},
+		"backup_pins": {
+			Description: "backup pins of the clients, not expected to be in use. A warning is raised when the endpoint only matches one of them.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateSPKIPin,
+			},
+		},
+		"served_pins": {
+			Description: "pins of the certificates served by the endpoint, leaf first.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"matched_pin": {
+			Description: "first of `served_pins` found in `pins`, or else in `backup_pins`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"backup_pin_matched": {
+			Description: "true when the endpoint matches `backup_pins` but none of `pins`.",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Verify the certificates served by a TLS endpoint match the pins of its clients, failing when none does",
+		ReadContext: dataSourceEndpointPinVerifyRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceEndpointPinVerifyRead(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	pins := listOfStrings(d.Get("pins").([]interface{}))
+	backupPins := listOfStrings(d.Get("backup_pins").([]interface{}))
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	address := d.Get("address").(string)
+	certs, err := fetchPeerCertificates(ctx, options, address, d.Get("server_name").(string), d.Get("starttls").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// like HPKP, a pin matching any certificate of the served chain validates the connection
+	servedPins := make([]string, 0, len(certs))
+	matchedPin, backupPinMatched := "", false
+	for _, cert := range certs {
+		pin := spkiPin(cert.RawSubjectPublicKeyInfo)
+		servedPins = append(servedPins, pin)
+		if slices.Contains(pins, pin) && (matchedPin == "" || backupPinMatched) {
+			matchedPin, backupPinMatched = pin, false
+		} else if slices.Contains(backupPins, pin) && matchedPin == "" {
+			matchedPin, backupPinMatched = pin, true
+		}
+	}
+	if matchedPin == "" {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Served certificates do not match the pins",
+			Detail:   fmt.Sprintf("None of the certificates served by %s matches pins or backup_pins, clients pinning them would reject the connection. Served pins, leaf first: %s.", address, strings.Join(servedPins, ", ")),
+		}}
+	}
+
+	d.SetId(hashForState(address, strings.Join(servedPins, ",")))
+
+	values := map[string]interface{}{
+		"served_pins":        servedPins,
+		"matched_pin":        matchedPin,
+		"backup_pin_matched": backupPinMatched,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	if backupPinMatched {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Endpoint serves a backup pin",
+			Detail:   fmt.Sprintf("%s only matches the backup pin %s. Clients accept it, but it should move to pins and a new backup pin should be shipped before the next rotation.", address, matchedPin),
+		}}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceEndpointPinVerify(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}}, intermediate, intermediateKey)
+	other, _ := testCertificateAuthority(t, "Other CA", nil, nil)
+	address := testTLSServer(t, leafKey, leaf, intermediate)
+	leafPin, intermediatePin, otherPin := spkiPin(leaf.RawSubjectPublicKeyInfo), spkiPin(intermediate.RawSubjectPublicKeyInfo), spkiPin(other.RawSubjectPublicKeyInfo)
+
+	for name, test := range map[string]struct {
+		pins       []interface{}
+		backupPins []interface{}
+		matched    string
+		backup     bool
+		diag       string
+	}{
+		"leaf pinned": {
+			pins:    []interface{}{leafPin},
+			matched: leafPin,
+		},
+		"intermediate pinned": {
+			pins:    []interface{}{otherPin, intermediatePin},
+			matched: intermediatePin,
+		},
+		"pin preferred over backup pin": {
+			pins:       []interface{}{intermediatePin},
+			backupPins: []interface{}{leafPin},
+			matched:    intermediatePin,
+		},
+		"backup pin only": {
+			pins:       []interface{}{otherPin},
+			backupPins: []interface{}{leafPin},
+			matched:    leafPin,
+			backup:     true,
+			diag:       "Endpoint serves a backup pin",
+		},
+		"mismatch": {
+			pins:       []interface{}{otherPin},
+			backupPins: []interface{}{otherPin},
+			diag:       "Served certificates do not match the pins",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourceEndpointPinVerify().Schema, map[string]interface{}{
+				"address":     address,
+				"pins":        test.pins,
+				"backup_pins": test.backupPins,
+			})
+			diags := dataSourceEndpointPinVerifyRead(context.Background(), d, &providerMeta{})
+			if test.diag != "" {
+				if len(diags) != 1 || diags[0].Summary != test.diag {
+					t.Fatalf("expected %q, got %v", test.diag, diags)
+				}
+				if test.matched == "" {
+					if diags[0].Severity != diag.Error || !strings.Contains(diags[0].Detail, leafPin+", "+intermediatePin) {
+						t.Errorf("expected an error listing the served pins, got %v", diags[0])
+					}
+					return
+				}
+			} else if len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			if got := d.Get("matched_pin").(string); got != test.matched {
+				t.Errorf("expected matched_pin %s, got %s", test.matched, got)
+			}
+			if got := d.Get("backup_pin_matched").(bool); got != test.backup {
+				t.Errorf("expected backup_pin_matched %t, got %t", test.backup, got)
+			}
+			if got := listOfStrings(d.Get("served_pins").([]interface{})); len(got) != 2 || got[0] != leafPin || got[1] != intermediatePin {
+				t.Errorf("expected the pins of the leaf and the intermediate, got %v", got)
+			}
+		})
+	}
+}
+
+func TestValidateSPKIPin(t *testing.T) {
+	for name, test := range map[string]struct {
+		value interface{}
+		valid bool
+	}{
+		"pin":            {value: spkiPin([]byte("spki")), valid: true},
+		"not base64":     {value: "not a pin!"},
+		"SHA-1 length":   {value: "2jmj7l5rSw0yVb/vlWAYkK/YBwk="},
+		"not a string":   {value: 42},
+		"hex not base64": {value: strings.Repeat("ab", 32)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, errs := validateSPKIPin(test.value, "pins.0")
+			if test.valid != (len(errs) == 0) {
+				t.Errorf("expected valid %t, got %v", test.valid, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net"
+	"time"
+)
+
+// ephemeralCertificateClockSkew backdates the ephemeral certificates, so hosts with a clock slightly behind accept them.
+const ephemeralCertificateClockSkew = 5 * time.Minute
+
+func dataSourceEphemeralCertificate() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"common_name": {
+			Description: "common name of the leaf certificate.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "localhost",
+		},
+		"dns_names": {
+			Description: "DNS names the leaf certificate is valid for. Unicode names are converted to A-labels (punycode). Defaults to `localhost` when `ip_addresses` is not set either.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateDNSName,
+			},
+		},
+		"ip_addresses": {
+			Description: "IP addresses the leaf certificate is valid for.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.IsIPAddress,
+			},
+		},
+		"ttl_minutes": {
+			Description:      "validity of the CA and leaf certificates in minutes, at most a day.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          60,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 1440)),
+		},
+		"ca_cert_pem": {
+			Description: "ephemeral CA certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ca_private_key_pem": {
+			Description: "private key of the ephemeral CA in PEM format, to sign other fixtures.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"cert_pem": {
+			Description: "leaf certificate in PEM format, for server and client authentication.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`cert_pem` rendered like `openssl x509 -text`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`cert_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"private_key_pem": {
+			Description: "private key of the leaf certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"fullchain_pem": {
+			Description: "leaf certificate followed by the CA certificate, in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"validity_end_time": {
+			Description: "time the certificates expire at, in RFC3339.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the certificates") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Generate a short-lived CA and leaf certificate on every read, as test fixtures",
+		ReadContext: dataSourceEphemeralCertificateRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceEphemeralCertificateRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	notBefore := now(d, m).UTC().Truncate(time.Second)
+	notAfter := notBefore.Add(time.Duration(d.Get("ttl_minutes").(int)) * time.Minute)
+	notBefore = notBefore.Add(-ephemeralCertificateClockSkew)
+
+	dnsNames := make([]string, 0)
+	for _, name := range d.Get("dns_names").([]interface{}) {
+		ascii, err := dnsNameToASCII(name.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		dnsNames = append(dnsNames, ascii)
+	}
+	ipAddresses := make([]net.IP, 0)
+	for _, address := range d.Get("ip_addresses").([]interface{}) {
+		ipAddresses = append(ipAddresses, net.ParseIP(address.(string)))
+	}
+	if len(dnsNames) == 0 && len(ipAddresses) == 0 {
+		dnsNames = append(dnsNames, "localhost")
+	}
+
+	caKey, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	caSerialNumber, err := randomSerialNumber()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          caSerialNumber,
+		Subject:               pkix.Name{CommonName: "tlsutils ephemeral CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	caCertPem, err := signCertificate(caTemplate, caKey.(crypto.Signer).Public(), caTemplate, caKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign ephemeral CA certificate: %w", err))
+	}
+	caCert, err := parsePEMCertificate([]byte(caCertPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse ephemeral CA certificate: %w", err))
+	}
+
+	leafKey, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	serialNumber, err := randomSerialNumber()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	leafCertPem, err := signCertificate(&x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: d.Get("common_name").(string)},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+	}, leafKey.(crypto.Signer).Public(), caCert, caKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign ephemeral leaf certificate: %w", err))
+	}
+
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	leafKeyPem, err := privateKeyToPEM(leafKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	leafCertText, err := certificateTextPEM(leafCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	leafCertJSON, err := certificateJSONPEM(leafCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(hashForState(leafCertPem))
+
+	values := map[string]interface{}{
+		"ca_cert_pem":        caCertPem,
+		"ca_private_key_pem": caKeyPem,
+		"cert_pem":           leafCertPem,
+		"cert_text":          leafCertText,
+		"cert_json":          leafCertJSON,
+		"private_key_pem":    leafKeyPem,
+		"fullchain_pem":      leafCertPem + caCertPem,
+		"validity_end_time":  notAfter.Format(time.RFC3339),
+	}
+	for key, value := range certificateValidity("", caCert, now(d, m)) {
+		values[key] = value
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
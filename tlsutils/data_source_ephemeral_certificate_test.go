@@ -0,0 +1,65 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+	"time"
+)
+
+func TestDataSourceEphemeralCertificate(t *testing.T) {
+	evaluationTime := time.Now().UTC().Truncate(time.Second)
+	raw := map[string]interface{}{
+		"dns_names":    []interface{}{"bücher.example"},
+		"ip_addresses": []interface{}{"127.0.0.1"},
+		"ttl_minutes":  5,
+	}
+	d := schema.TestResourceDataRaw(t, dataSourceEphemeralCertificate().Schema, raw)
+	if diags := dataSourceEphemeralCertificateRead(context.Background(), d, &providerMeta{evaluationTime: evaluationTime}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	ca, err := parsePEMCertificate([]byte(d.Get("ca_cert_pem").(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := tls.X509KeyPair([]byte(d.Get("fullchain_pem").(string)), []byte(d.Get("private_key_pem").(string)))
+	if err != nil {
+		t.Fatalf("expected the leaf to match its key: %s", err)
+	}
+	cert, err := x509.ParseCertificate(leaf.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	for _, name := range []string{"xn--bcher-kva.example", "127.0.0.1"} {
+		if _, err = cert.Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: evaluationTime}); err != nil {
+			t.Errorf("expected the leaf to be valid for %s: %s", name, err)
+		}
+	}
+	if want := evaluationTime.Add(5 * time.Minute); !cert.NotAfter.Equal(want) || d.Get("validity_end_time").(string) != want.Format(time.RFC3339) {
+		t.Errorf("expected the leaf to expire at %s, got %s", want, cert.NotAfter)
+	}
+	if !cert.NotBefore.Before(evaluationTime) {
+		t.Errorf("expected the leaf to be backdated, got not before %s", cert.NotBefore)
+	}
+
+	again := schema.TestResourceDataRaw(t, dataSourceEphemeralCertificate().Schema, raw)
+	if diags := dataSourceEphemeralCertificateRead(context.Background(), again, &providerMeta{evaluationTime: evaluationTime}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if again.Get("ca_private_key_pem") == d.Get("ca_private_key_pem") || again.Id() == d.Id() {
+		t.Errorf("expected a new CA on every read")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceEphemeralCertificate().Schema, map[string]interface{}{})
+	if diags := dataSourceEphemeralCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if cert, err = parsePEMCertificate([]byte(d.Get("cert_pem").(string))); err != nil || len(cert.DNSNames) != 1 || cert.DNSNames[0] != "localhost" {
+		t.Errorf("expected a leaf for localhost by default, got %v", cert.DNSNames)
+	}
+}
@@ -0,0 +1,289 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// instanceIdentity is the identity of a cloud instance, read from its identity document.
+type instanceIdentity struct {
+	instanceID  string
+	dnsNames    []string
+	ipAddresses []net.IP
+	uris        []*url.URL
+	// attestation is the value of the attestation extension
+	attestation instanceAttestation
+}
+
+// instanceAttestation is the ASN.1 value of the attestation extension:
+//
+//	InstanceAttestation ::= SEQUENCE {
+//	  type      UTF8String,  -- "aws-iid" or "gcp-identity-token"
+//	  document  OCTET STRING,
+//	  signature OCTET STRING OPTIONAL }
+type instanceAttestation struct {
+	Type      string `asn1:"utf8"`
+	Document  []byte
+	Signature []byte `asn1:"optional"`
+}
+
+func dataSourceInstanceIdentityCSR() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"private_key_pem": {
+			Description: "private key in PEM format signing the request.",
+			Type:        schema.TypeString,
+			Required:    true,
+			Sensitive:   true,
+		},
+		"aws_instance_identity": {
+			Description:  "AWS EC2 instance identity document, from `http://169.254.169.254/latest/dynamic/instance-identity/`.",
+			Type:         schema.TypeList,
+			Optional:     true,
+			MaxItems:     1,
+			ExactlyOneOf: []string{"aws_instance_identity", "gcp_identity_token"},
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"document": {
+						Description: "JSON identity document, from `document`.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"signature": {
+						Description: "signature of the document, from `pkcs7` or `rsa2048`, base64 encoded as served.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+				},
+			},
+		},
+		"gcp_identity_token": {
+			Description:  "GCP instance identity token with the `full` format, from `http://metadata/computeMetadata/v1/instance/service-accounts/default/identity`.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			ExactlyOneOf: []string{"aws_instance_identity", "gcp_identity_token"},
+		},
+		"attestation_oid": {
+			Description:  "OID of the non-critical extension carrying the identity document, chosen by the CA verifying it. Its value is `SEQUENCE { type UTF8String, document OCTET STRING, signature OCTET STRING OPTIONAL }`, with type `aws-iid` or `gcp-identity-token`.",
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validateOID,
+		},
+		"subject": certificateSubjectSchema("subject of the request. The common name defaults to the instance ID."),
+		"dns_names": {
+			Description: "DNS names added to the ones derived from the identity.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateDNSName,
+			},
+		},
+		"instance_id": {
+			Description: "ID of the instance.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"uris": {
+			Description: "URIs of the request: the instance ARN on AWS, its self link on GCP.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"ip_addresses": {
+			Description: "IP addresses of the request: the private IP of an AWS instance.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"cert_request_pem": {
+			Description: "certificate request in PEM format. A new request is signed on every read.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateRequestAttributesSchema() {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Build a certificate request for a cloud instance, with names derived from its identity document and the document attached for the CA to verify",
+		ReadContext: dataSourceInstanceIdentityCSRRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceInstanceIdentityCSRRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	prvKey, _, err := parsePrivateKeyPEM([]byte(d.Get("private_key_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to parse private key PEM: %w", err))
+	}
+	oid, err := parseOID(d.Get("attestation_oid").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var identity *instanceIdentity
+	if documents := d.Get("aws_instance_identity").([]interface{}); len(documents) > 0 && documents[0] != nil {
+		document := documents[0].(map[string]interface{})
+		identity, err = awsInstanceIdentity(document["document"].(string), document["signature"].(string))
+	} else {
+		identity, err = gcpInstanceIdentity(d.Get("gcp_identity_token").(string))
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	attestation, err := asn1.Marshal(identity.attestation)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode attestation: %w", err))
+	}
+	template := &x509.CertificateRequest{
+		Subject:         pkix.Name{CommonName: identity.instanceID},
+		DNSNames:        identity.dnsNames,
+		IPAddresses:     identity.ipAddresses,
+		URIs:            identity.uris,
+		ExtraExtensions: []pkix.Extension{{Id: oid, Value: attestation}},
+	}
+	if subjects := d.Get("subject").([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+		template.Subject = certificateSubject(subjects[0].(map[string]interface{}))
+		if template.Subject.CommonName == "" {
+			template.Subject.CommonName = identity.instanceID
+		}
+	}
+	for _, name := range d.Get("dns_names").([]interface{}) {
+		ascii, err := dnsNameToASCII(name.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		template.DNSNames = append(template.DNSNames, ascii)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, prvKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to create certificate request: %w", err))
+	}
+	if attributes := certificateRequestAttributes(d); len(attributes) > 0 {
+		if der, err = certificateRequestWithAttributes(der, attributes, prvKey); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to add attributes to certificate request: %w", err))
+		}
+	}
+	csrPem := string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificateRequest.String(), Bytes: der}))
+
+	uris := make([]string, 0, len(identity.uris))
+	for _, uri := range identity.uris {
+		uris = append(uris, uri.String())
+	}
+	ipAddresses := make([]string, 0, len(identity.ipAddresses))
+	for _, ip := range identity.ipAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+
+	d.SetId(hashForState(string(identity.attestation.Document)))
+
+	values := map[string]interface{}{
+		"instance_id":      identity.instanceID,
+		"uris":             uris,
+		"ip_addresses":     ipAddresses,
+		"cert_request_pem": csrPem,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// awsInstanceIdentity reads the identity of an EC2 instance from its identity document and signature.
+// The signature is attached without being verified, the CA has the AWS certificates to do so.
+func awsInstanceIdentity(document, signature string) (*instanceIdentity, error) {
+	var iid struct {
+		InstanceID string `json:"instanceId"`
+		AccountID  string `json:"accountId"`
+		Region     string `json:"region"`
+		PrivateIP  string `json:"privateIp"`
+	}
+	if err := json.Unmarshal([]byte(document), &iid); err != nil {
+		return nil, fmt.Errorf("unable to parse AWS instance identity document: %w", err)
+	}
+	if iid.InstanceID == "" || iid.AccountID == "" || iid.Region == "" {
+		return nil, fmt.Errorf("AWS instance identity document must have instanceId, accountId and region")
+	}
+	signatureDER, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signature), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode AWS instance identity signature: %w", err)
+	}
+
+	partition := "aws"
+	if strings.HasPrefix(iid.Region, "cn-") {
+		partition = "aws-cn"
+	} else if strings.HasPrefix(iid.Region, "us-gov-") {
+		partition = "aws-us-gov"
+	}
+	identity := &instanceIdentity{
+		instanceID:  iid.InstanceID,
+		uris:        []*url.URL{{Scheme: "arn", Opaque: fmt.Sprintf("%s:ec2:%s:%s:instance/%s", partition, iid.Region, iid.AccountID, iid.InstanceID)}},
+		attestation: instanceAttestation{Type: "aws-iid", Document: []byte(document), Signature: signatureDER},
+	}
+	if ip := net.ParseIP(iid.PrivateIP); ip != nil {
+		identity.ipAddresses = append(identity.ipAddresses, ip)
+	}
+
+	return identity, nil
+}
+
+// gcpInstanceIdentity reads the identity of a Compute Engine instance from the claims of its identity token.
+// The token is attached without being verified, the CA has the Google keys to do so.
+func gcpInstanceIdentity(token string) (*instanceIdentity, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("GCP identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode GCP identity token: %w", err)
+	}
+	var claims struct {
+		Google struct {
+			ComputeEngine struct {
+				InstanceID   string `json:"instance_id"`
+				InstanceName string `json:"instance_name"`
+				ProjectID    string `json:"project_id"`
+				Zone         string `json:"zone"`
+			} `json:"compute_engine"`
+		} `json:"google"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("unable to parse GCP identity token claims: %w", err)
+	}
+	instance := claims.Google.ComputeEngine
+	if instance.InstanceID == "" || instance.InstanceName == "" || instance.ProjectID == "" || instance.Zone == "" {
+		return nil, fmt.Errorf("GCP identity token has no compute_engine claims, request it with format=full")
+	}
+
+	return &instanceIdentity{
+		instanceID: instance.InstanceID,
+		// zonal internal DNS name of the instance
+		dnsNames: []string{fmt.Sprintf("%s.%s.c.%s.internal", instance.InstanceName, instance.Zone, instance.ProjectID)},
+		uris: []*url.URL{{
+			Scheme: "https",
+			Host:   "www.googleapis.com",
+			Path:   fmt.Sprintf("/compute/v1/projects/%s/zones/%s/instances/%s", instance.ProjectID, instance.Zone, instance.InstanceName),
+		}},
+		attestation: instanceAttestation{Type: "gcp-identity-token", Document: []byte(strings.TrimSpace(token))},
+	}, nil
+}
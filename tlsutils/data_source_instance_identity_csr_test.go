@@ -0,0 +1,160 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+)
+
+func TestDataSourceInstanceIdentityCSR(t *testing.T) {
+	prvKey, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := `{"instanceId": "i-0123456789abcdef0", "accountId": "123456789012", "region": "us-gov-west-1", "privateIp": "10.0.0.12"}`
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"google": {"compute_engine": {"instance_id": "4242", "instance_name": "web-1", "project_id": "example", "zone": "europe-west1-b"}}}`))
+
+	for name, test := range map[string]struct {
+		raw         map[string]interface{}
+		commonName  string
+		uri         string
+		dnsNames    []string
+		ipAddresses []string
+		attestation instanceAttestation
+	}{
+		"AWS": {
+			raw: map[string]interface{}{
+				"aws_instance_identity": []interface{}{map[string]interface{}{"document": document, "signature": "c2ln\nbmF0dXJl"}},
+				"dns_names":             []interface{}{"web.example.com"},
+			},
+			commonName:  "i-0123456789abcdef0",
+			uri:         "arn:aws-us-gov:ec2:us-gov-west-1:123456789012:instance/i-0123456789abcdef0",
+			dnsNames:    []string{"web.example.com"},
+			ipAddresses: []string{"10.0.0.12"},
+			attestation: instanceAttestation{Type: "aws-iid", Document: []byte(document), Signature: []byte("signature")},
+		},
+		"GCP with subject": {
+			raw: map[string]interface{}{
+				"gcp_identity_token": "header." + claims + ".signature",
+				"subject":            []interface{}{map[string]interface{}{"organization": "Example"}},
+			},
+			commonName:  "4242",
+			uri:         "https://www.googleapis.com/compute/v1/projects/example/zones/europe-west1-b/instances/web-1",
+			dnsNames:    []string{"web-1.europe-west1-b.c.example.internal"},
+			attestation: instanceAttestation{Type: "gcp-identity-token", Document: []byte("header." + claims + ".signature")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			test.raw["private_key_pem"] = privateKeyPem
+			test.raw["attestation_oid"] = "1.3.6.1.4.1.99999.7"
+			d := schema.TestResourceDataRaw(t, dataSourceInstanceIdentityCSR().Schema, test.raw)
+			if diags := dataSourceInstanceIdentityCSRRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			block, _ := pem.Decode([]byte(d.Get("cert_request_pem").(string)))
+			if block == nil {
+				t.Fatal("expected cert_request_pem in PEM format")
+			}
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = csr.CheckSignature(); err != nil {
+				t.Errorf("invalid signature: %s", err)
+			}
+			if csr.Subject.CommonName != test.commonName {
+				t.Errorf("expected common name %s, got %s", test.commonName, csr.Subject.CommonName)
+			}
+			if len(csr.URIs) != 1 || csr.URIs[0].String() != test.uri || d.Get("uris.0").(string) != test.uri {
+				t.Errorf("expected URI %s, got %v", test.uri, csr.URIs)
+			}
+			if len(csr.DNSNames) != len(test.dnsNames) || len(csr.DNSNames) > 0 && csr.DNSNames[0] != test.dnsNames[0] {
+				t.Errorf("expected DNS names %v, got %v", test.dnsNames, csr.DNSNames)
+			}
+			if len(csr.IPAddresses) != len(test.ipAddresses) || len(csr.IPAddresses) > 0 && csr.IPAddresses[0].String() != test.ipAddresses[0] {
+				t.Errorf("expected IP addresses %v, got %v", test.ipAddresses, csr.IPAddresses)
+			}
+
+			var attestation instanceAttestation
+			for _, extension := range csr.Extensions {
+				if extension.Id.String() == "1.3.6.1.4.1.99999.7" {
+					if extension.Critical {
+						t.Errorf("expected a non-critical attestation extension")
+					}
+					if _, err = asn1.Unmarshal(extension.Value, &attestation); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if attestation.Type != test.attestation.Type || string(attestation.Document) != string(test.attestation.Document) || string(attestation.Signature) != string(test.attestation.Signature) {
+				t.Errorf("expected attestation %+v, got %+v", test.attestation, attestation)
+			}
+		})
+	}
+}
+
+func TestDataSourceInstanceIdentityCSRAttributes(t *testing.T) {
+	prvKey, err := generatePrivateKey(RSA, 2048, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceInstanceIdentityCSR().Schema, map[string]interface{}{
+		"private_key_pem":       privateKeyPem,
+		"attestation_oid":       "1.3.6.1.4.1.99999.7",
+		"aws_instance_identity": []interface{}{map[string]interface{}{"document": `{"instanceId": "i-0123456789abcdef0", "accountId": "123456789012", "region": "eu-west-1"}`, "signature": "c2lnbmF0dXJl"}},
+		"challenge_password":    "one-time secret",
+		"unstructured_name":     "router.example.com",
+	})
+	if diags := dataSourceInstanceIdentityCSRRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	block, _ := pem.Decode([]byte(d.Get("cert_request_pem").(string)))
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		t.Errorf("invalid signature: %s", err)
+	}
+	if len(csr.URIs) != 1 {
+		t.Errorf("expected the extensions to be kept, got URIs %v", csr.URIs)
+	}
+	var tbs struct {
+		Version       int
+		Subject       asn1.RawValue
+		PublicKey     asn1.RawValue
+		RawAttributes []asn1.RawValue `asn1:"tag:0"`
+	}
+	if _, err = asn1.Unmarshal(csr.RawTBSCertificateRequest, &tbs); err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, raw := range tbs.RawAttributes[1:] {
+		var attribute struct {
+			Type   asn1.ObjectIdentifier
+			Values []string `asn1:"set"`
+		}
+		if _, err = asn1.Unmarshal(raw.FullBytes, &attribute); err != nil {
+			t.Fatal(err)
+		}
+		values[attribute.Type.String()] = attribute.Values[0]
+	}
+	if values[oidChallengePassword.String()] != "one-time secret" || values[oidUnstructuredName.String()] != "router.example.com" {
+		t.Errorf("unexpected attributes %v", values)
+	}
+}
@@ -0,0 +1,156 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func dataSourceOSTrustStore() *schema.Resource {
+	return &schema.Resource{
+		Description: "Read the certificates trusted by the operating system running terraform",
+		ReadContext: dataSourceOSTrustStoreRead,
+		Schema: map[string]*schema.Schema{
+			"subject_regex": {
+				Description:      "only return the certificates whose subject, in RFC 2253 format, matches this regular expression.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+			},
+			"expires_before": {
+				Description:      "only return the certificates expiring before this time, in RFC3339 format.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+			},
+			"include_expired": {
+				Description: "return the certificates already expired at the evaluation time of the provider.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			"source": {
+				Description: "where the certificates were read from: the CA bundle or certificate directories on Linux and BSD, the system keychains on macOS, `ROOT` on Windows. Certificates that cannot be parsed are skipped with a warning.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"certificates_pem": {
+				Description: "matching certificates in PEM format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"certificates": {
+				Description: "matching certificates.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        certificateSummarySchema(),
+			},
+		},
+	}
+}
+
+func dataSourceOSTrustStoreRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var subjectRegex *regexp.Regexp
+	if expression := d.Get("subject_regex").(string); expression != "" {
+		subjectRegex = regexp.MustCompile(expression)
+	}
+	var expiresBefore time.Time
+	if value := d.Get("expires_before").(string); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid expires_before: %w", err))
+		}
+		expiresBefore = t
+	}
+	includeExpired := d.Get("include_expired").(bool)
+	at := now(d, m)
+
+	certs, source, skipped, err := readOSTrustStore()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to read the operating system trust store: %w", err))
+	}
+
+	certsPem := &strings.Builder{}
+	parsed := make([]interface{}, 0, len(certs))
+	seen := make(map[string]bool, len(certs))
+	fingerprints := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		fingerprint := sha256Fingerprint(cert)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+
+		if subjectRegex != nil && !subjectRegex.MatchString(cert.Subject.String()) {
+			continue
+		}
+		if !expiresBefore.IsZero() && !cert.NotAfter.Before(expiresBefore) {
+			continue
+		}
+		if !includeExpired && cert.NotAfter.Before(at) {
+			continue
+		}
+
+		certsPem.WriteString(certificateToPEM(cert))
+		parsed = append(parsed, certificateSummary(cert, at))
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	d.SetId(hashForState(append([]string{source}, fingerprints...)...))
+
+	values := map[string]interface{}{
+		"source":           source,
+		"certificates_pem": certsPem.String(),
+		"certificates":     parsed,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	if len(skipped) > 0 {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Skipped unparsable trust store certificates",
+			Detail:   fmt.Sprintf("%d certificates of the operating system trust store cannot be parsed and are left out:\n%s", len(skipped), strings.Join(skipped, "\n")),
+		}}
+	}
+
+	return nil
+}
+
+// parseTrustStorePEM parses the certificates of the PEM trust store source, skipping the ones crypto/x509 cannot
+// parse instead of failing, like readOSTrustStore on every operating system. It also returns why each one was skipped.
+func parseTrustStorePEM(data []byte, source string) ([]*x509.Certificate, []string) {
+	certs := make([]*x509.Certificate, 0)
+	skipped := make([]string, 0)
+	index := 0
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != PreambleCertificate.String() {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("certificate #%d of %s: %s", index, source, err))
+		} else {
+			certs = append(certs, cert)
+		}
+		index++
+	}
+
+	return certs, skipped
+}
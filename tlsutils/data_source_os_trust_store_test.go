@@ -0,0 +1,41 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceOSTrustStoreSkipsUnparsable(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("SSL_CERT_FILE is only honored on Linux and BSD")
+	}
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), &providerMeta{})
+	unparsable := string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: []byte{0x30, 0x03, 0x02, 0x01, 0x01}}))
+	bundle := filepath.Join(t.TempDir(), "ca-bundle.crt")
+	if err := os.WriteFile(bundle, []byte(ca.Attributes["root_cert_pem"]+unparsable+ca.Attributes["intermediate_cert_pem"]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSL_CERT_FILE", bundle)
+
+	d := schema.TestResourceDataRaw(t, dataSourceOSTrustStore().Schema, map[string]interface{}{})
+	diags := dataSourceOSTrustStoreRead(context.Background(), d, &providerMeta{})
+	if diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "certificate #1 of "+bundle) {
+		t.Errorf("expected a warning for the second certificate, got %v", diags)
+	}
+	if got := len(d.Get("certificates").([]interface{})); got != 2 {
+		t.Errorf("expected the 2 parsable certificates, got %d", got)
+	}
+	if got := d.Get("source").(string); got != bundle {
+		t.Errorf("expected source %s, got %s", bundle, got)
+	}
+}
@@ -0,0 +1,252 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func dataSourcePEMBlocks() *schema.Resource {
+	return &schema.Resource{
+		Description: "List the PEM blocks contained in an input",
+		ReadContext: dataSourcePEMBlocksRead,
+		Schema: map[string]*schema.Schema{
+			"content": {
+				Description:  "content containing PEM blocks, possibly mixed with other text.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"content", "path"},
+			},
+			"path": {
+				Description:  "local file containing PEM blocks, read instead of `content`. The file is read block by block, so large bundles are never loaded whole.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"content", "path"},
+			},
+			"types": {
+				Description: "only list the blocks with one of these preambles, e.g. `CERTIFICATE`.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"offset": {
+				Description:      "number of matching blocks to skip, to page through large bundles.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"limit": {
+				Description:      "maximum number of matching blocks to list after `offset`. 0 lists them all.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"include_pem": {
+				Description: "set the `pem` of the blocks. Disable it to keep the state small when only the other attributes are needed.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			"total_count": {
+				Description: "number of matching blocks, before `offset` and `limit`.",
+				Type:        schema.TypeInt,
+				Computed:    true,
+			},
+			"lenient": {
+				Description: "inventory nonconforming certificates instead of failing: the CERTIFICATE blocks crypto/x509 rejects, like the ones with duplicate extensions or negative serial numbers, get a `parse_error` and no `certificate`. They and the certificates with unknown critical extensions are reported as warnings.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+			},
+			"blocks": {
+				Description: "matching PEM blocks, in order. Their `index` is their position among all the blocks of the input.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"preamble": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"supported": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"headers": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"pem": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"certificate": {
+							Description: "decoded certificate of the CERTIFICATE blocks, empty for the other blocks.",
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        certificateDetailsSchema(),
+						},
+						"parse_error": {
+							Description: "reason crypto/x509 rejected the certificate of the block, with `lenient` only.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+						"key_algorithm": {
+							Description: "algorithm of the key of certificates, certificate requests, public and private keys, including GOST R 34.10 ones. Empty for other blocks and encrypted PKCS#8 and SEC 1 keys.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+						"key_parameters": {
+							Description: "ECDSA curve or GOST R 34.10 parameter set of the key.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourcePEMBlocksRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	var reader io.Reader = strings.NewReader(d.Get("content").(string))
+	if path := d.Get("path").(string); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to open %s: %w", path, err))
+		}
+		defer f.Close()
+		reader = f
+	}
+	digest := sha256.New()
+	reader = io.TeeReader(reader, digest)
+
+	types := make([]string, 0)
+	for _, preamble := range d.Get("types").([]interface{}) {
+		types = append(types, preamble.(string))
+	}
+	offset := d.Get("offset").(int)
+	limit := d.Get("limit").(int)
+	includePEM := d.Get("include_pem").(bool)
+	lenient := d.Get("lenient").(bool)
+
+	var diags diag.Diagnostics
+	blocks := make([]map[string]interface{}, 0)
+	index, matching := 0, 0
+	scanner := newPEMBlockScanner(reader)
+	for ; scanner.Scan(); index++ {
+		block := scanner.Block()
+		if len(types) > 0 && !slices.Contains(types, block.Type) {
+			continue
+		}
+		matching++
+		if matching <= offset || (limit > 0 && len(blocks) >= limit) {
+			continue
+		}
+
+		preamble, err := pemBlockToPEMPreamble(block)
+		certificate := make([]interface{}, 0, 1)
+		parseError := ""
+		if err == nil && preamble == PreambleCertificate {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			switch {
+			case err != nil && !lenient:
+				return diag.FromErr(fmt.Errorf("unable to parse certificate of block #%d: %w", index, err))
+			case err != nil:
+				parseError = err.Error()
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Nonconforming certificate",
+					Detail:   fmt.Sprintf("the certificate of block #%d cannot be parsed: %s.", index, err),
+				})
+			default:
+				if lenient && len(cert.UnhandledCriticalExtensions) > 0 {
+					oids := make([]string, 0, len(cert.UnhandledCriticalExtensions))
+					for _, oid := range cert.UnhandledCriticalExtensions {
+						oids = append(oids, oid.String())
+					}
+					diags = append(diags, diag.Diagnostic{
+						Severity: diag.Warning,
+						Summary:  "Certificate with unknown critical extensions",
+						Detail:   fmt.Sprintf("the certificate of block #%d (subject %q) has the unknown critical extensions %s: verifiers reject it.", index, cert.Subject.String(), strings.Join(oids, ", ")),
+					})
+				}
+				certificate = append(certificate, certificateDetails(cert))
+			}
+		}
+		algorithm := pemBlockKeyAlgorithm(block)
+		pemBlock := ""
+		if includePEM {
+			pemBlock = string(pem.EncodeToMemory(block))
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"index":          index,
+			"preamble":       block.Type,
+			"supported":      err == nil,
+			"headers":        block.Headers,
+			"pem":            pemBlock,
+			"certificate":    certificate,
+			"parse_error":    parseError,
+			"key_algorithm":  algorithm.Name,
+			"key_parameters": algorithm.Parameters,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to read PEM blocks: %w", err))
+	}
+
+	d.SetId(hashForState(hex.EncodeToString(digest.Sum(nil)), strings.Join(types, ","), strconv.Itoa(offset), strconv.Itoa(limit)))
+
+	if err := d.Set("total_count", matching); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save total_count: %w", err))
+	}
+	if err := d.Set("blocks", blocks); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save blocks: %w", err))
+	}
+
+	return diags
+}
+
+// pemBlockKeyAlgorithm describes the key of a PEM block, the zero publicKeyAlgorithm when it has none or it cannot be parsed.
+func pemBlockKeyAlgorithm(block *pem.Block) publicKeyAlgorithm {
+	var algorithm publicKeyAlgorithm
+	switch block.Type {
+	case PreambleCertificate.String():
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			algorithm, _ = describePublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+		}
+	case PreambleCertificateRequest.String():
+		if csr, err := x509.ParseCertificateRequest(block.Bytes); err == nil {
+			algorithm, _ = describePublicKeyInfo(csr.RawSubjectPublicKeyInfo)
+		}
+	case PreamblePublicKey.String():
+		algorithm, _ = describePublicKeyInfo(block.Bytes)
+	case PreamblePrivateKeyPKCS8.String():
+		algorithm, _ = describePrivateKeyInfo(block.Bytes)
+	case PreamblePrivateKeyRSA.String():
+		algorithm.Name = RSA.String()
+	case PreamblePrivateKeyEC.String():
+		if prvKey, err := parseECPrivateKey(block.Bytes); err == nil {
+			algorithm = publicKeyAlgorithm{Name: ECDSA.String(), Parameters: strings.ReplaceAll(prvKey.Curve.Params().Name, "-", "")}
+		}
+	}
+
+	return algorithm
+}
@@ -0,0 +1,367 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataSourcePEMBlocks(t *testing.T) {
+	cert, prvKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "example.com"}}, nil, nil)
+	keyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := string(pem.EncodeToMemory(&pem.Block{Type: "CUSTOM DATA", Headers: map[string]string{"Proc-Type": "4,ENCRYPTED"}, Bytes: []byte("data")}))
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{
+		"content": "bundle:\n" + certificateToPEM(cert) + "comment\n" + custom + keyPem,
+	})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	expected := []struct {
+		preamble  string
+		supported bool
+		pem       string
+	}{
+		{preamble: PreambleCertificate.String(), supported: true, pem: certificateToPEM(cert)},
+		{preamble: "CUSTOM DATA", supported: false, pem: custom},
+		{preamble: PreamblePrivateKeyEC.String(), supported: true, pem: keyPem},
+	}
+	if got := d.Get("blocks.#").(int); got != len(expected) {
+		t.Fatalf("expected %d blocks, got %d", len(expected), got)
+	}
+	for i, block := range expected {
+		got := d.Get("blocks").([]interface{})[i].(map[string]interface{})
+		if got["index"] != i || got["preamble"] != block.preamble || got["supported"] != block.supported || got["pem"] != block.pem {
+			t.Errorf("expected block %d to be %+v, got %v", i, block, got)
+		}
+	}
+	if got := d.Get("blocks.1.headers.Proc-Type"); got != "4,ENCRYPTED" {
+		t.Errorf("expected the headers of the block, got %v", got)
+	}
+}
+
+func TestDataSourcePEMBlocksCertificate(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	leafURI, _ := url.Parse("spiffe://example.com/web")
+	leaf, _ := testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "web.example.com", Organization: []string{"Example"}},
+		DNSNames:              []string{"web.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("192.0.2.1")},
+		URIs:                  []*url.URL{leafURI},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		UnknownExtKeyUsage:    []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 99999, 1}},
+		BasicConstraintsValid: true,
+		OCSPServer:            []string{"http://ocsp.example.com"},
+		IssuingCertificateURL: []string{"http://pki.example.com/ca.crt"},
+		CRLDistributionPoints: []string{"http://pki.example.com/ca.crl"},
+		ExtraExtensions:       []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 2}, Critical: true, Value: asn1.NullBytes}},
+	}, ca, caKey)
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{
+		"content": certificateToPEM(leaf) + certificateToPEM(ca),
+	})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	for key, expected := range map[string]interface{}{
+		"blocks.0.certificate.0.subject":                    "CN=web.example.com,O=Example",
+		"blocks.0.certificate.0.issuer":                     "CN=Example CA",
+		"blocks.0.certificate.0.is_ca":                      false,
+		"blocks.0.certificate.0.max_path_len":               -1,
+		"blocks.0.certificate.0.key_usages":                 []interface{}{"digital_signature", "key_encipherment"},
+		"blocks.0.certificate.0.ext_key_usages":             []interface{}{"server_auth", "1.3.6.1.4.1.99999.1"},
+		"blocks.0.certificate.0.dns_names":                  []interface{}{"web.example.com"},
+		"blocks.0.certificate.0.ip_addresses":               []interface{}{"192.0.2.1"},
+		"blocks.0.certificate.0.uris":                       []interface{}{"spiffe://example.com/web"},
+		"blocks.0.certificate.0.ocsp_servers":               []interface{}{"http://ocsp.example.com"},
+		"blocks.0.certificate.0.issuing_certificate_urls":   []interface{}{"http://pki.example.com/ca.crt"},
+		"blocks.0.certificate.0.crl_distribution_points":    []interface{}{"http://pki.example.com/ca.crl"},
+		"blocks.1.certificate.0.is_ca":                      true,
+		"blocks.1.certificate.0.key_usages":                 []interface{}{"cert_signing", "crl_signing"},
+		"blocks.0.certificate.0.serial_number":              leaf.SerialNumber.Text(16),
+		"blocks.0.certificate.0.extensions.#":               len(leaf.Extensions),
+		"blocks.0.certificate.0.email_addresses.#":          0,
+		"blocks.1.certificate.0.ext_key_usages.#":           0,
+		"blocks.1.certificate.0.issuing_certificate_urls.#": 0,
+	} {
+		if got := d.Get(key); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %s to be %v, got %v", key, expected, got)
+		}
+	}
+
+	var custom map[string]interface{}
+	for _, extension := range d.Get("blocks.0.certificate.0.extensions").([]interface{}) {
+		if extension.(map[string]interface{})["oid"] == "1.3.6.1.4.1.99999.2" {
+			custom = extension.(map[string]interface{})
+		}
+	}
+	if custom == nil || custom["critical"] != true || custom["value_base64"] != "BQA=" {
+		t.Errorf("expected the custom extension with its value, got %v", custom)
+	}
+}
+
+func TestDataSourcePEMBlocksDistinguishedNames(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	cert, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{
+		CommonName:         "web.example.com",
+		Organization:       []string{"Example", "Example Holding"},
+		OrganizationalUnit: []string{"Web"},
+		Country:            []string{"NL"},
+		ExtraNames:         []pkix.AttributeTypeAndValue{{Type: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}, Value: "web@example.com"}},
+	}}, ca, caKey)
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{"content": certificateToPEM(cert)})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	for key, expected := range map[string]interface{}{
+		"blocks.0.certificate.0.subject_name.0.common_name":         "web.example.com",
+		"blocks.0.certificate.0.subject_name.0.organization":        []interface{}{"Example", "Example Holding"},
+		"blocks.0.certificate.0.subject_name.0.organizational_unit": []interface{}{"Web"},
+		"blocks.0.certificate.0.subject_name.0.country":             []interface{}{"NL"},
+		"blocks.0.certificate.0.subject_name.0.locality.#":          0,
+		"blocks.0.certificate.0.subject_name.0.extra_rdns":          []interface{}{map[string]interface{}{"oid": "1.2.840.113549.1.9.1", "value": "web@example.com"}},
+		"blocks.0.certificate.0.issuer_name.0.common_name":          "Example CA",
+		"blocks.0.certificate.0.issuer_name.0.extra_rdns.#":         0,
+	} {
+		if got := d.Get(key); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %s to be %v, got %v", key, expected, got)
+		}
+	}
+}
+
+func TestDataSourcePEMBlocksLenient(t *testing.T) {
+	_, key := testCertificate(t, &x509.Certificate{}, nil, nil)
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+	content := &strings.Builder{}
+	for _, extensions := range [][]pkix.Extension{
+		{{Id: oid, Critical: true, Value: asn1.NullBytes}},
+		{{Id: oid, Value: asn1.NullBytes}, {Id: oid, Value: asn1.NullBytes}},
+	} {
+		// testCertificate cannot be used, the certificate with duplicate extensions does not parse
+		template := &x509.Certificate{
+			SerialNumber:    big.NewInt(1),
+			Subject:         pkix.Name{CommonName: "legacy"},
+			NotBefore:       time.Now(),
+			NotAfter:        time.Now().Add(time.Hour),
+			ExtraExtensions: extensions,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content.Write(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: der}))
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{"content": content.String()})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); !diags.HasError() {
+		t.Fatalf("expected the duplicate extensions to fail without lenient")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{"content": content.String(), "lenient": true})
+	diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{})
+	if diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if len(diags) != 2 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, oid.String()) || !strings.Contains(diags[1].Detail, "duplicate") {
+		t.Errorf("expected warnings for the unknown critical and duplicate extensions, got %v", diags)
+	}
+	if got := d.Get("blocks.0.certificate.0.subject_name.0.common_name").(string); got != "legacy" || d.Get("blocks.0.parse_error").(string) != "" {
+		t.Errorf("expected the certificate with the unknown critical extension to be decoded, got %q", got)
+	}
+	if d.Get("blocks.1.parse_error").(string) == "" || d.Get("blocks.1.certificate.#").(int) != 0 || d.Get("blocks.1.pem").(string) == "" {
+		t.Errorf("expected the block of the certificate with duplicate extensions without certificate but with its parse error")
+	}
+}
+
+// testGOSTCertificate returns a self-issued certificate with a GOST R 34.10-2012 256 key and signature, which
+// crypto/x509 parses without being able to verify it.
+func testGOSTCertificate(t *testing.T) []byte {
+	t.Helper()
+
+	gostKey := testGOSTPublicKeyInfo(t)
+	signatureAlgorithm := pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 3, 2}}
+	name, err := asn1.Marshal(pkix.Name{CommonName: "GOST CA"}.ToRDNSequence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbs, err := asn1.Marshal(struct {
+		Version            int `asn1:"optional,explicit,default:0,tag:0"`
+		SerialNumber       *big.Int
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Issuer             asn1.RawValue
+		Validity           struct{ NotBefore, NotAfter time.Time }
+		Subject            asn1.RawValue
+		PublicKey          asn1.RawValue
+	}{
+		Version:            2,
+		SerialNumber:       big.NewInt(1),
+		SignatureAlgorithm: signatureAlgorithm,
+		Issuer:             asn1.RawValue{FullBytes: name},
+		Validity:           struct{ NotBefore, NotAfter time.Time }{time.Now().Add(-time.Hour).UTC(), time.Now().Add(time.Hour).UTC()},
+		Subject:            asn1.RawValue{FullBytes: name},
+		PublicKey:          asn1.RawValue{FullBytes: gostKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(struct {
+		TBSCertificate     asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		SignatureValue     asn1.BitString
+	}{asn1.RawValue{FullBytes: tbs}, signatureAlgorithm, asn1.BitString{Bytes: make([]byte, 64), BitLength: 512}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+// testGOSTPublicKeyInfo returns a DER SubjectPublicKeyInfo of a GOST R 34.10-2012 256 key with the tc26-256-A
+// parameter set.
+func testGOSTPublicKeyInfo(t *testing.T) []byte {
+	t.Helper()
+
+	parameters, err := asn1.Marshal(struct{ PublicKeyParamSet asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := asn1.Marshal(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 1}, Parameters: asn1.RawValue{FullBytes: parameters}},
+		PublicKey: asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func TestDataSourcePEMBlocksKeyAlgorithm(t *testing.T) {
+	cert, prvKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "example.com"}}, nil, nil)
+	pkcs8, err := encodeAnyPrivateKey(prvKey, keyFormatPKCS8, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Key, err := generatePrivateKey(ED25519, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Pem, err := privateKeyToPEM(ed25519Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []struct {
+		pem        string
+		algorithm  string
+		parameters string
+	}{
+		{pem: certificateToPEM(cert), algorithm: "ECDSA", parameters: "P256"},
+		{pem: pkcs8, algorithm: "ECDSA", parameters: "P256"},
+		{pem: ed25519Pem, algorithm: "ED25519"},
+		{pem: string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: testGOSTCertificate(t)})), algorithm: "GOST R 34.10-2012 256", parameters: "tc26-256-A"},
+		{pem: string(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: testGOSTPublicKeyInfo(t)})), algorithm: "GOST R 34.10-2012 256", parameters: "tc26-256-A"},
+		{pem: string(pem.EncodeToMemory(&pem.Block{Type: "CUSTOM DATA", Bytes: []byte("data")}))},
+	}
+	content := &strings.Builder{}
+	for _, block := range blocks {
+		content.WriteString(block.pem)
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{"content": content.String()})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+	for i, block := range blocks {
+		got := d.Get("blocks").([]interface{})[i].(map[string]interface{})
+		if got["key_algorithm"] != block.algorithm || got["key_parameters"] != block.parameters {
+			t.Errorf("expected block %d to have key %s %s, got %s %s", i, block.algorithm, block.parameters, got["key_algorithm"], got["key_parameters"])
+		}
+	}
+}
+
+func TestDataSourcePEMBlocksPaging(t *testing.T) {
+	var bundle strings.Builder
+	serialNumbers := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		cert, prvKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "example.com"}}, nil, nil)
+		keyPem, err := privateKeyToPEM(prvKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle.WriteString(certificateToPEM(cert) + keyPem)
+		serialNumbers = append(serialNumbers, cert.SerialNumber.Text(16))
+	}
+	path := filepath.Join(t.TempDir(), "bundle.pem")
+	if err := os.WriteFile(path, []byte(bundle.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{
+		"path":        path,
+		"types":       []interface{}{PreambleCertificate.String()},
+		"offset":      1,
+		"limit":       2,
+		"include_pem": false,
+	})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	if got := d.Get("total_count").(int); got != 5 {
+		t.Errorf("expected total_count 5, got %d", got)
+	}
+	blocks := d.Get("blocks").([]interface{})
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	for i, want := range []struct {
+		index        int
+		serialNumber string
+	}{
+		{2, serialNumbers[1]},
+		{4, serialNumbers[2]},
+	} {
+		block := blocks[i].(map[string]interface{})
+		if block["index"] != want.index || block["pem"] != "" {
+			t.Errorf("expected block %d at index %d without pem, got %v", i, want.index, block)
+		}
+		if got := d.Get("blocks." + strconv.Itoa(i) + ".certificate.0.serial_number"); got != want.serialNumber {
+			t.Errorf("expected block %d to be certificate %s, got %v", i, want.serialNumber, got)
+		}
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourcePEMBlocks().Schema, map[string]interface{}{"path": filepath.Join(t.TempDir(), "missing.pem")})
+	if diags := dataSourcePEMBlocksRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.HasPrefix(diags[0].Summary, "failed to open") {
+		t.Errorf("expected a missing file to fail, got %v", diags)
+	}
+}
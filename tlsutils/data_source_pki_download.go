@@ -0,0 +1,253 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"time"
+)
+
+func dataSourcePKIDownload() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"url": {
+			Description: "`http://`, `https://` or `file://` URL of a certificate, a certificate bundle or a CRL, in PEM, DER or PKCS#7 (`.p7c`) format.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"expected_sha256_fingerprint": {
+			Description: "hex encoded SHA-256 the first certificate or the CRL must have, to pin the download.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"lenient": {
+			Description: "inventory nonconforming certificates instead of failing: the certificates crypto/x509 rejects, like the ones with duplicate extensions, are listed in `certificates` with their `cert_pem`, `sha256_fingerprint` and `parse_error` only. They and the certificates with unknown critical extensions are reported as warnings.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"type": {
+			Description: "`certificates` or `crl`, depending on the downloaded content.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"sha256_fingerprint": {
+			Description: "hex encoded SHA-256 of the DER encoding of the first certificate or the CRL.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"certificates_pem": {
+			Description: "downloaded certificates in PEM format, in order.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"certificates": {
+			Description: "downloaded certificates, in order.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        certificateSummarySchema(),
+		},
+		"crl_pem": {
+			Description: "downloaded CRL in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"crl_issuer": {
+			Description: "issuer of the CRL.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"crl_number": {
+			Description: "CRL number in decimal.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"crl_this_update": {
+			Description: "issue time of the CRL in RFC3339 format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"crl_next_update": {
+			Description: "time of the next CRL in RFC3339 format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"delta_crl_base_number": {
+			Description: "CRL number in decimal of the base CRL, when the CRL is a delta CRL. It only lists the changes since its base CRL.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"revoked_serial_numbers": {
+			Description: "hex serial numbers of the certificates revoked by the CRL.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description: "Download a certificate, certificate bundle or CRL from a URL",
+		ReadContext: dataSourcePKIDownloadRead,
+		Schema:      s,
+	}
+}
+
+func dataSourcePKIDownloadRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rawURL := d.Get("url").(string)
+	content, err := options.download(ctx, rawURL)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var diags diag.Diagnostics
+	certs, crl, err := parseCertificatesOrCRL(content)
+	var lenientCerts []lenientCertificate
+	if err != nil {
+		if d.Get("lenient").(bool) {
+			lenientCerts = parseCertificatesLenient(content)
+		}
+		if len(lenientCerts) == 0 {
+			return diag.FromErr(fmt.Errorf("unable to parse content of %s: %w", rawURL, err))
+		}
+	}
+	for _, cert := range certs {
+		lenientCerts = append(lenientCerts, lenientCertificate{der: cert.Raw, cert: cert})
+	}
+
+	values := map[string]interface{}{
+		"certificates_pem":       "",
+		"certificates":           []interface{}{},
+		"crl_pem":                "",
+		"crl_issuer":             "",
+		"crl_number":             "",
+		"crl_this_update":        "",
+		"crl_next_update":        "",
+		"delta_crl_base_number":  "",
+		"revoked_serial_numbers": []interface{}{},
+	}
+
+	var der []byte
+	if crl != nil {
+		der = crl.Raw
+		revoked := make([]interface{}, 0, len(crl.RevokedCertificateEntries))
+		for _, entry := range crl.RevokedCertificateEntries {
+			revoked = append(revoked, entry.SerialNumber.Text(16))
+		}
+		values["type"] = "crl"
+		values["crl_pem"] = string(pem.EncodeToMemory(&pem.Block{Type: PreambleCRL.String(), Bytes: crl.Raw}))
+		values["crl_issuer"] = crl.Issuer.String()
+		values["crl_this_update"] = crl.ThisUpdate.Format(time.RFC3339)
+		values["revoked_serial_numbers"] = revoked
+		if crl.Number != nil {
+			values["crl_number"] = crl.Number.String()
+		}
+		if !crl.NextUpdate.IsZero() {
+			values["crl_next_update"] = crl.NextUpdate.Format(time.RFC3339)
+		}
+		baseNumber, err := crlDeltaBaseNumber(crl)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse CRL of %s: %w", rawURL, err))
+		}
+		if baseNumber != nil {
+			values["delta_crl_base_number"] = baseNumber.String()
+		}
+	} else {
+		der = lenientCerts[0].der
+		certsPem := &strings.Builder{}
+		parsed := make([]interface{}, 0, len(lenientCerts))
+		for i, cert := range lenientCerts {
+			if cert.cert == nil {
+				summary := rejectedCertificateSummary(cert.der, cert.err)
+				certsPem.WriteString(summary["cert_pem"].(string))
+				parsed = append(parsed, summary)
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Nonconforming certificate",
+					Detail:   fmt.Sprintf("certificate #%d of %s (SHA-256 %s) cannot be parsed: %s.", i, rawURL, summary["sha256_fingerprint"], cert.err),
+				})
+				continue
+			}
+
+			if d.Get("lenient").(bool) && len(cert.cert.UnhandledCriticalExtensions) > 0 {
+				oids := make([]string, 0, len(cert.cert.UnhandledCriticalExtensions))
+				for _, oid := range cert.cert.UnhandledCriticalExtensions {
+					oids = append(oids, oid.String())
+				}
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Certificate with unknown critical extensions",
+					Detail:   fmt.Sprintf("certificate #%d of %s (subject %q) has the unknown critical extensions %s: verifiers reject it.", i, rawURL, cert.cert.Subject.String(), strings.Join(oids, ", ")),
+				})
+			}
+			certsPem.WriteString(certificateToPEM(cert.cert))
+			parsed = append(parsed, certificateSummary(cert.cert, now(d, m)))
+		}
+		values["type"] = "certificates"
+		values["certificates_pem"] = certsPem.String()
+		values["certificates"] = parsed
+	}
+
+	sum := sha256.Sum256(der)
+	fingerprint := hex.EncodeToString(sum[:])
+	if expected := d.Get("expected_sha256_fingerprint").(string); expected != "" && !strings.EqualFold(expected, fingerprint) {
+		return diag.FromErr(fmt.Errorf("content of %s has SHA-256 fingerprint %s, expected %s", rawURL, fingerprint, expected))
+	}
+	values["sha256_fingerprint"] = fingerprint
+
+	d.SetId(hashForState(rawURL, fingerprint))
+
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return diags
+}
+
+// parseCertificatesOrCRL parses PEM certificates or CRL, or a DER certificate, CRL or PKCS#7 certificate bundle.
+// Exactly one of the returned certificates and CRL is set.
+func parseCertificatesOrCRL(content []byte) ([]*x509.Certificate, *x509.RevocationList, error) {
+	if block, _ := pem.Decode(content); block != nil {
+		if block.Type == PreambleCRL.String() {
+			crl, err := parsePEMRevocationList(content)
+			return nil, crl, err
+		}
+		certs, err := parsePEMCertificates(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(certs) == 0 {
+			return nil, nil, fmt.Errorf("no certificate or CRL found in PEM content")
+		}
+		return certs, nil, nil
+	}
+
+	if cert, err := x509.ParseCertificate(content); err == nil {
+		return []*x509.Certificate{cert}, nil, nil
+	}
+	if crl, err := x509.ParseRevocationList(content); err == nil {
+		return nil, crl, nil
+	}
+	certs, err := parsePKCS7Certificates(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("content is not a certificate, a CRL or a PKCS#7 bundle in PEM or DER format")
+	}
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("PKCS#7 bundle without certificate")
+	}
+
+	return certs, nil, nil
+}
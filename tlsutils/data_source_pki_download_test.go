@@ -0,0 +1,240 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPKCS7Certificates encodes certs as a DER PKCS#7 SignedData without signers, like a .p7c file.
+func testPKCS7Certificates(t *testing.T, certs ...*x509.Certificate) []byte {
+	t.Helper()
+
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      struct{ ContentType asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidPKCS7SignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+// testFileURL writes content to a temporary file and returns its file:// URL.
+func testFileURL(t *testing.T, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return "file://" + path
+}
+
+func TestDataSourcePKIDownload(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, _ := testCertificateAuthority(t, "Intermediate CA", root, rootKey)
+	bundle := certificateToPEM(intermediate) + certificateToPEM(root)
+
+	for name, content := range map[string][]byte{
+		"PEM bundle": []byte(bundle),
+		"DER":        intermediate.Raw,
+		"p7c bundle": testPKCS7Certificates(t, intermediate, root),
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{
+				"url":                         testFileURL(t, "ca", content),
+				"expected_sha256_fingerprint": strings.ToUpper(sha256Fingerprint(intermediate)),
+			})
+			if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+			if got := d.Get("type").(string); got != "certificates" {
+				t.Errorf("expected type certificates, got %q", got)
+			}
+			if got := d.Get("certificates.0.subject").(string); got != intermediate.Subject.String() {
+				t.Errorf("expected the intermediate first, got %q", got)
+			}
+			if got := d.Get("certificates.0.issuer_name.0.common_name").(string); got != "Root CA" {
+				t.Errorf("expected issuer common_name Root CA, got %q", got)
+			}
+			if got := d.Get("certificates_pem").(string); !strings.HasPrefix(bundle, got) || d.Get("crl_pem").(string) != "" {
+				t.Errorf("expected the certificates in PEM format, got %q", got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{
+		"url":                         testFileURL(t, "ca.pem", []byte(bundle)),
+		"expected_sha256_fingerprint": sha256Fingerprint(root),
+	})
+	if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "expected "+sha256Fingerprint(root)) {
+		t.Errorf("expected the pin of another certificate to be refused, got %v", diags)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{
+		"url": testFileURL(t, "garbage", []byte("no certificate here")),
+	})
+	if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); !diags.HasError() {
+		t.Errorf("expected content without certificate or CRL to be refused")
+	}
+}
+
+func TestDataSourcePKIDownloadHTTP(t *testing.T) {
+	cert, _ := testCertificateAuthority(t, "Root CA", nil, nil)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(cert.Raw)
+	}))
+	t.Cleanup(server.Close)
+
+	d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{
+		"url":           server.URL + "/root.crt",
+		"retries":       1,
+		"retry_backoff": "1ms",
+	})
+	if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if atomic.LoadInt32(&requests) != 2 || d.Get("sha256_fingerprint").(string) != sha256Fingerprint(cert) {
+		t.Errorf("expected the certificate on the second request, got %d requests", requests)
+	}
+}
+
+func TestDataSourcePKIDownloadDeltaCRL(t *testing.T) {
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), &providerMeta{})
+	crlConfig := map[string]interface{}{
+		"certificate_pem": ca.Attributes["intermediate_cert_pem"],
+		"private_key_pem": ca.Attributes["intermediate_private_key_pem"],
+	}
+	full := testResourceApply(t, resourceX509Crl(), nil, crlConfig, &providerMeta{})
+	crlConfig["delta_crl_base_number"] = 1000
+	delta := testResourceApply(t, resourceX509Crl(), nil, crlConfig, &providerMeta{})
+
+	for name, test := range map[string]struct {
+		crlPem     string
+		baseNumber string
+	}{
+		"complete CRL": {crlPem: full.Attributes["crl_pem"]},
+		"delta CRL":    {crlPem: delta.Attributes["crl_pem"], baseNumber: "1000"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{"url": testFileURL(t, "crl.pem", []byte(test.crlPem))})
+			if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+				t.Fatalf("read failed: %v", diags)
+			}
+			if got := d.Get("type").(string); got != "crl" || d.Get("crl_issuer").(string) != "CN=Test Intermediate CA" {
+				t.Errorf("expected the CRL of the intermediate, got type %q issued by %q", got, d.Get("crl_issuer"))
+			}
+			if got := d.Get("delta_crl_base_number").(string); got != test.baseNumber {
+				t.Errorf("expected delta_crl_base_number %q, got %q", test.baseNumber, got)
+			}
+		})
+	}
+}
+
+func TestDataSourcePKIDownloadLenient(t *testing.T) {
+	_, key := testCertificate(t, &x509.Certificate{}, nil, nil)
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+	bundle := &strings.Builder{}
+	for _, extensions := range [][]pkix.Extension{
+		{{Id: oid, Critical: true, Value: asn1.NullBytes}},
+		{{Id: oid, Value: asn1.NullBytes}, {Id: oid, Value: asn1.NullBytes}},
+	} {
+		// testCertificate cannot be used, the certificate with duplicate extensions does not parse
+		template := &x509.Certificate{
+			SerialNumber:    big.NewInt(1),
+			Subject:         pkix.Name{CommonName: "legacy"},
+			NotBefore:       time.Now(),
+			NotAfter:        time.Now().Add(time.Hour),
+			ExtraExtensions: extensions,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle.Write(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificate.String(), Bytes: der}))
+	}
+	url := testFileURL(t, "legacy.pem", []byte(bundle.String()))
+
+	d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{"url": url})
+	if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); !diags.HasError() {
+		t.Fatalf("expected the duplicate extensions to fail without lenient")
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{"url": url, "lenient": true})
+	diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{})
+	if diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if len(diags) != 2 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, oid.String()) || !strings.Contains(diags[1].Detail, "duplicate") {
+		t.Errorf("expected warnings for the unknown critical and duplicate extensions, got %v", diags)
+	}
+	if got := d.Get("certificates.#").(int); got != 2 {
+		t.Fatalf("expected 2 certificates, got %d", got)
+	}
+	if got := d.Get("certificates.0.subject_name.0.common_name").(string); got != "legacy" || d.Get("certificates.0.parse_error").(string) != "" {
+		t.Errorf("expected the certificate with the unknown critical extension to be parsed, got %q", got)
+	}
+	if d.Get("certificates.1.parse_error").(string) == "" || d.Get("certificates.1.sha256_fingerprint").(string) == "" || d.Get("certificates.1.cert_pem").(string) == "" {
+		t.Errorf("expected partial data of the certificate with duplicate extensions")
+	}
+}
+
+func TestDataSourcePKIDownloadGOST(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourcePKIDownload().Schema, map[string]interface{}{"url": testFileURL(t, "gost.crt", testGOSTCertificate(t))})
+	if diags := dataSourcePKIDownloadRead(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("read failed: %v", diags)
+	}
+
+	for key, want := range map[string]string{
+		"certificates.0.subject":               "CN=GOST CA",
+		"certificates.0.public_key_algorithm":  "GOST R 34.10-2012 256",
+		"certificates.0.public_key_parameters": "tc26-256-A",
+		"certificates.0.signature_algorithm":   "GOST R 34.11-2012 256 with GOST R 34.10-2012 256",
+	} {
+		if got := d.Get(key).(string); got != want {
+			t.Errorf("expected %s %q, got %q", key, want, got)
+		}
+	}
+}
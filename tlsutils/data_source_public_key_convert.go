@@ -0,0 +1,95 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+func dataSourcePublicKeyConvert() *schema.Resource {
+	return &schema.Resource{
+		Description: "Convert a public key between the OpenSSH authorized_keys and PEM (SubjectPublicKeyInfo) formats",
+		ReadContext: dataSourcePublicKeyConvertRead,
+		Schema: map[string]*schema.Schema{
+			"public_key": {
+				Description: "public key in OpenSSH authorized_keys format, e.g. `ssh-ed25519 AAAA...`, or public key, certificate or private key in PEM format.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"comment": {
+				Description: "comment appended to `public_key_openssh`. Defaults to the comment of an OpenSSH `public_key`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"algorithm": {
+				Description: "algorithm of the public key.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"public_key_pem": {
+				Description: "public key in PEM (SubjectPublicKeyInfo) format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"public_key_openssh": {
+				Description: "public key in OpenSSH authorized_keys format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourcePublicKeyConvertRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	input := d.Get("public_key").(string)
+	pubKey, err := parseAnyPublicKey(input)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	comment := d.Get("comment").(string)
+	if !strings.HasPrefix(strings.TrimSpace(input), "-----BEGIN") && comment == "" {
+		if _, inputComment, _, _, err := ssh.ParseAuthorizedKey([]byte(input)); err == nil {
+			comment = inputComment
+		}
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal public key: %w", err))
+	}
+	algorithm, err := describePublicKeyInfo(spki)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sshKey, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("%s keys have no OpenSSH format: %w", algorithm.Name, err))
+	}
+
+	openssh := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshKey)))
+	if comment != "" {
+		openssh += " " + comment
+	}
+	publicKeyPem := string(pem.EncodeToMemory(&pem.Block{Type: PreamblePublicKey.String(), Bytes: spki}))
+
+	d.SetId(hashForState(publicKeyPem))
+
+	values := map[string]string{
+		"algorithm":          algorithm.Name,
+		"public_key_pem":     publicKeyPem,
+		"public_key_openssh": openssh + "\n",
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package tlsutils
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func TestDataSourcePublicKeyConvert(t *testing.T) {
+	opensshKey := strings.TrimSuffix(testSSHFingerprintRSAKey, " test")
+
+	for name, test := range map[string]struct {
+		raw     map[string]interface{}
+		openssh string
+	}{
+		"authorized_keys keeps the comment": {
+			raw:     map[string]interface{}{"public_key": testSSHFingerprintRSAKey},
+			openssh: testSSHFingerprintRSAKey + "\n",
+		},
+		"authorized_keys with another comment": {
+			raw:     map[string]interface{}{"public_key": testSSHFingerprintRSAKey, "comment": "deploy@example.com"},
+			openssh: opensshKey + " deploy@example.com\n",
+		},
+		"PEM": {
+			raw:     map[string]interface{}{"public_key": testSSHFingerprintRSAKeyPem},
+			openssh: opensshKey + "\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourcePublicKeyConvert().Schema, test.raw)
+			if diags := dataSourcePublicKeyConvertRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			if got := d.Get("public_key_pem").(string); got != testSSHFingerprintRSAKeyPem {
+				t.Errorf("expected public_key_pem %q, got %q", testSSHFingerprintRSAKeyPem, got)
+			}
+			if got := d.Get("public_key_openssh").(string); got != test.openssh {
+				t.Errorf("expected public_key_openssh %q, got %q", test.openssh, got)
+			}
+			if got := d.Get("algorithm").(string); got != RSA.String() {
+				t.Errorf("expected algorithm %s, got %s", RSA, got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourcePublicKeyConvert().Schema, map[string]interface{}{"public_key": "-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----\n"})
+	if diags := dataSourcePublicKeyConvertRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "PEM does not contain any public key") {
+		t.Errorf("expected PEM without key to be refused, got %v", diags)
+	}
+}
@@ -0,0 +1,195 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"math/big"
+)
+
+func dataSourceSignatureVerify() *schema.Resource {
+	return &schema.Resource{
+		Description: "Verify a detached RSA, ECDSA or ED25519 signature, e.g. of a release artifact in a precondition",
+		ReadContext: dataSourceSignatureVerifyRead,
+		Schema: map[string]*schema.Schema{
+			"public_key_pem": {
+				Description: "public key, certificate or private key in PEM format. The first key or certificate found is used.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"data": {
+				Description:  "signed data.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"data", "data_base64"},
+			},
+			"data_base64": {
+				Description:      "base64 encoded signed data, for binary content.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+				ExactlyOneOf:     []string{"data", "data_base64"},
+			},
+			"signature_base64": {
+				Description:      "base64 encoded signature.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+			},
+			"hash": {
+				Description:      "hash of the data signed with RSA or ECDSA: `sha1`, `sha256`, `sha384` or `sha512`. ED25519 signs the data itself.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "sha256",
+				ValidateDiagFunc: warnWeakParameter(weakHash, validation.ToDiagFunc(validation.StringInSlice(supportedHashAlgorithmsStr(), false))),
+			},
+			"rsa_padding": {
+				Description:      "padding of RSA signatures: `pkcs1v15` or `pss`, with a salt of any length.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "pkcs1v15",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"pkcs1v15", "pss"}, false)),
+			},
+			"ecdsa_signature_format": {
+				Description:      "encoding of ECDSA signatures: `der` (ASN.1, as produced by openssl and cloud KMS) or `raw` (r and s concatenated, as in JWS and PKCS#11).",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "der",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"der", "raw"}, false)),
+			},
+			"algorithm": {
+				Description: "algorithm of the public key.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"valid": {
+				Description: "true when the signature is valid.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"error": {
+				Description: "reason the signature is not valid, empty when `valid` is true.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceSignatureVerifyRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := checkWeakParameters(d, m, map[string]weakParameterCheck{"hash": weakHash}); err != nil {
+		return diag.FromErr(err)
+	}
+
+	spkis, err := subjectPublicKeyInfosFromPEM([]byte(d.Get("public_key_pem").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(spkis) == 0 {
+		return diag.FromErr(fmt.Errorf("public_key_pem does not contain any key or certificate"))
+	}
+	pubKey, err := x509.ParsePKIXPublicKey(spkis[0])
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse public key: %w", err))
+	}
+
+	data := []byte(d.Get("data").(string))
+	if dataBase64, ok := d.GetOk("data_base64"); ok {
+		if data, err = base64.StdEncoding.DecodeString(dataBase64.(string)); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to decode data_base64: %w", err))
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(d.Get("signature_base64").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to decode signature_base64: %w", err))
+	}
+
+	algorithm, verifyErr := verifySignature(pubKey, data, signature, hashAlgorithms[d.Get("hash").(string)], d.Get("rsa_padding").(string), d.Get("ecdsa_signature_format").(string))
+	if algorithm == "" {
+		return diag.FromErr(verifyErr)
+	}
+
+	errorMessage := ""
+	if verifyErr != nil {
+		errorMessage = verifyErr.Error()
+	}
+
+	d.SetId(hashForState(string(spkis[0]), string(data), string(signature)))
+
+	values := map[string]interface{}{
+		"algorithm": algorithm,
+		"valid":     verifyErr == nil,
+		"error":     errorMessage,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// verifySignature checks signature is a signature of data by pubKey. It returns the algorithm of the key,
+// empty when the key is not supported.
+func verifySignature(pubKey crypto.PublicKey, data, signature []byte, hash crypto.Hash, rsaPadding, ecdsaFormat string) (string, error) {
+	if pubKey, ok := pubKey.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(pubKey, data, signature) {
+			return ED25519.String(), fmt.Errorf("invalid ED25519 signature")
+		}
+		return ED25519.String(), nil
+	}
+
+	digest := hash.New()
+	digest.Write(data)
+	hashed := digest.Sum(nil)
+
+	switch pubKey := pubKey.(type) {
+	case *rsa.PublicKey:
+		var err error
+		if rsaPadding == "pss" {
+			err = rsa.VerifyPSS(pubKey, hash, hashed, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		} else {
+			err = rsa.VerifyPKCS1v15(pubKey, hash, hashed, signature)
+		}
+		if err != nil {
+			return RSA.String(), fmt.Errorf("invalid RSA signature: %w", err)
+		}
+		return RSA.String(), nil
+	case *ecdsa.PublicKey:
+		if ecdsaFormat == "raw" {
+			var err error
+			if signature, err = ecdsaRawSignatureToASN1(pubKey, signature); err != nil {
+				return ECDSA.String(), err
+			}
+		}
+		if !ecdsa.VerifyASN1(pubKey, hashed, signature) {
+			return ECDSA.String(), fmt.Errorf("invalid ECDSA signature")
+		}
+		return ECDSA.String(), nil
+	}
+
+	return "", fmt.Errorf("unsupported public key type %T", pubKey)
+}
+
+// ecdsaRawSignatureToASN1 converts an ECDSA signature made of r and s concatenated to its ASN.1 encoding.
+func ecdsaRawSignatureToASN1(pubKey *ecdsa.PublicKey, signature []byte) ([]byte, error) {
+	size := (pubKey.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return nil, fmt.Errorf("raw ECDSA signature must be %d bytes, got %d", 2*size, len(signature))
+	}
+
+	return asn1.Marshal(struct{ R, S *big.Int }{
+		new(big.Int).SetBytes(signature[:size]),
+		new(big.Int).SetBytes(signature[size:]),
+	})
+}
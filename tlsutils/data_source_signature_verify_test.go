@@ -0,0 +1,143 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceSignatureVerify(t *testing.T) {
+	data := []byte("release-1.2.3.tar.gz")
+	sha256Digest := sha256.Sum256(data)
+	sha384Digest := sha512.Sum384(data)
+
+	keys := map[Algorithm]crypto.Signer{}
+	for _, algorithm := range []Algorithm{RSA, ECDSA, ED25519} {
+		prvKey, err := generatePrivateKey(algorithm, 2048, P384)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[algorithm] = prvKey.(crypto.Signer)
+	}
+	publicKeyPem := func(algorithm Algorithm) string {
+		pubKeyPem, err := publicKeyToPEM(keys[algorithm])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pubKeyPem
+	}
+	sign := func(algorithm Algorithm, digest []byte, opts crypto.SignerOpts) []byte {
+		signature, err := keys[algorithm].Sign(rand.Reader, digest, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	ecdsaSignature := sign(ECDSA, sha384Digest[:], crypto.SHA384)
+	r, s, err := ecdsaSignatureValues(ecdsaSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawECDSASignature := append(r.FillBytes(make([]byte, 48)), s.FillBytes(make([]byte, 48))...)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "release signing"}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, keys[ED25519].Public(), keys[ED25519])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		raw       map[string]interface{}
+		algorithm Algorithm
+		err       string
+	}{
+		"RSA PKCS#1 v1.5": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(RSA), "signature_base64": base64.StdEncoding.EncodeToString(sign(RSA, sha256Digest[:], crypto.SHA256))},
+			algorithm: RSA,
+		},
+		"RSA PSS": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(RSA), "rsa_padding": "pss", "signature_base64": base64.StdEncoding.EncodeToString(sign(RSA, sha256Digest[:], &rsa.PSSOptions{Hash: crypto.SHA256}))},
+			algorithm: RSA,
+		},
+		"RSA PSS with PKCS#1 v1.5": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(RSA), "signature_base64": base64.StdEncoding.EncodeToString(sign(RSA, sha256Digest[:], &rsa.PSSOptions{Hash: crypto.SHA256}))},
+			algorithm: RSA,
+			err:       "invalid RSA signature",
+		},
+		"ECDSA DER": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(ECDSA), "hash": "sha384", "signature_base64": base64.StdEncoding.EncodeToString(ecdsaSignature)},
+			algorithm: ECDSA,
+		},
+		"ECDSA raw": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(ECDSA), "hash": "sha384", "ecdsa_signature_format": "raw", "signature_base64": base64.StdEncoding.EncodeToString(rawECDSASignature)},
+			algorithm: ECDSA,
+		},
+		"ECDSA raw of the wrong size": {
+			raw:       map[string]interface{}{"public_key_pem": publicKeyPem(ECDSA), "hash": "sha384", "ecdsa_signature_format": "raw", "signature_base64": base64.StdEncoding.EncodeToString(rawECDSASignature[1:])},
+			algorithm: ECDSA,
+			err:       "raw ECDSA signature must be 96 bytes, got 95",
+		},
+		"ED25519 certificate": {
+			raw:       map[string]interface{}{"public_key_pem": certificateToPEM(cert), "signature_base64": base64.StdEncoding.EncodeToString(ed25519.Sign(keys[ED25519].(ed25519.PrivateKey), data))},
+			algorithm: ED25519,
+		},
+		"tampered data": {
+			raw:       map[string]interface{}{"public_key_pem": certificateToPEM(cert), "data": "release-1.2.4.tar.gz", "signature_base64": base64.StdEncoding.EncodeToString(ed25519.Sign(keys[ED25519].(ed25519.PrivateKey), data))},
+			algorithm: ED25519,
+			err:       "invalid ED25519 signature",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := test.raw["data"]; !ok {
+				test.raw["data_base64"] = base64.StdEncoding.EncodeToString(data)
+			}
+			d := schema.TestResourceDataRaw(t, dataSourceSignatureVerify().Schema, test.raw)
+			if diags := dataSourceSignatureVerifyRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			if got := d.Get("algorithm").(string); got != test.algorithm.String() {
+				t.Errorf("expected algorithm %s, got %s", test.algorithm, got)
+			}
+			if got := d.Get("valid").(bool); got != (test.err == "") {
+				t.Errorf("expected valid %t, got %t", test.err == "", got)
+			}
+			if got := d.Get("error").(string); test.err == "" && got != "" || !strings.Contains(got, test.err) {
+				t.Errorf("expected error %q, got %q", test.err, got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceSignatureVerify().Schema, map[string]interface{}{
+		"public_key_pem":   "not PEM",
+		"data":             string(data),
+		"signature_base64": base64.StdEncoding.EncodeToString([]byte("signature")),
+	})
+	if diags := dataSourceSignatureVerifyRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "does not contain any key or certificate") {
+		t.Errorf("expected public_key_pem without a key to be refused, got %v", diags)
+	}
+}
+
+// ecdsaSignatureValues returns r and s of an ASN.1 encoded ECDSA signature.
+func ecdsaSignatureValues(signature []byte) (*big.Int, *big.Int, error) {
+	var values struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &values); err != nil {
+		return nil, nil, err
+	}
+	return values.R, values.S, nil
+}
@@ -0,0 +1,178 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/ssh"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func dataSourceSSHCertificate() *schema.Resource {
+	return &schema.Resource{
+		Description: "Parse an OpenSSH certificate, like `ssh-keygen -L`",
+		ReadContext: dataSourceSSHCertificateRead,
+		Schema: map[string]*schema.Schema{
+			"certificate": {
+				Description: "OpenSSH certificate, as in the `-cert.pub` files, e.g. `ssh-ed25519-cert-v01@openssh.com AAAA...`.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"evaluation_time": {
+				Description:      "time in RFC3339 format at which `within_validity` is evaluated, instead of the provider evaluation_time or the current time.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+			},
+			"type": {
+				Description: "certificate type: `user` or `host`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"key_id": {
+				Description: "key identifier of the certificate, logged by sshd on authentication.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"serial_number": {
+				Description: "serial number of the certificate in decimal, as a string since it is a 64-bit unsigned integer.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"principals": {
+				Description: "users or host names the certificate is valid for, empty when it is valid for any.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"valid_after": {
+				Description: "time from which the certificate is valid, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"valid_before": {
+				Description: "time until which the certificate is valid, in RFC3339, empty when it never expires.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"within_validity": {
+				Description: "true when the evaluation time is between `valid_after` and `valid_before`.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"critical_options": {
+				Description: "critical options, e.g. `force-command` or `source-address`, with their values.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"extensions": {
+				Description: "extensions, e.g. `permit-pty`, with their values, empty for most of them.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"public_key_openssh": {
+				Description: "certified public key in OpenSSH authorized_keys format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"public_key_fingerprint": {
+				Description: "OpenSSH SHA256 fingerprint of the certified public key.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"signing_ca_openssh": {
+				Description: "public key of the CA that signed the certificate in OpenSSH authorized_keys format, for `TrustedUserCAKeys` or `@cert-authority`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"signing_ca_fingerprint": {
+				Description: "OpenSSH SHA256 fingerprint of the signing CA public key.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"signature_valid": {
+				Description: "true when the signature of the certificate verifies with the signing CA public key.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceSSHCertificateRead(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	data := d.Get("certificate").(string)
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(data))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse OpenSSH certificate: %w", err))
+	}
+	cert, ok := sshKey.(*ssh.Certificate)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("%s is a public key, not an OpenSSH certificate", sshKey.Type()))
+	}
+
+	certType := "user"
+	if cert.CertType == ssh.HostCert {
+		certType = "host"
+	}
+	validAfter := time.Unix(int64(cert.ValidAfter), 0).UTC()
+	validBefore := ""
+	at := now(d, m)
+	withinValidity := !at.Before(validAfter)
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		// values past the int64 range are in practice never reached
+		expiry := time.Unix(int64(min(cert.ValidBefore, math.MaxInt64)), 0).UTC()
+		validBefore = expiry.Format(time.RFC3339)
+		withinValidity = withinValidity && at.Before(expiry)
+	}
+
+	principals := make([]interface{}, 0, len(cert.ValidPrincipals))
+	for _, principal := range cert.ValidPrincipals {
+		principals = append(principals, principal)
+	}
+	criticalOptions := make(map[string]interface{}, len(cert.CriticalOptions))
+	for name, value := range cert.CriticalOptions {
+		criticalOptions[name] = value
+	}
+	extensions := make(map[string]interface{}, len(cert.Extensions))
+	for name, value := range cert.Extensions {
+		extensions[name] = value
+	}
+
+	// the signature covers the encoded certificate up to the signature itself
+	signed := cert.Marshal()
+	signed = signed[:len(signed)-4-len(ssh.Marshal(cert.Signature))]
+	signatureValid := cert.Signature != nil && cert.SignatureKey.Verify(signed, cert.Signature) == nil
+
+	d.SetId(hashForState(strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))), at.Format(time.RFC3339)))
+
+	values := map[string]interface{}{
+		"type":                   certType,
+		"key_id":                 cert.KeyId,
+		"serial_number":          strconv.FormatUint(cert.Serial, 10),
+		"principals":             principals,
+		"valid_after":            validAfter.Format(time.RFC3339),
+		"valid_before":           validBefore,
+		"within_validity":        withinValidity,
+		"critical_options":       criticalOptions,
+		"extensions":             extensions,
+		"public_key_openssh":     strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert.Key))),
+		"public_key_fingerprint": ssh.FingerprintSHA256(cert.Key),
+		"signing_ca_openssh":     strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert.SignatureKey))),
+		"signing_ca_fingerprint": ssh.FingerprintSHA256(cert.SignatureKey),
+		"signature_valid":        signatureValid,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSSHCertificate returns cert signed by a new ED25519 CA for a new ED25519 key, in authorized_keys format, with
+// the CA signer.
+func testSSHCertificate(t *testing.T, cert *ssh.Certificate) (string, ssh.Signer) {
+	t.Helper()
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Key, err = ssh.NewPublicKey(pubKey); err != nil {
+		t.Fatal(err)
+	}
+	if err = cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	return string(ssh.MarshalAuthorizedKey(cert)), ca
+}
+
+func TestDataSourceSSHCertificate(t *testing.T) {
+	validAfter := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	certificate, ca := testSSHCertificate(t, &ssh.Certificate{
+		CertType:        ssh.UserCert,
+		KeyId:           "alice@example.com",
+		Serial:          1<<63 + 42,
+		ValidPrincipals: []string{"alice", "deploy"},
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validAfter.Add(24 * time.Hour).Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: map[string]string{"source-address": "192.0.2.0/24"},
+			Extensions:      map[string]string{"permit-pty": ""},
+		},
+	})
+
+	d := schema.TestResourceDataRaw(t, dataSourceSSHCertificate().Schema, map[string]interface{}{
+		"certificate":     certificate,
+		"evaluation_time": validAfter.Add(time.Hour).Format(time.RFC3339),
+	})
+	if diags := dataSourceSSHCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certifiedKey := sshKey.(*ssh.Certificate).Key
+	for key, want := range map[string]interface{}{
+		"type":                   "user",
+		"key_id":                 "alice@example.com",
+		"serial_number":          "9223372036854775850",
+		"principals":             []interface{}{"alice", "deploy"},
+		"valid_after":            "2026-01-01T00:00:00Z",
+		"valid_before":           "2026-01-02T00:00:00Z",
+		"within_validity":        true,
+		"critical_options":       map[string]interface{}{"source-address": "192.0.2.0/24"},
+		"extensions":             map[string]interface{}{"permit-pty": ""},
+		"public_key_openssh":     strings.TrimSpace(string(ssh.MarshalAuthorizedKey(certifiedKey))),
+		"public_key_fingerprint": ssh.FingerprintSHA256(certifiedKey),
+		"signing_ca_openssh":     strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))),
+		"signing_ca_fingerprint": ssh.FingerprintSHA256(ca.PublicKey()),
+		"signature_valid":        true,
+	} {
+		if got := d.Get(key); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %s %v, got %v", key, want, got)
+		}
+	}
+
+	// a host certificate valid forever, whose key ID was changed after signing
+	certificate, _ = testSSHCertificate(t, &ssh.Certificate{CertType: ssh.HostCert, KeyId: "host", ValidBefore: ssh.CertTimeInfinity})
+	sshKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey.(*ssh.Certificate).KeyId = "tampered"
+	d = schema.TestResourceDataRaw(t, dataSourceSSHCertificate().Schema, map[string]interface{}{"certificate": string(ssh.MarshalAuthorizedKey(sshKey))})
+	if diags := dataSourceSSHCertificateRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+		t.Fatalf("read failed: %v", diags)
+	}
+	if d.Get("type") != "host" || d.Get("valid_before") != "" || !d.Get("within_validity").(bool) || d.Get("signature_valid").(bool) {
+		t.Errorf("expected a host certificate valid forever with an invalid signature, got type %v, valid_before %q, within_validity %v and signature_valid %v",
+			d.Get("type"), d.Get("valid_before"), d.Get("within_validity"), d.Get("signature_valid"))
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceSSHCertificate().Schema, map[string]interface{}{"certificate": strings.TrimSpace(d.Get("public_key_openssh").(string))})
+	if diags := dataSourceSSHCertificateRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "is a public key, not an OpenSSH certificate") {
+		t.Errorf("expected a public key to be refused, got %v", diags)
+	}
+}
@@ -0,0 +1,90 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+func dataSourceSSHFingerprint() *schema.Resource {
+	return &schema.Resource{
+		Description: "Compute the OpenSSH and AWS EC2 key pair fingerprints of a public key",
+		ReadContext: dataSourceSSHFingerprintRead,
+		Schema: map[string]*schema.Schema{
+			"public_key": {
+				Description: "public key in OpenSSH authorized_keys format, or public key, certificate or private key in PEM format.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"public_key_openssh": {
+				Description: "public key in OpenSSH authorized_keys format, without comment.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"sha256_fingerprint": {
+				Description: "OpenSSH SHA256 fingerprint, e.g. `SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8`, as shown by `ssh-keygen -l`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"md5_fingerprint": {
+				Description: "OpenSSH legacy MD5 fingerprint in colon separated hex, as shown by `ssh-keygen -l -E md5` without the `MD5:` prefix.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"aws_md5_fingerprint": {
+				Description: "colon separated hex MD5 of the DER SubjectPublicKeyInfo, the fingerprint AWS EC2 shows for imported RSA key pairs.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceSSHFingerprintRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	pubKey, err := parseAnyPublicKey(d.Get("public_key").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	sshKey, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal OpenSSH public key: %w", err))
+	}
+	spki, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal public key: %w", err))
+	}
+	spkiSum := md5.Sum(spki)
+
+	openssh := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshKey)))
+	d.SetId(hashForState(openssh))
+
+	values := map[string]string{
+		"public_key_openssh":  openssh,
+		"sha256_fingerprint":  ssh.FingerprintSHA256(sshKey),
+		"md5_fingerprint":     ssh.FingerprintLegacyMD5(sshKey),
+		"aws_md5_fingerprint": colonHex(spkiSum[:]),
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// colonHex encodes data in lowercase hex, with the bytes separated by colons.
+func colonHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,76 @@
+package tlsutils
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+// testSSHFingerprintRSAKey is a 1024 bits RSA key, with the fingerprints of ssh-keygen -l and of
+// openssl pkey -pubin -outform DER | openssl md5 -c.
+const testSSHFingerprintRSAKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDjXkowfXxe5m7xUF6aS390GNZqEJzljJU+BzX1fCVupgOC9BYB0yP4zlLjdBl3VXrxS8hY0c7NBf8f/Io6gcvybP7A7kfY/mTYMXsu+g3XGjCbE+QNU9MLihElF0jGwReLd67CG/vjW8W1TCAvop14Isu740wSTg7kkHe30gXujQ== test"
+
+const testSSHFingerprintRSAKeyPem = `-----BEGIN PUBLIC KEY-----
+MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDjXkowfXxe5m7xUF6aS390GNZq
+EJzljJU+BzX1fCVupgOC9BYB0yP4zlLjdBl3VXrxS8hY0c7NBf8f/Io6gcvybP7A
+7kfY/mTYMXsu+g3XGjCbE+QNU9MLihElF0jGwReLd67CG/vjW8W1TCAvop14Isu7
+40wSTg7kkHe30gXujQIDAQAB
+-----END PUBLIC KEY-----
+`
+
+func TestDataSourceSSHFingerprint(t *testing.T) {
+	for name, test := range map[string]struct {
+		publicKey string
+		openssh   string
+		sha256    string
+		md5       string
+		awsMD5    string
+	}{
+		"RSA authorized_keys": {
+			publicKey: testSSHFingerprintRSAKey,
+			openssh:   strings.TrimSuffix(testSSHFingerprintRSAKey, " test"),
+			sha256:    "SHA256:DtF4zZD/I3ih7leWFmzsesdKhUFPTt2UqFFDpXtmg9g",
+			md5:       "1c:fe:ef:fd:0e:91:2b:f5:6b:1c:76:71:92:7e:2e:c6",
+			awsMD5:    "74:21:31:5d:c0:d2:16:96:7f:b6:6c:3d:f9:cf:ac:f1",
+		},
+		"RSA PEM": {
+			publicKey: testSSHFingerprintRSAKeyPem,
+			openssh:   strings.TrimSuffix(testSSHFingerprintRSAKey, " test"),
+			sha256:    "SHA256:DtF4zZD/I3ih7leWFmzsesdKhUFPTt2UqFFDpXtmg9g",
+			md5:       "1c:fe:ef:fd:0e:91:2b:f5:6b:1c:76:71:92:7e:2e:c6",
+			awsMD5:    "74:21:31:5d:c0:d2:16:96:7f:b6:6c:3d:f9:cf:ac:f1",
+		},
+		"ED25519 authorized_keys": {
+			publicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDRBVeXX2yjs8IXagHghrQw18h/imbqPrAu5VYMOyZ/I test",
+			openssh:   "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDRBVeXX2yjs8IXagHghrQw18h/imbqPrAu5VYMOyZ/I",
+			sha256:    "SHA256:kcQY3WmQ1Fjxj7ZW6NQJJrojxmNrXsU6PZdL5PeCL3A",
+			md5:       "a2:65:4a:b0:05:71:02:17:25:38:82:3d:95:c8:ec:5d",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, dataSourceSSHFingerprint().Schema, map[string]interface{}{"public_key": test.publicKey})
+			if diags := dataSourceSSHFingerprintRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+
+			for key, want := range map[string]string{
+				"public_key_openssh": test.openssh,
+				"sha256_fingerprint": test.sha256,
+				"md5_fingerprint":    test.md5,
+			} {
+				if got := d.Get(key).(string); got != want {
+					t.Errorf("expected %s %q, got %q", key, want, got)
+				}
+			}
+			if got := d.Get("aws_md5_fingerprint").(string); test.awsMD5 != "" && got != test.awsMD5 {
+				t.Errorf("expected aws_md5_fingerprint %q, got %q", test.awsMD5, got)
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceSSHFingerprint().Schema, map[string]interface{}{"public_key": "ssh-rsa not-base64"})
+	if diags := dataSourceSSHFingerprintRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "unable to parse OpenSSH public key") {
+		t.Errorf("expected an invalid key to be refused, got %v", diags)
+	}
+}
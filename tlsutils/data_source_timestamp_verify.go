@@ -0,0 +1,195 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"regexp"
+	"slices"
+	"time"
+)
+
+func dataSourceTimestampVerify() *schema.Resource {
+	return &schema.Resource{
+		Description: "Verify an RFC 3161 timestamp token against the certificate of a Time Stamping Authority",
+		ReadContext: dataSourceTimestampVerifyRead,
+		Schema: map[string]*schema.Schema{
+			"token_base64": {
+				Description:      "base64 encoded DER TimeStampToken, e.g. the `token_base64` of a `tlsutils_timestamp_token`.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+			},
+			"tsa_cert_pem": {
+				Description: "trusted certificates in PEM format: the certificate of the TSA itself, or the CA certificates it must chain to through the certificates of the token.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"digest_hex": {
+				Description:      "hex encoded digest the token must be for. When not set, any digest is accepted and returned in `imprint_hex`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(`^([0-9a-fA-F]{2})+$`), "expected a hex encoded digest")),
+			},
+			"hash": {
+				Description:      "hash the digest of the token must use: `sha1`, `sha256`, `sha384` or `sha512`. When not set, any is accepted and returned in `imprint_hash`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedHashAlgorithmsStr(), false)),
+			},
+			"valid": {
+				Description: "true when the token is signed by a trusted TSA certificate with the `time_stamping` extended key usage, valid at `gen_time`, and matches `digest_hex` and `hash`.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"error": {
+				Description: "reason the token is not valid, empty when `valid` is true.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"gen_time": {
+				Description: "time of the timestamp in RFC3339 format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"serial_number": {
+				Description: "serial number of the timestamp, as colon separated hex.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"policy_oid": {
+				Description: "policy of the TSA under which the token was issued.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"imprint_hash": {
+				Description: "hash of the timestamped digest.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"imprint_hex": {
+				Description: "hex encoded timestamped digest.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"tsa_subject": {
+				Description: "subject of the certificate that signed the token, empty when it was not found.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceTimestampVerifyRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	tokenBase64 := d.Get("token_base64").(string)
+	tokenDER, err := base64.StdEncoding.DecodeString(tokenBase64)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to decode token_base64: %w", err))
+	}
+	token, err := parseTimestampToken(tokenDER)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	trusted, err := parsePEMCertificates([]byte(d.Get("tsa_cert_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse tsa_cert_pem: %w", err))
+	}
+	if len(trusted) == 0 {
+		return diag.FromErr(fmt.Errorf("tsa_cert_pem does not contain any certificate"))
+	}
+
+	imprintHash, err := hashAlgorithmName(token.info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	imprintHex := hex.EncodeToString(token.info.MessageImprint.HashedMessage)
+
+	tsaSubject := ""
+	tsaCert, verifyErr := token.signerCertificate(trusted)
+	if verifyErr == nil {
+		tsaSubject = tsaCert.Subject.String()
+		verifyErr = dataSourceTimestampVerifyToken(d, token, tsaCert, trusted, imprintHash)
+	}
+
+	errorMessage := ""
+	if verifyErr != nil {
+		errorMessage = verifyErr.Error()
+	}
+
+	d.SetId(hashForState(tokenBase64, d.Get("tsa_cert_pem").(string), d.Get("digest_hex").(string), d.Get("hash").(string)))
+
+	values := map[string]interface{}{
+		"valid":         verifyErr == nil,
+		"error":         errorMessage,
+		"gen_time":      token.info.GenTime.UTC().Format(time.RFC3339),
+		"serial_number": colonHex(token.info.SerialNumber.Bytes()),
+		"policy_oid":    token.info.Policy.String(),
+		"imprint_hash":  imprintHash,
+		"imprint_hex":   imprintHex,
+		"tsa_subject":   tsaSubject,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+// dataSourceTimestampVerifyToken checks the token is signed by tsaCert, trusted at the time of the timestamp,
+// and is for the configured digest.
+func dataSourceTimestampVerifyToken(d *schema.ResourceData, token *timestampToken, tsaCert *x509.Certificate, trusted []*x509.Certificate, imprintHash string) error {
+	if err := token.verifySignature(tsaCert); err != nil {
+		return err
+	}
+
+	genTime := token.info.GenTime
+	if genTime.Before(tsaCert.NotBefore) || genTime.After(tsaCert.NotAfter) {
+		return fmt.Errorf("the TSA certificate is not valid at %s", genTime.UTC().Format(time.RFC3339))
+	}
+	if !slices.Contains(tsaCert.ExtKeyUsage, x509.ExtKeyUsageTimeStamping) {
+		return fmt.Errorf("the TSA certificate does not have the time_stamping extended key usage")
+	}
+	if !slices.ContainsFunc(trusted, tsaCert.Equal) {
+		roots := x509.NewCertPool()
+		for _, cert := range trusted {
+			roots.AddCert(cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range token.certs {
+			intermediates.AddCert(cert)
+		}
+		_, err := tsaCert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   genTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		})
+		if err != nil {
+			return fmt.Errorf("the TSA certificate is not trusted: %w", err)
+		}
+	}
+
+	if hash := d.Get("hash").(string); hash != "" && hash != imprintHash {
+		return fmt.Errorf("the timestamped digest is %s, not %s", imprintHash, hash)
+	}
+	if digestHex := d.Get("digest_hex").(string); digestHex != "" {
+		digest, err := hex.DecodeString(digestHex)
+		if err != nil {
+			return fmt.Errorf("invalid digest_hex: %w", err)
+		}
+		if !bytes.Equal(digest, token.info.MessageImprint.HashedMessage) {
+			return fmt.Errorf("the token is for another digest")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataSourceTimestampVerify(t *testing.T) {
+	tsaCert, tsaKey, ca := testTimestampAuthority(t)
+	otherCA, otherCAKey := testCertificateAuthority(t, "Other CA", nil, nil)
+	digest := sha256.Sum256([]byte("artifact"))
+	info := timestampInfo{
+		Version:        1,
+		Policy:         testTimestampPolicy,
+		MessageImprint: timestampMessageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashAlgorithmOIDs["sha256"]}, HashedMessage: digest[:]},
+		SerialNumber:   big.NewInt(7),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+	}
+	token, err := testTimestampToken(tsaCert, tsaKey, info, tsaCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenBase64 := base64.StdEncoding.EncodeToString(token)
+	expired := info
+	expired.GenTime = tsaCert.NotAfter.Add(time.Hour)
+	expiredToken, err := testTimestampToken(tsaCert, tsaKey, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCAToken, err := testTimestampToken(otherCA, otherCAKey, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		raw map[string]interface{}
+		err string
+	}{
+		"TSA certificate": {
+			raw: map[string]interface{}{"tsa_cert_pem": certificateToPEM(tsaCert), "digest_hex": hex.EncodeToString(digest[:]), "hash": "sha256"},
+		},
+		"CA of the TSA": {
+			raw: map[string]interface{}{"tsa_cert_pem": certificateToPEM(ca)},
+		},
+		"another CA": {
+			raw: map[string]interface{}{"tsa_cert_pem": certificateToPEM(otherCA)},
+			err: "the TSA certificate is not trusted",
+		},
+		"another digest": {
+			raw: map[string]interface{}{"tsa_cert_pem": certificateToPEM(tsaCert), "digest_hex": strings.Repeat("00", 32)},
+			err: "the token is for another digest",
+		},
+		"another hash": {
+			raw: map[string]interface{}{"tsa_cert_pem": certificateToPEM(tsaCert), "hash": "sha384"},
+			err: "the timestamped digest is sha256, not sha384",
+		},
+		"TSA certificate expired": {
+			raw: map[string]interface{}{"token_base64": base64.StdEncoding.EncodeToString(expiredToken), "tsa_cert_pem": certificateToPEM(tsaCert)},
+			err: "the TSA certificate is not valid at",
+		},
+		"TSA certificate missing": {
+			raw: map[string]interface{}{"token_base64": base64.StdEncoding.EncodeToString(expiredToken), "tsa_cert_pem": certificateToPEM(ca)},
+			err: "the certificate of the TSA is neither in the timestamp token nor given",
+		},
+		"signed without time_stamping": {
+			raw: map[string]interface{}{"token_base64": base64.StdEncoding.EncodeToString(otherCAToken), "tsa_cert_pem": certificateToPEM(otherCA)},
+			err: "the TSA certificate does not have the time_stamping extended key usage",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := test.raw["token_base64"]; !ok {
+				test.raw["token_base64"] = tokenBase64
+			}
+			d := schema.TestResourceDataRaw(t, dataSourceTimestampVerify().Schema, test.raw)
+			if diags := dataSourceTimestampVerifyRead(context.Background(), d, &providerMeta{}); len(diags) > 0 {
+				t.Fatalf("read failed: %v", diags)
+			}
+			if valid, errorMessage := d.Get("valid").(bool), d.Get("error").(string); valid != (test.err == "") || !strings.Contains(errorMessage, test.err) {
+				t.Errorf("expected error %q, got valid %v with error %q", test.err, valid, errorMessage)
+			}
+			if d.Get("imprint_hash") != "sha256" || d.Get("imprint_hex") != hex.EncodeToString(digest[:]) || d.Get("serial_number") != "07" || d.Get("policy_oid") != testTimestampPolicy.String() {
+				t.Errorf("expected the attributes of the token, got imprint %s %s, serial number %s and policy %s", d.Get("imprint_hash"), d.Get("imprint_hex"), d.Get("serial_number"), d.Get("policy_oid"))
+			}
+		})
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceTimestampVerify().Schema, map[string]interface{}{"token_base64": base64.StdEncoding.EncodeToString([]byte("not a token")), "tsa_cert_pem": certificateToPEM(tsaCert)})
+	if diags := dataSourceTimestampVerifyRead(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "unable to parse timestamp token content info") {
+		t.Errorf("expected an invalid token to fail, got %v", diags)
+	}
+}
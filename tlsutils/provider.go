@@ -1,15 +1,316 @@
 package tlsutils
 
 import (
+	"context"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
 )
 
 // Provider -
 func Provider() *schema.Provider {
 	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"evaluation_time": {
+				Description:      "time in RFC3339 format used instead of the current time for validity computations, to get deterministic results in tests.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+			},
+			"default_subject": subjectDefaultsSchema("subject attributes of the issued certificates and CA certificates, for the ones their resource leaves empty."),
+			"default_validity_period_hours": {
+				Description:      "validity of the issued certificates when their resource does not set `validity_period_hours`, `validity_period` or `ttl`.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ConflictsWith:    []string{"default_validity_period"},
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+			},
+			"default_validity_period": {
+				Description:      "`default_validity_period_hours` as " + validityPeriodDescription + ".",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"default_validity_period_hours"},
+				ValidateDiagFunc: validation.ToDiagFunc(validateValidityPeriod),
+			},
+			"default_early_renewal_hours": {
+				Description:      "`early_renewal_hours` of the resources that do not set it.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"certificate_profile": certificateProfileSchema(),
+			"default_key_algorithm": {
+				Description:      "`algorithm` of the keys generated by the resources that do not set it: `RSA`, `ECDSA` or `ED25519`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedAlgorithmsStr(), false)),
+			},
+			"default_rsa_bits": {
+				Description:      "`rsa_bits` of the RSA keys generated by the resources that do not set it.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+			},
+			"default_ecdsa_curve": {
+				Description:      "`ecdsa_curve` of the ECDSA keys generated by the resources that do not set it.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+			},
+			"issuance_journal": {
+				Description: "journal where `tlsutils_dual_cert`, `tlsutils_cert_batch` and `tlsutils_pki_bootstrap` record the serial number, subject and validity of the certificates they issue, like the `index.txt` of easy-rsa. `tlsutils_x509_crl` can revoke the certificates it marks as superseded, and the `max_issue_validity_hours` of `tlsutils_pki_bootstrap` is recorded there.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Description:      "local JSON file of the journal, created when missing. Updates are serialized with a `.lock` file next to it, so Terraform runs sharing the file, on one machine or a shared filesystem, do not lose entries. Only local files are supported, there is no SQLite or object storage backend.",
+							Type:             schema.TypeString,
+							Required:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotEmpty),
+						},
+					},
+				},
+			},
+			"weak_parameters_as_errors": {
+				Description: "fail the plans using weak parameters instead of warning about them: SHA-1, RSA CA keys under 3072 bits, input certificates signed with SHA-1, with RSA keys under 2048 bits or past 90% of their validity, and leaf certificates valid for more than the 398 days browsers accept.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+			},
+		},
 		ResourcesMap: map[string]*schema.Resource{
-			"tlsutils_x509_crl": resourceX509Crl(),
+			"tlsutils_x509_crl":              resourceX509Crl(),
+			"tlsutils_jwt_signing_key":       resourceJWTSigningKey(),
+			"tlsutils_secure_file":           resourceSecureFile(),
+			"tlsutils_vault_pki_signed_cert": resourceVaultPKISignedCert(),
+			"tlsutils_pfx":                   resourcePFX(),
+			"tlsutils_ct_submission":         resourceCTSubmission(),
+			"tlsutils_shamir_private_key":    resourceShamirPrivateKey(),
+			"tlsutils_dual_cert":             resourceDualCert(),
+			"tlsutils_pki_bootstrap":         resourcePKIBootstrap(),
+			"tlsutils_session_ticket_keys":   resourceSessionTicketKeys(),
+			"tlsutils_dh_params":             resourceDHParams(),
+			"tlsutils_symmetric_key":         resourceSymmetricKey(),
+			"tlsutils_key_convert":           resourceKeyConvert(),
+			"tlsutils_ocsp_responses":        resourceOCSPResponses(),
+			"tlsutils_public_key_encrypt":    resourcePublicKeyEncrypt(),
+			"tlsutils_wireguard_key":         resourceWireGuardKey(),
+			"tlsutils_cert_batch":            resourceCertBatch(),
+			"tlsutils_webhook_signed_cert":   resourceWebhookSignedCert(),
+			"tlsutils_step_ca_signed_cert":   resourceStepCASignedCert(),
+			"tlsutils_cfssl_signed_cert":     resourceCFSSLSignedCert(),
+			"tlsutils_ejbca_enrolled_cert":   resourceEJBCAEnrolledCert(),
+			"tlsutils_timestamp_token":       resourceTimestampToken(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"tlsutils_acm_certificate":            dataSourceACMCertificate(),
+			"tlsutils_pem_blocks":                 dataSourcePEMBlocks(),
+			"tlsutils_android_pin_set":            dataSourceAndroidPinSet(),
+			"tlsutils_endpoint_certificate":       dataSourceEndpointCertificate(),
+			"tlsutils_pki_download":               dataSourcePKIDownload(),
+			"tlsutils_ejbca_certificates":         dataSourceEJBCACertificates(),
+			"tlsutils_aia_chain":                  dataSourceAIAChain(),
+			"tlsutils_os_trust_store":             dataSourceOSTrustStore(),
+			"tlsutils_certificate_hostname_check": dataSourceCertificateHostnameCheck(),
+			"tlsutils_certificate_policy_check":   dataSourceCertificatePolicyCheck(),
+			"tlsutils_ephemeral_certificate":      dataSourceEphemeralCertificate(),
+			"tlsutils_signature_verify":           dataSourceSignatureVerify(),
+			"tlsutils_ssh_fingerprint":            dataSourceSSHFingerprint(),
+			"tlsutils_ssh_certificate":            dataSourceSSHCertificate(),
+			"tlsutils_public_key_convert":         dataSourcePublicKeyConvert(),
+			"tlsutils_instance_identity_csr":      dataSourceInstanceIdentityCSR(),
+			"tlsutils_ecdh_shared_secret":         dataSourceECDHSharedSecret(),
+			"tlsutils_timestamp_verify":           dataSourceTimestampVerify(),
+			"tlsutils_endpoint_pin_verify":        dataSourceEndpointPinVerify(),
+		},
+		ConfigureContextFunc: providerConfigure,
+	}
+}
+
+// providerMeta holds the provider configuration passed to resources and data sources.
+type providerMeta struct {
+	evaluationTime           time.Time
+	defaultSubject           pkix.Name
+	defaultValidityPeriod    time.Duration
+	defaultEarlyRenewalHours int
+	certificateProfiles      map[string]certificateProfile
+	defaultKeyAlgorithm      Algorithm
+	defaultRSABits           int
+	defaultECDSACurve        ECDSACurve
+	issuanceJournal          issuanceJournal
+	weakParametersAsErrors   bool
+}
+
+func providerConfigure(_ context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	meta := &providerMeta{}
+
+	if evaluationTime := d.Get("evaluation_time").(string); evaluationTime != "" {
+		t, err := time.Parse(time.RFC3339, evaluationTime)
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("invalid evaluation_time: %w", err))
+		}
+		meta.evaluationTime = t
+	}
+
+	if subjects := d.Get("default_subject").([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+		meta.defaultSubject = certificateSubject(subjects[0].(map[string]interface{}))
+	}
+	meta.defaultValidityPeriod = effectiveValidityPeriod(d.Get("default_validity_period").(string), d.Get("default_validity_period_hours").(int))
+	meta.defaultEarlyRenewalHours = d.Get("default_early_renewal_hours").(int)
+	meta.defaultKeyAlgorithm = Algorithm(d.Get("default_key_algorithm").(string))
+	meta.defaultRSABits = d.Get("default_rsa_bits").(int)
+	meta.defaultECDSACurve = ECDSACurve(d.Get("default_ecdsa_curve").(string))
+	meta.weakParametersAsErrors = d.Get("weak_parameters_as_errors").(bool)
+
+	profiles, err := certificateProfilesFromConfig(d.Get("certificate_profile").([]interface{}))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	meta.certificateProfiles = profiles
+	if journals := d.Get("issuance_journal").([]interface{}); len(journals) > 0 && journals[0] != nil {
+		meta.issuanceJournal = &fileIssuanceJournal{path: journals[0].(map[string]interface{})["path"].(string)}
+	}
+
+	return meta, nil
+}
+
+// resourceAttributes is implemented by both *schema.ResourceData and *schema.ResourceDiff.
+type resourceAttributes interface {
+	Get(key string) interface{}
+	GetOk(key string) (interface{}, bool)
+	GetRawConfig() cty.Value
+}
+
+// resourceChanges is implemented by both *schema.ResourceData and *schema.ResourceDiff. Unlike Get, GetChange
+// returns the prior state of the attributes the plan marked as computed.
+type resourceChanges interface {
+	resourceAttributes
+	GetChange(key string) (interface{}, interface{})
+}
+
+// now returns the time used for validity computations: the evaluation_time of the resource if it has one,
+// then the evaluation_time of the provider, and finally the current time.
+func now(d resourceAttributes, m interface{}) time.Time {
+	if evaluationTime, ok := d.GetOk("evaluation_time"); ok {
+		if t, err := time.Parse(time.RFC3339, evaluationTime.(string)); err == nil {
+			return t
+		}
+	}
+
+	if meta, ok := m.(*providerMeta); ok && !meta.evaluationTime.IsZero() {
+		return meta.evaluationTime
+	}
+
+	return time.Now()
+}
+
+// subjectDefaultsSchema returns a block of the subject attributes the provider fills in the certificates.
+func subjectDefaultsSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Description: description,
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"organization": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"organizational_unit": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"locality": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"province": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"country": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+			},
 		},
-		DataSourcesMap: map[string]*schema.Resource{},
 	}
 }
+
+// subjectWithDefaults fills the attributes of subject left empty with the default_subject of the provider.
+func subjectWithDefaults(subject pkix.Name, m interface{}) pkix.Name {
+	meta, ok := m.(*providerMeta)
+	if !ok {
+		return subject
+	}
+
+	return fillSubjectDefaults(subject, meta.defaultSubject)
+}
+
+// fillSubjectDefaults returns subject with its empty organization, organizational unit, locality, province and
+// country taken from defaults.
+func fillSubjectDefaults(subject, defaults pkix.Name) pkix.Name {
+	if len(subject.Organization) == 0 {
+		subject.Organization = defaults.Organization
+	}
+	if len(subject.OrganizationalUnit) == 0 {
+		subject.OrganizationalUnit = defaults.OrganizationalUnit
+	}
+	if len(subject.Locality) == 0 {
+		subject.Locality = defaults.Locality
+	}
+	if len(subject.Province) == 0 {
+		subject.Province = defaults.Province
+	}
+	if len(subject.Country) == 0 {
+		subject.Country = defaults.Country
+	}
+
+	return subject
+}
+
+// intOrProviderDefault returns the top level attribute key when it is set in the configuration of the resource,
+// and the provider default returned by fallback otherwise.
+func intOrProviderDefault(d resourceAttributes, key string, m interface{}, fallback func(meta *providerMeta) int) int {
+	if value, ok := configuredAttribute(d, key); ok {
+		return value.(int)
+	}
+
+	if meta, ok := m.(*providerMeta); ok {
+		return fallback(meta)
+	}
+	return d.Get(key).(int)
+}
+
+// stringOrProviderDefault is intOrProviderDefault for string attributes.
+func stringOrProviderDefault(d resourceAttributes, key string, m interface{}, fallback func(meta *providerMeta) string) string {
+	if value, ok := configuredAttribute(d, key); ok {
+		return value.(string)
+	}
+
+	if meta, ok := m.(*providerMeta); ok {
+		return fallback(meta)
+	}
+	return d.Get(key).(string)
+}
+
+// configuredAttribute returns the top level attribute key and true when it is set in the configuration of the resource.
+func configuredAttribute(d resourceAttributes, key string) (interface{}, bool) {
+	config := d.GetRawConfig()
+	if config.IsKnown() && !config.IsNull() && config.Type().IsObjectType() && config.Type().HasAttribute(key) {
+		if !config.GetAttr(key).IsNull() {
+			return d.Get(key), true
+		}
+	} else if value, ok := d.GetOk(key); ok {
+		// without the configuration, the zero value cannot be told apart from unset
+		return value, true
+	}
+
+	return nil, false
+}
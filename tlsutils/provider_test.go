@@ -0,0 +1,248 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testResourceApply plans raw as the configuration of r on top of state, nil to create the resource, and applies the
+// plan like Terraform does, CustomizeDiff included. It returns state unchanged when nothing is planned.
+func testResourceApply(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, m interface{}) *terraform.InstanceState {
+	t.Helper()
+
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), m)
+	if err != nil {
+		t.Fatalf("plan failed: %s", err)
+	}
+	if diff == nil || diff.Empty() {
+		return state
+	}
+
+	applied, diags := r.Apply(context.Background(), state, diff, m)
+	for _, diagnostic := range diags {
+		t.Fatalf("apply failed: %s: %s", diagnostic.Summary, diagnostic.Detail)
+	}
+
+	return applied
+}
+
+// testPublicKeyOf returns the public key of the private key PEM attribute key of state, failing the test when it
+// cannot be parsed.
+func testPublicKeyOf(t *testing.T, state *terraform.InstanceState, key string) string {
+	t.Helper()
+
+	prvKey, _, err := parsePrivateKeyPEM([]byte(state.Attributes[key]))
+	if err != nil {
+		t.Fatalf("unable to parse %s: %s", key, err)
+	}
+	publicKey, err := publicKeyToOpenSSH(prvKey)
+	if err != nil {
+		t.Fatalf("failed to encode the public key of %s: %s", key, err)
+	}
+
+	return publicKey
+}
+
+// testCertificate issues a certificate of template for a new ECDSA P-256 key, signed by parent and its parentKey, or
+// self-signed when parent is nil. The serial number and validity default to the current hour.
+func testCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	prvKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	if template.SerialNumber == nil {
+		template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+	if parent == nil {
+		parent, parentKey = template, prvKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, prvKey.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+
+	return cert, prvKey
+}
+
+// testCertificateAuthority returns a new CA certificate for commonName with its key, signed by parent and its
+// parentKey or self-signed when parent is nil. It is valid for a year, outliving the certificates it issues.
+func testCertificateAuthority(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	return testCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+	}, parent, parentKey)
+}
+
+// testCertificateRequest returns the PEM format CSR of a new ECDSA key for commonName.
+func testCertificateRequest(t *testing.T, commonName string) string {
+	t.Helper()
+
+	prvKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, prvKey)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %s", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: PreambleCertificateRequest.String(), Bytes: der}))
+}
+
+// testSignCertificateRequest signs csrPem with caCert and caKey, like a remote CA would. It returns errors instead of
+// failing the test, to be called by test servers.
+func testSignCertificateRequest(caCert *x509.Certificate, caKey *ecdsa.PrivateKey, csrPem string, validity time.Duration) (*x509.Certificate, error) {
+	csr, err := parsePEMCertificateRequest([]byte(csrPem))
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := randomSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+func TestProviderEvaluationTime(t *testing.T) {
+	p := Provider()
+	if diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{"evaluation_time": "2024-01-01T00:00:00Z"})); diags.HasError() {
+		t.Fatalf("configure failed: %v", diags)
+	}
+	if got := p.Meta().(*providerMeta).evaluationTime; !got.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the evaluation_time of the provider, got %s", got)
+	}
+}
+
+func TestProviderDefaults(t *testing.T) {
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"default_subject":               []interface{}{map[string]interface{}{"organization": "Example", "country": "NL"}},
+		"default_validity_period_hours": 48,
+		"default_early_renewal_hours":   72,
+	}))
+	if diags.HasError() {
+		t.Fatalf("configure failed: %v", diags)
+	}
+	meta := p.Meta()
+
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, test := range map[string]struct {
+		attributes   map[string]interface{}
+		organization string
+		country      string
+		validity     time.Duration
+	}{
+		"provider defaults": {
+			organization: "Example",
+			country:      "NL",
+			validity:     48 * time.Hour,
+		},
+		"resource attributes": {
+			attributes: map[string]interface{}{
+				"subject":               []interface{}{map[string]interface{}{"organization": "Other"}},
+				"validity_period_hours": 24,
+			},
+			organization: "Other",
+			country:      "NL",
+			validity:     24 * time.Hour,
+		},
+		"resource duration string": {
+			attributes: map[string]interface{}{
+				"validity_period": "PT36H",
+			},
+			organization: "Example",
+			country:      "NL",
+			validity:     36 * time.Hour,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"ca_cert_pem":        certificateToPEM(ca),
+				"ca_private_key_pem": caKeyPem,
+			}
+			for key, value := range test.attributes {
+				raw[key] = value
+			}
+			state := testResourceApply(t, resourceDualCert(), nil, raw, meta)
+
+			cert, err := parsePEMCertificate([]byte(state.Attributes["ecdsa_cert_pem"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(cert.Subject.Organization, ",") != test.organization || strings.Join(cert.Subject.Country, ",") != test.country {
+				t.Errorf("expected organization %s and country %s, got %s", test.organization, test.country, cert.Subject)
+			}
+			if got := cert.NotAfter.Sub(cert.NotBefore); got != test.validity {
+				t.Errorf("expected a validity of %s, got %s", test.validity, got)
+			}
+		})
+	}
+
+	state := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), meta)
+	root, err := parsePEMCertificate([]byte(state.Attributes["root_cert_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(root.Subject.Organization, ",") != "Example" || root.Subject.CommonName != "Test Root CA" {
+		t.Errorf("expected the default subject in the root CA certificate, got %s", root.Subject)
+	}
+
+	if _, err = certificateTemplate(schema.TestResourceDataRaw(t, resourceDualCert().Schema, map[string]interface{}{}), &providerMeta{}); err == nil || !strings.Contains(err.Error(), "validity_period_hours or validity_period must be set") {
+		t.Errorf("expected an error without validity, got %v", err)
+	}
+
+	p = Provider()
+	if diags = p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{"default_validity_period": "P1W"})); diags.HasError() {
+		t.Fatalf("configure failed: %v", diags)
+	}
+	if got := p.Meta().(*providerMeta).defaultValidityPeriod; got != 7*24*time.Hour {
+		t.Errorf("expected a default validity of a week, got %s", got)
+	}
+}
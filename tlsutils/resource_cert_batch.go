@@ -0,0 +1,724 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certBatchPrivateKeys are the maps holding the private keys, encrypted to age_recipient or pgp_key when set.
+var certBatchPrivateKeys = []string{"private_key_pem", "private_key_openssh"}
+
+func resourceCertBatch() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"ca_cert_pem": {
+			Description:      "certificate of the CA signing the certificates, in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"ca_private_key_pem": {
+			Description:      "private key of the CA in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			Sensitive:        true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"algorithm": {
+			Description:      "name of the algorithm of the keys. Defaults to the `default_key_algorithm` of the provider, then `ECDSA`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedAlgorithmsStr(), false)),
+		},
+		"rsa_bits": {
+			Description:      "size of the RSA keys in bits, when `algorithm` is `RSA`. Defaults to the `default_rsa_bits` of the provider, then 2048.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+		},
+		"ecdsa_curve": {
+			Description:      "elliptic curve of the keys, when `algorithm` is `ECDSA`. Defaults to the `default_ecdsa_curve` of the provider, then `P256`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+		},
+		"truncate_to_ca_expiry": {
+			Description: "cap the validity of the certificates to the not after of the CA certificate, instead of failing when they would outlive it.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"subject":              certificateSubjectSchema("subject shared by the certificates. The common name defaults to the `name` of each certificate."),
+		"serial_number_prefix": serialNumberPrefixSchema("serial numbers of the certificates"),
+		"certificate": {
+			Description: "certificates to issue. Adding, changing or removing one only issues or drops that one, the others keep their key and certificate.",
+			Type:        schema.TypeSet,
+			Required:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Description:      "unique name of the certificate, its key in the output maps.",
+						Type:             schema.TypeString,
+						Required:         true,
+						ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotEmpty),
+					},
+					"dns_names": {
+						Description: "DNS names the certificate is valid for. Unicode names are converted to A-labels (punycode).",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type:         schema.TypeString,
+							ValidateFunc: validateDNSName,
+						},
+					},
+					"ip_addresses": {
+						Description: "IP addresses the certificate is valid for.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem: &schema.Schema{
+							Type:         schema.TypeString,
+							ValidateFunc: validation.IsIPAddress,
+						},
+					},
+					"uris": {
+						Description: "URIs the certificate is valid for.",
+						Type:        schema.TypeList,
+						Optional:    true,
+						Elem:        &schema.Schema{Type: schema.TypeString},
+					},
+					"profile": {
+						Description:      "usages of the certificate, like the `profile` of `tlsutils_dual_cert`: `tls_server`, `tls_client`, `smartcard_logon` (requires `user_principal_name`), `ocsp_responder` or `devid` (requires the `serial_number` of the subject).",
+						Type:             schema.TypeString,
+						Optional:         true,
+						Default:          "tls_server",
+						ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedCertificateProfilesStr(), false)),
+					},
+					"user_principal_name": {
+						Description: "Active Directory user principal name, e.g. `user@corp.example.com`, added to the subject alternative names as an otherName.",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+				},
+			},
+		},
+		"private_key_pem": {
+			Description: "private keys in PEM format, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Sensitive:   true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"private_key_openssh": {
+			Description: "private keys in OpenSSH format when `openssh_output` is set, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Sensitive:   true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"public_key_openssh": {
+			Description: "public keys in OpenSSH authorized_keys format when `openssh_output` is set, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"cert_pem": {
+			Description: "certificates in PEM format, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"cert_text": {
+			Description: "certificates rendered like `openssl x509 -text`, for reviewing plans, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"cert_json": {
+			Description: "certificates parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"fullchain_pem": {
+			Description: "certificates followed by `ca_cert_pem` unless it is a self-signed root, by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+	}
+	for name, attribute := range validityPeriodSchema("", "number of hours the certificates remain valid for after being issued. Defaults to the default validity of the provider.") {
+		s[name] = attribute
+	}
+	for name, attribute := range keyFormatSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range certBatchValiditySchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range privateKeyEncryptionSchema(true, certBatchPrivateKeys...) {
+		if strings.HasPrefix(name, "encrypted_") {
+			attribute.Description = strings.TrimSuffix(attribute.Description, ".") + ", by certificate name."
+			attribute.Type = schema.TypeMap
+			attribute.Elem = &schema.Schema{Type: schema.TypeString}
+		}
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	issue := certificateIssueSchema()
+	s["validation_preset"] = issue["validation_preset"]
+	s["authority_key_id"] = issue["authority_key_id"]
+	// each certificate has its own key, so there is no subject_key_id to pin
+	skiMethod := *issue["ski_method"]
+	skiMethod.ConflictsWith = nil
+	s["ski_method"] = &skiMethod
+	s["extension_criticality"] = extensionCriticalitySchema("the certificates", "subject_alt_name", "key_usage", "extended_key_usage", "basic_constraints")
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+	for name, attribute := range crlDistributionSchema() {
+		s[name] = attribute
+	}
+	warnWeakParameters(s, certBatchWeakParameters)
+
+	return &schema.Resource{
+		Description:   "Issue many leaf certificates with their keys from one CA, concurrently, in a single resource",
+		CreateContext: resourceCertBatchCreate,
+		ReadContext:   resourceCertBatchRead,
+		UpdateContext: resourceCertBatchUpdate,
+		DeleteContext: resourceCertBatchDelete,
+		CustomizeDiff: resourceCertBatchCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// certBatchValiditySchema returns the certificateValiditySchema attributes, and validity_start_time and
+// validity_end_time like certificateIssueSchema, as maps by certificate name filled by certBatchSetValidity.
+func certBatchValiditySchema() map[string]*schema.Schema {
+	attributes := certificateValiditySchema("", "the certificate")
+	issue := certificateIssueSchema()
+	attributes["validity_start_time"], attributes["validity_end_time"] = issue["validity_start_time"], issue["validity_end_time"]
+
+	s := make(map[string]*schema.Schema, len(attributes))
+	for name, attribute := range attributes {
+		s[name] = &schema.Schema{
+			Description: strings.TrimSuffix(attribute.Description, ".") + ", by certificate name.",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: attribute.Type},
+		}
+	}
+
+	return s
+}
+
+// certBatchKeyAttributes are the maps holding the keys of the batch.
+var certBatchKeyAttributes = []string{"private_key_pem", "private_key_openssh", "public_key_openssh", "encrypted_private_key_pem", "encrypted_private_key_openssh"}
+
+// certBatchOutputAttributes are the maps holding the keys and certificates of the batch.
+var certBatchOutputAttributes = append(slices.Clone(certBatchKeyAttributes), "cert_pem", "cert_text", "cert_json", "fullchain_pem")
+
+// certBatchIssued is a certificate of the batch, in PEM format, with its key.
+type certBatchIssued struct {
+	// prvKey is nil for a kept certificate whose key is only in the state encrypted, keys holding its
+	// certBatchKeyAttributes values then
+	prvKey  crypto.PrivateKey
+	keys    map[string]interface{}
+	certPem string
+}
+
+func resourceCertBatchCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	entries, err := certBatchEntries(d.Get("certificate").(*schema.Set))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	issued, err := certBatchIssue(d, m, entries)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(id)
+
+	if err = certBatchSave(d, m, issued); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+// resourceCertBatchRead refreshes the validity of the certificates. With check_revocation, the revoked certificates
+// are removed from the output maps, which plans issuing them again.
+func resourceCertBatchRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if d.Get("check_revocation").(bool) {
+		client, err := newHTTPClient("")
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+		}
+
+		revoked := make([]string, 0)
+		for name, certPem := range d.Get("cert_pem").(map[string]interface{}) {
+			isRevoked, certDiags := revocationDiagnostics(ctx, client, certPem.(string), d.Get("ca_cert_pem").(string), now(d, m))
+			diags = append(diags, certDiags...)
+			if diags.HasError() {
+				return diags
+			}
+			if isRevoked {
+				revoked = append(revoked, name)
+			}
+		}
+		if err = certBatchDrop(d, revoked...); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	certPems := make(map[string]string)
+	for name, certPem := range d.Get("cert_pem").(map[string]interface{}) {
+		certPems[name] = certPem.(string)
+	}
+	if err := certBatchSetValidity(d, m, certPems); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	return diags
+}
+
+// resourceCertBatchUpdate issues the added and changed certificates, keeping the others, and drops the removed ones.
+// The keys of all the certificates are encoded again, for the keyFormatSchema attributes.
+func resourceCertBatchUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	previousSet, currentSet := d.GetChange("certificate")
+	previous, err := certBatchEntries(previousSet.(*schema.Set))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	current, err := certBatchEntries(currentSet.(*schema.Set))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	previousKeys := make(map[string]map[string]interface{}, len(certBatchKeyAttributes))
+	for _, key := range certBatchKeyAttributes {
+		values, _ := d.GetChange(key)
+		previousKeys[key] = values.(map[string]interface{})
+	}
+	previousCerts, _ := d.GetChange("cert_pem")
+	kept := make(map[string]certBatchIssued, len(current))
+	pending := make(map[string]map[string]interface{})
+	for name, entry := range current {
+		certPem, ok := previousCerts.(map[string]interface{})[name].(string)
+		if !ok || !reflect.DeepEqual(previous[name], entry) {
+			pending[name] = entry
+			continue
+		}
+
+		certificate := certBatchIssued{certPem: certPem}
+		if privateKeyPem, _ := previousKeys["private_key_pem"][name].(string); privateKeyPem != "" {
+			if certificate.prvKey, _, err = parsePrivateKeyPEM([]byte(privateKeyPem)); err != nil {
+				return diag.FromErr(fmt.Errorf("unable to parse private key of %q: %w", name, err))
+			}
+		} else {
+			// the key is encrypted, keyFormatSetNewComputed replaces the resource when its encoding changes
+			certificate.keys = make(map[string]interface{}, len(certBatchKeyAttributes))
+			for _, key := range certBatchKeyAttributes {
+				certificate.keys[key] = previousKeys[key][name]
+			}
+		}
+		kept[name] = certificate
+	}
+
+	issued, err := certBatchIssue(d, m, pending)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	superseded := make([]string, 0)
+	for name, certPem := range previousCerts.(map[string]interface{}) {
+		if _, ok := kept[name]; !ok {
+			superseded = append(superseded, certPem.(string))
+		}
+	}
+	if err = supersedeIssuedCertificates(m, now(d, m), superseded...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	for name, certificate := range kept {
+		issued[name] = certificate
+	}
+	if err = certBatchSave(d, m, issued); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceCertBatchDelete(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	certPems := make([]string, 0)
+	for _, certPem := range d.Get("cert_pem").(map[string]interface{}) {
+		certPems = append(certPems, certPem.(string))
+	}
+	if err := supersedeIssuedCertificates(m, now(d, m), certPems...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+// certBatchWeakParameters are the checks of the weak parameters of tlsutils_cert_batch.
+var certBatchWeakParameters = map[string]weakParameterCheck{
+	"ca_cert_pem":           weakCertificates,
+	"validity_period_hours": weakServerValidityHours,
+	"validity_period":       weakServerValidityPeriod,
+}
+
+func resourceCertBatchCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := checkWeakParameters(diff, m, certBatchWeakParameters); err != nil {
+		return err
+	}
+
+	algorithm := keyAlgorithmOrProviderDefault(diff, m, ECDSA)
+	parameters := map[string]interface{}{
+		"algorithm":   algorithm.String(),
+		"rsa_bits":    rsaBitsOrProviderDefault(diff, m, 2048),
+		"ecdsa_curve": ecdsaCurveOrProviderDefault(diff, m, P256).String(),
+	}
+	if err := planKeyParameters(diff, parameters, keyParametersUsedBy(algorithm)...); err != nil {
+		return err
+	}
+
+	if diff.Id() == "" {
+		return nil
+	}
+	if err := keyFormatSetNewComputed(diff, ""); err != nil {
+		return err
+	}
+	if err := planFullChains(diff, "fullchain_pem"); err != nil {
+		return err
+	}
+
+	// the certificates dropped from the state by check_revocation are issued again
+	missing := false
+	certPems := diff.Get("cert_pem").(map[string]interface{})
+	for _, raw := range diff.Get("certificate").(*schema.Set).List() {
+		if _, ok := certPems[raw.(map[string]interface{})["name"].(string)]; !ok {
+			missing = true
+		}
+	}
+	if !missing && !diff.HasChange("certificate") {
+		return nil
+	}
+
+	computed := slices.Clone(certBatchOutputAttributes)
+	for name := range certBatchValiditySchema() {
+		computed = append(computed, name)
+	}
+	for _, key := range computed {
+		if err := diff.SetNewComputed(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// certBatchEntries returns the certificate blocks by name, rejecting duplicated names.
+func certBatchEntries(set *schema.Set) (map[string]map[string]interface{}, error) {
+	entries := make(map[string]map[string]interface{}, set.Len())
+	for _, raw := range set.List() {
+		entry := raw.(map[string]interface{})
+		name := entry["name"].(string)
+		if _, ok := entries[name]; ok {
+			return nil, fmt.Errorf("duplicate certificate name %q", name)
+		}
+		entries[name] = entry
+	}
+
+	return entries, nil
+}
+
+// certBatchIssue generates the keys and issues the certificates of entries, spread over one worker per CPU,
+// and records them in the issuance journal.
+func certBatchIssue(d *schema.ResourceData, m interface{}, entries map[string]map[string]interface{}) (map[string]certBatchIssued, error) {
+	issued := make(map[string]certBatchIssued, len(entries))
+	if len(entries) == 0 {
+		return issued, nil
+	}
+
+	caCert, caKey, err := parseCertificateAuthority(d.Get("ca_cert_pem").(string), d.Get("ca_private_key_pem").(string), now(d, m))
+	if err != nil {
+		return nil, err
+	}
+
+	validityPeriod := validityPeriodOrProviderDefault(d, "validity_period", "validity_period_hours", m)
+	if validityPeriod <= 0 {
+		return nil, fmt.Errorf("validity_period_hours or validity_period must be set, in the resource or as default of the provider")
+	}
+	notBefore := now(d, m).UTC().Truncate(time.Second)
+	notAfter, err := issuancePolicyNotAfter(m, caCert, notBefore, notBefore.Add(validityPeriod))
+	if err != nil {
+		return nil, err
+	}
+	if notAfter, err = caExpiryNotAfter(caCert, notBefore, notAfter, d.Get("truncate_to_ca_expiry").(bool)); err != nil {
+		return nil, err
+	}
+
+	subject := pkix.Name{}
+	if subjects := d.Get("subject").([]interface{}); len(subjects) > 0 && subjects[0] != nil {
+		subject = certificateSubject(subjects[0].(map[string]interface{}))
+	}
+	subject = subjectWithDefaults(subject, m)
+
+	algorithm := keyAlgorithmOrProviderDefault(d, m, ECDSA)
+	rsaBits := rsaBitsOrProviderDefault(d, m, 2048)
+	curve := ecdsaCurveOrProviderDefault(d, m, P256)
+	// the workers must not read d concurrently
+	options := issueOptionsFromResourceData(d)
+
+	templates := make(map[string]*x509.Certificate, len(entries))
+	for name, entry := range entries {
+		if templates[name], err = certBatchTemplate(entry, subject, d.Get("serial_number_prefix").(string), notBefore, notAfter); err != nil {
+			return nil, fmt.Errorf("certificate %q: %w", name, err)
+		}
+		if err = crlDistributionExtensions(d, templates[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make(chan string)
+	var lock sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for i := 0; i < min(runtime.NumCPU(), len(templates)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				certificate, err := certBatchIssueOne(templates[name], algorithm, rsaBits, curve, options, caCert, caKey)
+				lock.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("certificate %q: %w", name, err))
+				} else {
+					issued[name] = certificate
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for name := range templates {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to issue certificates: %s", joinErrors(errs))
+	}
+
+	certPems := make([]string, 0, len(issued))
+	for _, certificate := range issued {
+		certPems = append(certPems, certificate.certPem)
+	}
+	if err = recordIssuedCertificates(m, certPems...); err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+// certBatchIssueOne generates a key and issues the certificate of template for it with the options of the batch.
+func certBatchIssueOne(template *x509.Certificate, algorithm Algorithm, rsaBits int, curve ECDSACurve, options issueOptions, caCert *x509.Certificate, caKey crypto.PrivateKey) (certBatchIssued, error) {
+	prvKey, err := generatePrivateKey(algorithm, rsaBits, curve)
+	if err != nil {
+		return certBatchIssued{}, err
+	}
+
+	certPem, diags := issueCertificateWithOptions(options, template, prvKey.(crypto.Signer).Public(), caCert, caKey)
+	if diags.HasError() {
+		errs := make([]string, 0, len(diags))
+		for _, diagnostic := range diags {
+			errs = append(errs, diagnostic.Summary+": "+diagnostic.Detail)
+		}
+		return certBatchIssued{}, errors.New(strings.Join(errs, ", "))
+	}
+
+	return certBatchIssued{prvKey: prvKey, certPem: certPem}, nil
+}
+
+// certBatchTemplate builds the certificate template of a certificate block, with a random serial number starting with
+// serialNumberPrefix.
+func certBatchTemplate(entry map[string]interface{}, subject pkix.Name, serialNumberPrefix string, notBefore, notAfter time.Time) (*x509.Certificate, error) {
+	serialNumber, err := prefixedSerialNumber(serialNumberPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+	}
+	if template.Subject.CommonName == "" {
+		template.Subject.CommonName = entry["name"].(string)
+	}
+
+	for _, name := range entry["dns_names"].([]interface{}) {
+		ascii, err := dnsNameToASCII(name.(string))
+		if err != nil {
+			return nil, err
+		}
+		template.DNSNames = append(template.DNSNames, ascii)
+	}
+	for _, address := range entry["ip_addresses"].([]interface{}) {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(address.(string)))
+	}
+	for _, rawURI := range entry["uris"].([]interface{}) {
+		uri, err := url.Parse(rawURI.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %q: %w", rawURI, err)
+		}
+		template.URIs = append(template.URIs, uri)
+	}
+
+	name := entry["profile"].(string)
+	upn, _ := entry["user_principal_name"].(string)
+	if err = applyNamedCertificateProfile(template, name, certificateProfiles[name], upn); err != nil {
+		return nil, err
+	}
+	if err = setOtherNames(template, upn, nil); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// certBatchSave saves the keys, encoded according to the keyFormatSchema attributes and encrypted to the
+// age_recipient or pgp_key when set, and certificates of the batch as the output maps.
+func certBatchSave(d *schema.ResourceData, m interface{}, issued map[string]certBatchIssued) error {
+	values := make(map[string]map[string]interface{}, len(certBatchOutputAttributes))
+	for _, key := range certBatchOutputAttributes {
+		values[key] = make(map[string]interface{}, len(issued))
+	}
+	certPems := make(map[string]string, len(issued))
+	caCertPem := d.Get("ca_cert_pem").(string)
+	for name, certificate := range issued {
+		keys := certificate.keys
+		if certificate.prvKey != nil {
+			representations, err := keyRepresentations(d, certificate.prvKey)
+			if err != nil {
+				return fmt.Errorf("failed to encode private key of %q: %w", name, err)
+			}
+			keys = make(map[string]interface{}, len(certBatchKeyAttributes))
+			for key, value := range representations {
+				keys[key] = value
+			}
+			if err = encryptPrivateKeys(d, keys, certBatchPrivateKeys...); err != nil {
+				return fmt.Errorf("certificate %q: %w", name, err)
+			}
+		}
+		for _, key := range certBatchKeyAttributes {
+			values[key][name] = keys[key]
+		}
+
+		fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), certificate.certPem, caCertPem)
+		if err != nil {
+			return fmt.Errorf("failed to build full chain of %q: %w", name, err)
+		}
+		certText, err := certificateTextPEM(certificate.certPem)
+		if err != nil {
+			return fmt.Errorf("failed to render %q: %w", name, err)
+		}
+		certJSON, err := certificateJSONPEM(certificate.certPem)
+		if err != nil {
+			return fmt.Errorf("failed to render %q: %w", name, err)
+		}
+		values["cert_pem"][name] = certificate.certPem
+		values["cert_text"][name] = certText
+		values["cert_json"][name] = certJSON
+		values["fullchain_pem"][name] = fullChainPem
+		certPems[name] = certificate.certPem
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return certBatchSetValidity(d, m, certPems)
+}
+
+// certBatchSetValidity saves the certBatchValiditySchema maps of the certificates in PEM format, by name.
+func certBatchSetValidity(d *schema.ResourceData, m interface{}, certPems map[string]string) error {
+	values := make(map[string]map[string]interface{})
+	for name := range certBatchValiditySchema() {
+		values[name] = make(map[string]interface{}, len(certPems))
+	}
+	for name, certPem := range certPems {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return fmt.Errorf("unable to parse certificate %q: %w", name, err)
+		}
+		validity := certificateValidity("", cert, now(d, m))
+		validity["validity_start_time"] = validity["not_before"]
+		validity["validity_end_time"] = validity["not_after"]
+		for key, value := range validity {
+			values[key][name] = value
+		}
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// certBatchDrop removes the certificates called names, with their keys, from the output maps.
+func certBatchDrop(d *schema.ResourceData, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, key := range certBatchOutputAttributes {
+		values := d.Get(key).(map[string]interface{})
+		for _, name := range names {
+			delete(values, name)
+		}
+		if err := d.Set(key, values); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+func resourceCFSSLSignedCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"cfssl_url": {
+			Description:      "URL of the CFSSL server, e.g. `https://cfssl.example.com:8888`.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPorHTTPS),
+		},
+		"ca_cert_pem": {
+			Description:      "CA certificates in PEM format trusted for the TLS connection, in addition to the system roots.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"auth_key": {
+			Description:      "hex encoded key of a `standard` entry of the `auth_keys` of the server. When set, the request is authenticated with its HMAC and sent to `authsign` instead of `sign`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Sensitive:        true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(`^([0-9a-fA-F]{2})+$`), "expected a hex encoded key")),
+		},
+		"csr_pem": {
+			Description:      "certificate signing request in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"hosts": {
+			Description: "DNS names and IP addresses of the certificate, instead of the SANs of the CSR.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"profile": {
+			Description: "signing profile of the server. Defaults to the default profile.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"label": {
+			Description: "label of the signer, for multi-root servers. Defaults to the default signer.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"certificate_pem": {
+			Description: "signed certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"issuing_ca_pem": {
+			Description: "certificate of the signer returned by the `info` endpoint, in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"fullchain_pem": {
+			Description: "signed certificate followed by the issuing CA unless it is a self-signed root, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"serial_number": {
+			Description: "serial number of the signed certificate, as colon separated hex.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the signed certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	s["check_revocation"].Description += " The issuer is `issuing_ca_pem`."
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Sign a CSR with the API of a remote CFSSL server",
+		CreateContext: resourceCFSSLSignedCertCreate,
+		ReadContext:   resourceCFSSLSignedCertRead,
+		UpdateContext: resourceCFSSLSignedCertUpdate,
+		DeleteContext: resourceCFSSLSignedCertDelete,
+		CustomizeDiff: resourceCFSSLSignedCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// cfsslSignRequest is the body of the CFSSL sign and info endpoints.
+type cfsslSignRequest struct {
+	CertificateRequest string   `json:"certificate_request,omitempty"`
+	Hosts              []string `json:"hosts,omitempty"`
+	Profile            string   `json:"profile,omitempty"`
+	Label              string   `json:"label,omitempty"`
+}
+
+// cfsslAuthenticatedRequest is the body of the CFSSL authsign endpoint: the request and its HMAC, both base64 encoded.
+type cfsslAuthenticatedRequest struct {
+	Token   []byte `json:"token"`
+	Request []byte `json:"request"`
+}
+
+// cfsslResponse is the envelope of the responses of the CFSSL API.
+type cfsslResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		Certificate string `json:"certificate"`
+	} `json:"result"`
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func resourceCFSSLSignedCertCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	csrPem := d.Get("csr_pem").(string)
+	csr, err := parsePEMCertificateRequest([]byte(csrPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse csr_pem: %w", err))
+	}
+
+	signReq := cfsslSignRequest{
+		CertificateRequest: csrPem,
+		Profile:            d.Get("profile").(string),
+		Label:              d.Get("label").(string),
+	}
+	for _, host := range d.Get("hosts").([]interface{}) {
+		signReq.Hosts = append(signReq.Hosts, host.(string))
+	}
+
+	var reqBody interface{} = signReq
+	endpoint := "sign"
+	if authKey := d.Get("auth_key").(string); authKey != "" {
+		key, err := hex.DecodeString(authKey)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid auth_key: %w", err))
+		}
+		signJSON, err := json.Marshal(signReq)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to marshal request: %w", err))
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(signJSON)
+		reqBody = cfsslAuthenticatedRequest{Token: mac.Sum(nil), Request: signJSON}
+		endpoint = "authsign"
+	}
+
+	certPem, err := resourceCFSSLRequest(ctx, d, endpoint, reqBody)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign CSR with CFSSL: %w", err))
+	}
+	cert, err := parsePEMCertificate([]byte(certPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("CFSSL returned an invalid certificate: %w", err))
+	}
+	if pubKey, ok := csr.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !pubKey.Equal(cert.PublicKey) {
+		return diag.FromErr(fmt.Errorf("CFSSL returned a certificate for another public key than the one of the CSR"))
+	}
+
+	// the signer certificate is only informative: a failure to fetch it does not lose the signed certificate
+	var diags diag.Diagnostics
+	issuingCAPem, err := resourceCFSSLRequest(ctx, d, "info", cfsslSignRequest{Profile: signReq.Profile, Label: signReq.Label})
+	if err != nil {
+		issuingCAPem = ""
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Unable to fetch the certificate of the CFSSL signer",
+			Detail:   err.Error(),
+		})
+	}
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), certPem, issuingCAPem)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build full chain: %w", err))
+	}
+	certText, err := certificateTextPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	certJSON, err := certificateJSONPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serialNumber := colonHex(cert.SerialNumber.Bytes())
+	d.SetId(serialNumber)
+
+	values := map[string]interface{}{
+		"certificate_pem": certPem,
+		"cert_text":       certText,
+		"cert_json":       certJSON,
+		"issuing_ca_pem":  issuingCAPem,
+		"fullchain_pem":   fullChainPem,
+		"serial_number":   serialNumber,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+	if err = setCertificateValidity(d, m, "", certPem); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceCFSSLSignedCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := setCertificateValidity(d, m, "", d.Get("certificate_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+	issuingCAPem := d.Get("issuing_ca_pem").(string)
+	if issuingCAPem == "" {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Unable to check the revocation status of the certificate",
+			Detail:   "The certificate of the CFSSL signer could not be fetched, so the issuer of the certificate is unknown.",
+		}}
+	}
+
+	client, err := newHTTPClient(d.Get("ca_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("certificate_pem").(string), issuingCAPem)
+}
+
+func resourceCFSSLSignedCertUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// The other in-place attributes are only used by later requests to CFSSL.
+	return diag.FromErr(updateFullChain(d, m, d.Get("issuing_ca_pem").(string)))
+}
+
+func resourceCFSSLSignedCertDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceCFSSLSignedCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	return planFullChains(diff, "fullchain_pem")
+}
+
+// resourceCFSSLRequest sends a request to the given endpoint of the CFSSL API and returns the certificate of its result.
+func resourceCFSSLRequest(ctx context.Context, d *schema.ResourceData, endpoint string, reqBody interface{}) (string, error) {
+	client, err := newHTTPClient(d.Get("ca_cert_pem").(string))
+	if err != nil {
+		return "", fmt.Errorf("failed to configure CFSSL client: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/cfssl/%s", strings.TrimRight(d.Get("cfssl_url").(string), "/"), endpoint)
+
+	var resp cfsslResponse
+	if err = doJSONRequest(ctx, client, http.MethodPost, url, nil, reqBody, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		messages := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			messages = append(messages, fmt.Sprintf("%s (code %d)", e.Message, e.Code))
+		}
+		return "", fmt.Errorf("CFSSL %s failed: %s", endpoint, strings.Join(messages, ", "))
+	}
+
+	return resp.Result.Certificate, nil
+}
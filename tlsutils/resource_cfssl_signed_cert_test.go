@@ -0,0 +1,143 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCFSSLAuthKey is the hex encoded auth key of the authsign endpoint of testCFSSLServer.
+const testCFSSLAuthKey = "0123456789abcdef0123456789abcdef"
+
+// testCFSSLServer serves the sign, authsign and info endpoints of the CFSSL API for the server profile, signing
+// certificates valid for 24 hours with caCert and caKey.
+func testCFSSLServer(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fail := func(code int, message string) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": code, "message": message}}})
+		}
+		var req cfsslSignRequest
+		switch r.URL.Path {
+		case "/api/v1/cfssl/sign", "/api/v1/cfssl/info":
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				fail(1000, err.Error())
+				return
+			}
+		case "/api/v1/cfssl/authsign":
+			var authReq cfsslAuthenticatedRequest
+			if err := json.NewDecoder(r.Body).Decode(&authReq); err != nil {
+				fail(1000, err.Error())
+				return
+			}
+			key, _ := hex.DecodeString(testCFSSLAuthKey)
+			mac := hmac.New(sha256.New, key)
+			mac.Write(authReq.Request)
+			if !hmac.Equal(mac.Sum(nil), authReq.Token) {
+				fail(2400, "invalid token")
+				return
+			}
+			if err := json.Unmarshal(authReq.Request, &req); err != nil {
+				fail(1000, err.Error())
+				return
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if req.Profile != "server" {
+			fail(5100, "unknown profile")
+			return
+		}
+
+		certPem := certificateToPEM(caCert)
+		if r.URL.Path != "/api/v1/cfssl/info" {
+			cert, err := testSignCertificateRequest(caCert, caKey, req.CertificateRequest, 24*time.Hour)
+			if err != nil {
+				fail(1000, err.Error())
+				return
+			}
+			certPem = certificateToPEM(cert)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"certificate": certPem}})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestResourceCFSSLSignedCert(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "CFSSL CA", nil, nil)
+	server := testCFSSLServer(t, ca, caKey)
+	config := map[string]interface{}{
+		"cfssl_url": server.URL,
+		"csr_pem":   testCertificateRequest(t, "cfssl.example.com"),
+		"profile":   "server",
+	}
+
+	r := resourceCFSSLSignedCert()
+	for _, authKey := range []string{"", testCFSSLAuthKey} {
+		config["auth_key"] = authKey
+		state := testResourceApply(t, r, nil, config, &providerMeta{})
+		cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+		if err != nil {
+			t.Fatalf("unable to parse certificate_pem: %s", err)
+		}
+		if cert.Subject.CommonName != "cfssl.example.com" || state.ID != colonHex(cert.SerialNumber.Bytes()) {
+			t.Errorf("expected the certificate of the CSR with its serial number as ID, got %s with ID %s", cert.Subject, state.ID)
+		}
+		if state.Attributes["issuing_ca_pem"] != certificateToPEM(ca) || state.Attributes["fullchain_pem"] != state.Attributes["certificate_pem"] {
+			t.Errorf("expected the signer certificate as issuing CA, left out of the full chain")
+		}
+	}
+
+	config["auth_key"] = "abcdef"
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "invalid token (code 2400)") {
+		t.Errorf("expected a wrong auth_key to be refused, got %v", diags)
+	}
+}
+
+func TestResourceCFSSLSignedCertRevocation(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "CFSSL CA", nil, nil)
+	sources := testRevocationSources{ocspThisUpdate: time.Now().Add(-time.Hour), ocspNextUpdate: time.Now().Add(time.Hour), crlThisUpdate: time.Now().Add(-time.Hour), crlNextUpdate: time.Now().Add(time.Hour)}
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	attributes := func(serialNumber *big.Int, issuingCAPem string) map[string]string {
+		return map[string]string{
+			"certificate_pem": certificateToPEM(testRevocationCertificate(t, server, serialNumber, issuer, issuerKey)),
+			"issuing_ca_pem":  issuingCAPem,
+		}
+	}
+
+	r := resourceCFSSLSignedCert()
+	state, diags := testRefreshRevocation(t, r, attributes(testRevokedSerialNumber, certificateToPEM(issuer)))
+	if state != nil || len(diags) != 1 || !strings.Contains(diags[0].Summary, "serial number 42 has been revoked") {
+		t.Errorf("expected a revoked certificate to be removed from the state, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(big.NewInt(43), certificateToPEM(issuer)))
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected a good certificate to be kept, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(testRevokedSerialNumber, ""))
+	if state == nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "issuer of the certificate is unknown") {
+		t.Errorf("expected a warning without issuing CA, got %v", diags)
+	}
+}
@@ -0,0 +1,223 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// oidExtensionCTPoison is the critical poison extension marking a precertificate, see RFC 6962 section 3.1.
+var oidExtensionCTPoison = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+
+func resourceCTSubmission() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Submit a certificate or precertificate to Certificate Transparency logs and record the returned SCTs",
+		CreateContext: resourceCTSubmissionCreate,
+		ReadContext:   resourceCTSubmissionRead,
+		DeleteContext: resourceCTSubmissionDelete,
+		Schema: map[string]*schema.Schema{
+			"certificate_pem": {
+				Description:      "certificate or precertificate in PEM format.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"chain_pem": {
+				Description:      "issuer chain of the certificate in PEM format, up to a root accepted by the logs.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"log_urls": {
+				Description: "base URLs of the CT logs, e.g. `https://ct.googleapis.com/logs/us1/argon2025h1`.",
+				Type:        schema.TypeList,
+				Required:    true,
+				ForceNew:    true,
+				MinItems:    1,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"precertificate": {
+				Description: "true if the certificate is a precertificate, submitted with add-pre-chain.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"scts": {
+				Description: "signed certificate timestamps returned by each log, in the order of `log_urls`.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"log_url": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"sct_version": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"log_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"timestamp": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"extensions": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"signature": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"sct_list_base64": {
+				Description: "base64 encoded SignedCertificateTimestampList (RFC 6962 section 3.3), for TLS or OCSP stapling.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// ctAddChainResponse is the response of the add-chain and add-pre-chain endpoints, see RFC 6962 section 4.1.
+type ctAddChainResponse struct {
+	SCTVersion int    `json:"sct_version"`
+	ID         string `json:"id"`
+	Timestamp  uint64 `json:"timestamp"`
+	Extensions string `json:"extensions"`
+	Signature  string `json:"signature"`
+}
+
+func resourceCTSubmissionCreate(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	cert, err := parsePEMCertificate([]byte(d.Get("certificate_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+
+	chainCerts, err := parsePEMCertificates([]byte(d.Get("chain_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse chain_pem: %w", err))
+	}
+	if len(chainCerts) == 0 {
+		return diag.FromErr(fmt.Errorf("chain_pem does not contain any certificate"))
+	}
+
+	precertificate := false
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtensionCTPoison) {
+			precertificate = true
+		}
+	}
+
+	chain := []string{base64.StdEncoding.EncodeToString(cert.Raw)}
+	for _, chainCert := range chainCerts {
+		chain = append(chain, base64.StdEncoding.EncodeToString(chainCert.Raw))
+	}
+
+	endpoint := "add-chain"
+	if precertificate {
+		endpoint = "add-pre-chain"
+	}
+
+	client, err := newHTTPClient("")
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	scts := make([]map[string]interface{}, 0)
+	sctList := make([]byte, 0)
+	for _, logURL := range d.Get("log_urls").([]interface{}) {
+		logURL := strings.TrimRight(logURL.(string), "/")
+
+		var resp ctAddChainResponse
+		err := doJSONRequest(ctx, client, http.MethodPost, logURL+"/ct/v1/"+endpoint, nil, map[string][]string{"chain": chain}, &resp)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to submit certificate to %s: %w", logURL, err))
+		}
+
+		sct, err := ctSerializeSCT(&resp)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid SCT returned by %s: %w", logURL, err))
+		}
+		sctList = binary.BigEndian.AppendUint16(sctList, uint16(len(sct)))
+		sctList = append(sctList, sct...)
+
+		scts = append(scts, map[string]interface{}{
+			"log_url":     logURL,
+			"sct_version": resp.SCTVersion,
+			"log_id":      resp.ID,
+			"timestamp":   strconv.FormatUint(resp.Timestamp, 10),
+			"extensions":  resp.Extensions,
+			"signature":   resp.Signature,
+		})
+	}
+	sctList = append(binary.BigEndian.AppendUint16(nil, uint16(len(sctList))), sctList...)
+
+	d.SetId(hashForState(string(cert.Raw)))
+
+	if err = d.Set("precertificate", precertificate); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save precertificate: %w", err))
+	}
+	if err = d.Set("scts", scts); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save scts: %w", err))
+	}
+	if err = d.Set("sct_list_base64", base64.StdEncoding.EncodeToString(sctList)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save sct_list_base64: %w", err))
+	}
+
+	return nil
+}
+
+func resourceCTSubmissionRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceCTSubmissionDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+// ctSerializeSCT returns the TLS encoding of a SignedCertificateTimestamp, see RFC 6962 section 3.2.
+// The signature returned by the logs is already a TLS encoded DigitallySigned struct.
+func ctSerializeSCT(resp *ctAddChainResponse) ([]byte, error) {
+	logID, err := base64.StdEncoding.DecodeString(resp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode log id: %w", err)
+	}
+	if len(logID) != 32 {
+		return nil, fmt.Errorf("log id should be 32 bytes, got %d", len(logID))
+	}
+
+	extensions, err := base64.StdEncoding.DecodeString(resp.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode extensions: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	sct := []byte{byte(resp.SCTVersion)}
+	sct = append(sct, logID...)
+	sct = binary.BigEndian.AppendUint64(sct, resp.Timestamp)
+	sct = binary.BigEndian.AppendUint16(sct, uint16(len(extensions)))
+	sct = append(sct, extensions...)
+	sct = append(sct, signature...)
+
+	return sct, nil
+}
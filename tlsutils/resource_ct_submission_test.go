@@ -0,0 +1,77 @@
+package tlsutils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResourceCTSubmission(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	cert, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, ca, caKey)
+	precert, _ := testCertificate(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "www.example.com"},
+		ExtraExtensions: []pkix.Extension{{Id: oidExtensionCTPoison, Critical: true, Value: []byte{0x05, 0x00}}},
+	}, ca, caKey)
+
+	logID := bytes.Repeat([]byte{0x01}, 32)
+	var paths []string
+	var chain []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var req struct {
+			Chain []string `json:"chain"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %s", err)
+		}
+		chain = req.Chain
+		_ = json.NewEncoder(w).Encode(ctAddChainResponse{
+			ID:        base64.StdEncoding.EncodeToString(logID),
+			Timestamp: 1700000000000,
+			Signature: base64.StdEncoding.EncodeToString([]byte{0x04, 0x03, 0x00, 0x02, 0xab, 0xcd}),
+		})
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"certificate_pem": certificateToPEM(cert),
+		"chain_pem":       certificateToPEM(ca),
+		"log_urls":        []interface{}{server.URL + "/log1/", server.URL + "/log2"},
+	}
+	state := testResourceApply(t, resourceCTSubmission(), nil, config, &providerMeta{})
+	if len(paths) != 2 || paths[0] != "/log1/ct/v1/add-chain" || paths[1] != "/log2/ct/v1/add-chain" {
+		t.Errorf("expected add-chain on both logs, got %v", paths)
+	}
+	if len(chain) != 2 || chain[0] != base64.StdEncoding.EncodeToString(cert.Raw) || chain[1] != base64.StdEncoding.EncodeToString(ca.Raw) {
+		t.Errorf("expected the certificate followed by its chain, got %v", chain)
+	}
+	if state.Attributes["precertificate"] != "false" || state.Attributes["scts.#"] != "2" || state.Attributes["scts.0.timestamp"] != "1700000000000" {
+		t.Errorf("expected two SCTs of a certificate, got %v", state.Attributes)
+	}
+	if state.Attributes["scts.1.log_url"] != server.URL+"/log2" {
+		t.Errorf("expected the log URL without trailing slash, got %s", state.Attributes["scts.1.log_url"])
+	}
+
+	// list length, then each SCT with its length: version, log id, timestamp, no extensions and the signature
+	sct := append([]byte{0x00}, logID...)
+	sct = append(sct, 0x00, 0x00, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x00, 0x00, 0x00, 0x04, 0x03, 0x00, 0x02, 0xab, 0xcd)
+	entry := append([]byte{0x00, byte(len(sct))}, sct...)
+	list := append([]byte{0x00, byte(2 * len(entry))}, append(entry, entry...)...)
+	if got, _ := base64.StdEncoding.DecodeString(state.Attributes["sct_list_base64"]); !bytes.Equal(got, list) {
+		t.Errorf("expected the SignedCertificateTimestampList %x, got %x", list, got)
+	}
+
+	paths = nil
+	config["certificate_pem"] = certificateToPEM(precert)
+	config["log_urls"] = []interface{}{server.URL}
+	state = testResourceApply(t, resourceCTSubmission(), nil, config, &providerMeta{})
+	if len(paths) != 1 || paths[0] != "/ct/v1/add-pre-chain" || state.Attributes["precertificate"] != "true" {
+		t.Errorf("expected the precertificate to be submitted with add-pre-chain, got %v", paths)
+	}
+}
@@ -0,0 +1,159 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"math/big"
+	"time"
+)
+
+// dhParamsGenerator is the generator of the parameters. With a prime p ≡ 23 mod 24,
+// 2 generates the subgroup of prime order (p-1)/2, like `openssl dhparam` does.
+const dhParamsGenerator = 2
+
+func resourceDHParams() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Generate Diffie-Hellman parameters with a safe prime",
+		CreateContext: resourceDHParamsCreate,
+		ReadContext:   resourceDHParamsRead,
+		DeleteContext: resourceDHParamsDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+		},
+		Schema: map[string]*schema.Schema{
+			"bits": {
+				Description:      "size of the prime in bits: 2048, 3072 or 4096. Larger sizes can take several minutes to generate.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          2048,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntInSlice([]int{2048, 3072, 4096})),
+			},
+			"params_pem": {
+				Description: "parameters in PEM format (`DH PARAMETERS`), as read by nginx `ssl_dhparam` and `openssl dhparam`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"generator": {
+				Description: "generator of the parameters.",
+				Type:        schema.TypeInt,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourceDHParamsCreate(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	prime, err := generateSafePrime(ctx, d.Get("bits").(int))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	der, err := asn1.Marshal(struct {
+		P *big.Int
+		G int
+	}{prime, dhParamsGenerator})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode DH parameters: %w", err))
+	}
+	paramsPem := string(pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: der}))
+
+	d.SetId(hashForState(paramsPem))
+
+	if err = d.Set("params_pem", paramsPem); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save params_pem: %w", err))
+	}
+	if err = d.Set("generator", dhParamsGenerator); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save generator: %w", err))
+	}
+
+	return nil
+}
+
+func resourceDHParamsRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceDHParamsDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+// generateSafePrime returns a prime p of the given size such that (p-1)/2 is also prime and p ≡ 23 mod 24.
+// Candidates are sieved by small primes, for both q and 2q+1, before the probabilistic tests.
+func generateSafePrime(ctx context.Context, bits int) (*big.Int, error) {
+	smallPrimes := dhSmallPrimes(4096)
+	residues := make([]uint64, len(smallPrimes))
+	one := big.NewInt(1)
+	twelve := big.NewInt(12)
+
+	for {
+		// q has bits-1 bits with the two top bits set, so p = 2q+1 always has exactly bits bits.
+		q, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(bits-3)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate DH prime candidate: %w", err)
+		}
+		q.SetBit(q, bits-2, 1)
+		q.SetBit(q, bits-3, 1)
+		// q ≡ 11 mod 12 makes q odd, not a multiple of 3, and p ≡ 23 mod 24.
+		q.Sub(q, new(big.Int).Mod(q, twelve))
+		q.Add(q, big.NewInt(11))
+
+		for i, sp := range smallPrimes {
+			residues[i] = new(big.Int).Mod(q, new(big.Int).SetUint64(sp)).Uint64()
+		}
+
+	candidates:
+		for delta := uint64(0); delta < 1<<20; delta += 12 {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("failed to generate %d bits DH prime: %w", bits, err)
+			}
+
+			for i, sp := range smallPrimes {
+				r := (residues[i] + delta) % sp
+				if r == 0 || (2*r+1)%sp == 0 {
+					continue candidates
+				}
+			}
+
+			candidate := new(big.Int).Add(q, new(big.Int).SetUint64(delta))
+			if candidate.BitLen() != bits-1 || !candidate.ProbablyPrime(0) {
+				continue
+			}
+			p := new(big.Int).Lsh(candidate, 1)
+			p.Add(p, one)
+			if !p.ProbablyPrime(0) {
+				continue
+			}
+			if candidate.ProbablyPrime(20) && p.ProbablyPrime(20) {
+				return p, nil
+			}
+		}
+	}
+}
+
+// dhSmallPrimes returns the primes from 5 to limit, used to sieve safe prime candidates.
+func dhSmallPrimes(limit int) []uint64 {
+	composite := make([]bool, limit+1)
+	var primes []uint64
+	for i := 2; i <= limit; i++ {
+		if composite[i] {
+			continue
+		}
+		if i > 3 {
+			primes = append(primes, uint64(i))
+		}
+		for j := i * i; j <= limit; j += i {
+			composite[j] = true
+		}
+	}
+
+	return primes
+}
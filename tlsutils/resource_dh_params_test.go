@@ -0,0 +1,52 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"math/big"
+	"testing"
+)
+
+func TestResourceDHParamsSafePrime(t *testing.T) {
+	// sizes far below the allowed ones, for a fast test; the schema validation is bypassed
+	for _, bits := range []int{64, 128, 256} {
+		d := schema.TestResourceDataRaw(t, resourceDHParams().Schema, map[string]interface{}{"bits": bits})
+		if diags := resourceDHParamsCreate(context.Background(), d, &providerMeta{}); diags.HasError() {
+			t.Fatalf("create failed: %v", diags)
+		}
+
+		block, rest := pem.Decode([]byte(d.Get("params_pem").(string)))
+		if block == nil || block.Type != "DH PARAMETERS" || len(rest) > 0 {
+			t.Fatalf("expected a single DH PARAMETERS PEM block, got %q", d.Get("params_pem"))
+		}
+		// PKCS #3 DHParameter, without the optional privateValueLength
+		var params struct {
+			P *big.Int
+			G int
+		}
+		if rest, err := asn1.Unmarshal(block.Bytes, &params); err != nil || len(rest) > 0 {
+			t.Fatalf("unable to parse the PKCS #3 parameters: %v", err)
+		}
+
+		p := params.P
+		q := new(big.Int).Rsh(p, 1)
+		if p.BitLen() != bits {
+			t.Errorf("expected a prime of %d bits, got %d", bits, p.BitLen())
+		}
+		if !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+			t.Errorf("expected p and (p-1)/2 to be prime, p = %s", p)
+		}
+		if r := new(big.Int).Mod(p, big.NewInt(24)).Int64(); r != 23 {
+			t.Errorf("expected p mod 24 = 23, got %d", r)
+		}
+		if params.G != dhParamsGenerator || d.Get("generator").(int) != dhParamsGenerator {
+			t.Errorf("expected generator %d, got %d and %d", dhParamsGenerator, params.G, d.Get("generator"))
+		}
+		// 2 is a quadratic residue modulo p ≡ 7 mod 8, so it generates the subgroup of order q
+		if new(big.Int).Exp(big.NewInt(dhParamsGenerator), q, p).Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("expected the generator to have order (p-1)/2")
+		}
+	}
+}
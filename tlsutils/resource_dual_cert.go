@@ -0,0 +1,413 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+// dualCertPrivateKeys are the attributes holding the private keys, encrypted to age_recipient or pgp_key when set.
+var dualCertPrivateKeys = []string{
+	"ecdsa_private_key_pem", "rsa_private_key_pem", "ecdsa_combined_pem", "rsa_combined_pem", "ecdsa_private_key_openssh", "rsa_private_key_openssh",
+}
+
+func resourceDualCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"ca_cert_pem": {
+			Description:      "certificate of the CA signing both certificates, in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"ca_private_key_pem": {
+			Description:      "private key of the CA in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			Sensitive:        true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"ecdsa_curve": {
+			Description:      "elliptic curve of the ECDSA key. Defaults to the `default_ecdsa_curve` of the provider, then `P256`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+		},
+		"rsa_bits": {
+			Description:      "size of the RSA key in bits. Defaults to the `default_rsa_bits` of the provider, then 2048.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+		},
+		"ecdsa_private_key_pem": {
+			Description: "ECDSA private key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"ecdsa_cert_pem": {
+			Description: "certificate of the ECDSA key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ecdsa_cert_text": {
+			Description: "`ecdsa_cert_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ecdsa_cert_json": {
+			Description: "`ecdsa_cert_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rsa_private_key_pem": {
+			Description: "RSA private key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"rsa_cert_pem": {
+			Description: "certificate of the RSA key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rsa_cert_text": {
+			Description: "`rsa_cert_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rsa_cert_json": {
+			Description: "`rsa_cert_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ecdsa_fullchain_pem": {
+			Description: "ECDSA certificate followed by the CA certificate unless it is self-signed, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ecdsa_combined_pem": {
+			Description: "ECDSA private key followed by `ecdsa_fullchain_pem`, for HAProxy `crt`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"rsa_fullchain_pem": {
+			Description: "RSA certificate followed by the CA certificate unless it is self-signed, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rsa_combined_pem": {
+			Description: "RSA private key followed by `rsa_fullchain_pem`, for HAProxy `crt`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"ecdsa_private_key_openssh": {
+			Description: "ECDSA private key in OpenSSH format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"ecdsa_public_key_openssh": {
+			Description: "ECDSA public key in OpenSSH authorized_keys format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"rsa_private_key_openssh": {
+			Description: "RSA private key in OpenSSH format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"rsa_public_key_openssh": {
+			Description: "RSA public key in OpenSSH authorized_keys format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateIssueSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range keyFormatSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range privateKeyEncryptionSchema(true, dualCertPrivateKeys...) {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range chainSchema("`ecdsa_fullchain_pem`, `rsa_fullchain_pem` and the combined outputs") {
+		s[name] = attribute
+	}
+	s["allowed_uses"].Description += " `key_encipherment` only applies to the RSA certificate."
+	s["subject_key_id"].Description += " Both certificates get it although their keys differ."
+	warnWeakParameters(s, dualCertWeakParameters)
+
+	return &schema.Resource{
+		Description:   "Generate an ECDSA and an RSA key with certificates for the same subject and names, signed by the same CA",
+		CreateContext: resourceDualCertCreate,
+		ReadContext:   resourceDualCertRead,
+		UpdateContext: resourceDualCertUpdate,
+		DeleteContext: resourceDualCertDelete,
+		CustomizeDiff: resourceDualCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+func resourceDualCertCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	caCert, caKey, err := parseCertificateAuthority(d.Get("ca_cert_pem").(string), d.Get("ca_private_key_pem").(string), now(d, m))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	curve := ecdsaCurveOrProviderDefault(d, m, P256)
+	ecdsaKey, err := generatePrivateKey(ECDSA, 0, curve)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rsaBits := rsaBitsOrProviderDefault(d, m, 2048)
+	rsaKey, err := generatePrivateKey(RSA, rsaBits, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ecdsaTemplate, err := certificateTemplate(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	ecdsaTemplate.KeyUsage &^= keyUsages["key_encipherment"]
+	if ecdsaTemplate.NotAfter, err = issuancePolicyNotAfter(m, caCert, ecdsaTemplate.NotBefore, ecdsaTemplate.NotAfter); err != nil {
+		return diag.FromErr(err)
+	}
+	if ecdsaTemplate.NotAfter, err = caExpiryNotAfter(caCert, ecdsaTemplate.NotBefore, ecdsaTemplate.NotAfter, d.Get("truncate_to_ca_expiry").(bool)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	rsaTemplate, err := certificateTemplate(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// both certificates share the validity of the first one
+	rsaTemplate.NotBefore, rsaTemplate.NotAfter = ecdsaTemplate.NotBefore, ecdsaTemplate.NotAfter
+
+	ecdsaKeys, err := keyRepresentations(d, ecdsaKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode ECDSA private key: %w", err))
+	}
+
+	rsaKeys, err := keyRepresentations(d, rsaKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode RSA private key: %w", err))
+	}
+
+	ecdsaCertPem, diags := issueCertificate(d, ecdsaTemplate, ecdsaKey.(crypto.Signer).Public(), caCert, caKey)
+	if diags.HasError() {
+		return diags
+	}
+
+	rsaCertPem, diags := issueCertificate(d, rsaTemplate, rsaKey.(crypto.Signer).Public(), caCert, caKey)
+	if diags.HasError() {
+		return diags
+	}
+
+	ecdsaFullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), ecdsaCertPem, d.Get("ca_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build ECDSA full chain: %w", err))
+	}
+
+	rsaFullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), rsaCertPem, d.Get("ca_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build RSA full chain: %w", err))
+	}
+
+	ecdsaCertText, err := certificateTextPEM(ecdsaCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ecdsaCertJSON, err := certificateJSONPEM(ecdsaCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rsaCertText, err := certificateTextPEM(rsaCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	rsaCertJSON, err := certificateJSONPEM(rsaCertPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err = recordIssuedCertificates(m, ecdsaCertPem, rsaCertPem); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%s-%s", ecdsaTemplate.SerialNumber.Text(16), rsaTemplate.SerialNumber.Text(16)))
+
+	values := map[string]interface{}{
+		"ecdsa_curve":         curve.String(),
+		"rsa_bits":            rsaBits,
+		"ecdsa_cert_pem":      ecdsaCertPem,
+		"rsa_cert_pem":        rsaCertPem,
+		"ecdsa_cert_text":     ecdsaCertText,
+		"ecdsa_cert_json":     ecdsaCertJSON,
+		"rsa_cert_text":       rsaCertText,
+		"rsa_cert_json":       rsaCertJSON,
+		"ecdsa_fullchain_pem": ecdsaFullChainPem,
+		"ecdsa_combined_pem":  ecdsaKeys["private_key_pem"] + ecdsaFullChainPem,
+		"rsa_fullchain_pem":   rsaFullChainPem,
+		"rsa_combined_pem":    rsaKeys["private_key_pem"] + rsaFullChainPem,
+		"validity_start_time": ecdsaTemplate.NotBefore.Format(time.RFC3339),
+		"validity_end_time":   ecdsaTemplate.NotAfter.Format(time.RFC3339),
+	}
+	for key, value := range certificateValidity("", ecdsaTemplate, now(d, m)) {
+		values[key] = value
+	}
+	for suffix, value := range ecdsaKeys {
+		values["ecdsa_"+suffix] = value
+	}
+	for suffix, value := range rsaKeys {
+		values["rsa_"+suffix] = value
+	}
+	if err = encryptPrivateKeys(d, values, dualCertPrivateKeys...); err != nil {
+		return diag.FromErr(err)
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourceDualCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := setCertificateValidity(d, m, "", d.Get("ecdsa_cert_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+
+	client, err := newHTTPClient("")
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	var diags diag.Diagnostics
+	for _, key := range []string{"ecdsa_cert_pem", "rsa_cert_pem"} {
+		diags = append(diags, refreshRevocationStatus(ctx, d, m, client, d.Get(key).(string), d.Get("ca_cert_pem").(string))...)
+		// a revoked certificate issues both again
+		if d.Id() == "" || diags.HasError() {
+			break
+		}
+	}
+
+	return diags
+}
+
+// resourceDualCertUpdate re-encodes both keys and rebuilds the full chains, the keyFormatSchema and chainSchema
+// attributes being the only ones changing in-place with check_revocation, which takes effect on the next refresh.
+func resourceDualCertUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.HasChanges(append([]string{"private_key_format", "openssh_output"}, chainAttributes...)...) {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, prefix := range []string{"ecdsa_", "rsa_"} {
+		fullChainPem := d.Get(prefix + "fullchain_pem").(string)
+		if d.HasChanges(chainAttributes...) {
+			var err error
+			if fullChainPem, err = fullChainPEM(chainOptionsFromResourceData(d, m), d.Get(prefix+"cert_pem").(string), d.Get("ca_cert_pem").(string)); err != nil {
+				return diag.FromErr(fmt.Errorf("failed to build %sfullchain_pem: %w", prefix, err))
+			}
+			values[prefix+"fullchain_pem"] = fullChainPem
+		}
+
+		// keyFormatSetNewComputed left the new encoding unknown, the key is in the prior state
+		privateKeyPem, _ := d.GetChange(prefix + "private_key_pem")
+		prvKey, _, err := parsePrivateKeyPEM([]byte(privateKeyPem.(string)))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse %sprivate_key_pem: %w", prefix, err))
+		}
+
+		keys, err := keyRepresentations(d, prvKey)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to encode %sprivate_key_pem: %w", prefix, err))
+		}
+		for suffix, value := range keys {
+			values[prefix+suffix] = value
+		}
+		values[prefix+"combined_pem"] = keys["private_key_pem"] + fullChainPem
+	}
+
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourceDualCertDelete(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := supersedeIssuedCertificates(m, now(d, m), d.Get("ecdsa_cert_pem").(string), d.Get("rsa_cert_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+// dualCertWeakParameters are the checks of the weak parameters of tlsutils_dual_cert.
+var dualCertWeakParameters = map[string]weakParameterCheck{
+	"ca_cert_pem":           weakCertificates,
+	"validity_period_hours": weakServerValidityHours,
+	"validity_period":       weakServerValidityPeriod,
+}
+
+func resourceDualCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := checkWeakParameters(diff, m, dualCertWeakParameters); err != nil {
+		return err
+	}
+
+	parameters := map[string]interface{}{
+		"ecdsa_curve": ecdsaCurveOrProviderDefault(diff, m, P256).String(),
+		"rsa_bits":    rsaBitsOrProviderDefault(diff, m, 2048),
+	}
+	if err := planKeyParameters(diff, parameters, "ecdsa_curve", "rsa_bits"); err != nil {
+		return err
+	}
+
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if diff.HasChange("private_key_format") {
+		for _, computed := range []string{"ecdsa_combined_pem", "rsa_combined_pem"} {
+			if err := diff.SetNewComputed(computed); err != nil {
+				return err
+			}
+		}
+	}
+	if err := planFullChains(diff, "ecdsa_fullchain_pem", "ecdsa_combined_pem", "rsa_fullchain_pem", "rsa_combined_pem"); err != nil {
+		return err
+	}
+
+	return keyFormatSetNewComputed(diff, "ecdsa_", "rsa_")
+}
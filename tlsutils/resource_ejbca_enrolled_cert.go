@@ -0,0 +1,288 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ejbcaRevocationReasons are the reasons accepted by the EJBCA revoke endpoint.
+var ejbcaRevocationReasons = []string{
+	"UNSPECIFIED", "KEY_COMPROMISE", "CA_COMPROMISE", "AFFILIATION_CHANGED", "SUPERSEDED",
+	"CESSATION_OF_OPERATION", "CERTIFICATE_HOLD", "PRIVILEGES_WITHDRAWN", "AA_COMPROMISE",
+}
+
+func resourceEJBCAEnrolledCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"csr_pem": {
+			Description:      "certificate signing request in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"certificate_profile_name": {
+			Description: "EJBCA certificate profile.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"end_entity_profile_name": {
+			Description: "EJBCA end entity profile.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"certificate_authority_name": {
+			Description: "name of the EJBCA CA signing the CSR.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"username": {
+			Description: "username of the end entity, created by the enrollment when it does not exist.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"enrollment_code": {
+			Description: "enrollment code (password) of the end entity.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Sensitive:   true,
+		},
+		"email": {
+			Description: "email address of the end entity.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"revoke_on_destroy": {
+			Description: "revoke the certificate in EJBCA when the resource is destroyed.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+		"revocation_reason": {
+			Description:      "reason of the revocation on destroy: " + strings.Join(ejbcaRevocationReasons, ", ") + ".",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          "UNSPECIFIED",
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(ejbcaRevocationReasons, false)),
+		},
+		"certificate_pem": {
+			Description: "signed certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ca_chain_pem": {
+			Description: "CA chain returned by EJBCA, one certificate per element in PEM format.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"fullchain_pem": {
+			Description: "signed certificate followed by the CA chain without self-signed roots, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"issuer_dn": {
+			Description: "issuer distinguished name of the signed certificate, as used by the EJBCA API.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"serial_number": {
+			Description: "serial number of the signed certificate in hex, as used by the EJBCA API.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range ejbcaSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range certificateValiditySchema("", "the signed certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	s["check_revocation"].Description += " The issuer is the first certificate of `ca_chain_pem`."
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Enroll a CSR with the REST API of EJBCA",
+		CreateContext: resourceEJBCAEnrolledCertCreate,
+		ReadContext:   resourceEJBCAEnrolledCertRead,
+		UpdateContext: resourceEJBCAEnrolledCertUpdate,
+		DeleteContext: resourceEJBCAEnrolledCertDelete,
+		CustomizeDiff: resourceEJBCAEnrolledCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// ejbcaEnrollRequest is the body of the EJBCA pkcs10enroll endpoint.
+type ejbcaEnrollRequest struct {
+	CertificateRequest       string `json:"certificate_request"`
+	CertificateProfileName   string `json:"certificate_profile_name"`
+	EndEntityProfileName     string `json:"end_entity_profile_name"`
+	CertificateAuthorityName string `json:"certificate_authority_name"`
+	Username                 string `json:"username"`
+	Password                 string `json:"password,omitempty"`
+	Email                    string `json:"email,omitempty"`
+	IncludeChain             bool   `json:"include_chain"`
+}
+
+func resourceEJBCAEnrolledCertCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	csrPem := d.Get("csr_pem").(string)
+	csr, err := parsePEMCertificateRequest([]byte(csrPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse csr_pem: %w", err))
+	}
+
+	reqBody := ejbcaEnrollRequest{
+		CertificateRequest:       csrPem,
+		CertificateProfileName:   d.Get("certificate_profile_name").(string),
+		EndEntityProfileName:     d.Get("end_entity_profile_name").(string),
+		CertificateAuthorityName: d.Get("certificate_authority_name").(string),
+		Username:                 d.Get("username").(string),
+		Password:                 d.Get("enrollment_code").(string),
+		Email:                    d.Get("email").(string),
+		IncludeChain:             true,
+	}
+	var resp ejbcaCertificate
+	if err = ejbcaRequest(ctx, d, http.MethodPost, "v1/certificate/pkcs10enroll", reqBody, &resp); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to enroll CSR with EJBCA: %w", err))
+	}
+
+	cert, err := parseEJBCACertificate(resp.Certificate, resp.ResponseFormat)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("EJBCA returned an invalid certificate: %w", err))
+	}
+	if pubKey, ok := csr.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !pubKey.Equal(cert.PublicKey) {
+		return diag.FromErr(fmt.Errorf("EJBCA returned a certificate for another public key than the one of the CSR"))
+	}
+
+	certPem := certificateToPEM(cert)
+	chainPems := make([]string, 0, len(resp.CertificateChain))
+	for i, raw := range resp.CertificateChain {
+		chainCert, err := parseEJBCACertificate(raw, resp.ResponseFormat)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("EJBCA returned an invalid certificate_chain.%d: %w", i, err))
+		}
+		// some versions start the chain with the signed certificate itself
+		if i == 0 && chainCert.Equal(cert) {
+			continue
+		}
+		chainPems = append(chainPems, certificateToPEM(chainCert))
+	}
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), certPem, chainPems...)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build full chain: %w", err))
+	}
+	certText, err := certificateTextPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	certJSON, err := certificateJSONPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serialNumber := cert.SerialNumber.Text(16)
+	d.SetId(serialNumber)
+
+	values := map[string]interface{}{
+		"certificate_pem": certPem,
+		"cert_text":       certText,
+		"cert_json":       certJSON,
+		"ca_chain_pem":    chainPems,
+		"fullchain_pem":   fullChainPem,
+		"issuer_dn":       cert.Issuer.String(),
+		"serial_number":   serialNumber,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+	if err = setCertificateValidity(d, m, "", certPem); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceEJBCAEnrolledCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := setCertificateValidity(d, m, "", d.Get("certificate_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+	chain := d.Get("ca_chain_pem").([]interface{})
+	if len(chain) == 0 {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Unable to check the revocation status of the certificate",
+			Detail:   "EJBCA returned no CA chain, so the issuer of the certificate is unknown.",
+		}}
+	}
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	client, err := ejbcaClient(d, options)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("certificate_pem").(string), chain[0].(string))
+}
+
+func resourceEJBCAEnrolledCertUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// The other in-place attributes are only used by later requests to EJBCA.
+	return diag.FromErr(updateFullChain(d, m, listOfStrings(d.Get("ca_chain_pem").([]interface{}))...))
+}
+
+func resourceEJBCAEnrolledCertDelete(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if d.Get("revoke_on_destroy").(bool) {
+		path := fmt.Sprintf("v1/certificate/%s/%s/revoke?reason=%s",
+			url.PathEscape(d.Get("issuer_dn").(string)),
+			d.Get("serial_number").(string),
+			url.QueryEscape(d.Get("revocation_reason").(string)),
+		)
+		if err := ejbcaRequest(ctx, d, http.MethodPut, path, nil, nil); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to revoke certificate in EJBCA: %w", err))
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceEJBCAEnrolledCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	return planFullChains(diff, "fullchain_pem")
+}
@@ -0,0 +1,181 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testEJBCAServer serves the pkcs10enroll, search and revoke endpoints of the EJBCA REST API over TLS, for the bearer
+// token "token", enrolling certificates valid for 24 hours with caCert and caKey. The first request fails with 503
+// Service Unavailable when flaky is set.
+type testEJBCAServer struct {
+	*httptest.Server
+	flaky   atomic.Bool
+	mu      sync.Mutex
+	issued  []*x509.Certificate
+	revoked []string
+}
+
+func newTestEJBCAServer(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateKey) *testEJBCAServer {
+	t.Helper()
+
+	s := &testEJBCAServer{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.flaky.CompareAndSwap(true, false) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/certificate/pkcs10enroll":
+			var req ejbcaEnrollRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CertificateProfileName != "SERVER" || !req.IncludeChain {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			cert, err := testSignCertificateRequest(caCert, caKey, req.CertificateRequest, 24*time.Hour)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.issued = append(s.issued, cert)
+			_ = json.NewEncoder(w).Encode(ejbcaCertificate{
+				Certificate:      base64.StdEncoding.EncodeToString(cert.Raw),
+				SerialNumber:     cert.SerialNumber.Text(16),
+				ResponseFormat:   "DER",
+				CertificateChain: []string{base64.StdEncoding.EncodeToString(cert.Raw), base64.StdEncoding.EncodeToString(caCert.Raw)},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/certificate/search":
+			var req ejbcaSearchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			var resp ejbcaSearchResponse
+			for _, cert := range s.issued {
+				if len(resp.Certificates) == req.MaxNumberOfResults {
+					resp.MoreResults = true
+					break
+				}
+				resp.Certificates = append(resp.Certificates, ejbcaCertificate{Certificate: certificateToPEM(cert), ResponseFormat: "PEM"})
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/revoke"):
+			s.revoked = append(s.revoked, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+			_, _ = w.Write([]byte("{}"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// testEJBCAConfig returns the configuration of the EJBCA attributes for server.
+func testEJBCAConfig(server *testEJBCAServer) map[string]interface{} {
+	return map[string]interface{}{
+		"ejbca_url":     server.URL,
+		"bearer_token":  "token",
+		"ca_cert_pem":   certificateToPEM(server.Certificate()),
+		"retries":       1,
+		"retry_backoff": "1ms",
+	}
+}
+
+func TestResourceEJBCAEnrolledCert(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "EJBCA CA", nil, nil)
+	server := newTestEJBCAServer(t, ca, caKey)
+	config := testEJBCAConfig(server)
+	for name, value := range map[string]interface{}{
+		"csr_pem":                    testCertificateRequest(t, "ejbca.example.com"),
+		"certificate_profile_name":   "SERVER",
+		"end_entity_profile_name":    "SERVER",
+		"certificate_authority_name": "EJBCA CA",
+		"username":                   "ejbca.example.com",
+		"revoke_on_destroy":          true,
+		"revocation_reason":          "SUPERSEDED",
+	} {
+		config[name] = value
+	}
+
+	// the first request is retried
+	server.flaky.Store(true)
+	r := resourceEJBCAEnrolledCert()
+	state := testResourceApply(t, r, nil, config, &providerMeta{})
+
+	cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse certificate_pem: %s", err)
+	}
+	if cert.Subject.CommonName != "ejbca.example.com" || state.ID != cert.SerialNumber.Text(16) || state.Attributes["issuer_dn"] != "CN=EJBCA CA" {
+		t.Errorf("expected the certificate of the CSR with its serial number as ID, got %s with ID %s issued by %s", cert.Subject, state.ID, state.Attributes["issuer_dn"])
+	}
+	// the signed certificate starting the chain is left out
+	if state.Attributes["ca_chain_pem.#"] != "1" || state.Attributes["ca_chain_pem.0"] != certificateToPEM(ca) {
+		t.Errorf("expected the CA as chain, got %v", state.Attributes["ca_chain_pem.0"])
+	}
+
+	d := r.Data(state)
+	if diags := resourceEJBCAEnrolledCertDelete(context.Background(), d, &providerMeta{}); diags.HasError() {
+		t.Fatalf("destroy failed: %v", diags)
+	}
+	if want := "/v1/certificate/CN=EJBCA%20CA/" + state.ID + "/revoke?reason=SUPERSEDED"; len(server.revoked) != 1 || server.revoked[0] != want {
+		t.Errorf("expected the certificate to be revoked with %s, got %v", want, server.revoked)
+	}
+}
+
+func TestResourceEJBCAEnrolledCertRevocation(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "EJBCA CA", nil, nil)
+	sources := testRevocationSources{ocspThisUpdate: time.Now().Add(-time.Hour), ocspNextUpdate: time.Now().Add(time.Hour), crlThisUpdate: time.Now().Add(-time.Hour), crlNextUpdate: time.Now().Add(time.Hour)}
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	network := map[string]string{"connect_timeout": "30s", "retries": "0", "retry_backoff": "1s"}
+	attributes := func(serialNumber *big.Int, chain ...string) map[string]string {
+		attributes := map[string]string{
+			"certificate_pem": certificateToPEM(testRevocationCertificate(t, server, serialNumber, issuer, issuerKey)),
+			"ca_chain_pem.#":  strconv.Itoa(len(chain)),
+		}
+		for i, chainCert := range chain {
+			attributes["ca_chain_pem."+strconv.Itoa(i)] = chainCert
+		}
+		for name, value := range network {
+			attributes[name] = value
+		}
+		return attributes
+	}
+
+	r := resourceEJBCAEnrolledCert()
+	state, diags := testRefreshRevocation(t, r, attributes(testRevokedSerialNumber, certificateToPEM(issuer)))
+	if state != nil || len(diags) != 1 || !strings.Contains(diags[0].Summary, "serial number 42 has been revoked") {
+		t.Errorf("expected a revoked certificate to be removed from the state, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(big.NewInt(43), certificateToPEM(issuer)))
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected a good certificate to be kept, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(testRevokedSerialNumber))
+	if state == nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "no CA chain") {
+		t.Errorf("expected a warning without CA chain, got %v", diags)
+	}
+}
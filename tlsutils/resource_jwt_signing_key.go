@@ -0,0 +1,298 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceJWTSigningKey() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"algorithm": {
+			Description:      "JWS algorithm the key is used with. Changing it rotates the key.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          "RS256",
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedJWSAlgorithmsStr(), false)),
+		},
+		"rsa_bits": {
+			Description:      "size of the RSA key in bits, used by the RS* and PS* algorithms. Changing it rotates the key.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          2048,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+		},
+		"rotation_trigger": {
+			Description: "arbitrary value; changing it rotates the key, keeping the previous public key in `jwks`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"keep_previous": {
+			Description:      "number of previous public keys kept in `jwks` after rotation.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Default:          1,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		},
+		"kid": {
+			Description: "RFC 7638 JWK thumbprint of the current key, used as its key ID.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"private_key_pem": {
+			Description: "current private key in PEM format. Empty when `age_recipient` or `pgp_key` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"public_key_pem": {
+			Description: "current public key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"private_key_jwk": {
+			Description: "current private key in JWK format. Empty when `age_recipient` or `pgp_key` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"public_key_jwk": {
+			Description: "current public key in JWK format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"private_key_cose": {
+			Description: "current private key as a base64 encoded CBOR COSE_Key. Only set for the ES* and EdDSA algorithms, when neither `age_recipient` nor `pgp_key` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"public_key_cose": {
+			Description: "current public key as a base64 encoded CBOR COSE_Key. Only set for the ES* and EdDSA algorithms.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"previous_public_keys_jwk": {
+			Description: "public keys replaced by rotation in JWK format, most recent first.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"jwks": {
+			Description: "JWK Set of the current and previous public keys.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	// the private keys are rotated in place when the recipient changes, like for the other rotation attributes
+	for name, attribute := range privateKeyEncryptionSchema(false, jwtSigningKeyPrivateKeys...) {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Generate a JWT signing key with JWK and JWKS outputs",
+		CreateContext: resourceJWTSigningKeyCreate,
+		ReadContext:   resourceJWTSigningKeyRead,
+		UpdateContext: resourceJWTSigningKeyUpdate,
+		DeleteContext: resourceJWTSigningKeyDelete,
+		CustomizeDiff: resourceJWTSigningKeyCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// jwtSigningKeyPrivateKeys are the attributes holding the private key, encrypted to age_recipient or pgp_key when set.
+var jwtSigningKeyPrivateKeys = []string{"private_key_pem", "private_key_jwk", "private_key_cose"}
+
+// jwtSigningKeyRotationAttributes are the attributes that, once changed, cause a new key to be generated.
+// The plaintext private key is not kept when it is encrypted, so a new recipient needs a new key.
+var jwtSigningKeyRotationAttributes = []string{"algorithm", "rsa_bits", "rotation_trigger", "age_recipient", "pgp_key"}
+
+func resourceJWTSigningKeyCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if err := resourceJWTSigningKeyGenerate(d); err != nil {
+		return diag.FromErr(err)
+	}
+	// the ID stays the kid of the first key, rotations only change the kid attribute
+	d.SetId(d.Get("kid").(string))
+
+	return resourceJWTSigningKeySetJWKS(d, []string{})
+}
+
+func resourceJWTSigningKeyRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceJWTSigningKeyUpdate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	oldPrevious, _ := d.GetChange("previous_public_keys_jwk")
+	previous := make([]string, 0)
+	for _, jwk := range oldPrevious.([]interface{}) {
+		previous = append(previous, jwk.(string))
+	}
+
+	if d.HasChanges(jwtSigningKeyRotationAttributes...) {
+		oldPublicKeyJWK, _ := d.GetChange("public_key_jwk")
+		previous = append([]string{oldPublicKeyJWK.(string)}, previous...)
+
+		if err := resourceJWTSigningKeyGenerate(d); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceJWTSigningKeySetJWKS(d, previous)
+}
+
+func resourceJWTSigningKeyDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceJWTSigningKeyCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	for _, key := range jwtSigningKeyRotationAttributes {
+		if diff.HasChange(key) {
+			for _, computed := range []string{"kid", "private_key_pem", "public_key_pem", "private_key_jwk", "public_key_jwk", "private_key_cose", "public_key_cose", "encrypted_private_key_pem", "encrypted_private_key_jwk", "encrypted_private_key_cose", "previous_public_keys_jwk", "jwks"} {
+				if err := diff.SetNewComputed(computed); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if diff.HasChange("keep_previous") {
+		for _, computed := range []string{"previous_public_keys_jwk", "jwks"} {
+			if err := diff.SetNewComputed(computed); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resourceJWTSigningKeyGenerate generates a new key for the configured algorithm
+// and stores all of its representations.
+func resourceJWTSigningKeyGenerate(d *schema.ResourceData) error {
+	alg := d.Get("algorithm").(string)
+	jwsAlg, ok := jwsAlgorithms[alg]
+	if !ok {
+		return fmt.Errorf("unsupported JWS algorithm: %s", alg)
+	}
+
+	prvKey, err := generatePrivateKey(jwsAlg.algorithm, d.Get("rsa_bits").(int), jwsAlg.curve)
+	if err != nil {
+		return err
+	}
+
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key PEM: %w", err)
+	}
+
+	publicKeyPem, err := publicKeyToPEM(prvKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key PEM: %w", err)
+	}
+
+	publicJWK, err := privateKeyToJWK(prvKey, false)
+	if err != nil {
+		return fmt.Errorf("failed to build public JWK: %w", err)
+	}
+
+	privateJWK, err := privateKeyToJWK(prvKey, true)
+	if err != nil {
+		return fmt.Errorf("failed to build private JWK: %w", err)
+	}
+
+	kid, err := publicJWK.thumbprint()
+	if err != nil {
+		return fmt.Errorf("failed to compute JWK thumbprint: %w", err)
+	}
+
+	for _, jwk := range []*jsonWebKey{publicJWK, privateJWK} {
+		jwk.Kid = kid
+		jwk.Use = "sig"
+		jwk.Alg = alg
+	}
+
+	publicJWKJSON, err := json.Marshal(publicJWK)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public JWK: %w", err)
+	}
+
+	privateJWKJSON, err := json.Marshal(privateJWK)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private JWK: %w", err)
+	}
+
+	var privateKeyCOSE, publicKeyCOSE string
+	if supportsCOSEKey(prvKey) {
+		privateCOSEKey, err := privateKeyToCOSEKey(prvKey, alg, kid, true)
+		if err != nil {
+			return fmt.Errorf("failed to encode private COSE_Key: %w", err)
+		}
+		publicCOSEKey, err := privateKeyToCOSEKey(prvKey, alg, kid, false)
+		if err != nil {
+			return fmt.Errorf("failed to encode public COSE_Key: %w", err)
+		}
+		privateKeyCOSE = base64.StdEncoding.EncodeToString(privateCOSEKey)
+		publicKeyCOSE = base64.StdEncoding.EncodeToString(publicCOSEKey)
+	}
+
+	values := map[string]interface{}{
+		"kid":              kid,
+		"private_key_pem":  privateKeyPem,
+		"public_key_pem":   publicKeyPem,
+		"private_key_jwk":  string(privateJWKJSON),
+		"public_key_jwk":   string(publicJWKJSON),
+		"private_key_cose": privateKeyCOSE,
+		"public_key_cose":  publicKeyCOSE,
+	}
+	if err = encryptPrivateKeys(d, values, jwtSigningKeyPrivateKeys...); err != nil {
+		return err
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// resourceJWTSigningKeySetJWKS stores the previous public keys, trimmed to keep_previous,
+// and the JWK Set made of the current public key followed by them.
+func resourceJWTSigningKeySetJWKS(d *schema.ResourceData, previous []string) diag.Diagnostics {
+	if keep := d.Get("keep_previous").(int); len(previous) > keep {
+		previous = previous[:keep]
+	}
+
+	keys := []json.RawMessage{json.RawMessage(d.Get("public_key_jwk").(string))}
+	for _, jwk := range previous {
+		keys = append(keys, json.RawMessage(jwk))
+	}
+
+	jwks, err := json.Marshal(struct {
+		Keys []json.RawMessage `json:"keys"`
+	}{keys})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal JWKS: %w", err))
+	}
+
+	if err = d.Set("previous_public_keys_jwk", previous); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save previous_public_keys_jwk: %w", err))
+	}
+
+	if err = d.Set("jwks", string(jwks)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save jwks: %w", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package tlsutils
+
+import (
+	"encoding/json"
+	"filippo.io/age"
+	"testing"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 8037 appendix A.3
+	jwk := &jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	thumbprint, err := jwk.thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; thumbprint != expected {
+		t.Errorf("expected thumbprint %s, got %s", expected, thumbprint)
+	}
+}
+
+func TestResourceJWTSigningKeyRotation(t *testing.T) {
+	r := resourceJWTSigningKey()
+	config := map[string]interface{}{"algorithm": "ES256", "keep_previous": 1}
+	created := testResourceApply(t, r, nil, config, nil)
+
+	var publicJWK jsonWebKey
+	if err := json.Unmarshal([]byte(created.Attributes["public_key_jwk"]), &publicJWK); err != nil {
+		t.Fatal(err)
+	}
+	kid, err := publicJWK.thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != kid || created.Attributes["kid"] != kid || publicJWK.Kid != kid || publicJWK.Crv != "P-256" || publicJWK.D != "" {
+		t.Errorf("expected the public P-256 JWK with its thumbprint as ID and kid, got ID %s and %+v", created.ID, publicJWK)
+	}
+	prvKey, _, err := parsePrivateKeyPEM([]byte(created.Attributes["private_key_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromPEM, _ := privateKeyToJWK(prvKey, false); fromPEM.X != publicJWK.X || fromPEM.Y != publicJWK.Y {
+		t.Errorf("expected private_key_pem to hold the key of public_key_jwk")
+	}
+
+	config["rotation_trigger"] = "1"
+	rotated := testResourceApply(t, r, created, config, nil)
+	if rotated.ID != created.ID {
+		t.Errorf("expected the ID to stay %s on rotation, got %s", created.ID, rotated.ID)
+	}
+	if rotated.Attributes["kid"] == kid {
+		t.Errorf("expected a new kid on rotation")
+	}
+	if rotated.Attributes["previous_public_keys_jwk.#"] != "1" || rotated.Attributes["previous_public_keys_jwk.0"] != created.Attributes["public_key_jwk"] {
+		t.Errorf("expected the previous public key to be kept, got %v", rotated.Attributes)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.Unmarshal([]byte(rotated.Attributes["jwks"]), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != rotated.Attributes["kid"] || jwks.Keys[1].Kid != kid {
+		t.Errorf("expected the current and previous keys in jwks, got %+v", jwks.Keys)
+	}
+
+	config["rotation_trigger"] = "2"
+	rotatedAgain := testResourceApply(t, r, rotated, config, nil)
+	if rotatedAgain.Attributes["previous_public_keys_jwk.#"] != "1" || rotatedAgain.Attributes["previous_public_keys_jwk.0"] != rotated.Attributes["public_key_jwk"] {
+		t.Errorf("expected keep_previous to trim the previous keys to the last one")
+	}
+}
+
+func TestResourceJWTSigningKeyEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := resourceJWTSigningKey()
+	config := map[string]interface{}{"algorithm": "EdDSA", "age_recipient": identity.Recipient().String()}
+	created := testResourceApply(t, r, nil, config, nil)
+
+	for _, key := range jwtSigningKeyPrivateKeys {
+		if created.Attributes[key] != "" {
+			t.Errorf("expected no plaintext %s, got %q", key, created.Attributes[key])
+		}
+	}
+	prvKey, _, err := parsePrivateKeyPEM([]byte(testAgeDecrypt(t, created.Attributes["encrypted_private_key_pem"], identity)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var privateJWK jsonWebKey
+	if err = json.Unmarshal([]byte(testAgeDecrypt(t, created.Attributes["encrypted_private_key_jwk"], identity)), &privateJWK); err != nil {
+		t.Fatal(err)
+	}
+	if publicJWK, _ := privateKeyToJWK(prvKey, false); privateJWK.D == "" || privateJWK.X != publicJWK.X || privateJWK.Kid != created.Attributes["kid"] {
+		t.Errorf("expected the encrypted private JWK of the key, got %+v", privateJWK)
+	}
+	if testAgeDecrypt(t, created.Attributes["encrypted_private_key_cose"], identity) == "" {
+		t.Errorf("expected the encrypted COSE_Key of the EdDSA key")
+	}
+
+	// the plaintext key is not kept, so a new recipient rotates the key
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config["age_recipient"] = other.Recipient().String()
+	rotated := testResourceApply(t, r, created, config, nil)
+	if rotated.ID != created.ID || rotated.Attributes["kid"] == created.Attributes["kid"] || rotated.Attributes["previous_public_keys_jwk.0"] != created.Attributes["public_key_jwk"] {
+		t.Errorf("expected a rotation keeping the ID and the previous public key")
+	}
+	testAgeDecrypt(t, rotated.Attributes["encrypted_private_key_pem"], other)
+}
@@ -0,0 +1,168 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceKeyConvert() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Convert an existing private key to another format or encryption",
+		CreateContext: resourceKeyConvertCreate,
+		ReadContext:   resourceKeyConvertRead,
+		UpdateContext: resourceKeyConvertUpdate,
+		DeleteContext: resourceKeyConvertDelete,
+		CustomizeDiff: resourceKeyConvertCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"private_key": {
+				Description: "private key to convert: PEM (PKCS#1, SEC 1 or PKCS#8, encrypted or not), OpenSSH (encrypted or not), JWK, or base64 encoded DER. EC keys with explicit curve parameters, as exported by some HSMs, are converted with the named curve.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Sensitive:   true,
+			},
+			"passphrase": {
+				Description: "passphrase of `private_key`, when it is encrypted.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Sensitive:   true,
+			},
+			"target_format": {
+				Description:      "format of `converted_private_key`: `pkcs1` (RSA only), `sec1` (ECDSA only), `pkcs8`, `openssh`, `jwk`, `ppk2` or `ppk3` (PuTTY private key files, version 2 for PuTTY before 0.75).",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          keyFormatPKCS8,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedKeyConvertFormatsStr(), false)),
+			},
+			"target_passphrase": {
+				Description: "encrypt `converted_private_key` with this passphrase: PBES2 with PBKDF2-HMAC-SHA256 and AES-256-CBC for `pkcs8`, bcrypt with AES-256-CTR for `openssh`, AES-256-CBC for `ppk2` and `ppk3` (with Argon2id for `ppk3`), the legacy OpenSSL PEM encryption with AES-256-CBC for `pkcs1` and `sec1`, which only older software should need. Not supported for `jwk`. Changing it re-encrypts the same key.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"target_passphrase_version": {
+				Description: "version of `target_passphrase`, recorded in the state to tell which passphrase `converted_private_key` is encrypted with. Changing it re-encrypts the same key, with a new salt.",
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+			},
+			"converted_private_key": {
+				Description: "private key in `target_format`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"source_format": {
+				Description: "format `private_key` was found in: `pkcs1`, `sec1`, `pkcs8`, `openssh`, `jwk`, `ppk2` or `ppk3` (PuTTY private key files, version 2 for PuTTY before 0.75).",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"source_encrypted": {
+				Description: "whether `private_key` was encrypted.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"algorithm": {
+				Description: "algorithm of the key: `RSA`, `ECDSA` or `ED25519`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"public_key_pem": {
+				Description: "public key in PEM format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"public_key_openssh": {
+				Description: "public key in OpenSSH `authorized_keys` format. Empty for ECDSA keys on the P224 curve.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// keyConvertReencryptAttributes are the attributes that, once changed, cause the key to be re-encrypted in-place.
+var keyConvertReencryptAttributes = []string{"target_passphrase", "target_passphrase_version"}
+
+func resourceKeyConvertCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return resourceKeyConvertEncode(d)
+}
+
+func resourceKeyConvertRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+// resourceKeyConvertUpdate re-encrypts the key: private_key cannot change in-place, so decoding it again gives the same key material.
+func resourceKeyConvertUpdate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if !d.HasChanges(keyConvertReencryptAttributes...) {
+		return nil
+	}
+
+	return resourceKeyConvertEncode(d)
+}
+
+func resourceKeyConvertDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceKeyConvertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	for _, key := range keyConvertReencryptAttributes {
+		if diff.HasChange(key) {
+			return diff.SetNewComputed("converted_private_key")
+		}
+	}
+
+	return nil
+}
+
+// resourceKeyConvertEncode decodes private_key and saves it in target_format, encrypted with target_passphrase.
+func resourceKeyConvertEncode(d *schema.ResourceData) diag.Diagnostics {
+	prvKey, sourceFormat, encrypted, err := parseAnyPrivateKey(d.Get("private_key").(string), d.Get("passphrase").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	algorithm, err := privateKeyToAlgorithm(prvKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	converted, err := encodeAnyPrivateKey(prvKey, d.Get("target_format").(string), d.Get("target_passphrase").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	publicKeyPem, err := publicKeyToPEM(prvKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// OpenSSH has no P224 keys
+	publicKeyOpenSSH, _ := publicKeyToOpenSSH(prvKey)
+
+	values := map[string]interface{}{
+		"converted_private_key": converted,
+		"source_format":         sourceFormat,
+		"source_encrypted":      encrypted,
+		"algorithm":             algorithm.String(),
+		"public_key_pem":        publicKeyPem,
+		"public_key_openssh":    publicKeyOpenSSH,
+	}
+	for k, value := range values {
+		if err := d.Set(k, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", k, err))
+		}
+	}
+
+	d.SetId(hashForState(publicKeyPem + d.Get("target_format").(string)))
+
+	return nil
+}
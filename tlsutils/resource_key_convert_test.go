@@ -0,0 +1,208 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestResourceKeyConvert(t *testing.T) {
+	keys := map[Algorithm]crypto.PrivateKey{}
+	for _, algorithm := range []Algorithm{RSA, ECDSA, ED25519} {
+		prvKey, err := generatePrivateKey(algorithm, 2048, P256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[algorithm] = prvKey
+	}
+	encode := func(algorithm Algorithm, format, passphrase string) string {
+		encoded, err := encodeAnyPrivateKey(keys[algorithm], format, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	der, _ := pem.Decode([]byte(encode(ECDSA, keyFormatPKCS8, "")))
+
+	for name, test := range map[string]struct {
+		algorithm        Algorithm
+		privateKey       string
+		passphrase       string
+		targetFormat     string
+		targetPassphrase string
+		sourceFormat     string
+		sourceEncrypted  bool
+	}{
+		"PKCS#1 to encrypted PKCS#8": {
+			algorithm:        RSA,
+			privateKey:       encode(RSA, keyFormatPKCS1, ""),
+			targetFormat:     keyFormatPKCS8,
+			targetPassphrase: "target secret",
+			sourceFormat:     keyFormatPKCS1,
+		},
+		"encrypted PKCS#8 to encrypted SEC 1": {
+			algorithm:        ECDSA,
+			privateKey:       encode(ECDSA, keyFormatPKCS8, "secret"),
+			passphrase:       "secret",
+			targetFormat:     keyFormatSEC1,
+			targetPassphrase: "target secret",
+			sourceFormat:     keyFormatPKCS8,
+			sourceEncrypted:  true,
+		},
+		"legacy encrypted PKCS#1 to OpenSSH": {
+			algorithm:       RSA,
+			privateKey:      encode(RSA, keyFormatPKCS1, "secret"),
+			passphrase:      "secret",
+			targetFormat:    keyFormatOpenSSH,
+			sourceFormat:    keyFormatPKCS1,
+			sourceEncrypted: true,
+		},
+		"encrypted OpenSSH to JWK": {
+			algorithm:       ED25519,
+			privateKey:      encode(ED25519, keyFormatOpenSSH, "secret"),
+			passphrase:      "secret",
+			targetFormat:    keyFormatJWK,
+			sourceFormat:    keyFormatOpenSSH,
+			sourceEncrypted: true,
+		},
+		"JWK to encrypted OpenSSH": {
+			algorithm:        ECDSA,
+			privateKey:       encode(ECDSA, keyFormatJWK, ""),
+			targetFormat:     keyFormatOpenSSH,
+			targetPassphrase: "target secret",
+			sourceFormat:     keyFormatJWK,
+		},
+		"base64 DER to PKCS#8": {
+			algorithm:    ECDSA,
+			privateKey:   base64.StdEncoding.EncodeToString(der.Bytes),
+			targetFormat: keyFormatPKCS8,
+			sourceFormat: keyFormatPKCS8,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			state := testResourceApply(t, resourceKeyConvert(), nil, map[string]interface{}{
+				"private_key":       test.privateKey,
+				"passphrase":        test.passphrase,
+				"target_format":     test.targetFormat,
+				"target_passphrase": test.targetPassphrase,
+			}, &providerMeta{})
+
+			if got := state.Attributes["source_format"]; got != test.sourceFormat {
+				t.Errorf("expected source_format %s, got %s", test.sourceFormat, got)
+			}
+			if got := state.Attributes["source_encrypted"]; got != strconv.FormatBool(test.sourceEncrypted) {
+				t.Errorf("expected source_encrypted %t, got %s", test.sourceEncrypted, got)
+			}
+			if got := state.Attributes["algorithm"]; got != test.algorithm.String() {
+				t.Errorf("expected algorithm %s, got %s", test.algorithm, got)
+			}
+
+			converted, format, encrypted, err := parseAnyPrivateKey(state.Attributes["converted_private_key"], test.targetPassphrase)
+			if err != nil {
+				t.Fatalf("unable to parse converted_private_key: %s", err)
+			}
+			if format != test.targetFormat || encrypted != (test.targetPassphrase != "") {
+				t.Errorf("expected converted_private_key in %s encrypted %t, got %s encrypted %t", test.targetFormat, test.targetPassphrase != "", format, encrypted)
+			}
+			want, err := publicKeyToPEM(keys[test.algorithm])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := publicKeyToPEM(converted); got != want || state.Attributes["public_key_pem"] != want {
+				t.Errorf("expected the converted key to keep public key %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestResourceKeyConvertErrors(t *testing.T) {
+	prvKey, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encodeAnyPrivateKey(prvKey, keyFormatPKCS8, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		raw map[string]interface{}
+		err string
+	}{
+		"missing passphrase": {
+			raw: map[string]interface{}{"private_key": encrypted},
+			err: "the PKCS#8 private key is encrypted and no passphrase is set",
+		},
+		"wrong passphrase": {
+			raw: map[string]interface{}{"private_key": encrypted, "passphrase": "wrong"},
+			err: "decrypt",
+		},
+		"PKCS#1 of an ECDSA key": {
+			raw: map[string]interface{}{"private_key": encrypted, "passphrase": "secret", "target_format": keyFormatPKCS1},
+			err: "only RSA keys can be encoded in PKCS#1",
+		},
+		"encrypted JWK": {
+			raw: map[string]interface{}{"private_key": encrypted, "passphrase": "secret", "target_format": keyFormatJWK, "target_passphrase": "secret"},
+			err: "JWK private keys cannot be encrypted",
+		},
+		"not a key": {
+			raw: map[string]interface{}{"private_key": "not a key"},
+			err: "private key is neither PEM, JWK nor base64 encoded DER",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := resourceKeyConvert()
+			diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(test.raw), &providerMeta{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, test.err) {
+				t.Errorf("expected an error containing %q, got %v", test.err, diags)
+			}
+		})
+	}
+}
+
+func TestResourceKeyConvertRotatePassphrase(t *testing.T) {
+	prvKey, err := generatePrivateKey(ECDSA, 0, P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := func(targetPassphrase string, targetPassphraseVersion int) map[string]interface{} {
+		return map[string]interface{}{
+			"private_key":               privateKeyPem,
+			"target_passphrase":         targetPassphrase,
+			"target_passphrase_version": targetPassphraseVersion,
+		}
+	}
+
+	r := resourceKeyConvert()
+	created := testResourceApply(t, r, nil, config("old", 0), &providerMeta{})
+	rotated := testResourceApply(t, r, created, config("new", 0), &providerMeta{})
+	bumped := testResourceApply(t, r, rotated, config("new", 1), &providerMeta{})
+
+	if rotated.ID != created.ID || bumped.ID != created.ID {
+		t.Errorf("expected the rotations to keep ID %s, got %s and %s", created.ID, rotated.ID, bumped.ID)
+	}
+	if bumped.Attributes["converted_private_key"] == rotated.Attributes["converted_private_key"] {
+		t.Errorf("expected target_passphrase_version to re-encrypt converted_private_key")
+	}
+	for _, state := range []*terraform.InstanceState{rotated, bumped} {
+		converted, _, _, err := parseAnyPrivateKey(state.Attributes["converted_private_key"], "new")
+		if err != nil {
+			t.Fatalf("unable to decrypt converted_private_key with the new passphrase: %s", err)
+		}
+		if got, _ := publicKeyToPEM(converted); got != state.Attributes["public_key_pem"] {
+			t.Errorf("expected the same key to be re-encrypted")
+		}
+	}
+}
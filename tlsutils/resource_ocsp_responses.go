@@ -0,0 +1,325 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/ocsp"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ocspStatuses maps the supported statuses of the static OCSP responses to their ocsp package value.
+var ocspStatuses = map[string]int{
+	string(revocationStatusGood):    ocsp.Good,
+	string(revocationStatusRevoked): ocsp.Revoked,
+	string(revocationStatusUnknown): ocsp.Unknown,
+}
+
+func resourceOCSPResponses() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Generate pre-signed OCSP responses, for nginx ssl_stapling_file or a static OCSP responder",
+		CreateContext: resourceOCSPResponsesCreate,
+		ReadContext:   resourceOCSPResponsesRead,
+		UpdateContext: resourceOCSPResponsesUpdate,
+		DeleteContext: resourceOCSPResponsesDelete,
+		CustomizeDiff: resourceOCSPResponsesCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"issuer_cert_pem": {
+				Description:      "certificate of the CA that issued the certificates, in PEM format.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"responder_cert_pem": {
+				Description:      "delegated OCSP signing certificate in PEM format, issued by the CA with the `ocsp_signing` extended key usage and embedded in the responses. When not set, the responses are signed by the CA itself.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"responder_private_key_pem": {
+				Description:      "private key in PEM format of `responder_cert_pem`, or of the CA when it is not set.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Sensitive:        true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"certificate": {
+				Description: "certificates to generate a response for.",
+				Type:        schema.TypeList,
+				Required:    true,
+				ForceNew:    true,
+				MinItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"certificate_pem": {
+							Description:      "certificate in PEM format.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ForceNew:         true,
+							DiffSuppressFunc: suppressEquivalentPEM,
+						},
+						"serial_number": {
+							Description: "serial number of the certificate in hex, optionally colon separated. Used when certificate_pem is not set.",
+							Type:        schema.TypeString,
+							Optional:    true,
+							ForceNew:    true,
+						},
+						"status": {
+							Description:      "status of the certificate: `good`, `revoked` or `unknown`.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ForceNew:         true,
+							Default:          string(revocationStatusGood),
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"good", "revoked", "unknown"}, false)),
+						},
+						"revocation_time": {
+							Description:      "revocation time in RFC3339 format, required when `status` is `revoked`.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ForceNew:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+						},
+						"reason": {
+							Description:      "revocation reason: " + strings.Join(supportedCRLReasonsStr(), ", ") + ".",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ForceNew:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedCRLReasonsStr(), false)),
+						},
+					},
+				},
+			},
+			"validity_hours": {
+				Description:      "hours between the `this_update` and `next_update` times of the responses.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          168,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+			},
+			"renew_before_hours": {
+				Description:      "sign new responses in-place when `next_update` is less than this many hours away. 0 disables renewal.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          48,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"evaluation_time": {
+				Description:      "time in RFC3339 format used as `this_update`, instead of the provider evaluation_time or the current time.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+			},
+			"this_update": {
+				Description: "time the responses were signed at, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"next_update": {
+				Description: "time the responses expire at, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"responses": {
+				Description: "DER encoded OCSP responses, in the order of `certificate`.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"serial_number": {
+							Description: "serial number of the certificate in hex.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+						"status": {
+							Description: "status of the certificate.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+						"response_base64": {
+							Description: "base64 encoded DER OCSP response, to decode into the file of nginx `ssl_stapling_file`.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceOCSPResponsesCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := resourceOCSPResponsesSign(d, m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(hashForState(d.Get("issuer_cert_pem").(string), d.Get("this_update").(string)))
+
+	return nil
+}
+
+func resourceOCSPResponsesRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceOCSPResponsesUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	renew, err := resourceOCSPResponsesRenewalDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if renew {
+		if err = resourceOCSPResponsesSign(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return nil
+}
+
+func resourceOCSPResponsesDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceOCSPResponsesCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+	renew, err := resourceOCSPResponsesRenewalDue(diff, m)
+	if err != nil || !renew {
+		return err
+	}
+
+	for _, computed := range []string{"this_update", "next_update", "responses"} {
+		if err = diff.SetNewComputed(computed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceOCSPResponsesRenewalDue reports whether the next_update of the prior state is less than renew_before_hours
+// away.
+func resourceOCSPResponsesRenewalDue(d resourceChanges, m interface{}) (bool, error) {
+	renewBefore := d.Get("renew_before_hours").(int)
+	if renewBefore == 0 {
+		return false, nil
+	}
+
+	return priorTimeReached(d, m, "next_update", -time.Duration(renewBefore)*time.Hour)
+}
+
+// resourceOCSPResponsesSign signs the responses of every certificate, valid from now for validity_hours.
+func resourceOCSPResponsesSign(d *schema.ResourceData, m interface{}) error {
+	issuer, err := parsePEMCertificate([]byte(d.Get("issuer_cert_pem").(string)))
+	if err != nil {
+		return fmt.Errorf("unable to parse issuer_cert_pem: %w", err)
+	}
+
+	prvKey, _, err := parsePrivateKeyPEM([]byte(d.Get("responder_private_key_pem").(string)))
+	if err != nil {
+		return fmt.Errorf("failed to parse private key PEM: %w", err)
+	}
+
+	responder := issuer
+	var embedded *x509.Certificate
+	if responderPem := d.Get("responder_cert_pem").(string); responderPem != "" {
+		if responder, err = parsePEMCertificate([]byte(responderPem)); err != nil {
+			return fmt.Errorf("unable to parse responder_cert_pem: %w", err)
+		}
+		if err = responder.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("responder_cert_pem is not issued by issuer_cert_pem: %w", err)
+		}
+		if !slices.Contains(responder.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+			return fmt.Errorf("responder_cert_pem does not have the ocsp_signing extended key usage")
+		}
+		embedded = responder
+	}
+	if !privateKeyMatchesCertificate(prvKey, responder) {
+		return fmt.Errorf("responder_private_key_pem does not match the responder certificate")
+	}
+
+	thisUpdate := now(d, m).UTC().Truncate(time.Second)
+	nextUpdate := thisUpdate.Add(time.Duration(d.Get("validity_hours").(int)) * time.Hour)
+
+	responses := make([]interface{}, 0)
+	for i, rawEntry := range d.Get("certificate").([]interface{}) {
+		template, err := resourceOCSPResponsesTemplate(rawEntry.(map[string]interface{}))
+		if err != nil {
+			return fmt.Errorf("invalid certificate (element #%d): %w", i, err)
+		}
+		template.ThisUpdate, template.NextUpdate, template.Certificate = thisUpdate, nextUpdate, embedded
+
+		response, err := ocsp.CreateResponse(issuer, responder, *template, prvKey.(crypto.Signer))
+		if err != nil {
+			return fmt.Errorf("failed to sign OCSP response (element #%d): %w", i, err)
+		}
+		responses = append(responses, map[string]interface{}{
+			"serial_number":   template.SerialNumber.Text(16),
+			"status":          rawEntry.(map[string]interface{})["status"].(string),
+			"response_base64": base64.StdEncoding.EncodeToString(response),
+		})
+	}
+
+	values := map[string]interface{}{
+		"this_update": thisUpdate.Format(time.RFC3339),
+		"next_update": nextUpdate.Format(time.RFC3339),
+		"responses":   responses,
+	}
+	for k, value := range values {
+		if err := d.Set(k, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+// resourceOCSPResponsesTemplate builds the response template of a certificate block, without its validity.
+func resourceOCSPResponsesTemplate(entry map[string]interface{}) (*ocsp.Response, error) {
+	template := &ocsp.Response{Status: ocspStatuses[entry["status"].(string)]}
+
+	if certPem := entry["certificate_pem"].(string); certPem != "" {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate_pem: %w", err)
+		}
+		template.SerialNumber = cert.SerialNumber
+	} else if serial := entry["serial_number"].(string); serial != "" {
+		serialNumber, ok := new(big.Int).SetString(strings.ReplaceAll(serial, ":", ""), 16)
+		if !ok {
+			return nil, fmt.Errorf("serial_number %q is not a hex number", serial)
+		}
+		template.SerialNumber = serialNumber
+	} else {
+		return nil, fmt.Errorf("one of certificate_pem or serial_number must be set")
+	}
+
+	if template.Status != ocsp.Revoked {
+		return template, nil
+	}
+	revocationTime := entry["revocation_time"].(string)
+	if revocationTime == "" {
+		return nil, fmt.Errorf("revocation_time is required when status is revoked")
+	}
+	template.RevokedAt, _ = time.Parse(time.RFC3339, revocationTime)
+	if reason := entry["reason"].(string); reason != "" {
+		template.RevocationReason = crlReasons[reason]
+	}
+
+	return template, nil
+}
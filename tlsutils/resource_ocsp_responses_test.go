@@ -0,0 +1,116 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/crypto/ocsp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResourceOCSPResponses(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	responder, responderKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Example OCSP"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}}, ca, caKey)
+	responderKeyPem, err := privateKeyToPEM(responderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}}, ca, caKey)
+	revokedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	config := map[string]interface{}{
+		"issuer_cert_pem":           certificateToPEM(ca),
+		"responder_cert_pem":        certificateToPEM(responder),
+		"responder_private_key_pem": responderKeyPem,
+		"certificate": []interface{}{
+			map[string]interface{}{"certificate_pem": certificateToPEM(leaf)},
+			map[string]interface{}{"serial_number": "0a:bc", "status": "revoked", "revocation_time": revokedAt.Format(time.RFC3339), "reason": "key_compromise"},
+		},
+		"validity_hours":     24,
+		"renew_before_hours": 12,
+	}
+
+	r := resourceOCSPResponses()
+	state := testResourceApply(t, r, nil, config, &providerMeta{})
+	for i, want := range []struct {
+		serialNumber string
+		status       int
+	}{
+		{leaf.SerialNumber.Text(16), ocsp.Good},
+		{"abc", ocsp.Revoked},
+	} {
+		der, err := base64.StdEncoding.DecodeString(state.Attributes["responses."+strconv.Itoa(i)+".response_base64"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := ocsp.ParseResponse(der, ca)
+		if err != nil {
+			t.Fatalf("unable to parse response %d: %s", i, err)
+		}
+		if response.SerialNumber.Text(16) != want.serialNumber || response.Status != want.status {
+			t.Errorf("expected status %d for serial number %s, got %d for %s", want.status, want.serialNumber, response.Status, response.SerialNumber.Text(16))
+		}
+		if response.Certificate == nil || !response.Certificate.Equal(responder) {
+			t.Errorf("expected the delegated responder certificate in response %d", i)
+		}
+		if want.status == ocsp.Revoked && (!response.RevokedAt.Equal(revokedAt) || response.RevocationReason != ocsp.KeyCompromise) {
+			t.Errorf("expected a key compromise at %s, got reason %d at %s", revokedAt, response.RevocationReason, response.RevokedAt)
+		}
+	}
+
+	// the responses are re-signed in-place renew_before_hours before next_update
+	nextUpdate, err := time.Parse(time.RFC3339, state.Attributes["next_update"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged := testResourceApply(t, r, state, config, &providerMeta{}); unchanged != state {
+		t.Errorf("expected no change before the renewal time")
+	}
+	meta := &providerMeta{evaluationTime: nextUpdate.Add(-time.Hour)}
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.RequiresNew() {
+		t.Fatalf("expected the responses to be re-signed in-place, got %v", diff)
+	}
+	renewed := testResourceApply(t, r, state, config, meta)
+	if renewed.ID != state.ID || renewed.Attributes["this_update"] != meta.evaluationTime.UTC().Format(time.RFC3339) {
+		t.Errorf("expected responses signed at %s with ID %s, got %s with ID %s", meta.evaluationTime.UTC().Format(time.RFC3339), state.ID, renewed.Attributes["this_update"], renewed.ID)
+	}
+}
+
+func TestResourceOCSPResponsesRenewal(t *testing.T) {
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), &providerMeta{})
+	config := map[string]interface{}{
+		"issuer_cert_pem":           ca.Attributes["intermediate_cert_pem"],
+		"responder_private_key_pem": ca.Attributes["intermediate_private_key_pem"],
+		"certificate":               []interface{}{map[string]interface{}{"serial_number": "01:02"}},
+		"validity_hours":            168,
+		"renew_before_hours":        48,
+	}
+	signedAt := time.Now().UTC().Truncate(time.Second)
+
+	r := resourceOCSPResponses()
+	created := testResourceApply(t, r, nil, config, &providerMeta{evaluationTime: signedAt})
+
+	kept := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: signedAt.Add(100 * time.Hour)})
+	if kept.Attributes["this_update"] != created.Attributes["this_update"] || kept.Attributes["responses.0.response_base64"] != created.Attributes["responses.0.response_base64"] {
+		t.Errorf("expected no renewal while next_update is more than renew_before_hours away")
+	}
+
+	renewedAt := signedAt.Add(130 * time.Hour)
+	renewed := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: renewedAt})
+	if got, want := renewed.Attributes["this_update"], renewedAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected this_update %s, got %s", want, got)
+	}
+	if got, want := renewed.Attributes["next_update"], renewedAt.Add(168*time.Hour).Format(time.RFC3339); got != want {
+		t.Errorf("expected next_update %s, got %s", want, got)
+	}
+	if renewed.Attributes["responses.0.response_base64"] == created.Attributes["responses.0.response_base64"] {
+		t.Errorf("expected a renewed response")
+	}
+}
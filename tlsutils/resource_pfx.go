@@ -0,0 +1,185 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// pfxEncoders provides the PKCS#12 encoder for each supported encoding.
+var pfxEncoders = map[string]*pkcs12.Encoder{
+	// 3DES and SHA-1 MAC: the encoding accepted by Azure Key Vault, Application Gateway and older Windows versions
+	"legacy": pkcs12.LegacyDES,
+	// AES-256-CBC, PBKDF2 and SHA-256 MAC
+	"modern": pkcs12.Modern2023,
+	// no encryption nor MAC, requires an empty password
+	"passwordless": pkcs12.Passwordless,
+}
+
+func resourcePFX() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"certificate_pem": {
+			Description:      "certificate in PEM format. When it contains a bundle, the first certificate is the leaf and the others are added as CA certificates.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"chain_pem": {
+			Description:      "CA certificates in PEM format added to the archive.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"private_key_pem": {
+			Description:      "private key in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			Sensitive:        true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"password": {
+			Description: "password protecting the archive. May be empty. Changing it re-encrypts the same key and certificates.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Default:     "",
+		},
+		"password_version": {
+			Description: "version of `password`, recorded in the state to tell which password `pfx_base64` is protected with. Changing it re-encrypts the archive, with a new salt.",
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     0,
+		},
+		"encoding": {
+			Description:      "PKCS#12 encryption: `legacy` (3DES, SHA-1 MAC, accepted by Azure), `modern` (AES-256, SHA-256 MAC) or `passwordless` (no encryption, password must be empty).",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			Default:          "legacy",
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"legacy", "modern", "passwordless"}, false)),
+		},
+		"pfx_base64": {
+			Description: "PKCS#12 archive encoded in base64. Empty when `age_recipient` or `pgp_key` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+	}
+	for name, attribute := range privateKeyEncryptionSchema(true, "pfx_base64") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Generate a PKCS#12 (PFX) archive in base64, as expected by Azure certificate imports",
+		CreateContext: resourcePFXCreate,
+		ReadContext:   resourcePFXRead,
+		UpdateContext: resourcePFXUpdate,
+		DeleteContext: resourcePFXDelete,
+		CustomizeDiff: resourcePFXCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// pfxReencryptAttributes are the attributes that, once changed, cause the archive to be re-encrypted in-place.
+var pfxReencryptAttributes = []string{"password", "password_version"}
+
+func resourcePFXCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return resourcePFXEncode(d)
+}
+
+func resourcePFXRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+// resourcePFXUpdate re-encrypts the archive from the inputs, which cannot change in-place.
+func resourcePFXUpdate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if !d.HasChanges(pfxReencryptAttributes...) {
+		return nil
+	}
+
+	return resourcePFXEncode(d)
+}
+
+func resourcePFXDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourcePFXCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	for _, key := range pfxReencryptAttributes {
+		if !diff.HasChange(key) {
+			continue
+		}
+		// only one of them is set, depending on age_recipient and pgp_key
+		for _, computed := range []string{"pfx_base64", "encrypted_pfx_base64"} {
+			if err := diff.SetNewComputed(computed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// resourcePFXEncode builds the PKCS#12 archive of the inputs.
+func resourcePFXEncode(d *schema.ResourceData) diag.Diagnostics {
+	certs, err := parsePEMCertificates([]byte(d.Get("certificate_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse certificate_pem: %w", err))
+	}
+	if len(certs) == 0 {
+		return diag.FromErr(fmt.Errorf("certificate_pem does not contain any certificate"))
+	}
+
+	chainCerts, err := parsePEMCertificates([]byte(d.Get("chain_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse chain_pem: %w", err))
+	}
+
+	prvKey, _, err := parsePrivateKeyPEM([]byte(d.Get("private_key_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to parse private key PEM: %w", err))
+	}
+
+	if !privateKeyMatchesCertificate(prvKey, certs[0]) {
+		return diag.FromErr(fmt.Errorf("private_key_pem does not match the public key of the certificate"))
+	}
+
+	encoding := d.Get("encoding").(string)
+	password := d.Get("password").(string)
+	if encoding == "passwordless" && password != "" {
+		return diag.FromErr(fmt.Errorf("password must be empty with encoding %q", encoding))
+	}
+
+	pfx, err := pfxEncoders[encoding].Encode(prvKey, certs[0], append(certs[1:], chainCerts...), password)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to encode PKCS#12 archive: %w", err))
+	}
+
+	d.SetId(hashForState(d.Get("certificate_pem").(string), d.Get("chain_pem").(string), encoding))
+
+	values := map[string]interface{}{"pfx_base64": base64.StdEncoding.EncodeToString(pfx)}
+	if err = encryptPrivateKeys(d, values, "pfx_base64"); err != nil {
+		return diag.FromErr(err)
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
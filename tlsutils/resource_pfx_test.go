@@ -0,0 +1,144 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"software.sslmate.com/src/go-pkcs12"
+	"strings"
+	"testing"
+)
+
+func TestResourcePFX(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Intermediate CA", nil, nil)
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "app.example.com"}}, ca, caKey)
+	leafKeyPem, err := privateKeyToPEM(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []string{"legacy", "modern", "passwordless"} {
+		t.Run(encoding, func(t *testing.T) {
+			password := "changeit"
+			if encoding == "passwordless" {
+				password = ""
+			}
+			state := testResourceApply(t, resourcePFX(), nil, map[string]interface{}{
+				"certificate_pem": certificateToPEM(leaf),
+				"chain_pem":       certificateToPEM(ca),
+				"private_key_pem": leafKeyPem,
+				"password":        password,
+				"encoding":        encoding,
+			}, &providerMeta{})
+
+			pfx, err := base64.StdEncoding.DecodeString(state.Attributes["pfx_base64"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			prvKey, cert, caCerts, err := pkcs12.DecodeChain(pfx, password)
+			if err != nil {
+				t.Fatalf("unable to decode the archive: %s", err)
+			}
+			if !cert.Equal(leaf) || len(caCerts) != 1 || !caCerts[0].Equal(ca) {
+				t.Errorf("expected the leaf and its CA in the archive, got %s and %d CA certificates", cert.Subject, len(caCerts))
+			}
+			if !privateKeyMatchesCertificate(prvKey, leaf) {
+				t.Errorf("expected the key of the leaf in the archive")
+			}
+		})
+	}
+
+	r := resourcePFX()
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"certificate_pem": certificateToPEM(leaf),
+		"private_key_pem": leafKeyPem,
+		"password":        "changeit",
+		"encoding":        "passwordless",
+	}), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "password must be empty") {
+		t.Errorf("expected a password error with the passwordless encoding, got %v", diags)
+	}
+}
+
+func TestResourcePFXEncrypted(t *testing.T) {
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "app.example.com"}}, nil, nil)
+	leafKeyPem, err := privateKeyToPEM(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, publicKey := testPGPEntity(t)
+
+	state := testResourceApply(t, resourcePFX(), nil, map[string]interface{}{
+		"certificate_pem": certificateToPEM(leaf),
+		"private_key_pem": leafKeyPem,
+		"encoding":        "passwordless",
+		"pgp_key":         publicKey,
+	}, &providerMeta{})
+	if state.Attributes["pfx_base64"] != "" {
+		t.Errorf("expected no plaintext archive, got %q", state.Attributes["pfx_base64"])
+	}
+
+	pfx, err := base64.StdEncoding.DecodeString(testPGPDecrypt(t, state.Attributes["encrypted_pfx_base64"], entity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, cert, _, err := pkcs12.DecodeChain(pfx, ""); err != nil || !cert.Equal(leaf) {
+		t.Errorf("expected the archive of the leaf once decrypted, got %v", err)
+	}
+}
+
+func TestResourcePFXRotatePassword(t *testing.T) {
+	leaf, leafKey := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "app.example.com"}}, nil, nil)
+	leafKeyPem, err := privateKeyToPEM(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, publicKey := testPGPEntity(t)
+	config := func(password string, passwordVersion int, pgpKey string) map[string]interface{} {
+		return map[string]interface{}{
+			"certificate_pem":  certificateToPEM(leaf),
+			"private_key_pem":  leafKeyPem,
+			"password":         password,
+			"password_version": passwordVersion,
+			"encoding":         "modern",
+			"pgp_key":          pgpKey,
+		}
+	}
+	decode := func(state *terraform.InstanceState, password string) {
+		t.Helper()
+		pfxBase64 := state.Attributes["pfx_base64"]
+		if state.Attributes["pgp_key"] != "" {
+			pfxBase64 = testPGPDecrypt(t, state.Attributes["encrypted_pfx_base64"], entity)
+		}
+		pfx, err := base64.StdEncoding.DecodeString(pfxBase64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prvKey, _, _, err := pkcs12.DecodeChain(pfx, password); err != nil || !privateKeyMatchesCertificate(prvKey, leaf) {
+			t.Errorf("expected the archive to open with password %q, got %v", password, err)
+		}
+	}
+
+	for name, pgpKey := range map[string]string{"plaintext": "", "encrypted": publicKey} {
+		t.Run(name, func(t *testing.T) {
+			r := resourcePFX()
+			created := testResourceApply(t, r, nil, config("old", 0, pgpKey), &providerMeta{})
+			rotated := testResourceApply(t, r, created, config("new", 0, pgpKey), &providerMeta{})
+			if rotated.ID != created.ID {
+				t.Errorf("expected the password change to keep ID %s, got %s", created.ID, rotated.ID)
+			}
+			decode(rotated, "new")
+
+			bumped := testResourceApply(t, r, rotated, config("new", 1, pgpKey), &providerMeta{})
+			if bumped.ID != created.ID || bumped.Attributes["pfx_base64"]+bumped.Attributes["encrypted_pfx_base64"] == rotated.Attributes["pfx_base64"]+rotated.Attributes["encrypted_pfx_base64"] {
+				t.Errorf("expected password_version to re-encrypt the archive in-place")
+			}
+			decode(bumped, "new")
+		})
+	}
+}
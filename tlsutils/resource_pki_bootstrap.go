@@ -0,0 +1,455 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+// pkiBootstrapPrivateKeys are the attributes holding the private keys, encrypted to age_recipient or pgp_key when set.
+var pkiBootstrapPrivateKeys = []string{"root_private_key_pem", "intermediate_private_key_pem", "root_private_key_openssh", "intermediate_private_key_openssh"}
+
+func resourcePKIBootstrap() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"algorithm": {
+			Description:      "name of the algorithm of both CA keys. Defaults to the `default_key_algorithm` of the provider, then `ECDSA`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedAlgorithmsStr(), false)),
+		},
+		"rsa_bits": {
+			Description:      "size of the RSA keys in bits, when `algorithm` is `RSA`. Defaults to the `default_rsa_bits` of the provider, then 4096.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+		},
+		"ecdsa_curve": {
+			Description:      "elliptic curve of the keys, when `algorithm` is `ECDSA`. Defaults to the `default_ecdsa_curve` of the provider, then `P384`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+		},
+		"root_subject":         certificateSubjectSchema("subject of the root CA certificate. Must not be empty."),
+		"intermediate_subject": certificateSubjectSchema("subject of the intermediate CA certificate. Must not be empty."),
+		"serial_number_prefix": serialNumberPrefixSchema("serial numbers of both CA certificates"),
+		"root_authority_key_id": {
+			Description:      "authority key identifier of the self-signed root CA certificate: `omit` leaves the extension out, `subject_key_id` repeats the subject key identifier, for validators requiring it. Never includes the issuer and serial number. Defaults to `omit`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"omit", "subject_key_id"}, false)),
+		},
+		"permitted_dns_domains": {
+			Description: "DNS domains the intermediate CA is constrained to, as a critical name constraints extension.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			ForceNew:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				DiffSuppressFunc: suppressReorderedList(normalizeDNSName),
+			},
+		},
+		"max_issue_validity_hours": {
+			Description:      "maximum number of hours the certificates issued by the two CAs through the provider are valid for, e.g. by `tlsutils_dual_cert` and `tlsutils_cert_batch`. Recorded with the CA certificates in the provider `issuance_journal`, which is required.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+		},
+		"max_issue_validity_action": {
+			Description:      "what happens to a certificate that would be valid for longer than `max_issue_validity_hours`: `truncate` caps its validity, `error` fails its issuance. Defaults to `truncate`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"truncate", "error"}, false)),
+		},
+		"root_private_key_pem": {
+			Description: "private key of the root CA in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"root_cert_pem": {
+			Description: "self-signed certificate of the root CA in PEM format, with a path length of 1.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"root_cert_text": {
+			Description: "`root_cert_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"root_cert_json": {
+			Description: "`root_cert_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"intermediate_private_key_pem": {
+			Description: "private key of the intermediate CA in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"intermediate_cert_pem": {
+			Description: "certificate of the intermediate CA in PEM format, signed by the root CA, with a path length of 0.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"intermediate_cert_text": {
+			Description: "`intermediate_cert_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"intermediate_cert_json": {
+			Description: "`intermediate_cert_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"chain_pem": {
+			Description: "intermediate followed by root CA certificates in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"root_private_key_openssh": {
+			Description: "private key of the root CA in OpenSSH format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"root_public_key_openssh": {
+			Description: "public key of the root CA in OpenSSH authorized_keys format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"intermediate_private_key_openssh": {
+			Description: "private key of the intermediate CA in OpenSSH format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+		},
+		"intermediate_public_key_openssh": {
+			Description: "public key of the intermediate CA in OpenSSH authorized_keys format, when `openssh_output` is set.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range keyFormatSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range policyExtensionsSchema("the intermediate CA certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range crlDistributionSchema() {
+		s[name] = attribute
+	}
+	s["crl_distribution_points"].Description = "URLs of the CRLs of the root CA, e.g. the published `crl_pem` of a `tlsutils_x509_crl`, as a CRL distribution points extension of the intermediate CA certificate."
+	s["freshest_crl_urls"].Description = "URLs of the delta CRLs of the root CA, as a freshest CRL extension of the intermediate CA certificate. Clients combine them with the CRLs of `crl_distribution_points`."
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	s["check_revocation"].Description = "on refresh, ask the CRL distribution points of the intermediate CA certificate whether the root CA revoked it, and plan both CAs again if so."
+	for name, attribute := range certificateValiditySchema("root_", "the root CA certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range certificateValiditySchema("intermediate_", "the intermediate CA certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range privateKeyEncryptionSchema(true, pkiBootstrapPrivateKeys...) {
+		s[name] = attribute
+	}
+
+	for name, attribute := range validityPeriodSchema("root_", "number of hours the root CA certificate is valid for.") {
+		s[name] = attribute
+	}
+	s["root_validity_period_hours"].Default = 10 * 365 * 24
+	for name, attribute := range validityPeriodSchema("intermediate_", "number of hours the intermediate CA certificate is valid for. Must not exceed the root validity.") {
+		s[name] = attribute
+	}
+	s["intermediate_validity_period_hours"].Default = 5 * 365 * 24
+	warnWeakParameters(s, pkiBootstrapWeakParameters)
+
+	return &schema.Resource{
+		Description:   "Generate a root CA and an issuing intermediate CA, with keys, certificates and chain",
+		CreateContext: resourcePKIBootstrapCreate,
+		ReadContext:   resourcePKIBootstrapRead,
+		UpdateContext: resourcePKIBootstrapUpdate,
+		DeleteContext: resourcePKIBootstrapDelete,
+		CustomizeDiff: resourcePKIBootstrapCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+func resourcePKIBootstrapCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	rootValidity := effectiveValidityPeriod(d.Get("root_validity_period").(string), d.Get("root_validity_period_hours").(int))
+	intermediateValidity := effectiveValidityPeriod(d.Get("intermediate_validity_period").(string), d.Get("intermediate_validity_period_hours").(int))
+	if intermediateValidity > rootValidity {
+		return diag.FromErr(fmt.Errorf("the intermediate validity (%s) exceeds the root validity (%s)", intermediateValidity, rootValidity))
+	}
+
+	algorithm := keyAlgorithmOrProviderDefault(d, m, ECDSA)
+	rsaBits := rsaBitsOrProviderDefault(d, m, 4096)
+	curve := ecdsaCurveOrProviderDefault(d, m, P384)
+
+	rootKey, err := generatePrivateKey(algorithm, rsaBits, curve)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	intermediateKey, err := generatePrivateKey(algorithm, rsaBits, curve)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	notBefore := now(d, m).UTC().Truncate(time.Second)
+
+	rootTemplate, err := pkiBootstrapCATemplate(d.Get("root_subject").([]interface{}), d.Get("serial_number_prefix").(string), m, notBefore, rootValidity, 1)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// crypto/x509 only fills the authority key identifier of certificates signed by another CA
+	if d.Get("root_authority_key_id").(string) == "subject_key_id" {
+		if rootTemplate.SubjectKeyId, err = subjectKeyID(rootKey.(crypto.Signer).Public()); err != nil {
+			return diag.FromErr(err)
+		}
+		rootTemplate.AuthorityKeyId = rootTemplate.SubjectKeyId
+	}
+
+	intermediateTemplate, err := pkiBootstrapCATemplate(d.Get("intermediate_subject").([]interface{}), d.Get("serial_number_prefix").(string), m, notBefore, intermediateValidity, 0)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	for _, domain := range d.Get("permitted_dns_domains").([]interface{}) {
+		intermediateTemplate.PermittedDNSDomains = append(intermediateTemplate.PermittedDNSDomains, domain.(string))
+	}
+	intermediateTemplate.PermittedDNSDomainsCritical = len(intermediateTemplate.PermittedDNSDomains) > 0
+	if err = policyExtensions(d, intermediateTemplate); err != nil {
+		return diag.FromErr(err)
+	}
+	if err = crlDistributionExtensions(d, intermediateTemplate); err != nil {
+		return diag.FromErr(err)
+	}
+
+	rootCertPem, err := signCertificate(rootTemplate, rootKey.(crypto.Signer).Public(), rootTemplate, rootKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign root CA certificate: %w", err))
+	}
+
+	rootCert, err := parsePEMCertificate([]byte(rootCertPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse root CA certificate: %w", err))
+	}
+
+	intermediateCertPem, err := signCertificate(intermediateTemplate, intermediateKey.(crypto.Signer).Public(), rootCert, rootKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign intermediate CA certificate: %w", err))
+	}
+
+	rootKeys, err := keyRepresentations(d, rootKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode root private key: %w", err))
+	}
+
+	intermediateKeys, err := keyRepresentations(d, intermediateKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode intermediate private key: %w", err))
+	}
+
+	if err = recordIssuedCertificateAuthorities(m, pkiBootstrapIssuancePolicy(d), rootCertPem, intermediateCertPem); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(hashForState(rootCertPem, intermediateCertPem))
+
+	values := map[string]interface{}{
+		"algorithm":             algorithm.String(),
+		"rsa_bits":              rsaBits,
+		"ecdsa_curve":           curve.String(),
+		"root_cert_pem":         rootCertPem,
+		"intermediate_cert_pem": intermediateCertPem,
+		"chain_pem":             intermediateCertPem + rootCertPem,
+	}
+	for _, prefix := range []string{"root_", "intermediate_"} {
+		if values[prefix+"cert_text"], err = certificateTextPEM(values[prefix+"cert_pem"].(string)); err != nil {
+			return diag.FromErr(err)
+		}
+		if values[prefix+"cert_json"], err = certificateJSONPEM(values[prefix+"cert_pem"].(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	for key, value := range certificateValidity("root_", rootTemplate, now(d, m)) {
+		values[key] = value
+	}
+	for key, value := range certificateValidity("intermediate_", intermediateTemplate, now(d, m)) {
+		values[key] = value
+	}
+	for suffix, value := range rootKeys {
+		values["root_"+suffix] = value
+	}
+	for suffix, value := range intermediateKeys {
+		values["intermediate_"+suffix] = value
+	}
+	if err = encryptPrivateKeys(d, values, pkiBootstrapPrivateKeys...); err != nil {
+		return diag.FromErr(err)
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourcePKIBootstrapRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	for _, prefix := range []string{"root_", "intermediate_"} {
+		if err := setCertificateValidity(d, m, prefix, d.Get(prefix+"cert_pem").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+
+	client, err := newHTTPClient("")
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	// the intermediate is signed by the root, which cannot be reissued alone
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("intermediate_cert_pem").(string), d.Get("root_cert_pem").(string))
+}
+
+// resourcePKIBootstrapUpdate records the new issuance policy and re-encodes both CA keys, the issuance policy and
+// keyFormatSchema attributes being the only ones changing in-place with check_revocation, which takes effect on the
+// next refresh.
+func resourcePKIBootstrapUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.HasChanges("max_issue_validity_hours", "max_issue_validity_action") {
+		if err := setIssuancePolicy(m, pkiBootstrapIssuancePolicy(d), d.Get("root_cert_pem").(string), d.Get("intermediate_cert_pem").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if !d.HasChanges("private_key_format", "openssh_output") {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, prefix := range []string{"root_", "intermediate_"} {
+		// keyFormatSetNewComputed left the new encoding unknown, the key is in the prior state
+		privateKeyPem, _ := d.GetChange(prefix + "private_key_pem")
+		prvKey, _, err := parsePrivateKeyPEM([]byte(privateKeyPem.(string)))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse %sprivate_key_pem: %w", prefix, err))
+		}
+
+		keys, err := keyRepresentations(d, prvKey)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("failed to encode %sprivate_key_pem: %w", prefix, err))
+		}
+		for suffix, value := range keys {
+			values[prefix+suffix] = value
+		}
+	}
+
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourcePKIBootstrapDelete(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := supersedeIssuedCertificates(m, now(d, m), d.Get("root_cert_pem").(string), d.Get("intermediate_cert_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+// pkiBootstrapWeakParameters are the checks of the weak parameters of tlsutils_pki_bootstrap.
+var pkiBootstrapWeakParameters = map[string]weakParameterCheck{
+	"rsa_bits": weakCARSABits,
+}
+
+func resourcePKIBootstrapCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := checkWeakParameters(diff, m, pkiBootstrapWeakParameters); err != nil {
+		return err
+	}
+
+	algorithm := keyAlgorithmOrProviderDefault(diff, m, ECDSA)
+	parameters := map[string]interface{}{
+		"algorithm":   algorithm.String(),
+		"rsa_bits":    rsaBitsOrProviderDefault(diff, m, 4096),
+		"ecdsa_curve": ecdsaCurveOrProviderDefault(diff, m, P384).String(),
+	}
+	if err := planKeyParameters(diff, parameters, keyParametersUsedBy(algorithm)...); err != nil {
+		return err
+	}
+
+	if _, ok := diff.GetOk("max_issue_validity_hours"); ok && providerIssuanceJournal(m) == nil {
+		return fmt.Errorf("max_issue_validity_hours requires the issuance_journal of the provider")
+	}
+
+	if diff.Id() == "" {
+		return nil
+	}
+
+	return keyFormatSetNewComputed(diff, "root_", "intermediate_")
+}
+
+// pkiBootstrapIssuancePolicy returns the issuance policy of the CAs, nil when max_issue_validity_hours is not set.
+func pkiBootstrapIssuancePolicy(d *schema.ResourceData) *issuancePolicy {
+	hours, ok := d.GetOk("max_issue_validity_hours")
+	if !ok {
+		return nil
+	}
+
+	action := d.Get("max_issue_validity_action").(string)
+	if action == "" {
+		action = "truncate"
+	}
+
+	return &issuancePolicy{MaxValidityHours: hours.(int), Action: action}
+}
+
+// pkiBootstrapCATemplate builds a CA certificate template restricted to certificate, CRL and OCSP response signing.
+func pkiBootstrapCATemplate(subjects []interface{}, serialNumberPrefix string, m interface{}, notBefore time.Time, validity time.Duration, maxPathLen int) (*x509.Certificate, error) {
+	serialNumber, err := prefixedSerialNumber(serialNumberPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := pkix.Name{}
+	if len(subjects) > 0 && subjects[0] != nil {
+		subject = certificateSubject(subjects[0].(map[string]interface{}))
+	}
+	subject = subjectWithDefaults(subject, m)
+	if len(subject.ToRDNSequence()) == 0 {
+		return nil, fmt.Errorf("CA certificates need a non-empty subject")
+	}
+
+	return &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            maxPathLen,
+		MaxPathLenZero:        maxPathLen == 0,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourcePublicKeyEncrypt() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Encrypt a small secret to a public key, for systems publishing only a public key such as the GitHub Actions secrets API or cloud key import tokens",
+		CreateContext: resourcePublicKeyEncryptCreate,
+		ReadContext:   resourcePublicKeyEncryptRead,
+		DeleteContext: resourcePublicKeyEncryptDelete,
+		Schema: map[string]*schema.Schema{
+			"public_key": {
+				Description: "public key to encrypt to: public key, certificate or private key in PEM format (RSA, ECDSA or X25519), or base64 encoded raw X25519 public key as published by the GitHub Actions secrets API.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"plaintext": {
+				Description: "secret to encrypt.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Sensitive:   true,
+			},
+			"scheme": {
+				Description:      "encryption scheme: `rsa_oaep` (RSA keys), `hpke` (RFC 9180 base mode with DHKEM of the ECDSA or X25519 key, HKDF-SHA256 and AES-128-GCM) or `sealed_box` (libsodium crypto_box_seal to an X25519 key, as expected by the GitHub Actions secrets API). Defaults to `rsa_oaep` for RSA keys and `hpke` for the others.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{publicKeyEncryptRSAOAEP, publicKeyEncryptHPKE, publicKeyEncryptSealedBox}, false)),
+			},
+			"oaep_hash": {
+				Description:      "hash of `rsa_oaep`: `sha1`, `sha256`, `sha384` or `sha512`, used for both the label and MGF1. AWS KMS import tokens accept `sha1` and `sha256`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          "sha256",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedHashAlgorithmsStr(), false)),
+			},
+			"hpke_info": {
+				Description: "application info bound to the key schedule of `hpke`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"ciphertext_base64": {
+				Description: "base64 encoded ciphertext. With `hpke`, the encapsulated key followed by the AES-GCM ciphertext.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourcePublicKeyEncryptCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	pubKey, err := parseEncryptionPublicKey(d.Get("public_key").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	scheme := d.Get("scheme").(string)
+	if scheme == "" {
+		scheme = defaultPublicKeyEncryptScheme(pubKey)
+	}
+
+	ciphertext, err := encryptToPublicKey(pubKey, scheme, []byte(d.Get("plaintext").(string)), hashAlgorithms[d.Get("oaep_hash").(string)], []byte(d.Get("hpke_info").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	ciphertextBase64 := base64.StdEncoding.EncodeToString(ciphertext)
+
+	d.SetId(hashForState(ciphertextBase64))
+
+	values := map[string]string{
+		"scheme":            scheme,
+		"ciphertext_base64": ciphertextBase64,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourcePublicKeyEncryptRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourcePublicKeyEncryptDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// ownerOnlyPermissionRegexp matches octal file modes granting nothing to group and others.
+var ownerOnlyPermissionRegexp = regexp.MustCompile(`^0[0-7]00$`)
+
+func resourceSecureFile() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Write key or certificate material to a local file with owner-only permissions",
+		CreateContext: resourceSecureFileCreate,
+		ReadContext:   resourceSecureFileRead,
+		UpdateContext: resourceSecureFileUpdate,
+		DeleteContext: resourceSecureFileDelete,
+		CustomizeDiff: resourceSecureFileCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"filename": {
+				Description: "path of the file to write.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"content": {
+				Description:  "content of the file.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				ExactlyOneOf: []string{"content", "content_base64"},
+			},
+			"content_base64": {
+				Description:      "base64 encoded content of the file, for binary material.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsBase64),
+				ExactlyOneOf:     []string{"content", "content_base64"},
+			},
+			"file_permission": {
+				Description:      "owner-only octal permission of the file, e.g. `0600` or `0400`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0600",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(ownerOnlyPermissionRegexp, "must be an owner-only octal permission like 0600 or 0400")),
+			},
+			"directory_permission": {
+				Description:      "owner-only octal permission of parent directories created for the file.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0700",
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(ownerOnlyPermissionRegexp, "must be an owner-only octal permission like 0700")),
+			},
+			"shred_on_destroy": {
+				Description: "overwrite the file with random data before removing it on destroy.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"content_sha256": {
+				Description: "SHA-256 hash of the file content, in hex.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourceSecureFileCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	content, err := resourceSecureFileContent(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	filename := d.Get("filename").(string)
+
+	dirPerm, err := parseFilePermission(d.Get("directory_permission").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid directory_permission: %w", err))
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), dirPerm); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to create directory for %s: %w", filename, err))
+	}
+
+	if diags := resourceSecureFileWrite(d, filename, content); diags.HasError() {
+		return diags
+	}
+
+	// the file stays the same object when its content changes in-place
+	d.SetId(filename)
+
+	return nil
+}
+
+func resourceSecureFileRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	filename := d.Get("filename").(string)
+
+	info, err := os.Stat(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to stat %s: %w", filename, err))
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to read %s: %w", filename, err))
+	}
+
+	// Content changed outside of terraform: the file has to be written again
+	if hash := sha256.Sum256(content); hex.EncodeToString(hash[:]) != d.Get("content_sha256").(string) {
+		d.SetId("")
+		return nil
+	}
+
+	if err = d.Set("file_permission", fmt.Sprintf("%04o", info.Mode().Perm())); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save file_permission: %w", err))
+	}
+
+	return nil
+}
+
+func resourceSecureFileUpdate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	content, err := resourceSecureFileContent(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceSecureFileWrite(d, d.Get("filename").(string), content)
+}
+
+func resourceSecureFileDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	filename := d.Get("filename").(string)
+
+	if d.Get("shred_on_destroy").(bool) {
+		if err := shredFile(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return diag.FromErr(fmt.Errorf("failed to shred %s: %w", filename, err))
+		}
+	}
+
+	if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return diag.FromErr(fmt.Errorf("failed to remove %s: %w", filename, err))
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceSecureFileCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	if diff.Id() != "" && (diff.HasChange("content") || diff.HasChange("content_base64")) {
+		return diff.SetNewComputed("content_sha256")
+	}
+
+	return nil
+}
+
+// resourceSecureFileContent returns the configured content, decoding content_base64 when used.
+func resourceSecureFileContent(d *schema.ResourceData) ([]byte, error) {
+	if contentBase64, ok := d.GetOk("content_base64"); ok {
+		content, err := base64.StdEncoding.DecodeString(contentBase64.(string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode content_base64: %w", err)
+		}
+		return content, nil
+	}
+
+	return []byte(d.Get("content").(string)), nil
+}
+
+// resourceSecureFileWrite atomically writes content to filename and stores its hash.
+func resourceSecureFileWrite(d *schema.ResourceData, filename string, content []byte) diag.Diagnostics {
+	perm, err := parseFilePermission(d.Get("file_permission").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid file_permission: %w", err))
+	}
+
+	if err = writeFileAtomic(filename, content, perm); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to write %s: %w", filename, err))
+	}
+
+	hash := sha256.Sum256(content)
+	if err = d.Set("content_sha256", hex.EncodeToString(hash[:])); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save content_sha256: %w", err))
+	}
+
+	return nil
+}
+
+// parseFilePermission parses an octal permission string like "0600".
+func parseFilePermission(perm string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return os.FileMode(mode), nil
+}
+
+// writeFileAtomic writes content to a temporary file in the same directory as filename,
+// with perm applied before any content is written, and then renames it over filename.
+// Readers never observe a partially written or temporarily world-readable file.
+func writeFileAtomic(filename string, content []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err = tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, filename)
+}
+
+// shredFile overwrites the content of filename with random data and flushes it to disk.
+// Journaling and copy-on-write filesystems may still retain older copies of the data.
+func shredFile(filename string) error {
+	// the file is usually read-only, e.g. 0400 for private keys, and is removed right after
+	if err := os.Chmod(filename, 0600); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.CopyN(f, rand.Reader, info.Size()); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
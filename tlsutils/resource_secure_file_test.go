@@ -0,0 +1,71 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourceSecureFileUpdateContent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keys", "server.key")
+	r := resourceSecureFile()
+	created := testResourceApply(t, r, nil, map[string]interface{}{"filename": filename, "content": "first"}, &providerMeta{})
+	if created.ID != filename {
+		t.Errorf("expected ID %s, got %s", filename, created.ID)
+	}
+
+	updated := testResourceApply(t, r, created, map[string]interface{}{"filename": filename, "content": "second"}, &providerMeta{})
+	if updated.ID != created.ID {
+		t.Errorf("expected the update to keep ID %s, got %s", created.ID, updated.ID)
+	}
+	hash := sha256.Sum256([]byte("second"))
+	if got, want := updated.Attributes["content_sha256"], hex.EncodeToString(hash[:]); got != want {
+		t.Errorf("expected content_sha256 %s, got %s", want, got)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "second" {
+		t.Errorf("expected the new content to be written, got %q", content)
+	}
+}
+
+func TestResourceSecureFileShredReadOnly(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "server.key")
+	r := resourceSecureFile()
+	created := testResourceApply(t, r, nil, map[string]interface{}{
+		"filename":         filename,
+		"content":          "secret",
+		"file_permission":  "0400",
+		"shred_on_destroy": true,
+	}, &providerMeta{})
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0400 {
+		t.Fatalf("expected file permission 0400, got %04o", info.Mode().Perm())
+	}
+
+	if err = shredFile(filename); err != nil {
+		t.Fatalf("unable to shred a read-only file: %s", err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content) != len("secret") || string(content) == "secret" {
+		t.Errorf("expected the content to be overwritten, got %q", content)
+	}
+
+	if diags := resourceSecureFileDelete(context.Background(), r.Data(created), &providerMeta{}); diags.HasError() {
+		t.Fatalf("delete failed: %v", diags)
+	}
+	if _, err = os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+func resourceSessionTicketKeys() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Generate and rotate TLS session ticket keys for nginx and HAProxy",
+		CreateContext: resourceSessionTicketKeysCreate,
+		ReadContext:   resourceSessionTicketKeysRead,
+		UpdateContext: resourceSessionTicketKeysUpdate,
+		DeleteContext: resourceSessionTicketKeysDelete,
+		CustomizeDiff: resourceSessionTicketKeysCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"key_length": {
+				Description:      "length of the keys in bytes: 48 (AES-128) or 80 (AES-256).",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          80,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntInSlice([]int{48, 80})),
+			},
+			"rotation_interval_hours": {
+				Description:      "rotate the keys when this many hours have passed since the last rotation. 0 disables time based rotation.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"rotation_trigger": {
+				Description: "arbitrary value; changing it rotates the keys.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"current_base64": {
+				Description: "key used to encrypt new tickets, base64 encoded. Decode it to the first nginx `ssl_session_ticket_key` file.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"previous_base64": {
+				Description: "key replaced by the last rotation, still accepted to decrypt tickets, base64 encoded. Decode it to the second nginx `ssl_session_ticket_key` file.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"next_base64": {
+				Description: "key that becomes current at the next rotation, base64 encoded.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"haproxy_tls_ticket_keys": {
+				Description: "content of an HAProxy `tls-ticket-keys` file: the previous, current and next keys, one per line, so HAProxy encrypts with the current one.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"rotated_at": {
+				Description: "time of the last rotation, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourceSessionTicketKeysCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	keys := make([]string, 3)
+	for i := range keys {
+		key, err := resourceSessionTicketKeysGenerate(d.Get("key_length").(int))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		keys[i] = key
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(id)
+
+	return resourceSessionTicketKeysSet(d, m, keys[0], keys[1], keys[2])
+}
+
+func resourceSessionTicketKeysRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceSessionTicketKeysUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	rotate, err := rotationDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !rotate && !d.HasChange("rotation_trigger") {
+		return nil
+	}
+
+	next, err := resourceSessionTicketKeysGenerate(d.Get("key_length").(int))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// the plan left the keys unknown, the rotation shifts those of the prior state
+	previous, _ := d.GetChange("current_base64")
+	current, _ := d.GetChange("next_base64")
+	return resourceSessionTicketKeysSet(d, m, previous.(string), current.(string), next)
+}
+
+func resourceSessionTicketKeysDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceSessionTicketKeysCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	rotate, err := rotationDue(diff, m)
+	if err != nil {
+		return err
+	}
+	if rotate || diff.HasChange("rotation_trigger") {
+		for _, computed := range []string{"current_base64", "previous_base64", "next_base64", "haproxy_tls_ticket_keys", "rotated_at"} {
+			if err = diff.SetNewComputed(computed); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resourceSessionTicketKeysGenerate returns a random key of the given length, base64 encoded.
+func resourceSessionTicketKeysGenerate(length int) (string, error) {
+	key := make([]byte, length)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate session ticket key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// resourceSessionTicketKeysSet stores the keys and their HAProxy file, recording the rotation time.
+func resourceSessionTicketKeysSet(d *schema.ResourceData, m interface{}, previous, current, next string) diag.Diagnostics {
+	rotatedAt := now(d, m).UTC().Format(time.RFC3339)
+
+	values := map[string]string{
+		"previous_base64":         previous,
+		"current_base64":          current,
+		"next_base64":             next,
+		"haproxy_tls_ticket_keys": previous + "\n" + current + "\n" + next + "\n",
+		"rotated_at":              rotatedAt,
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package tlsutils
+
+import (
+	"testing"
+)
+
+func TestResourceSessionTicketKeysRotation(t *testing.T) {
+	r := resourceSessionTicketKeys()
+	state := testResourceApply(t, r, nil, map[string]interface{}{"rotation_trigger": "0"}, &providerMeta{})
+
+	for _, trigger := range []string{"1", "2"} {
+		rotated := testResourceApply(t, r, state, map[string]interface{}{"rotation_trigger": trigger}, &providerMeta{})
+
+		if got, want := rotated.Attributes["previous_base64"], state.Attributes["current_base64"]; got != want {
+			t.Errorf("rotation %s: expected previous_base64 %q, got %q", trigger, want, got)
+		}
+		if got, want := rotated.Attributes["current_base64"], state.Attributes["next_base64"]; got != want {
+			t.Errorf("rotation %s: expected current_base64 %q, got %q", trigger, want, got)
+		}
+		next := rotated.Attributes["next_base64"]
+		if next == "" || next == state.Attributes["next_base64"] || next == state.Attributes["current_base64"] {
+			t.Errorf("rotation %s: expected a new next_base64, got %q", trigger, next)
+		}
+		if want := rotated.Attributes["previous_base64"] + "\n" + rotated.Attributes["current_base64"] + "\n" + next + "\n"; rotated.Attributes["haproxy_tls_ticket_keys"] != want {
+			t.Errorf("rotation %s: expected haproxy_tls_ticket_keys %q, got %q", trigger, want, rotated.Attributes["haproxy_tls_ticket_keys"])
+		}
+		state = rotated
+	}
+}
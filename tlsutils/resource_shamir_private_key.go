@@ -0,0 +1,232 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+func resourceShamirPrivateKey() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"algorithm": {
+			Description:      "name of the algorithm to use when generating the private key. Defaults to the `default_key_algorithm` of the provider, then `RSA`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedAlgorithmsStr(), false)),
+		},
+		"rsa_bits": {
+			Description:      "size of the RSA key in bits, when `algorithm` is `RSA`. Defaults to the `default_rsa_bits` of the provider, then 4096.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2048)),
+		},
+		"ecdsa_curve": {
+			Description:      "elliptic curve of the key, when `algorithm` is `ECDSA`. Defaults to the `default_ecdsa_curve` of the provider, then `P384`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			Computed:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedECDSACurvesStr(), false)),
+		},
+		"threshold": {
+			Description:      "number of shares needed to rebuild the private key.",
+			Type:             schema.TypeInt,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(2)),
+		},
+		"share_recipient": {
+			Description: "recipients of the shares, one share each. Exactly one of `age_recipient` and `pgp_key` must be set per recipient.",
+			Type:        schema.TypeList,
+			Required:    true,
+			ForceNew:    true,
+			MinItems:    2,
+			MaxItems:    255,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"age_recipient": {
+						Description: "age X25519 recipient (`age1...`).",
+						Type:        schema.TypeString,
+						Optional:    true,
+						ForceNew:    true,
+					},
+					"pgp_key": {
+						Description: "PGP public key, ASCII armored or base64 encoded.",
+						Type:        schema.TypeString,
+						Optional:    true,
+						ForceNew:    true,
+					},
+				},
+			},
+		},
+		"public_key_pem": {
+			Description: "public key in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"certificate_pem": {
+			Description: "self-signed root certificate of the private key in PEM format, signed before the private key is split.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"shares": {
+			Description: "base64 encoded shares of the private key PEM, in the order of `share_recipient`, each encrypted to its recipient and ASCII armored.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+	}
+	for name, attribute := range certificateIssueSchema() {
+		s[name] = attribute
+	}
+	// a self-signed root has no issuer certificate to identify nor to outlive
+	delete(s, "authority_key_id")
+	delete(s, "truncate_to_ca_expiry")
+	warnWeakParameters(s, shamirPrivateKeyWeakParameters)
+
+	return &schema.Resource{
+		Description:   "Generate a private key with its self-signed root certificate, split into Shamir shares each encrypted to a distinct recipient",
+		CreateContext: resourceShamirPrivateKeyCreate,
+		ReadContext:   resourceShamirPrivateKeyRead,
+		DeleteContext: resourceShamirPrivateKeyDelete,
+		CustomizeDiff: resourceShamirPrivateKeyCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+func resourceShamirPrivateKeyCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	recipients := d.Get("share_recipient").([]interface{})
+	threshold := d.Get("threshold").(int)
+	if threshold > len(recipients) {
+		return diag.FromErr(fmt.Errorf("threshold %d is greater than the %d share recipients", threshold, len(recipients)))
+	}
+
+	encryptors := make([]func([]byte) (string, error), len(recipients))
+	for i, recipient := range recipients {
+		recipient := recipient.(map[string]interface{})
+		ageRecipient := recipient["age_recipient"].(string)
+		pgpKey := recipient["pgp_key"].(string)
+		if (ageRecipient == "") == (pgpKey == "") {
+			return diag.FromErr(fmt.Errorf("share_recipient %d: exactly one of age_recipient and pgp_key must be set", i))
+		}
+		encryptors[i] = recipientEncryptor(ageRecipient, pgpKey)
+	}
+
+	algorithm := keyAlgorithmOrProviderDefault(d, m, RSA)
+	rsaBits := rsaBitsOrProviderDefault(d, m, 4096)
+	curve := ecdsaCurveOrProviderDefault(d, m, P384)
+	prvKey, err := generatePrivateKey(algorithm, rsaBits, curve)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	privateKeyPem, err := privateKeyToPEM(prvKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode private key PEM: %w", err))
+	}
+
+	publicKeyPem, err := publicKeyToPEM(prvKey)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to encode public key PEM: %w", err))
+	}
+
+	// the root certificate is signed now, as the private key is not available anymore once split
+	template, err := certificateTemplate(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	template.IsCA = true
+	template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	pubKey := prvKey.(crypto.Signer).Public()
+	// crypto/x509 derives the subject key identifier of CA certificates unless ski_method or subject_key_id is set
+	if err = setSubjectKeyID(template, pubKey, d.Get("subject_key_id").(string), d.Get("ski_method").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err = setExtensionCriticality(template, d.Get("extension_criticality").(map[string]interface{})); err != nil {
+		return diag.FromErr(err)
+	}
+	certificatePem, err := signCertificate(template, pubKey, template, prvKey)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	cert, err := parsePEMCertificate([]byte(certificatePem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse issued certificate: %w", err))
+	}
+	if diags := validateCertificatePreset(d.Get("validation_preset").(string), cert); diags.HasError() {
+		return diags
+	}
+
+	parts, err := shamirSplit([]byte(privateKeyPem), len(recipients), threshold)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to split private key: %w", err))
+	}
+
+	shares := make([]string, len(parts))
+	for i, part := range parts {
+		if shares[i], err = encryptors[i]([]byte(base64.StdEncoding.EncodeToString(part))); err != nil {
+			return diag.FromErr(fmt.Errorf("share_recipient %d: %w", i, err))
+		}
+	}
+
+	d.SetId(hashForState(publicKeyPem))
+
+	values := map[string]interface{}{
+		"algorithm":           algorithm.String(),
+		"rsa_bits":            rsaBits,
+		"ecdsa_curve":         curve.String(),
+		"public_key_pem":      publicKeyPem,
+		"certificate_pem":     certificatePem,
+		"validity_start_time": template.NotBefore.Format(time.RFC3339),
+		"validity_end_time":   template.NotAfter.Format(time.RFC3339),
+		"shares":              shares,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourceShamirPrivateKeyRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+// shamirPrivateKeyWeakParameters are the checks of the weak parameters of tlsutils_shamir_private_key, whose key is
+// the one of a root CA.
+var shamirPrivateKeyWeakParameters = map[string]weakParameterCheck{
+	"rsa_bits": weakCARSABits,
+}
+
+func resourceShamirPrivateKeyCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := checkWeakParameters(diff, m, shamirPrivateKeyWeakParameters); err != nil {
+		return err
+	}
+
+	algorithm := keyAlgorithmOrProviderDefault(diff, m, RSA)
+	parameters := map[string]interface{}{
+		"algorithm":   algorithm.String(),
+		"rsa_bits":    rsaBitsOrProviderDefault(diff, m, 4096),
+		"ecdsa_curve": ecdsaCurveOrProviderDefault(diff, m, P384).String(),
+	}
+
+	return planKeyParameters(diff, parameters, keyParametersUsedBy(algorithm)...)
+}
+
+func resourceShamirPrivateKeyDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
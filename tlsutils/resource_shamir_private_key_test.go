@@ -0,0 +1,99 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"filippo.io/age"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"strings"
+	"testing"
+)
+
+func TestResourceShamirPrivateKey(t *testing.T) {
+	first, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, pgpKey := testPGPEntity(t)
+
+	r := resourceShamirPrivateKey()
+	state := testResourceApply(t, r, nil, map[string]interface{}{
+		"algorithm":             "ECDSA",
+		"ecdsa_curve":           "P256",
+		"threshold":             2,
+		"validity_period_hours": 24,
+		"allowed_uses":          []interface{}{"digital_signature"},
+		"subject":               []interface{}{map[string]interface{}{"common_name": "Offline Root CA"}},
+		"share_recipient": []interface{}{
+			map[string]interface{}{"age_recipient": first.Recipient().String()},
+			map[string]interface{}{"age_recipient": second.Recipient().String()},
+			map[string]interface{}{"pgp_key": pgpKey},
+		},
+	}, nil)
+
+	if state.Attributes["shares.#"] != "3" {
+		t.Fatalf("expected three shares, got %s", state.Attributes["shares.#"])
+	}
+	var shares [][]byte
+	for _, share := range []string{
+		testAgeDecrypt(t, state.Attributes["shares.0"], first),
+		testPGPDecrypt(t, state.Attributes["shares.2"], entity),
+	} {
+		part, err := base64.StdEncoding.DecodeString(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares = append(shares, part)
+	}
+	prvKey, _, err := parsePrivateKeyPEM(testShamirCombine(shares))
+	if err != nil {
+		t.Fatalf("expected the private key to be rebuilt from two shares: %s", err)
+	}
+
+	cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privateKeyMatchesCertificate(prvKey, cert) {
+		t.Errorf("expected the root certificate of the split private key")
+	}
+	if err = cert.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("expected a self-signed root certificate: %s", err)
+	}
+	if !cert.IsCA || cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign|x509.KeyUsageCRLSign || len(cert.SubjectKeyId) == 0 {
+		t.Errorf("expected a CA with a subject key identifier and the signing usages, got IsCA %t and key usage %b", cert.IsCA, cert.KeyUsage)
+	}
+	if cert.Subject.CommonName != "Offline Root CA" || cert.NotAfter.Sub(cert.NotBefore).Hours() != 24 {
+		t.Errorf("expected the subject and validity of the configuration, got %s from %s to %s", cert.Subject, cert.NotBefore, cert.NotAfter)
+	}
+	if state.Attributes["validity_end_time"] == "" {
+		t.Errorf("expected the validity end time")
+	}
+}
+
+func TestResourceShamirPrivateKeyThreshold(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := resourceShamirPrivateKey()
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"threshold":             3,
+		"validity_period_hours": 24,
+		"share_recipient": []interface{}{
+			map[string]interface{}{"age_recipient": identity.Recipient().String()},
+			map[string]interface{}{"age_recipient": identity.Recipient().String()},
+		},
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, nil); !diags.HasError() || !strings.Contains(diags[0].Summary, "threshold 3 is greater than the 2 share recipients") {
+		t.Errorf("expected a threshold error, got %v", diags)
+	}
+}
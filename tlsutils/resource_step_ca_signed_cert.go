@@ -0,0 +1,400 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// stepCATokenLifetime is the validity of the provisioning tokens built for JWK provisioners, as with `step ca token`.
+const stepCATokenLifetime = 5 * time.Minute
+
+func resourceStepCASignedCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"ca_url": {
+			Description:      "URL of the step-ca instance, e.g. `https://ca.example.com`.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPS),
+		},
+		"root_cert_pem": {
+			Description:      "root certificate of the step-ca instance in PEM format, trusted for the TLS connection in addition to the system roots, and pinned in the provisioning tokens like `step ca token --root`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"jwk_provisioner": {
+			Description:  "JWK provisioner the provisioning token is built and signed with.",
+			Type:         schema.TypeList,
+			Optional:     true,
+			MaxItems:     1,
+			ExactlyOneOf: []string{"jwk_provisioner", "oidc_token"},
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Description: "name of the provisioner, as in `step ca provisioner list`.",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"key": {
+						Description: "private key of the provisioner: the `encryptedKey` of `step ca provisioner list` with `password`, or a private JWK in JSON without it.",
+						Type:        schema.TypeString,
+						Required:    true,
+						Sensitive:   true,
+					},
+					"password": {
+						Description: "password decrypting `key`.",
+						Type:        schema.TypeString,
+						Optional:    true,
+						Sensitive:   true,
+					},
+					"key_id": {
+						Description: "`kid` of the provisioner key. Defaults to the `kid` of the JWK, then to its thumbprint, as for the keys generated by step.",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+				},
+			},
+		},
+		"oidc_token": {
+			Description:  "ID token issued by the identity provider of an OIDC provisioner, sent as the provisioning token.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			ExactlyOneOf: []string{"jwk_provisioner", "oidc_token"},
+		},
+		"early_renewal_hours": {
+			Description:      "sign a new certificate in-place this many hours before the current one expires. 0 disables early renewal. Defaults to the `default_early_renewal_hours` of the provider, then 0.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		},
+		"certificate_pem": {
+			Description: "signed certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"issuing_ca_pem": {
+			Description: "issuing CA certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ca_chain_pem": {
+			Description: "certificate chain returned by step-ca after the signed certificate, in PEM format.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"fullchain_pem": {
+			Description: "signed certificate followed by the CA chain without self-signed roots, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"serial_number": {
+			Description: "serial number of the signed certificate, as colon separated hex.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the signed certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range validityPeriodSchema("", "requested validity of the certificate. Defaults to the default validity of the provider, then to the default of the provisioner.") {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range generatedCSRSchema("certificate signing request in PEM format. The common name, or the first SAN when there is none, is the subject of the provisioning token and the SANs, or the common name when there are none, are its `sans`.") {
+		s[name] = attribute
+	}
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+	warnWeakParameters(s, stepCASignedCertWeakParameters)
+
+	return &schema.Resource{
+		Description:   "Sign a CSR with a Smallstep step-ca instance, using a JWK or OIDC provisioner",
+		CreateContext: resourceStepCASignedCertCreate,
+		ReadContext:   resourceStepCASignedCertRead,
+		UpdateContext: resourceStepCASignedCertUpdate,
+		DeleteContext: resourceStepCASignedCertDelete,
+		CustomizeDiff: resourceStepCASignedCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// stepCASignRequest is the body of the step-ca /1.0/sign endpoint.
+type stepCASignRequest struct {
+	CSR      string `json:"csr"`
+	OTT      string `json:"ott"`
+	NotAfter string `json:"notAfter,omitempty"`
+}
+
+// stepCASignResponse is the relevant part of the response of the step-ca /1.0/sign endpoint.
+type stepCASignResponse struct {
+	Certificate string   `json:"crt"`
+	CA          string   `json:"ca"`
+	CertChain   []string `json:"certChain"`
+}
+
+func resourceStepCASignedCertCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := generateCertificateRequest(d, m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceStepCASignedCertSign(ctx, d, m)
+}
+
+// resourceStepCASignedCertSign signs the CSR with step-ca and stores the certificate returned.
+func resourceStepCASignedCertSign(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	csrPem := d.Get("csr_pem").(string)
+	csr, err := parsePEMCertificateRequest([]byte(csrPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse csr_pem: %w", err))
+	}
+
+	var root *x509.Certificate
+	if rootPem := d.Get("root_cert_pem").(string); rootPem != "" {
+		if root, err = parsePEMCertificate([]byte(rootPem)); err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse root_cert_pem: %w", err))
+		}
+	}
+
+	signURL := strings.TrimRight(d.Get("ca_url").(string), "/") + "/1.0/sign"
+	ott := d.Get("oidc_token").(string)
+	if provisioners := d.Get("jwk_provisioner").([]interface{}); len(provisioners) > 0 && provisioners[0] != nil {
+		if ott, err = stepCAJWKToken(provisioners[0].(map[string]interface{}), csr, signURL, root, time.Now()); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to build provisioning token: %w", err))
+		}
+	}
+
+	reqBody := stepCASignRequest{CSR: csrPem, OTT: ott}
+	if validityPeriod := validityPeriodOrProviderDefault(d, "validity_period", "validity_period_hours", m); validityPeriod > 0 {
+		reqBody.NotAfter = formatValidityPeriod(validityPeriod)
+	}
+
+	client, err := newHTTPClient(d.Get("root_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure step-ca client: %w", err))
+	}
+	var resp stepCASignResponse
+	if err = doJSONRequest(ctx, client, http.MethodPost, signURL, nil, reqBody, &resp); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign CSR with step-ca: %w", err))
+	}
+
+	cert, err := parsePEMCertificate([]byte(resp.Certificate))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("step-ca returned an invalid certificate: %w", err))
+	}
+
+	// certChain starts with the signed certificate itself
+	chain := resp.CertChain
+	if len(chain) > 0 {
+		chain = chain[1:]
+	} else if resp.CA != "" {
+		chain = []string{resp.CA}
+	}
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), resp.Certificate, chain...)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build full chain: %w", err))
+	}
+	certText, err := certificateTextPEM(resp.Certificate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	certJSON, err := certificateJSONPEM(resp.Certificate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serialNumber := colonHex(cert.SerialNumber.Bytes())
+	d.SetId(serialNumber)
+
+	values := map[string]interface{}{
+		"certificate_pem": resp.Certificate,
+		"cert_text":       certText,
+		"cert_json":       certJSON,
+		"issuing_ca_pem":  resp.CA,
+		"ca_chain_pem":    chain,
+		"fullchain_pem":   fullChainPem,
+		"serial_number":   serialNumber,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+	if err = setCertificateValidity(d, m, "", resp.Certificate); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceStepCASignedCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := setCertificateValidity(d, m, "", d.Get("certificate_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+
+	client, err := newHTTPClient(d.Get("root_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("certificate_pem").(string), d.Get("issuing_ca_pem").(string))
+}
+
+func resourceStepCASignedCertUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// The other in-place attributes are only used by later requests to step-ca.
+	renew, err := earlyRenewalDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !renew {
+		return diag.FromErr(updateFullChain(d, m, listOfStrings(d.Get("ca_chain_pem").([]interface{}))...))
+	}
+
+	return resourceStepCASignedCertSign(ctx, d, m)
+}
+
+func resourceStepCASignedCertDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+// stepCASignedCertWeakParameters are the checks of the weak parameters of tlsutils_step_ca_signed_cert.
+var stepCASignedCertWeakParameters = map[string]weakParameterCheck{
+	"validity_period_hours": weakServerValidityHours,
+	"validity_period":       weakServerValidityPeriod,
+}
+
+func resourceStepCASignedCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := checkWeakParameters(diff, m, stepCASignedCertWeakParameters); err != nil {
+		return err
+	}
+
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if err := planFullChains(diff, "fullchain_pem"); err != nil {
+		return err
+	}
+
+	renew, err := earlyRenewalDue(diff, m)
+	if err != nil || !renew {
+		return err
+	}
+
+	for _, computed := range []string{"certificate_pem", "cert_text", "cert_json", "issuing_ca_pem", "ca_chain_pem", "fullchain_pem", "serial_number", "not_before", "not_after", "not_before_unix", "not_after_unix", "remaining_seconds"} {
+		if err = diff.SetNewComputed(computed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stepCAJWKToken builds the provisioning token of a jwk_provisioner block for the CSR,
+// with the claims of `step ca token`.
+func stepCAJWKToken(provisioner map[string]interface{}, csr *x509.CertificateRequest, audience string, root *x509.Certificate, at time.Time) (string, error) {
+	jwkJSON := []byte(provisioner["key"].(string))
+	if password := provisioner["password"].(string); password != "" {
+		var err error
+		if jwkJSON, err = decryptPBES2JWE(string(jwkJSON), []byte(password)); err != nil {
+			return "", fmt.Errorf("unable to decrypt jwk_provisioner key: %w", err)
+		}
+	}
+	var jwk jsonWebKey
+	if err := json.Unmarshal(jwkJSON, &jwk); err != nil {
+		return "", fmt.Errorf("unable to parse jwk_provisioner key: %w", err)
+	}
+	prvKey, err := jwkToPrivateKey(&jwk)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse jwk_provisioner key: %w", err)
+	}
+
+	kid := provisioner["key_id"].(string)
+	if kid == "" {
+		kid = jwk.Kid
+	}
+	if kid == "" {
+		if kid, err = jwk.thumbprint(); err != nil {
+			return "", err
+		}
+	}
+
+	sans := make([]string, 0)
+	sans = append(sans, csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, csr.EmailAddresses...)
+	for _, uri := range csr.URIs {
+		sans = append(sans, uri.String())
+	}
+	subject := csr.Subject.CommonName
+	if subject == "" && len(sans) > 0 {
+		subject = sans[0]
+	}
+	if subject == "" {
+		return "", fmt.Errorf("the CSR has neither a common name nor SANs")
+	}
+	if len(sans) == 0 {
+		// like step, the common name is the only SAN of the certificate step-ca issues
+		sans = append(sans, subject)
+	}
+
+	jti := make([]byte, 32)
+	if _, err = rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	claims := map[string]interface{}{
+		"iss":  provisioner["name"].(string),
+		"sub":  subject,
+		"aud":  audience,
+		"iat":  at.Unix(),
+		"nbf":  at.Unix(),
+		"exp":  at.Add(stepCATokenLifetime).Unix(),
+		"jti":  hex.EncodeToString(jti),
+		"sans": sans,
+	}
+	if root != nil {
+		fingerprint := sha256.Sum256(root.Raw)
+		claims["sha"] = hex.EncodeToString(fingerprint[:])
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token claims: %w", err)
+	}
+
+	return signCompactJWS(prvKey, map[string]interface{}{"kid": kid, "typ": "JWT"}, payload)
+}
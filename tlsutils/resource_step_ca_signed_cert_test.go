@@ -0,0 +1,220 @@
+package tlsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"filippo.io/age"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStepCAServer serves the /1.0/sign endpoint of step-ca over TLS, signing certificates valid for validity with
+// caCert and caKey. The provisioning tokens received are sent to tokens when it is not nil.
+func testStepCAServer(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateKey, validity time.Duration, tokens chan<- string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/1.0/sign" {
+			http.NotFound(w, r)
+			return
+		}
+		var req stepCASignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tokens != nil {
+			tokens <- req.OTT
+		}
+
+		cert, err := testSignCertificateRequest(caCert, caKey, req.CSR, validity)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		certPem := certificateToPEM(cert)
+		_ = json.NewEncoder(w).Encode(stepCASignResponse{
+			Certificate: certPem,
+			CA:          certificateToPEM(caCert),
+			CertChain:   []string{certPem, certificateToPEM(caCert)},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestResourceStepCASignedCertEarlyRenewal(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Step CA", root, rootKey)
+	server := testStepCAServer(t, intermediate, intermediateKey, 24*time.Hour, nil)
+	csrPem := testCertificateRequest(t, "step.example.com")
+	config := func(earlyRenewalHours int) map[string]interface{} {
+		return map[string]interface{}{
+			"ca_url":              server.URL,
+			"root_cert_pem":       certificateToPEM(server.Certificate()),
+			"csr_pem":             csrPem,
+			"oidc_token":          "token",
+			"early_renewal_hours": earlyRenewalHours,
+		}
+	}
+
+	r := resourceStepCASignedCert()
+	created := testResourceApply(t, r, nil, config(1), &providerMeta{})
+	if kept := testResourceApply(t, r, created, config(2), &providerMeta{}); kept.Attributes["certificate_pem"] != created.Attributes["certificate_pem"] {
+		t.Errorf("expected no renewal 2 hours before the expiry of a 24 hours certificate")
+	}
+
+	// the certificate is valid for 24 hours, renewing 48 hours before it expires is due right away
+	renewed := testResourceApply(t, r, created, config(48), &providerMeta{})
+	if renewed.Attributes["certificate_pem"] == created.Attributes["certificate_pem"] {
+		t.Fatalf("expected a renewed certificate_pem")
+	}
+	if renewed.ID == created.ID || renewed.ID != renewed.Attributes["serial_number"] {
+		t.Errorf("expected the ID to be the renewed serial number %s, got %s", renewed.Attributes["serial_number"], renewed.ID)
+	}
+	if want := renewed.Attributes["certificate_pem"] + certificateToPEM(intermediate); renewed.Attributes["fullchain_pem"] != want {
+		t.Errorf("expected fullchain_pem to start with the renewed certificate, got %q", renewed.Attributes["fullchain_pem"])
+	}
+}
+
+func TestResourceStepCASignedCertJWKProvisioner(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Step CA", nil, nil)
+	tokens := make(chan string, 1)
+	server := testStepCAServer(t, ca, caKey, 24*time.Hour, tokens)
+	provisionerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := privateKeyToJWK(provisionerKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwkJSON, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testResourceApply(t, resourceStepCASignedCert(), nil, map[string]interface{}{
+		"ca_url":        server.URL,
+		"root_cert_pem": certificateToPEM(server.Certificate()),
+		"csr_pem":       testCertificateRequest(t, "step.example.com"),
+		"jwk_provisioner": []interface{}{map[string]interface{}{
+			"name": "admin@example.com",
+			"key":  string(jwkJSON),
+		}},
+	}, &providerMeta{})
+
+	parts := strings.Split(<-tokens, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a compact JWS, got %d parts", len(parts))
+	}
+	var header, claims map[string]interface{}
+	for i, v := range []interface{}{&header, &claims} {
+		decoded, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(decoded, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	thumbprint, err := jwk.thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header["alg"] != "ES256" || header["kid"] != thumbprint {
+		t.Errorf("expected an ES256 token with the thumbprint of the key as kid, got %v", header)
+	}
+	fingerprint := sha256.Sum256(server.Certificate().Raw)
+	for claim, want := range map[string]interface{}{
+		"iss":  "admin@example.com",
+		"sub":  "step.example.com",
+		"aud":  server.URL + "/1.0/sign",
+		"sans": []interface{}{"step.example.com"},
+		"sha":  hex.EncodeToString(fingerprint[:]),
+	} {
+		if !reflect.DeepEqual(claims[claim], want) {
+			t.Errorf("expected claim %s %v, got %v", claim, want, claims[claim])
+		}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&provisionerKey.PublicKey, digest[:], r, s) {
+		t.Errorf("expected the token to be signed by the provisioner key")
+	}
+}
+
+func TestResourceStepCASignedCertGenerateKey(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Step CA", nil, nil)
+	server := testStepCAServer(t, ca, caKey, 24*time.Hour, nil)
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := testResourceApply(t, resourceStepCASignedCert(), nil, map[string]interface{}{
+		"ca_url":        server.URL,
+		"root_cert_pem": certificateToPEM(server.Certificate()),
+		"oidc_token":    "token",
+		"age_recipient": identity.Recipient().String(),
+		"generate_key": []interface{}{map[string]interface{}{
+			"ecdsa_curve": "P384",
+			"subject":     []interface{}{map[string]interface{}{"common_name": "step.example.com"}},
+		}},
+	}, &providerMeta{})
+
+	if state.Attributes["private_key_pem"] != "" {
+		t.Errorf("expected no plaintext private_key_pem")
+	}
+	prvKey, _, err := parsePrivateKeyPEM([]byte(testAgeDecrypt(t, state.Attributes["encrypted_private_key_pem"], identity)))
+	if err != nil {
+		t.Fatalf("unable to parse the decrypted private_key_pem: %s", err)
+	}
+	cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse certificate_pem: %s", err)
+	}
+	ecdsaKey, ok := prvKey.(*ecdsa.PrivateKey)
+	if !ok || ecdsaKey.Curve != elliptic.P384() || !ecdsaKey.PublicKey.Equal(cert.PublicKey) || cert.Subject.CommonName != "step.example.com" {
+		t.Errorf("expected the certificate of a generated P384 key, got %T for %s", prvKey, cert.Subject)
+	}
+}
+
+func TestResourceStepCASignedCertRevocation(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "Step CA", nil, nil)
+	sources := testRevocationSources{ocspThisUpdate: time.Now().Add(-time.Hour), ocspNextUpdate: time.Now().Add(time.Hour), crlThisUpdate: time.Now().Add(-time.Hour), crlNextUpdate: time.Now().Add(time.Hour)}
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	attributes := func(serialNumber *big.Int) map[string]string {
+		return map[string]string{
+			"certificate_pem": certificateToPEM(testRevocationCertificate(t, server, serialNumber, issuer, issuerKey)),
+			"issuing_ca_pem":  certificateToPEM(issuer),
+		}
+	}
+
+	r := resourceStepCASignedCert()
+	state, diags := testRefreshRevocation(t, r, attributes(testRevokedSerialNumber))
+	if state != nil || len(diags) != 1 || !strings.Contains(diags[0].Summary, "serial number 42 has been revoked") {
+		t.Errorf("expected a revoked certificate to be removed from the state, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(big.NewInt(43)))
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected a good certificate to be kept, got %v", diags)
+	}
+}
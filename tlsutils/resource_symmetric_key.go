@@ -0,0 +1,210 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+func resourceSymmetricKey() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Generate a symmetric key for TLS-PSK or HMAC, with rotation",
+		CreateContext: resourceSymmetricKeyCreate,
+		ReadContext:   resourceSymmetricKeyRead,
+		UpdateContext: resourceSymmetricKeyUpdate,
+		DeleteContext: resourceSymmetricKeyDelete,
+		CustomizeDiff: resourceSymmetricKeyCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"length": {
+				Description:      "length of the key in bytes. Changing it rotates the key.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          32,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(16, 1024)),
+			},
+			"identity": {
+				Description: "TLS-PSK identity of the key, used in `psk_file_line`. Defaults to `key_id`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"rotation_interval_hours": {
+				Description:      "rotate the key when this many hours have passed since the last rotation. 0 disables time based rotation.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"rotation_trigger": {
+				Description: "arbitrary value; changing it rotates the key, keeping the previous one in `previous_hex` and `previous_base64`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"hex": {
+				Description: "current key, hex encoded, as used by `openssl s_server -psk`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"base64": {
+				Description: "current key, base64 encoded.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"key_id": {
+				Description: "hex encoded first 8 bytes of the SHA-256 of the current key, to tell keys apart without revealing them.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"psk_file_line": {
+				Description: "`identity:hex` line of a GnuTLS `psktool` keys file for the current key.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"previous_hex": {
+				Description: "key replaced by the last rotation, hex encoded. Empty until the first rotation.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"previous_base64": {
+				Description: "key replaced by the last rotation, base64 encoded. Empty until the first rotation.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"rotated_at": {
+				Description: "time the current key was generated, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// symmetricKeyRotationAttributes are the attributes that, once changed, cause a new key to be generated.
+var symmetricKeyRotationAttributes = []string{"length", "rotation_trigger"}
+
+func resourceSymmetricKeyCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	id, err := randomID()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(id)
+
+	if err = resourceSymmetricKeyGenerate(d, m, nil); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceSymmetricKeySetIdentity(d)
+}
+
+func resourceSymmetricKeyRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceSymmetricKeyUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	rotate, err := rotationDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if rotate || d.HasChanges(symmetricKeyRotationAttributes...) {
+		// the plan left hex unknown, the current key is in the prior state
+		currentHex, _ := d.GetChange("hex")
+		current, err := hex.DecodeString(currentHex.(string))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to decode current key: %w", err))
+		}
+
+		if err = resourceSymmetricKeyGenerate(d, m, current); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceSymmetricKeySetIdentity(d)
+}
+
+func resourceSymmetricKeyDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceSymmetricKeyCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	rotate, err := rotationDue(diff, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range symmetricKeyRotationAttributes {
+		rotate = rotate || diff.HasChange(key)
+	}
+	if rotate {
+		for _, computed := range []string{"hex", "base64", "key_id", "psk_file_line", "previous_hex", "previous_base64", "rotated_at"} {
+			if err = diff.SetNewComputed(computed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if diff.HasChange("identity") {
+		return diff.SetNewComputed("psk_file_line")
+	}
+
+	return nil
+}
+
+// resourceSymmetricKeyGenerate generates a new key, keeping previous as the previous key, and records the rotation time.
+func resourceSymmetricKeyGenerate(d *schema.ResourceData, m interface{}, previous []byte) error {
+	key := make([]byte, d.Get("length").(int))
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate symmetric key: %w", err)
+	}
+	keyID := sha256.Sum256(key)
+
+	values := map[string]string{
+		"hex":             hex.EncodeToString(key),
+		"base64":          base64.StdEncoding.EncodeToString(key),
+		"key_id":          hex.EncodeToString(keyID[:8]),
+		"previous_hex":    "",
+		"previous_base64": "",
+		"rotated_at":      now(d, m).UTC().Format(time.RFC3339),
+	}
+	if previous != nil {
+		values["previous_hex"] = hex.EncodeToString(previous)
+		values["previous_base64"] = base64.StdEncoding.EncodeToString(previous)
+	}
+	for k, value := range values {
+		if err := d.Set(k, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+// resourceSymmetricKeySetIdentity stores the psktool line of the current key under identity, or key_id when it is not set.
+func resourceSymmetricKeySetIdentity(d *schema.ResourceData) diag.Diagnostics {
+	identity := d.Get("identity").(string)
+	if identity == "" {
+		identity = d.Get("key_id").(string)
+	}
+
+	if err := d.Set("psk_file_line", identity+":"+d.Get("hex").(string)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save psk_file_line: %w", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package tlsutils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestResourceSymmetricKeyRotation(t *testing.T) {
+	r := resourceSymmetricKey()
+	state := testResourceApply(t, r, nil, map[string]interface{}{"rotation_trigger": "0"}, &providerMeta{})
+	if state.Attributes["previous_hex"] != "" {
+		t.Errorf("expected no previous_hex before the first rotation, got %q", state.Attributes["previous_hex"])
+	}
+
+	for _, trigger := range []string{"1", "2"} {
+		rotated := testResourceApply(t, r, state, map[string]interface{}{"rotation_trigger": trigger}, &providerMeta{})
+
+		if got, want := rotated.Attributes["previous_hex"], state.Attributes["hex"]; got != want || got == "" {
+			t.Errorf("rotation %s: expected previous_hex %q, got %q", trigger, want, got)
+		}
+		if got, want := rotated.Attributes["previous_base64"], state.Attributes["base64"]; got != want {
+			t.Errorf("rotation %s: expected previous_base64 %q, got %q", trigger, want, got)
+		}
+		if rotated.Attributes["hex"] == state.Attributes["hex"] {
+			t.Errorf("rotation %s: expected a new key, got %q again", trigger, rotated.Attributes["hex"])
+		}
+		key, err := hex.DecodeString(rotated.Attributes["hex"])
+		if err != nil {
+			t.Fatalf("rotation %s: invalid hex: %s", trigger, err)
+		}
+		if got, want := rotated.Attributes["base64"], base64.StdEncoding.EncodeToString(key); got != want {
+			t.Errorf("rotation %s: expected base64 %q, got %q", trigger, want, got)
+		}
+		if got, want := rotated.Attributes["psk_file_line"], rotated.Attributes["key_id"]+":"+rotated.Attributes["hex"]; got != want {
+			t.Errorf("rotation %s: expected psk_file_line %q, got %q", trigger, want, got)
+		}
+		state = rotated
+	}
+
+	renamed := testResourceApply(t, r, state, map[string]interface{}{"rotation_trigger": "2", "identity": "client"}, &providerMeta{})
+	if renamed.Attributes["hex"] != state.Attributes["hex"] || renamed.Attributes["previous_hex"] != state.Attributes["previous_hex"] {
+		t.Errorf("expected an identity change to keep the keys")
+	}
+	if got, want := renamed.Attributes["psk_file_line"], "client:"+state.Attributes["hex"]; got != want {
+		t.Errorf("expected psk_file_line %q, got %q", want, got)
+	}
+}
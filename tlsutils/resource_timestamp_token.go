@@ -0,0 +1,245 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timestampStatuses are the PKIStatus values of RFC 3161 section 2.4.2.
+var timestampStatuses = []string{"granted", "grantedWithMods", "rejection", "waiting", "revocationWarning", "revocationNotification"}
+
+func resourceTimestampToken() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Obtain an RFC 3161 timestamp token for a digest from a Time Stamping Authority, to anchor an artifact in time",
+		CreateContext: resourceTimestampTokenCreate,
+		ReadContext:   resourceTimestampTokenRead,
+		DeleteContext: resourceTimestampTokenDelete,
+		CustomizeDiff: resourceTimestampTokenCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"tsa_url": {
+				Description:      "URL of the Time Stamping Authority, e.g. `http://timestamp.digicert.com`.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPorHTTPS),
+			},
+			"digest_hex": {
+				Description:      "hex encoded digest of the timestamped data, e.g. the `sha256` of an artifact.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(`^([0-9a-fA-F]{2})+$`), "expected a hex encoded digest")),
+			},
+			"hash": {
+				Description:      "hash of `digest_hex`: `sha1`, `sha256`, `sha384` or `sha512`.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          "sha256",
+				ValidateDiagFunc: warnWeakParameter(weakHash, validation.ToDiagFunc(validation.StringInSlice(supportedHashAlgorithmsStr(), false))),
+			},
+			"policy_oid": {
+				Description:      "policy under which the token is requested. Defaults to the default policy of the TSA, saved once known.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validateOID),
+			},
+			"nonce": {
+				Description: "send a random nonce, checked in the token to detect replayed responses.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+			},
+			"cert_req": {
+				Description: "ask the TSA to include its certificate in the token, so that it can be verified without it.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+			},
+			"ca_cert_pem": {
+				Description:      "CA certificates in PEM format trusted for the TLS connection, in addition to the system roots.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
+			},
+			"token_base64": {
+				Description: "base64 encoded DER TimeStampToken, a CMS SignedData as embedded in signatures, for `openssl ts -verify -token_in`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"response_base64": {
+				Description: "base64 encoded DER TimeStampResp of the TSA, the content of `.tsr` files.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"gen_time": {
+				Description: "time of the timestamp in RFC3339 format.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"serial_number": {
+				Description: "serial number of the timestamp, as colon separated hex.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"tsa_cert_pem": {
+				Description: "certificate of the TSA included in the token, in PEM format. Empty when `cert_req` is false.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourceTimestampTokenCreate(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	hashName := d.Get("hash").(string)
+	digest, err := hex.DecodeString(d.Get("digest_hex").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid digest_hex: %w", err))
+	}
+	if size := hashAlgorithms[hashName].Size(); len(digest) != size {
+		return diag.FromErr(fmt.Errorf("digest_hex is %d bytes, a %s digest is %d bytes", len(digest), hashName, size))
+	}
+
+	req := timestampRequest{
+		Version: 1,
+		MessageImprint: timestampMessageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashAlgorithmOIDs[hashName], Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		CertReq: d.Get("cert_req").(bool),
+	}
+	if policy := d.Get("policy_oid").(string); policy != "" {
+		if req.ReqPolicy, err = parseOID(policy); err != nil {
+			return diag.FromErr(fmt.Errorf("invalid policy_oid: %w", err))
+		}
+	}
+	if d.Get("nonce").(bool) {
+		if req.Nonce, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64)); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to generate nonce: %w", err))
+		}
+	}
+	reqDER, err := asn1.Marshal(req)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal timestamp request: %w", err))
+	}
+
+	client, err := newHTTPClient(d.Get("ca_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+	url := d.Get("tsa_url").(string)
+	headers := map[string]string{
+		"Accept":       "application/timestamp-reply",
+		"Content-Type": "application/timestamp-query",
+	}
+	respDER, err := doRequest(ctx, client, http.MethodPost, url, headers, reqDER)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to request timestamp from %s: %w", url, err))
+	}
+
+	tokenDER, token, err := resourceTimestampTokenParseResponse(respDER, req)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid response of %s: %w", url, err))
+	}
+
+	tsaCertPem := ""
+	if len(token.certs) > 0 {
+		tsaCert, err := token.signerCertificate(nil)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid response of %s: %w", url, err))
+		}
+		if err = token.verifySignature(tsaCert); err != nil {
+			return diag.FromErr(fmt.Errorf("invalid response of %s: %w", url, err))
+		}
+		tsaCertPem = certificateToPEM(tsaCert)
+	}
+
+	tokenBase64 := base64.StdEncoding.EncodeToString(tokenDER)
+	d.SetId(hashForState(tokenBase64))
+
+	values := map[string]interface{}{
+		"policy_oid":      token.info.Policy.String(),
+		"token_base64":    tokenBase64,
+		"response_base64": base64.StdEncoding.EncodeToString(respDER),
+		"gen_time":        token.info.GenTime.UTC().Format(time.RFC3339),
+		"serial_number":   colonHex(token.info.SerialNumber.Bytes()),
+		"tsa_cert_pem":    tsaCertPem,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+
+	return nil
+}
+
+func resourceTimestampTokenRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceTimestampTokenDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceTimestampTokenCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	return checkWeakParameters(diff, m, map[string]weakParameterCheck{"hash": weakHash})
+}
+
+// resourceTimestampTokenParseResponse returns the token of a TimeStampResp, checking it was granted
+// for the message imprint, policy and nonce of req.
+func resourceTimestampTokenParseResponse(respDER []byte, req timestampRequest) ([]byte, *timestampToken, error) {
+	var resp timestampResponse
+	if _, err := asn1.Unmarshal(respDER, &resp); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse timestamp response: %w", err)
+	}
+	if status := resp.Status.Status; status > 1 {
+		name := fmt.Sprint(status)
+		if status < len(timestampStatuses) {
+			name = timestampStatuses[status]
+		}
+		if len(resp.Status.StatusString) > 0 {
+			name += ": " + strings.Join(resp.Status.StatusString, ", ")
+		}
+		return nil, nil, fmt.Errorf("timestamp request not granted, status %s", name)
+	}
+
+	token, err := parseTimestampToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	imprint := token.info.MessageImprint
+	if !imprint.HashAlgorithm.Algorithm.Equal(req.MessageImprint.HashAlgorithm.Algorithm) || !bytes.Equal(imprint.HashedMessage, req.MessageImprint.HashedMessage) {
+		return nil, nil, fmt.Errorf("the timestamp is for another digest than the requested one")
+	}
+	if len(req.ReqPolicy) > 0 && !token.info.Policy.Equal(req.ReqPolicy) {
+		return nil, nil, fmt.Errorf("the timestamp has policy %s instead of the requested %s", token.info.Policy, req.ReqPolicy)
+	}
+	if req.Nonce != nil && (token.info.Nonce == nil || token.info.Nonce.Cmp(req.Nonce) != 0) {
+		return nil, nil, fmt.Errorf("the timestamp does not have the nonce of the request")
+	}
+
+	return resp.TimeStampToken.FullBytes, token, nil
+}
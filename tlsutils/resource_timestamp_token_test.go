@@ -0,0 +1,248 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTimestampPolicy is the default policy of the test TSA.
+var testTimestampPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+// testTimestampServer serves RFC 3161 timestamp requests over HTTP, signing the tokens with tsaCert and tsaKey, and
+// including chain in the tokens that request certificates. Requests are rejected with status when it is set.
+func testTimestampServer(t *testing.T, tsaCert *x509.Certificate, tsaKey *ecdsa.PrivateKey, status *int, chain ...*x509.Certificate) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var req timestampRequest
+		if err == nil {
+			_, err = asn1.Unmarshal(body, &req)
+		}
+		if err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		var resp timestampResponse
+		resp.Status.Status = *status
+		if *status > 1 {
+			resp.Status.StatusString = []string{"unaccepted policy"}
+		} else {
+			policy := testTimestampPolicy
+			if len(req.ReqPolicy) > 0 {
+				policy = req.ReqPolicy
+			}
+			certs := chain
+			if !req.CertReq {
+				certs = nil
+			}
+			token, err := testTimestampToken(tsaCert, tsaKey, timestampInfo{
+				Version:        1,
+				Policy:         policy,
+				MessageImprint: req.MessageImprint,
+				SerialNumber:   big.NewInt(7),
+				GenTime:        time.Now().UTC().Truncate(time.Second),
+				Nonce:          req.Nonce,
+			}, certs...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.TimeStampToken = asn1.RawValue{FullBytes: token}
+		}
+		der, err := asn1.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		_, _ = w.Write(der)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// testTimestampToken returns the DER TimeStampToken of info, signed by tsaCert and tsaKey with the signed attributes
+// of RFC 5652 section 5.4, and including certs.
+func testTimestampToken(tsaCert *x509.Certificate, tsaKey *ecdsa.PrivateKey, info timestampInfo, certs ...*x509.Certificate) ([]byte, error) {
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	contentDigest := sha256.Sum256(content)
+	attribute := func(oid asn1.ObjectIdentifier, value interface{}) (cmsAttribute, error) {
+		der, err := asn1.Marshal(value)
+		return cmsAttribute{Type: oid, Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: der}}, err
+	}
+	contentType, err := attribute(oidAttributeContentType, oidContentTypeTSTInfo)
+	if err != nil {
+		return nil, err
+	}
+	messageDigest, err := attribute(oidAttributeMessageDigest, contentDigest[:])
+	if err != nil {
+		return nil, err
+	}
+	signedAttrs, err := asn1.MarshalWithParams([]cmsAttribute{contentType, messageDigest}, "set")
+	if err != nil {
+		return nil, err
+	}
+	signedAttrsDigest := sha256.Sum256(signedAttrs)
+	signature, err := ecdsa.SignASN1(rand.Reader, tsaKey, signedAttrsDigest[:])
+	if err != nil {
+		return nil, err
+	}
+	var signedAttrsSet asn1.RawValue
+	if _, err = asn1.Unmarshal(signedAttrs, &signedAttrsSet); err != nil {
+		return nil, err
+	}
+	sid, err := asn1.Marshal(struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}{asn1.RawValue{FullBytes: tsaCert.RawIssuer}, tsaCert.SerialNumber})
+	if err != nil {
+		return nil, err
+	}
+
+	sha256Algorithm := pkix.AlgorithmIdentifier{Algorithm: hashAlgorithmOIDs["sha256"]}
+	digestAlgorithms, err := asn1.MarshalWithParams([]pkix.AlgorithmIdentifier{sha256Algorithm}, "set")
+	if err != nil {
+		return nil, err
+	}
+	var rawCerts []byte
+	for _, cert := range certs {
+		rawCerts = append(rawCerts, cert.Raw...)
+	}
+	signedData := struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo struct {
+			EContentType asn1.ObjectIdentifier
+			EContent     []byte `asn1:"explicit,tag:0"`
+		}
+		Certificates asn1.RawValue   `asn1:"optional,tag:0"`
+		SignerInfos  []cmsSignerInfo `asn1:"set"`
+	}{
+		Version:          3,
+		DigestAlgorithms: asn1.RawValue{FullBytes: digestAlgorithms},
+		SignerInfos: []cmsSignerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    sha256Algorithm,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedAttrsSet.Bytes},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          signature,
+		}},
+	}
+	signedData.EncapContentInfo.EContentType = oidContentTypeTSTInfo
+	signedData.EncapContentInfo.EContent = content
+	if len(rawCerts) > 0 {
+		signedData.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: rawCerts}
+	}
+	signedDataDER, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidPKCS7SignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedDataDER}})
+}
+
+// testTimestampAuthority returns a TSA certificate with its key, issued by a new CA returned with it.
+func testTimestampAuthority(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	ca, caKey := testCertificateAuthority(t, "TSA CA", nil, nil)
+	tsaCert, tsaKey := testCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "Test TSA"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, ca, caKey)
+
+	return tsaCert, tsaKey, ca
+}
+
+func TestResourceTimestampToken(t *testing.T) {
+	tsaCert, tsaKey, _ := testTimestampAuthority(t)
+	status := 0
+	server := testTimestampServer(t, tsaCert, tsaKey, &status, tsaCert)
+	digest := sha256.Sum256([]byte("artifact"))
+
+	r := resourceTimestampToken()
+	state := testResourceApply(t, r, nil, map[string]interface{}{
+		"tsa_url":    server.URL,
+		"digest_hex": hex.EncodeToString(digest[:]),
+	}, &providerMeta{})
+	if state.Attributes["tsa_cert_pem"] != certificateToPEM(tsaCert) || state.Attributes["policy_oid"] != testTimestampPolicy.String() || state.Attributes["serial_number"] != "07" {
+		t.Errorf("expected a token of the TSA with its default policy, got %v", state.Attributes)
+	}
+	if _, err := time.Parse(time.RFC3339, state.Attributes["gen_time"]); err != nil {
+		t.Errorf("invalid gen_time: %s", err)
+	}
+	tokenDER, err := base64.StdEncoding.DecodeString(state.Attributes["token_base64"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := parseTimestampToken(tokenDER)
+	if err != nil {
+		t.Fatalf("unable to parse token_base64: %s", err)
+	}
+	if token.info.Nonce == nil || string(token.info.MessageImprint.HashedMessage) != string(digest[:]) {
+		t.Errorf("expected a token with a nonce for the digest, got %+v", token.info)
+	}
+
+	// without cert_req, the token cannot be verified when obtained
+	state = testResourceApply(t, r, nil, map[string]interface{}{
+		"tsa_url":    server.URL,
+		"digest_hex": hex.EncodeToString(digest[:]),
+		"policy_oid": "1.3.6.1.4.1.99999.2",
+		"nonce":      false,
+		"cert_req":   false,
+	}, &providerMeta{})
+	if state.Attributes["tsa_cert_pem"] != "" || state.Attributes["policy_oid"] != "1.3.6.1.4.1.99999.2" {
+		t.Errorf("expected a token with the requested policy and without certificate, got %v", state.Attributes)
+	}
+
+	for name, test := range map[string]struct {
+		status int
+		raw    map[string]interface{}
+		err    string
+	}{
+		"rejected": {
+			status: 2,
+			raw:    map[string]interface{}{"tsa_url": server.URL, "digest_hex": hex.EncodeToString(digest[:])},
+			err:    "timestamp request not granted, status rejection: unaccepted policy",
+		},
+		"digest of another hash": {
+			raw: map[string]interface{}{"tsa_url": server.URL, "digest_hex": hex.EncodeToString(digest[:]), "hash": "sha1"},
+			err: "digest_hex is 32 bytes, a sha1 digest is 20 bytes",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			status = test.status
+			defer func() { status = 0 }()
+			d := schema.TestResourceDataRaw(t, r.Schema, test.raw)
+			if diags := resourceTimestampTokenCreate(context.Background(), d, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, test.err) {
+				t.Errorf("expected error %q, got %v", test.err, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package tlsutils
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strings"
+)
+
+const (
+	vaultPKIEndpointSignIntermediate = "sign-intermediate"
+	vaultPKIEndpointSignVerbatim     = "sign-verbatim"
+	vaultPKIEndpointSign             = "sign"
+)
+
+func resourceVaultPKISignedCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"vault_address": {
+			Description: "address of the Vault server. Defaults to `VAULT_ADDR`.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VAULT_ADDR", nil),
+		},
+		"vault_token": {
+			Description: "Vault token used to sign the CSR. Defaults to `VAULT_TOKEN`.",
+			Type:        schema.TypeString,
+			Required:    true,
+			Sensitive:   true,
+			DefaultFunc: schema.EnvDefaultFunc("VAULT_TOKEN", nil),
+		},
+		"vault_namespace": {
+			Description: "Vault Enterprise namespace of the PKI mount. Defaults to `VAULT_NAMESPACE`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VAULT_NAMESPACE", ""),
+		},
+		"vault_ca_cert_pem": {
+			Description:      "CA certificates in PEM format trusted for the Vault TLS connection, in addition to the system roots.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"backend": {
+			Description: "path of the PKI mount, e.g. `pki`.",
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+		},
+		"endpoint": {
+			Description:      "signing endpoint: `sign-intermediate`, `sign-verbatim` or `sign`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			Default:          vaultPKIEndpointSignIntermediate,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{vaultPKIEndpointSignIntermediate, vaultPKIEndpointSignVerbatim, vaultPKIEndpointSign}, false)),
+		},
+		"role": {
+			Description: "Vault PKI role, required by `sign` and optional for `sign-verbatim`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"common_name": {
+			Description: "common name requested from Vault.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+		},
+		"ttl": {
+			Description:      "requested certificate TTL, e.g. `8760h`, or as an ISO 8601 duration such as `P1Y`, years counting 365 days and months 30 days. Defaults to the default validity of the provider, then to the TTL of the Vault role.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentValidityPeriod("ttl", ""),
+		},
+		"parameters": {
+			Description: "additional request parameters passed as-is to the signing endpoint.",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			ForceNew:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"revoke_on_destroy": {
+			Description: "revoke the certificate in Vault when the resource is destroyed.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+		"early_renewal_hours": {
+			Description:      "sign a new certificate in-place this many hours before the current one expires, keeping the current one in `previous_certificate_pem` until it expires. 0 disables early renewal. Defaults to the `default_early_renewal_hours` of the provider, then 0.",
+			Type:             schema.TypeInt,
+			Optional:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+		},
+		"certificate_pem": {
+			Description: "signed certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"previous_certificate_pem": {
+			Description: "certificate replaced by early renewal in PEM format, until it expires.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"issuing_ca_pem": {
+			Description: "issuing CA certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ca_chain_pem": {
+			Description: "CA chain returned by Vault, in PEM format.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"fullchain_pem": {
+			Description: "signed certificate followed by the CA chain without self-signed roots, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"serial_number": {
+			Description: "serial number of the signed certificate, as colon separated hex.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range certificateValiditySchema("", "the signed certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	for name, attribute := range generatedCSRSchema("certificate signing request in PEM format.") {
+		s[name] = attribute
+	}
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Sign a CSR with a HashiCorp Vault PKI secrets engine",
+		CreateContext: resourceVaultPKISignedCertCreate,
+		ReadContext:   resourceVaultPKISignedCertRead,
+		UpdateContext: resourceVaultPKISignedCertUpdate,
+		DeleteContext: resourceVaultPKISignedCertDelete,
+		CustomizeDiff: resourceVaultPKISignedCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// vaultPKISignResponse is the relevant part of the response of the Vault PKI signing endpoints.
+type vaultPKISignResponse struct {
+	Data struct {
+		Certificate  string   `json:"certificate"`
+		IssuingCA    string   `json:"issuing_ca"`
+		CAChain      []string `json:"ca_chain"`
+		SerialNumber string   `json:"serial_number"`
+	} `json:"data"`
+}
+
+func resourceVaultPKISignedCertCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := generateCertificateRequest(d, m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceVaultPKISignedCertSign(ctx, d, m)
+}
+
+// resourceVaultPKISignedCertSign signs the CSR with Vault and stores the certificate returned.
+func resourceVaultPKISignedCertSign(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	endpoint := d.Get("endpoint").(string)
+	role := d.Get("role").(string)
+
+	var path string
+	switch endpoint {
+	case vaultPKIEndpointSignIntermediate:
+		path = "root/sign-intermediate"
+	case vaultPKIEndpointSignVerbatim:
+		path = "sign-verbatim"
+		if role != "" {
+			path += "/" + role
+		}
+	case vaultPKIEndpointSign:
+		if role == "" {
+			return diag.FromErr(fmt.Errorf("role is required with endpoint %q", endpoint))
+		}
+		path = "sign/" + role
+	}
+
+	if _, err := parsePEMCertificateRequest([]byte(d.Get("csr_pem").(string))); err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse csr_pem: %w", err))
+	}
+
+	reqBody := map[string]string{}
+	for name, value := range d.Get("parameters").(map[string]interface{}) {
+		reqBody[name] = value.(string)
+	}
+	reqBody["csr"] = d.Get("csr_pem").(string)
+	reqBody["format"] = "pem"
+	if commonName := d.Get("common_name").(string); commonName != "" {
+		reqBody["common_name"] = commonName
+	}
+	if ttl := d.Get("ttl").(string); strings.HasPrefix(ttl, "P") {
+		// Vault only understands Go durations and seconds
+		validityPeriod, err := parseValidityPeriod(ttl)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid ttl: %w", err))
+		}
+		reqBody["ttl"] = formatValidityPeriod(validityPeriod)
+	} else if ttl != "" {
+		reqBody["ttl"] = ttl
+	} else if meta, ok := m.(*providerMeta); ok && meta.defaultValidityPeriod > 0 {
+		reqBody["ttl"] = formatValidityPeriod(meta.defaultValidityPeriod)
+	}
+
+	var resp vaultPKISignResponse
+	if err := resourceVaultPKIRequest(ctx, d, http.MethodPost, path, reqBody, &resp); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign CSR with Vault: %w", err))
+	}
+
+	if _, err := parsePEMCertificate([]byte(resp.Data.Certificate)); err != nil {
+		return diag.FromErr(fmt.Errorf("vault returned an invalid certificate: %w", err))
+	}
+
+	chain := resp.Data.CAChain
+	if len(chain) == 0 {
+		chain = []string{resp.Data.IssuingCA}
+	}
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), resp.Data.Certificate, chain...)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build full chain: %w", err))
+	}
+
+	d.SetId(resp.Data.SerialNumber)
+
+	if err := d.Set("certificate_pem", resp.Data.Certificate); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save certificate_pem: %w", err))
+	}
+	certText, err := certificateTextPEM(resp.Data.Certificate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("cert_text", certText); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save cert_text: %w", err))
+	}
+	certJSON, err := certificateJSONPEM(resp.Data.Certificate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("cert_json", certJSON); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save cert_json: %w", err))
+	}
+	if err := d.Set("issuing_ca_pem", resp.Data.IssuingCA); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save issuing_ca_pem: %w", err))
+	}
+	if err := d.Set("ca_chain_pem", resp.Data.CAChain); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save ca_chain_pem: %w", err))
+	}
+	if err := d.Set("fullchain_pem", fullChainPem); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save fullchain_pem: %w", err))
+	}
+	if err := d.Set("serial_number", resp.Data.SerialNumber); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save serial_number: %w", err))
+	}
+	if err := setCertificateValidity(d, m, "", resp.Data.Certificate); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceVaultPKISignedCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if previous := d.Get("previous_certificate_pem").(string); previous != "" {
+		previousCert, err := parsePEMCertificate([]byte(previous))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to parse previous_certificate_pem: %w", err))
+		}
+		if now(d, m).After(previousCert.NotAfter) {
+			if err = d.Set("previous_certificate_pem", ""); err != nil {
+				return diag.FromErr(fmt.Errorf("failed to save previous_certificate_pem: %w", err))
+			}
+		}
+	}
+
+	if err := setCertificateValidity(d, m, "", d.Get("certificate_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+
+	client, err := newHTTPClient(d.Get("vault_ca_cert_pem").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("certificate_pem").(string), d.Get("issuing_ca_pem").(string))
+}
+
+func resourceVaultPKISignedCertUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// The other in-place attributes are only used by later requests to Vault.
+	renew, err := earlyRenewalDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !renew {
+		chain := listOfStrings(d.Get("ca_chain_pem").([]interface{}))
+		if len(chain) == 0 {
+			chain = []string{d.Get("issuing_ca_pem").(string)}
+		}
+		return diag.FromErr(updateFullChain(d, m, chain...))
+	}
+
+	// the plan left certificate_pem unknown, the certificate being replaced is in the prior state
+	previous, _ := d.GetChange("certificate_pem")
+	if err = d.Set("previous_certificate_pem", previous.(string)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save previous_certificate_pem: %w", err))
+	}
+
+	return resourceVaultPKISignedCertSign(ctx, d, m)
+}
+
+func resourceVaultPKISignedCertDelete(ctx context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if d.Get("revoke_on_destroy").(bool) {
+		reqBody := map[string]string{"serial_number": d.Get("serial_number").(string)}
+		if err := resourceVaultPKIRequest(ctx, d, http.MethodPost, "revoke", reqBody, nil); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to revoke certificate in Vault: %w", err))
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func resourceVaultPKISignedCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if err := planFullChains(diff, "fullchain_pem"); err != nil {
+		return err
+	}
+
+	renew, err := earlyRenewalDue(diff, m)
+	if err != nil || !renew {
+		return err
+	}
+
+	for _, computed := range []string{"certificate_pem", "cert_text", "cert_json", "previous_certificate_pem", "issuing_ca_pem", "ca_chain_pem", "fullchain_pem", "serial_number", "not_before", "not_after", "not_before_unix", "not_after_unix", "remaining_seconds"} {
+		if err = diff.SetNewComputed(computed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceVaultPKIRequest sends a request to the given path of the configured PKI mount.
+func resourceVaultPKIRequest(ctx context.Context, d *schema.ResourceData, method, path string, reqBody, respBody interface{}) error {
+	client, err := newHTTPClient(d.Get("vault_ca_cert_pem").(string))
+	if err != nil {
+		return fmt.Errorf("failed to configure Vault client: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s",
+		strings.TrimRight(d.Get("vault_address").(string), "/"),
+		strings.Trim(d.Get("backend").(string), "/"),
+		path,
+	)
+
+	headers := map[string]string{"X-Vault-Token": d.Get("vault_token").(string)}
+	if namespace := d.Get("vault_namespace").(string); namespace != "" {
+		headers["X-Vault-Namespace"] = namespace
+	}
+
+	return doJSONRequest(ctx, client, method, url, headers, reqBody, respBody)
+}
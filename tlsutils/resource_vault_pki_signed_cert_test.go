@@ -0,0 +1,272 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"filippo.io/age"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testVaultPKIServer serves the sign-intermediate endpoint of a Vault PKI mount at pki, signing certificates valid
+// for validity with caCert and caKey.
+func testVaultPKIServer(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateKey, validity time.Duration) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/pki/root/sign-intermediate" {
+			http.NotFound(w, r)
+			return
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cert, err := testSignCertificateRequest(caCert, caKey, req["csr"], validity)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var resp vaultPKISignResponse
+		resp.Data.Certificate = certificateToPEM(cert)
+		resp.Data.IssuingCA = certificateToPEM(caCert)
+		resp.Data.CAChain = []string{certificateToPEM(caCert)}
+		resp.Data.SerialNumber = strings.ReplaceAll(fmt.Sprintf("% x", cert.SerialNumber.Bytes()), " ", ":")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestResourceVaultPKISignedCertEarlyRenewal(t *testing.T) {
+	caCert, caKey := testCertificateAuthority(t, "Vault CA", nil, nil)
+	server := testVaultPKIServer(t, caCert, caKey, 24*time.Hour)
+	csrPem := testCertificateRequest(t, "vault.example.com")
+	config := func(earlyRenewalHours int) map[string]interface{} {
+		return map[string]interface{}{
+			"vault_address":       server.URL,
+			"vault_token":         "token",
+			"backend":             "pki",
+			"csr_pem":             csrPem,
+			"early_renewal_hours": earlyRenewalHours,
+		}
+	}
+
+	r := resourceVaultPKISignedCert()
+	created := testResourceApply(t, r, nil, config(1), &providerMeta{})
+	if created.Attributes["previous_certificate_pem"] != "" {
+		t.Errorf("expected no previous_certificate_pem before renewal, got %q", created.Attributes["previous_certificate_pem"])
+	}
+
+	// the certificate is valid for 24 hours, renewing 48 hours before it expires is due right away
+	renewed := testResourceApply(t, r, created, config(48), &providerMeta{})
+	if renewed.Attributes["certificate_pem"] == created.Attributes["certificate_pem"] {
+		t.Fatalf("expected a renewed certificate_pem")
+	}
+	if got, want := renewed.Attributes["previous_certificate_pem"], created.Attributes["certificate_pem"]; got != want {
+		t.Errorf("expected previous_certificate_pem %q, got %q", want, got)
+	}
+	if renewed.ID == created.ID || renewed.ID != renewed.Attributes["serial_number"] {
+		t.Errorf("expected the ID to be the renewed serial number %s, got %s", renewed.Attributes["serial_number"], renewed.ID)
+	}
+
+	// once the previous certificate expired, refresh drops it
+	state, diags := resourceVaultPKISignedCert().RefreshWithoutUpgrade(context.Background(), renewed, &providerMeta{evaluationTime: time.Now().Add(48 * time.Hour)})
+	if diags.HasError() || state.Attributes["previous_certificate_pem"] != "" {
+		t.Errorf("expected previous_certificate_pem to be dropped once expired, got %q (%v)", state.Attributes["previous_certificate_pem"], diags)
+	}
+}
+
+func TestResourceVaultPKISignedCertFullChain(t *testing.T) {
+	root, rootKey := testCertificateAuthority(t, "Root CA", nil, nil)
+	intermediate, intermediateKey := testCertificateAuthority(t, "Vault CA", root, rootKey)
+
+	for name, test := range map[string]struct {
+		caCert *x509.Certificate
+		caKey  *ecdsa.PrivateKey
+		chain  string
+	}{
+		"self-signed CA":  {caCert: root, caKey: rootKey},
+		"intermediate CA": {caCert: intermediate, caKey: intermediateKey, chain: certificateToPEM(intermediate)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := testVaultPKIServer(t, test.caCert, test.caKey, 24*time.Hour)
+			state := testResourceApply(t, resourceVaultPKISignedCert(), nil, map[string]interface{}{
+				"vault_address": server.URL,
+				"vault_token":   "token",
+				"backend":       "pki",
+				"csr_pem":       testCertificateRequest(t, "vault.example.com"),
+			}, &providerMeta{})
+
+			if want := state.Attributes["certificate_pem"] + test.chain; state.Attributes["fullchain_pem"] != want {
+				t.Errorf("expected the certificate followed by the chain without self-signed root, got %q", state.Attributes["fullchain_pem"])
+			}
+		})
+	}
+}
+
+func TestResourceVaultPKISignedCertProviderDefaults(t *testing.T) {
+	caCert, caKey := testCertificateAuthority(t, "Vault CA", nil, nil)
+	ttls := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttls <- req["ttl"]
+
+		cert, err := testSignCertificateRequest(caCert, caKey, req["csr"], 24*time.Hour)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var resp vaultPKISignResponse
+		resp.Data.Certificate = certificateToPEM(cert)
+		resp.Data.IssuingCA = certificateToPEM(caCert)
+		resp.Data.SerialNumber = strings.ReplaceAll(fmt.Sprintf("% x", cert.SerialNumber.Bytes()), " ", ":")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	config := map[string]interface{}{
+		"vault_address": server.URL,
+		"vault_token":   "token",
+		"backend":       "pki",
+		"csr_pem":       testCertificateRequest(t, "vault.example.com"),
+	}
+	meta := &providerMeta{defaultValidityPeriod: 48 * time.Hour, defaultEarlyRenewalHours: 48}
+
+	r := resourceVaultPKISignedCert()
+	created := testResourceApply(t, r, nil, config, meta)
+	if ttl := <-ttls; ttl != "48h" {
+		t.Errorf("expected the default validity as ttl, got %q", ttl)
+	}
+
+	// the certificate is valid for 24 hours, the default early renewal of 48 hours is due right away
+	renewed := testResourceApply(t, r, created, config, meta)
+	if renewed.Attributes["certificate_pem"] == created.Attributes["certificate_pem"] {
+		t.Errorf("expected the default early renewal to renew the certificate")
+	}
+	<-ttls
+
+	// the attributes of the resource take precedence
+	config["ttl"] = "12h"
+	config["early_renewal_hours"] = 1
+	created = testResourceApply(t, r, nil, config, meta)
+	if ttl := <-ttls; ttl != "12h" {
+		t.Errorf("expected the ttl of the resource, got %q", ttl)
+	}
+	if state := testResourceApply(t, r, created, config, meta); state.Attributes["certificate_pem"] != created.Attributes["certificate_pem"] {
+		t.Errorf("expected no renewal with the early_renewal_hours of the resource")
+	}
+
+	// ISO 8601 durations are converted to hours for Vault
+	config["ttl"] = "P1W"
+	testResourceApply(t, r, nil, config, meta)
+	if ttl := <-ttls; ttl != "168h" {
+		t.Errorf("expected the ttl of the resource in hours, got %q", ttl)
+	}
+}
+
+func TestResourceVaultPKISignedCertGenerateKey(t *testing.T) {
+	caCert, caKey := testCertificateAuthority(t, "Vault CA", nil, nil)
+	server := testVaultPKIServer(t, caCert, caKey, 24*time.Hour)
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]interface{}{
+		"vault_address": server.URL,
+		"vault_token":   "token",
+		"backend":       "pki",
+		"generate_key": []interface{}{map[string]interface{}{
+			"algorithm": "RSA",
+			"subject":   []interface{}{map[string]interface{}{"common_name": "vault.example.com"}},
+			"dns_names": []interface{}{"vault.example.com"},
+		}},
+	}
+
+	r := resourceVaultPKISignedCert()
+	state := testResourceApply(t, r, nil, config, &providerMeta{defaultRSABits: 3072})
+	cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse certificate_pem: %s", err)
+	}
+	prvKey, _, err := parsePrivateKeyPEM([]byte(state.Attributes["private_key_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse private_key_pem: %s", err)
+	}
+	rsaKey, ok := prvKey.(*rsa.PrivateKey)
+	if !ok || rsaKey.N.BitLen() != 3072 || !rsaKey.PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("expected the certificate of a generated RSA 3072 key, got %T", prvKey)
+	}
+	csr, err := parsePEMCertificateRequest([]byte(state.Attributes["csr_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse the generated csr_pem: %s", err)
+	}
+	if csr.Subject.CommonName != "vault.example.com" || len(csr.DNSNames) != 1 || csr.DNSNames[0] != "vault.example.com" {
+		t.Errorf("expected the subject and DNS names of generate_key, got %s %v", csr.Subject, csr.DNSNames)
+	}
+
+	// the key algorithm defaults to ECDSA
+	delete(config["generate_key"].([]interface{})[0].(map[string]interface{}), "algorithm")
+	config["age_recipient"] = identity.Recipient().String()
+	state = testResourceApply(t, r, nil, config, &providerMeta{})
+	if state.Attributes["private_key_pem"] != "" {
+		t.Errorf("expected no plaintext private_key_pem")
+	}
+	prvKey, _, err = parsePrivateKeyPEM([]byte(testAgeDecrypt(t, state.Attributes["encrypted_private_key_pem"], identity)))
+	if err != nil {
+		t.Fatalf("unable to parse the decrypted private_key_pem: %s", err)
+	}
+	if cert, err = parsePEMCertificate([]byte(state.Attributes["certificate_pem"])); err != nil || !prvKey.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("expected the certificate of the encrypted ECDSA key, got %v", err)
+	}
+
+	delete(config, "generate_key")
+	if diags := r.Validate(terraform.NewResourceConfigRaw(config)); !diags.HasError() {
+		t.Errorf("expected neither csr_pem nor generate_key to be refused")
+	}
+}
+
+func TestResourceVaultPKISignedCertChainOptions(t *testing.T) {
+	caCert, caKey := testCertificateAuthority(t, "Vault CA", nil, nil)
+	server := testVaultPKIServer(t, caCert, caKey, 24*time.Hour)
+	config := map[string]interface{}{
+		"vault_address": server.URL,
+		"vault_token":   "token",
+		"backend":       "pki",
+		"csr_pem":       testCertificateRequest(t, "vault.example.com"),
+	}
+
+	r := resourceVaultPKISignedCert()
+	created := testResourceApply(t, r, nil, config, &providerMeta{})
+
+	// the full chain is rebuilt in-place from the stored certificates
+	config["chain_include_root"] = true
+	updated := testResourceApply(t, r, created, config, &providerMeta{})
+	if updated.ID != created.ID || updated.Attributes["certificate_pem"] != created.Attributes["certificate_pem"] {
+		t.Errorf("expected the certificate to be kept")
+	}
+	if want := created.Attributes["certificate_pem"] + certificateToPEM(caCert); updated.Attributes["fullchain_pem"] != want {
+		t.Errorf("expected the certificate followed by the self-signed CA, got %q", updated.Attributes["fullchain_pem"])
+	}
+
+	config["chain_max_depth"] = 1
+	config["chain_exclude_expired"] = true
+	if kept := testResourceApply(t, r, updated, config, &providerMeta{}); kept.Attributes["fullchain_pem"] != updated.Attributes["fullchain_pem"] {
+		t.Errorf("expected the valid CA within the maximum depth to be kept, got %q", kept.Attributes["fullchain_pem"])
+	}
+}
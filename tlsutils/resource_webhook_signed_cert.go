@@ -0,0 +1,363 @@
+package tlsutils
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strings"
+)
+
+func resourceWebhookSignedCert() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"url": {
+			Description:      "HTTPS endpoint the CSR is POSTed to.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPS),
+		},
+		"csr_pem": {
+			Description:      "certificate signing request in PEM format.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"csr_field": {
+			Description: "field of the JSON request body holding `csr_pem`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Default:     "csr",
+		},
+		"parameters": {
+			Description: "additional fields of the JSON request body, passed as-is.",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			ForceNew:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"bearer_token": {
+			Description: "token sent in the `Authorization: Bearer` header.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
+		"client_cert_pem": {
+			Description:      "client certificate in PEM format for mutual TLS, optionally followed by its chain.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+			RequiredWith:     []string{"client_key_pem"},
+		},
+		"client_key_pem": {
+			Description:  "private key of `client_cert_pem` in PEM format.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			RequiredWith: []string{"client_cert_pem"},
+		},
+		"ca_cert_pem": {
+			Description:      "CA certificates in PEM format trusted for the TLS connection, in addition to the system roots.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: suppressEquivalentPEM,
+		},
+		"certificate_field": {
+			Description: "field of the JSON response holding the signed certificate in PEM format. A response that is PEM instead of JSON is read as the certificate followed by its chain.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Default:     "certificate",
+		},
+		"chain_field": {
+			Description: "field of the JSON response holding the CA chain, as a PEM string or a list of PEM strings. A missing field means no chain.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Default:     "ca_chain",
+		},
+		"certificate_pem": {
+			Description: "signed certificate in PEM format.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_text": {
+			Description: "`certificate_pem` rendered like `openssl x509 -text`, for reviewing plans.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"cert_json": {
+			Description: "`certificate_pem` parsed as JSON with a stable schema, for `jsondecode` and policy engines like OPA.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"ca_chain_pem": {
+			Description: "CA chain returned by the endpoint, one certificate per element in PEM format.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"fullchain_pem": {
+			Description: "signed certificate followed by the CA chain without self-signed roots, in PEM format, for nginx `ssl_certificate` or Apache `SSLCertificateFile`.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"serial_number": {
+			Description: "serial number of the signed certificate, as colon separated hex.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+	for name, attribute := range networkSchema() {
+		s[name] = attribute
+	}
+	s["retries"].Description = "number of times a failed request, or a response failing validation, is retried."
+	for name, attribute := range certificateValiditySchema("", "the signed certificate") {
+		s[name] = attribute
+	}
+	for name, attribute := range revocationCheckSchema() {
+		s[name] = attribute
+	}
+	s["check_revocation"].Description += " The issuer is the first certificate of `ca_chain_pem`."
+	for name, attribute := range chainSchema("`fullchain_pem`") {
+		s[name] = attribute
+	}
+
+	return &schema.Resource{
+		Description:   "Sign a CSR by POSTing it to a custom CA service over HTTPS",
+		CreateContext: resourceWebhookSignedCertCreate,
+		ReadContext:   resourceWebhookSignedCertRead,
+		UpdateContext: resourceWebhookSignedCertUpdate,
+		DeleteContext: resourceWebhookSignedCertDelete,
+		CustomizeDiff: resourceWebhookSignedCertCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+func resourceWebhookSignedCertCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	csrPem := d.Get("csr_pem").(string)
+	csr, err := parsePEMCertificateRequest([]byte(csrPem))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse csr_pem: %w", err))
+	}
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	client, err := resourceWebhookSignedCertClient(d, options)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	reqBody := map[string]string{}
+	for name, value := range d.Get("parameters").(map[string]interface{}) {
+		reqBody[name] = value.(string)
+	}
+	reqBody[d.Get("csr_field").(string)] = csrPem
+	reqJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to marshal request: %w", err))
+	}
+
+	headers := map[string]string{
+		"Accept":       "application/json, application/x-pem-file",
+		"Content-Type": "application/json",
+	}
+	if token := d.Get("bearer_token").(string); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	url := d.Get("url").(string)
+	var cert *x509.Certificate
+	var chain []*x509.Certificate
+	err = options.retry(ctx, func(ctx context.Context) error {
+		respBytes, err := doRequest(ctx, client, http.MethodPost, url, headers, reqJSON)
+		if err != nil {
+			return err
+		}
+		cert, chain, err = resourceWebhookSignedCertParseResponse(d, respBytes)
+		if err != nil {
+			return fmt.Errorf("invalid response of %s: %w", url, err)
+		}
+
+		return resourceWebhookSignedCertValidate(csr, cert, chain)
+	})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to sign CSR with %s: %w", url, err))
+	}
+
+	certPem := certificateToPEM(cert)
+	chainPems := make([]string, 0, len(chain))
+	for _, chainCert := range chain {
+		chainPems = append(chainPems, certificateToPEM(chainCert))
+	}
+	fullChainPem, err := fullChainPEM(chainOptionsFromResourceData(d, m), certPem, chainPems...)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to build full chain: %w", err))
+	}
+	certText, err := certificateTextPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	certJSON, err := certificateJSONPEM(certPem)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serialNumber := colonHex(cert.SerialNumber.Bytes())
+	d.SetId(serialNumber)
+
+	values := map[string]interface{}{
+		"certificate_pem": certPem,
+		"cert_text":       certText,
+		"cert_json":       certJSON,
+		"ca_chain_pem":    chainPems,
+		"fullchain_pem":   fullChainPem,
+		"serial_number":   serialNumber,
+	}
+	for key, value := range values {
+		if err = d.Set(key, value); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to save %s: %w", key, err))
+		}
+	}
+	if err = setCertificateValidity(d, m, "", certPem); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceWebhookSignedCertRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := setCertificateValidity(d, m, "", d.Get("certificate_pem").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !d.Get("check_revocation").(bool) {
+		return nil
+	}
+	chain := d.Get("ca_chain_pem").([]interface{})
+	if len(chain) == 0 {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Unable to check the revocation status of the certificate",
+			Detail:   "The endpoint returned no CA chain, so the issuer of the certificate is unknown.",
+		}}
+	}
+
+	options, err := networkOptionsFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	client, err := resourceWebhookSignedCertClient(d, options)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to configure HTTP client: %w", err))
+	}
+
+	return refreshRevocationStatus(ctx, d, m, client, d.Get("certificate_pem").(string), chain[0].(string))
+}
+
+func resourceWebhookSignedCertUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// The other in-place attributes only configure the connection, used again when the certificate is replaced.
+	return diag.FromErr(updateFullChain(d, m, listOfStrings(d.Get("ca_chain_pem").([]interface{}))...))
+}
+
+func resourceWebhookSignedCertDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceWebhookSignedCertCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+	return planFullChains(diff, "fullchain_pem")
+}
+
+// resourceWebhookSignedCertClient returns the HTTP client of networkOptions, trusting ca_cert_pem
+// in addition to the system roots and presenting client_cert_pem when set.
+func resourceWebhookSignedCertClient(d *schema.ResourceData, options *networkOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSClientConfig(d.Get("ca_cert_pem").(string), d.Get("client_cert_pem").(string), d.Get("client_key_pem").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	client := options.httpClient()
+	client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+
+	return client, nil
+}
+
+// resourceWebhookSignedCertParseResponse returns the signed certificate and the CA chain of a response,
+// either PEM or a JSON object with the configured certificate_field and chain_field.
+func resourceWebhookSignedCertParseResponse(d *schema.ResourceData, respBytes []byte) (*x509.Certificate, []*x509.Certificate, error) {
+	var certPems []string
+	if bytes.HasPrefix(bytes.TrimSpace(respBytes), []byte("-----BEGIN ")) {
+		certPems = []string{string(respBytes)}
+	} else {
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(respBytes, &resp); err != nil {
+			return nil, nil, fmt.Errorf("expected a JSON object or PEM: %w", err)
+		}
+
+		certificateField := d.Get("certificate_field").(string)
+		var certPem string
+		if err := json.Unmarshal(resp[certificateField], &certPem); err != nil || certPem == "" {
+			return nil, nil, fmt.Errorf("field %q should be a PEM string", certificateField)
+		}
+		certPems = []string{certPem}
+
+		chainField := d.Get("chain_field").(string)
+		if rawChain, ok := resp[chainField]; ok && string(rawChain) != "null" {
+			var chainPem string
+			var chainPems []string
+			if err := json.Unmarshal(rawChain, &chainPem); err == nil {
+				chainPems = []string{chainPem}
+			} else if err = json.Unmarshal(rawChain, &chainPems); err != nil {
+				return nil, nil, fmt.Errorf("field %q should be a PEM string or a list of PEM strings", chainField)
+			}
+			certPems = append(certPems, chainPems...)
+		}
+	}
+
+	var certs []*x509.Certificate
+	for _, certPem := range certPems {
+		if strings.TrimSpace(certPem) == "" {
+			continue
+		}
+		parsed, err := parsePEMCertificates([]byte(certPem))
+		if err != nil {
+			return nil, nil, err
+		}
+		certs = append(certs, parsed...)
+	}
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("no certificate found")
+	}
+
+	return certs[0], certs[1:], nil
+}
+
+// resourceWebhookSignedCertValidate checks the signed certificate is for the key of the CSR
+// and, when a chain was returned, is signed by its first certificate.
+func resourceWebhookSignedCertValidate(csr *x509.CertificateRequest, cert *x509.Certificate, chain []*x509.Certificate) error {
+	pubKey, ok := csr.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pubKey.Equal(cert.PublicKey) {
+		return fmt.Errorf("the public key of the returned certificate is not the one of the CSR")
+	}
+
+	if len(chain) > 0 {
+		if err := cert.CheckSignatureFrom(chain[0]); err != nil {
+			return fmt.Errorf("the returned certificate is not signed by the first certificate of the chain: %w", err)
+		}
+	}
+
+	return nil
+}
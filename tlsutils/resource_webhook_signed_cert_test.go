@@ -0,0 +1,136 @@
+package tlsutils
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResourceWebhookSignedCert(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Webhook CA", nil, nil)
+	otherCSRPem := testCertificateRequest(t, "other.example.com")
+	var calls atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req["profile"] != "server" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		csrPem := req["request"]
+		if r.URL.Path == "/flaky" && calls.Load() == 1 {
+			// a certificate for another key, to be retried
+			csrPem = otherCSRPem
+		}
+		cert, err := testSignCertificateRequest(ca, caKey, csrPem, 24*time.Hour)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/pem" {
+			_, _ = w.Write([]byte(certificateToPEM(cert) + certificateToPEM(ca)))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"signed": certificateToPEM(cert), "chain": []string{certificateToPEM(ca)}})
+	}))
+	t.Cleanup(server.Close)
+
+	csrPem := testCertificateRequest(t, "webhook.example.com")
+	config := func(path string) map[string]interface{} {
+		return map[string]interface{}{
+			"url":               server.URL + path,
+			"csr_pem":           csrPem,
+			"csr_field":         "request",
+			"parameters":        map[string]interface{}{"profile": "server"},
+			"bearer_token":      "token",
+			"ca_cert_pem":       certificateToPEM(server.Certificate()),
+			"certificate_field": "signed",
+			"chain_field":       "chain",
+			"retries":           1,
+			"retry_backoff":     "1ms",
+		}
+	}
+
+	r := resourceWebhookSignedCert()
+	for _, path := range []string{"/json", "/pem", "/flaky"} {
+		calls.Store(0)
+		state := testResourceApply(t, r, nil, config(path), &providerMeta{})
+
+		cert, err := parsePEMCertificate([]byte(state.Attributes["certificate_pem"]))
+		if err != nil {
+			t.Fatalf("%s: unable to parse certificate_pem: %s", path, err)
+		}
+		if cert.Subject.CommonName != "webhook.example.com" || state.ID != colonHex(cert.SerialNumber.Bytes()) {
+			t.Errorf("%s: expected the certificate of the CSR with its serial number as ID, got %s with ID %s", path, cert.Subject, state.ID)
+		}
+		if state.Attributes["ca_chain_pem.#"] != "1" || state.Attributes["ca_chain_pem.0"] != certificateToPEM(ca) {
+			t.Errorf("%s: expected the CA as chain, got %v", path, state.Attributes["ca_chain_pem.0"])
+		}
+		// the self-signed CA is left out of the full chain
+		if state.Attributes["fullchain_pem"] != state.Attributes["certificate_pem"] {
+			t.Errorf("%s: expected the certificate alone as full chain, got %s", path, state.Attributes["fullchain_pem"])
+		}
+		if want := map[string]int32{"/flaky": 2}[path]; want > 0 && calls.Load() != want {
+			t.Errorf("%s: expected %d requests, got %d", path, want, calls.Load())
+		}
+	}
+
+	raw := config("/json")
+	raw["bearer_token"] = "wrong"
+	raw["retries"] = 0
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "failed to sign CSR with "+server.URL+"/json") {
+		t.Errorf("expected an unauthorized request to fail, got %v", diags)
+	}
+}
+
+func TestResourceWebhookSignedCertRevocation(t *testing.T) {
+	issuer, issuerKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	sources := testRevocationSources{ocspThisUpdate: time.Now().Add(-time.Hour), ocspNextUpdate: time.Now().Add(time.Hour), crlThisUpdate: time.Now().Add(-time.Hour), crlNextUpdate: time.Now().Add(time.Hour)}
+	server := testRevocationServer(t, issuer, issuerKey, &sources)
+	network := map[string]string{"connect_timeout": "30s", "retries": "0", "retry_backoff": "1s"}
+	attributes := func(cert string, chain ...string) map[string]string {
+		attributes := map[string]string{"certificate_pem": cert, "ca_chain_pem.#": strconv.Itoa(len(chain))}
+		for i, chainCert := range chain {
+			attributes["ca_chain_pem."+strconv.Itoa(i)] = chainCert
+		}
+		for name, value := range network {
+			attributes[name] = value
+		}
+		return attributes
+	}
+	revoked := certificateToPEM(testRevocationCertificate(t, server, testRevokedSerialNumber, issuer, issuerKey))
+	good := certificateToPEM(testRevocationCertificate(t, server, big.NewInt(43), issuer, issuerKey))
+
+	r := resourceWebhookSignedCert()
+	state, diags := testRefreshRevocation(t, r, attributes(revoked, certificateToPEM(issuer)))
+	if state != nil || len(diags) != 1 || !strings.Contains(diags[0].Summary, "serial number 42 has been revoked") {
+		t.Errorf("expected a revoked certificate to be removed from the state, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(good, certificateToPEM(issuer)))
+	if state == nil || len(diags) > 0 {
+		t.Errorf("expected a good certificate to be kept, got %v", diags)
+	}
+
+	state, diags = testRefreshRevocation(t, r, attributes(revoked))
+	if state == nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "no CA chain") {
+		t.Errorf("expected a warning without CA chain, got %v", diags)
+	}
+}
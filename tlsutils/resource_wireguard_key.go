@@ -0,0 +1,168 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
+)
+
+func resourceWireGuardKey() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Generate a WireGuard Curve25519 key pair and preshared key, with rotation",
+		CreateContext: resourceWireGuardKeyCreate,
+		ReadContext:   resourceWireGuardKeyRead,
+		UpdateContext: resourceWireGuardKeyUpdate,
+		DeleteContext: resourceWireGuardKeyDelete,
+		CustomizeDiff: resourceWireGuardKeyCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"preshared_key": {
+				Description: "also generate a preshared key, rotated with the key pair. Changing it rotates the keys.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"rotation_interval_hours": {
+				Description:      "rotate the keys when this many hours have passed since the last rotation. 0 disables time based rotation.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"rotation_trigger": {
+				Description: "arbitrary value; changing it rotates the keys.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"private_key": {
+				Description: "base64 encoded private key, as generated by `wg genkey`, for the `PrivateKey` of the `[Interface]`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"public_key": {
+				Description: "base64 encoded public key, as derived by `wg pubkey`, for the `PublicKey` of the `[Peer]` on the other side.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"preshared_key_base64": {
+				Description: "base64 encoded preshared key, as generated by `wg genpsk`, when `preshared_key` is set.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"rotated_at": {
+				Description: "time the current keys were generated, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// wireGuardKeyRotationAttributes are the attributes that, once changed, cause new keys to be generated.
+var wireGuardKeyRotationAttributes = []string{"preshared_key", "rotation_trigger"}
+
+func resourceWireGuardKeyCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	id, err := randomID()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(id)
+
+	if err = resourceWireGuardKeyGenerate(d, m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceWireGuardKeyRead(_ context.Context, _ *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceWireGuardKeyUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	rotate, err := rotationDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if rotate || d.HasChanges(wireGuardKeyRotationAttributes...) {
+		if err = resourceWireGuardKeyGenerate(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return nil
+}
+
+func resourceWireGuardKeyDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	d.SetId("")
+
+	return nil
+}
+
+func resourceWireGuardKeyCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	rotate, err := rotationDue(diff, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range wireGuardKeyRotationAttributes {
+		rotate = rotate || diff.HasChange(key)
+	}
+	if !rotate {
+		return nil
+	}
+
+	for _, computed := range []string{"private_key", "public_key", "preshared_key_base64", "rotated_at"} {
+		if err = diff.SetNewComputed(computed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceWireGuardKeyGenerate generates a new key pair, and preshared key when enabled, and records the rotation time.
+func resourceWireGuardKeyGenerate(d *schema.ResourceData, m interface{}) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate WireGuard private key: %w", err)
+	}
+	// clamped like wg genkey
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+	prvKey, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to generate WireGuard private key: %w", err)
+	}
+
+	values := map[string]string{
+		"private_key":          base64.StdEncoding.EncodeToString(prvKey.Bytes()),
+		"public_key":           base64.StdEncoding.EncodeToString(prvKey.PublicKey().Bytes()),
+		"preshared_key_base64": "",
+		"rotated_at":           now(d, m).UTC().Format(time.RFC3339),
+	}
+	if d.Get("preshared_key").(bool) {
+		psk := make([]byte, 32)
+		if _, err = rand.Read(psk); err != nil {
+			return fmt.Errorf("failed to generate WireGuard preshared key: %w", err)
+		}
+		values["preshared_key_base64"] = base64.StdEncoding.EncodeToString(psk)
+	}
+	for k, value := range values {
+		if err = d.Set(k, value); err != nil {
+			return fmt.Errorf("failed to save %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
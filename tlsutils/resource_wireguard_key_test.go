@@ -0,0 +1,34 @@
+package tlsutils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResourceWireGuardKeyRotationInterval(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	config := map[string]interface{}{"rotation_interval_hours": 24}
+
+	r := resourceWireGuardKey()
+	created := testResourceApply(t, r, nil, config, &providerMeta{evaluationTime: createdAt})
+	if got, want := created.Attributes["rotated_at"], createdAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected rotated_at %s, got %s", want, got)
+	}
+
+	kept := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: createdAt.Add(23 * time.Hour)})
+	if kept.Attributes["private_key"] != created.Attributes["private_key"] || kept.Attributes["rotated_at"] != created.Attributes["rotated_at"] {
+		t.Errorf("expected no rotation before rotation_interval_hours")
+	}
+
+	rotatedAt := createdAt.Add(25 * time.Hour)
+	rotated := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: rotatedAt})
+	if rotated.Attributes["private_key"] == created.Attributes["private_key"] || rotated.Attributes["public_key"] == created.Attributes["public_key"] {
+		t.Errorf("expected new keys after rotation_interval_hours")
+	}
+	if got, want := rotated.Attributes["rotated_at"], rotatedAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected rotated_at %s, got %s", want, got)
+	}
+	if rotated.ID != created.ID {
+		t.Errorf("expected the rotation to keep ID %s, got %s", created.ID, rotated.ID)
+	}
+}
@@ -6,51 +6,178 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"math/big"
+	"reflect"
+	"strings"
 	"time"
 )
 
+// oidExtensionDeltaCRLIndicator is the delta CRL indicator extension, see RFC 5280 section 5.2.4.
+var oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+// oidExtensionReasonCode is the reason code CRL entry extension, see RFC 5280 section 5.3.1.
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// oidExtensionInvalidityDate is the invalidity date CRL entry extension, see RFC 5280 section 5.3.2.
+var oidExtensionInvalidityDate = asn1.ObjectIdentifier{2, 5, 29, 24}
+
 func resourceX509Crl() *schema.Resource {
 	return &schema.Resource{
 		Description:   "Generate x509 crl",
 		CreateContext: resourceX509CrlCreate,
 		ReadContext:   resourceX509CrlRead,
+		UpdateContext: resourceX509CrlUpdate,
 		DeleteContext: resourceX509CrlDelete,
+		CustomizeDiff: resourceX509CrlCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"private_key_pem": {
-				Description: "private key in PEM format.",
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
+				Description:      "private key in PEM format.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
 			},
 			"certificate_pem": {
-				Description: "certificate in PEM format.",
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
+				Description:      "certificate in PEM format.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentPEM,
 			},
 			"revocation_list": {
 				Description: "revoked certificates in pem format.",
 				Type:        schema.TypeList,
-				Required:    true,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type:             schema.TypeString,
+					DiffSuppressFunc: suppressReorderedList(normalizePEM),
+				},
+			},
+			"revoked_certificate": {
+				Description: "revoked certificate entries with a reason code and invalidity date.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"certificate_pem": {
+							Description:      "revoked certificate in PEM format.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							DiffSuppressFunc: suppressEquivalentPEM,
+						},
+						"serial_number": {
+							Description: "serial number of the revoked certificate in hex, optionally colon separated. Used when certificate_pem is not set.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+						"revocation_time": {
+							Description:      "revocation time in RFC3339 format. Defaults to the certificate not before time.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+						},
+						"reason": {
+							Description:      "revocation reason: " + strings.Join(supportedCRLReasonsStr(), ", ") + ". `remove_from_crl` is only allowed in delta CRLs, or with `incremental` to drop a carried over entry.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(supportedCRLReasonsStr(), false)),
+						},
+						"invalidity_date": {
+							Description:      "date in RFC3339 format on which the key is known or suspected to have been compromised.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+						},
+					},
+				},
+			},
+			"revoke_superseded": {
+				Description: "also revoke, with reason `superseded`, the certificates of the provider `issuance_journal` issued by this CA whose resource was replaced or destroyed, until they expire. The CRL is regenerated when they change.",
+				Type:        schema.TypeBool,
+				Optional:    true,
 				ForceNew:    true,
+				Default:     false,
+			},
+			"superseded_serial_numbers": {
+				Description: "serial numbers in hex of the certificates revoked by `revoke_superseded`.",
+				Type:        schema.TypeList,
+				Computed:    true,
 				Elem:        &schema.Schema{Type: schema.TypeString},
 			},
+			"incremental": {
+				Description: "when the revoked certificates change, carry the entries of the current CRL over to the next one and number it after the current one, instead of deriving both from the configuration and the time. Certificates stay revoked once removed from the configuration, so only the new ones need to be declared.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"delta_crl_base_number": {
+				Description: "CRL number of the base CRL. When set, a delta CRL carrying the delta CRL indicator extension is generated.",
+				Type:        schema.TypeInt,
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"validity_hours": {
+				Description:      "hours between the `this_update` and `next_update` times of the CRL.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          168,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+			},
+			"renew_before_hours": {
+				Description:      "generate the CRL again in-place, numbered after the current one, when `next_update` is less than this many hours away. 0 disables renewal.",
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          48,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"evaluation_time": {
+				Description:      "time in RFC3339 format used as `this_update` and to derive the CRL number, instead of the provider evaluation_time or the current time. Not used by `incremental` updates.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsRFC3339Time),
+			},
+			"crl_number": {
+				Description: "CRL number of the generated CRL: the current time as a Unix timestamp, or the previous number plus one after an `incremental` update or when the time did not move past it.",
+				Type:        schema.TypeInt,
+				Computed:    true,
+			},
 			"crl_pem": {
 				Description: "CRL in pem format.",
 				Type:        schema.TypeString,
 				Computed:    true,
 			},
+			"this_update": {
+				Description: "time the CRL was signed at, in RFC3339.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"next_update": {
+				Description: "time by which the next CRL is issued, in RFC3339. Relying parties consider the CRL stale afterwards.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
 		},
 	}
 }
 
-func resourceX509CrlCreate(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+func resourceX509CrlCreate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceX509CrlGenerate(d, m, nil)
+}
+
+// resourceX509CrlGenerate signs the CRL of the revoked certificates, numbered after previous when set. With
+// incremental, the entries of previous are carried over unless the configuration revokes the same serial number
+// again or removes it.
+func resourceX509CrlGenerate(d *schema.ResourceData, m interface{}, previous *x509.RevocationList) diag.Diagnostics {
 	privKey, _, err := parsePrivateKeyPEM([]byte(d.Get("private_key_pem").(string)))
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("failed to parse private key PEM: %w", err))
@@ -73,10 +200,77 @@ func resourceX509CrlCreate(_ context.Context, d *schema.ResourceData, _ interfac
 		})
 	}
 
-	crlBytes, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+	for i, rawEntry := range d.Get("revoked_certificate").([]interface{}) {
+		entry, err := resourceX509CrlRevokedCertificate(rawEntry.(map[string]interface{}))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("invalid revoked_certificate (element #%d): %w", i, err))
+		}
+		if entry.ReasonCode == crlReasons["remove_from_crl"] && d.Get("delta_crl_base_number").(int) == 0 && !d.Get("incremental").(bool) {
+			return diag.FromErr(fmt.Errorf("invalid revoked_certificate (element #%d): reason remove_from_crl is only allowed in delta or incremental CRLs", i))
+		}
+		revocationList = append(revocationList, *entry)
+	}
+
+	superseded := make([]string, 0)
+	if d.Get("revoke_superseded").(bool) {
+		entries, err := supersededJournalEntries(m, cert, now(d, m))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		for _, entry := range entries {
+			serialNumber, _ := new(big.Int).SetString(entry.SerialNumber, 16)
+			if serialNumber == nil {
+				return diag.FromErr(fmt.Errorf("invalid serial number %q in the issuance journal", entry.SerialNumber))
+			}
+			revocationList = append(revocationList, x509.RevocationListEntry{
+				SerialNumber:   serialNumber,
+				RevocationTime: *entry.SupersededAt,
+				ReasonCode:     crlReasons["superseded"],
+			})
+			superseded = append(superseded, entry.SerialNumber)
+		}
+	}
+
+	thisUpdate := now(d, m).UTC().Truncate(time.Second)
+	crlNumber := thisUpdate.Unix()
+	if d.Get("incremental").(bool) {
+		var previousEntries []x509.RevocationListEntry
+		if previous != nil {
+			previousEntries = previous.RevokedCertificateEntries
+		}
+		revocationList = carryOverRevocationEntries(previousEntries, revocationList, d.Get("delta_crl_base_number").(int) == 0)
+	}
+	if previous != nil && previous.Number != nil {
+		// CRL numbers must increase, whatever the time
+		if d.Get("incremental").(bool) || previous.Number.Int64() >= crlNumber {
+			crlNumber = previous.Number.Int64() + 1
+		}
+	}
+
+	template := &x509.RevocationList{
 		RevokedCertificateEntries: revocationList,
-		Number:                    big.NewInt(time.Now().Unix()),
-	}, cert, privKey.(crypto.Signer))
+		Number:                    big.NewInt(crlNumber),
+		ThisUpdate:                thisUpdate,
+		NextUpdate:                thisUpdate.Add(time.Duration(d.Get("validity_hours").(int)) * time.Hour),
+	}
+
+	if baseNumber, ok := d.GetOk("delta_crl_base_number"); ok {
+		if int64(baseNumber.(int)) >= crlNumber {
+			return diag.FromErr(fmt.Errorf("delta_crl_base_number (%d) must be lower than the generated CRL number (%d)", baseNumber.(int), crlNumber))
+		}
+
+		deltaCRLIndicator, err := asn1.Marshal(big.NewInt(int64(baseNumber.(int))))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("unable to encode delta CRL indicator: %w", err))
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:       oidExtensionDeltaCRLIndicator,
+			Critical: true,
+			Value:    deltaCRLIndicator,
+		})
+	}
+
+	crlBytes, err := x509.CreateRevocationList(rand.Reader, template, cert, privKey.(crypto.Signer))
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("unable to create crl: %w", err))
 	}
@@ -89,6 +283,34 @@ func resourceX509CrlCreate(_ context.Context, d *schema.ResourceData, _ interfac
 		return diag.FromErr(fmt.Errorf("failed to save crl: %w", err))
 	}
 
+	if err = d.Set("crl_number", int(crlNumber)); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save crl number: %w", err))
+	}
+
+	if err = d.Set("superseded_serial_numbers", superseded); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save superseded_serial_numbers: %w", err))
+	}
+
+	if err = resourceX509CrlSetUpdateTimes(d, template); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+// resourceX509CrlSetUpdateTimes saves this_update and next_update of crl, empty when not set.
+func resourceX509CrlSetUpdateTimes(d *schema.ResourceData, crl *x509.RevocationList) error {
+	values := map[string]time.Time{"this_update": crl.ThisUpdate, "next_update": crl.NextUpdate}
+	for k, value := range values {
+		formatted := ""
+		if !value.IsZero() {
+			formatted = value.UTC().Format(time.RFC3339)
+		}
+		if err := d.Set(k, formatted); err != nil {
+			return fmt.Errorf("failed to save %s: %w", k, err)
+		}
+	}
+
 	return nil
 }
 
@@ -96,15 +318,219 @@ func resourceX509CrlRead(ctx context.Context, d *schema.ResourceData, m interfac
 	if d.Id() != resourceX509GetHash(d) {
 		return resourceX509CrlCreate(ctx, d, m)
 	}
+
+	crl, err := parsePEMRevocationList([]byte(d.Get("crl_pem").(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse crl_pem: %w", err))
+	}
+
+	if err = d.Set("crl_number", int(crl.Number.Int64())); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to save crl number: %w", err))
+	}
+
+	if err = resourceX509CrlSetUpdateTimes(d, crl); err != nil {
+		return diag.FromErr(err)
+	}
+
 	return nil
 }
 
+// resourceX509CrlUpdate generates the CRL again, numbered after the current one, when the revoked certificates changed
+// or its renewal is due.
+func resourceX509CrlUpdate(_ context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	renew, err := resourceX509CrlRenewalDue(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !renew && !d.HasChanges(x509CrlRevocationAttributes...) {
+		return nil
+	}
+
+	previousPem, _ := d.GetChange("crl_pem")
+	previous, err := parsePEMRevocationList([]byte(previousPem.(string)))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to parse the current crl_pem: %w", err))
+	}
+
+	return resourceX509CrlGenerate(d, m, previous)
+}
+
 func resourceX509CrlDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
 	d.SetId("")
 
 	return nil
 }
 
+// x509CrlRevocationAttributes are the attributes that, once changed, cause the CRL to be generated again in-place.
+var x509CrlRevocationAttributes = []string{"revocation_list", "revoked_certificate", "superseded_serial_numbers"}
+
+// resourceX509CrlCustomizeDiff generates the CRL again when the revoked certificates, or the superseded certificates
+// of the issuance journal, changed, or when its renewal is due.
+func resourceX509CrlCustomizeDiff(_ context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if err := resourceX509CrlPlanSuperseded(diff, m); err != nil {
+		return err
+	}
+
+	regenerate, err := resourceX509CrlRenewalDue(diff, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range x509CrlRevocationAttributes {
+		regenerate = regenerate || diff.HasChange(key)
+	}
+	if !regenerate {
+		return nil
+	}
+
+	for _, computed := range []string{"crl_pem", "crl_number", "this_update", "next_update"} {
+		if err = diff.SetNewComputed(computed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceX509CrlRenewalDue reports whether the next_update of the prior state is less than renew_before_hours away.
+func resourceX509CrlRenewalDue(d resourceChanges, m interface{}) (bool, error) {
+	renewBefore := d.Get("renew_before_hours").(int)
+	if renewBefore == 0 {
+		return false, nil
+	}
+	// CRLs generated before validity_hours existed have no next_update
+	if nextUpdate, _ := d.GetChange("next_update"); nextUpdate.(string) == "" {
+		return true, nil
+	}
+
+	return priorTimeReached(d, m, "next_update", -time.Duration(renewBefore)*time.Hour)
+}
+
+// resourceX509CrlPlanSuperseded plans superseded_serial_numbers from the issuance journal.
+func resourceX509CrlPlanSuperseded(diff *schema.ResourceDiff, m interface{}) error {
+	if !diff.Get("revoke_superseded").(bool) || diff.HasChange("revoke_superseded") {
+		return nil
+	}
+
+	cert, err := parsePEMCertificate([]byte(diff.Get("certificate_pem").(string)))
+	if err != nil {
+		// the new certificate_pem replaces the CRL anyway
+		return nil
+	}
+	entries, err := supersededJournalEntries(m, cert, now(diff, m))
+	if err != nil {
+		return err
+	}
+
+	superseded := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		superseded = append(superseded, entry.SerialNumber)
+	}
+	if reflect.DeepEqual(superseded, diff.Get("superseded_serial_numbers").([]interface{})) {
+		return nil
+	}
+
+	return diff.SetNew("superseded_serial_numbers", superseded)
+}
+
+// carryOverRevocationEntries returns the previous CRL entries followed by the new ones. A new entry replaces the
+// previous entry of the same serial number, or drops it when full is set and its reason is remove_from_crl.
+func carryOverRevocationEntries(previous, entries []x509.RevocationListEntry, full bool) []x509.RevocationListEntry {
+	bySerial := make(map[string]int, len(previous))
+	result := make([]x509.RevocationListEntry, 0, len(previous)+len(entries))
+	for _, entry := range previous {
+		// the reason code extension is added again from ReasonCode
+		extensions := make([]pkix.Extension, 0, len(entry.Extensions))
+		for _, extension := range entry.Extensions {
+			if !extension.Id.Equal(oidExtensionReasonCode) {
+				extensions = append(extensions, extension)
+			}
+		}
+		bySerial[entry.SerialNumber.String()] = len(result)
+		result = append(result, x509.RevocationListEntry{
+			SerialNumber:    entry.SerialNumber,
+			RevocationTime:  entry.RevocationTime,
+			ReasonCode:      entry.ReasonCode,
+			ExtraExtensions: extensions,
+		})
+	}
+
+	removed := make(map[int]bool)
+	for _, entry := range entries {
+		i, ok := bySerial[entry.SerialNumber.String()]
+		switch {
+		case full && entry.ReasonCode == crlReasons["remove_from_crl"]:
+			if ok {
+				removed[i] = true
+			}
+		case ok:
+			result[i] = entry
+			delete(removed, i)
+		default:
+			bySerial[entry.SerialNumber.String()] = len(result)
+			result = append(result, entry)
+		}
+	}
+
+	kept := result[:0]
+	for i, entry := range result {
+		if !removed[i] {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept
+}
+
+// resourceX509CrlRevokedCertificate builds the CRL entry of a revoked_certificate block.
+func resourceX509CrlRevokedCertificate(entry map[string]interface{}) (*x509.RevocationListEntry, error) {
+	result := &x509.RevocationListEntry{}
+
+	if certPem := entry["certificate_pem"].(string); certPem != "" {
+		cert, err := parsePEMCertificate([]byte(certPem))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate_pem: %w", err)
+		}
+		result.SerialNumber = cert.SerialNumber
+		result.RevocationTime = cert.NotBefore
+	} else if serial := entry["serial_number"].(string); serial != "" {
+		serialNumber, ok := new(big.Int).SetString(strings.ReplaceAll(serial, ":", ""), 16)
+		if !ok {
+			return nil, fmt.Errorf("serial_number %q is not a hex number", serial)
+		}
+		result.SerialNumber = serialNumber
+	} else {
+		return nil, fmt.Errorf("one of certificate_pem or serial_number must be set")
+	}
+
+	if revocationTime := entry["revocation_time"].(string); revocationTime != "" {
+		result.RevocationTime, _ = time.Parse(time.RFC3339, revocationTime)
+	} else if result.RevocationTime.IsZero() {
+		return nil, fmt.Errorf("revocation_time is required when certificate_pem is not set")
+	}
+
+	if reason := entry["reason"].(string); reason != "" {
+		result.ReasonCode = crlReasons[reason]
+	}
+
+	if invalidityDate := entry["invalidity_date"].(string); invalidityDate != "" {
+		date, _ := time.Parse(time.RFC3339, invalidityDate)
+		value, err := asn1.MarshalWithParams(date.UTC(), "generalized")
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode invalidity_date: %w", err)
+		}
+		result.ExtraExtensions = append(result.ExtraExtensions, pkix.Extension{
+			Id:    oidExtensionInvalidityDate,
+			Value: value,
+		})
+	}
+
+	return result, nil
+}
+
 func resourceX509GetHash(d *schema.ResourceData) string {
 	idHash := sha1.New()
 	idHash.Write([]byte(d.Get("private_key_pem").(string)))
@@ -113,6 +539,21 @@ func resourceX509GetHash(d *schema.ResourceData) string {
 		revocationCertPem := []byte(revocationCertPem.(string))
 		idHash.Write(revocationCertPem)
 	}
+	for _, entry := range d.Get("revoked_certificate").([]interface{}) {
+		entry := entry.(map[string]interface{})
+		for _, key := range []string{"certificate_pem", "serial_number", "revocation_time", "reason", "invalidity_date"} {
+			idHash.Write([]byte(entry[key].(string)))
+		}
+	}
+	if evaluationTime, ok := d.GetOk("evaluation_time"); ok {
+		idHash.Write([]byte(evaluationTime.(string)))
+	}
+	if d.Get("revoke_superseded").(bool) {
+		idHash.Write([]byte("revoke_superseded"))
+	}
+	if baseNumber, ok := d.GetOk("delta_crl_base_number"); ok {
+		idHash.Write([]byte(fmt.Sprintf("delta:%d", baseNumber.(int))))
+	}
 	hash := idHash.Sum([]byte{})
 	result := make([]byte, base64.StdEncoding.EncodedLen(len(hash)))
 	base64.StdEncoding.Encode(result, hash)
@@ -0,0 +1,334 @@
+package tlsutils
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceX509CrlDelta(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "revoked.example.com"}}, ca, caKey)
+	config := map[string]interface{}{
+		"certificate_pem": certificateToPEM(ca),
+		"private_key_pem": caKeyPem,
+		"revocation_list": []interface{}{certificateToPEM(revoked)},
+	}
+
+	full := testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{})
+	fullCRL, err := parsePEMRevocationList([]byte(full.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Attributes["crl_number"] != fullCRL.Number.String() {
+		t.Errorf("expected crl_number %s, got %s", fullCRL.Number, full.Attributes["crl_number"])
+	}
+	for _, extension := range fullCRL.Extensions {
+		if extension.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			t.Errorf("expected a complete CRL without delta CRL indicator")
+		}
+	}
+
+	baseNumber, _ := strconv.Atoi(full.Attributes["crl_number"])
+	config["delta_crl_base_number"] = baseNumber - 1
+	delta := testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{})
+	if delta.ID == full.ID {
+		t.Errorf("expected the delta CRL to have its own ID")
+	}
+	deltaCRL, err := parsePEMRevocationList([]byte(delta.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = deltaCRL.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("expected the delta CRL to be signed by the CA: %s", err)
+	}
+	var indicator *pkix.Extension
+	for i, extension := range deltaCRL.Extensions {
+		if extension.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			indicator = &deltaCRL.Extensions[i]
+		}
+	}
+	if indicator == nil || !indicator.Critical {
+		t.Fatalf("expected a critical delta CRL indicator, got %v", indicator)
+	}
+	var base *big.Int
+	if _, err = asn1.Unmarshal(indicator.Value, &base); err != nil || base.Int64() != int64(baseNumber-1) {
+		t.Errorf("expected the base CRL number %d, got %v: %v", baseNumber-1, base, err)
+	}
+
+	config["delta_crl_base_number"] = baseNumber + 3600
+	r := resourceX509Crl()
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "must be lower than the generated CRL number") {
+		t.Errorf("expected a base CRL number error, got %v", diags)
+	}
+}
+
+func TestResourceX509CrlRevokedCertificate(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := testCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "revoked.example.com"}}, ca, caKey)
+	config := map[string]interface{}{
+		"certificate_pem": certificateToPEM(ca),
+		"private_key_pem": caKeyPem,
+		"revoked_certificate": []interface{}{
+			map[string]interface{}{
+				"certificate_pem": certificateToPEM(revoked),
+				"reason":          "key_compromise",
+				"invalidity_date": "2024-01-02T03:04:05Z",
+			},
+			map[string]interface{}{
+				"serial_number":   "01:0a",
+				"revocation_time": "2024-02-01T00:00:00Z",
+				"reason":          "certificate_hold",
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{})
+	crl, err := parsePEMRevocationList([]byte(state.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crl.RevokedCertificateEntries) != 2 {
+		t.Fatalf("expected 2 CRL entries, got %d", len(crl.RevokedCertificateEntries))
+	}
+
+	compromised := crl.RevokedCertificateEntries[0]
+	if compromised.SerialNumber.Cmp(revoked.SerialNumber) != 0 || compromised.ReasonCode != 1 || !compromised.RevocationTime.Equal(revoked.NotBefore.UTC().Truncate(time.Second)) {
+		t.Errorf("expected the key compromise of the certificate, got %+v", compromised)
+	}
+	var invalidityDate time.Time
+	for _, extension := range compromised.Extensions {
+		if extension.Id.Equal(oidExtensionInvalidityDate) {
+			if _, err = asn1.UnmarshalWithParams(extension.Value, &invalidityDate, "generalized"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !invalidityDate.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("expected the invalidity date 2024-01-02T03:04:05Z, got %s", invalidityDate)
+	}
+
+	hold := crl.RevokedCertificateEntries[1]
+	if hold.SerialNumber.Int64() != 0x010a || hold.ReasonCode != 6 || !hold.RevocationTime.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the certificate hold of serial number 01:0a, got %+v", hold)
+	}
+
+	config["revoked_certificate"] = []interface{}{map[string]interface{}{"serial_number": "01:0a", "revocation_time": "2024-03-01T00:00:00Z", "reason": "remove_from_crl"}}
+	r := resourceX509Crl()
+	diff, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &providerMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.Apply(context.Background(), nil, diff, &providerMeta{}); !diags.HasError() || !strings.Contains(diags[0].Summary, "only allowed in delta or incremental CRLs") {
+		t.Errorf("expected remove_from_crl to be rejected in a complete CRL, got %v", diags)
+	}
+
+	config["delta_crl_base_number"] = 1
+	delta := testResourceApply(t, r, nil, config, &providerMeta{})
+	deltaCRL, err := parsePEMRevocationList([]byte(delta.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltaCRL.RevokedCertificateEntries) != 1 || deltaCRL.RevokedCertificateEntries[0].ReasonCode != 8 {
+		t.Errorf("expected the removal from CRL in the delta CRL, got %+v", deltaCRL.RevokedCertificateEntries)
+	}
+}
+
+func TestResourceX509CrlEvaluationTime(t *testing.T) {
+	ca, caKey := testCertificateAuthority(t, "Example CA", nil, nil)
+	caKeyPem, err := privateKeyToPEM(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]interface{}{
+		"certificate_pem": certificateToPEM(ca),
+		"private_key_pem": caKeyPem,
+	}
+
+	providerTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{evaluationTime: providerTime})
+	if state.Attributes["crl_number"] != strconv.FormatInt(providerTime.Unix(), 10) {
+		t.Errorf("expected the CRL number of the provider evaluation_time, got %s", state.Attributes["crl_number"])
+	}
+
+	config["evaluation_time"] = "2024-06-01T00:00:00Z"
+	state = testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{evaluationTime: providerTime})
+	if state.Attributes["crl_number"] != strconv.FormatInt(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), 10) {
+		t.Errorf("expected the CRL number of the resource evaluation_time, got %s", state.Attributes["crl_number"])
+	}
+	again := testResourceApply(t, resourceX509Crl(), nil, config, &providerMeta{})
+	if again.ID != state.ID || again.Attributes["crl_number"] != state.Attributes["crl_number"] {
+		t.Errorf("expected the same ID and CRL number with the same evaluation_time")
+	}
+}
+
+func TestResourceX509CrlRevokeSuperseded(t *testing.T) {
+	meta := &providerMeta{issuanceJournal: &fileIssuanceJournal{path: filepath.Join(t.TempDir(), "journal.json")}}
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), meta)
+	r := resourceDualCert()
+	cert := testResourceApply(t, r, nil, map[string]interface{}{
+		"ca_cert_pem":           ca.Attributes["intermediate_cert_pem"],
+		"ca_private_key_pem":    ca.Attributes["intermediate_private_key_pem"],
+		"validity_period_hours": 24,
+	}, meta)
+
+	crl := resourceX509Crl()
+	config := map[string]interface{}{
+		"certificate_pem":   ca.Attributes["intermediate_cert_pem"],
+		"private_key_pem":   ca.Attributes["intermediate_private_key_pem"],
+		"revoke_superseded": true,
+	}
+	state := testResourceApply(t, crl, nil, config, meta)
+	if state.Attributes["superseded_serial_numbers.#"] != "0" {
+		t.Errorf("expected no superseded certificate, got %s", state.Attributes["superseded_serial_numbers.#"])
+	}
+
+	if _, diags := r.Apply(context.Background(), cert, &terraform.InstanceDiff{Destroy: true}, meta); diags.HasError() {
+		t.Fatalf("destroy failed: %v", diags)
+	}
+	diff, err := crl.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.RequiresNew() {
+		t.Fatalf("expected the superseded certificates to update the CRL in-place, got %v", diff)
+	}
+
+	state = testResourceApply(t, crl, state, config, meta)
+	revocationList, err := parsePEMRevocationList([]byte(state.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked := map[string]int{}
+	for _, entry := range revocationList.RevokedCertificateEntries {
+		revoked[entry.SerialNumber.Text(16)] = entry.ReasonCode
+	}
+	for _, key := range []string{"ecdsa_cert_pem", "rsa_cert_pem"} {
+		issued, err := parsePEMCertificate([]byte(cert.Attributes[key]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason, ok := revoked[issued.SerialNumber.Text(16)]; !ok || reason != crlReasons["superseded"] {
+			t.Errorf("expected the certificate of %s to be revoked as superseded, got %v", key, revoked)
+		}
+	}
+	if len(revoked) != 2 || state.Attributes["superseded_serial_numbers.#"] != "2" {
+		t.Errorf("expected only the certificates of the destroyed tlsutils_dual_cert to be revoked, got %v", revoked)
+	}
+}
+
+func TestResourceX509CrlUpdateTimes(t *testing.T) {
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), &providerMeta{})
+	config := map[string]interface{}{
+		"certificate_pem":     ca.Attributes["intermediate_cert_pem"],
+		"private_key_pem":     ca.Attributes["intermediate_private_key_pem"],
+		"revoked_certificate": []interface{}{map[string]interface{}{"serial_number": "01:02", "revocation_time": "2024-01-01T00:00:00Z"}},
+		"validity_hours":      168,
+		"renew_before_hours":  48,
+	}
+	signedAt := time.Now().UTC().Truncate(time.Second)
+
+	r := resourceX509Crl()
+	created := testResourceApply(t, r, nil, config, &providerMeta{evaluationTime: signedAt})
+
+	crl, err := parsePEMRevocationList([]byte(created.Attributes["crl_pem"]))
+	if err != nil {
+		t.Fatalf("unable to parse crl_pem: %s", err)
+	}
+	if !crl.ThisUpdate.Equal(signedAt) {
+		t.Errorf("expected thisUpdate %s, got %s", signedAt, crl.ThisUpdate)
+	}
+	if want := signedAt.Add(168 * time.Hour); !crl.NextUpdate.Equal(want) {
+		t.Errorf("expected nextUpdate %s, got %s", want, crl.NextUpdate)
+	}
+	if got, want := created.Attributes["this_update"], signedAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected this_update %s, got %s", want, got)
+	}
+	if got, want := created.Attributes["next_update"], signedAt.Add(168*time.Hour).Format(time.RFC3339); got != want {
+		t.Errorf("expected next_update %s, got %s", want, got)
+	}
+	if err = checkRevocationFreshness("CRL", crl.ThisUpdate, crl.NextUpdate, signedAt); err != nil {
+		t.Errorf("expected a fresh CRL: %s", err)
+	}
+
+	kept := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: signedAt.Add(100 * time.Hour)})
+	if kept.Attributes["crl_pem"] != created.Attributes["crl_pem"] {
+		t.Errorf("expected no renewal while next_update is more than renew_before_hours away")
+	}
+
+	renewedAt := signedAt.Add(130 * time.Hour)
+	renewed := testResourceApply(t, r, created, config, &providerMeta{evaluationTime: renewedAt})
+	if got, want := renewed.Attributes["this_update"], renewedAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected this_update %s, got %s", want, got)
+	}
+	createdNumber, _ := strconv.Atoi(created.Attributes["crl_number"])
+	if renewedNumber, _ := strconv.Atoi(renewed.Attributes["crl_number"]); renewedNumber <= createdNumber {
+		t.Errorf("expected crl_number to increase from %s, got %s", created.Attributes["crl_number"], renewed.Attributes["crl_number"])
+	}
+	if renewed.ID != created.ID {
+		t.Errorf("expected the renewal to keep ID %s, got %s", created.ID, renewed.ID)
+	}
+}
+
+func TestResourceX509CrlIncremental(t *testing.T) {
+	ca := testResourceApply(t, resourcePKIBootstrap(), nil, testPKIBootstrapConfig("traditional", false), &providerMeta{})
+	config := func(entries ...map[string]interface{}) map[string]interface{} {
+		revoked := make([]interface{}, 0, len(entries))
+		for _, entry := range entries {
+			revoked = append(revoked, entry)
+		}
+		return map[string]interface{}{
+			"certificate_pem":     ca.Attributes["intermediate_cert_pem"],
+			"private_key_pem":     ca.Attributes["intermediate_private_key_pem"],
+			"incremental":         true,
+			"revoked_certificate": revoked,
+		}
+	}
+	serialNumbers := func(state *terraform.InstanceState) map[string]int {
+		crl, err := parsePEMRevocationList([]byte(state.Attributes["crl_pem"]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		revoked := map[string]int{}
+		for _, entry := range crl.RevokedCertificateEntries {
+			revoked[entry.SerialNumber.Text(16)] = entry.ReasonCode
+		}
+		return revoked
+	}
+
+	r := resourceX509Crl()
+	created := testResourceApply(t, r, nil, config(map[string]interface{}{"serial_number": "01:02", "revocation_time": "2024-01-01T00:00:00Z", "reason": "certificate_hold"}), &providerMeta{})
+	added := testResourceApply(t, r, created, config(map[string]interface{}{"serial_number": "03:04", "revocation_time": "2024-01-01T00:00:00Z", "reason": "key_compromise"}), &providerMeta{})
+	if got := serialNumbers(added); len(got) != 2 || got["102"] != crlReasons["certificate_hold"] || got["304"] != crlReasons["key_compromise"] {
+		t.Errorf("expected the entry of the current CRL to be carried over, got %v", got)
+	}
+	createdNumber, _ := strconv.Atoi(created.Attributes["crl_number"])
+	if got := added.Attributes["crl_number"]; got != strconv.Itoa(createdNumber+1) {
+		t.Errorf("expected crl_number %d, got %s", createdNumber+1, got)
+	}
+
+	released := testResourceApply(t, r, added, config(map[string]interface{}{"serial_number": "01:02", "revocation_time": "2024-01-01T00:00:00Z", "reason": "remove_from_crl"}), &providerMeta{})
+	if got := serialNumbers(released); len(got) != 1 || got["304"] != crlReasons["key_compromise"] {
+		t.Errorf("expected remove_from_crl to drop the carried over certificate_hold, got %v", got)
+	}
+}
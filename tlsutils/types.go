@@ -27,6 +27,16 @@ func supportedAlgorithms() []Algorithm {
 	}
 }
 
+// supportedAlgorithmsStr returns the same content of supportedAlgorithms but as a slice of string.
+func supportedAlgorithmsStr() []string {
+	supported := supportedAlgorithms()
+	supportedStr := make([]string, len(supported))
+	for i := range supported {
+		supportedStr[i] = supported[i].String()
+	}
+	return supportedStr
+}
+
 // ECDSACurve represents a type of ECDSA elliptic curve.
 type ECDSACurve string
 
@@ -61,6 +71,27 @@ func supportedECDSACurvesStr() []string {
 	return supportedStr
 }
 
+// PrivateKeyFormat represents an encoding of private keys in PEM format.
+type PrivateKeyFormat string
+
+const (
+	// PrivateKeyFormatTraditional is PKCS#1 for RSA, SEC 1 for ECDSA and PKCS#8 for ED25519.
+	PrivateKeyFormatTraditional PrivateKeyFormat = "traditional"
+	PrivateKeyFormatPKCS8       PrivateKeyFormat = "pkcs8"
+)
+
+func (f PrivateKeyFormat) String() string {
+	return string(f)
+}
+
+// supportedPrivateKeyFormatsStr returns the names of the supported PrivateKeyFormat.
+func supportedPrivateKeyFormatsStr() []string {
+	return []string{
+		PrivateKeyFormatTraditional.String(),
+		PrivateKeyFormatPKCS8.String(),
+	}
+}
+
 // PEMPreamble represents the heading used in a PEM-formatted for the "encapsulation boundaries",
 // that is used to delimit the "encapsulated text portion" of cryptographic documents.
 //
@@ -106,3 +137,34 @@ func pemBlockToPEMPreamble(block *pem.Block) (PEMPreamble, error) {
 		return "", fmt.Errorf("unsupported PEM preamble/type: %s", block.Type)
 	}
 }
+
+// crlReasons maps the supported CRL entry revocation reasons to their reason code,
+// as defined in RFC 5280 section 5.3.1.
+var crlReasons = map[string]int{
+	"unspecified":            0,
+	"key_compromise":         1,
+	"ca_compromise":          2,
+	"affiliation_changed":    3,
+	"superseded":             4,
+	"cessation_of_operation": 5,
+	"certificate_hold":       6,
+	"remove_from_crl":        8,
+	"privilege_withdrawn":    9,
+	"aa_compromise":          10,
+}
+
+// supportedCRLReasonsStr returns the names of the supported revocation reasons, ordered by reason code.
+func supportedCRLReasonsStr() []string {
+	return []string{
+		"unspecified",
+		"key_compromise",
+		"ca_compromise",
+		"affiliation_changed",
+		"superseded",
+		"cessation_of_operation",
+		"certificate_hold",
+		"remove_from_crl",
+		"privilege_withdrawn",
+		"aa_compromise",
+	}
+}